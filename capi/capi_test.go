@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// TestCProgram 把包构建为共享库，编译 testdata/capi_test.c 并在测试映像上运行
+func TestCProgram(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the C test program is built and run on Linux only")
+	}
+	if testing.Short() {
+		t.Skip("builds a shared library")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("no C compiler")
+	}
+
+	dir := t.TempDir()
+	run := func(name string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(name, args...)
+		cmd.Env = append(os.Environ(), "LD_LIBRARY_PATH="+dir)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s %s: %v\n%s", filepath.Base(name), strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+	run("go", "build", "-buildmode=c-shared", "-o", filepath.Join(dir, "libexfat.so"), ".")
+	program := filepath.Join(dir, "capi_test")
+	run(cc, "-o", program, filepath.Join("testdata", "capi_test.c"), "-I"+dir, "-L"+dir, "-lexfat", "-lpthread")
+
+	content := bytes.Repeat([]byte("extracted "), 300)
+	img := testimage.Build(testimage.Options{},
+		testimage.File("hello.txt", []byte("hello world")),
+		testimage.Dir("Dir", testimage.File("inner.bin", content)))
+	image := filepath.Join(dir, "image.vhd")
+	if err := os.WriteFile(image, testimage.FixedVHD(img.Bytes), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+
+	if got := run(program, image, out); strings.TrimSpace(got) != "ok" {
+		t.Errorf("unexpected output: %s", got)
+	}
+	got, err := os.ReadFile(filepath.Join(out, "inner.bin"))
+	if err != nil || !bytes.Equal(got, content) {
+		t.Errorf("extracted file: %d bytes, %v", len(got), err)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * 演示 libexfat 的 C ABI：列出目录、读取文件开头、提取目录。
+ *
+ *   go build -buildmode=c-shared -o capi/example/libexfat.so ./capi
+ *   cc -o capi/example/example capi/example/example.c -Icapi/example -Lcapi/example -lexfat
+ *   LD_LIBRARY_PATH=capi/example capi/example/example image.vhd / /Dir/file.txt out
+ */
+#include <stdio.h>
+#include <stdlib.h>
+#include "libexfat.h"
+
+static void print_error(int64_t h, const char *what) {
+	char *msg = exfat_last_error(h);
+	fprintf(stderr, "%s: %s\n", what, msg ? msg : "unknown error");
+	exfat_free(msg);
+}
+
+int main(int argc, char **argv) {
+	if (argc != 5) {
+		fprintf(stderr, "usage: %s <image> <dir> <file> <output dir>\n", argv[0]);
+		return 2;
+	}
+
+	int64_t h = exfat_open(argv[1]);
+	if (h == 0) {
+		print_error(0, "open");
+		return 1;
+	}
+
+	char *list = exfat_list(h, argv[2]);
+	if (list == NULL) {
+		print_error(h, "list");
+		return 1;
+	}
+	printf("%s\n", list);
+	exfat_free(list);
+
+	char buf[64];
+	int64_t n = exfat_read(h, argv[3], 0, sizeof(buf), buf);
+	if (n < 0) {
+		print_error(h, "read");
+		return 1;
+	}
+	printf("read %lld bytes: %.*s\n", (long long)n, (int)n, buf);
+
+	if (exfat_extract(h, argv[2], argv[4]) != EXFAT_OK) {
+		print_error(h, "extract");
+		return 1;
+	}
+	if (exfat_read(h, "/no/such/file", 0, sizeof(buf), buf) != EXFAT_ERR_NOT_FOUND) {
+		print_error(h, "missing file");
+	}
+	return exfat_close(h) == EXFAT_OK ? 0 : 1;
+}
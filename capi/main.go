@@ -0,0 +1,253 @@
+// Package main 以 C ABI 导出读取 exFAT 映像的最小接口，供 C/C++ 等非 Go 程序使用
+//
+// 构建为共享库（同时生成头文件 libexfat.h）：
+//
+//	go build -buildmode=c-shared -o libexfat.so ./capi
+//
+// 映像通过句柄访问：exfat_open 返回正数句柄，exfat_close 释放。所有字符串都是 UTF-8；
+// 返回字符串的函数（exfat_list、exfat_last_error）分配的内存由调用者用 exfat_free 释放。
+// 失败的调用返回 EXFAT_ERR_* 错误码（exfat_list 返回 NULL），详细信息用 exfat_last_error 取得。
+//
+// 同一个句柄可以被多个线程同时使用：列目录、读取和提取都只读映像。
+// exfat_close 可以与其他调用并发：它等待进行中的调用结束后才关闭映像，之后的调用返回 EXFAT_ERR_HANDLE。
+// 句柄的最后错误在线程之间共享，并发调用时应在各自的调用失败后立即读取。
+// capi/example 中的 C 程序演示了完整的调用流程，testdata 中的 C 程序由测试编译运行。
+package main
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+#define EXFAT_OK             0
+#define EXFAT_ERR_HANDLE    -1
+#define EXFAT_ERR_NOT_FOUND -2
+#define EXFAT_ERR_ARGUMENT  -3
+#define EXFAT_ERR_IO        -4
+#define EXFAT_ERR_INCOMPLETE -5
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	iofs "io/fs"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// handle 一个打开的映像
+type handle struct {
+	vhd *exfat.VHD
+
+	// use 由使用映像的调用读锁定，exfat_close 写锁定，保证关闭时没有进行中的调用
+	use    sync.RWMutex
+	closed bool
+
+	mu      sync.Mutex
+	lastErr string
+}
+
+var (
+	handlesMu  sync.Mutex
+	handles    = map[int64]*handle{}
+	nextHandle = int64(1)
+	openErr    string // exfat_open 失败时的错误（句柄 0 的最后错误）
+)
+
+// lookup 返回句柄对应的映像
+func lookup(h C.int64_t) *handle {
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+	return handles[int64(h)]
+}
+
+// acquire 返回句柄对应的映像并锁定，使它在调用期间不会被关闭；调用结束后必须调用 release
+// 句柄无效或已经关闭时返回 nil。
+func acquire(h C.int64_t) *handle {
+	hd := lookup(h)
+	if hd == nil {
+		return nil
+	}
+	hd.use.RLock()
+	if hd.closed {
+		// exfat_close 在 lookup 之后、锁定之前关闭了映像
+		hd.use.RUnlock()
+		return nil
+	}
+	return hd
+}
+
+// release 结束 acquire 开始的使用
+func (h *handle) release() {
+	h.use.RUnlock()
+}
+
+// fail 记录错误并返回对应的错误码
+func (h *handle) fail(err error) C.int64_t {
+	h.mu.Lock()
+	h.lastErr = err.Error()
+	h.mu.Unlock()
+	return errorCode(err)
+}
+
+// errorCode 把错误映射为 EXFAT_ERR_* 错误码
+func errorCode(err error) C.int64_t {
+	switch {
+	case errors.Is(err, iofs.ErrNotExist):
+		return C.EXFAT_ERR_NOT_FOUND
+	case errors.Is(err, exfat.ErrIncomplete):
+		return C.EXFAT_ERR_INCOMPLETE
+	default:
+		return C.EXFAT_ERR_IO
+	}
+}
+
+//export exfat_open
+func exfat_open(path *C.char) C.int64_t {
+	vhd, err := exfat.OpenVHD(C.GoString(path))
+
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+	if err != nil {
+		openErr = err.Error()
+		return 0
+	}
+	id := nextHandle
+	nextHandle++
+	handles[id] = &handle{vhd: vhd}
+	return C.int64_t(id)
+}
+
+//export exfat_close
+func exfat_close(h C.int64_t) C.int {
+	handlesMu.Lock()
+	hd := handles[int64(h)]
+	delete(handles, int64(h))
+	handlesMu.Unlock()
+
+	if hd == nil {
+		return C.EXFAT_ERR_HANDLE
+	}
+	// 等待进行中的调用结束
+	hd.use.Lock()
+	defer hd.use.Unlock()
+	hd.closed = true
+	if err := hd.vhd.Close(); err != nil {
+		return C.EXFAT_ERR_IO
+	}
+	return C.EXFAT_OK
+}
+
+// listEntry exfat_list 输出的 JSON 条目
+type listEntry struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	IsDir      bool      `json:"is_dir"`
+	ModTime    time.Time `json:"mtime"`
+	CreateTime time.Time `json:"crtime"`
+	AccessTime time.Time `json:"atime"`
+	Attributes string    `json:"attributes"`
+}
+
+//export exfat_list
+func exfat_list(h C.int64_t, path *C.char) *C.char {
+	hd := acquire(h)
+	if hd == nil {
+		return nil
+	}
+	defer hd.release()
+	entries, err := hd.vhd.ListDir(C.GoString(path))
+	if err != nil {
+		hd.fail(err)
+		return nil
+	}
+
+	list := make([]listEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, listEntry{
+			Name:       e.Name,
+			Size:       e.Size,
+			IsDir:      e.IsDir,
+			ModTime:    e.ModTime,
+			CreateTime: e.CreateTime,
+			AccessTime: e.AccessTime,
+			Attributes: e.AttributeString(),
+		})
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		hd.fail(err)
+		return nil
+	}
+	return C.CString(string(data))
+}
+
+//export exfat_read
+func exfat_read(h C.int64_t, path *C.char, offset C.int64_t, length C.int64_t, buf *C.char) C.int64_t {
+	hd := acquire(h)
+	if hd == nil {
+		return C.EXFAT_ERR_HANDLE
+	}
+	defer hd.release()
+	if offset < 0 || length < 0 || (buf == nil && length > 0) {
+		hd.fail(errors.New("invalid offset, length or buffer"))
+		return C.EXFAT_ERR_ARGUMENT
+	}
+
+	f, err := hd.vhd.Open(C.GoString(path))
+	if err != nil {
+		return hd.fail(err)
+	}
+	defer f.Close()
+
+	dst := unsafe.Slice((*byte)(unsafe.Pointer(buf)), int(length))
+	n, err := f.ReadAt(dst, int64(offset))
+	if err != nil && err != io.EOF {
+		return hd.fail(err)
+	}
+	return C.int64_t(n)
+}
+
+//export exfat_extract
+func exfat_extract(h C.int64_t, src, dst *C.char) C.int {
+	hd := acquire(h)
+	if hd == nil {
+		return C.EXFAT_ERR_HANDLE
+	}
+	defer hd.release()
+	if _, err := hd.vhd.Extract(C.GoString(src), C.GoString(dst)); err != nil {
+		return C.int(hd.fail(err))
+	}
+	return C.EXFAT_OK
+}
+
+//export exfat_last_error
+func exfat_last_error(h C.int64_t) *C.char {
+	var msg string
+	if h == 0 {
+		handlesMu.Lock()
+		msg = openErr
+		handlesMu.Unlock()
+	} else if hd := lookup(h); hd != nil {
+		hd.mu.Lock()
+		msg = hd.lastErr
+		hd.mu.Unlock()
+	} else {
+		msg = "invalid handle"
+	}
+	if msg == "" {
+		return nil
+	}
+	return C.CString(msg)
+}
+
+//export exfat_free
+func exfat_free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+func main() {}
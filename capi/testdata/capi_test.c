@@ -0,0 +1,112 @@
+/*
+ * 由 capi_test.go 编译运行：检查 C ABI 的返回值、错误信息，以及 exfat_close 与读取并发时的行为。
+ *
+ *   capi_test <image> <output dir>
+ *
+ * 映像的根目录中有 hello.txt（内容 "hello world"）和目录 Dir。全部检查通过时输出 "ok" 并返回 0。
+ */
+#include <pthread.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include "libexfat.h"
+
+static int failures;
+
+#define CHECK(cond, ...) do { \
+	if (!(cond)) { \
+		fprintf(stderr, "%s:%d: ", __FILE__, __LINE__); \
+		fprintf(stderr, __VA_ARGS__); \
+		fprintf(stderr, "\n"); \
+		failures++; \
+	} \
+} while (0)
+
+/* has_error 句柄是否有非空的最后错误 */
+static int has_error(int64_t h) {
+	char *msg = exfat_last_error(h);
+	int ok = msg != NULL && msg[0] != '\0';
+	exfat_free(msg);
+	return ok;
+}
+
+struct reader {
+	int64_t h;
+	int bad;   /* 既不是正确数据也不是 EXFAT_ERR_HANDLE 的结果数 */
+	int reads; /* 成功读取的次数 */
+};
+
+/* read_until_closed 反复读取，直到句柄被另一个线程关闭 */
+static void *read_until_closed(void *arg) {
+	struct reader *r = arg;
+	char buf[16];
+	for (;;) {
+		int64_t n = exfat_read(r->h, "/hello.txt", 0, 11, buf);
+		if (n == EXFAT_ERR_HANDLE) {
+			return NULL;
+		}
+		if (n != 11 || memcmp(buf, "hello world", 11) != 0) {
+			r->bad++;
+		}
+		r->reads++;
+	}
+}
+
+int main(int argc, char **argv) {
+	if (argc != 3) {
+		fprintf(stderr, "usage: %s <image> <output dir>\n", argv[0]);
+		return 2;
+	}
+
+	CHECK(exfat_open("/nonexistent/image.vhd") == 0, "opening a missing image succeeded");
+	CHECK(has_error(0), "no error after a failed open");
+
+	int64_t h = exfat_open(argv[1]);
+	if (h <= 0) {
+		fprintf(stderr, "open failed\n");
+		return 1;
+	}
+
+	char *list = exfat_list(h, "/");
+	CHECK(list != NULL && strstr(list, "\"name\":\"hello.txt\"") != NULL, "list: %s", list ? list : "NULL");
+	exfat_free(list);
+	CHECK(exfat_list(h, "/missing") == NULL, "listing a missing directory succeeded");
+	CHECK(has_error(h), "no error after a failed list");
+
+	char buf[64];
+	int64_t n = exfat_read(h, "/hello.txt", 6, 5, buf);
+	CHECK(n == 5 && memcmp(buf, "world", 5) == 0, "read at offset 6: %lld", (long long)n);
+	n = exfat_read(h, "/hello.txt", 0, sizeof(buf), buf);
+	CHECK(n == 11, "read past the end returned %lld", (long long)n);
+	CHECK(exfat_read(h, "/no/such/file", 0, sizeof(buf), buf) == EXFAT_ERR_NOT_FOUND, "missing file");
+	CHECK(exfat_read(h, "/hello.txt", -1, 1, buf) == EXFAT_ERR_ARGUMENT, "negative offset");
+	CHECK(exfat_read(h, "/hello.txt", 0, 1, NULL) == EXFAT_ERR_ARGUMENT, "NULL buffer");
+	CHECK(exfat_extract(h, "/Dir", argv[2]) == EXFAT_OK, "extract");
+
+	/* 关闭与进行中的读取并发：读取要么得到正确的数据，要么得到 EXFAT_ERR_HANDLE */
+	enum { THREADS = 8 };
+	pthread_t threads[THREADS];
+	struct reader readers[THREADS];
+	for (int i = 0; i < THREADS; i++) {
+		readers[i] = (struct reader){ .h = h };
+		pthread_create(&threads[i], NULL, read_until_closed, &readers[i]);
+	}
+	struct timespec pause = { 0, 50 * 1000 * 1000 };
+	nanosleep(&pause, NULL);
+	CHECK(exfat_close(h) == EXFAT_OK, "close");
+	for (int i = 0; i < THREADS; i++) {
+		pthread_join(threads[i], NULL);
+		CHECK(readers[i].bad == 0, "thread %d: %d bad reads of %d", i, readers[i].bad, readers[i].reads);
+	}
+
+	CHECK(exfat_close(h) == EXFAT_ERR_HANDLE, "closing twice");
+	CHECK(exfat_list(h, "/") == NULL, "list after close");
+	CHECK(exfat_read(h, "/hello.txt", 0, 1, buf) == EXFAT_ERR_HANDLE, "read after close");
+
+	if (failures > 0) {
+		return 1;
+	}
+	printf("ok\n");
+	return 0;
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// RunCheck 检查卷并把每个存在问题的条目写成一行（按路径排序），最后输出汇总
+// 输出与 opts.Workers 无关，同一映像多次运行得到相同的输出。有问题时返回包装了 ErrPartial 的错误。
+func RunCheck(ctx context.Context, w io.Writer, v *exfat.VHD, opts exfat.CheckOptions) error {
+	if opts.Workers < 0 {
+		return usagef("-workers must not be negative")
+	}
+	result, err := v.Check(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for _, f := range result.Findings {
+		path := f.Path
+		if f.IsDir && path != "/" {
+			path += "/"
+		}
+		var problems []string
+		for _, a := range f.Anomalies {
+			if a == exfat.AnomalyUsageDisagreement && result.Usage != nil {
+				problems = append(problems, fmt.Sprintf("%s (%d clusters, see stats)", a, result.Usage.Disagreement))
+				continue
+			}
+			problems = append(problems, string(a))
+		}
+		if f.Err != nil {
+			problems = append(problems, fmt.Sprintf("unreadable: %v", f.Err))
+		}
+		fmt.Fprintf(w, "%s: %s\n", path, strings.Join(problems, ", "))
+	}
+	fmt.Fprintf(w, "Checked %d entries, %d with problems\n", result.Checked, len(result.Findings))
+	if len(result.Findings) > 0 {
+		return partialf("%d entries with problems", len(result.Findings))
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+// Package cli 实现 exfat-tool 各命令的行为，供其他命令行程序嵌入
+//
+// 每个命令对应一个 Run 函数：参数是已经打开的映像、输出目标和选项结构，
+// 输出格式与 exfat-tool 相同。解析命令行、打开文件和决定退出码由调用者负责；
+// Run 函数只返回错误：选项无效时返回 *UsageError，部分条目失败（详细信息已经写入输出）时返回包装了 ErrPartial 的错误。
+package cli
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPartial 表示部分条目处理失败，每个失败已经写入输出
+var ErrPartial = errors.New("some items failed")
+
+// UsageError 表示选项的值或组合无效
+// exfat-tool 遇到这类错误时打印用法并以 2 退出。
+type UsageError struct {
+	Msg string
+}
+
+func (e *UsageError) Error() string {
+	return e.Msg
+}
+
+// usagef 返回格式化的 *UsageError
+func usagef(format string, args ...interface{}) error {
+	return &UsageError{Msg: fmt.Sprintf(format, args...)}
+}
+
+// partialf 返回包装了 ErrPartial 的错误
+func partialf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrPartial, fmt.Sprintf(format, args...))
+}
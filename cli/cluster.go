@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"io"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// ClusterOptions RunExtractCluster 的选项
+type ClusterOptions struct {
+	Start      uint32 // 数据的第一个簇
+	Length     uint64 // 要读取的字节数，0 表示读到簇链末尾（Contiguous 时读到第一个未分配的簇）
+	Contiguous bool   // 读取连续的簇而不是沿 FAT 读取
+}
+
+// RunExtractCluster 从 Start 簇开始读取数据写入 w，不需要目录条目，返回写入的字节数
+// Start 不是有效的簇号时返回 *UsageError。
+func RunExtractCluster(w io.Writer, v *exfat.VHD, opts ClusterOptions) (int64, error) {
+	if opts.Start == 0 {
+		return 0, usagef("a start cluster is required")
+	}
+	r, err := v.ReadChain(opts.Start, opts.Length, opts.Contiguous)
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(w, r)
+}
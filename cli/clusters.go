@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// ParseClusterRanges 解析以逗号分隔的簇号或簇区间，例如 "100-199,512"
+// 格式无效或区间颠倒时返回 *UsageError。
+func ParseClusterRanges(s string) ([]exfat.ClusterRange, error) {
+	var ranges []exfat.ClusterRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		if !isRange {
+			last = first
+		}
+		lo, err1 := strconv.ParseUint(strings.TrimSpace(first), 0, 32)
+		hi, err2 := strconv.ParseUint(strings.TrimSpace(last), 0, 32)
+		if err1 != nil || err2 != nil || lo > hi {
+			return nil, usagef("invalid cluster range %q", part)
+		}
+		ranges = append(ranges, exfat.ClusterRange{First: uint32(lo), Last: uint32(hi)})
+	}
+	return ranges, nil
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// ExportOptions RunExport 的选项
+type ExportOptions struct {
+	Root      string // 要导出的目录，为空时使用 /
+	Format    string // pax、tar.gz（gzip 压缩的 pax）或 cpio（newc，只保留修改时间和权限），为空时使用 pax
+	GzipLevel int    // Format 为 tar.gz 时的压缩级别，gzip.DefaultCompression 为默认级别
+}
+
+// Validate 检查选项，Format 未知时返回 *UsageError
+// 调用者可以在创建输出文件之前调用它。
+func (o ExportOptions) Validate() error {
+	switch o.Format {
+	case "", "pax", "tar.gz", "cpio":
+		return nil
+	}
+	return usagef("unknown archive format %q", o.Format)
+}
+
+// RunExport 把 Root 导出为归档写入 w
+// 选项无效时返回 *UsageError，不写入任何内容。
+func RunExport(w io.Writer, v *exfat.VHD, opts ExportOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	root := opts.Root
+	if root == "" {
+		root = "/"
+	}
+	var exportOpts exfat.ExportOptions
+	if opts.Format == "cpio" {
+		exportOpts.Format = exfat.FormatCpio
+	} else {
+		exportOpts.Format = exfat.FormatPax
+	}
+
+	bw := bufio.NewWriter(w)
+	var err error
+	if opts.Format == "tar.gz" {
+		err = v.WriteTarGz(root, bw, exfat.WithGzipLevel(opts.GzipLevel))
+	} else {
+		err = v.ExportPax(root, bw, exportOpts)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// ExportRawOptions RunExportRaw 的选项
+type ExportRawOptions struct {
+	Output         io.Writer // 导出的数据；是新建的 *os.File 时全零的范围写成稀疏文件
+	Partition      int       // 要导出的分区序号（见 partitions 命令），0 表示第一个探测到 exFAT 的分区
+	WholeDisk      bool      // 导出整个磁盘，忽略 Partition
+	BytesPerSecond int64     // 读取映像的速度上限，0 表示不限制
+
+	ProgressInterval time.Duration // 输出进度的间隔，0 为每秒，负数不输出
+}
+
+// RunExportRaw 把映像中的一个分区（或整个磁盘）经过 VHD 的块转换后原样写到 Output，状态信息写到 status
+// 映像没有分区表时导出整个磁盘（即卷本身）。ctx 取消时停止并返回 ctx.Err()。
+func RunExportRaw(ctx context.Context, status io.Writer, path string, opts ExportRawOptions, openOpts ...exfat.Option) error {
+	var start, length int64
+	what := "disk"
+	if !opts.WholeDisk {
+		parts, err := exfat.ListPartitions(path, openOpts...)
+		if err != nil {
+			return err
+		}
+		var found *exfat.Partition
+		for i, p := range parts {
+			if (opts.Partition == 0 && p.ExFAT()) || p.Index == opts.Partition {
+				found = &parts[i]
+				break
+			}
+		}
+		switch {
+		case found != nil:
+			start, length = found.Offset, found.Size
+			what = fmt.Sprintf("partition %d", found.Index)
+		case opts.Partition > 0:
+			return usagef("partition %d not found (the disk has %d partitions)", opts.Partition, len(parts))
+		case len(parts) > 0:
+			return fmt.Errorf("no partition contains exFAT; choose one with -partition or use -whole-disk")
+		}
+	}
+
+	disk, err := exfat.OpenVHDFile(path, openOpts...)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+	if what == "disk" {
+		length = disk.Size()
+	}
+	// 分区表声明的大小可能超出截断的映像
+	if start+length > disk.Size() {
+		fmt.Fprintf(status, "Warning: %s extends past the end of the disk, exporting %d of %d bytes\n", what, max(disk.Size()-start, 0), length)
+		length = max(disk.Size()-start, 0)
+	}
+
+	interval := opts.ProgressInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+	last := time.Now()
+	progress := func(done int64) {
+		if interval > 0 && time.Since(last) >= interval {
+			last = time.Now()
+			fmt.Fprintf(status, "Exported %s of %s\n", exfat.FormatFileSize(done), exfat.FormatFileSize(length))
+		}
+	}
+	err = disk.ExportRawContext(ctx, opts.Output, start, length, exfat.RawExportOptions{Progress: progress, BytesPerSecond: opts.BytesPerSecond})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(status, "Exported %s (offset %d, %s)\n", what, start, exfat.FormatFileSize(length))
+	return nil
+}
@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// ExtractOptions RunExtract 的选项
+type ExtractOptions struct {
+	Paths   []string // 要提取的文件或目录
+	Outputs []string // 目标目录：第一个是主目标，其余作为镜像同时写入；为空时使用 ./output
+
+	Manifest io.Writer // 每个提取成功的条目写一行以制表符分隔的清单（可以为 nil）
+	Report   io.Writer // 每个路径的提取报告写一行 NDJSON，模式为 exfat.SchemaReport（可以为 nil）
+
+	RepairPlans bool              // 簇链提前结束的文件按修复计划读取
+	Policy      exfat.CheckPolicy // 不为 nil 时在提取的同时检查，按策略处理异常
+	Sample      exfat.SampleLimits
+
+	SkipOSMetadata   bool // 跳过 macOS 和 Windows 的元数据，AppleDouble 文件在 macOS 上合并，其他系统上丢弃
+	SkipAndroidCache bool // 跳过 Android 的缓存、缩略图和 .nomedia 标记
+	CRLF             bool // 把文本文件的 LF 换行转换为 CRLF，二进制文件原样写出
+
+	Decompress         bool     // 解压 gzip、zlib 和单文件 zip 压缩的文件并去掉后缀，损坏的原样写出；与 CRLF 同时使用时这些文件不转换换行
+	DecompressDepth    int      // 嵌套压缩最多解压的层数，0 与 1 相同
+	DecompressSuffixes []string // 要解压的文件后缀，为空时使用 exfat.DefaultDecompressSuffixes
+	DecompressMaxSize  int64    // 每个文件解压结果的大小上限（字节），超过时原样写出；0 使用 exfat.DefaultDecompressMaxSize，负数表示不限制
+
+	Layout     exfat.Layout     // 目标布局，零值按目录结构写出
+	DateSource exfat.DateSource // 按日期分组时使用的时间
+
+	ProgressInterval time.Duration // 输出进度的间隔，0 为每秒，负数不输出
+}
+
+// ParseCheckPolicy 根据坏簇、短簇链、校验和不匹配和交叉链接四类异常的处理方式名称构建检查策略
+func ParseCheckPolicy(onBadCluster, onShortChain, onChecksum, onCrossLink string) (exfat.CheckPolicy, error) {
+	policy := exfat.CheckPolicy{}
+	for anomaly, name := range map[exfat.Anomaly]string{
+		exfat.AnomalyBadCluster:       onBadCluster,
+		exfat.AnomalyShortChain:       onShortChain,
+		exfat.AnomalyChecksumMismatch: onChecksum,
+		exfat.AnomalyCrossLinked:      onCrossLink,
+	} {
+		action, err := exfat.ParseCheckAction(name)
+		if err != nil {
+			return nil, &UsageError{Msg: err.Error()}
+		}
+		policy[anomaly] = action
+	}
+	return policy, nil
+}
+
+// RunExtract 提取 Paths 中的文件或目录，状态信息（警告、检查结果、进度和汇总）写入 status
+// 抽样上限对所有路径合计。有路径提取失败时，失败写入 status 并返回包装了 ErrPartial 的错误；
+// 无法创建目标目录时直接返回错误。
+func RunExtract(status io.Writer, v *exfat.VHD, opts ExtractOptions) error {
+	outputs := opts.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{"output"}
+	}
+	for _, dir := range outputs {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create output directory: %v", err)
+		}
+	}
+	outputDir := outputs[0]
+
+	var extractOpts []exfat.ExtractOption
+	if opts.Manifest != nil {
+		extractOpts = append(extractOpts, exfat.WithManifest(func(e exfat.ManifestEntry) {
+			writeManifestEntry(opts.Manifest, e)
+		}))
+	}
+	if opts.RepairPlans {
+		extractOpts = append(extractOpts, exfat.WithRepairPlans())
+	}
+	if len(outputs) > 1 {
+		extractOpts = append(extractOpts, exfat.WithMirrors(outputs[1:]...))
+	}
+	if opts.Layout != exfat.LayoutMirror {
+		extractOpts = append(extractOpts, exfat.WithLayout(opts.Layout), exfat.WithDateSource(opts.DateSource))
+	}
+	if opts.SkipOSMetadata {
+		extractOpts = append(extractOpts, exfat.WithPreset(exfat.PresetSkipOSMetadata), exfat.WithAppleDouble(exfat.AppleDoubleMerge))
+	}
+	if opts.SkipAndroidCache {
+		extractOpts = append(extractOpts, exfat.WithPreset(exfat.PresetSkipAndroidCache))
+	}
+	if opts.CRLF || opts.Decompress {
+		decompress := exfat.Decompress(exfat.DecompressOptions{Suffixes: opts.DecompressSuffixes, Depth: opts.DecompressDepth, MaxSize: opts.DecompressMaxSize})
+		extractOpts = append(extractOpts, exfat.WithTransform(func(p string, e exfat.FileEntry) exfat.TransformFunc {
+			// 变换按压缩数据的开头判断内容，解压出的文本无法再判断，因此要解压的文件不做 CRLF 转换
+			if opts.Decompress {
+				if tf := decompress(p, e); tf != nil {
+					return tf
+				}
+			}
+			if opts.CRLF {
+				return exfat.CRLF
+			}
+			return nil
+		}))
+	}
+	var reportOut *json.Encoder
+	if opts.Report != nil {
+		reportOut = json.NewEncoder(opts.Report)
+	}
+
+	// 进度由汇总器统一累计：计划值来自提取前的统计，提取较慢时按间隔输出进度
+	var paths []string
+	var planned int64
+	for _, p := range opts.Paths {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+			if _, bytes, err := v.TreeSize(p); err == nil {
+				planned += bytes
+			}
+		}
+	}
+	progress := exfat.NewProgressAggregator(planned)
+	extractOpts = append(extractOpts, exfat.WithAggregator(progress, 0))
+	stop := func() {}
+	if interval := opts.ProgressInterval; interval >= 0 {
+		if interval == 0 {
+			interval = time.Second
+		}
+		stop = progress.Tick(interval, func(p exfat.AggregateProgress) {
+			fmt.Fprintf(status, "Progress: %s of %s (%d files)\n", exfat.FormatFileSize(p.Bytes), exfat.FormatFileSize(p.PlannedBytes), p.Files)
+		})
+	}
+	if s := opts.Sample; s.MaxFiles > 0 || s.MaxBytes > 0 || s.MaxDuration > 0 || s.SampleEvery > 1 {
+		extractOpts = append(extractOpts, exfat.WithSampler(exfat.NewSampler(s)))
+	}
+
+	failed := 0
+	for _, p := range paths {
+		var report *exfat.ExtractReport
+		var err error
+		if opts.Policy != nil {
+			var check *exfat.CheckReport
+			check, report, err = v.ExtractWithCheck(p, outputDir, opts.Policy, extractOpts...)
+			for _, f := range check.Findings {
+				fmt.Fprintf(status, "Check: %s: %v: %s\n", f.Path, f.Anomalies, f.Action)
+			}
+		} else {
+			report, err = v.Extract(p, outputDir, extractOpts...)
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(status, "Failed to extract %s: %v\n", p, err)
+		}
+		if report == nil {
+			continue
+		}
+		if reportOut != nil {
+			reportOut.Encode(struct {
+				exfat.Envelope
+				exfat.SourceReport
+			}{exfat.NewEnvelope(exfat.SchemaReport), exfat.SourceReport{Source: p, Report: report}})
+		}
+		writeReportWarnings(status, report)
+		if report.Stopped != "" {
+			fmt.Fprintf(status, "Stopped sampling: reached %s\n", report.Stopped)
+			break
+		}
+	}
+	stop()
+
+	total := progress.Snapshot()
+	fmt.Fprintf(status, "Extracted %s to %s (%d files, %s)\n", strings.Join(opts.Paths, ","), strings.Join(outputs, ", "), total.Files, exfat.FormatFileSize(total.Bytes))
+	if failed > 0 {
+		return partialf("%d of %d paths failed", failed, len(paths))
+	}
+	return nil
+}
+
+// writeManifestEntry 把提取成功的条目写成一行清单：类型、大小、源路径、目标路径、条目标识、修改时间、
+// 写出时应用的变换、写出的字节数和变换的说明。修改时间使用带小数秒的 RFC 3339 格式，保留 exFAT 的 10 毫秒精度；
+// 没有修改时间时为 "-"。未经变换的文件变换为 "-"，写出的字节数与大小相同，
+// 经过变换的文件只能按写出的字节数核对，大小是映像中的原始大小。
+// 说明（如解压前后的大小和 CRC-32）没有时为 "-"。
+func writeManifestEntry(w io.Writer, e exfat.ManifestEntry) {
+	if e.Outcome != exfat.OutcomeOK {
+		return
+	}
+	mtime := "-"
+	if !e.ModTime.IsZero() {
+		mtime = e.ModTime.Format(time.RFC3339Nano)
+	}
+	transform := "-"
+	if e.Transform != "" {
+		transform = e.Transform
+	}
+	note := "-"
+	if e.Note != "" {
+		note = e.Note
+	}
+	if e.IsDir {
+		fmt.Fprintf(w, "D\t-\t%s\t%s\t%s\t%s\t-\t-\t-\n", e.Path, e.Dest, e.ID, mtime)
+	} else {
+		fmt.Fprintf(w, "F\t%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", e.Size, e.Path, e.Dest, e.ID, mtime, transform, e.Bytes, note)
+	}
+}
+
+// writeReportWarnings 输出报告中有问题的条目
+func writeReportWarnings(w io.Writer, report *exfat.ExtractReport) {
+	for _, f := range report.Files {
+		if f.Err != nil && f.Dest != "" {
+			fmt.Fprintf(w, "Warning: failed to extract %s to %s: %v\n", f.Path, f.Dest, f.Err)
+		} else if f.Err != nil {
+			fmt.Fprintf(w, "Warning: failed to extract %s: %v\n", f.Path, f.Err)
+		}
+		if len(f.Anomalies) > 0 {
+			fmt.Fprintf(w, "Warning: %s: %v\n", f.Path, f.Anomalies)
+		}
+		if f.Warning != "" {
+			fmt.Fprintf(w, "Warning: %s: %s\n", f.Path, f.Warning)
+		}
+		if f.Repair != nil {
+			fmt.Fprintf(w, "Repaired: %s using a repair plan (%.0f%% confidence)\n", f.Path, f.Repair.Confidence*100)
+		}
+		if f.FAT != 0 {
+			fmt.Fprintf(w, "Recovered: %s using FAT %d\n", f.Path, f.FAT)
+		}
+	}
+	if report.Omitted > 0 {
+		fmt.Fprintf(w, "Warning: ... and %d more entries with issues\n", report.Omitted)
+	}
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// RunIdentify 扫描与 patterns 匹配的映像，以 NDJSON 把每个卷的标识写入 w（每行一个映像，模式为 exfat.SchemaIdentity）
+// 不是 exFAT 映像的文件写入 errw 后继续扫描，最后返回包装了 ErrPartial 的错误；模式无效时返回 *UsageError。
+func RunIdentify(w, errw io.Writer, patterns []string, opts ...exfat.Option) error {
+	enc := json.NewEncoder(w)
+	envelope := exfat.NewEnvelope(exfat.SchemaIdentity)
+	failed, total := 0, 0
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return usagef("invalid pattern %q: %v", pattern, err)
+		}
+		for _, p := range matches {
+			total++
+			id, err := exfat.Identify(p, opts...)
+			if err != nil {
+				fmt.Fprintf(errw, "%s: %v\n", p, err)
+				failed++
+				continue
+			}
+			if err := enc.Encode(struct {
+				exfat.Envelope
+				exfat.VolumeIdentity
+			}{envelope, id}); err != nil {
+				return fmt.Errorf("failed to write index: %v", err)
+			}
+		}
+	}
+	if failed > 0 {
+		return partialf("%d of %d images failed", failed, total)
+	}
+	return nil
+}
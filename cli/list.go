@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// RunInfo 输出映像信息：差分链上的每个磁盘、打开时记录的诊断和偏离规范的写法
+func RunInfo(w io.Writer, v *exfat.VHD) error {
+	fmt.Fprintf(w, "%-13s %-13s %s\n", "Role", "Type", "Path")
+	for _, link := range v.ChainInfo() {
+		fmt.Fprintf(w, "%-13s %-13s %s\n", link.Role, link.DiskType, link.Path)
+	}
+	for _, d := range v.Diagnostics() {
+		fmt.Fprintf(w, "Note: %s\n", d)
+	}
+	for _, c := range v.Conformance() {
+		fmt.Fprintf(w, "Nonconforming: %s (%s, formatter %s)\n", c, c.Deviation, c.Formatter)
+	}
+	return nil
+}
+
+// ListOptions RunList 的选项
+type ListOptions struct {
+	Dir        string     // 要列出的目录
+	TimeFormat TimeFormat // 时间的格式，默认为本地时间并精确到分钟
+	AllTimes   bool       // 在修改时间之后再列出创建时间和最后访问时间
+}
+
+// RunList 列出目录内容：修改时间、属性、类型、大小和名称
+// AllTimes 时增加创建时间和最后访问时间两列，没有记录的时间（时间戳为 0）显示为 "-"。
+func RunList(w io.Writer, v *exfat.VHD, opts ListOptions) error {
+	entries, err := v.ListDir(opts.Dir)
+	if err != nil {
+		return err
+	}
+	columns := []timeColumn{{title: "Modify Time", time: func(e exfat.FileEntry) time.Time { return e.ModTime }}}
+	if opts.AllTimes {
+		columns = append(columns,
+			timeColumn{title: "Create Time", time: func(e exfat.FileEntry) time.Time { return e.CreateTime }, optional: true},
+			timeColumn{title: "Access Time", time: func(e exfat.FileEntry) time.Time { return e.AccessTime }, optional: true})
+	}
+	// 时间列按最长的时间对齐，并多留一个空格（本地时间精确到分钟时为原来固定的 17 列）
+	times := make([][]string, len(columns))
+	header := ""
+	for c, column := range columns {
+		times[c] = make([]string, len(entries))
+		width := len(column.title)
+		for i, entry := range entries {
+			times[c][i] = column.format(opts.TimeFormat, entry)
+			width = max(width, len(times[c][i]))
+		}
+		width++
+		for i := range times[c] {
+			times[c][i] = fmt.Sprintf("%-*s ", width, times[c][i])
+		}
+		header += fmt.Sprintf("%-*s ", width, column.title)
+	}
+	fmt.Fprintf(w, "%s%-5s %-5s %-10s %s\n", header, "Attr", "Type", "Size", "Name")
+	for i, entry := range entries {
+		entryTimes := ""
+		for c := range columns {
+			entryTimes += times[c][i]
+		}
+		entryType := "File"
+		if entry.IsDir {
+			entryType = "Dir"
+		}
+		entrySize := exfat.FormatFileSize(entry.Size)
+		if entry.IsDir {
+			entrySize = "-"
+		}
+		fmt.Fprintf(w, "%s%-5s %-5s %-10s %s\n", entryTimes, entry.AttributeString(), entryType, entrySize, entry.Name)
+	}
+	return nil
+}
+
+// timeColumn 列表中的一个时间列
+type timeColumn struct {
+	title    string
+	time     func(exfat.FileEntry) time.Time
+	optional bool // 时间戳可以为 0（没有记录），这时显示 "-"
+}
+
+// format 格式化条目在这一列的时间
+func (c timeColumn) format(f TimeFormat, e exfat.FileEntry) string {
+	t := c.time(e)
+	if c.optional && t.IsZero() {
+		return "-"
+	}
+	return f.Format(t)
+}
+
+// AnalyzeOptions RunAnalyze 的选项
+type AnalyzeOptions struct {
+	Dir string // 要递归分析的目录
+}
+
+// RunAnalyze 递归列出目录下的文件及其是否连续分配
+// 连续分配的文件不经过 FAT 即可读取，碎片化的文件需要沿 FAT 簇链读取。
+// 无法读取的子目录写入输出后跳过；Dir 本身无法读取时返回错误。
+func RunAnalyze(w io.Writer, v *exfat.VHD, opts AnalyzeOptions) error {
+	var files, contiguous int
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		entries, err := v.ListDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			p := path.Join(dir, entry.Name)
+			if entry.IsDir {
+				if err := walk(p); err != nil {
+					fmt.Fprintf(w, "Failed to list %s: %v\n", p, err)
+				}
+				continue
+			}
+			layout := "fat-chain"
+			if entry.Contiguous {
+				layout = "contiguous"
+				contiguous++
+			}
+			files++
+			fmt.Fprintf(w, "%-10s %-10s %s\n", layout, exfat.FormatFileSize(entry.Size), p)
+		}
+		return nil
+	}
+
+	fmt.Fprintf(w, "%-10s %-10s %s\n", "Layout", "Size", "Path")
+	if err := walk(opts.Dir); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%d of %d files are contiguous\n", contiguous, files)
+	return nil
+}
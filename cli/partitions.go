@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// RunPartitions 列出映像分区表中的分区：声明的类型和按引导扇区探测到的内容
+// 两者不一致时（如标为 NTFS 的 exFAT 分区）以探测到的内容为准，打开映像时自动选择第一个 exFAT 分区。
+func RunPartitions(w io.Writer, path string, opts ...exfat.Option) error {
+	parts, err := exfat.ListPartitions(path, opts...)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 0 {
+		fmt.Fprintln(w, "No partition table: the image starts with the volume")
+		return nil
+	}
+	// 声明类型列按最长的一项对齐（GPT 类型 GUID 较长）
+	declared := make([]string, len(parts))
+	width := len("Declared Type")
+	for i, p := range parts {
+		declared[i] = p.Type
+		if p.TypeName != "" {
+			declared[i] += " (" + p.TypeName + ")"
+		}
+		width = max(width, len(declared[i]))
+	}
+	fmt.Fprintf(w, "%-3s %-6s %-*s %-8s %-12s %-10s %s\n", "#", "Scheme", width, "Declared Type", "Content", "Offset", "Size", "Name")
+	for i, p := range parts {
+		content := p.Content
+		if content == "" {
+			content = "-"
+		}
+		fmt.Fprintf(w, "%-3d %-6s %-*s %-8s %-12d %-10s %s\n", p.Index, p.Scheme, width, declared[i], content, p.Offset, exfat.FormatFileSize(p.Size), p.Name)
+	}
+	return nil
+}
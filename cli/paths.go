@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// PathsOptions RunPaths 的选项
+type PathsOptions struct {
+	Root     string         // 要列出的目录，为空时使用 /
+	Dirs     bool           // 同时列出目录，目录路径以 "/" 结尾
+	NUL      bool           // 以 NUL 而不是换行分隔路径（供 xargs -0、fzf --read0 使用）
+	MaxDepth int            // 大于 0 时只列出 Root 下这么多层
+	Include  []string       // 非空时只列出匹配任一模式的条目，目录仍然会被遍历
+	Exclude  []string       // 排除匹配任一模式的条目，被排除的目录连同内容一起跳过
+	Presets  []exfat.Preset // 额外的排除规则预设
+}
+
+// RunPaths 把 Root 下的路径逐行写入 w，每找到一个条目就立即输出，适合管道给 fzf 等选择器
+// 路径的顺序与 VHD.WalkPaths 相同，同一映像多次运行得到相同的输出。模式的写法与 Preset 相同。
+// 换行分隔时，名称中的控制字符和反斜杠（合法的 exFAT 名称中都不会出现）按 Go 字符串的写法转义，
+// 每个被转义的路径在 warn 中警告一次；NUL 分隔时路径原样输出。
+func RunPaths(w, warn io.Writer, v *exfat.VHD, opts PathsOptions) error {
+	if opts.MaxDepth < 0 {
+		return usagef("-max-depth must not be negative")
+	}
+	root := opts.Root
+	if root == "" {
+		root = "/"
+	}
+	sep := byte('\n')
+	if opts.NUL {
+		sep = 0
+	}
+	exclude := exfat.Preset{Exclude: opts.Exclude}
+	// 用只有 Exclude 的预设复用模式的匹配规则
+	include := exfat.Preset{Exclude: opts.Include}
+
+	out := bufio.NewWriter(w)
+	err := v.WalkPaths(root, opts.MaxDepth, func(p string, e exfat.FileEntry) error {
+		if exclude.Excludes(p, e) {
+			return skip(e)
+		}
+		for _, preset := range opts.Presets {
+			if preset.Excludes(p, e) {
+				return skip(e)
+			}
+		}
+		if (e.IsDir && !opts.Dirs) || (len(opts.Include) > 0 && !include.Excludes(p, e)) {
+			return nil
+		}
+
+		if !opts.NUL {
+			if escaped, ok := escapePath(p); ok {
+				fmt.Fprintf(warn, "warning: %q contains control characters; printed escaped (use -0 for raw names)\n", p)
+				p = escaped
+			}
+		}
+		out.WriteString(p)
+		if e.IsDir {
+			out.WriteByte('/')
+		}
+		return out.WriteByte(sep)
+	})
+	if ferr := out.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// skip 返回排除条目时遍历回调的返回值：目录连同内容一起跳过
+func skip(e exfat.FileEntry) error {
+	if e.IsDir {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// escapePath 转义路径中的控制字符和反斜杠，返回转义后的路径和是否有字符被转义
+func escapePath(p string) (string, bool) {
+	if !strings.ContainsFunc(p, needsEscape) {
+		return p, false
+	}
+	var b strings.Builder
+	for _, r := range p {
+		if !needsEscape(r) {
+			b.WriteRune(r)
+			continue
+		}
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		}
+	}
+	return b.String(), true
+}
+
+// needsEscape 判断字符在换行分隔的输出中是否需要转义
+func needsEscape(r rune) bool {
+	return r < 0x20 || r == 0x7f || r == '\\'
+}
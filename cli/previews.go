@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// PreviewOptions RunPreviews 的选项
+type PreviewOptions struct {
+	Dir      string // 要查找的目录，为空时使用 /
+	Output   string // 写入预览图的本地目录
+	MaxBytes int    // 每个文件最多读取的字节数，0 表示 exfat.DefaultPreviewBytes
+}
+
+// previewSuffixes 查找预览图的文件后缀
+var previewSuffixes = map[string]bool{
+	".jpg": true, ".jpeg": true, ".heic": true, ".heif": true,
+	".mp4": true, ".m4v": true, ".mov": true,
+}
+
+// previewExt 预览图的 MIME 类型对应的文件后缀
+var previewExt = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/heic": ".heic",
+}
+
+// RunPreviews 把 Dir 下图片和视频中嵌入的预览图写到 Output，每个源文件一个，保留目录结构
+// 预览图的文件名是源文件名加上预览图格式的后缀（如 IMG_0001.HEIC.jpg），同名的照片和视频（实况照片）不会互相覆盖。
+// 没有预览图的文件只计数；打开失败的文件写入 w，并返回包装了 ErrPartial 的错误。
+func RunPreviews(w io.Writer, v *exfat.VHD, opts PreviewOptions) error {
+	if opts.Output == "" {
+		return usagef("an output directory is required")
+	}
+	if opts.MaxBytes < 0 {
+		return usagef("-max-bytes must not be negative")
+	}
+	root := opts.Dir
+	if root == "" {
+		root = "/"
+	}
+
+	var written, missing, failed int
+	err := v.WalkPaths(root, 0, func(p string, e exfat.FileEntry) error {
+		if e.IsDir || !previewSuffixes[strings.ToLower(path.Ext(p))] {
+			return nil
+		}
+		data, mime, err := v.ExtractPreview(p, opts.MaxBytes)
+		switch {
+		case errors.Is(err, exfat.ErrNoPreview):
+			missing++
+			return nil
+		case err != nil:
+			fmt.Fprintf(w, "Failed: %s: %v\n", p, err)
+			failed++
+			return nil
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, strings.TrimSuffix(root, "/")), "/")
+		dest := filepath.Join(opts.Output, filepath.FromSlash(rel)+previewExt[mime])
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s -> %s (%s, %s)\n", p, dest, mime, exfat.FormatFileSize(int64(len(data))))
+		written++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Wrote %d previews to %s; %d files had no preview\n", written, opts.Output, missing)
+	if failed > 0 {
+		return partialf("%d files could not be read", failed)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// RunRepairPlan 为 paths 中簇链断裂的文件制定并输出修复计划（只读，不修改映像）
+// 无法制定计划的文件写入输出后继续，最后返回包装了 ErrPartial 的错误。
+func RunRepairPlan(w io.Writer, v *exfat.VHD, paths []string) error {
+	failed := 0
+	for _, p := range paths {
+		plan, err := v.FileSystem().PlanChainRepair(p)
+		if err != nil {
+			fmt.Fprintf(w, "%s: %v\n", p, err)
+			failed++
+			continue
+		}
+		writeRepairPlan(w, plan)
+	}
+	if failed > 0 {
+		return partialf("%d of %d files failed", failed, len(paths))
+	}
+	return nil
+}
+
+// writeRepairPlan 输出修复计划
+func writeRepairPlan(w io.Writer, plan exfat.RepairPlan) {
+	if !plan.Broken() {
+		fmt.Fprintf(w, "%s: chain intact (%d clusters)\n", plan.Path, len(plan.Clusters))
+		return
+	}
+	fmt.Fprintf(w, "%s: chain ends after %d of %d clusters\n", plan.Path, plan.ChainLength, len(plan.Clusters))
+	fmt.Fprintf(w, "  Proposed clusters: %s\n", clusterRuns(plan.Clusters))
+	fmt.Fprintf(w, "  Confidence:        %.0f%%\n", plan.Confidence*100)
+	for _, reason := range plan.Reasons {
+		fmt.Fprintf(w, "  - %s\n", reason)
+	}
+}
+
+// clusterRuns 把簇序列压缩为连续区间的列表，例如 "5-9, 20-31"
+func clusterRuns(clusters []uint32) string {
+	var runs []string
+	for i := 0; i < len(clusters); {
+		j := i
+		for j+1 < len(clusters) && clusters[j+1] == clusters[j]+1 {
+			j++
+		}
+		if i == j {
+			runs = append(runs, fmt.Sprint(clusters[i]))
+		} else {
+			runs = append(runs, fmt.Sprintf("%d-%d", clusters[i], clusters[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(runs, ", ")
+}
@@ -0,0 +1,479 @@
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// memDisk 内存中的映像，实现 container.Backend
+type memDisk struct {
+	*testimage.Disk
+}
+
+func (memDisk) Close() error { return nil }
+
+// fixture 返回 Run 函数测试使用的映像：照片目录、碎片化的大文件、嵌套目录和带控制字符的名称
+func fixture() *testimage.Image {
+	big := testimage.File("big.bin", bytes.Repeat([]byte("0123456789abcdef"), 200))
+	big.Fragmented = true
+	return testimage.Build(testimage.Options{},
+		testimage.Dir("DCIM", testimage.File("a.jpg", []byte("photo a")), testimage.File("b.jpg", []byte("photo b"))),
+		big,
+		testimage.File("notes.txt", []byte("notes")),
+		testimage.Dir("Sub", testimage.Dir("Deep", testimage.File("x.txt", []byte("x")))),
+		testimage.File("tab\there", []byte("t")))
+}
+
+// openVHD 打开内存中的映像
+func openVHD(t *testing.T, img *testimage.Image, opts ...exfat.Option) *exfat.VHD {
+	t.Helper()
+	v, err := exfat.NewVHD(memDisk{img.Disk()}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { v.Close() })
+	return v
+}
+
+// lines 把输出按行拆开，去掉最后的空行
+func lines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func TestRunInfoAndList(t *testing.T) {
+	v := openVHD(t, fixture())
+	var buf bytes.Buffer
+	if err := RunInfo(&buf, v); err != nil || !strings.HasPrefix(buf.String(), "Role") {
+		t.Errorf("RunInfo: %q, %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	err := RunList(&buf, v, ListOptions{Dir: "/", TimeFormat: TimeFormat{Style: TimeISO, UTC: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Modify Time        Attr  Type  Size       Name\n" +
+		"2023-05-06T07:08Z  ----D Dir   -          DCIM\n" +
+		"2023-05-06T07:08Z  ---A- File  3.12 KB    big.bin\n" +
+		"2023-05-06T07:08Z  ---A- File  5 B        notes.txt\n" +
+		"2023-05-06T07:08Z  ----D Dir   -          Sub\n" +
+		"2023-05-06T07:08Z  ---A- File  1 B        tab\there\n"
+	if buf.String() != want {
+		t.Errorf("RunList:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := RunList(&buf, v, ListOptions{Dir: "/DCIM", AllTimes: true}); err != nil {
+		t.Fatal(err)
+	}
+	if header := lines(buf.String())[0]; !strings.Contains(header, "Create Time") || !strings.Contains(header, "Access Time") {
+		t.Errorf("AllTimes header %q", header)
+	}
+	if err := RunList(io.Discard, v, ListOptions{Dir: "/missing"}); !errors.Is(err, exfat.ErrNotExist) {
+		t.Errorf("missing directory: %v", err)
+	}
+}
+
+func TestRunAnalyze(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RunAnalyze(&buf, openVHD(t, fixture()), AnalyzeOptions{Dir: "/"}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"fat-chain  3.12 KB    /big.bin\n", "contiguous 7 B        /DCIM/a.jpg\n", "5 of 6 files are contiguous\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunExtract(t *testing.T) {
+	dest := t.TempDir()
+	var status, manifest, report bytes.Buffer
+	err := RunExtract(&status, openVHD(t, fixture()), ExtractOptions{
+		Paths:            []string{"/DCIM", " /notes.txt ", ""},
+		Outputs:          []string{dest},
+		Manifest:         &manifest,
+		Report:           &report,
+		ProgressInterval: -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"a.jpg": "photo a", "b.jpg": "photo b", "notes.txt": "notes"} {
+		if got, err := os.ReadFile(filepath.Join(dest, name)); err != nil || string(got) != want {
+			t.Errorf("%s: %q, %v", name, got, err)
+		}
+	}
+	if want := "Extracted /DCIM, /notes.txt , to " + dest + " (3 files, 19 B)\n"; status.String() != want {
+		t.Errorf("status %q, want %q", status.String(), want)
+	}
+	manifestLines := lines(manifest.String())
+	if len(manifestLines) != 3 {
+		t.Fatalf("manifest %q", manifest.String())
+	}
+	for _, line := range manifestLines {
+		if fields := strings.Split(line, "\t"); len(fields) != 9 || fields[0] != "F" || fields[6] != "-" || fields[1] != fields[7] {
+			t.Errorf("manifest line %q", line)
+		}
+	}
+	// 每个路径一行报告
+	dec := json.NewDecoder(&report)
+	for _, source := range []string{"/DCIM", "/notes.txt"} {
+		var doc struct {
+			Schema string
+			Source string
+		}
+		if err := dec.Decode(&doc); err != nil || doc.Source != source || doc.Schema != exfat.SchemaReport {
+			t.Errorf("report for %s: %+v, %v", source, doc, err)
+		}
+	}
+}
+
+func TestRunExtractBurstOrder(t *testing.T) {
+	// 同一秒内连拍的两张照片只有 10 毫秒增量不同；目录中较晚的一张在前
+	second := time.Date(2024, 6, 13, 12, 15, 16, 0, time.UTC)
+	first, next := second.Add(10*time.Millisecond), second.Add(20*time.Millisecond)
+	late := testimage.File("IMG_0002.jpg", []byte("second shot"))
+	late.Modified = next
+	early := testimage.File("IMG_0001.jpg", []byte("first shot"))
+	early.Modified = first
+	v := openVHD(t, testimage.Build(testimage.Options{}, testimage.Dir("Burst", late, early)))
+
+	// 清单中的修改时间保留 10 毫秒精度，按时间排序得到拍摄顺序
+	dest := t.TempDir()
+	var manifest bytes.Buffer
+	err := RunExtract(io.Discard, v, ExtractOptions{Paths: []string{"/Burst"}, Outputs: []string{dest}, Manifest: &manifest, ProgressInterval: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type shot struct {
+		path string
+		mod  time.Time
+	}
+	var shots []shot
+	for _, line := range lines(manifest.String()) {
+		fields := strings.Split(line, "\t")
+		mod, err := time.Parse(time.RFC3339Nano, fields[5])
+		if err != nil {
+			t.Fatalf("manifest line %q: %v", line, err)
+		}
+		shots = append(shots, shot{fields[2], mod})
+	}
+	sort.SliceStable(shots, func(i, j int) bool { return shots[i].mod.Before(shots[j].mod) })
+	want := []shot{{"/Burst/IMG_0001.jpg", first}, {"/Burst/IMG_0002.jpg", next}}
+	if len(shots) != len(want) {
+		t.Fatalf("manifest %q", manifest.String())
+	}
+	for i := range want {
+		if shots[i].path != want[i].path || !shots[i].mod.Equal(want[i].mod) {
+			t.Errorf("manifest order %d: %s at %s, want %s at %s", i, shots[i].path, shots[i].mod, want[i].path, want[i].mod)
+		}
+	}
+
+	// 写出的文件带有同样精度的修改时间
+	for _, w := range want {
+		info, err := os.Stat(filepath.Join(dest, path.Base(w.path)))
+		if err != nil || !info.ModTime().Equal(w.mod) {
+			t.Errorf("%s: modified %v, want %v (%v)", w.path, info.ModTime(), w.mod, err)
+		}
+	}
+
+	// 列表在毫秒精度下分出先后
+	var list bytes.Buffer
+	if err := RunList(&list, v, ListOptions{Dir: "/Burst", TimeFormat: TimeFormat{Precision: TimeMillis, UTC: true}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"2024-06-13 12:15:16.020  ---A- File  11 B       IMG_0002.jpg", "2024-06-13 12:15:16.010  ---A- File  10 B       IMG_0001.jpg"} {
+		if !strings.Contains(list.String(), want) {
+			t.Errorf("listing lacks %q:\n%s", want, list.String())
+		}
+	}
+}
+
+func TestRunExtractPartial(t *testing.T) {
+	var status bytes.Buffer
+	err := RunExtract(&status, openVHD(t, fixture()), ExtractOptions{
+		Paths:            []string{"/missing", "/notes.txt"},
+		Outputs:          []string{t.TempDir()},
+		ProgressInterval: -1,
+	})
+	if !errors.Is(err, ErrPartial) {
+		t.Fatalf("got %v, want ErrPartial", err)
+	}
+	if out := status.String(); !strings.HasPrefix(out, "Failed to extract /missing: ") || !strings.Contains(out, "(1 files, 5 B)") {
+		t.Errorf("status %q", out)
+	}
+	if _, err := ParseCheckPolicy("extract", "zap", "extract", "extract"); !isUsage(err) {
+		t.Errorf("invalid action: got %v, want a usage error", err)
+	}
+}
+
+func TestRunExtractMirrorsAndLayout(t *testing.T) {
+	primary, mirror := t.TempDir(), t.TempDir()
+	err := RunExtract(io.Discard, openVHD(t, fixture()), ExtractOptions{
+		Paths:            []string{"/"},
+		Outputs:          []string{primary, mirror},
+		Layout:           exfat.LayoutFlat,
+		ProgressInterval: -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{primary, mirror} {
+		if got, err := os.ReadFile(filepath.Join(dir, "x.txt")); err != nil || string(got) != "x" {
+			t.Errorf("%s/x.txt: %q, %v", dir, got, err)
+		}
+	}
+}
+
+// brokenChain 返回 big.bin 的簇链在第二个簇之后提前结束的映像
+func brokenChain() *testimage.Image {
+	img := fixture()
+	img.SetFAT(img.Entry("/big.bin").Clusters[1], 0xFFFFFFFF)
+	return img
+}
+
+func TestRunCheck(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RunCheck(context.Background(), &buf, openVHD(t, fixture()), exfat.CheckOptions{Workers: 4}); err != nil {
+		t.Fatalf("clean volume: %v\n%s", err, buf.String())
+	}
+	if lines := lines(buf.String()); len(lines) != 1 || !strings.HasSuffix(lines[0], ", 0 with problems") {
+		t.Errorf("clean volume: %q", buf.String())
+	}
+
+	buf.Reset()
+	err := RunCheck(context.Background(), &buf, openVHD(t, brokenChain()), exfat.CheckOptions{})
+	if !errors.Is(err, ErrPartial) {
+		t.Fatalf("broken chain: got %v, want ErrPartial", err)
+	}
+	if got := lines(buf.String()); len(got) != 2 || !strings.HasPrefix(got[0], "/big.bin: ") || !strings.HasSuffix(got[1], ", 1 with problems") {
+		t.Errorf("broken chain: %q", buf.String())
+	}
+
+	// 位图中标记了没有条目引用的簇：已用空间的统计不一致，报告在根目录上
+	leaked := fixture()
+	c := uint32(len(leaked.Bytes)-int(leaked.HeapOffset))/uint32(leaked.BytesPerCluster) + 1
+	leaked.Bytes[leaked.ClusterOffset(leaked.BitmapCluster)+int64(c-2)/8] |= 1 << ((c - 2) % 8)
+	buf.Reset()
+	if err := RunCheck(context.Background(), &buf, openVHD(t, leaked), exfat.CheckOptions{}); !errors.Is(err, ErrPartial) {
+		t.Fatalf("leaked cluster: got %v, want ErrPartial", err)
+	}
+	if got := lines(buf.String()); len(got) != 2 || got[0] != "/: usage disagreement (1 clusters, see stats)" {
+		t.Errorf("leaked cluster: %q", buf.String())
+	}
+	if err := RunCheck(context.Background(), io.Discard, openVHD(t, fixture()), exfat.CheckOptions{Workers: -1}); !isUsage(err) {
+		t.Errorf("negative workers: got %v, want a usage error", err)
+	}
+}
+
+func TestRunRepairPlan(t *testing.T) {
+	var buf bytes.Buffer
+	err := RunRepairPlan(&buf, openVHD(t, brokenChain()), []string{"/big.bin", "/notes.txt", "/missing"})
+	if !errors.Is(err, ErrPartial) {
+		t.Fatalf("got %v, want ErrPartial", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"/big.bin: chain ends after 2 of 7 clusters\n", "  Proposed clusters: ", "/notes.txt: chain intact (1 clusters)\n", "/missing: "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if got := clusterRuns([]uint32{5, 6, 7, 9, 20, 21}); got != "5-7, 9, 20-21" {
+		t.Errorf("clusterRuns = %q", got)
+	}
+}
+
+func TestRunExtractCluster(t *testing.T) {
+	img := fixture()
+	v := openVHD(t, img)
+	var buf bytes.Buffer
+	n, err := RunExtractCluster(&buf, v, ClusterOptions{Start: img.Entry("/big.bin").Clusters[0], Length: 3200})
+	if err != nil || n != 3200 || !bytes.Equal(buf.Bytes(), bytes.Repeat([]byte("0123456789abcdef"), 200)) {
+		t.Errorf("RunExtractCluster: %d bytes, %v", n, err)
+	}
+	if _, err := RunExtractCluster(io.Discard, v, ClusterOptions{}); !isUsage(err) {
+		t.Errorf("no start cluster: got %v, want a usage error", err)
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	v := openVHD(t, fixture())
+	for _, format := range []string{"", "pax", "tar.gz"} {
+		var buf bytes.Buffer
+		if err := RunExport(&buf, v, ExportOptions{Root: "/DCIM", Format: format, GzipLevel: gzip.DefaultCompression}); err != nil {
+			t.Fatalf("%q: %v", format, err)
+		}
+		var r io.Reader = &buf
+		if format == "tar.gz" {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r = gz
+		}
+		tr := tar.NewReader(r)
+		var names []string
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%q: %v", format, err)
+			}
+			names = append(names, hdr.Name)
+		}
+		if strings.Join(names, " ") != "a.jpg b.jpg" {
+			t.Errorf("%q: archive holds %q", format, names)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := RunExport(&buf, v, ExportOptions{Root: "/notes.txt", Format: "cpio"}); err != nil || !bytes.HasPrefix(buf.Bytes(), []byte("070701")) {
+		t.Errorf("cpio: %q, %v", buf.Bytes(), err)
+	}
+	buf.Reset()
+	if err := RunExport(&buf, v, ExportOptions{Format: "zip"}); !isUsage(err) || buf.Len() != 0 {
+		t.Errorf("unknown format: %v, wrote %d bytes", err, buf.Len())
+	}
+}
+
+func TestRunPaths(t *testing.T) {
+	v := openVHD(t, fixture())
+	tests := []struct {
+		opts PathsOptions
+		want string
+	}{
+		{PathsOptions{}, "/DCIM/a.jpg\n/DCIM/b.jpg\n/big.bin\n/notes.txt\n/Sub/Deep/x.txt\n/tab\\there\n"},
+		{PathsOptions{Root: "/Sub", Dirs: true}, "/Sub/Deep/\n/Sub/Deep/x.txt\n"},
+		{PathsOptions{MaxDepth: 1, Dirs: true}, "/DCIM/\n/big.bin\n/notes.txt\n/Sub/\n/tab\\there\n"},
+		{PathsOptions{Include: []string{"*.jpg"}}, "/DCIM/a.jpg\n/DCIM/b.jpg\n"},
+		{PathsOptions{Exclude: []string{"DCIM", "*.txt"}}, "/big.bin\n/tab\\there\n"},
+		{PathsOptions{Root: "/DCIM", NUL: true}, "/DCIM/a.jpg\x00/DCIM/b.jpg\x00"},
+		{PathsOptions{Presets: []exfat.Preset{{Exclude: []string{"Sub"}}}, Include: []string{"tab*"}, NUL: true}, "/tab\there\x00"},
+	}
+	for _, tt := range tests {
+		var out, warn bytes.Buffer
+		if err := RunPaths(&out, &warn, v, tt.opts); err != nil || out.String() != tt.want {
+			t.Errorf("%+v: %q, %v; want %q", tt.opts, out.String(), err, tt.want)
+		}
+		// 只有换行分隔且输出了带控制字符的路径时才警告
+		if escaped := strings.Contains(tt.want, `\t`); escaped != (warn.Len() > 0) {
+			t.Errorf("%+v: warnings %q", tt.opts, warn.String())
+		}
+	}
+	if err := RunPaths(io.Discard, io.Discard, v, PathsOptions{MaxDepth: -1}); !isUsage(err) {
+		t.Errorf("negative depth: got %v, want a usage error", err)
+	}
+}
+
+func TestRunStatsAndTop(t *testing.T) {
+	v := openVHD(t, fixture())
+	var buf bytes.Buffer
+	if err := RunStats(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "Method   Used clusters Free clusters  Used size\n") || !strings.Contains(out, "\nUsed: ") || strings.Contains(out, "Disagreement") {
+		t.Errorf("RunStats:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := RunTop(&buf, v, TopOptions{N: 2}); err != nil {
+		t.Fatal(err)
+	}
+	// 最大的 N 个文件在前，之后是最大的 N 个目录
+	want := "Type  Size         Used% Path\n" +
+		"File  3.12 KB     24.04% /big.bin\n" +
+		"File  7 B          0.05% /DCIM/a.jpg\n" +
+		"Dir   3.15 KB     24.20% /\n" +
+		"Dir   14 B         0.11% /DCIM\n"
+	if buf.String() != want {
+		t.Errorf("RunTop:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestRunTraceCheck(t *testing.T) {
+	var trace bytes.Buffer
+	v := openVHD(t, fixture(), exfat.WithReadTracing(&trace))
+	if _, err := v.ReadFile("/big.bin"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := RunTraceCheck(&buf, v, &trace); err != nil || buf.String() != "No suspicious reads\n" {
+		t.Errorf("clean trace: %q, %v", buf.String(), err)
+	}
+	if err := RunTraceCheck(io.Discard, v, strings.NewReader("not json\n")); !errors.Is(err, ErrPartial) {
+		t.Errorf("malformed trace: got %v, want ErrPartial", err)
+	}
+}
+
+func TestRunIdentifyAndPartitions(t *testing.T) {
+	dir := t.TempDir()
+	img := fixture()
+	if err := img.Save(filepath.Join(dir, "a.img")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.img"), []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errw bytes.Buffer
+	err := RunIdentify(&out, &errw, []string{filepath.Join(dir, "*.img")})
+	if !errors.Is(err, ErrPartial) {
+		t.Fatalf("got %v, want ErrPartial", err)
+	}
+	var id struct {
+		Schema string
+		Serial string
+	}
+	if err := json.Unmarshal(out.Bytes(), &id); err != nil || id.Schema != exfat.SchemaIdentity {
+		t.Errorf("identity %q: %v", out.String(), err)
+	}
+	if got := lines(errw.String()); len(got) != 1 || !strings.HasPrefix(got[0], filepath.Join(dir, "b.img")+": ") {
+		t.Errorf("errors %q", errw.String())
+	}
+	if err := RunIdentify(io.Discard, io.Discard, []string{"["}); !isUsage(err) {
+		t.Errorf("invalid pattern: got %v, want a usage error", err)
+	}
+
+	out.Reset()
+	if err := RunPartitions(&out, filepath.Join(dir, "a.img")); err != nil || out.String() != "No partition table: the image starts with the volume\n" {
+		t.Errorf("RunPartitions: %q, %v", out.String(), err)
+	}
+}
+
+func TestRunPreviews(t *testing.T) {
+	v := openVHD(t, fixture())
+	var buf bytes.Buffer
+	dest := t.TempDir()
+	// 测试映像中的 JPEG 只是文本，没有嵌入的预览图
+	if err := RunPreviews(&buf, v, PreviewOptions{Output: dest}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Wrote 0 previews to " + dest + "; 2 files had no preview\n"; buf.String() != want {
+		t.Errorf("RunPreviews: %q, want %q", buf.String(), want)
+	}
+	for _, opts := range []PreviewOptions{{}, {Output: dest, MaxBytes: -1}} {
+		if err := RunPreviews(io.Discard, v, opts); !isUsage(err) {
+			t.Errorf("%+v: got %v, want a usage error", opts, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// RunStats 输出卷的已用和空闲空间，以及位图、FAT 和目录树三种统计方法的结果和差异
+// 方法之间的差异（损坏的信号）在最后一行给出。
+func RunStats(w io.Writer, v *exfat.VHD) error {
+	stats, err := v.VolumeStats()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%-8s %13s %13s %10s\n", "Method", "Used clusters", "Free clusters", "Used size")
+	for _, e := range stats.Estimates {
+		if e.Err != nil {
+			fmt.Fprintf(w, "%-8s unavailable: %v\n", e.Method, e.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%-8s %13d %13d %10s\n", e.Method, e.UsedClusters, e.FreeClusters(stats.TotalClusters),
+			exfat.FormatFileSize(int64(e.UsedClusters)*int64(stats.BytesPerCluster)))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Used: %s of %s (%d%%, from %s)\n", exfat.FormatFileSize(int64(stats.UsedBytes())),
+		exfat.FormatFileSize(int64(stats.TotalClusters)*int64(stats.BytesPerCluster)), stats.PercentInUse, stats.Method)
+	fmt.Fprintf(w, "Free: %s (%d clusters)\n", exfat.FormatFileSize(int64(stats.FreeBytes())), stats.FreeClusters)
+	if stats.BootPercentInUse == 0xFF {
+		fmt.Fprintln(w, "Boot sector PercentInUse: not recorded")
+	} else {
+		fmt.Fprintf(w, "Boot sector PercentInUse: %d%%\n", stats.BootPercentInUse)
+	}
+	if stats.Disagreement > 0 {
+		fmt.Fprintf(w, "Disagreement: %d clusters (possible corruption)\n", stats.Disagreement)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimePrecision 列表中时间的显示精度
+// exFAT 的修改时间和创建时间精确到 10 毫秒（2 秒精度的时间加 10 毫秒增量），
+// 同一秒内连拍的照片只有在 TimeMillis 或 TimeFull 下才能分出先后。
+type TimePrecision int
+
+const (
+	TimeMinutes TimePrecision = iota // 精确到分钟，exfat-tool 的默认格式
+	TimeSeconds                      // 精确到秒
+	TimeMillis                       // 精确到毫秒，显示 10 毫秒增量
+	TimeFull                         // 完整精度，RFC 3339 格式并带 UTC 偏移
+)
+
+var timePrecisionNames = map[string]TimePrecision{
+	"min":  TimeMinutes,
+	"s":    TimeSeconds,
+	"ms":   TimeMillis,
+	"full": TimeFull,
+}
+
+// ParseTimePrecision 解析精度名称：min、s、ms 或 full，空串表示 min
+func ParseTimePrecision(s string) (TimePrecision, error) {
+	if s == "" {
+		return TimeMinutes, nil
+	}
+	if p, ok := timePrecisionNames[s]; ok {
+		return p, nil
+	}
+	return 0, usagef("invalid time precision %q: expected one of min, s, ms, full", s)
+}
+
+// layout 返回对应精度的时间格式
+func (p TimePrecision) layout() string {
+	switch p {
+	case TimeSeconds:
+		return "2006-01-02 15:04:05"
+	case TimeMillis:
+		return "2006-01-02 15:04:05.000"
+	case TimeFull:
+		return "2006-01-02T15:04:05.00Z07:00"
+	}
+	return "2006-01-02 15:04"
+}
+
+// Format 按精度格式化时间；低于精度的部分直接截去，不四舍五入，保证先后顺序不变
+func (p TimePrecision) Format(t time.Time) string {
+	return t.Format(p.layout())
+}
+
+// TimeStyle 列表中时间的显示方式，与 TimePrecision 组合使用
+// 清单、报告等供程序读取的输出固定使用 RFC 3339，不受影响。
+type TimeStyle int
+
+const (
+	TimeLocal    TimeStyle = iota // 本地时间的 "2006-01-02 15:04" 形式，exfat-tool 的默认格式
+	TimeISO                       // ISO 8601（RFC 3339）并带 UTC 偏移，按字符串排序即按时间排序
+	TimeEpoch                     // Unix 时间戳（秒），精度为 ms 或 full 时带三位小数
+	TimeRelative                  // 相对于当前时间，如 "2 days ago"，见 RelativeTime
+)
+
+var timeStyleNames = map[string]TimeStyle{
+	"local":    TimeLocal,
+	"iso":      TimeISO,
+	"epoch":    TimeEpoch,
+	"relative": TimeRelative,
+}
+
+// ParseTimeStyle 解析显示方式的名称：local、iso、epoch 或 relative，空串表示 local
+func ParseTimeStyle(s string) (TimeStyle, error) {
+	if s == "" {
+		return TimeLocal, nil
+	}
+	if style, ok := timeStyleNames[s]; ok {
+		return style, nil
+	}
+	return 0, usagef("invalid time format %q: expected one of local, iso, epoch, relative", s)
+}
+
+// TimeFormat 列表中时间的格式：显示方式、精度和时区
+type TimeFormat struct {
+	Style     TimeStyle
+	Precision TimePrecision
+	UTC       bool      // 以 UTC 而不是时间自身的时区显示（local 和 iso）
+	Now       time.Time // relative 的参照时间，为零时使用当前时间
+}
+
+// Format 格式化时间；除 local 外，没有时间（零值）时返回 "-"
+func (f TimeFormat) Format(t time.Time) string {
+	if f.UTC {
+		t = t.UTC()
+	}
+	switch f.Style {
+	case TimeLocal:
+		return f.Precision.Format(t)
+	case TimeISO:
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format(f.Precision.isoLayout())
+	case TimeEpoch:
+		if t.IsZero() {
+			return "-"
+		}
+		if f.Precision >= TimeMillis {
+			return fmt.Sprintf("%d.%03d", t.Unix(), t.Nanosecond()/int(time.Millisecond))
+		}
+		return fmt.Sprintf("%d", t.Unix())
+	case TimeRelative:
+		if t.IsZero() {
+			return "-"
+		}
+		now := f.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		return RelativeTime(t, now)
+	}
+	return f.Precision.Format(t)
+}
+
+// isoLayout 返回对应精度的 ISO 8601 格式
+func (p TimePrecision) isoLayout() string {
+	switch p {
+	case TimeSeconds:
+		return "2006-01-02T15:04:05Z07:00"
+	case TimeMillis:
+		return "2006-01-02T15:04:05.000Z07:00"
+	case TimeFull:
+		return "2006-01-02T15:04:05.00Z07:00"
+	}
+	return "2006-01-02T15:04Z07:00"
+}
+
+// relativeUnits RelativeTime 使用的单位，从大到小；月按 30 天、年按 365 天计
+var relativeUnits = []struct {
+	name string
+	d    time.Duration
+}{
+	{"year", 365 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+	{"day", 24 * time.Hour},
+	{"hour", time.Hour},
+	{"minute", time.Minute},
+	{"second", time.Second},
+}
+
+// RelativeTime 以最大的整单位描述 t 相对于 now 的时间，如 "3 minutes ago"、"1 year ago"
+// 时钟不准的设备可能写入未来的时间，这时返回 "in 2 days" 的形式；相差不到一秒时返回 "just now"。
+func RelativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	for _, u := range relativeUnits {
+		if d < u.d {
+			continue
+		}
+		n := int64(d / u.d)
+		unit := u.name
+		if n != 1 {
+			unit += "s"
+		}
+		if future {
+			return fmt.Sprintf("in %d %s", n, unit)
+		}
+		return fmt.Sprintf("%d %s ago", n, unit)
+	}
+	return "just now"
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// isUsage 判断 err 是否为 *UsageError
+func isUsage(err error) bool {
+	var usage *UsageError
+	return errors.As(err, &usage)
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		ago  time.Duration // now 减去 t；为负表示未来
+		want string
+	}{
+		{0, "just now"},
+		{999 * time.Millisecond, "just now"},
+		{-999 * time.Millisecond, "just now"},
+		{time.Second, "1 second ago"},
+		{59 * time.Second, "59 seconds ago"},
+		{time.Minute, "1 minute ago"},
+		{3*time.Minute + 59*time.Second, "3 minutes ago"}, // 截去而不是四舍五入
+		{time.Hour, "1 hour ago"},
+		{23*time.Hour + 59*time.Minute, "23 hours ago"},
+		{day, "1 day ago"},
+		{29 * day, "29 days ago"},
+		{30 * day, "1 month ago"},
+		{364 * day, "12 months ago"},
+		{365 * day, "1 year ago"},
+		{3*365*day + 200*day, "3 years ago"},
+		{-time.Second, "in 1 second"},
+		{-90 * time.Minute, "in 1 hour"},
+		{-2 * day, "in 2 days"},
+		{-400 * day, "in 1 year"},
+	}
+	for _, tt := range tests {
+		if got := RelativeTime(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("RelativeTime(now - %v) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+
+	// 时区不影响结果
+	tokyo := time.FixedZone("", 9*3600)
+	if got := RelativeTime(now.Add(-2*time.Hour).In(tokyo), now); got != "2 hours ago" {
+		t.Errorf("time in another zone: %q", got)
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	ts := time.Date(2024, 6, 13, 14, 15, 16, 789e6, time.FixedZone("", 2*3600))
+	tests := []struct {
+		format TimeFormat
+		t      time.Time
+		want   string
+	}{
+		{TimeFormat{}, ts, "2024-06-13 14:15"},
+		{TimeFormat{Precision: TimeMillis}, ts, "2024-06-13 14:15:16.789"},
+		{TimeFormat{UTC: true, Precision: TimeSeconds}, ts, "2024-06-13 12:15:16"},
+		{TimeFormat{Style: TimeISO}, ts, "2024-06-13T14:15+02:00"},
+		{TimeFormat{Style: TimeISO, Precision: TimeFull, UTC: true}, ts, "2024-06-13T12:15:16.78Z"},
+		{TimeFormat{Style: TimeEpoch}, ts, "1718280916"},
+		{TimeFormat{Style: TimeEpoch, Precision: TimeMillis}, ts, "1718280916.789"},
+		{TimeFormat{Style: TimeRelative, Now: now}, ts, "1 day ago"},
+		{TimeFormat{Style: TimeISO}, time.Time{}, "-"},
+		{TimeFormat{Style: TimeEpoch}, time.Time{}, "-"},
+		{TimeFormat{Style: TimeRelative, Now: now}, time.Time{}, "-"},
+	}
+	for _, tt := range tests {
+		if got := tt.format.Format(tt.t); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimeOptions(t *testing.T) {
+	for name, want := range timeStyleNames {
+		if got, err := ParseTimeStyle(name); err != nil || got != want {
+			t.Errorf("ParseTimeStyle(%q) = %v, %v", name, got, err)
+		}
+	}
+	for name, want := range timePrecisionNames {
+		if got, err := ParseTimePrecision(name); err != nil || got != want {
+			t.Errorf("ParseTimePrecision(%q) = %v, %v", name, got, err)
+		}
+	}
+	if style, err := ParseTimeStyle(""); err != nil || style != TimeLocal {
+		t.Errorf("empty style: %v, %v", style, err)
+	}
+	if p, err := ParseTimePrecision(""); err != nil || p != TimeMinutes {
+		t.Errorf("empty precision: %v, %v", p, err)
+	}
+	if _, err := ParseTimeStyle("unix"); !isUsage(err) {
+		t.Errorf("invalid style: got %v, want a usage error", err)
+	}
+	if _, err := ParseTimePrecision("ns"); !isUsage(err) {
+		t.Errorf("invalid precision: got %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// TopOptions RunTop 的选项
+type TopOptions struct {
+	Root      string // 要分析的目录，为空时使用 /
+	N         int    // 报告的文件和目录数量
+	Allocated bool   // 按分配的簇空间而不是文件的逻辑大小计算
+}
+
+// RunTop 列出 Root 下占用空间最多的文件和目录
+func RunTop(w io.Writer, v *exfat.VHD, opts TopOptions) error {
+	root := opts.Root
+	if root == "" {
+		root = "/"
+	}
+	consumers, err := v.FileSystem().TopConsumers(root, opts.N, opts.Allocated)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%-5s %-10s %7s %s\n", "Type", "Size", "Used%", "Path")
+	for _, c := range consumers {
+		entryType := "File"
+		if c.IsDir {
+			entryType = "Dir"
+		}
+		fmt.Fprintf(w, "%-5s %-10s %6.2f%% %s\n", entryType, exfat.FormatFileSize(int64(c.Size)), c.Percent, c.Path)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// RunTraceCheck 按 v 的卷布局检查 -trace-reads 写出的读取跟踪，每个可疑的读取输出一行
+// 有可疑的读取时返回包装了 ErrPartial 的错误。
+func RunTraceCheck(w io.Writer, v *exfat.VHD, trace io.Reader) error {
+	findings := exfat.TraceCheck(trace, v.FSInfo())
+	for _, f := range findings {
+		fmt.Fprintln(w, f)
+	}
+	if len(findings) > 0 {
+		return partialf("%d suspicious reads", len(findings))
+	}
+	fmt.Fprintln(w, "No suspicious reads")
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// metadataCachePath 返回映像在缓存目录中的缓存文件路径
+// 以映像绝对路径、大小和修改时间的哈希命名，映像被改写后自然换用新的缓存文件；
+// 指定了分区时分区序号也计入哈希，同一映像的不同分区使用各自的缓存
+func metadataCachePath(dir, image string, partition int) string {
+	if abs, err := filepath.Abs(image); err == nil {
+		image = abs
+	}
+	key := image
+	if info, err := os.Stat(image); err == nil {
+		key = fmt.Sprintf("%s\x00%d\x00%d", image, info.Size(), info.ModTime().UnixNano())
+	}
+	if partition != 0 {
+		key += fmt.Sprintf("\x00%d", partition)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".cache")
+}
+
+// openWithCache 打开映像；cacheDir 非空时先尝试使用其中的元数据缓存，未命中时在打开后写入新的缓存
+// 缓存只是加速手段，读写缓存失败不影响打开映像
+func openWithCache(path, cacheDir string, partition int, opts ...exfat.Option) (*exfat.VHD, error) {
+	if cacheDir == "" {
+		return exfat.OpenVHD(path, opts...)
+	}
+
+	cachePath := metadataCachePath(cacheDir, path, partition)
+	if f, err := os.Open(cachePath); err == nil {
+		defer f.Close()
+		opts = append(opts, exfat.WithMetadataCache(f))
+	}
+
+	vhd, err := exfat.OpenVHD(path, opts...)
+	if err != nil || vhd.UsedMetadataCache() {
+		return vhd, err
+	}
+	if err := saveMetadataCache(vhd, cachePath); err != nil {
+		fmt.Fprintf(os.Stderr, "exfat-tool: cannot write metadata cache: %v\n", err)
+	}
+	return vhd, nil
+}
+
+// saveMetadataCache 先写入临时文件再改名，避免并发运行时读到写了一半的缓存
+func saveMetadataCache(vhd *exfat.VHD, cachePath string) error {
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(cachePath), ".cache-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := vhd.SaveMetadataCache(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cachePath)
+}
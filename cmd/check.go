@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+)
+
+// runCheck 实现 check 子命令：并行检查每个条目的元数据、簇链、分配位图和交叉链接
+func runCheck(args []string) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file")
+	workers := flags.Int("workers", 0, "Number of goroutines checking cluster chains (0 for one per CPU); the output does not depend on it")
+	partition := flags.Int("partition", 0, "Partition to check (numbered as in the partitions command)")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool check -vhd <path_to_vhd> [-workers N] [<dir_in_volume>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *vhdPath == "" || flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	opts := []exfat.Option{exfat.WithParentDir(*parentDir)}
+	if *partition != 0 {
+		opts = append(opts, exfat.WithPartition(*partition))
+	}
+	vhd, err := exfat.OpenVHD(*vhdPath, opts...)
+	if err != nil {
+		fmt.Printf("Failed to open VHD file: %v\n", err)
+		os.Exit(1)
+	}
+	defer vhd.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = cli.RunCheck(ctx, os.Stdout, vhd, exfat.CheckOptions{Root: flags.Arg(0), Workers: *workers})
+	var usage *cli.UsageError
+	switch {
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "exfat-tool: %v\n", err)
+		os.Exit(2)
+	case err != nil && !errors.Is(err, cli.ErrPartial):
+		fmt.Printf("Failed to check: %v\n", err)
+		os.Exit(1)
+	case err != nil:
+		os.Exit(1)
+	}
+}
@@ -1,33 +1,168 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+	"io"
 	"os"
 	"strings"
+	"time"
 )
 
 var (
-	vhdPath   string
-	listDir   string
-	extract   string
-	outputDir string
+	vhdPath    string
+	listDir    string
+	extract    string
+	outputDirs stringList
+	showInfo   bool
+	parentDir  string
+	noProbe    bool
+	noChecksum bool
+	partition  int
+	manifest   string
+	reportPath string
+	repair     bool
+	analyze    string
+	cacheDir   string
+
+	maxFiles    int
+	maxBytes    int64
+	maxDuration time.Duration
+	sampleEvery int
+
+	skipOSMetadata   bool
+	skipAndroidCache bool
+	crlf             bool
+
+	decompress         bool
+	decompressDepth    int
+	decompressSuffixes string
+	decompressMaxSize  int64
+
+	layout            string
+	dateSource        string
+	extractLayout     exfat.Layout
+	extractDateSource exfat.DateSource
+	listTimeFormat    cli.TimeFormat
+
+	checked      bool
+	onBadCluster string
+	onShortChain string
+	onChecksum   string
+	onCrossLink  string
+	crosslinks   bool
+
+	timePrecision   string
+	timeFormat      string
+	utc             bool
+	allTimes        bool
+	excludeClusters string
+	traceReads      string
 )
 
 func init() {
-	flag.StringVar(&vhdPath, "vhd", "", "Path to the VHD file")
-	flag.StringVar(&listDir, "list", "", "Directory path inside the exFAT filesystem to list (optional)")
-	flag.StringVar(&extract, "extract", "", "Comma-separated list of files/directories to extract (optional)")
-	flag.StringVar(&outputDir, "output", "output", "Destination folder for extracted files (default: ./output)")
+	registerFlags(flag.CommandLine)
 
 	flag.Usage = func() {
 		fmt.Println("Usage: exfat-tool -vhd <path_to_vhd> [options]")
+		fmt.Println("       exfat-tool <command> -vhd <path_to_vhd> [options]")
+		fmt.Println()
+		fmt.Println("Commands:")
+		fmt.Println("  top              Report the largest files and directories")
+		fmt.Println("  check            Check every entry's metadata, cluster chain, allocation and cross-links in parallel")
+		fmt.Println("  stats            Report used and free space from the bitmap, the FAT and the directory tree")
+		fmt.Println("  repair-plan      Propose a cluster sequence for files with a broken chain")
+		fmt.Println("  export-pax       Export the volume as a pax, tar.gz or cpio archive with exFAT metadata")
+		fmt.Println("  extract-cluster  Recover data starting at a cluster, without a directory entry")
+		fmt.Println("  trace-check      Check a -trace-reads log for reads that cross regions or are misaligned")
+		fmt.Println("  export-raw       Copy a partition or the whole disk out of the image as a flat file")
+		fmt.Println("  partitions       List the partitions of a disk image and the filesystem detected in each")
+		fmt.Println("  identify         Print the volume serial, label and image UUID of many images as NDJSON")
+		fmt.Println("  paths            Print every path in the volume, one per line, for pickers such as fzf")
+		fmt.Println("  previews         Write the thumbnails embedded in photos and videos (EXIF, HEIC, MP4 cover art)")
+		fmt.Println("  serve            Serve the files in the volume over HTTP, with range and conditional requests")
+		fmt.Println("  export-sqlite    Export the directory tree to a SQLite database (needs exfat-tool-export-sqlite)")
+		fmt.Println()
+		fmt.Println("-info, -analyze, -list and -extract may be combined; they run in that order.")
+		fmt.Println()
+		fmt.Println("Options:")
 		flag.PrintDefaults()
 	}
 }
 
+// registerFlags 在 flags 上注册主命令的选项，并把对应的变量重置为默认值
+func registerFlags(flags *flag.FlagSet) {
+	outputDirs = nil // flags.Var 不会重置可重复的选项
+	flags.StringVar(&vhdPath, "vhd", "", "Path to the VHD file")
+	flags.StringVar(&listDir, "list", "", "Directory path inside the exFAT filesystem to list (optional)")
+	flags.StringVar(&extract, "extract", "", "Comma-separated list of files/directories to extract (optional)")
+	flags.Var(&outputDirs, "output", "Destination folder for extracted files (default: ./output); repeat to write identical copies to several folders")
+	flags.StringVar(&analyze, "analyze", "", "Directory path to analyse recursively for file contiguity (optional)")
+	flags.BoolVar(&showInfo, "info", false, "Show image information, including the differencing disk chain (optional)")
+	flags.StringVar(&parentDir, "parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.BoolVar(&noProbe, "no-probe", false, "Do not probe for a vendor header before the exFAT boot sector of raw images")
+	flags.BoolVar(&noChecksum, "no-boot-checksum", false, "Open the image even if the boot region checksum does not match (the mismatch is shown by -info)")
+	flags.IntVar(&partition, "partition", 0, "Open this partition (numbered as in the partitions command) instead of the first one containing exFAT")
+	flags.BoolVar(&repair, "with-repair-plans", false, "With -extract, read files whose cluster chain ends early using a repair plan")
+	flags.BoolVar(&checked, "checked", false, "With -extract, check entries while extracting and handle anomalies according to the -on-* policies")
+	flags.StringVar(&onBadCluster, "on-badcluster", "extract", "With -checked, action for files whose chain runs into a bad cluster: extract, zero, repair, skip or fail")
+	flags.StringVar(&onShortChain, "on-shortchain", "extract", "With -checked, action for files whose chain ends early: extract, zero, repair, skip or fail")
+	flags.StringVar(&onChecksum, "on-checksum", "extract", "With -checked, action for entries whose entry set checksum does not match: extract, skip or fail")
+	flags.StringVar(&onCrossLink, "on-crosslink", "extract", "With -checked and -detect-crosslinks, action for files that share clusters with a file extracted before them: extract, skip or fail")
+	flags.BoolVar(&crosslinks, "detect-crosslinks", false, "Report files whose cluster chain shares clusters with another file read before them")
+	flags.IntVar(&maxFiles, "max-files", 0, "With -extract, stop after extracting this many files (0 for no limit)")
+	flags.Int64Var(&maxBytes, "max-bytes", 0, "With -extract, stop before the extracted data would exceed this many bytes (0 for no limit)")
+	flags.DurationVar(&maxDuration, "max-duration", 0, "With -extract, stop after this long, e.g. 60s (0 for no limit)")
+	flags.IntVar(&sampleEvery, "sample-every", 0, "With -extract, extract only every Nth file")
+	flags.BoolVar(&skipOSMetadata, "skip-os-metadata", false, "With -extract, skip macOS and Windows metadata such as .DS_Store and System Volume Information; AppleDouble ._ files are merged on macOS and dropped elsewhere")
+	flags.BoolVar(&skipAndroidCache, "skip-android-cache", false, "With -extract, skip Android caches, thumbnails and .nomedia markers")
+	flags.BoolVar(&crlf, "crlf", false, "With -extract, convert LF line endings to CRLF in files detected as text; binary files are written unchanged")
+	flags.BoolVar(&decompress, "decompress", false, "With -extract, decompress gzip, zlib and single-file zip files matching -decompress-suffixes and strip the suffix; corrupt ones are written unchanged with a warning")
+	flags.IntVar(&decompressDepth, "decompress-depth", 1, "With -decompress, how many nested compression layers to decompress (e.g. 2 for .log.gz.gz)")
+	flags.StringVar(&decompressSuffixes, "decompress-suffixes", strings.Join(exfat.DefaultDecompressSuffixes, ","), "With -decompress, comma-separated file suffixes to decompress")
+	flags.Int64Var(&decompressMaxSize, "decompress-max-size", exfat.DefaultDecompressMaxSize, "With -decompress, largest decompressed size in bytes per file; larger ones are written unchanged with a warning (-1 for no limit)")
+	flags.StringVar(&layout, "layout", "mirror", "With -extract, where files go: mirror (the volume's directories), flat (all in the output directory, duplicate names suffixed) or date:<Go time layout> (e.g. date:2006/01/02; files without a time go to undated/)")
+	flags.StringVar(&dateSource, "date-source", "modify", "With -layout date, which time to group by: modify or create")
+	flags.StringVar(&timePrecision, "time-precision", "min", "With -list, precision of modification times: min, s, ms (shows the 10 ms component) or full (RFC 3339 with UTC offset)")
+	flags.StringVar(&timeFormat, "time-format", "local", "With -list, how to show modification times: local, iso (RFC 3339 with UTC offset), epoch (Unix seconds) or relative (e.g. 2 days ago)")
+	flags.BoolVar(&utc, "utc", false, "With -list, show local and iso times in UTC")
+	flags.BoolVar(&allTimes, "all-times", false, "With -list, also show creation and last access times")
+	flags.StringVar(&excludeClusters, "exclude-clusters", "", "Comma-separated clusters or ranges (e.g. 100-199) that end any chain reaching them, such as a vendor firmware area (optional)")
+	flags.StringVar(&traceReads, "trace-reads", "", "Log every read of the image to this file as NDJSON (layer, offsets, length, purpose) for debugging; check it with trace-check")
+	flags.StringVar(&cacheDir, "cache-dir", "", "Directory for metadata caches that speed up reopening the same image (optional)")
+	flags.StringVar(&manifest, "manifest", "", "With -extract, write a listing of extracted entries to this file (\"-\" for stdout)")
+	flags.StringVar(&reportPath, "report", "", "With -extract, write the extraction report of each path to this file as NDJSON")
+}
+
+// commands 子命令
+var commands = map[string]func(args []string){
+	"top":             runTop,
+	"stats":           runStats,
+	"check":           runCheck,
+	"repair-plan":     runRepairPlan,
+	"export-pax":      runExportPax,
+	"extract-cluster": runExtractCluster,
+	"partitions":      runPartitions,
+	"export-raw":      runExportRaw,
+	"trace-check":     runTraceCheck,
+	"identify":        runIdentify,
+	"paths":           runPaths,
+	"previews":        runPreviews,
+	"serve":           runServe,
+	"export-sqlite":   runExportSQLite,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
 	flag.Parse()
 
 	if vhdPath == "" {
@@ -35,54 +170,233 @@ func main() {
 		return
 	}
 
-	vhd, err := exfat.OpenVHD(vhdPath)
+	policy, err := checkFlags()
+	if err != nil {
+		usageError(err.Error())
+	}
+
+	opts := []exfat.Option{exfat.WithParentDir(parentDir)}
+	if noProbe {
+		opts = append(opts, exfat.WithoutProbe())
+	}
+	if noChecksum {
+		opts = append(opts, exfat.WithoutBootChecksum())
+	}
+	if partition != 0 {
+		opts = append(opts, exfat.WithPartition(partition))
+	}
+	if crosslinks {
+		opts = append(opts, exfat.WithCrosslinkDetection())
+	}
+	if excludeClusters != "" {
+		ranges, err := cli.ParseClusterRanges(excludeClusters)
+		if err != nil {
+			usageError(err.Error())
+		}
+		opts = append(opts, exfat.WithClusterFilter(exfat.ExcludeClusterRanges(ranges...)))
+	}
+
+	if traceReads != "" {
+		f, err := os.Create(traceReads)
+		if err != nil {
+			fmt.Printf("Failed to create read trace: %v\n", err)
+			return
+		}
+		defer f.Close()
+		opts = append(opts, exfat.WithReadTracing(f))
+	}
+
+	vhd, err := openWithCache(vhdPath, cacheDir, partition, opts...)
 	if err != nil {
 		fmt.Printf("Failed to open VHD file: %v\n", err)
 		return
 	}
 	defer vhd.Close()
 
-	// 列目录
+	runOperations(os.Stdout, os.Stderr, vhd, policy)
+}
+
+// checkFlags 校验主命令的选项组合并解析布局、日期来源和时间格式，返回 -checked 的检查策略（未指定时为 nil）
+// 清单只在提取时有意义；清单写到标准输出时不能与其他输出混在一起。组合无效时返回 *cli.UsageError。
+func checkFlags() (exfat.CheckPolicy, error) {
+	usage := func(msg string) (exfat.CheckPolicy, error) {
+		return nil, &cli.UsageError{Msg: msg}
+	}
+	if manifest != "" && extract == "" {
+		return usage("-manifest requires -extract")
+	}
+	if reportPath != "" && extract == "" {
+		return usage("-report requires -extract")
+	}
+	if (maxFiles != 0 || maxBytes != 0 || maxDuration != 0 || sampleEvery != 0) && extract == "" {
+		return usage("-max-files, -max-bytes, -max-duration and -sample-every require -extract")
+	}
+	if (skipOSMetadata || skipAndroidCache) && extract == "" {
+		return usage("-skip-os-metadata and -skip-android-cache require -extract")
+	}
+	if crlf && extract == "" {
+		return usage("-crlf requires -extract")
+	}
+	if decompress && extract == "" {
+		return usage("-decompress requires -extract")
+	}
+	if decompressDepth < 1 {
+		return usage("-decompress-depth must be at least 1")
+	}
+	if layout != "mirror" && extract == "" {
+		return usage("-layout requires -extract")
+	}
+	var err error
+	if extractLayout, err = exfat.ParseLayout(layout); err != nil {
+		return usage(err.Error())
+	}
+	if extractDateSource, err = exfat.ParseDateSource(dateSource); err != nil {
+		return usage(err.Error())
+	}
+	if repair && extract == "" {
+		return usage("-with-repair-plans requires -extract")
+	}
+	if checked && extract == "" {
+		return usage("-checked requires -extract")
+	}
+	if checked && repair {
+		return usage("-checked cannot be combined with -with-repair-plans; use -on-shortchain=repair")
+	}
+	var policy exfat.CheckPolicy
+	if checked {
+		if policy, err = cli.ParseCheckPolicy(onBadCluster, onShortChain, onChecksum, onCrossLink); err != nil {
+			return nil, err
+		}
+	}
+	listTimeFormat = cli.TimeFormat{UTC: utc}
+	if listTimeFormat.Precision, err = cli.ParseTimePrecision(timePrecision); err != nil {
+		return nil, err
+	}
+	if listTimeFormat.Style, err = cli.ParseTimeStyle(timeFormat); err != nil {
+		return nil, err
+	}
+	if manifest == "-" && (showInfo || analyze != "" || listDir != "") {
+		return usage("-manifest - cannot be combined with -info, -analyze or -list")
+	}
+	return policy, nil
+}
+
+// runOperations 按 信息、分析、列目录、提取 的固定顺序执行选中的操作，各部分之间用标题分隔
+// 输出写到 stdout；清单写到标准输出时，提取的状态信息改写到 stderr。
+func runOperations(stdout, stderr io.Writer, vhd *exfat.VHD, policy exfat.CheckPolicy) {
+	sections := 0
+	for _, on := range []bool{showInfo, analyze != "", listDir != "", extract != ""} {
+		if on {
+			sections++
+		}
+	}
+	section := func(title string) {
+		if sections > 1 {
+			fmt.Fprintf(stdout, "== %s ==\n", title)
+		}
+	}
+
+	if showInfo {
+		section("Info")
+		cli.RunInfo(stdout, vhd)
+		if sections > 1 {
+			fmt.Fprintln(stdout)
+		}
+	}
+
+	if analyze != "" {
+		section("Analyze " + analyze)
+		if err := cli.RunAnalyze(stdout, vhd, cli.AnalyzeOptions{Dir: analyze}); err != nil {
+			fmt.Fprintf(stdout, "Failed to analyse directory: %v\n", err)
+		}
+		if listDir != "" || extract != "" {
+			fmt.Fprintln(stdout)
+		}
+	}
+
 	if listDir != "" {
-		entries, err := vhd.ListDir(listDir)
-		if err != nil {
-			fmt.Printf("Failed to list directory: %v\n", err)
-			return
+		section("List " + listDir)
+		if err := cli.RunList(stdout, vhd, cli.ListOptions{Dir: listDir, AllTimes: allTimes, TimeFormat: listTimeFormat}); err != nil {
+			fmt.Fprintf(stdout, "Failed to list directory: %v\n", err)
 		}
-		fmt.Printf("%-17s %-5s %-10s %s\n", "Modify Time", "Type", "Size", "Name")
-		for _, entry := range entries {
-			entryModTime := entry.ModTime.Format("2006-01-02 15:04")
-			entryType := "File"
-			if entry.IsDir {
-				entryType = "Dir"
-			}
-			entrySize := exfat.FormatFileSize(entry.Size)
-			if entry.IsDir {
-				entrySize = "-"
-			}
-			fmt.Printf("%-17s %-5s %-10s %s\n", entryModTime, entryType, entrySize, entry.Name)
+		if extract != "" {
+			fmt.Fprintln(stdout)
 		}
-		return
 	}
 
-	// 解压文件或目录
 	if extract != "" {
-		if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
-			fmt.Printf("Failed to create output directory: %v\n", err)
-			return
-		}
+		section("Extract")
+		extractPaths(stdout, stderr, vhd, policy)
+	}
+}
 
-		paths := strings.Split(extract, ",")
-		for _, p := range paths {
-			p = strings.TrimSpace(p)
-			if p == "" {
-				continue
-			}
-			if err := vhd.ExtractFile(p, outputDir); err != nil {
-				fmt.Printf("Failed to extract %s: %v\n", p, err)
+// stringList 可重复指定的字符串选项
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// usageError 报告选项组合错误并退出
+func usageError(msg string) {
+	fmt.Fprintf(os.Stderr, "exfat-tool: %s\n", msg)
+	flag.Usage()
+	os.Exit(2)
+}
+
+// extractPaths 解压 -extract 指定的文件或目录，policy 不为 nil 时在提取的同时按策略检查
+// 状态信息默认写到 stdout；清单写到标准输出时改写到 stderr，保证清单可以直接被管道消费。
+// 部分路径提取失败时只报告，不改变退出码。
+func extractPaths(stdout, stderr io.Writer, vhd *exfat.VHD, policy exfat.CheckPolicy) {
+	opts := cli.ExtractOptions{
+		Paths:            strings.Split(extract, ","),
+		Outputs:          outputDirs,
+		RepairPlans:      repair,
+		Policy:           policy,
+		Sample:           exfat.SampleLimits{MaxFiles: maxFiles, MaxBytes: maxBytes, MaxDuration: maxDuration, SampleEvery: sampleEvery},
+		SkipOSMetadata:   skipOSMetadata,
+		SkipAndroidCache: skipAndroidCache,
+		CRLF:             crlf,
+		Layout:           extractLayout,
+		DateSource:       extractDateSource,
+	}
+	if decompress {
+		opts.Decompress, opts.DecompressDepth, opts.DecompressMaxSize = true, decompressDepth, decompressMaxSize
+		for _, s := range strings.Split(decompressSuffixes, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				opts.DecompressSuffixes = append(opts.DecompressSuffixes, s)
 			}
 		}
-		fmt.Printf("Extracted %s to %s\n", extract, outputDir)
-		return
+	}
+	status := stdout
+	switch manifest {
+	case "":
+	case "-":
+		status = stderr
+		opts.Manifest = stdout
+	default:
+		f, err := os.Create(manifest)
+		if err != nil {
+			fmt.Fprintf(status, "Failed to create manifest: %v\n", err)
+			return
+		}
+		defer f.Close()
+		opts.Manifest = f
+	}
+	if reportPath != "" {
+		f, err := os.Create(reportPath)
+		if err != nil {
+			fmt.Fprintf(status, "Failed to create report: %v\n", err)
+			return
+		}
+		defer f.Close()
+		opts.Report = f
+	}
+
+	if err := cli.RunExtract(status, vhd, opts); err != nil && !errors.Is(err, cli.ErrPartial) {
+		fmt.Fprintf(status, "Failed to extract: %v\n", err)
 	}
 }
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// memDisk 内存中的映像，实现 container.Backend
+type memDisk struct {
+	*testimage.Disk
+}
+
+func (memDisk) Close() error { return nil }
+
+// openFixture 打开内存中构造的测试卷
+func openFixture(t *testing.T) *exfat.VHD {
+	t.Helper()
+	img := testimage.Build(testimage.Options{},
+		testimage.Dir("DCIM", testimage.File("a.jpg", []byte("photo a")), testimage.File("b.jpg", []byte("photo b"))),
+		testimage.File("notes.txt", []byte("notes")))
+	vhd, err := exfat.NewVHD(memDisk{img.Disk()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { vhd.Close() })
+	return vhd
+}
+
+// parseFlags 把主命令的选项重置为默认值后解析 args，再校验组合
+func parseFlags(t *testing.T, args ...string) (exfat.CheckPolicy, error) {
+	t.Helper()
+	flags := flag.NewFlagSet("exfat-tool", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	registerFlags(flags)
+	if err := flags.Parse(append([]string{"-vhd", "test.vhd"}, args...)); err != nil {
+		t.Fatalf("parse %q: %v", args, err)
+	}
+	return checkFlags()
+}
+
+func TestFlagCombinations(t *testing.T) {
+	tests := []struct {
+		args  []string
+		usage string // 期望的用法错误，为空表示组合有效
+	}{
+		{[]string{"-list", "/"}, ""},
+		{[]string{"-list", "/", "-extract", "/DCIM"}, ""},
+		{[]string{"-info", "-analyze", "/", "-list", "/", "-extract", "/DCIM"}, ""},
+		{[]string{"-extract", "/DCIM", "-manifest", "-"}, ""},
+		{[]string{"-extract", "/DCIM", "-manifest", "m.tsv", "-list", "/"}, ""},
+		{[]string{"-extract", "/DCIM", "-checked", "-on-shortchain", "repair"}, ""},
+		{[]string{"-manifest", "-"}, "-manifest requires -extract"},
+		{[]string{"-list", "/", "-manifest", "m.tsv"}, "-manifest requires -extract"},
+		{[]string{"-extract", "/DCIM", "-manifest", "-", "-list", "/"}, "cannot be combined"},
+		{[]string{"-extract", "/DCIM", "-manifest", "-", "-info"}, "cannot be combined"},
+		{[]string{"-extract", "/DCIM", "-manifest", "-", "-analyze", "/"}, "cannot be combined"},
+		{[]string{"-list", "/", "-report", "r.ndjson"}, "-report requires -extract"},
+		{[]string{"-list", "/", "-max-files", "3"}, "require -extract"},
+		{[]string{"-list", "/", "-skip-os-metadata"}, "require -extract"},
+		{[]string{"-list", "/", "-crlf"}, "-crlf requires -extract"},
+		{[]string{"-list", "/", "-decompress"}, "-decompress requires -extract"},
+		{[]string{"-extract", "/", "-decompress-depth", "0"}, "at least 1"},
+		{[]string{"-list", "/", "-layout", "flat"}, "-layout requires -extract"},
+		{[]string{"-extract", "/", "-layout", "tree"}, "tree"},
+		{[]string{"-list", "/", "-with-repair-plans"}, "-with-repair-plans requires -extract"},
+		{[]string{"-list", "/", "-checked"}, "-checked requires -extract"},
+		{[]string{"-extract", "/", "-checked", "-with-repair-plans"}, "cannot be combined"},
+		{[]string{"-extract", "/", "-checked", "-on-checksum", "bogus"}, "bogus"},
+		{[]string{"-list", "/", "-time-format", "unix"}, "unix"},
+		{[]string{"-list", "/", "-time-precision", "ns"}, "ns"},
+	}
+	for _, tt := range tests {
+		_, err := parseFlags(t, tt.args...)
+		var usage *cli.UsageError
+		switch {
+		case tt.usage == "" && err != nil:
+			t.Errorf("%q: %v", tt.args, err)
+		case tt.usage != "" && (!errors.As(err, &usage) || !strings.Contains(err.Error(), tt.usage)):
+			t.Errorf("%q: got %v, want a usage error containing %q", tt.args, err, tt.usage)
+		}
+	}
+}
+
+func TestListOnly(t *testing.T) {
+	policy, err := parseFlags(t, "-list", "/DCIM", "-time-format", "iso", "-utc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	runOperations(&stdout, &stderr, openFixture(t), policy)
+	out := stdout.String()
+	// 只有一个操作时不输出标题
+	if strings.Contains(out, "==") || !strings.Contains(out, "a.jpg") || !strings.Contains(out, "b.jpg") || stderr.Len() != 0 {
+		t.Errorf("stdout %q, stderr %q", out, stderr.String())
+	}
+	if !strings.Contains(out, "Z ") {
+		t.Errorf("-time-format iso -utc not applied: %q", out)
+	}
+}
+
+func TestListThenExtract(t *testing.T) {
+	dest := t.TempDir()
+	policy, err := parseFlags(t, "-list", "/", "-extract", "/DCIM,/notes.txt", "-output", dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	runOperations(&stdout, &stderr, openFixture(t), policy)
+	out := stdout.String()
+
+	// 先列目录再提取，两部分各有标题并用空行分隔；提取不再被 -list 跳过
+	list := strings.Index(out, "== List / ==\n")
+	extract := strings.Index(out, "\n\n== Extract ==\n")
+	if list != 0 || extract < 0 {
+		t.Fatalf("sections out of order:\n%s", out)
+	}
+	if listing := out[:extract]; !strings.Contains(listing, "DCIM") || !strings.Contains(listing, "notes.txt") {
+		t.Errorf("listing section: %q", listing)
+	}
+	if !strings.Contains(out[extract:], "Extracted /DCIM,/notes.txt to "+dest+" (3 files") {
+		t.Errorf("extract section: %q", out[extract:])
+	}
+	for name, want := range map[string]string{"a.jpg": "photo a", "b.jpg": "photo b", "notes.txt": "notes"} {
+		if got, err := os.ReadFile(filepath.Join(dest, name)); err != nil || string(got) != want {
+			t.Errorf("%s: %q, %v", name, got, err)
+		}
+	}
+}
+
+func TestAllSectionsInOrder(t *testing.T) {
+	policy, err := parseFlags(t, "-info", "-analyze", "/", "-list", "/", "-extract", "/notes.txt", "-output", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	runOperations(&stdout, io.Discard, openFixture(t), policy)
+	out := stdout.String()
+	last := -1
+	for _, title := range []string{"== Info ==", "== Analyze / ==", "== List / ==", "== Extract =="} {
+		i := strings.Index(out, title)
+		if i <= last {
+			t.Fatalf("%s missing or out of order:\n%s", title, out)
+		}
+		last = i
+	}
+}
+
+func TestExtractManifestToStdout(t *testing.T) {
+	dest := t.TempDir()
+	policy, err := parseFlags(t, "-extract", "/DCIM", "-output", dest, "-manifest", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	runOperations(&stdout, &stderr, openFixture(t), policy)
+
+	// 标准输出只有清单，状态信息在标准错误中
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	want := map[string]string{"/DCIM/a.jpg": "a.jpg", "/DCIM/b.jpg": "b.jpg"}
+	if len(lines) != len(want) {
+		t.Fatalf("manifest %q, want %d lines", stdout.String(), len(want))
+	}
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 9 || fields[0] != "F" || fields[1] != "7" || fields[3] != filepath.Join(dest, want[fields[2]]) || fields[7] != "7" {
+			t.Errorf("manifest line %q", line)
+		}
+	}
+	if !strings.Contains(stderr.String(), "Extracted /DCIM to "+dest) {
+		t.Errorf("status %q", stderr.String())
+	}
+}
+
+func TestExtractManifestToFile(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "manifest.tsv")
+	policy, err := parseFlags(t, "-list", "/", "-extract", "/notes.txt", "-output", filepath.Join(dir, "out"), "-manifest", manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	runOperations(&stdout, &stderr, openFixture(t), policy)
+
+	// 清单写到文件时，列表和状态信息都在标准输出中
+	if !strings.Contains(stdout.String(), "== List / ==") || !strings.Contains(stdout.String(), "Extracted /notes.txt") || stderr.Len() != 0 {
+		t.Errorf("stdout %q, stderr %q", stdout.String(), stderr.String())
+	}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], "F\t5\t/notes.txt\t") {
+		t.Errorf("manifest %q", data)
+	}
+}
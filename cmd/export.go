@@ -0,0 +1,91 @@
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+)
+
+// runExportPax 实现 export-pax 子命令：把整个卷（或某个目录）导出为 pax、gzip 压缩的 pax 或 cpio 归档
+func runExportPax(args []string) {
+	flags := flag.NewFlagSet("export-pax", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file")
+	output := flags.String("o", "", "Archive to write (\"-\" for stdout)")
+	root := flags.String("root", "/", "Directory inside the exFAT filesystem to export")
+	format := flags.String("format", "pax", "Archive format: pax, tar.gz (gzip-compressed pax) or cpio (newc; keeps only mtime and permissions)")
+	gzipLevel := flags.Int("gzip-level", gzip.DefaultCompression, "With -format tar.gz, the gzip compression level (1-9, -1 for the default)")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool export-pax -vhd <path_to_vhd> -o <archive> [-root /] [-format pax|tar.gz|cpio]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *vhdPath == "" || *output == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	opts := cli.ExportOptions{Root: *root, Format: *format, GzipLevel: *gzipLevel}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "exfat-tool: %v\n", err)
+		os.Exit(2)
+	}
+
+	vhd, err := exfat.OpenVHD(*vhdPath, exfat.WithParentDir(*parentDir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open VHD file: %v\n", err)
+		os.Exit(1)
+	}
+	defer vhd.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create archive: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := cli.RunExport(w, vhd, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export %s: %v\n", *root, err)
+		os.Exit(1)
+	}
+}
+
+// exportSQLiteHelper 实现 export-sqlite 子命令的独立程序
+// SQLite 驱动放在单独的模块中，exfat-tool 本身不依赖它
+const exportSQLiteHelper = "exfat-tool-export-sqlite"
+
+// runExportSQLite 实现 export-sqlite 子命令：调用 exfat-tool 所在目录或 PATH 中的 exfat-tool-export-sqlite
+func runExportSQLite(args []string) {
+	helper, err := exec.LookPath(exportSQLiteHelper)
+	if self, serr := os.Executable(); err != nil && serr == nil {
+		helper, err = exec.LookPath(filepath.Join(filepath.Dir(self), exportSQLiteHelper))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "exfat-tool: export-sqlite needs %s; install it with\n", exportSQLiteHelper)
+		fmt.Fprintf(os.Stderr, "  go install github.com/0xXA/go-exfat/sqliteexport/cmd/%s@latest\n", exportSQLiteHelper)
+		os.Exit(1)
+	}
+
+	cmd := exec.Command(helper, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Fprintf(os.Stderr, "exfat-tool: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+)
+
+// runExportRaw 实现 export-raw 子命令：把分区或整个磁盘导出为平面映像文件
+func runExportRaw(args []string) {
+	flags := flag.NewFlagSet("export-raw", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file")
+	output := flags.String("o", "", "Output file (\"-\" for stdout); zero ranges are written as holes in a file")
+	partition := flags.Int("partition", 0, "Partition to export (numbered as in the partitions command); default is the first one containing exFAT")
+	wholeDisk := flags.Bool("whole-disk", false, "Export the whole disk instead of a partition")
+	limit := flags.Int64("limit", 0, "Read at most this many bytes per second from the image (0 for no limit)")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool export-raw -vhd <path_to_vhd> -o <file> [-partition N | -whole-disk] [-limit bytes/s]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *vhdPath == "" || *output == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *wholeDisk && *partition != 0 {
+		fmt.Fprintln(os.Stderr, "exfat-tool: -whole-disk cannot be combined with -partition")
+		os.Exit(2)
+	}
+
+	// 状态信息写到标准错误，导出到标准输出时不与数据混在一起
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create %s: %v\n", *output, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	opts := cli.ExportRawOptions{Output: out, Partition: *partition, WholeDisk: *wholeDisk, BytesPerSecond: *limit}
+	err := cli.RunExportRaw(ctx, os.Stderr, *vhdPath, opts, exfat.WithParentDir(*parentDir))
+	var usage *cli.UsageError
+	switch {
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "exfat-tool: %v\n", err)
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Failed to export: %v\n", err)
+		os.Exit(1)
+	}
+}
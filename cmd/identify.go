@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+)
+
+// runIdentify 实现 identify 子命令：扫描映像并以 NDJSON 输出每个卷的标识（每行一个映像）
+func runIdentify(args []string) {
+	flags := flag.NewFlagSet("identify", flag.ExitOnError)
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool identify [-parent-dir <dir>] <glob> [<glob>...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	err := cli.RunIdentify(os.Stdout, os.Stderr, flags.Args(), exfat.WithParentDir(*parentDir))
+	var usage *cli.UsageError
+	switch {
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "exfat-tool: %v\n", err)
+		os.Exit(2)
+	case err != nil && !errors.Is(err, cli.ErrPartial):
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	case err != nil:
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+)
+
+// runPartitions 实现 partitions 子命令：列出分区表中的分区及其中探测到的文件系统
+func runPartitions(args []string) {
+	flags := flag.NewFlagSet("partitions", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool partitions -vhd <path_to_vhd>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *vhdPath == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if err := cli.RunPartitions(os.Stdout, *vhdPath, exfat.WithParentDir(*parentDir)); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read partition table: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+)
+
+// runPaths 实现 paths 子命令：逐行输出卷中的路径，供 fzf 等选择器和 shell 补全使用
+func runPaths(args []string) {
+	flags := flag.NewFlagSet("paths", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file")
+	root := flags.String("root", "/", "Directory inside the exFAT filesystem to list")
+	dirs := flags.Bool("dirs", false, "Also print directories, with a trailing /")
+	nul := flags.Bool("0", false, "Separate paths with NUL instead of newline and print names unescaped (for xargs -0, fzf --read0)")
+	maxDepth := flags.Int("max-depth", 0, "Descend at most this many levels below -root (0 for no limit)")
+	var include, exclude stringList
+	flags.Var(&include, "include", "Only print entries whose name (or full path, for patterns starting with /) matches this pattern; repeatable")
+	flags.Var(&exclude, "exclude", "Skip entries matching this pattern, and the contents of matching directories; repeatable")
+	skipOSMetadata := flags.Bool("skip-os-metadata", false, "Skip macOS and Windows metadata such as .DS_Store and System Volume Information")
+	skipAndroidCache := flags.Bool("skip-android-cache", false, "Skip Android caches, thumbnails and .nomedia markers")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool paths -vhd <path_to_vhd> [-root /] [-dirs] [-0] [-max-depth N] [-include <pattern>] [-exclude <pattern>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *vhdPath == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	vhd, err := exfat.OpenVHD(*vhdPath, exfat.WithParentDir(*parentDir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open VHD file: %v\n", err)
+		os.Exit(1)
+	}
+	defer vhd.Close()
+
+	opts := cli.PathsOptions{Root: *root, Dirs: *dirs, NUL: *nul, MaxDepth: *maxDepth, Include: include, Exclude: exclude}
+	if *skipOSMetadata {
+		opts.Presets = append(opts.Presets, exfat.PresetSkipOSMetadata)
+	}
+	if *skipAndroidCache {
+		opts.Presets = append(opts.Presets, exfat.PresetSkipAndroidCache)
+	}
+	err = cli.RunPaths(os.Stdout, os.Stderr, vhd, opts)
+	var usage *cli.UsageError
+	switch {
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "exfat-tool: %v\n", err)
+		flags.Usage()
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Failed to list %s: %v\n", *root, err)
+		os.Exit(1)
+	}
+}
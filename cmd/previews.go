@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+)
+
+// runPreviews 实现 previews 子命令：提取图片和视频中嵌入的缩略图
+func runPreviews(args []string) {
+	flags := flag.NewFlagSet("previews", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file")
+	output := flags.String("o", "thumbs", "Directory to write the previews to")
+	maxBytes := flags.Int("max-bytes", exfat.DefaultPreviewBytes, "Read at most this many bytes of each file")
+	partition := flags.Int("partition", 0, "Partition to read (numbered as in the partitions command)")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool previews -vhd <path_to_vhd> [-o <dir>] [-max-bytes N] [<dir_in_volume>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *vhdPath == "" || flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	opts := []exfat.Option{exfat.WithParentDir(*parentDir)}
+	if *partition != 0 {
+		opts = append(opts, exfat.WithPartition(*partition))
+	}
+	vhd, err := exfat.OpenVHD(*vhdPath, opts...)
+	if err != nil {
+		fmt.Printf("Failed to open VHD file: %v\n", err)
+		os.Exit(1)
+	}
+	defer vhd.Close()
+
+	err = cli.RunPreviews(os.Stdout, vhd, cli.PreviewOptions{Dir: flags.Arg(0), Output: *output, MaxBytes: *maxBytes})
+	var usage *cli.UsageError
+	switch {
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "exfat-tool: %v\n", err)
+		os.Exit(2)
+	case err != nil && !errors.Is(err, cli.ErrPartial):
+		fmt.Printf("Failed to extract previews: %v\n", err)
+		os.Exit(1)
+	case err != nil:
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+)
+
+// runRepairPlan 实现 repair-plan 子命令：为簇链断裂的文件制定修复计划（只读，不修改映像）
+func runRepairPlan(args []string) {
+	flags := flag.NewFlagSet("repair-plan", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool repair-plan -vhd <path_to_vhd> <path> [<path>...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *vhdPath == "" || flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	vhd, err := exfat.OpenVHD(*vhdPath, exfat.WithParentDir(*parentDir))
+	if err != nil {
+		fmt.Printf("Failed to open VHD file: %v\n", err)
+		os.Exit(1)
+	}
+	defer vhd.Close()
+
+	if err := cli.RunRepairPlan(os.Stdout, vhd, flags.Args()); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/httpfs"
+)
+
+// runServe 实现 serve 子命令：通过 HTTP 提供映像中的文件
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file")
+	addr := flags.String("addr", "127.0.0.1:8080", "Address to listen on")
+	cacheControl := flags.String("cache-control", "", "Cache-Control header for every response, e.g. \"public, max-age=3600\" (optional)")
+	noIndex := flags.Bool("no-index", false, "Do not render directory listings")
+	preindex := flags.Bool("preindex", false, "Index the directory tree in the background and answer lookups from it once ready")
+	preindexMemory := flags.Int64("preindex-memory", 256<<20, "With -preindex, approximate memory limit in bytes; beyond it only directories are indexed")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool serve -vhd <path_to_vhd> [-addr 127.0.0.1:8080] [-cache-control <value>] [-no-index] [-preindex]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *vhdPath == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	vhd, err := exfat.OpenVHD(*vhdPath, exfat.WithParentDir(*parentDir))
+	if err != nil {
+		fmt.Printf("Failed to open VHD file: %v\n", err)
+		os.Exit(1)
+	}
+	defer vhd.Close()
+
+	opts := httpfs.Options{CacheControl: *cacheControl, NoIndex: *noIndex}
+	if *preindex {
+		// 索引在后台建立，完成之前请求照常实时解析
+		var index atomic.Pointer[exfat.Index]
+		opts.Index = index.Load
+		go func() {
+			ix, err := vhd.BuildIndex(context.Background(), exfat.IndexOptions{MaxMemory: *preindexMemory})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "exfat-tool: directory index not built: %v\n", err)
+				return
+			}
+			index.Store(ix)
+			kind := "entries"
+			if ix.Skeleton() {
+				kind = "directories (memory limit reached, files are looked up live)"
+			}
+			fmt.Fprintf(os.Stderr, "Indexed %d %s\n", ix.Len(), kind)
+		}()
+	}
+	handler := httpfs.Handler(vhd.FileSystem(), opts)
+	fmt.Printf("Serving %s on http://%s/\n", *vhdPath, *addr)
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to serve: %v\n", err)
+		os.Exit(1)
+	}
+}
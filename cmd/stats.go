@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+)
+
+// runStats 实现 stats 子命令：按位图、FAT 和目录树统计已用空间并比较
+func runStats(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool stats -vhd <path_to_vhd>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *vhdPath == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	vhd, err := exfat.OpenVHD(*vhdPath, exfat.WithParentDir(*parentDir))
+	if err != nil {
+		fmt.Printf("Failed to open VHD file: %v\n", err)
+		os.Exit(1)
+	}
+	defer vhd.Close()
+
+	if err := cli.RunStats(os.Stdout, vhd); err != nil {
+		fmt.Printf("Failed to compute usage: %v\n", err)
+		os.Exit(1)
+	}
+}
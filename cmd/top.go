@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+)
+
+// runTop 实现 top 子命令：列出占用空间最多的文件和目录
+func runTop(args []string) {
+	flags := flag.NewFlagSet("top", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file")
+	root := flags.String("root", "/", "Directory inside the exFAT filesystem to analyse")
+	n := flags.Int("n", 10, "Number of files and directories to report")
+	allocated := flags.Bool("allocated", false, "Measure allocated cluster space instead of logical file size")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool top -vhd <path_to_vhd> [-n 10] [-allocated] [-root /]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *vhdPath == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	vhd, err := exfat.OpenVHD(*vhdPath, exfat.WithParentDir(*parentDir))
+	if err != nil {
+		fmt.Printf("Failed to open VHD file: %v\n", err)
+		os.Exit(1)
+	}
+	defer vhd.Close()
+
+	opts := cli.TopOptions{Root: *root, N: *n, Allocated: *allocated}
+	if err := cli.RunTop(os.Stdout, vhd, opts); err != nil {
+		fmt.Printf("Failed to analyse %s: %v\n", *root, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/cli"
+)
+
+// runTraceCheck 实现 trace-check 子命令：检查 -trace-reads 写出的读取跟踪
+func runTraceCheck(args []string) {
+	flags := flag.NewFlagSet("trace-check", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file the trace was recorded from")
+	partition := flags.Int("partition", 0, "Partition the trace was recorded from (numbered as in the partitions command)")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool trace-check -vhd <path_to_vhd> <trace.ndjson>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *vhdPath == "" || flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	opts := []exfat.Option{exfat.WithParentDir(*parentDir)}
+	if *partition != 0 {
+		opts = append(opts, exfat.WithPartition(*partition))
+	}
+	vhd, err := exfat.OpenVHD(*vhdPath, opts...)
+	if err != nil {
+		fmt.Printf("Failed to open VHD file: %v\n", err)
+		os.Exit(1)
+	}
+	defer vhd.Close()
+
+	trace, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Failed to open trace: %v\n", err)
+		os.Exit(1)
+	}
+	defer trace.Close()
+
+	err = cli.RunTraceCheck(os.Stdout, vhd, trace)
+	switch {
+	case err != nil && !errors.Is(err, cli.ErrPartial):
+		fmt.Printf("Failed to check trace: %v\n", err)
+		os.Exit(1)
+	case err != nil:
+		os.Exit(1)
+	}
+}
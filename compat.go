@@ -0,0 +1,120 @@
+package exfat
+
+import (
+	"io"
+
+	"github.com/0xXA/go-exfat/container"
+	exfatfs "github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/extract"
+)
+
+// 以下名称在拆分子包之前位于本包，保留为别名以兼容现有代码
+
+// 文件系统类型
+type (
+	FileEntry          = exfatfs.FileEntry
+	DirEntry           = exfatfs.DirEntry
+	ExFATBootSector    = exfatfs.ExFATBootSector
+	ExFATFileEntry     = exfatfs.ExFATFileEntry
+	ExFATFileInfoEntry = exfatfs.ExFATFileInfoEntry
+	ExFATFileNameEntry = exfatfs.ExFATFileNameEntry
+)
+
+// 磁盘映像类型
+type (
+	VHDHeader        = container.VHDHeader
+	VHDDynamicHeader = container.VHDDynamicHeader
+	VHDFile          = container.VHDFile
+	ChainLink        = container.ChainLink
+	Partition        = container.Partition
+	RawExportOptions = container.RawExportOptions
+)
+
+// exFAT 目录条目类型
+const (
+	EntryTypeEndOfDirectory   = exfatfs.EntryTypeEndOfDirectory
+	EntryTypeFile             = exfatfs.EntryTypeFile
+	EntryTypeVolumeLabel      = exfatfs.EntryTypeVolumeLabel
+	EntryTypeAllocationBitmap = exfatfs.EntryTypeAllocationBitmap
+	EntryTypeUpcaseTable      = exfatfs.EntryTypeUpcaseTable
+	EntryTypeFileInfo         = exfatfs.EntryTypeFileInfo
+	EntryTypeFileName         = exfatfs.EntryTypeFileName
+)
+
+// 特殊簇值
+const (
+	EndOfClusterChain = exfatfs.EndOfClusterChain
+	BadCluster        = exfatfs.BadCluster
+	ReservedCluster   = exfatfs.ReservedCluster
+)
+
+// 文件属性位
+const (
+	AttrReadOnly  = exfatfs.AttrReadOnly
+	AttrHidden    = exfatfs.AttrHidden
+	AttrSystem    = exfatfs.AttrSystem
+	AttrDirectory = exfatfs.AttrDirectory
+	AttrArchive   = exfatfs.AttrArchive
+)
+
+// 次要条目 GeneralSecondaryFlags 标志位
+const (
+	AllocationPossibleFlag = exfatfs.AllocationPossibleFlag
+	NoFatChainFlag         = exfatfs.NoFatChainFlag
+)
+
+// VHD 文件类型和常量
+const (
+	BlockUnallocated = container.BlockUnallocated
+	SectorSize       = container.SectorSize
+	FixedDisk        = container.FixedDisk
+	DynamicDisk      = container.DynamicDisk
+	DifferencingDisk = container.DifferencingDisk
+)
+
+// 父磁盘定位器平台代码
+const (
+	PlatformCodeNone = container.PlatformCodeNone
+	PlatformCodeWi2r = container.PlatformCodeWi2r
+	PlatformCodeWi2k = container.PlatformCodeWi2k
+	PlatformCodeW2ru = container.PlatformCodeW2ru
+	PlatformCodeW2ku = container.PlatformCodeW2ku
+	PlatformCodeMac  = container.PlatformCodeMac
+	PlatformCodeMacX = container.PlatformCodeMacX
+)
+
+// ExFATFileSystem 表示 exFAT 文件系统
+// 解析逻辑来自 exfat 子包，这里额外提供提取到本地文件系统的方法
+type ExFATFileSystem struct {
+	*exfatfs.ExFATFileSystem
+}
+
+// NewExFATFileSystem 创建新的 exFAT 文件系统实例
+func NewExFATFileSystem(vhd io.ReaderAt, opts ...Option) (*ExFATFileSystem, error) {
+	fs, err := exfatfs.NewExFATFileSystem(vhd, applyOptions(opts).fs...)
+	if err != nil {
+		return nil, err
+	}
+	return &ExFATFileSystem{fs}, nil
+}
+
+// ExtractFile 提取文件到本地路径
+func (fs *ExFATFileSystem) ExtractFile(srcPath, destPath string) error {
+	return extract.File(fs.ExFATFileSystem, srcPath, destPath)
+}
+
+// ExtractAllRecursive 递归提取目录内容到本地路径
+// 有条目提取失败时返回包装了 ErrIncomplete 的错误。
+func (fs *ExFATFileSystem) ExtractAllRecursive(srcPath, destPath string) error {
+	return extract.All(fs.ExFATFileSystem, srcPath, destPath)
+}
+
+// OpenVHDFile 打开一个 VHD 文件
+func OpenVHDFile(path string, opts ...Option) (*VHDFile, error) {
+	return container.OpenVHDFile(path, applyOptions(opts).container...)
+}
+
+// FormatFileSize 格式化文件大小显示
+func FormatFileSize(size int64) string {
+	return exfatfs.FormatFileSize(size)
+}
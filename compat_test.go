@@ -0,0 +1,132 @@
+package exfat_test
+
+import (
+	"io"
+	"testing"
+
+	exfat "github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/container"
+	exfatfs "github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/extract"
+)
+
+// 编译期检查：拆分子包之前的每个导出名称（compat.go）和根包的每个选项（options.go）都仍然存在，
+// 且别名与子包中的类型相同。删除或改变其中任何一个名称时本文件无法编译。
+
+// 文件系统和磁盘映像类型：别名必须与子包的类型可以互相赋值
+var (
+	_ exfatfs.FileEntry          = exfat.FileEntry{}
+	_ exfatfs.DirEntry           = exfat.DirEntry{}
+	_ exfatfs.ExFATBootSector    = exfat.ExFATBootSector{}
+	_ exfatfs.ExFATFileEntry     = exfat.ExFATFileEntry{}
+	_ exfatfs.ExFATFileInfoEntry = exfat.ExFATFileInfoEntry{}
+	_ exfatfs.ExFATFileNameEntry = exfat.ExFATFileNameEntry{}
+
+	_ container.VHDHeader        = exfat.VHDHeader{}
+	_ container.VHDDynamicHeader = exfat.VHDDynamicHeader{}
+	_ *container.VHDFile         = (*exfat.VHDFile)(nil)
+	_ container.ChainLink        = exfat.ChainLink{}
+	_ container.Partition        = exfat.Partition{}
+	_ container.RawExportOptions = exfat.RawExportOptions{}
+)
+
+// 兼容的函数和方法
+var (
+	_ func(string, ...exfat.Option) (*exfat.VHD, error)                  = exfat.OpenVHD
+	_ func(*exfat.VHD) error                                             = (*exfat.VHD).Close
+	_ func(*exfat.VHD, string) ([]exfat.FileEntry, error)                = (*exfat.VHD).ListDir
+	_ func(*exfat.VHD, string) ([]byte, error)                           = (*exfat.VHD).ReadFile
+	_ func(*exfat.VHD, string, string) error                             = (*exfat.VHD).ExtractFile
+	_ func(io.ReaderAt, ...exfat.Option) (*exfat.ExFATFileSystem, error) = exfat.NewExFATFileSystem
+	_ func(*exfat.ExFATFileSystem, string) ([]exfat.FileEntry, error)    = (*exfat.ExFATFileSystem).ListDir
+	_ func(*exfat.ExFATFileSystem, string) ([]byte, error)               = (*exfat.ExFATFileSystem).ReadFile
+	_ func(*exfat.ExFATFileSystem, string, string) error                 = (*exfat.ExFATFileSystem).ExtractFile
+	_ func(*exfat.ExFATFileSystem, string, string) error                 = (*exfat.ExFATFileSystem).ExtractAllRecursive
+	_ *exfatfs.ExFATFileSystem                                           = exfat.ExFATFileSystem{}.ExFATFileSystem
+	_ func(string, ...exfat.Option) (*exfat.VHDFile, error)              = exfat.OpenVHDFile
+	_ func(*exfat.VHDFile, []byte, int64) (int, error)                   = (*exfat.VHDFile).ReadAt
+	_ func(*exfat.VHDFile) int64                                         = (*exfat.VHDFile).Size
+	_ func(*exfat.VHDFile) error                                         = (*exfat.VHDFile).Close
+	_ func(int64) string                                                 = exfat.FormatFileSize
+)
+
+// 根包的打开选项和提取选项（options.go）
+var (
+	_ func(string) exfat.Option                                                   = exfat.WithParentDir
+	_ func() exfat.Option                                                         = exfat.WithoutProbe
+	_ func(int) exfat.Option                                                      = exfat.WithSectorSize
+	_ func() exfat.Option                                                         = exfat.WithWritable
+	_ func(int) exfat.Option                                                      = exfat.WithPartition
+	_ func() exfat.Option                                                         = exfat.WithBackupBootRecovery
+	_ func() exfat.Option                                                         = exfat.WithoutBootChecksum
+	_ func() exfat.Option                                                         = exfat.WithStrict
+	_ func() exfat.Option                                                         = exfat.WithFAT32StyleEOC
+	_ func(int) exfat.Option                                                      = exfat.WithMaxDirEntries
+	_ func(int) exfat.Option                                                      = exfat.WithMaxReadSize
+	_ func(exfat.ClusterFilter) exfat.Option                                      = exfat.WithClusterFilter
+	_ func(...exfat.ClusterRange) exfat.ClusterFilter                             = exfat.ExcludeClusterRanges
+	_ func(io.Reader) exfat.Option                                                = exfat.WithMetadataCache
+	_ func() exfat.Option                                                         = exfat.WithCrosslinkDetection
+	_ func(io.Writer) exfat.Option                                                = exfat.WithReadTracing
+	_ func(func(exfat.ManifestEntry)) exfat.ExtractOption                         = exfat.WithManifest
+	_ func(func(exfat.ExtractProgress)) exfat.ExtractOption                       = exfat.WithProgress
+	_ func() exfat.ExtractOption                                                  = exfat.WithRepairPlans
+	_ func(...string) exfat.ExtractOption                                         = exfat.WithMirrors
+	_ func(exfat.Layout) exfat.ExtractOption                                      = exfat.WithLayout
+	_ func(exfat.DateSource) exfat.ExtractOption                                  = exfat.WithDateSource
+	_ func(*exfat.ProgressAggregator, int) exfat.ExtractOption                    = exfat.WithAggregator
+	_ func(int) exfat.ExtractOption                                               = exfat.WithReportLimit
+	_ func(exfat.SampleLimits) exfat.ExtractOption                                = exfat.WithSample
+	_ func(*exfat.Sampler) exfat.ExtractOption                                    = exfat.WithSampler
+	_ func(...exfat.Preset) exfat.ExtractOption                                   = exfat.WithPreset
+	_ func(exfat.AppleDoubleMode) exfat.ExtractOption                             = exfat.WithAppleDouble
+	_ func(func(string, exfat.FileEntry) bool) exfat.ExtractOption                = exfat.WithFilter
+	_ func(func(string, exfat.FileEntry) exfat.TransformFunc) exfat.ExtractOption = exfat.WithTransform
+	_ func(int) exfat.TarGzOption                                                 = exfat.WithGzipLevel
+
+	_ extract.Option      = exfat.ExtractOption(nil)
+	_ extract.TarGzOption = exfat.TarGzOption(nil)
+)
+
+// 常量的值必须与子包相同
+func TestCompatConstants(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want uint64
+	}{
+		{"EntryTypeEndOfDirectory", exfat.EntryTypeEndOfDirectory, exfatfs.EntryTypeEndOfDirectory},
+		{"EntryTypeFile", exfat.EntryTypeFile, exfatfs.EntryTypeFile},
+		{"EntryTypeVolumeLabel", exfat.EntryTypeVolumeLabel, exfatfs.EntryTypeVolumeLabel},
+		{"EntryTypeAllocationBitmap", exfat.EntryTypeAllocationBitmap, exfatfs.EntryTypeAllocationBitmap},
+		{"EntryTypeUpcaseTable", exfat.EntryTypeUpcaseTable, exfatfs.EntryTypeUpcaseTable},
+		{"EntryTypeFileInfo", exfat.EntryTypeFileInfo, exfatfs.EntryTypeFileInfo},
+		{"EntryTypeFileName", exfat.EntryTypeFileName, exfatfs.EntryTypeFileName},
+		{"EndOfClusterChain", exfat.EndOfClusterChain, exfatfs.EndOfClusterChain},
+		{"BadCluster", exfat.BadCluster, exfatfs.BadCluster},
+		{"ReservedCluster", exfat.ReservedCluster, exfatfs.ReservedCluster},
+		{"AttrReadOnly", uint64(exfat.AttrReadOnly), uint64(exfatfs.AttrReadOnly)},
+		{"AttrHidden", uint64(exfat.AttrHidden), uint64(exfatfs.AttrHidden)},
+		{"AttrSystem", uint64(exfat.AttrSystem), uint64(exfatfs.AttrSystem)},
+		{"AttrDirectory", uint64(exfat.AttrDirectory), uint64(exfatfs.AttrDirectory)},
+		{"AttrArchive", uint64(exfat.AttrArchive), uint64(exfatfs.AttrArchive)},
+		{"AllocationPossibleFlag", exfat.AllocationPossibleFlag, exfatfs.AllocationPossibleFlag},
+		{"NoFatChainFlag", exfat.NoFatChainFlag, exfatfs.NoFatChainFlag},
+		{"BlockUnallocated", exfat.BlockUnallocated, container.BlockUnallocated},
+		{"SectorSize", exfat.SectorSize, container.SectorSize},
+		{"FixedDisk", exfat.FixedDisk, container.FixedDisk},
+		{"DynamicDisk", exfat.DynamicDisk, container.DynamicDisk},
+		{"DifferencingDisk", exfat.DifferencingDisk, container.DifferencingDisk},
+		{"PlatformCodeNone", exfat.PlatformCodeNone, container.PlatformCodeNone},
+		{"PlatformCodeWi2r", exfat.PlatformCodeWi2r, container.PlatformCodeWi2r},
+		{"PlatformCodeWi2k", exfat.PlatformCodeWi2k, container.PlatformCodeWi2k},
+		{"PlatformCodeW2ru", exfat.PlatformCodeW2ru, container.PlatformCodeW2ru},
+		{"PlatformCodeW2ku", exfat.PlatformCodeW2ku, container.PlatformCodeW2ku},
+		{"PlatformCodeMac", exfat.PlatformCodeMac, container.PlatformCodeMac},
+		{"PlatformCodeMacX", exfat.PlatformCodeMacX, container.PlatformCodeMacX},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %#x, want %#x", tt.name, tt.got, tt.want)
+		}
+	}
+}
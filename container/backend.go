@@ -0,0 +1,37 @@
+package container
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// ErrReadOnly 表示映像不能写入：没有以 WithWritable 打开，或者写入的位置不支持（如差分磁盘），
+// 可以用 errors.Is(err, fs.ErrPermission) 判断
+var ErrReadOnly = fmt.Errorf("disk image is read-only: %w", fs.ErrPermission)
+
+// Backend 表示一个可随机读取的磁盘映像
+// VHD（固定、动态、差分）和原始 exFAT 磁盘映像都通过 VHDFile 实现该接口
+type Backend interface {
+	io.ReaderAt
+	Size() int64 // 磁盘的逻辑大小
+	Close() error
+}
+
+var _ Backend = (*VHDFile)(nil)
+
+// Open 打开磁盘映像，自动识别 VHD 与原始 exFAT 映像
+// 磁盘带有分区表（MBR、GPT 或混合 MBR）时返回其中 exFAT 分区的 PartitionBackend。
+func Open(path string, opts ...Option) (Backend, error) {
+	o := applyOptions(opts)
+	vhd, err := openVHDFile(path, o, nil)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := selectPartition(vhd, o.partition)
+	if err != nil {
+		vhd.Close()
+		return nil, err
+	}
+	return backend, nil
+}
@@ -0,0 +1,310 @@
+package container
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// 差分链的最大深度，防止定位器互相引用造成无限递归
+const maxChainDepth = 64
+
+// ChainLink 描述差分链中的一个映像
+type ChainLink struct {
+	Path     string // 映像文件路径
+	Role     string // 在链中的角色："leaf"（打开的映像）、"intermediate" 或 "base"（最底层的基础磁盘）
+	DiskType string // "fixed"、"dynamic"、"differencing" 或 "raw"
+}
+
+// 父磁盘定位器数据的最大长度，防止按文件中的长度分配过大的缓冲区
+const maxLocatorData = 64 * 1024
+
+// checkpointName 匹配 Hyper-V 检查点的命名约定：disk_GUID.avhd
+// VHDX 格式的 .avhdx 检查点不支持（VHDX 映像在打开时被拒绝）
+var checkpointName = regexp.MustCompile(`^(.+)_[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}\.avhd$`)
+
+// ChainInfo 返回从打开的映像到基础磁盘的完整差分链
+func (v *VHDFile) ChainInfo() []ChainLink {
+	var links []ChainLink
+	for cur := v; cur != nil; cur = cur.parent {
+		role := "intermediate"
+		switch {
+		case cur.parent == nil:
+			role = "base"
+		case cur == v:
+			role = "leaf"
+		}
+		links = append(links, ChainLink{
+			Path:     cur.path,
+			Role:     role,
+			DiskType: cur.diskTypeName(),
+		})
+	}
+	return links
+}
+
+// diskTypeName 返回磁盘类型的名称
+func (v *VHDFile) diskTypeName() string {
+	if string(v.header.Cookie[:7]) == "rawdisk" {
+		return "raw"
+	}
+	switch v.header.DiskType {
+	case FixedDisk:
+		return "fixed"
+	case DynamicDisk:
+		return "dynamic"
+	case DifferencingDisk:
+		return "differencing"
+	}
+	return fmt.Sprintf("unknown(%d)", v.header.DiskType)
+}
+
+// openParent 解析父磁盘定位器并打开父磁盘
+func (v *VHDFile) openParent(opts *openOptions, chain []string) error {
+	if len(chain) > maxChainDepth {
+		return fmt.Errorf("differencing chain is deeper than %d images", maxChainDepth)
+	}
+
+	parentName := decodeUTF16BE(v.dynamicHeader.ParentUnicodeName[:])
+	candidates := v.parentCandidates(parentName, opts.parentDirs)
+	if parentName == "" && len(candidates) > 0 {
+		parentName = candidates[0]
+	}
+
+	var mismatched []string
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err != nil {
+			continue
+		}
+		if inChain(candidate, chain) {
+			return fmt.Errorf("differencing chain loops back to %s", candidate)
+		}
+
+		parent, err := openVHDFile(candidate, opts, chain)
+		if err != nil {
+			return fmt.Errorf("failed to open parent disk %s: %v", candidate, err)
+		}
+		if parent.header.UniqueID != v.dynamicHeader.ParentUniqueID {
+			// 同名但不是同一个磁盘（例如被重新创建过），继续查找
+			parent.Close()
+			mismatched = append(mismatched, candidate)
+			continue
+		}
+
+		v.parent = parent
+		return nil
+	}
+
+	if len(mismatched) > 0 {
+		return fmt.Errorf("parent disk %q of %s not found: candidates %s have a different unique ID",
+			parentName, v.path, strings.Join(mismatched, ", "))
+	}
+	return fmt.Errorf("parent disk %q of %s not found; looked in: %s",
+		parentName, v.path, strings.Join(candidates, ", "))
+}
+
+// parentCandidates 按优先级生成父磁盘可能所在的路径
+func (v *VHDFile) parentCandidates(parentName string, parentDirs []string) []string {
+	childDir := filepath.Dir(v.path)
+
+	var names []string // 定位器及头部中记录的父磁盘路径
+	for _, locator := range v.dynamicHeader.ParentLocators {
+		if locator.PlatformDataLength == 0 || locator.PlatformDataLength > locatorSpace(locator.PlatformDataSpace) {
+			continue
+		}
+		data := make([]byte, locator.PlatformDataLength)
+		if _, err := v.file.ReadAt(data, int64(locator.PlatformDataOffset)); err != nil && err != io.EOF {
+			continue
+		}
+
+		switch locator.PlatformCode {
+		case PlatformCodeW2ru, PlatformCodeW2ku:
+			names = append(names, decodeUTF16LE(data))
+		case PlatformCodeWi2r, PlatformCodeWi2k:
+			names = append(names, strings.TrimRight(string(data), "\x00"))
+		case PlatformCodeMacX:
+			if u, err := url.Parse(strings.TrimRight(string(data), "\x00")); err == nil && u.Path != "" {
+				names = append(names, u.Path)
+			}
+		}
+	}
+	if parentName != "" {
+		names = append(names, parentName)
+	}
+
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			candidates = append(candidates, p)
+		}
+	}
+
+	// 1. 定位器中的路径：相对路径相对于子磁盘所在目录，绝对路径原样使用
+	for _, name := range names {
+		local := filepath.FromSlash(strings.ReplaceAll(name, `\`, "/"))
+		if isWindowsAbs(name) || filepath.IsAbs(local) {
+			add(local)
+		} else {
+			add(filepath.Join(childDir, local))
+		}
+	}
+
+	// 2. 把 Windows 绝对路径重映射到子磁盘所在目录
+	var bases []string
+	for _, name := range names {
+		bases = append(bases, path.Base(strings.ReplaceAll(name, `\`, "/")))
+	}
+
+	// 3. Hyper-V 检查点命名约定：disk_GUID.avhd 的基础磁盘为 disk.vhd
+	if m := checkpointName.FindStringSubmatch(filepath.Base(v.path)); m != nil {
+		bases = append(bases, m[1]+".vhd")
+	}
+
+	for _, base := range bases {
+		add(filepath.Join(childDir, base))
+	}
+
+	// 4. 用户指定的父磁盘目录
+	for _, dir := range parentDirs {
+		for _, base := range bases {
+			add(filepath.Join(dir, base))
+		}
+	}
+
+	return candidates
+}
+
+// locatorSpace 返回定位器数据区的字节数
+// 规范中 PlatformDataSpace 以扇区为单位，Hyper-V 等实现写入的是字节数；小于一个扇区的值按扇区数解释。
+// 结果不超过 maxLocatorData。
+func locatorSpace(space uint32) uint32 {
+	if space < SectorSize {
+		space = min(space, maxLocatorData/SectorSize) * SectorSize
+	}
+	return min(space, maxLocatorData)
+}
+
+// readDifferencingBlock 读取差分磁盘中已分配块的数据
+// 扇区位图中置位的扇区由本磁盘提供，其余扇区从父磁盘读取
+func (v *VHDFile) readDifferencingBlock(blockIndex uint32, blockOffset int64, buf []byte, offset int64) error {
+	bitmap, err := v.blockBitmap(blockIndex)
+	if err != nil {
+		return err
+	}
+
+	dataOffset := int64(v.bat[blockIndex])*v.sectorSize + v.bitmapSize
+	for len(buf) > 0 {
+		// 找出状态相同的连续扇区，合并成一次读取
+		sector := blockOffset / v.sectorSize
+		present := sectorPresent(bitmap, sector)
+		n := int64(0)
+		for n < int64(len(buf)) {
+			if sectorPresent(bitmap, (blockOffset+n)/v.sectorSize) != present {
+				break
+			}
+			n += v.sectorSize - (blockOffset+n)%v.sectorSize
+		}
+		if n > int64(len(buf)) {
+			n = int64(len(buf))
+		}
+
+		if present {
+			_, err = v.readFile(buf[:n], dataOffset+blockOffset, offset, "data")
+		} else {
+			_, err = v.parent.ReadAt(buf[:n], offset)
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		buf = buf[n:]
+		blockOffset += n
+		offset += n
+	}
+
+	return nil
+}
+
+// blockBitmap 读取（并缓存）块的扇区位图
+func (v *VHDFile) blockBitmap(blockIndex uint32) ([]byte, error) {
+	v.bitmapMu.Lock()
+	defer v.bitmapMu.Unlock()
+
+	if bitmap, ok := v.bitmapCache[blockIndex]; ok {
+		return bitmap, nil
+	}
+
+	bitmap := make([]byte, v.bitmapSize)
+	logical := int64(blockIndex) * int64(v.blockSize)
+	if _, err := v.readFile(bitmap, int64(v.bat[blockIndex])*v.sectorSize, logical, "sector-bitmap"); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read sector bitmap of block %d: %v", blockIndex, err)
+	}
+
+	if v.bitmapCache == nil {
+		v.bitmapCache = make(map[uint32][]byte)
+	}
+	v.bitmapCache[blockIndex] = bitmap
+	return bitmap, nil
+}
+
+// sectorPresent 检查扇区位图中某个扇区是否置位（高位在前）
+func sectorPresent(bitmap []byte, sector int64) bool {
+	if sector/8 >= int64(len(bitmap)) {
+		return false
+	}
+	return bitmap[sector/8]&(0x80>>uint(sector%8)) != 0
+}
+
+// inChain 检查路径是否已在差分链中
+func inChain(p string, chain []string) bool {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		abs = p
+	}
+	for _, c := range chain {
+		if cabs, err := filepath.Abs(c); err == nil && cabs == abs {
+			return true
+		}
+	}
+	return false
+}
+
+// isWindowsAbs 检查是否为 Windows 绝对路径（如 C:\dir\disk.vhd 或 \\server\share）
+func isWindowsAbs(p string) bool {
+	return (len(p) >= 3 && p[1] == ':' && (p[2] == '\\' || p[2] == '/')) || strings.HasPrefix(p, `\\`)
+}
+
+// decodeUTF16LE 解码以 NUL 结尾的 UTF-16LE 字符串
+func decodeUTF16LE(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		u := binary.LittleEndian.Uint16(data[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
+
+// decodeUTF16BE 解码以 NUL 结尾的 UTF-16BE 字符串
+func decodeUTF16BE(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		u := binary.BigEndian.Uint16(data[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
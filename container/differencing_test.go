@@ -0,0 +1,183 @@
+package container
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// 差分链测试使用的检查点名称
+const (
+	checkpoint1 = "disk_0A1B2C3D-1111-2222-3333-444455556666.avhd"
+	checkpoint2 = "disk_0A1B2C3D-7777-8888-9999-AAAABBBBCCCC.avhd"
+)
+
+// chainVersions 返回三个版本的卷内容：同样的布局，a.txt 的内容不同
+func chainVersions() (base, mid, leaf []byte) {
+	build := func(text string) []byte {
+		return testimage.Build(testimage.Options{ClusterCount: 512},
+			testimage.Dir("DCIM", testimage.File("a.txt", []byte(text))), testimage.File("same.txt", []byte("unchanged"))).Bytes
+	}
+	return build("base version"), build("mid version!"), build("leaf version")
+}
+
+// writeFile 把 data 写入 dir 中的 name，返回路径
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// readText 通过 exFAT 读取 VHD 中的文件
+func readText(t *testing.T, v *VHDFile, p string) string {
+	t.Helper()
+	fs, err := exfat.NewExFATFileSystem(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := fs.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestDifferencingChain(t *testing.T) {
+	base, mid, leaf := chainVersions()
+	midID := [16]byte([]byte("checkpoint-one.."))
+	dir := t.TempDir()
+	basePath := writeFile(t, dir, "disk.vhd", testimage.DynamicVHD(base, 64<<10, 512))
+	midPath := writeFile(t, dir, checkpoint1, testimage.DifferencingVHD(mid, base, 64<<10, midID,
+		testimage.Parent{ID: testimage.VHDID, Name: "disk.vhd", Relative: `.\disk.vhd`}))
+	leafPath := writeFile(t, dir, checkpoint2, testimage.DifferencingVHD(leaf, mid, 64<<10, [16]byte([]byte("checkpoint-two..")),
+		testimage.Parent{ID: midID, Name: checkpoint1, Relative: `.\` + checkpoint1}))
+
+	v, err := OpenVHDFile(leafPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+	if !bytes.Equal(readAll(t, v), leaf) {
+		t.Error("merged view differs from the newest checkpoint")
+	}
+	if got := readText(t, v, "/DCIM/a.txt"); got != "leaf version" {
+		t.Errorf("a.txt = %q", got)
+	}
+
+	want := []ChainLink{
+		{leafPath, "leaf", "differencing"},
+		{midPath, "intermediate", "differencing"},
+		{basePath, "base", "dynamic"},
+	}
+	got := v.ChainInfo()
+	if len(got) != len(want) {
+		t.Fatalf("ChainInfo() = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("link %d: %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// 打开中间的检查点：看到的是它自己的版本
+	m, err := OpenVHDFile(midPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if got := readText(t, m, "/DCIM/a.txt"); got != "mid version!" || len(m.ChainInfo()) != 2 {
+		t.Errorf("intermediate: a.txt = %q, chain %+v", got, m.ChainInfo())
+	}
+}
+
+func TestParentLocatorResolution(t *testing.T) {
+	base, _, leaf := chainVersions()
+	tests := []struct {
+		name      string
+		child     string // 子磁盘在临时目录中的路径
+		parent    testimage.Parent
+		parentDir bool // 父磁盘放在单独的目录中，通过 WithParentDir 查找
+	}{
+		{"relative locator", "vm/child.vhd", testimage.Parent{Relative: `..\base\disk.vhd`}, false},
+		{"windows absolute remapped", "child.vhd", testimage.Parent{Absolute: `C:\Hyper-V\Old\disk.vhd`}, false},
+		{"unicode name only", "child.vhd", testimage.Parent{Name: `D:\VMs\disk.vhd`}, false},
+		{"checkpoint naming", checkpoint1, testimage.Parent{}, false},
+		{"stale locators with parent dir", "child.vhd",
+			testimage.Parent{Name: "disk.vhd", Relative: `..\moved\disk.vhd`, Absolute: `C:\VMs\disk.vhd`}, true},
+		{"oversized locator length", "child.vhd",
+			testimage.Parent{Name: "disk.vhd", Relative: `missing.vhd`, LocatorLength: 0xFFFFFFF0}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.parent.ID = testimage.VHDID
+			childPath := writeFile(t, dir, tt.child, testimage.DifferencingVHD(leaf, base, 64<<10, [16]byte{1}, tt.parent))
+			parentDir := filepath.Dir(childPath)
+			switch {
+			case tt.parentDir:
+				parentDir = filepath.Join(dir, "parents")
+			case strings.HasPrefix(tt.parent.Relative, `..\base`):
+				parentDir = filepath.Join(dir, "base")
+			}
+			basePath := writeFile(t, parentDir, "disk.vhd", testimage.DynamicVHD(base, 64<<10, 512))
+
+			var opts []Option
+			if tt.parentDir {
+				if _, err := OpenVHDFile(childPath); err == nil {
+					t.Fatal("opened without -parent-dir")
+				}
+				opts = append(opts, WithParentDir(parentDir))
+			}
+			v, err := OpenVHDFile(childPath, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer v.Close()
+			if chain := v.ChainInfo(); len(chain) != 2 || chain[1].Path != basePath || chain[1].Role != "base" {
+				t.Errorf("ChainInfo() = %+v, want base %s", chain, basePath)
+			}
+			if got := readText(t, v, "/DCIM/a.txt"); got != "leaf version" {
+				t.Errorf("a.txt = %q", got)
+			}
+		})
+	}
+}
+
+func TestMissingParent(t *testing.T) {
+	base, _, leaf := chainVersions()
+	dir := t.TempDir()
+	parentDir := t.TempDir()
+	childPath := writeFile(t, dir, "child.vhd", testimage.DifferencingVHD(leaf, base, 64<<10, [16]byte{1},
+		testimage.Parent{ID: testimage.VHDID, Name: "disk.vhd", Absolute: `C:\VMs\disk.vhd`}))
+
+	_, err := OpenVHDFile(childPath, WithParentDir(parentDir))
+	if err == nil {
+		t.Fatal("opened a differencing disk without its parent")
+	}
+	// 错误中写明缺少哪个父磁盘以及查找过的每个位置
+	for _, want := range []string{`"disk.vhd"`, childPath, filepath.Join(dir, "disk.vhd"), filepath.Join(parentDir, "disk.vhd")} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+
+	// 同名但唯一标识不同的磁盘不是父磁盘
+	writeFile(t, dir, "disk.vhd", testimage.DynamicVHD(base, 64<<10, 512))
+	other := testimage.DifferencingVHD(leaf, base, 64<<10, [16]byte{2},
+		testimage.Parent{ID: [16]byte{9}, Name: "disk.vhd"})
+	_, err = OpenVHDFile(writeFile(t, dir, "other.vhd", other))
+	if err == nil || !strings.Contains(err.Error(), "different unique ID") {
+		t.Errorf("unique ID mismatch: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// 导出时每次读取的最大字节数
+const exportChunkSize = 1 << 20
+
+// RawExportOptions ExportRawContext 的选项
+type RawExportOptions struct {
+	Progress       func(done int64) // 每写出（或跳过）一段数据后以已完成的字节数调用（可选）
+	BytesPerSecond int64            // 读取映像的速度上限，0 表示不限制；未分配的块不读取，不计入限速
+}
+
+// ExportRaw 把磁盘上从 start 开始的 length 字节（经过 VHD 的块转换后的逻辑数据）写到 w
+// 与 ExportRawContext 相同，不能取消、不限速。
+func (v *VHDFile) ExportRaw(w io.Writer, start, length int64, progress func(done int64)) error {
+	return v.ExportRawContext(context.Background(), w, start, length, RawExportOptions{Progress: progress})
+}
+
+// ExportRawContext 把磁盘上从 start 开始的 length 字节写到 w，如导出一个分区供其他工具使用
+// 动态和差分磁盘中未分配的块不读取，直接作为零写出；w 是 *os.File 且可以定位时，全零的数据
+// （包括读出为零的已分配数据）改为向后定位，生成稀疏文件，最后按需要截断到完整的长度；
+// 这时文件在写入位置之后应当为空（新建或截断的文件），否则跳过的范围保留原有的数据。
+// ctx 取消时停止并返回 ctx.Err()，已写出的数据保留。范围超出磁盘时返回错误，不写出任何数据。
+func (v *VHDFile) ExportRawContext(ctx context.Context, w io.Writer, start, length int64, opts RawExportOptions) error {
+	// length 可能很大，与剩余的长度比较，避免 start+length 溢出
+	if start < 0 || length < 0 || start > v.Size() || length > v.Size()-start {
+		return fmt.Errorf("range %d+%d is outside the %d-byte disk", start, length, v.Size())
+	}
+
+	out := newSparseWriter(w)
+	buf := make([]byte, exportChunkSize)
+	began := time.Now()
+	var done, read int64
+	for done < length {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		offset := start + done
+		n := min(length-done, exportChunkSize)
+		if v.isDynamic {
+			// 每段不跨越块，未分配的块整段跳过
+			n = min(n, int64(v.blockSize)-offset%int64(v.blockSize))
+		}
+		chunk := buf[:n]
+
+		if v.unallocated(offset, n) {
+			if err := out.zeros(n); err != nil {
+				return err
+			}
+		} else {
+			if _, err := v.ReadAt(chunk, offset); err != nil {
+				return fmt.Errorf("failed to read disk at %d: %v", offset, err)
+			}
+			if err := out.write(chunk); err != nil {
+				return err
+			}
+			read += n
+			if err := throttle(ctx, began, read, opts.BytesPerSecond); err != nil {
+				return err
+			}
+		}
+
+		done += n
+		if opts.Progress != nil {
+			opts.Progress(done)
+		}
+	}
+	return out.finish()
+}
+
+// unallocated 判断 [offset, offset+n) 是否位于同一个在整个差分链上都未分配的块中，读取结果必然为零
+func (v *VHDFile) unallocated(offset, n int64) bool {
+	if !v.isDynamic {
+		return false
+	}
+	first, last := offset/int64(v.blockSize), (offset+n-1)/int64(v.blockSize)
+	if first != last || first >= int64(len(v.bat)) || v.bat[first] != BlockUnallocated {
+		return false
+	}
+	return v.parent == nil || v.parent.unallocated(offset, n)
+}
+
+// throttle 按速度上限等待，使读取 read 字节所用的时间不少于 read/bytesPerSecond
+func throttle(ctx context.Context, began time.Time, read, bytesPerSecond int64) error {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	due := began.Add(time.Duration(float64(read) / float64(bytesPerSecond) * float64(time.Second)))
+	wait := time.Until(due)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// sparseWriter 写出导出的数据；目标是可以定位的文件时，全零的数据改为定位跳过
+type sparseWriter struct {
+	w       io.Writer
+	file    *os.File // 可以定位的目标文件，否则为 nil
+	pending int64    // 已跳过、尚未写出的零字节数
+	zero    []byte
+}
+
+func newSparseWriter(w io.Writer) *sparseWriter {
+	s := &sparseWriter{w: w}
+	if f, ok := w.(*os.File); ok {
+		// 管道和终端不能定位，按普通 writer 写出零
+		if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+			if _, err := f.Seek(0, io.SeekCurrent); err == nil {
+				s.file = f
+			}
+		}
+	}
+	return s
+}
+
+// write 写出数据，全零的数据按零处理
+func (s *sparseWriter) write(p []byte) error {
+	if s.file != nil && isZero(p) {
+		return s.zeros(int64(len(p)))
+	}
+	if err := s.flush(); err != nil {
+		return err
+	}
+	_, err := s.w.Write(p)
+	return err
+}
+
+// zeros 写出 n 个零字节：稀疏模式下只记录，在下一次写入或结束时定位
+func (s *sparseWriter) zeros(n int64) error {
+	if s.file != nil {
+		s.pending += n
+		return nil
+	}
+	if s.zero == nil {
+		s.zero = make([]byte, exportChunkSize)
+	}
+	for n > 0 {
+		m := min(n, int64(len(s.zero)))
+		if _, err := s.w.Write(s.zero[:m]); err != nil {
+			return err
+		}
+		n -= m
+	}
+	return nil
+}
+
+// flush 向后定位越过已跳过的零
+func (s *sparseWriter) flush() error {
+	if s.pending == 0 {
+		return nil
+	}
+	_, err := s.file.Seek(s.pending, io.SeekCurrent)
+	s.pending = 0
+	return err
+}
+
+// finish 结束导出：数据以零结尾时把文件截断（扩展）到完整的长度
+func (s *sparseWriter) finish() error {
+	if s.pending == 0 {
+		return nil
+	}
+	end, err := s.file.Seek(s.pending, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	s.pending = 0
+	if info, err := s.file.Stat(); err != nil || info.Size() >= end {
+		return err
+	}
+	return s.file.Truncate(end)
+}
+
+// isZero 判断数据是否全为零
+func isZero(p []byte) bool {
+	for len(p) > 0 {
+		n := min(len(p), len(zeroPage))
+		if !bytes.Equal(p[:n], zeroPage[:n]) {
+			return false
+		}
+		p = p[n:]
+	}
+	return true
+}
+
+// zeroPage 用于比较的全零数据
+var zeroPage = make([]byte, 4096)
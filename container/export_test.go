@@ -0,0 +1,122 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// exportFixture 返回动态 VHD 及其磁盘内容：卷之后有大段未分配的块，导出时不读取
+func exportFixture(t *testing.T) (*VHDFile, []byte) {
+	t.Helper()
+	img := testimage.Build(testimage.Options{ClusterCount: 512},
+		testimage.Dir("DCIM", testimage.File("a.jpg", bytes.Repeat([]byte("exported "), 700))))
+	const size = 4 << 20
+	v, err := OpenVHDFile(writeTemp(t, "dynamic.vhd", testimage.SparseDynamicVHD(img.Bytes, size, 64<<10, 512)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { v.Close() })
+	disk := make([]byte, size)
+	copy(disk, img.Bytes)
+	return v, disk
+}
+
+func TestExportRawRoundTrip(t *testing.T) {
+	v, disk := exportFixture(t)
+	var buf bytes.Buffer
+	var last int64
+	if err := v.ExportRaw(&buf, 0, v.Size(), func(done int64) { last = done }); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), disk) || last != v.Size() {
+		t.Fatalf("exported %d bytes (progress %d), want the %d-byte disk", buf.Len(), last, len(disk))
+	}
+
+	// 导出的平面文件可以作为原始映像重新打开
+	flat, err := OpenVHDFile(writeTemp(t, "flat.img", buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer flat.Close()
+	fs, err := exfat.NewExFATFileSystem(flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fs.ReadFile("/DCIM/a.jpg"); err != nil || !bytes.Equal(got, bytes.Repeat([]byte("exported "), 700)) {
+		t.Errorf("ReadFile from the flat copy: %d bytes, %v", len(got), err)
+	}
+
+	// 部分范围
+	buf.Reset()
+	if err := v.ExportRaw(&buf, 1000, 70000, nil); err != nil || !bytes.Equal(buf.Bytes(), disk[1000:71000]) {
+		t.Errorf("range 1000+70000: %d bytes, %v", buf.Len(), err)
+	}
+}
+
+func TestExportRawSparseFile(t *testing.T) {
+	v, disk := exportFixture(t)
+	path := filepath.Join(t.TempDir(), "sparse.img")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 磁盘以未分配的块结尾：文件靠最后的截断扩展到完整的长度
+	if err := v.ExportRaw(f, 0, v.Size(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, disk) {
+		t.Errorf("sparse export: %d bytes, want %d identical bytes", len(got), len(disk))
+	}
+
+	// 写在文件中间：定位从当前位置开始，已有的前缀保留
+	f, err = os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte("prefix")); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.ExportRaw(f, 0, 1<<20, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, append([]byte("prefix"), disk[:1<<20]...)) {
+		t.Errorf("export after a prefix: %d bytes, %v", len(got), err)
+	}
+}
+
+func TestExportRawErrors(t *testing.T) {
+	v, _ := exportFixture(t)
+	size := v.Size()
+	for _, r := range [][2]int64{{-1, 10}, {0, -1}, {0, size + 1}, {size, 1}, {size + 1, 0}, {1, math.MaxInt64}, {math.MaxInt64, 1}} {
+		var buf bytes.Buffer
+		if err := v.ExportRaw(&buf, r[0], r[1], nil); err == nil || buf.Len() != 0 {
+			t.Errorf("range %d+%d: %v, wrote %d bytes", r[0], r[1], err, buf.Len())
+		}
+	}
+	var buf bytes.Buffer
+	if err := v.ExportRaw(&buf, size, 0, nil); err != nil || buf.Len() != 0 {
+		t.Errorf("empty range at the end: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := v.ExportRawContext(ctx, &buf, 0, size, RawExportOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled export: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package container
+
+// Option 配置 VHD 映像的打开方式
+type Option func(*openOptions)
+
+// openOptions 打开选项
+type openOptions struct {
+	parentDirs []string // 额外查找父磁盘的目录
+	noProbe    bool     // 不探测原始映像前的厂商头部
+	sectorSize int64    // 动态磁盘 BAT 与扇区位图使用的扇区大小（0 表示自动检测）
+	writable   bool     // 以读写方式打开映像（差分磁盘的父磁盘始终只读）
+	partition  int      // 打开的分区序号（0 表示自动选择）
+	tracer     ReadTracer
+}
+
+// WithParentDir 添加一个查找差分磁盘父映像的目录
+// 当父磁盘定位器中记录的路径已失效时（例如映像被复制到其他机器），会在这些目录中按文件名查找
+func WithParentDir(dir string) Option {
+	return func(o *openOptions) {
+		if dir != "" {
+			o.parentDirs = append(o.parentDirs, dir)
+		}
+	}
+}
+
+// WithoutProbe 禁止在原始映像开头探测厂商头部
+// 默认情况下，若偏移 0 处不是 exFAT 引导扇区，会在前 64 KiB 内按扇区对齐查找
+func WithoutProbe() Option {
+	return func(o *openOptions) {
+		o.noProbe = true
+	}
+}
+
+// WithSectorSize 指定动态磁盘 BAT 与扇区位图使用的扇区大小（512 或 4096）
+// 默认根据 BAT 项是否落在文件内以及首个数据块的内容自动检测
+func WithSectorSize(size int) Option {
+	return func(o *openOptions) {
+		o.sectorSize = int64(size)
+	}
+}
+
+// WithWritable 以读写方式打开映像，使 VHDFile.WriteAt 可用
+// 写入只支持原始映像、固定磁盘和动态磁盘中已分配的块；差分磁盘的父磁盘始终以只读方式打开。
+func WithWritable() Option {
+	return func(o *openOptions) {
+		o.writable = true
+	}
+}
+
+// WithPartition 打开磁盘分区表中指定序号（从 1 开始，见 ListPartitions）的分区
+// 默认在磁盘开头不是 exFAT 卷时选择第一个探测到 exFAT 的分区，不论分区表中声明的类型。
+func WithPartition(index int) Option {
+	return func(o *openOptions) {
+		o.partition = index
+	}
+}
+
+// ReadTracer 接收一次对底层数据的读取：所在的层、层内的逻辑偏移、下一层的物理偏移、长度和用途
+// 与 exfat.ReadTracer 的参数相同，两层可以共用同一个函数记录完整的读取路径。
+type ReadTracer func(layer string, logical, physical, length int64, purpose string)
+
+// WithReadTracer 在每次读取映像文件和分区时调用 fn，用于调试偏移换算
+// VHD 的层名为 "vhd:" 加文件名（差分链中的每个磁盘分别记录），逻辑偏移是磁盘上的偏移，物理偏移是文件中的偏移，
+// 用途为 "data"（块数据或固定磁盘的数据）或 "sector-bitmap"（差分磁盘块前的扇区位图）；
+// 分区的层名为 "partition"，逻辑偏移是分区内的偏移，物理偏移是磁盘上的偏移。未分配的块不读取，不记录。
+// fn 为 nil 时没有额外开销；fn 可能被多个 goroutine 同时调用。
+func WithReadTracer(fn ReadTracer) Option {
+	return func(o *openOptions) {
+		o.tracer = fn
+	}
+}
+
+// applyOptions 汇总选项
+func applyOptions(opts []Option) *openOptions {
+	o := &openOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
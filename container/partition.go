@@ -0,0 +1,318 @@
+package container
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// 分区中探测到的文件系统（见 Partition.Content）
+const (
+	ContentExFAT = "exFAT"
+	ContentNTFS  = "NTFS"
+	ContentFAT32 = "FAT32"
+	ContentFAT   = "FAT"
+)
+
+// Partition 磁盘分区表中的一个分区
+// 相机和游戏机写出的分区表中声明的类型经常与实际内容不符（如标为 0x07 NTFS 或 0x0C FAT32 的 exFAT 分区），
+// 因此除了声明的类型，还按分区开头的引导扇区记录实际内容。
+type Partition struct {
+	Index    int    // 从 1 开始的序号：先是 GPT 的分区，再是 MBR 中不与之重复的分区
+	Scheme   string // "GPT" 或 "MBR"
+	Type     string // 声明的类型：MBR 为 "0x07" 形式，GPT 为类型 GUID
+	TypeName string // 声明类型的常用名称，未知类型为空
+	Name     string // GPT 分区名，MBR 分区为空
+	Content  string // 按引导扇区探测到的文件系统（Content* 常量），无法识别时为空
+	Offset   int64  // 分区在磁盘上的字节偏移
+	Size     int64  // 分区的字节数
+}
+
+// ExFAT 返回分区中是否探测到 exFAT 文件系统（与声明的类型无关）
+func (p Partition) ExFAT() bool {
+	return p.Content == ContentExFAT
+}
+
+// String 返回分区的单行描述
+func (p Partition) String() string {
+	declared := p.Type
+	if p.TypeName != "" {
+		declared += " " + p.TypeName
+	}
+	content := p.Content
+	if content == "" {
+		content = "unknown"
+	}
+	return fmt.Sprintf("#%d %s type %s, contains %s, offset %d, %d bytes", p.Index, p.Scheme, declared, content, p.Offset, p.Size)
+}
+
+// mbrTypeNames 常见 MBR 分区类型的名称
+var mbrTypeNames = map[byte]string{
+	0x01: "FAT12",
+	0x04: "FAT16",
+	0x05: "extended",
+	0x06: "FAT16",
+	0x07: "NTFS/exFAT",
+	0x0B: "FAT32",
+	0x0C: "FAT32 (LBA)",
+	0x0E: "FAT16 (LBA)",
+	0x0F: "extended (LBA)",
+	0x83: "Linux",
+	0xEE: "GPT protective",
+	0xEF: "EFI system",
+}
+
+// gptTypeNames 常见 GPT 分区类型 GUID 的名称
+var gptTypeNames = map[string]string{
+	"EBD0A0A2-B9E5-4433-87C0-68B6B72699C7": "Microsoft basic data",
+	"C12A7328-F81F-11D2-BA4B-00A0C93EC93B": "EFI system",
+	"E3C9E316-0B5C-4DB8-817D-F92DF00215AE": "Microsoft reserved",
+	"0FC63DAF-8483-4772-8E79-3D69D8477DE4": "Linux filesystem",
+}
+
+// 解析 GPT 时的上限，防止损坏的头部导致过大的分配
+const (
+	maxGPTEntries   = 1024
+	maxGPTEntrySize = 4096
+)
+
+// ListPartitions 读取磁盘开头的 MBR 和 GPT，返回其中的分区
+// 分区按内容而不是声明的类型识别：每个分区都读取开头的引导扇区探测文件系统。
+// 混合 MBR（0xEE 保护项加普通分区项）中的普通分区项同样列出，与 GPT 分区重复的只列出一次。
+// 磁盘开头就是卷的引导扇区（没有分区表）时返回空列表。
+func ListPartitions(r io.ReaderAt) ([]Partition, error) {
+	mbr := make([]byte, SectorSize)
+	if _, err := r.ReadAt(mbr, 0); err != nil {
+		return nil, fmt.Errorf("failed to read MBR: %v", err)
+	}
+	if detectContent(mbr) != "" || mbr[510] != 0x55 || mbr[511] != 0xAA {
+		return nil, nil
+	}
+
+	var parts []Partition
+	sectorSize := int64(SectorSize)
+	protective := false
+	for i := 0; i < 4; i++ {
+		if mbr[446+i*16+4] == 0xEE {
+			protective = true
+		}
+	}
+	if protective {
+		gpt, size, err := readGPT(r)
+		if err != nil {
+			return nil, err
+		}
+		parts, sectorSize = gpt, size
+	}
+
+	for i := 0; i < 4; i++ {
+		e := mbr[446+i*16:]
+		typ := e[4]
+		start, count := int64(binary.LittleEndian.Uint32(e[8:])), int64(binary.LittleEndian.Uint32(e[12:]))
+		if typ == 0 || typ == 0xEE || count == 0 {
+			continue
+		}
+		p := Partition{
+			Scheme:   "MBR",
+			Type:     fmt.Sprintf("0x%02X", typ),
+			TypeName: mbrTypeNames[typ],
+			Offset:   start * sectorSize,
+			Size:     count * sectorSize,
+		}
+		if !duplicate(parts, p) {
+			parts = append(parts, p)
+		}
+	}
+
+	sector := make([]byte, SectorSize)
+	for i := range parts {
+		parts[i].Index = i + 1
+		if n, _ := r.ReadAt(sector, parts[i].Offset); n == len(sector) {
+			parts[i].Content = detectContent(sector)
+		}
+	}
+	return parts, nil
+}
+
+// duplicate 判断 p 是否与 parts 中的某个分区指向同一位置（混合 MBR 重复列出 GPT 分区）
+func duplicate(parts []Partition, p Partition) bool {
+	for _, q := range parts {
+		if q.Offset == p.Offset {
+			return true
+		}
+	}
+	return false
+}
+
+// readGPT 读取 GPT 头部和分区项，返回分区和 GPT 使用的扇区大小
+// 头部按 512 字节扇区查找，找不到时按 4096 字节扇区（4Kn 磁盘）查找。
+func readGPT(r io.ReaderAt) ([]Partition, int64, error) {
+	header := make([]byte, 92)
+	sectorSize := int64(0)
+	for _, size := range []int64{SectorSize, 4096} {
+		if _, err := r.ReadAt(header, size); err == nil && string(header[:8]) == "EFI PART" {
+			sectorSize = size
+			break
+		}
+	}
+	if sectorSize == 0 {
+		// 只有保护项、没有可用 GPT 头部时仍然列出 MBR 中的其他分区
+		return nil, SectorSize, nil
+	}
+
+	entryLBA := int64(binary.LittleEndian.Uint64(header[72:]))
+	count := binary.LittleEndian.Uint32(header[80:])
+	entrySize := binary.LittleEndian.Uint32(header[84:])
+	if count > maxGPTEntries || entrySize < 128 || entrySize > maxGPTEntrySize {
+		return nil, 0, fmt.Errorf("invalid GPT header: %d entries of %d bytes", count, entrySize)
+	}
+	table := make([]byte, int64(count)*int64(entrySize))
+	if _, err := r.ReadAt(table, entryLBA*sectorSize); err != nil {
+		return nil, 0, fmt.Errorf("failed to read GPT entries: %v", err)
+	}
+
+	var parts []Partition
+	for i := uint32(0); i < count; i++ {
+		e := table[i*entrySize : (i+1)*entrySize]
+		typ := formatGUID(e[:16])
+		if typ == "00000000-0000-0000-0000-000000000000" {
+			continue
+		}
+		first, last := int64(binary.LittleEndian.Uint64(e[32:])), int64(binary.LittleEndian.Uint64(e[40:]))
+		if last < first {
+			continue
+		}
+		units := make([]uint16, 36)
+		for j := range units {
+			units[j] = binary.LittleEndian.Uint16(e[56+j*2:])
+		}
+		for len(units) > 0 && units[len(units)-1] == 0 {
+			units = units[:len(units)-1]
+		}
+		parts = append(parts, Partition{
+			Scheme:   "GPT",
+			Type:     typ,
+			TypeName: gptTypeNames[typ],
+			Name:     string(utf16.Decode(units)),
+			Offset:   first * sectorSize,
+			Size:     (last - first + 1) * sectorSize,
+		})
+	}
+	return parts, sectorSize, nil
+}
+
+// formatGUID 按混合字节序把 16 字节的 GUID 格式化为大写字符串
+func formatGUID(b []byte) string {
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X",
+		binary.LittleEndian.Uint32(b[0:]), binary.LittleEndian.Uint16(b[4:]), binary.LittleEndian.Uint16(b[6:]), b[8:10], b[10:16])
+}
+
+// detectContent 按引导扇区识别文件系统，无法识别时返回空串
+func detectContent(sector []byte) string {
+	switch {
+	case len(sector) < 90:
+		return ""
+	case string(sector[3:11]) == "EXFAT   ":
+		return ContentExFAT
+	case string(sector[3:11]) == "NTFS    ":
+		return ContentNTFS
+	case string(sector[82:90]) == "FAT32   ":
+		return ContentFAT32
+	case string(sector[54:57]) == "FAT":
+		return ContentFAT
+	}
+	return ""
+}
+
+// PartitionBackend 磁盘中的一个分区，读取范围限制在分区之内
+// Open 在磁盘开头不是 exFAT 卷时自动选择分区（见 WithPartition）。
+type PartitionBackend struct {
+	disk *VHDFile
+	part Partition
+}
+
+var _ Backend = (*PartitionBackend)(nil)
+
+// Disk 返回分区所在的磁盘映像
+func (p *PartitionBackend) Disk() *VHDFile {
+	return p.disk
+}
+
+// Partition 返回分区的描述
+func (p *PartitionBackend) Partition() Partition {
+	return p.part
+}
+
+// Size 返回分区的字节数，分区超出磁盘时截到磁盘末尾
+func (p *PartitionBackend) Size() int64 {
+	return max(min(p.part.Size, p.disk.Size()-p.part.Offset), 0)
+}
+
+// ReadAt 从分区内的偏移读取，超出分区的部分不读取并返回 io.EOF
+func (p *PartitionBackend) ReadAt(buf []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset: %d", offset)
+	}
+	size := p.Size()
+	if offset >= size {
+		return 0, io.EOF
+	}
+	if p.disk.tracer != nil {
+		p.disk.tracer("partition", offset, p.part.Offset+offset, min(int64(len(buf)), size-offset), "data")
+	}
+	if int64(len(buf)) > size-offset {
+		n, err := p.disk.ReadAt(buf[:size-offset], p.part.Offset+offset)
+		if err == nil {
+			err = io.EOF
+		}
+		return n, err
+	}
+	return p.disk.ReadAt(buf, p.part.Offset+offset)
+}
+
+// WriteAt 在分区内的偏移写入，磁盘需要以 WithWritable 打开
+func (p *PartitionBackend) WriteAt(buf []byte, offset int64) (int, error) {
+	if offset < 0 || offset+int64(len(buf)) > p.Size() {
+		return 0, fmt.Errorf("write at %d+%d is outside the partition", offset, len(buf))
+	}
+	return p.disk.WriteAt(buf, p.part.Offset+offset)
+}
+
+// Writable 返回分区所在的磁盘是否可以写入
+func (p *PartitionBackend) Writable() bool {
+	return p.disk.Writable()
+}
+
+// Close 关闭分区所在的磁盘映像
+func (p *PartitionBackend) Close() error {
+	return p.disk.Close()
+}
+
+// selectPartition 磁盘开头不是 exFAT 卷时按分区表选择分区
+// index 大于 0 时使用该序号的分区；否则选择第一个探测到 exFAT 的分区，不论声明的类型。
+// 没有分区表或没有 exFAT 分区时返回磁盘本身，由文件系统层报告错误。
+func selectPartition(disk *VHDFile, index int) (Backend, error) {
+	if index == 0 {
+		sector := make([]byte, SectorSize)
+		if _, err := disk.ReadAt(sector, 0); err != nil || isExFATBootSector(sector) {
+			return disk, nil
+		}
+	}
+	parts, err := ListPartitions(disk)
+	if err != nil {
+		if index == 0 {
+			return disk, nil
+		}
+		return nil, err
+	}
+	for _, p := range parts {
+		if (index == 0 && p.ExFAT()) || p.Index == index {
+			return &PartitionBackend{disk: disk, part: p}, nil
+		}
+	}
+	if index > 0 {
+		return nil, fmt.Errorf("partition %d not found (the disk has %d partitions)", index, len(parts))
+	}
+	return disk, nil
+}
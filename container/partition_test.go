@@ -0,0 +1,186 @@
+package container
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// GPT 分区类型
+const (
+	basicDataGUID = "EBD0A0A2-B9E5-4433-87C0-68B6B72699C7"
+	efiSystemGUID = "C12A7328-F81F-11D2-BA4B-00A0C93EC93B"
+)
+
+// partitionVolume 返回分区中的 exFAT 卷，根目录下的 name 文件内容为 name
+func partitionVolume(name string) []byte {
+	return testimage.Build(testimage.Options{ClusterCount: 256}, testimage.File(name, []byte(name))).Bytes
+}
+
+// bootSectorOf 返回只有引导扇区的文件系统：NTFS 或 FAT32
+func bootSectorOf(content string) []byte {
+	sector := make([]byte, SectorSize)
+	switch content {
+	case ContentNTFS:
+		copy(sector[3:], "NTFS    ")
+	case ContentFAT32:
+		copy(sector[3:], "MSDOS5.0")
+		copy(sector[82:], "FAT32   ")
+	}
+	sector[510], sector[511] = 0x55, 0xAA
+	return sector
+}
+
+// mbrEntry 在 MBR 的第 i 项写入分区类型和扇区范围
+func mbrEntry(disk []byte, i int, typ byte, start, count uint32) {
+	e := disk[446+i*16:]
+	e[4] = typ
+	binary.LittleEndian.PutUint32(e[8:], start)
+	binary.LittleEndian.PutUint32(e[12:], count)
+	disk[510], disk[511] = 0x55, 0xAA
+}
+
+// gptEntry 在 LBA 2 开始的 GPT 分区项中写入第 i 项
+func gptEntry(disk []byte, i int, typ, name string, first, last uint64) {
+	e := disk[2*SectorSize+i*128:][:128]
+	guid, _ := hex.DecodeString(strings.ReplaceAll(typ, "-", ""))
+	binary.LittleEndian.PutUint32(e[0:], binary.BigEndian.Uint32(guid[0:]))
+	binary.LittleEndian.PutUint16(e[4:], binary.BigEndian.Uint16(guid[4:]))
+	binary.LittleEndian.PutUint16(e[6:], binary.BigEndian.Uint16(guid[6:]))
+	copy(e[8:16], guid[8:])
+	binary.LittleEndian.PutUint64(e[32:], first)
+	binary.LittleEndian.PutUint64(e[40:], last)
+	for j, r := range name {
+		binary.LittleEndian.PutUint16(e[56+j*2:], uint16(r))
+	}
+}
+
+// gptHeader 在 LBA 1 写入 GPT 头部：4 个 128 字节的分区项，从 LBA 2 开始
+func gptHeader(disk []byte) {
+	h := disk[SectorSize:]
+	copy(h, "EFI PART")
+	binary.LittleEndian.PutUint64(h[72:], 2)
+	binary.LittleEndian.PutUint32(h[80:], 4)
+	binary.LittleEndian.PutUint32(h[84:], 128)
+}
+
+// placeAt 把 data 写入 disk 的第 lba 个扇区，返回占用的扇区数
+func placeAt(disk []byte, lba int, data []byte) uint32 {
+	copy(disk[lba*SectorSize:], data)
+	return uint32((len(data) + SectorSize - 1) / SectorSize)
+}
+
+// checkPartitions 比较 ListPartitions 的结果
+func checkPartitions(t *testing.T, path string, want []Partition) {
+	t.Helper()
+	v, err := OpenVHDFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+	got, err := ListPartitions(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ListPartitions() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("partition %d: %+v, want %+v", i+1, got[i], want[i])
+		}
+	}
+}
+
+// openPartition 按 WithPartition(index) 打开磁盘，返回选中的分区和根目录下 name 文件的内容
+func openPartition(t *testing.T, path string, index int, name string) (Partition, string) {
+	t.Helper()
+	b, err := Open(path, WithPartition(index))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	pb, ok := b.(*PartitionBackend)
+	if !ok {
+		t.Fatalf("partition %d: opened %T", index, b)
+	}
+	fs, err := exfat.NewExFATFileSystem(b)
+	if err != nil {
+		t.Fatalf("partition %d: %v", index, err)
+	}
+	data, err := fs.ReadFile("/" + name)
+	if err != nil {
+		t.Fatalf("partition %d: %v", index, err)
+	}
+	return pb.Partition(), string(data)
+}
+
+func TestMBRTypeMismatch(t *testing.T) {
+	// 声明为 0x07 的 NTFS 分区在前，之后是声明为 0x07 和 0x0C 的 exFAT 分区
+	disk := make([]byte, 2048*SectorSize)
+	mbrEntry(disk, 0, 0x07, 64, placeAt(disk, 64, bootSectorOf(ContentNTFS)))
+	mbrEntry(disk, 1, 0x07, 128, placeAt(disk, 128, partitionVolume("ntfs-typed.txt")))
+	mbrEntry(disk, 2, 0x0C, 1024, placeAt(disk, 1024, partitionVolume("fat32-typed.txt")))
+	path := writeTemp(t, "mbr.img", disk)
+
+	size := int64(len(partitionVolume("a")))
+	checkPartitions(t, path, []Partition{
+		{Index: 1, Scheme: "MBR", Type: "0x07", TypeName: "NTFS/exFAT", Content: ContentNTFS, Offset: 64 * SectorSize, Size: SectorSize},
+		{Index: 2, Scheme: "MBR", Type: "0x07", TypeName: "NTFS/exFAT", Content: ContentExFAT, Offset: 128 * SectorSize, Size: size},
+		{Index: 3, Scheme: "MBR", Type: "0x0C", TypeName: "FAT32 (LBA)", Content: ContentExFAT, Offset: 1024 * SectorSize, Size: size},
+	})
+
+	// 自动选择第一个探测到 exFAT 的分区，而不是第一个声明为 0x07 的分区
+	if p, got := openPartition(t, path, 0, "ntfs-typed.txt"); p.Index != 2 || got != "ntfs-typed.txt" {
+		t.Errorf("auto-selected %v, read %q", p, got)
+	}
+	if p, got := openPartition(t, path, 3, "fat32-typed.txt"); p.Index != 3 || got != "fat32-typed.txt" {
+		t.Errorf("partition 3: %v, read %q", p, got)
+	}
+	// 明确选择 NTFS 分区时照样打开，由文件系统层报告错误
+	b1, err := Open(path, WithPartition(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b1.Close()
+	if _, err := exfat.NewExFATFileSystem(b1); err == nil {
+		t.Error("opened the NTFS partition as exFAT")
+	}
+	if _, err := Open(path, WithPartition(4)); err == nil || !strings.Contains(err.Error(), "partition 4 not found (the disk has 3 partitions)") {
+		t.Errorf("missing partition: %v", err)
+	}
+}
+
+func TestHybridMBR(t *testing.T) {
+	// GPT：EFI 系统分区（FAT32）和基本数据分区（exFAT）；
+	// 混合 MBR：保护项、与 GPT 的 EFI 分区重复的 0x0C 项，以及只在 MBR 中的 0x07 exFAT 分区
+	disk := make([]byte, 2048*SectorSize)
+	gptHeader(disk)
+	efi := placeAt(disk, 64, bootSectorOf(ContentFAT32))
+	gptEntry(disk, 0, efiSystemGUID, "EFI", 64, 127)
+	data := placeAt(disk, 128, partitionVolume("gpt.txt"))
+	gptEntry(disk, 1, basicDataGUID, "DATA", 128, 128+uint64(data)-1)
+	mbrEntry(disk, 0, 0xEE, 1, 63)
+	mbrEntry(disk, 1, 0x0C, 64, efi)
+	mbrEntry(disk, 2, 0x07, 1024, placeAt(disk, 1024, partitionVolume("mbr.txt")))
+	path := writeTemp(t, "hybrid.img", disk)
+
+	size := int64(data) * SectorSize
+	checkPartitions(t, path, []Partition{
+		{Index: 1, Scheme: "GPT", Type: efiSystemGUID, TypeName: "EFI system", Name: "EFI", Content: ContentFAT32, Offset: 64 * SectorSize, Size: 64 * SectorSize},
+		{Index: 2, Scheme: "GPT", Type: basicDataGUID, TypeName: "Microsoft basic data", Name: "DATA", Content: ContentExFAT, Offset: 128 * SectorSize, Size: size},
+		{Index: 3, Scheme: "MBR", Type: "0x07", TypeName: "NTFS/exFAT", Content: ContentExFAT, Offset: 1024 * SectorSize, Size: size},
+	})
+
+	if p, got := openPartition(t, path, 0, "gpt.txt"); p.Index != 2 || got != "gpt.txt" {
+		t.Errorf("auto-selected %v, read %q", p, got)
+	}
+	// 只在混合 MBR 中的分区同样可以打开
+	if p, got := openPartition(t, path, 3, "mbr.txt"); p.Scheme != "MBR" || got != "mbr.txt" {
+		t.Errorf("partition 3: %v, read %q", p, got)
+	}
+}
@@ -0,0 +1,90 @@
+package container
+
+import (
+	"os"
+	"sync"
+)
+
+// VHD 文件类型和常量
+const (
+	BlockUnallocated = 0xFFFFFFFF
+	SectorSize       = 512
+	LargeSectorSize  = 4096 // 4Kn 映像的扇区大小
+	FixedDisk        = 2
+	DynamicDisk      = 3
+	DifferencingDisk = 4
+)
+
+// 父磁盘定位器平台代码
+const (
+	PlatformCodeNone = 0x00000000
+	PlatformCodeWi2r = 0x57693272 // 相对路径（ANSI，已弃用）
+	PlatformCodeWi2k = 0x5769326B // 绝对路径（ANSI，已弃用）
+	PlatformCodeW2ru = 0x57327275 // 相对路径（UTF-16LE）
+	PlatformCodeW2ku = 0x57326B75 // 绝对路径（UTF-16LE）
+	PlatformCodeMac  = 0x4D616320 // Mac OS 别名
+	PlatformCodeMacX = 0x4D616358 // 文件 URL（UTF-8）
+)
+
+// VHDHeader VHD 文件头部结构
+type VHDHeader struct {
+	Cookie             [8]byte   // "conectix"
+	Features           uint32    // 功能标志
+	FileFormatVersion  uint32    // 文件格式版本
+	DataOffset         uint64    // 数据偏移（动态磁盘）
+	TimeStamp          uint32    // 时间戳
+	CreatorApplication [4]byte   // 创建应用
+	CreatorVersion     uint32    // 创建版本
+	CreatorHostOS      uint32    // 创建者操作系统
+	OriginalSize       uint64    // 原始大小
+	CurrentSize        uint64    // 当前大小
+	DiskGeometry       uint32    // 磁盘几何
+	DiskType           uint32    // 磁盘类型
+	Checksum           uint32    // 校验和
+	UniqueID           [16]byte  // 唯一ID
+	SavedState         byte      // 保存状态
+	Reserved           [427]byte // 保留字段
+}
+
+// VHDDynamicHeader VHD 动态磁盘头部
+type VHDDynamicHeader struct {
+	Cookie            [8]byte     // "cxsparse"
+	DataOffset        uint64      // 数据偏移
+	TableOffset       uint64      // BAT 表偏移
+	HeaderVersion     uint32      // 头部版本
+	MaxTableEntries   uint32      // 最大表项数
+	BlockSize         uint32      // 块大小
+	Checksum          uint32      // 校验和
+	ParentUniqueID    [16]byte    // 父磁盘ID
+	ParentTimeStamp   uint32      // 父磁盘时间戳
+	Reserved1         uint32      // 保留
+	ParentUnicodeName [512]byte   // 父磁盘名称
+	ParentLocators    [8]struct { // 父磁盘定位器
+		PlatformCode       uint32
+		PlatformDataSpace  uint32
+		PlatformDataLength uint32
+		Reserved           uint32
+		PlatformDataOffset uint64
+	}
+	Reserved2 [256]byte // 保留
+}
+
+// VHDFile 表示一个 VHD 文件
+type VHDFile struct {
+	path          string
+	file          *os.File
+	header        *VHDHeader
+	dynamicHeader *VHDDynamicHeader
+	bat           []uint32 // Block Allocation Table
+	blockSize     uint32
+	bitmapSize    int64 // 每个块前扇区位图的字节数（已按扇区对齐）
+	sectorSize    int64 // BAT 与扇区位图使用的扇区大小（4Kn 映像为 4096）
+	isDynamic     bool
+	rawOffset     int64    // 原始映像中被跳过的厂商头部长度
+	parent        *VHDFile // 差分磁盘的父磁盘
+	writable      bool     // 以读写方式打开（见 WithWritable）
+	tracer        ReadTracer
+
+	bitmapMu    sync.Mutex
+	bitmapCache map[uint32][]byte // 差分磁盘的块扇区位图缓存
+}
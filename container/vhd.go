@@ -0,0 +1,466 @@
+package container
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// OpenVHDFile 打开一个 VHD 文件
+// 对于差分磁盘，会按父磁盘定位器递归打开整个父链
+func OpenVHDFile(path string, opts ...Option) (*VHDFile, error) {
+	return openVHDFile(path, applyOptions(opts), nil)
+}
+
+// openVHDFile 打开 VHD 文件，chain 为已打开的子磁盘路径（用于检测循环引用）
+func openVHDFile(path string, opts *openOptions, chain []string) (*VHDFile, error) {
+	writable := opts.writable && len(chain) == 0
+	flag := os.O_RDONLY
+	if writable {
+		flag = os.O_RDWR
+	}
+	file, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	// 读取 VHD 头部（在文件末尾）
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to get file info: %v", err)
+	}
+
+	// 先尝试检查文件是否是标准 VHD 格式
+	header, err := tryReadVHDHeader(file, stat.Size())
+	if err != nil {
+		// 如果不是标准 VHD，尝试作为原始磁盘映像处理
+		raw, err := tryOpenAsRawDisk(file, stat.Size(), opts)
+		if err != nil {
+			return nil, err
+		}
+		raw.writable = writable
+		raw.tracer = opts.tracer
+		return raw, nil
+	}
+
+	vhd := &VHDFile{
+		path:       path,
+		file:       file,
+		header:     header,
+		sectorSize: SectorSize,
+		writable:   writable,
+		tracer:     opts.tracer,
+	}
+
+	// 检查磁盘类型
+	switch header.DiskType {
+	case FixedDisk: // 固定磁盘
+		vhd.isDynamic = false
+	case DynamicDisk: // 动态磁盘
+		vhd.isDynamic = true
+		if err := vhd.readDynamicHeader(opts, stat.Size()); err != nil {
+			file.Close()
+			return nil, err
+		}
+	case DifferencingDisk: // 差分磁盘
+		vhd.isDynamic = true
+		if err := vhd.readDynamicHeader(opts, stat.Size()); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if err := vhd.openParent(opts, append(chain, path)); err != nil {
+			file.Close()
+			return nil, err
+		}
+	default:
+		file.Close()
+		return nil, fmt.Errorf("unsupported disk type: %d", header.DiskType)
+	}
+
+	return vhd, nil
+}
+
+// readVHDHeaderAt 在指定偏移读取 VHD 头部
+func readVHDHeaderAt(file *os.File, offset int64) (*VHDHeader, error) {
+	_, err := file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &VHDHeader{}
+	if err := binary.Read(file, binary.BigEndian, header); err != nil {
+		return nil, err
+	}
+	if string(header.Cookie[:]) == "conectix" {
+		return header, nil
+	}
+
+	return nil, fmt.Errorf("invalid VHD header")
+}
+
+// tryReadVHDHeader 尝试从多个位置读取 VHD 头部
+func tryReadVHDHeader(file *os.File, fileSize int64) (*VHDHeader, error) {
+	// 尝试从文件末尾读取 VHD 头部（标准位置）
+	if header, err := readVHDHeaderAt(file, fileSize-SectorSize); err == nil {
+		return header, nil
+	}
+
+	// 尝试从文件开头读取（某些工具创建的 VHD 可能把头部放在开头）
+	if header, err := readVHDHeaderAt(file, 0); err == nil {
+		return header, nil
+	}
+
+	return nil, fmt.Errorf("no valid VHD header found")
+}
+
+// 探测厂商头部时搜索的范围
+const maxProbePrefix = 64 * 1024
+
+// tryOpenAsRawDisk 尝试作为原始磁盘映像打开
+func tryOpenAsRawDisk(file *os.File, fileSize int64, opts *openOptions) (*VHDFile, error) {
+	// 读取前 512 字节检查是否是 exFAT
+	bootSector := make([]byte, SectorSize)
+	if _, err := file.ReadAt(bootSector, 0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read boot sector: %v", err)
+	}
+
+	// 检查 exFAT 签名
+	if isExFATBootSector(bootSector) {
+		// 这是一个原始的 exFAT 磁盘映像，创建伪 VHD 头部
+		return createPseudoVHD(file, fileSize, 0), nil
+	}
+
+	if string(bootSector[:8]) == "vhdxfile" {
+		file.Close()
+		return nil, fmt.Errorf("invalid file format: VHDX images are not supported")
+	}
+
+	// 带分区表的整盘映像，由 Open 按内容选择其中的 exFAT 分区
+	if parts, err := ListPartitions(file); err == nil {
+		for _, p := range parts {
+			if p.ExFAT() {
+				return createPseudoVHD(file, fileSize, 0), nil
+			}
+		}
+	}
+
+	// 某些恢复工具和备份应用会在原始映像前加上专有头部，按扇区对齐探测 exFAT 引导扇区
+	if !opts.noProbe {
+		if offset, ok := probeExFATOffset(file, fileSize); ok {
+			return createPseudoVHD(file, fileSize, offset), nil
+		}
+	}
+
+	file.Close()
+	return nil, fmt.Errorf("invalid file format: not a standard VHD file or exFAT disk image")
+}
+
+// probeExFATOffset 在文件开头 64 KiB 内按扇区对齐查找 exFAT 引导扇区
+func probeExFATOffset(file *os.File, fileSize int64) (int64, bool) {
+	size := int64(maxProbePrefix + SectorSize)
+	if fileSize < size {
+		size = fileSize
+	}
+	data := make([]byte, size)
+	n, err := file.ReadAt(data, 0)
+	if err != nil && err != io.EOF {
+		return 0, false
+	}
+	data = data[:n]
+
+	for offset := int64(SectorSize); offset+SectorSize <= int64(len(data)); offset += SectorSize {
+		sector := data[offset : offset+SectorSize]
+		if isExFATBootSector(sector) && sector[510] == 0x55 && sector[511] == 0xAA {
+			return offset, true
+		}
+	}
+	return 0, false
+}
+
+// isExFATBootSector 检查引导扇区是否为 exFAT
+func isExFATBootSector(data []byte) bool {
+	return len(data) >= 11 && string(data[3:11]) == "EXFAT   "
+}
+
+// createPseudoVHD 为原始磁盘映像创建伪 VHD 结构
+// offset 为 exFAT 卷之前被跳过的厂商头部长度
+func createPseudoVHD(file *os.File, fileSize, offset int64) *VHDFile {
+	// 创建伪 VHD 头部用于原始磁盘映像
+	header := &VHDHeader{
+		DiskType:    FixedDisk, // 固定磁盘
+		CurrentSize: uint64(fileSize - offset),
+	}
+	copy(header.Cookie[:], "rawdisk") // 标记为原始磁盘
+
+	return &VHDFile{
+		path:       file.Name(),
+		file:       file,
+		header:     header,
+		isDynamic:  false,
+		rawOffset:  offset,
+		sectorSize: SectorSize,
+	}
+}
+
+// RawOffset 返回原始映像中 exFAT 卷之前被跳过的头部长度（字节）
+func (v *VHDFile) RawOffset() int64 {
+	return v.rawOffset
+}
+
+// UniqueID 返回 VHD 尾部中的唯一标识（按字节顺序格式化的 UUID），原始映像没有标识时返回空字符串
+func (v *VHDFile) UniqueID() string {
+	id := v.header.UniqueID
+	if id == [16]byte{} {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+// readDynamicHeader 读取动态磁盘头部
+func (v *VHDFile) readDynamicHeader(opts *openOptions, fileSize int64) error {
+	// 定位到动态头部
+	_, err := v.file.Seek(int64(v.header.DataOffset), io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("failed to seek dynamic header: %v", err)
+	}
+
+	v.dynamicHeader = &VHDDynamicHeader{}
+	err = binary.Read(v.file, binary.BigEndian, v.dynamicHeader)
+	if err != nil {
+		return fmt.Errorf("failed to read dynamic header: %v", err)
+	}
+
+	// 验证动态头部签名
+	if string(v.dynamicHeader.Cookie[:]) != "cxsparse" {
+		return fmt.Errorf("invalid dynamic disk header")
+	}
+
+	v.blockSize = v.dynamicHeader.BlockSize
+	if v.blockSize == 0 || v.blockSize%SectorSize != 0 {
+		return fmt.Errorf("invalid dynamic disk block size: %d", v.blockSize)
+	}
+
+	// 读取 BAT 表
+	_, err = v.file.Seek(int64(v.dynamicHeader.TableOffset), io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("failed to seek BAT table: %v", err)
+	}
+
+	v.bat = make([]uint32, v.dynamicHeader.MaxTableEntries)
+	err = binary.Read(v.file, binary.BigEndian, v.bat)
+	if err != nil {
+		return fmt.Errorf("failed to read BAT table: %v", err)
+	}
+
+	// BAT 项和扇区位图以扇区为单位，4Kn 映像的扇区为 4096 字节
+	switch opts.sectorSize {
+	case 0:
+		v.sectorSize = v.detectSectorSize(fileSize)
+	case SectorSize, LargeSectorSize:
+		v.sectorSize = opts.sectorSize
+	default:
+		return fmt.Errorf("unsupported sector size: %d", opts.sectorSize)
+	}
+	if v.blockSize%uint32(v.sectorSize) != 0 {
+		return fmt.Errorf("block size %d is not a multiple of the %d-byte sector size", v.blockSize, v.sectorSize)
+	}
+	v.bitmapSize = bitmapSizeFor(v.blockSize, v.sectorSize)
+
+	return nil
+}
+
+// bitmapSizeFor 计算块前扇区位图的字节数
+// 每个块以扇区位图开头（每扇区 1 位），位图按扇区大小对齐
+func bitmapSizeFor(blockSize uint32, sectorSize int64) int64 {
+	sectorsPerBlock := int64(blockSize) / sectorSize
+	return ((sectorsPerBlock+7)/8 + sectorSize - 1) / sectorSize * sectorSize
+}
+
+// detectSectorSize 推断 BAT 使用的扇区大小
+// 按 512 字节解释时某些 BAT 项超出文件范围、按 4096 字节则全部有效，说明是 4Kn 映像；
+// 两种解释都有效时，看首个数据块在哪种解释下是引导扇区。无法判断时使用标准的 512 字节。
+func (v *VHDFile) detectSectorSize(fileSize int64) int64 {
+	fits := func(sectorSize int64) bool {
+		if v.blockSize%uint32(sectorSize) != 0 {
+			return false
+		}
+		limit := fileSize - SectorSize // 末尾的 VHD 尾部
+		span := bitmapSizeFor(v.blockSize, sectorSize) + int64(v.blockSize)
+		for _, entry := range v.bat {
+			if entry != BlockUnallocated && int64(entry)*sectorSize+span > limit {
+				return false
+			}
+		}
+		return true
+	}
+
+	small, large := fits(SectorSize), fits(LargeSectorSize)
+	switch {
+	case large && !small:
+		return LargeSectorSize
+	case large && small && len(v.bat) > 0 && v.bat[0] != BlockUnallocated:
+		if !v.bootSectorAt(SectorSize) && v.bootSectorAt(LargeSectorSize) {
+			return LargeSectorSize
+		}
+	}
+	return SectorSize
+}
+
+// bootSectorAt 按指定扇区大小解释 BAT 时，第一个块是否以引导扇区开头
+func (v *VHDFile) bootSectorAt(sectorSize int64) bool {
+	sector := make([]byte, SectorSize)
+	offset := int64(v.bat[0])*sectorSize + bitmapSizeFor(v.blockSize, sectorSize)
+	if _, err := v.file.ReadAt(sector, offset); err != nil {
+		return false
+	}
+	return sector[510] == 0x55 && sector[511] == 0xAA
+}
+
+// SectorSize 返回 BAT 与扇区位图使用的扇区大小
+func (v *VHDFile) SectorSize() int64 {
+	return v.sectorSize
+}
+
+// ReadAt 从指定偏移读取数据
+// 读取范围限制在磁盘大小（CurrentSize）之内，超出的部分不读取并返回 io.EOF：
+// 动态磁盘的最后一个块可能超出磁盘大小，固定磁盘的数据之后紧跟着尾部，都不属于卷的数据。
+func (v *VHDFile) ReadAt(buf []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset: %d", offset)
+	}
+	if offset >= v.Size() {
+		return 0, io.EOF
+	}
+	if end := offset + int64(len(buf)); end > v.Size() {
+		n, err := v.ReadAt(buf[:v.Size()-offset], offset)
+		if err == nil {
+			err = io.EOF
+		}
+		return n, err
+	}
+
+	if !v.isDynamic {
+		// 固定磁盘，直接读取
+		return v.readFile(buf, offset+v.rawOffset, offset, "data")
+	}
+
+	// 动态磁盘，需要通过 BAT 表查找
+	bytesRead := 0
+	for len(buf) > 0 {
+		blockIndex := uint32(offset / int64(v.blockSize))
+		blockOffset := offset % int64(v.blockSize)
+
+		if blockIndex >= uint32(len(v.bat)) {
+			return bytesRead, io.EOF
+		}
+
+		// 计算本次读取的字节数
+		var toRead int
+		remaining := int(int64(v.blockSize) - blockOffset)
+		if len(buf) < remaining {
+			toRead = len(buf)
+		} else {
+			toRead = remaining
+		}
+
+		// 检查块是否分配
+		if v.bat[blockIndex] == BlockUnallocated {
+			if v.parent != nil {
+				// 差分磁盘中未分配的块由父磁盘提供
+				if _, err := v.parent.ReadAt(buf[:toRead], offset); err != nil && err != io.EOF {
+					return bytesRead, err
+				}
+			} else {
+				for i := 0; i < toRead; i++ {
+					buf[i] = 0
+				}
+			}
+		} else if v.parent != nil {
+			if err := v.readDifferencingBlock(blockIndex, blockOffset, buf[:toRead], offset); err != nil {
+				return bytesRead, err
+			}
+		} else {
+			// 计算块数据在文件中的实际偏移（跳过块前的扇区位图）
+			dataOffset := int64(v.bat[blockIndex])*v.sectorSize + v.bitmapSize
+			_, err := v.readFile(buf[:toRead], dataOffset+blockOffset, offset, "data")
+			if err != nil && err != io.EOF {
+				return bytesRead, err
+			}
+		}
+
+		buf = buf[toRead:]
+		offset += int64(toRead)
+		bytesRead += toRead
+	}
+
+	return bytesRead, nil
+}
+
+// readFile 从映像文件的 physical 处读取，logical 为对应的磁盘偏移，只用于读取跟踪（见 WithReadTracer）
+func (v *VHDFile) readFile(buf []byte, physical, logical int64, purpose string) (int, error) {
+	if v.tracer != nil {
+		v.tracer("vhd:"+filepath.Base(v.path), logical, physical, int64(len(buf)), purpose)
+	}
+	return v.file.ReadAt(buf, physical)
+}
+
+// Writable 返回映像是否可以写入：以 WithWritable 打开且不是差分磁盘
+func (v *VHDFile) Writable() bool {
+	return v.writable && v.parent == nil
+}
+
+// WriteAt 在指定偏移写入数据，映像需要以 WithWritable 打开
+// 动态磁盘只能写入已分配的块，差分磁盘不支持写入；不满足条件时返回 ErrReadOnly。
+func (v *VHDFile) WriteAt(buf []byte, offset int64) (int, error) {
+	if !v.writable {
+		return 0, fmt.Errorf("%w: opened without WithWritable", ErrReadOnly)
+	}
+	if offset < 0 || offset+int64(len(buf)) > v.Size() {
+		return 0, fmt.Errorf("write at %d+%d is outside the disk", offset, len(buf))
+	}
+	if !v.isDynamic {
+		return v.file.WriteAt(buf, offset+v.rawOffset)
+	}
+	if v.parent != nil {
+		return 0, fmt.Errorf("%w: writing to differencing disks is not supported", ErrReadOnly)
+	}
+
+	written := 0
+	for len(buf) > 0 {
+		blockIndex := uint32(offset / int64(v.blockSize))
+		blockOffset := offset % int64(v.blockSize)
+		toWrite := int(min(int64(len(buf)), int64(v.blockSize)-blockOffset))
+		if blockIndex >= uint32(len(v.bat)) || v.bat[blockIndex] == BlockUnallocated {
+			return written, fmt.Errorf("%w: block %d is not allocated", ErrReadOnly, blockIndex)
+		}
+
+		dataOffset := int64(v.bat[blockIndex])*v.sectorSize + v.bitmapSize
+		if _, err := v.file.WriteAt(buf[:toWrite], dataOffset+blockOffset); err != nil {
+			return written, err
+		}
+		buf = buf[toWrite:]
+		offset += int64(toWrite)
+		written += toWrite
+	}
+	return written, nil
+}
+
+// Size 返回磁盘大小
+func (v *VHDFile) Size() int64 {
+	return int64(v.header.CurrentSize)
+}
+
+// Close 关闭 VHD 文件（包括差分链上的所有父磁盘）
+func (v *VHDFile) Close() error {
+	err := v.file.Close()
+	if v.parent != nil {
+		if perr := v.parent.Close(); err == nil {
+			err = perr
+		}
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package container
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// writeTemp 把 data 写入临时目录中的 name，返回路径
+func writeTemp(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// readAll 通过 ReadAt 读取整个磁盘
+func readAll(t *testing.T, v *VHDFile) []byte {
+	t.Helper()
+	data := make([]byte, v.Size())
+	if n, err := v.ReadAt(data, 0); err != nil || n != len(data) {
+		t.Fatalf("ReadAt: %d of %d bytes, %v", n, len(data), err)
+	}
+	return data
+}
+
+func TestDynamicVHD4Kn(t *testing.T) {
+	content := bytes.Repeat([]byte("4Kn sector "), 2000)
+	img := testimage.Build(testimage.Options{SectorShift: 12, ClusterCount: 128},
+		testimage.Dir("DCIM", testimage.File("photo.jpg", content)))
+	// 块大小 64 KiB：按 512 字节解释 BAT 时后面的块落在文件之外，只能是 4Kn
+	path := writeTemp(t, "4kn.vhd", testimage.DynamicVHD(img.Bytes, 64<<10, 4096))
+
+	for _, opts := range [][]Option{nil, {WithSectorSize(4096)}} {
+		v, err := OpenVHDFile(path, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer v.Close()
+		if v.SectorSize() != 4096 {
+			t.Errorf("options %d: sector size %d, want 4096", len(opts), v.SectorSize())
+		}
+		if !bytes.Equal(readAll(t, v), img.Bytes) {
+			t.Fatalf("options %d: disk contents differ from the raw image", len(opts))
+		}
+
+		fs, err := exfat.NewExFATFileSystem(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := fs.ReadFile("/DCIM/photo.jpg")
+		if err != nil || !bytes.Equal(got, content) {
+			t.Errorf("options %d: ReadFile: %d bytes, %v", len(opts), len(got), err)
+		}
+	}
+}
+
+func TestDynamicVHD512(t *testing.T) {
+	img := testimage.Build(testimage.Options{ClusterCount: 512}, testimage.File("a.txt", []byte("512-byte sectors")))
+	path := writeTemp(t, "512.vhd", testimage.DynamicVHD(img.Bytes, 64<<10, 512))
+	v, err := OpenVHDFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+	if v.SectorSize() != 512 {
+		t.Errorf("sector size %d, want 512", v.SectorSize())
+	}
+	if !bytes.Equal(readAll(t, v), img.Bytes) {
+		t.Error("disk contents differ from the raw image")
+	}
+}
+
+func TestReadPastDiskSize(t *testing.T) {
+	img := testimage.Build(testimage.Options{ClusterCount: 512}, testimage.File("a.txt", []byte("tail")))
+	raw := img.Bytes
+	// 磁盘大小不是块大小的整数倍：最后一个块超出磁盘的部分填充 0xEE，固定磁盘的数据之后紧跟着尾部
+	if len(raw)%(64<<10) == 0 {
+		t.Fatalf("raw image of %d bytes fills its last block", len(raw))
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"dynamic", testimage.DynamicVHD(raw, 64<<10, 512)},
+		{"fixed", testimage.FixedVHD(raw)},
+	}
+	for _, tt := range tests {
+		v, err := OpenVHDFile(writeTemp(t, tt.name+".vhd", tt.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer v.Close()
+		size := v.Size()
+		if size != int64(len(raw)) {
+			t.Fatalf("%s: size %d, want %d", tt.name, size, len(raw))
+		}
+
+		buf := make([]byte, 4096)
+		n, err := v.ReadAt(buf, size-100)
+		if n != 100 || err != io.EOF {
+			t.Errorf("%s: read across the end: %d bytes, %v; want 100 bytes and io.EOF", tt.name, n, err)
+		}
+		if !bytes.Equal(buf[:n], raw[size-100:]) {
+			t.Errorf("%s: read across the end returned data that is not on the disk", tt.name)
+		}
+		for _, offset := range []int64{size, size + 1, size + 1<<20} {
+			if n, err := v.ReadAt(buf, offset); n != 0 || err != io.EOF {
+				t.Errorf("%s: read at %d: %d bytes, %v; want 0 bytes and io.EOF", tt.name, offset, n, err)
+			}
+		}
+		if n, err := v.ReadAt(buf[:100], size-100); n != 100 || err != nil {
+			t.Errorf("%s: read ending exactly at the end: %d bytes, %v", tt.name, n, err)
+		}
+	}
+}
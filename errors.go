@@ -0,0 +1,66 @@
+package exfat
+
+import (
+	exfatfs "github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/extract"
+)
+
+// ErrNotExist 表示路径不存在，errors.Is(err, fs.ErrNotExist) 同样成立
+var ErrNotExist = exfatfs.ErrNotExist
+
+// ErrNotDirectory 表示路径中间的组件是文件，或者对文件列目录
+var ErrNotDirectory = exfatfs.ErrNotDirectory
+
+// ErrIsDirectory 表示对目录执行了只适用于文件的操作（如 ReadFile、提取单个文件）
+var ErrIsDirectory = exfatfs.ErrIsDirectory
+
+// ErrInvalidImage 表示映像不是有效的 exFAT 卷或结构已损坏，ErrBadCluster 等具体的损坏也满足 errors.Is
+var ErrInvalidImage = exfatfs.ErrInvalidImage
+
+// ErrBadCluster 表示簇号无效（不在簇堆中，或者是坏簇标记等特殊值）
+var ErrBadCluster = exfatfs.ErrBadCluster
+
+// ErrBootChecksum 表示引导区的校验和与内容不符（打开时返回，见 WithoutBootChecksum）
+var ErrBootChecksum = exfatfs.ErrBootChecksum
+
+// ErrChecksumMismatch 表示条目集的校验和与内容不符（严格模式下返回，宽松模式下标记为 AnomalyChecksumMismatch）
+var ErrChecksumMismatch = exfatfs.ErrChecksumMismatch
+
+// ErrNoMatch 表示没有条目满足查找条件
+var ErrNoMatch = exfatfs.ErrNoMatch
+
+// ErrDirectoryTooLarge 表示目录超过了条目数上限（见 WithMaxDirEntries）
+var ErrDirectoryTooLarge = exfatfs.ErrDirectoryTooLarge
+
+// ErrBrokenChain 表示文件的簇链在数据结束之前断开（FAT 项为空闲），如 FAT 区域被清零的映像
+var ErrBrokenChain = exfatfs.ErrBrokenChain
+
+// ErrCrossLinked 表示文件的簇链与另一个条目共用簇（见 WithCrosslinkDetection），详细信息见 CrossLinkError
+var ErrCrossLinked = exfatfs.ErrCrossLinked
+
+// ErrReadOnly 表示映像不能写入（没有以 WithWritable 打开，或者是差分磁盘）
+var ErrReadOnly = exfatfs.ErrReadOnly
+
+// ErrTruncatedImage 表示声明的卷大小超过了映像的实际大小（严格模式下打开时返回）
+var ErrTruncatedImage = exfatfs.ErrTruncatedImage
+
+// ErrRegionOverlap 表示引导扇区声明的区域相互重叠（严格模式下打开时返回，宽松模式下读取受影响的簇时返回）
+var ErrRegionOverlap = exfatfs.ErrRegionOverlap
+
+// ErrNoPreview 表示文件中没有可以提取的预览图（见 VHD.ExtractPreview）
+var ErrNoPreview = exfatfs.ErrNoPreview
+
+// ErrSchema 表示 JSON 文档的模式标识不是预期的种类，或者版本比这个包支持的更新（见 CheckSchema）
+var ErrSchema = exfatfs.ErrSchema
+
+// ErrNotIndexed 表示索引无法回答查询（路径在索引范围之外，或索引已降级为目录骨架），应退回实时解析
+var ErrNotIndexed = exfatfs.ErrNotIndexed
+
+// ErrIndexTooLarge 表示目录骨架也超过了 IndexOptions.MaxMemory
+var ErrIndexTooLarge = exfatfs.ErrIndexTooLarge
+
+// ErrCheckFailed 表示检查策略要求中止提取（见 VHD.ExtractWithCheck）
+var ErrCheckFailed = extract.ErrCheckFailed
+
+// ErrIncomplete 表示有条目提取失败，失败的数量和样本见提取报告
+var ErrIncomplete = extract.ErrIncomplete
@@ -1,48 +1,156 @@
+// Package exfat 用于读取 VHD 文件并解析其中的 exFAT 文件系统
+//
+// 实现按层次拆分在子包中：container 负责磁盘映像格式（VHD、原始映像），
+// exfat 负责文件系统解析（只依赖 io.ReaderAt），extract 负责提取到本地文件系统的策略。
+// 本包组合三者，并保留原有的导出名称。
 package exfat
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"io/fs"
+	"strings"
 	"time"
-)
 
-// FileEntry 表示文件或目录的基本信息
-type FileEntry struct {
-	Name    string    // 文件/目录名
-	Size    int64     // 文件大小（目录为 0）
-	IsDir   bool      // 是否为目录
-	ModTime time.Time // 修改时间
-}
+	"github.com/0xXA/go-exfat/container"
+	exfatfs "github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/extract"
+)
 
 // VHD 表示一个打开的 VHD 文件和其中的 exFAT 文件系统
 type VHD struct {
-	vhdFile *VHDFile
-	exfat   *ExFATFileSystem
+	backend container.Backend
+	exfat   *exfatfs.ExFATFileSystem
+	view    bool // 由 Sub 创建，不拥有 backend
 }
 
 // OpenVHD 打开一个 VHD 文件并初始化 exFAT 文件系统
-func OpenVHD(path string) (*VHD, error) {
-	vhdFile, err := OpenVHDFile(path)
+func OpenVHD(path string, opts ...Option) (*VHD, error) {
+	o := applyOptions(opts)
+
+	backend, err := container.Open(path, o.container...)
+	if err != nil {
+		return nil, err
+	}
+
+	vhd, err := NewVHD(backend, opts...)
 	if err != nil {
+		backend.Close()
 		return nil, err
 	}
 
-	exfat, err := NewExFATFileSystem(vhdFile)
+	return vhd, nil
+}
+
+// NewVHD 在已打开的磁盘映像上初始化 exFAT 文件系统
+// 关闭返回的 VHD 时会一并关闭 backend
+func NewVHD(backend container.Backend, opts ...Option) (*VHD, error) {
+	exfat, err := exfatfs.NewExFATFileSystem(backend, applyOptions(opts).fs...)
 	if err != nil {
-		vhdFile.Close()
 		return nil, err
 	}
 
 	return &VHD{
-		vhdFile: vhdFile,
+		backend: backend,
 		exfat:   exfat,
 	}, nil
 }
 
-// Close 关闭 VHD 文件
+// Close 关闭 VHD 文件；对 Sub 返回的视图不做任何操作
 func (v *VHD) Close() error {
-	return v.vhdFile.Close()
+	if v.view {
+		return nil
+	}
+	return v.backend.Close()
+}
+
+// Sub 返回以 path 为根目录的视图，之后的所有路径都相对于该目录，".." 不会越过它
+// 视图与 v 共享打开的映像，只在 v 关闭之前有效，关闭视图不会关闭映像。
+func (v *VHD) Sub(path string) (*VHD, error) {
+	sub, err := v.exfat.Sub(path)
+	if err != nil {
+		return nil, err
+	}
+	return &VHD{backend: v.backend, exfat: sub, view: true}, nil
+}
+
+// FileSystem 返回映像中的 exFAT 文件系统
+func (v *VHD) FileSystem() *exfatfs.ExFATFileSystem {
+	return v.exfat
+}
+
+// diskOf 返回 backend 所在的磁盘映像，backend 为分区时返回分区所在的磁盘
+func diskOf(backend container.Backend) (*container.VHDFile, bool) {
+	switch b := backend.(type) {
+	case *container.VHDFile:
+		return b, true
+	case *container.PartitionBackend:
+		return b.Disk(), true
+	}
+	return nil, false
+}
+
+// Partition 返回打开的分区，映像没有分区表时 ok 为 false
+func (v *VHD) Partition() (p Partition, ok bool) {
+	if pb, ok := v.backend.(*container.PartitionBackend); ok {
+		return pb.Partition(), true
+	}
+	return Partition{}, false
+}
+
+// ListPartitions 打开磁盘映像并列出分区表中的分区及其中探测到的文件系统
+// 映像没有分区表（开头就是卷的引导扇区）时返回空列表。
+func ListPartitions(path string, opts ...Option) ([]Partition, error) {
+	disk, err := container.OpenVHDFile(path, applyOptions(opts).container...)
+	if err != nil {
+		return nil, err
+	}
+	defer disk.Close()
+	return container.ListPartitions(disk)
+}
+
+// ChainInfo 返回映像的差分链（非差分磁盘只有一项）
+func (v *VHD) ChainInfo() []ChainLink {
+	if vhdFile, ok := diskOf(v.backend); ok {
+		return vhdFile.ChainInfo()
+	}
+	return nil
+}
+
+// Diagnostics 返回映像层和文件系统层记录的诊断信息
+func (v *VHD) Diagnostics() []Diagnostic {
+	var list []Diagnostic
+	if p, ok := v.Partition(); ok {
+		list = append(list, Diagnostic{
+			Message: fmt.Sprintf("opened partition %s", p),
+		})
+	}
+	if vhdFile, ok := diskOf(v.backend); ok {
+		if vhdFile.RawOffset() > 0 {
+			list = append(list, Diagnostic{
+				Message: fmt.Sprintf("skipped %d-byte header before the exFAT boot sector", vhdFile.RawOffset()),
+			})
+		}
+		if vhdFile.SectorSize() != container.SectorSize {
+			list = append(list, Diagnostic{
+				Message: fmt.Sprintf("dynamic disk uses %d-byte sectors", vhdFile.SectorSize()),
+			})
+		}
+	}
+	return append(list, v.exfat.Diagnostics()...)
+}
+
+// Conformance 返回打开卷时发现的偏离规范、但读取时照常接受的写法
+func (v *VHD) Conformance() []ConformanceIssue {
+	return v.exfat.Conformance()
+}
+
+// Stat 返回指定路径的文件或目录信息，路径不存在时错误满足 errors.Is(err, fs.ErrNotExist)
+func (v *VHD) Stat(path string) (FileEntry, error) {
+	return v.exfat.Stat(path)
 }
 
 // ListDir 列出指定路径的目录内容
@@ -50,78 +158,276 @@ func (v *VHD) ListDir(path string) ([]FileEntry, error) {
 	return v.exfat.ListDir(path)
 }
 
+// Extents 返回文件或目录的数据在卷上的位置（连续簇的游程），只有一段表示没有碎片
+func (v *VHD) Extents(path string) ([]Extent, error) {
+	return v.exfat.Extents(path)
+}
+
+// GetClusterChain 严格按 FAT 返回文件或目录的连续簇游程及其在卷中的字节范围，坏簇或超出簇堆时返回 ErrBadCluster
+func (v *VHD) GetClusterChain(path string) ([]ClusterRun, error) {
+	return v.exfat.GetClusterChain(path)
+}
+
+// ListDeleted 列出目录中已删除（InUse 位为 0）但条目集仍可解析的文件和子目录，供数据恢复使用
+func (v *VHD) ListDeleted(path string) ([]DeletedEntry, error) {
+	return v.exfat.ListDeleted(path)
+}
+
+// ReadDir 列出目录内容，返回标准库的 fs.DirEntry，与 os.ReadDir 一样按名称排序
+func (v *VHD) ReadDir(path string) ([]fs.DirEntry, error) {
+	return v.exfat.ReadDir(path)
+}
+
 // ReadFile 读取文件内容
 func (v *VHD) ReadFile(path string) ([]byte, error) {
 	return v.exfat.ReadFile(path)
 }
 
+// ReadFileRange 读取文件中从 offset 开始的 length 字节，只解析到所需的簇；超出文件末尾时返回读到的部分和 io.EOF
+func (v *VHD) ReadFileRange(path string, offset, length int64) ([]byte, error) {
+	return v.exfat.ReadFileRange(path, offset, length)
+}
+
+// Open 打开文件用于流式读取，返回的句柄支持 Read、Seek、ReadAt 和 Close
+func (v *VHD) Open(path string) (*File, error) {
+	return v.exfat.Open(path)
+}
+
+// ExtractPreview 返回文件中嵌入的预览图（JPEG 的 EXIF 缩略图、MP4 的封面、HEIC 的缩略图）和 MIME 类型
+// 每个文件最多读取 maxBytes 字节（不大于 0 时为 DefaultPreviewBytes），没有预览图时返回 ErrNoPreview。
+func (v *VHD) ExtractPreview(path string, maxBytes int) ([]byte, string, error) {
+	return v.exfat.ExtractPreview(path, maxBytes)
+}
+
+// ReadChain 从指定的簇开始读取 length 字节，用于恢复目录条目已丢失的文件
+// length 为 0 时，连续模式读到第一个未分配的簇为止，FAT 模式读到簇链结束为止。
+func (v *VHD) ReadChain(start uint32, length uint64, noFatChain bool) (io.Reader, error) {
+	return v.exfat.ReadChain(start, length, noFatChain)
+}
+
+// WriteTo 把文件的全部内容以流的方式写入 w，返回写入的字节数
+func (v *VHD) WriteTo(path string, w io.Writer) (int64, error) {
+	return v.exfat.WriteTo(path, w)
+}
+
+// SaveMetadataCache 把已解析的 FAT 写入 w，供下次打开时通过 WithMetadataCache 使用
+func (v *VHD) SaveMetadataCache(w io.Writer) error {
+	return v.exfat.SaveMetadataCache(w)
+}
+
+// UsedMetadataCache 返回打开时是否使用了元数据缓存
+func (v *VHD) UsedMetadataCache() bool {
+	return v.exfat.UsedMetadataCache()
+}
+
+// ActiveFAT 返回活动 FAT 的编号（1 或 2）
+func (v *VHD) ActiveFAT() int {
+	return v.exfat.ActiveFAT()
+}
+
+// VolumeSize 返回引导扇区声明的卷大小（字节）
+func (v *VHD) VolumeSize() uint64 {
+	return v.exfat.VolumeSize()
+}
+
+// Info 返回卷的几何参数和标识（序列号、版本、扇区和簇的大小、各区域的偏移等）
+// 以及原始映像中卷之前被跳过的厂商头部长度
+func (v *VHD) Info() VolumeInfo {
+	info := v.exfat.Info()
+	if vhdFile, ok := diskOf(v.backend); ok {
+		info.SkippedPrefix = vhdFile.RawOffset()
+	}
+	return info
+}
+
+// FSInfo 返回卷的布局，供 TraceCheck 使用
+func (v *VHD) FSInfo() FSInfo {
+	return v.exfat.FSInfo()
+}
+
+// FreeClusters 按分配位图返回空闲的簇数
+func (v *VHD) FreeClusters() (uint32, error) {
+	return v.exfat.FreeClusters()
+}
+
+// Usage 按分配位图返回簇堆的总容量、已用和空闲空间（字节）
+func (v *VHD) Usage() (total, used, free uint64, err error) {
+	return v.exfat.Usage()
+}
+
+// VolumeStats 按分配位图、FAT 和目录树三种方法统计已用空间，位图不可用时退回到其他方法
+func (v *VHD) VolumeStats() (VolumeStats, error) {
+	return v.exfat.VolumeStats()
+}
+
+// Chtimes 修改 path 的修改时间和访问时间（零值时间保持原值），映像需要以 WithWritable 打开
+func (v *VHD) Chtimes(path string, mtime, atime time.Time) error {
+	return v.exfat.Chtimes(path, mtime, atime)
+}
+
+// Chattr 把 path 的属性设置为 attrs（目录位必须与条目类型一致），映像需要以 WithWritable 打开
+func (v *VHD) Chattr(path string, attrs Attributes) error {
+	return v.exfat.Chattr(path, attrs)
+}
+
+// VolumeLabel 返回卷标，没有卷标时返回空字符串
+func (v *VHD) VolumeLabel() (string, error) {
+	return v.exfat.VolumeLabel()
+}
+
+// FindFirst 返回 root 下第一个满足 pred 的条目及其路径，没有时返回 ErrNoMatch
+func (v *VHD) FindFirst(root string, pred func(path string, e FileEntry) bool) (string, *FileEntry, error) {
+	return v.exfat.FindFirst(root, pred)
+}
+
+// Check 检查目录树中每个条目的元数据、簇链、分配位图和交叉链接，簇链由 opts.Workers 个 goroutine 并行检查
+// 发现按路径排序，结果与并行度无关；ctx 取消时停止。
+func (v *VHD) Check(ctx context.Context, opts CheckOptions) (*VolumeCheck, error) {
+	return v.exfat.Check(ctx, opts)
+}
+
+// BuildIndex 遍历目录树建立只读索引，内存超过上限时降级为只索引目录，ctx 取消时停止
+func (v *VHD) BuildIndex(ctx context.Context, opts IndexOptions) (*Index, error) {
+	return v.exfat.BuildIndex(ctx, opts)
+}
+
+// Walk 深度优先遍历 root 及其下的目录树，对每个条目（包括 root 本身）以完整路径调用 fn，支持 filepath.SkipDir 和 filepath.SkipAll
+func (v *VHD) Walk(root string, fn func(path string, entry FileEntry) error) error {
+	return v.exfat.Walk(root, fn)
+}
+
+// WalkDir 与 fs.WalkDir 相同，深度优先遍历 root 下的目录树，支持 fs.SkipDir 和 fs.SkipAll
+// 指回上层的目录（损坏的映像）只进入一次，不会无限循环。
+func (v *VHD) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return v.exfat.WalkDir(root, fn)
+}
+
+// WalkPaths 按固定顺序对 root 下的每个条目调用 fn，maxDepth 大于 0 时限制遍历深度
+func (v *VHD) WalkPaths(root string, maxDepth int, fn func(path string, e FileEntry) error) error {
+	return v.exfat.WalkPaths(root, maxDepth, fn)
+}
+
 // ExtractFile 提取文件或目录到指定路径
 func (v *VHD) ExtractFile(srcPath, destPath string) error {
-	srcPath = normalizePath(srcPath)
+	return extract.Path(v.exfat, srcPath, destPath)
+}
+
+// ExtractAll 递归提取目录，并返回列出异常文件的报告
+// 单个文件的失败和结构异常（校验和、簇链、时间戳、名称哈希）都记录在报告中，不再打印警告；
+// 有条目失败时返回的错误包装了 ErrIncomplete。
+func (v *VHD) ExtractAll(srcPath, destPath string, opts ...ExtractOption) (*ExtractReport, error) {
+	return extract.AllWithReport(v.exfat, srcPath, destPath, opts...)
+}
+
+// ResolveEntryID 根据 EntryID 返回的标识查找对应的条目
+func (v *VHD) ResolveEntryID(id string) (FileEntry, error) {
+	return v.exfat.ResolveEntryID(id)
+}
+
+// EntryID 返回条目在映像中的确定性标识（VOLSER:DIRCLUSTER:OFFSET），只在映像未修改时稳定
+func EntryID(entry FileEntry) string {
+	return exfatfs.EntryID(entry)
+}
+
+// ExportPax 把 root 下的目录树以 pax（或 cpio）归档写入 w，保留 exFAT 的属性和时间
+func (v *VHD) ExportPax(root string, w io.Writer, opts ExportOptions) error {
+	return extract.ExportPax(v.exfat, root, w, opts)
+}
+
+// ExportPaxWithReport 与 ExportPax 相同，并返回列出无法读取的条目的报告
+func (v *VHD) ExportPaxWithReport(root string, w io.Writer, opts ExportOptions) (*ExtractReport, error) {
+	return extract.ExportPaxWithReport(v.exfat, root, w, opts)
+}
+
+// WriteTarGz 把 srcPath 下的目录树以流的方式写成 gzip 压缩的 tar（pax）归档
+// 头部保留修改时间和 exFAT 的属性、时间；文件按需读取，内存占用与文件大小无关。压缩级别用 WithGzipLevel 设置。
+func (v *VHD) WriteTarGz(srcPath string, w io.Writer, opts ...TarGzOption) error {
+	return extract.WriteTarGz(v.exfat, srcPath, w, opts...)
+}
+
+// Extract 与 ExtractFile 相同，提取文件或目录到 destDir 下，但返回报告而不是打印警告
+// 可以通过 WithManifest 和 WithProgress 在提取过程中获得逐条目的结果。
+func (v *VHD) Extract(srcPath, destDir string, opts ...ExtractOption) (*ExtractReport, error) {
+	return extract.PathWithReport(v.exfat, srcPath, destDir, opts...)
+}
+
+// ExtractWithCheck 在一次遍历中检查并提取 srcPath，按 policy 处理存在异常的条目
+// 返回检查结果和提取报告；policy 要求中止时返回的错误包装了 ErrCheckFailed。
+func (v *VHD) ExtractWithCheck(srcPath, destDir string, policy CheckPolicy, opts ...ExtractOption) (*CheckReport, *ExtractReport, error) {
+	return extract.ExtractWithCheck(v.exfat, srcPath, destDir, policy, opts...)
+}
+
+// TreeSize 统计 path 下的文件数和总字节数，用作进度汇总器的计划值
+func (v *VHD) TreeSize(path string) (files int, bytes int64, err error) {
+	return extract.TreeSize(v.exfat, path)
+}
+
+// VolumeIdentity 映像及其中 exFAT 卷的标识信息，用于在大量映像中查找指定的卷
+type VolumeIdentity struct {
+	Path         string `json:"path"`
+	DiskType     string `json:"disk_type"`           // "fixed"、"dynamic"、"differencing" 或 "raw"
+	UUID         string `json:"uuid,omitempty"`      // VHD 的唯一标识（原始映像没有）
+	Partition    int    `json:"partition,omitempty"` // 卷所在分区的序号（映像没有分区表时为 0）
+	VolumeOffset int64  `json:"volume_offset"`       // exFAT 卷在映像中的偏移（跳过的厂商头部或分区的起始位置）
+	Serial       string `json:"serial"`              // 卷序列号，如 "1A2B-3C4D"
+	Label        string `json:"label"`               // 卷标
+	Capacity     uint64 `json:"capacity"`            // 卷的字节数
+	ClusterSize  uint32 `json:"cluster_size"`        // 每簇字节数
+	Formatter    string `json:"formatter"`           // 格式化工具的指纹（引导代码的 CRC-32）
+}
 
-	entry, err := v.exfat.getEntry(srcPath)
+// Identify 打开映像并读取标识信息，不读取 FAT 和目录树
+// 只读取映像头部、引导扇区和根目录的第一个簇，扫描大量映像时每个只需要很少的读取。
+func Identify(path string, opts ...Option) (VolumeIdentity, error) {
+	backend, err := container.Open(path, applyOptions(opts).container...)
 	if err != nil {
-		return fmt.Errorf("failed to get entry for %s: %v", srcPath, err)
+		return VolumeIdentity{}, err
 	}
+	defer backend.Close()
 
-	if entry.IsDir {
-		return v.extractAllRecursive(srcPath, destPath)
+	id := VolumeIdentity{Path: path}
+	if vhdFile, ok := diskOf(backend); ok {
+		id.DiskType = vhdFile.ChainInfo()[0].DiskType
+		id.UUID = vhdFile.UniqueID()
+		id.VolumeOffset = vhdFile.RawOffset()
+	}
+	if pb, ok := backend.(*container.PartitionBackend); ok {
+		id.Partition = pb.Partition().Index
+		id.VolumeOffset += pb.Partition().Offset
 	}
 
-	return v.exfat.ExtractFile(srcPath, filepath.Join(destPath, entry.Name))
+	volume, err := exfatfs.IdentifyVolume(backend)
+	if err != nil {
+		return id, err
+	}
+	id.Serial = volume.Serial()
+	id.Label = volume.Label
+	id.Capacity = volume.Capacity
+	id.ClusterSize = volume.ClusterSize
+	id.Formatter = volume.Formatter
+	return id, nil
 }
 
-// extractAllRecursive 递归提取目录内容的内部实现
-func (v *VHD) extractAllRecursive(srcPath, destPath string) error {
-	// 获取当前目录的内容
-	entries, err := v.ListDir(srcPath)
-	if err != nil {
-		return fmt.Errorf("failed to list directory %s: %v", srcPath, err)
-	}
-
-	// 确保目标目录存在
-	if err := os.MkdirAll(destPath, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %v", destPath, err)
-	}
-
-	for _, entry := range entries {
-		// 构建源路径和目标路径
-		srcFullPath := filepath.Join(srcPath, entry.Name)
-		destFullPath := filepath.Join(destPath, entry.Name)
-
-		// 标准化路径分隔符（在 VHD 中使用正斜杠）
-		srcFullPath = normalizePath(srcFullPath)
-
-		if entry.IsDir {
-			// 创建目录
-			if err := os.MkdirAll(destFullPath, 0755); err != nil {
-				fmt.Printf("Warning: Failed to create directory %s: %v\n", destFullPath, err)
-				continue
-			}
-
-			// 尝试递归处理子目录
-			err := v.extractAllRecursive(srcFullPath, destFullPath)
-			if err != nil {
-				// 这可能是空目录或无效簇号的目录，这是正常的
-				fmt.Printf("Warning: Directory %s is empty or inaccessible: %v\n", entry.Name, err)
-				// 但目录结构已经创建，所以继续处理其他项目
-			}
-		} else {
-			// 处理文件
-			if err := v.exfat.ExtractFile(srcFullPath, destFullPath); err != nil {
-				fmt.Printf("Warning: Failed to extract file %s: %v\n", srcFullPath, err)
-				// 继续处理其他文件，不中断整个提取过程
-				continue
-			}
-
-			// 设置文件修改时间（如果可用）
-			if !entry.ModTime.IsZero() {
-				if err := setFileModTime(destFullPath, entry.ModTime); err != nil {
-					fmt.Printf("Warning: Failed to set modification time for file %s: %v\n", destFullPath, err)
-				}
-			}
+// DecodeIdentities 读取 identify 命令以 NDJSON 写出的卷标识，按顺序返回
+// 版本 0（没有 schema 字段）与版本 1 的字段相同；某一行无法解析或模式不符（见 CheckSchema）时返回带行号的错误。
+func DecodeIdentities(r io.Reader) ([]VolumeIdentity, error) {
+	var ids []VolumeIdentity
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var v struct {
+			Envelope
+			VolumeIdentity
 		}
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			return ids, fmt.Errorf("identity line %d: %w", line, err)
+		}
+		if _, err := CheckSchema(v.Schema, SchemaIdentity); err != nil {
+			return ids, fmt.Errorf("identity line %d: %w", line, err)
+		}
+		ids = append(ids, v.VolumeIdentity)
 	}
-
-	return nil
+	return ids, scanner.Err()
 }
@@ -0,0 +1,184 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Anomaly 描述条目上发现的一种结构异常
+type Anomaly string
+
+const (
+	AnomalyChecksumMismatch     Anomaly = "checksum mismatch"      // 条目集校验和不匹配
+	AnomalyShortChain           Anomaly = "short chain"            // 簇链比文件大小所需的短
+	AnomalyBadCluster           Anomaly = "bad cluster"            // 簇链经过坏簇或越界簇
+	AnomalyInvalidTimestamp     Anomaly = "invalid timestamp"      // 时间戳字段无法解码
+	AnomalyNameHashMismatch     Anomaly = "name hash mismatch"     // 名称哈希与文件名不符
+	AnomalyCrossLinked          Anomaly = "cross-linked"           // 簇链与另一个文件共用簇（需要 WithCrosslinkDetection，或由 Check 报告）
+	AnomalyExtraStreamExtension Anomaly = "extra stream extension" // 条目集中有多个流扩展条目，只使用第一个
+	AnomalyUnallocated          Anomaly = "unallocated cluster"    // 簇链经过分配位图中标为空闲的簇（只由 Check 报告）
+	AnomalyUsageDisagreement    Anomaly = "usage disagreement"     // 各种方法统计的已用空间不一致（只由 Check 报告在根目录上，见 VolumeStats）
+)
+
+// Anomalies 返回指定路径条目的异常列表
+// 元数据异常（校验和、名称哈希、时间戳）在解析目录时记录，簇链异常在此时沿 FAT 检查。
+func (fs *ExFATFileSystem) Anomalies(path string) ([]Anomaly, error) {
+	entry, err := fs.getEntry(normalizePath(path))
+	if err != nil {
+		return nil, err
+	}
+	return fs.entryAnomalies(entry), nil
+}
+
+// DirAnomalies 返回目录下每个存在异常的子条目（按名称）的异常列表
+// 提取整棵树时按目录批量获取，避免对每个文件重新解析路径。
+func (fs *ExFATFileSystem) DirAnomalies(path string) (map[string][]Anomaly, error) {
+	dir, err := fs.getEntry(normalizePath(path))
+	if err != nil {
+		return nil, err
+	}
+	if !dir.IsDir {
+		return nil, fmt.Errorf("%w: %s", ErrNotDirectory, path)
+	}
+
+	children, err := fs.readDirectoryEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]Anomaly)
+	for _, child := range children {
+		if anomalies := fs.entryAnomalies(child); len(anomalies) > 0 {
+			result[child.Name] = anomalies
+		}
+	}
+	return result, nil
+}
+
+// entryAnomalies 合并条目的元数据异常与簇链异常
+// 启用交叉链接检测时同时登记文件的簇链，因此提取时交叉链接在读取数据之前就作为异常报告。
+func (fs *ExFATFileSystem) entryAnomalies(entry *DirEntry) []Anomaly {
+	anomalies := append([]Anomaly(nil), entry.anomalies...)
+	if a := fs.chainAnomaly(entry); a != "" {
+		anomalies = append(anomalies, a)
+	}
+	if fs.crossLinked(entry) {
+		anomalies = append(anomalies, AnomalyCrossLinked)
+	}
+	return anomalies
+}
+
+// chainAnomaly 检查条目的簇链是否足以容纳其数据
+func (fs *ExFATFileSystem) chainAnomaly(entry *DirEntry) Anomaly {
+	if entry.Size <= 0 {
+		return ""
+	}
+	if !entry.hasClusters() {
+		return AnomalyShortChain
+	}
+
+	needed := (uint64(entry.Size) + uint64(fs.bytesPerCluster) - 1) / uint64(fs.bytesPerCluster)
+	lastCluster := uint64(fs.totalClusters) + 1
+
+	if entry.noFatChain {
+		// 连续分配的簇不经过 FAT，只需确认整段位于簇堆内
+		if uint64(entry.cluster)+needed-1 > lastCluster || fs.contiguousRun(entry.cluster, needed) < needed {
+			return AnomalyShortChain
+		}
+		return ""
+	}
+
+	cluster := fs.filter(entry.cluster)
+	for i := uint64(0); i < needed; i++ {
+		switch {
+		case cluster == BadCluster:
+			return AnomalyBadCluster
+		case fs.endOfChain(cluster) || cluster < 2:
+			return AnomalyShortChain
+		case uint64(cluster) > lastCluster || int(cluster) >= len(fs.fat):
+			return AnomalyBadCluster
+		}
+		cluster = fs.link(fs.fat, cluster)
+	}
+	return ""
+}
+
+// metadataAnomalies 检查条目集的校验和、名称哈希与时间戳
+// upcase 为卷上的大写表，读取失败时为 nil
+func metadataAnomalies(set []byte, fileEntry *ExFATFileEntry, info *ExFATFileInfoEntry, upcase []uint16) []Anomaly {
+	var anomalies []Anomaly
+	if len(set) < 32 || entrySetChecksum(set) != fileEntry.SetChecksum {
+		anomalies = append(anomalies, AnomalyChecksumMismatch)
+	}
+	if !nameHashMatches(setNameUnits(set, int(info.NameLength)), info.NameHash, upcase) {
+		anomalies = append(anomalies, AnomalyNameHashMismatch)
+	}
+	if !timestampValid(fileEntry.CreateTimestamp, fileEntry.Create10msIncrement) ||
+		!timestampValid(fileEntry.LastModifiedTimestamp, fileEntry.LastModified10msIncrement) ||
+		!timestampValid(fileEntry.LastAccessedTimestamp, 0) {
+		anomalies = append(anomalies, AnomalyInvalidTimestamp)
+	}
+	return anomalies
+}
+
+// setNameUnits 从条目集的文件名条目中取出前 nameLength 个 UTF-16 码元
+func setNameUnits(set []byte, nameLength int) []uint16 {
+	units := make([]uint16, 0, nameLength)
+	for off := 64; off+32 <= len(set) && len(units) < nameLength; off += 32 {
+		if set[off] != EntryTypeFileName {
+			continue
+		}
+		for j := 2; j < 32 && len(units) < nameLength; j += 2 {
+			units = append(units, binary.LittleEndian.Uint16(set[off+j:]))
+		}
+	}
+	return units
+}
+
+// timestampValid 判断时间戳能否解码；为 0 表示未设置，不算异常
+func timestampValid(timestamp uint32, increment uint8) bool {
+	if increment > 199 {
+		return false
+	}
+	return timestamp == 0 || !exfatTimeToTime(timestamp, 0, 0).IsZero()
+}
+
+// nameHashMatches 校验名称哈希
+// 优先使用卷上的大写表；大写表不可用时分别按 ASCII 和 Unicode 简单大写规则计算，
+// 任一匹配即认为正常，避免把大写规则的差异误报为异常。
+func nameHashMatches(nameUnits []uint16, hash uint16, upcase []uint16) bool {
+	if upcase != nil {
+		return nameHash(nameUnits, func(u uint16) uint16 { return upcase[u] }) == hash
+	}
+	return nameHash(nameUnits, upcaseASCII) == hash || nameHash(nameUnits, upcaseUnicode) == hash
+}
+
+// nameHash 按 exFAT 规范计算大写后名称的哈希
+func nameHash(nameUnits []uint16, upcase func(uint16) uint16) uint16 {
+	var hash uint16
+	for _, u := range nameUnits {
+		u = upcase(u)
+		for _, b := range [2]byte{byte(u), byte(u >> 8)} {
+			hash = (hash<<15 | hash>>1) + uint16(b)
+		}
+	}
+	return hash
+}
+
+func upcaseASCII(u uint16) uint16 {
+	if u >= 'a' && u <= 'z' {
+		return u - 'a' + 'A'
+	}
+	return u
+}
+
+func upcaseUnicode(u uint16) uint16 {
+	if utf16.IsSurrogate(rune(u)) {
+		return u
+	}
+	if r := unicode.ToUpper(rune(u)); r <= 0xFFFF {
+		return uint16(r)
+	}
+	return u
+}
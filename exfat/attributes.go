@@ -0,0 +1,177 @@
+package exfat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Attributes 文件属性位（FileAttributes 字段）
+type Attributes uint16
+
+// 文件属性位
+const (
+	AttrReadOnly  Attributes = 0x01 // 只读
+	AttrHidden    Attributes = 0x02 // 隐藏
+	AttrSystem    Attributes = 0x04 // 系统
+	AttrDirectory Attributes = 0x10 // 目录
+	AttrArchive   Attributes = 0x20 // 存档
+)
+
+// attributeNames 各属性位的字母和名称，按 String 的输出顺序排列
+var attributeNames = []struct {
+	bit    Attributes
+	letter byte
+	name   string
+}{
+	{AttrReadOnly, 'R', "readonly"},
+	{AttrHidden, 'H', "hidden"},
+	{AttrSystem, 'S', "system"},
+	{AttrArchive, 'A', "archive"},
+	{AttrDirectory, 'D', "directory"},
+}
+
+// Has 是否设置了 a 中的全部属性位
+func (a Attributes) Has(bits Attributes) bool {
+	return a&bits == bits
+}
+
+// String 以类似 attrib 的形式返回属性，如 "RHSA-"
+// 各位依次为只读、隐藏、系统、存档、目录，未设置的位用 "-" 表示；其他位不显示
+func (a Attributes) String() string {
+	s := make([]byte, len(attributeNames))
+	for i, n := range attributeNames {
+		s[i] = '-'
+		if a.Has(n.bit) {
+			s[i] = n.letter
+		}
+	}
+	return string(s)
+}
+
+// Names 返回已设置的属性的名称，如 ["readonly", "archive"]；未定义的位以十六进制列出
+func (a Attributes) Names() []string {
+	names := []string{}
+	rest := a
+	for _, n := range attributeNames {
+		if a.Has(n.bit) {
+			names = append(names, n.name)
+			rest &^= n.bit
+		}
+	}
+	if rest != 0 {
+		names = append(names, fmt.Sprintf("0x%04x", uint16(rest)))
+	}
+	return names
+}
+
+// Long 返回属性名称的长格式，如 "readonly,archive"，没有属性时为 "none"
+func (a Attributes) Long() string {
+	if a == 0 {
+		return "none"
+	}
+	return strings.Join(a.Names(), ",")
+}
+
+// MarshalJSON 把属性编码为名称数组
+func (a Attributes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Names())
+}
+
+// UnmarshalJSON 从名称数组解码属性
+func (a *Attributes) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	var attrs Attributes
+	for _, name := range names {
+		bit, err := parseAttributeName(name)
+		if err != nil {
+			return err
+		}
+		attrs |= bit
+	}
+	*a = attrs
+	return nil
+}
+
+// ParseAttributes 解析 String 的输出（如 "R--A-"），也接受不带 "-" 的字母组合（如 "RA"，不区分大小写）
+func ParseAttributes(s string) (Attributes, error) {
+	var attrs Attributes
+	for i := 0; i < len(s); i++ {
+		if s[i] == '-' {
+			continue
+		}
+		bit, ok := attributeLetter(s[i])
+		if !ok {
+			return 0, fmt.Errorf("invalid attribute %q in %q", s[i], s)
+		}
+		attrs |= bit
+	}
+	return attrs, nil
+}
+
+// ParseAttributeChanges 解析类似 attrib 的修改语法，如 "+h-r" 或 "+h,-r"
+// 返回需要设置和需要清除的属性位，可以用 ApplyChanges 应用到已有的属性上
+func ParseAttributeChanges(s string) (set, clear Attributes, err error) {
+	if s == "" {
+		return 0, 0, fmt.Errorf("empty attribute change")
+	}
+	sign := byte(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '+', '-':
+			sign = c
+			continue
+		case ',', ' ':
+			sign = 0
+			continue
+		}
+		bit, ok := attributeLetter(c)
+		if !ok || sign == 0 {
+			return 0, 0, fmt.Errorf("invalid attribute change %q: expected +X or -X with X one of R, H, S, A, D", s)
+		}
+		if sign == '+' {
+			set |= bit
+			clear &^= bit
+		} else {
+			clear |= bit
+			set &^= bit
+		}
+	}
+	return set, clear, nil
+}
+
+// ApplyChanges 设置 set 中的属性位并清除 clear 中的属性位
+func (a Attributes) ApplyChanges(set, clear Attributes) Attributes {
+	return a&^clear | set
+}
+
+// attributeLetter 返回属性字母对应的属性位（不区分大小写）
+func attributeLetter(c byte) (Attributes, bool) {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	for _, n := range attributeNames {
+		if n.letter == c {
+			return n.bit, true
+		}
+	}
+	return 0, false
+}
+
+// parseAttributeName 返回属性名称（或 Names 输出的十六进制值）对应的属性位
+func parseAttributeName(name string) (Attributes, error) {
+	for _, n := range attributeNames {
+		if strings.EqualFold(n.name, name) {
+			return n.bit, nil
+		}
+	}
+	var v uint16
+	if _, err := fmt.Sscanf(name, "0x%x", &v); err == nil {
+		return Attributes(v), nil
+	}
+	return 0, fmt.Errorf("unknown attribute %q", name)
+}
@@ -0,0 +1,104 @@
+package exfat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// allKnownAttributes String 能表示的全部属性位
+const allKnownAttributes = AttrReadOnly | AttrHidden | AttrSystem | AttrDirectory | AttrArchive
+
+func TestAttributesStringRoundTrip(t *testing.T) {
+	for v := 0; v <= 0xFFFF; v++ {
+		a := Attributes(v)
+		s := a.String()
+		if len(s) != 5 {
+			t.Fatalf("0x%04X: String %q is not 5 characters", v, s)
+		}
+		// 未定义的位不显示，往返之后只保留已知的位
+		got, err := ParseAttributes(s)
+		if err != nil || got != a&allKnownAttributes {
+			t.Fatalf("0x%04X: ParseAttributes(%q) = 0x%04X, %v", v, s, got, err)
+		}
+		// 去掉 "-"、改为小写后仍然解析为同样的属性
+		if got, err := ParseAttributes(strings.ToLower(strings.ReplaceAll(s, "-", ""))); err != nil || got != a&allKnownAttributes {
+			t.Fatalf("0x%04X: lower-case letters of %q parsed as 0x%04X, %v", v, s, got, err)
+		}
+	}
+}
+
+func TestAttributesJSONRoundTrip(t *testing.T) {
+	for v := 0; v <= 0xFFFF; v++ {
+		a := Attributes(v)
+		data, err := json.Marshal(a)
+		if err != nil {
+			t.Fatalf("0x%04X: %v", v, err)
+		}
+		var got Attributes
+		if err := json.Unmarshal(data, &got); err != nil || got != a {
+			t.Fatalf("0x%04X: %s decoded as 0x%04X, %v", v, data, got, err)
+		}
+		if want := strings.Join(a.Names(), ","); a != 0 && a.Long() != want {
+			t.Fatalf("0x%04X: Long %q, want %q", v, a.Long(), want)
+		}
+	}
+}
+
+func TestAttributesFormats(t *testing.T) {
+	tests := []struct {
+		attrs     Attributes
+		str, long string
+		json      string
+	}{
+		{0, "-----", "none", `[]`},
+		{AttrReadOnly | AttrArchive, "R--A-", "readonly,archive", `["readonly","archive"]`},
+		{AttrDirectory | AttrHidden | AttrSystem, "-HS-D", "hidden,system,directory", `["hidden","system","directory"]`},
+		{AttrArchive | 0x8000 | 0x40, "---A-", "archive,0x8040", `["archive","0x8040"]`},
+	}
+	for _, tt := range tests {
+		data, _ := json.Marshal(tt.attrs)
+		if tt.attrs.String() != tt.str || tt.attrs.Long() != tt.long || string(data) != tt.json {
+			t.Errorf("0x%04X: %q %q %s; want %q %q %s", uint16(tt.attrs), tt.attrs.String(), tt.attrs.Long(), data, tt.str, tt.long, tt.json)
+		}
+	}
+
+	for _, bad := range []string{"X", "R-Z", "RA!"} {
+		if _, err := ParseAttributes(bad); err == nil {
+			t.Errorf("ParseAttributes(%q) accepted invalid input", bad)
+		}
+	}
+	var a Attributes
+	if err := json.Unmarshal([]byte(`["readonly","bogus"]`), &a); err == nil {
+		t.Error("UnmarshalJSON accepted an unknown name")
+	}
+}
+
+func TestParseAttributeChanges(t *testing.T) {
+	tests := []struct {
+		in         string
+		set, clear Attributes
+		ok         bool
+	}{
+		{"+h", AttrHidden, 0, true},
+		{"+h-r", AttrHidden, AttrReadOnly, true},
+		{"+h,-r", AttrHidden, AttrReadOnly, true},
+		{"+HS -A", AttrHidden | AttrSystem, AttrArchive, true},
+		{"+r-r", 0, AttrReadOnly, true}, // 后出现的修改生效
+		{"-r+r", AttrReadOnly, 0, true},
+		{"", 0, 0, false},
+		{"h", 0, 0, false},
+		{"+h,r", 0, 0, false}, // 逗号之后需要重新指定符号
+		{"+x", 0, 0, false},
+	}
+	for _, tt := range tests {
+		set, clear, err := ParseAttributeChanges(tt.in)
+		if (err == nil) != tt.ok || set != tt.set || clear != tt.clear {
+			t.Errorf("ParseAttributeChanges(%q) = %v, %v, %v", tt.in, set, clear, err)
+		}
+	}
+
+	if got := (AttrReadOnly | AttrArchive).ApplyChanges(AttrHidden, AttrReadOnly); got != AttrHidden|AttrArchive {
+		t.Errorf("ApplyChanges = %v", got)
+	}
+}
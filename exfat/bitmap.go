@@ -0,0 +1,88 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// allocationBitmap 读取（并缓存）根目录中的簇分配位图
+func (fs *ExFATFileSystem) allocationBitmap() ([]byte, error) {
+	fs.bitmapOnce.Do(func() {
+		fs.bitmap, fs.bitmapErr = fs.readAllocationBitmap()
+	})
+	return fs.bitmap, fs.bitmapErr
+}
+
+// readAllocationBitmap 在根目录中查找分配位图条目并读取位图数据
+func (fs *ExFATFileSystem) readAllocationBitmap() ([]byte, error) {
+	buf, err := fs.readDirectoryData(fs.volumeRoot())
+	if err != nil {
+		return nil, err
+	}
+	defer fs.putBuffer(buf)
+	rootData := *buf
+
+	for offset := 0; offset+32 <= len(rootData); offset += 32 {
+		entryType := rootData[offset]
+		if entryType == EntryTypeEndOfDirectory {
+			break
+		}
+		// TexFAT 卷有两个位图，BitmapFlags 第 0 位为 0 的是第一个位图
+		if entryType != EntryTypeAllocationBitmap || rootData[offset+1]&0x01 != 0 {
+			continue
+		}
+
+		firstCluster := binary.LittleEndian.Uint32(rootData[offset+20:])
+		dataLength := binary.LittleEndian.Uint64(rootData[offset+24:])
+		if need := (uint64(fs.totalClusters) + 7) / 8; dataLength < need {
+			return nil, fmt.Errorf("allocation bitmap too small: %d bytes for %d clusters", dataLength, fs.totalClusters)
+		}
+
+		return fs.readClusterChain(firstCluster, dataLength, false, TraceBitmap)
+	}
+
+	return nil, fmt.Errorf("allocation bitmap entry not found in root directory")
+}
+
+// usedClusters 按分配位图统计已使用的簇数
+func (fs *ExFATFileSystem) usedClusters() (uint32, error) {
+	bitmap, err := fs.allocationBitmap()
+	if err != nil {
+		return 0, err
+	}
+
+	var used uint32
+	full := fs.totalClusters / 8
+	for _, b := range bitmap[:full] {
+		used += uint32(bits.OnesCount8(b))
+	}
+	// 最后一个字节中超出簇数量的位不计入
+	if rem := fs.totalClusters % 8; rem != 0 {
+		used += uint32(bits.OnesCount8(bitmap[full] & (1<<rem - 1)))
+	}
+	return used, nil
+}
+
+// FreeClusters 按分配位图返回空闲的簇数
+// 只读取位图（读取后缓存），不遍历目录树；位图条目缺失或被截断时返回错误，这时可以用 VolumeStats 退回到其他方法。
+func (fs *ExFATFileSystem) FreeClusters() (uint32, error) {
+	used, err := fs.usedClusters()
+	if err != nil {
+		return 0, err
+	}
+	return fs.totalClusters - min(used, fs.totalClusters), nil
+}
+
+// Usage 按分配位图返回簇堆的总容量、已用和空闲空间（字节），与 df 的统计方式相同
+// 容量只计簇堆，不包括引导区域和 FAT。
+func (fs *ExFATFileSystem) Usage() (total, used, free uint64, err error) {
+	freeClusters, err := fs.FreeClusters()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	clusterSize := uint64(fs.bytesPerCluster)
+	total = uint64(fs.totalClusters) * clusterSize
+	free = uint64(freeClusters) * clusterSize
+	return total, total - free, free, nil
+}
@@ -0,0 +1,157 @@
+package exfat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// 引导区布局
+const (
+	bootRegionSectors   = 12 // 主引导区与备份引导区各 12 个扇区
+	bootChecksumSector  = 11 // 校验和扇区
+	backupBootSectorLBA = 12 // 备份引导区起始扇区
+)
+
+// readBootSector 读取并解析引导扇区，验证引导区校验和
+// 返回的 bool 表示是否使用了备份引导区。校验和不匹配时，启用了备份引导区恢复则先尝试备份引导区，
+// 仍然无法使用时返回 ErrBootChecksum；跳过校验（WithoutBootChecksum）时改为在 ignored 中返回这个错误。
+func readBootSector(vhd io.ReaderAt, o *options) (bootSector *ExFATBootSector, usedBackup bool, ignored, err error) {
+	if o.tracer != nil {
+		vhd = tracedReader{r: vhd, tracer: o.tracer, purpose: TraceBoot}
+	}
+	bootSector, err = parseBootSectorAt(vhd, 0)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	checksumErr := verifyBootChecksum(vhd, 0, bootSector.BytesPerSectorShift)
+	if checksumErr == nil {
+		return bootSector, false, nil, nil
+	}
+
+	// 主引导区校验失败，尝试备份引导区
+	if o.backupBootRecovery {
+		if backup, ok := readBackupBootSector(vhd, bootSector); ok {
+			return backup, true, nil, nil
+		}
+	}
+	if o.skipBootChecksum {
+		return bootSector, false, checksumErr, nil
+	}
+	return nil, false, nil, checksumErr
+}
+
+// readBackupBootSector 读取备份引导区，仅在校验和正确且布局一致时返回
+func readBackupBootSector(vhd io.ReaderAt, primary *ExFATBootSector) (*ExFATBootSector, bool) {
+	// 优先使用主引导扇区声明的扇区大小，主引导扇区的该字段也可能损坏，因此再尝试其余合法值
+	shifts := []uint8{primary.BytesPerSectorShift, 9, 10, 11, 12}
+	for i, shift := range shifts {
+		if shift < 9 || shift > 12 || (i > 0 && shift == primary.BytesPerSectorShift) {
+			continue
+		}
+
+		base := backupBootSectorLBA * (int64(1) << shift)
+		backup, err := parseBootSectorAt(vhd, base)
+		if err != nil || backup.BytesPerSectorShift != shift {
+			continue
+		}
+		if verifyBootChecksum(vhd, base, shift) != nil {
+			continue
+		}
+		if validateLayout(backup) != nil {
+			continue
+		}
+		return backup, true
+	}
+	return nil, false
+}
+
+// parseBootSectorAt 在指定偏移解析引导扇区并验证签名
+func parseBootSectorAt(vhd io.ReaderAt, offset int64) (*ExFATBootSector, error) {
+	bootSectorData := make([]byte, 512)
+	_, err := vhd.ReadAt(bootSectorData, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read boot sector: %v", err)
+	}
+
+	bootSector := &ExFATBootSector{}
+	err = binary.Read(bytes.NewReader(bootSectorData), binary.LittleEndian, bootSector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse boot sector: %v", err)
+	}
+
+	// 验证 exFAT 签名
+	if string(bootSector.FileSystemName[:]) != "EXFAT   " {
+		return nil, ErrInvalidImage
+	}
+
+	return bootSector, nil
+}
+
+// verifyBootChecksum 验证从 base 开始、扇区大小为 2^shift 字节的引导区校验和
+// 校验和扇区中的每个 32 位值都必须等于前 11 个扇区的校验和；不匹配或无法读取引导区时返回包装了 ErrBootChecksum 的错误。
+func verifyBootChecksum(vhd io.ReaderAt, base int64, shift uint8) error {
+	if shift < 9 || shift > 12 {
+		return fmt.Errorf("%w: invalid bytes per sector shift %d", ErrBootChecksum, shift)
+	}
+	bytesPerSector := int64(1) << shift
+	region := make([]byte, bootRegionSectors*bytesPerSector)
+	if _, err := vhd.ReadAt(region, base); err != nil {
+		return fmt.Errorf("%w: failed to read boot region: %v", ErrBootChecksum, err)
+	}
+
+	sum := bootChecksum(region[:bootChecksumSector*bytesPerSector])
+	checksumSector := region[bootChecksumSector*bytesPerSector:]
+	for i := 0; i+4 <= len(checksumSector); i += 4 {
+		if stored := binary.LittleEndian.Uint32(checksumSector[i:]); stored != sum {
+			return fmt.Errorf("%w: checksum sector holds 0x%08X at offset %d, the boot region sums to 0x%08X", ErrBootChecksum, stored, i, sum)
+		}
+	}
+	return nil
+}
+
+// bootChecksum 计算引导区前 11 个扇区的校验和
+// VolumeFlags（偏移 106、107）和 PercentInUse（偏移 112）不参与计算
+func bootChecksum(data []byte) uint32 {
+	var sum uint32
+	for i, b := range data {
+		if i == 106 || i == 107 || i == 112 {
+			continue
+		}
+		sum = (sum<<31 | sum>>1) + uint32(b)
+	}
+	return sum
+}
+
+// validateLayout 检查引导扇区描述的各区域是否一致
+func validateLayout(bs *ExFATBootSector) error {
+	if bs.BytesPerSectorShift < 9 || bs.BytesPerSectorShift > 12 {
+		return fmt.Errorf("invalid bytes per sector shift: %d", bs.BytesPerSectorShift)
+	}
+	if uint32(bs.BytesPerSectorShift)+uint32(bs.SectorsPerClusterShift) > 25 {
+		return fmt.Errorf("invalid sectors per cluster shift: %d", bs.SectorsPerClusterShift)
+	}
+	if bs.NumberOfFats != 1 && bs.NumberOfFats != 2 {
+		return fmt.Errorf("invalid number of FATs: %d", bs.NumberOfFats)
+	}
+	if bs.FatOffset < 24 {
+		return fmt.Errorf("FAT offset %d overlaps the boot region", bs.FatOffset)
+	}
+	if uint64(bs.FatLength)<<bs.BytesPerSectorShift/4 < uint64(bs.ClusterCount)+2 {
+		return fmt.Errorf("FAT length %d is too small for %d clusters", bs.FatLength, bs.ClusterCount)
+	}
+	fatEnd := uint64(bs.FatOffset) + uint64(bs.FatLength)*uint64(bs.NumberOfFats)
+	if uint64(bs.ClusterHeapOffset) < fatEnd {
+		return fmt.Errorf("cluster heap offset %d overlaps the FAT", bs.ClusterHeapOffset)
+	}
+	heapEnd := uint64(bs.ClusterHeapOffset) + uint64(bs.ClusterCount)<<bs.SectorsPerClusterShift
+	if bs.VolumeLength != 0 && heapEnd > bs.VolumeLength {
+		return fmt.Errorf("cluster heap extends past the end of the volume")
+	}
+	if bs.FirstClusterOfRootDir < 2 || bs.FirstClusterOfRootDir > bs.ClusterCount+1 {
+		return fmt.Errorf("invalid root directory cluster: %d", bs.FirstClusterOfRootDir)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package exfat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// corruptBootImage 返回主引导扇区的 FatOffset 和根目录簇被改写、校验和不再匹配的卷
+func corruptBootImage(t *testing.T) (*testimage.Image, []byte) {
+	t.Helper()
+	data := fill(3000, 9)
+	img := testimage.Build(testimage.Options{}, testimage.Dir("Dir", testimage.File("file.bin", data)))
+	primary := img.BootSector(false)
+	binary.LittleEndian.PutUint32(primary[80:], 40)   // FatOffset
+	binary.LittleEndian.PutUint32(primary[96:], 1000) // FirstClusterOfRootDir
+	return img, data
+}
+
+func TestBackupBootRecovery(t *testing.T) {
+	img, data := corruptBootImage(t)
+
+	if _, err := NewExFATFileSystem(img.Disk()); !errors.Is(err, ErrBootChecksum) {
+		t.Fatalf("without recovery: got %v, want ErrBootChecksum", err)
+	}
+
+	fs := openImage(t, img, WithBackupBootRecovery())
+	if !fs.UsedBackupBootSector() {
+		t.Error("UsedBackupBootSector is false")
+	}
+	if fs.bootSector.FatOffset != 24 || fs.bootSector.FirstClusterOfRootDir != img.Entry("/").Clusters[0] {
+		t.Errorf("layout from the primary boot sector: FAT offset %d, root cluster %d", fs.bootSector.FatOffset, fs.bootSector.FirstClusterOfRootDir)
+	}
+	got, err := fs.ReadFile("/Dir/file.bin")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ReadFile: %d bytes, %v", len(got), err)
+	}
+}
+
+func TestBackupBootRecoveryIntactPrimary(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, testimage.File("a", []byte("a")))
+	// 备份引导区损坏不影响正常的主引导区
+	img.BootSector(true)[100] ^= 0xFF
+	fs := openImage(t, img, WithBackupBootRecovery())
+	if fs.UsedBackupBootSector() {
+		t.Error("used the backup boot sector although the primary is valid")
+	}
+}
+
+func TestBackupBootRecoveryBothBad(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(img *testimage.Image)
+	}{
+		{"backup checksum", func(img *testimage.Image) {
+			img.BootSector(true)[100] ^= 0xFF
+		}},
+		{"backup signature", func(img *testimage.Image) {
+			copy(img.BootSector(true)[3:], "NTFS    ")
+		}},
+		{"backup layout", func(img *testimage.Image) {
+			// 校验和正确但布局不一致（根目录簇超出簇堆）的备份同样不能使用
+			backup := img.BootSector(true)
+			binary.LittleEndian.PutUint32(backup[96:], 5000)
+			primary := append([]byte(nil), img.Bytes[:12*img.BytesPerSector]...)
+			img.SignBoot()
+			copy(img.Bytes, primary)
+		}},
+	}
+	for _, tt := range tests {
+		img, _ := corruptBootImage(t)
+		tt.corrupt(img)
+		if _, err := NewExFATFileSystem(img.Disk(), WithBackupBootRecovery()); !errors.Is(err, ErrBootChecksum) {
+			t.Errorf("%s: got %v, want ErrBootChecksum", tt.name, err)
+		}
+	}
+}
+
+// overallocatedFATImage 返回 FAT 区域远大于 ClusterCount 所需的卷：按 4000 个簇构建，
+// 引导扇区中的 ClusterCount 改为 64，FatLength 不变，所有数据都在前 64 个簇中
+func overallocatedFATImage() (*testimage.Image, []byte) {
+	data := fill(3000, 5)
+	img := testimage.Build(testimage.Options{ClusterCount: 4000}, testimage.Dir("Dir", testimage.File("file.bin", data)))
+	for _, backup := range []bool{false, true} {
+		binary.LittleEndian.PutUint32(img.BootSector(backup)[92:], 64)
+	}
+	img.SignBoot()
+	return img, data
+}
+
+func TestOverallocatedFAT(t *testing.T) {
+	img, data := overallocatedFATImage()
+	if sectors := img.FATLength / int64(img.BytesPerSector); sectors*int64(img.BytesPerSector)/4 < 10*66 {
+		t.Fatalf("FAT of %d sectors is not over-allocated", sectors)
+	}
+
+	var fatBytes int64
+	fs := openImage(t, img, WithStrict(), WithReadTracer(func(layer string, logical, physical, length int64, purpose string) {
+		if purpose == TraceFAT {
+			fatBytes += length
+		}
+	}))
+	// 只读取和解析 ClusterCount+2 项
+	if len(fs.fat) != 66 || fatBytes != 66*4 {
+		t.Errorf("parsed %d FAT entries from %d bytes, want 66 from %d", len(fs.fat), fatBytes, 66*4)
+	}
+	if got, err := fs.ReadFile("/Dir/file.bin"); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ReadFile: %d bytes, %v", len(got), err)
+	}
+	if d := fs.Diagnostics(); len(d) != 0 {
+		t.Errorf("diagnostics: %v", d)
+	}
+
+	// 指向裁剪后的 FAT 之外的簇链不会读取 FAT 区域中多余的项
+	fragmented := testimage.File("frag.bin", fill(1500, 6))
+	fragmented.Fragmented = true
+	img = testimage.Build(testimage.Options{ClusterCount: 4000}, fragmented)
+	img.SetFAT(img.Entry("/frag.bin").Clusters[0], 100)
+	img.SetFAT(100, img.Entry("/frag.bin").Clusters[1])
+	for _, backup := range []bool{false, true} {
+		binary.LittleEndian.PutUint32(img.BootSector(backup)[92:], 64)
+	}
+	img.SignBoot()
+	if _, err := openImage(t, img, WithStrict()).ReadFile("/frag.bin"); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("chain through cluster 100 of 65: got %v, want ErrBrokenChain", err)
+	}
+}
+
+func TestFATLengthTooSmall(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, testimage.File("file.bin", fill(100, 1)))
+	// 1024 个簇需要 9 个扇区的 FAT，只声明 8 个
+	for _, backup := range []bool{false, true} {
+		binary.LittleEndian.PutUint32(img.BootSector(backup)[84:], 8)
+	}
+	img.SignBoot()
+	for _, opts := range [][]Option{nil, {WithStrict()}, {WithBackupBootRecovery()}} {
+		_, err := NewExFATFileSystem(img.Disk(), opts...)
+		if !errors.Is(err, ErrInvalidImage) || !strings.Contains(err.Error(), "too few for 1024 clusters") {
+			t.Errorf("options %d: got %v, want ErrInvalidImage", len(opts), err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package exfat
+
+// 缓冲区池的所有权规则：从 getBuffer 取得的缓冲区只在取得它的函数内部使用，
+// 返回前交还给 putBuffer；需要交给调用者的数据（ReadFile 的结果、缓存的位图等）一律另行分配，
+// 或者在交还之前复制出来。
+
+// getBuffer 从池中取得长度为 n 的缓冲区
+// 池中的缓冲区至少为一个簇大小；目录等更大的数据在容量不足时重新分配，交还后同样可以复用
+func (fs *ExFATFileSystem) getBuffer(n int) *[]byte {
+	b, _ := fs.buffers.Get().(*[]byte)
+	if b == nil || cap(*b) < n {
+		buf := make([]byte, n, max(n, int(fs.bytesPerCluster)))
+		return &buf
+	}
+	*b = (*b)[:n]
+	return b
+}
+
+// putBuffer 把缓冲区交还给池
+func (fs *ExFATFileSystem) putBuffer(b *[]byte) {
+	fs.buffers.Put(b)
+}
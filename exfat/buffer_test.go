@@ -0,0 +1,164 @@
+package exfat
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// poolImage 返回 4 KiB 簇的卷：一个连续的和一个碎片化的 64 KiB 文件，以及一个占多个簇的目录
+func poolImage() *testimage.Image {
+	frag := testimage.File("frag.bin", fill(64<<10, 2))
+	frag.Fragmented = true
+	var many []*testimage.Node
+	for i := 0; i < 200; i++ {
+		many = append(many, testimage.File(fmt.Sprintf("f%03d", i), fill(i, byte(i))))
+	}
+	return testimage.Build(testimage.Options{ClusterShift: 3},
+		testimage.File("contig.bin", fill(64<<10, 1)), frag, testimage.Dir("Many", many...))
+}
+
+// allocatedBytes 返回 f 每次运行平均分配的字节数
+func allocatedBytes(runs int, f func()) uint64 {
+	f()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < runs; i++ {
+		f()
+	}
+	runtime.ReadMemStats(&after)
+	return (after.TotalAlloc - before.TotalAlloc) / uint64(runs)
+}
+
+func TestBufferPoolReuse(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops objects under the race detector")
+	}
+	fs := openImage(t, poolImage())
+	cluster := int(fs.bytesPerCluster)
+
+	b := fs.getBuffer(10)
+	if len(*b) != 10 || cap(*b) < cluster {
+		t.Fatalf("getBuffer(10): len %d cap %d, want cap of at least one cluster", len(*b), cap(*b))
+	}
+	fs.putBuffer(b)
+	if allocs := testing.AllocsPerRun(100, func() {
+		fs.putBuffer(fs.getBuffer(cluster))
+	}); allocs != 0 {
+		t.Errorf("get and put of a cluster buffer: %v allocations per run", allocs)
+	}
+
+	// 更大的缓冲区按需分配，交还后同样被复用
+	big := fs.getBuffer(3 * cluster)
+	if len(*big) != 3*cluster {
+		t.Fatalf("getBuffer(3 clusters): len %d", len(*big))
+	}
+	fs.putBuffer(big)
+	if allocs := testing.AllocsPerRun(100, func() {
+		fs.putBuffer(fs.getBuffer(3 * cluster))
+	}); allocs != 0 {
+		t.Errorf("get and put of a larger buffer: %v allocations per run", allocs)
+	}
+}
+
+func TestPooledReadsAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops objects under the race detector")
+	}
+	fs := openImage(t, poolImage())
+	for _, name := range []string{"/contig.bin", "/frag.bin"} {
+		f, err := fs.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 中转缓冲区来自池，簇游程已经解析，复制整个文件不再分配内存
+		if allocs := testing.AllocsPerRun(50, func() {
+			f.Seek(0, io.SeekStart)
+			if n, err := f.WriteTo(io.Discard); err != nil || n != 64<<10 {
+				t.Fatalf("%s: WriteTo = %d, %v", name, n, err)
+			}
+		}); allocs != 0 {
+			t.Errorf("%s: %v allocations per WriteTo, want 0", name, allocs)
+		}
+	}
+
+	// 目录数据读入池中的缓冲区，列出目录只为条目本身分配内存；每次清空池时多分配整个目录的数据
+	list := func() {
+		if _, err := fs.ListDir("/Many"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pooled := allocatedBytes(20, list)
+	unpooled := allocatedBytes(20, func() {
+		fs.buffers = sync.Pool{}
+		list()
+	})
+	if dirSize := uint64(200 * 3 * 32); unpooled < pooled+dirSize {
+		t.Errorf("ListDir allocates %d bytes with the pool and %d without, want a saving of at least %d", pooled, unpooled, dirSize)
+	}
+}
+
+func TestBufferPoolConcurrent(t *testing.T) {
+	img := poolImage()
+	fs := openImage(t, img)
+	want := map[string][]byte{"/contig.bin": fill(64<<10, 1), "/frag.bin": fill(64<<10, 2)}
+
+	// 每个 goroutine 交替使用读取、流式复制和列出目录，池中的缓冲区被不同的 goroutine 反复取用；
+	// 任何缓冲区在交还之后仍被引用都会使内容出错，并被竞态检测器发现
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				for name, data := range want {
+					got, err := fs.ReadFile(name)
+					if err != nil || !bytes.Equal(got, data) {
+						t.Errorf("ReadFile(%s): %d bytes, %v", name, len(got), err)
+						return
+					}
+					f, err := fs.Open(name)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					var buf bytes.Buffer
+					if _, err := io.Copy(&buf, f); err != nil || !bytes.Equal(buf.Bytes(), data) {
+						t.Errorf("copy of %s: %d bytes, %v", name, buf.Len(), err)
+						return
+					}
+				}
+				entries, err := fs.ListDir("/Many")
+				if err != nil || len(entries) != 200 || entries[(g+i)%200].Name != fmt.Sprintf("f%03d", (g+i)%200) {
+					t.Errorf("ListDir: %d entries, %v", len(entries), err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkFileWriteTo(b *testing.B) {
+	fs, err := NewExFATFileSystem(poolImage().Disk())
+	if err != nil {
+		b.Fatal(err)
+	}
+	f, err := fs.Open("/frag.bin")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.SetBytes(64 << 10)
+	for i := 0; i < b.N; i++ {
+		f.Seek(0, io.SeekStart)
+		if _, err := f.WriteTo(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package exfat
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// 元数据缓存文件的标识
+const metadataCacheMagic = "EXFATMC1"
+
+// errStaleCache 缓存与映像不一致
+var errStaleCache = errors.New("metadata cache does not match the image")
+
+// metadataCacheHeader 缓存文件头部，之后是 FATEntries 个 FAT 项和整个文件的 CRC-32
+type metadataCacheHeader struct {
+	Magic      [8]byte
+	Serial     uint32   // 卷序列号
+	BootHash   [32]byte // 主引导区（12 个扇区）的 SHA-256
+	BitmapHash [32]byte // 分配位图的 SHA-256
+	FATEntries uint32
+}
+
+// SaveMetadataCache 把已解析的 FAT 写入 w，供以后用 LoadMetadataCache 或 WithMetadataCache 跳过 FAT 的读取
+// 缓存以卷序列号、引导区和分配位图的哈希为键：文件的增删会改变分配位图，旧的缓存会被自动拒绝；
+// 只改动 FAT 而不改动位图的修改无法检测，调用方需要自行按映像的修改时间等淘汰缓存。
+func (fs *ExFATFileSystem) SaveMetadataCache(w io.Writer) error {
+	bootHash, err := fs.bootRegionHash()
+	if err != nil {
+		return err
+	}
+	bitmap, err := fs.allocationBitmap()
+	if err != nil {
+		return fmt.Errorf("cannot cache metadata without the allocation bitmap: %v", err)
+	}
+
+	hdr := metadataCacheHeader{
+		Serial:     fs.bootSector.VolumeSerialNumber,
+		BootHash:   bootHash,
+		BitmapHash: sha256.Sum256(bitmap),
+		FATEntries: uint32(len(fs.fat)),
+	}
+	copy(hdr.Magic[:], metadataCacheMagic)
+
+	var buf bytes.Buffer
+	buf.Grow(binary.Size(hdr) + len(fs.fat)*4 + 4)
+	binary.Write(&buf, binary.LittleEndian, hdr)
+	binary.Write(&buf, binary.LittleEndian, fs.fat)
+	binary.Write(&buf, binary.LittleEndian, crc32.ChecksumIEEE(buf.Bytes()))
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// LoadMetadataCache 从 SaveMetadataCache 写出的缓存中载入 FAT
+// 缓存损坏、属于其他卷或映像已被修改时返回错误，文件系统保持不变。
+func (fs *ExFATFileSystem) LoadMetadataCache(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata cache: %v", err)
+	}
+
+	var hdr metadataCacheHeader
+	hdrSize := binary.Size(hdr)
+	if len(data) < hdrSize+4 {
+		return fmt.Errorf("metadata cache is truncated")
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return fmt.Errorf("metadata cache is corrupted")
+	}
+	binary.Read(bytes.NewReader(body), binary.LittleEndian, &hdr)
+	if string(hdr.Magic[:]) != metadataCacheMagic {
+		return fmt.Errorf("not a metadata cache")
+	}
+	if uint64(hdr.FATEntries) != fs.fatEntries() || len(body) != hdrSize+int(hdr.FATEntries)*4 {
+		return errStaleCache
+	}
+	if hdr.Serial != fs.bootSector.VolumeSerialNumber {
+		return errStaleCache
+	}
+	bootHash, err := fs.bootRegionHash()
+	if err != nil {
+		return err
+	}
+	if hdr.BootHash != bootHash {
+		return errStaleCache
+	}
+
+	fat := make([]uint32, hdr.FATEntries)
+	for i := range fat {
+		fat[i] = binary.LittleEndian.Uint32(body[hdrSize+i*4:])
+	}
+
+	// 用缓存中的 FAT 读取分配位图并与缓存时的哈希比较，不一致说明映像已被修改
+	previous := fs.fat
+	fs.fat = fat
+	bitmap, err := fs.readAllocationBitmap()
+	if err != nil || sha256.Sum256(bitmap) != hdr.BitmapHash {
+		fs.fat = previous
+		return errStaleCache
+	}
+
+	fs.bitmapOnce.Do(func() {
+		fs.bitmap = bitmap
+	})
+	fs.usedCache = true
+	return nil
+}
+
+// UsedMetadataCache 返回 FAT 是否来自元数据缓存（见 WithMetadataCache）
+func (fs *ExFATFileSystem) UsedMetadataCache() bool {
+	return fs.usedCache
+}
+
+// bootRegionHash 计算主引导区的 SHA-256
+func (fs *ExFATFileSystem) bootRegionHash() ([32]byte, error) {
+	region := make([]byte, bootRegionSectors*int(fs.bytesPerSector))
+	if _, err := fs.readAt(region, 0, TraceBoot); err != nil {
+		return [32]byte{}, fmt.Errorf("failed to read boot region: %v", err)
+	}
+	return sha256.Sum256(region), nil
+}
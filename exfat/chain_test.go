@@ -0,0 +1,111 @@
+package exfat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+func TestZeroedFAT(t *testing.T) {
+	contiguous := fill(5000, 1)
+	fragmented := testimage.File("fragmented.bin", fill(5000, 2))
+	fragmented.Fragmented = true
+	single := testimage.File("single.bin", fill(300, 3)) // 只有一个簇，不需要 FAT
+	single.FATChain = true
+	img := testimage.Build(testimage.Options{}, testimage.Dir("D",
+		testimage.File("contiguous.bin", contiguous), fragmented, single))
+
+	// 像 TRIM 之后一样清零 FAT 中两个保留项之后的所有项（卷只有一个 FAT）
+	clear(img.Bytes[img.FATOffset+8 : img.FATOffset+img.FATLength])
+	fs := openImage(t, img)
+
+	readBoth := func(p string) ([]byte, error, error) {
+		data, err := fs.ReadFile(p)
+		f, openErr := fs.Open(p)
+		if openErr != nil {
+			return data, err, openErr
+		}
+		defer f.Close()
+		_, readErr := io.ReadAll(f)
+		return data, err, readErr
+	}
+
+	// 连续分配的文件不经过 FAT，照常读取
+	if data, err, readErr := readBoth("/D/contiguous.bin"); err != nil || readErr != nil || !bytes.Equal(data, contiguous) {
+		t.Errorf("contiguous: %d bytes, %v, %v", len(data), err, readErr)
+	}
+	if data, err, readErr := readBoth("/D/single.bin"); err != nil || readErr != nil || !bytes.Equal(data, single.Data) {
+		t.Errorf("single cluster: %d bytes, %v, %v", len(data), err, readErr)
+	}
+	// 碎片化的文件不能再按相邻簇猜测，ReadFile 和 File.Read 都报告 ErrBrokenChain
+	_, err, readErr := readBoth("/D/fragmented.bin")
+	if !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("fragmented ReadFile: got %v, want ErrBrokenChain", err)
+	}
+	if !errors.Is(readErr, ErrBrokenChain) {
+		t.Errorf("fragmented Read: got %v, want ErrBrokenChain", readErr)
+	}
+}
+
+func TestIntactFATFragmented(t *testing.T) {
+	fragmented := testimage.File("fragmented.bin", fill(5000, 2))
+	fragmented.Fragmented = true
+	fs := openImage(t, testimage.Build(testimage.Options{}, fragmented))
+	if data, err := fs.ReadFile("/fragmented.bin"); err != nil || !bytes.Equal(data, fragmented.Data) {
+		t.Errorf("ReadFile: %d bytes, %v", len(data), err)
+	}
+}
+
+func TestDivergedFATs(t *testing.T) {
+	for _, active := range []int{1, 2} {
+		fragmented := testimage.File("fragmented.bin", fill(5000, 4))
+		fragmented.Fragmented = true
+		img := testimage.Build(testimage.Options{NumberOfFATs: 2}, testimage.Dir("D", fragmented))
+		if active == 2 {
+			img.BootSector(false)[106] |= volumeFlagActiveFat
+			img.BootSector(true)[106] |= volumeFlagActiveFat
+			img.SignBoot()
+		}
+		// 只在活动 FAT 中把簇链的第二个簇标记为空闲，另一个 FAT 仍然完整
+		e := img.Entry("/D/fragmented.bin")
+		entry := img.FATOffset + int64(active-1)*img.FATLength + 4*int64(e.Clusters[1])
+		clear(img.Bytes[entry : entry+4])
+
+		fs := openImage(t, img)
+		if fs.ActiveFAT() != active {
+			t.Fatalf("ActiveFAT() = %d, want %d", fs.ActiveFAT(), active)
+		}
+		if data, err := fs.ReadFile("/D/fragmented.bin"); err != nil || !bytes.Equal(data, fragmented.Data) {
+			t.Errorf("FAT %d active: lenient ReadFile: %d bytes, %v", active, len(data), err)
+		}
+		f, err := fs.Open("/D/fragmented.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, err := io.ReadAll(f); err != nil || !bytes.Equal(data, fragmented.Data) || f.FAT() != 3-active {
+			t.Errorf("FAT %d active: Read: %d bytes, %v, served by FAT %d", active, len(data), err, f.FAT())
+		}
+		f.Close()
+		found := false
+		for _, d := range fs.Diagnostics() {
+			found = found || (d.Path == "/D/fragmented.bin" && strings.Contains(d.Message, fmt.Sprintf("read using FAT %d", 3-active)))
+		}
+		if !found {
+			t.Errorf("FAT %d active: fallback not recorded: %v", active, fs.Diagnostics())
+		}
+
+		// 严格模式不回退
+		strict := openImage(t, img, WithStrict())
+		if _, err := strict.ReadFile("/D/fragmented.bin"); !errors.Is(err, ErrBrokenChain) {
+			t.Errorf("FAT %d active: strict ReadFile: got %v, want ErrBrokenChain", active, err)
+		}
+		if _, err := strict.Open("/D/fragmented.bin"); !errors.Is(err, ErrBrokenChain) {
+			t.Errorf("FAT %d active: strict Open: got %v, want ErrBrokenChain", active, err)
+		}
+	}
+}
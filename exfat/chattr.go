@@ -0,0 +1,34 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// validAttributes exFAT 定义的属性位，其余位保留
+const validAttributes = AttrReadOnly | AttrHidden | AttrSystem | AttrDirectory | AttrArchive
+
+// Chattr 把 path 的属性设置为 attrs，并重新计算条目集校验和
+// 用于切换只读、隐藏、系统和存档位（如备份后清除存档位）。attrs 中的目录位必须与条目的类型一致，
+// 保留位不能设置。底层映像的要求与 Chtimes 相同，不可写时返回 ErrReadOnly。
+func (fs *ExFATFileSystem) Chattr(path string, attrs Attributes) error {
+	if attrs&^validAttributes != 0 {
+		return fmt.Errorf("invalid attribute bits 0x%04X", uint16(attrs&^validAttributes))
+	}
+	w, entry, set, offset, err := fs.entryForWrite(path)
+	if err != nil {
+		return err
+	}
+	if attrs.Has(AttrDirectory) != entry.IsDir {
+		if entry.IsDir {
+			return fmt.Errorf("%s is a directory: the directory attribute cannot be cleared", path)
+		}
+		return fmt.Errorf("%s is a file: the directory attribute cannot be set", path)
+	}
+
+	binary.LittleEndian.PutUint16(set[4:], uint16(attrs))
+	if err := writeEntrySet(w, set, offset); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
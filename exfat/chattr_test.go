@@ -0,0 +1,75 @@
+package exfat
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+func TestChattrRoundTrip(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, testimage.Dir("D", testimage.File("f.txt", []byte("x"))))
+	tests := []struct {
+		path  string
+		attrs Attributes
+	}{
+		{"/D/f.txt", AttrReadOnly | AttrHidden | AttrSystem | AttrArchive},
+		{"/D/f.txt", 0},
+		{"/D/f.txt", AttrReadOnly},
+		{"/D", AttrDirectory | AttrHidden},
+		{"/D", AttrDirectory},
+	}
+	for _, tt := range tests {
+		if err := openImage(t, img).Chattr(tt.path, tt.attrs); err != nil {
+			t.Fatalf("Chattr(%s, %v): %v", tt.path, tt.attrs, err)
+		}
+		if _, ok := storedSet(img, img.Entry(tt.path)); !ok {
+			t.Fatalf("%s: entry set checksum does not match after Chattr", tt.path)
+		}
+		e, err := openImage(t, img, WithStrict()).Stat(tt.path)
+		if err != nil || e.Attributes != tt.attrs {
+			t.Errorf("%s: attributes %v, %v; want %v", tt.path, e.Attributes, err, tt.attrs)
+		}
+	}
+
+	// 只改属性，文件内容和其他元数据不变
+	fs := openImage(t, img)
+	if got, err := fs.ReadFile("/D/f.txt"); err != nil || string(got) != "x" {
+		t.Errorf("ReadFile after Chattr: %q, %v", got, err)
+	}
+}
+
+func TestChattrErrors(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, testimage.Dir("D"), testimage.File("f", nil))
+	before := bytes.Clone(img.Bytes)
+	fs := openImage(t, img)
+
+	for _, tt := range []struct {
+		path  string
+		attrs Attributes
+	}{
+		{"/f", AttrArchive | 0x40}, // 保留位
+		{"/f", AttrDirectory},      // 文件不能设置目录位
+		{"/D", AttrHidden},         // 目录不能清除目录位
+		{"/", AttrDirectory},       // 根目录没有条目集
+	} {
+		if err := fs.Chattr(tt.path, tt.attrs); err == nil {
+			t.Errorf("Chattr(%s, 0x%04X) succeeded", tt.path, uint16(tt.attrs))
+		}
+	}
+	if err := fs.Chattr("/missing", AttrArchive); !errors.Is(err, ErrNotExist) {
+		t.Errorf("missing file: got %v, want ErrNotExist", err)
+	}
+	if !bytes.Equal(img.Bytes, before) {
+		t.Error("failed calls modified the image")
+	}
+
+	ro, err := NewExFATFileSystem(readerOnly{img.Disk()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ro.Chattr("/f", AttrReadOnly); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("read-only image: got %v, want ErrReadOnly", err)
+	}
+}
@@ -0,0 +1,267 @@
+package exfat
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// CheckOptions Check 的选项
+type CheckOptions struct {
+	Root    string // 检查的目录，默认为根目录
+	Workers int    // 并行检查簇链的 goroutine 数，0 表示 runtime.GOMAXPROCS(0)
+}
+
+// VolumeFinding Check 发现问题的一个条目
+type VolumeFinding struct {
+	Path      string    // 条目的路径
+	IsDir     bool      // 是否为目录
+	Anomalies []Anomaly // 发现的异常，顺序固定：元数据异常、簇链异常、未分配的簇、交叉链接、已用空间不一致
+	Err       error     // 目录无法读取时的错误，此时目录下的条目没有检查
+}
+
+// VolumeCheck Check 的结果
+type VolumeCheck struct {
+	Checked  int             // 检查的条目数
+	Findings []VolumeFinding // 存在问题的条目，按路径排序
+	Usage    *VolumeStats    // 检查整个卷时的已用空间统计，只检查子目录或无法统计时为 nil
+}
+
+// Check 检查 Root 下每个条目的元数据、簇链、分配位图和交叉链接
+// 目录树由一个 goroutine 按固定顺序遍历，每个条目的簇链交给 Workers 个 goroutine 并行检查：
+// 簇链是否足以容纳数据（AnomalyShortChain、AnomalyBadCluster）、链上的簇是否在分配位图中标为已分配
+// （AnomalyUnallocated，位图无法读取时不检查）、以及是否与其他条目共用簇（AnomalyCrossLinked）。
+// 交叉链接按整个检查范围判定，共用簇的所有条目都会被报告，与检查的先后无关，因此 Workers 不同时结果完全相同。
+// 与 WithCrosslinkDetection 无关，也不影响其登记的簇。Workers 大于 1 时簇过滤器（见 WithClusterFilter）会被并发调用。
+// 检查整个卷（Root 为卷的根目录）时还按 VolumeStats 统计已用空间，各方法的结果不一致时在根目录上报告
+// AnomalyUsageDisagreement。无法读取的目录作为带 Err 的发现报告，遍历继续；ctx 取消时返回 ctx.Err()。
+func (fs *ExFATFileSystem) Check(ctx context.Context, opts CheckOptions) (*VolumeCheck, error) {
+	root := opts.Root
+	if root == "" {
+		root = "/"
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	start, err := fs.getEntry(normalizePath(root))
+	if err != nil {
+		return nil, err
+	}
+
+	bitmap, err := fs.allocationBitmap()
+	if err != nil {
+		fs.diagnose("", "allocation bitmap unavailable, cluster allocation not checked: %v", err)
+		bitmap = nil
+	}
+	c := &checker{
+		fs:      fs,
+		bitmap:  bitmap,
+		claimed: newClusterBits(uint64(fs.totalClusters) + 2),
+		shared:  newClusterBits(uint64(fs.totalClusters) + 2),
+	}
+
+	// 第一遍：逐条目检查并登记簇，记下被多次登记的簇
+	var checked atomic.Int64
+	var mu sync.Mutex
+	findings := make(map[string]*VolumeFinding)
+	err = c.run(ctx, start, workers, func(e *DirEntry) {
+		checked.Add(1)
+		if anomalies := c.entry(e); len(anomalies) > 0 {
+			mu.Lock()
+			findings[e.path] = &VolumeFinding{Path: e.path, IsDir: e.IsDir, Anomalies: anomalies}
+			mu.Unlock()
+		}
+	}, func(dir *DirEntry, err error) {
+		mu.Lock()
+		f := findings[dir.path]
+		if f == nil {
+			f = &VolumeFinding{Path: dir.path, IsDir: true}
+			findings[dir.path] = f
+		}
+		f.Err = err
+		mu.Unlock()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 第二遍（只在有簇被多次登记时）：找出每个这样的簇属于哪些条目，属于两个以上条目的才是交叉链接，
+	// 只属于一个条目的是该条目的簇链自身循环
+	if c.sharedFound.Load() {
+		owners := make(map[uint32][]*DirEntry)
+		err = c.run(ctx, start, workers, func(e *DirEntry) {
+			touched := c.sharedClusters(e)
+			if len(touched) == 0 {
+				return
+			}
+			mu.Lock()
+			for _, cluster := range touched {
+				owners[cluster] = append(owners[cluster], e)
+			}
+			mu.Unlock()
+		}, func(*DirEntry, error) {})
+		if err != nil {
+			return nil, err
+		}
+		crossLinked := make(map[string]*DirEntry)
+		for _, list := range owners {
+			if len(list) > 1 {
+				for _, e := range list {
+					crossLinked[e.path] = e
+				}
+			}
+		}
+		for path, e := range crossLinked {
+			f := findings[path]
+			if f == nil {
+				f = &VolumeFinding{Path: path, IsDir: e.IsDir}
+				findings[path] = f
+			}
+			f.Anomalies = append(f.Anomalies, AnomalyCrossLinked)
+		}
+	}
+
+	result := &VolumeCheck{Checked: int(checked.Load())}
+	if start.IsDir && start.cluster == fs.bootSector.FirstClusterOfRootDir {
+		if stats, err := fs.VolumeStats(); err != nil {
+			fs.diagnose("", "volume usage not checked: %v", err)
+		} else {
+			result.Usage = &stats
+			if stats.Disagreement > 0 {
+				f := findings[start.path]
+				if f == nil {
+					f = &VolumeFinding{Path: start.path, IsDir: true}
+					findings[start.path] = f
+				}
+				f.Anomalies = append(f.Anomalies, AnomalyUsageDisagreement)
+			}
+		}
+	}
+	for _, f := range findings {
+		result.Findings = append(result.Findings, *f)
+	}
+	sort.Slice(result.Findings, func(i, j int) bool {
+		return result.Findings[i].Path < result.Findings[j].Path
+	})
+	return result, nil
+}
+
+// checker 一次 Check 的共享状态，entry 和 sharedClusters 可以并发调用
+type checker struct {
+	fs          *ExFATFileSystem
+	bitmap      []byte      // 分配位图，无法读取时为 nil
+	claimed     clusterBits // 已被某个条目登记的簇
+	shared      clusterBits // 被登记了不止一次的簇
+	sharedFound atomic.Bool // shared 中是否有任何簇
+}
+
+// run 单线程深度优先遍历 start 下的目录树，把每个条目（含 start）交给 workers 个 goroutine 执行 fn
+// 目录无法读取时调用 dirErr 并继续遍历；ctx 取消时停止遍历，等待已分发的条目完成后返回 ctx.Err()。
+func (c *checker) run(ctx context.Context, start *DirEntry, workers int, fn func(*DirEntry), dirErr func(*DirEntry, error)) error {
+	jobs := make(chan *DirEntry, workers*64)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				fn(e)
+			}
+		}()
+	}
+
+	visited := make(map[uint32]bool)
+	var walk func(e *DirEntry) error
+	walk = func(e *DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		jobs <- e
+		if !e.IsDir {
+			return nil
+		}
+		if !firstVisit(visited, e) {
+			c.fs.diagnose(e.path, "directory cluster %d already visited, not descending again", e.cluster)
+			return nil
+		}
+		children, err := c.fs.readDirectoryEntries(e)
+		if err != nil {
+			dirErr(e, err)
+			return nil
+		}
+		for _, child := range children {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := walk(start)
+	close(jobs)
+	wg.Wait()
+	return err
+}
+
+// entry 返回条目的元数据异常、簇链异常和未分配的簇，并在 claimed 中登记条目的簇
+func (c *checker) entry(e *DirEntry) []Anomaly {
+	anomalies := append([]Anomaly(nil), e.anomalies...)
+	if a := c.fs.chainAnomaly(e); a != "" {
+		anomalies = append(anomalies, a)
+	}
+	unallocated := false
+	c.fs.chainClusters(c.fs.fat, e, func(cluster uint32) {
+		if c.bitmap != nil && !clusterAllocated(c.bitmap, cluster) {
+			unallocated = true
+		}
+		if c.claimed.set(cluster) {
+			c.shared.set(cluster)
+			c.sharedFound.Store(true)
+		}
+	})
+	if unallocated {
+		anomalies = append(anomalies, AnomalyUnallocated)
+	}
+	return anomalies
+}
+
+// sharedClusters 返回条目的簇链经过的、被登记了不止一次的簇（不重复）
+func (c *checker) sharedClusters(e *DirEntry) []uint32 {
+	var touched []uint32
+	seen := make(map[uint32]bool)
+	c.fs.chainClusters(c.fs.fat, e, func(cluster uint32) {
+		if c.shared.has(cluster) && !seen[cluster] {
+			seen[cluster] = true
+			touched = append(touched, cluster)
+		}
+	})
+	return touched
+}
+
+// clusterBits 每个簇一位的位图，可以被多个 goroutine 同时置位
+type clusterBits []atomic.Uint64
+
+func newClusterBits(clusters uint64) clusterBits {
+	return make(clusterBits, (clusters+63)/64)
+}
+
+// set 置位 cluster，返回之前是否已经置位
+func (b clusterBits) set(cluster uint32) bool {
+	word, mask := &b[cluster/64], uint64(1)<<(cluster%64)
+	for {
+		old := word.Load()
+		if old&mask != 0 {
+			return true
+		}
+		if word.CompareAndSwap(old, old|mask) {
+			return false
+		}
+	}
+}
+
+// has 返回 cluster 是否已经置位
+func (b clusterBits) has(cluster uint32) bool {
+	return b[cluster/64].Load()&(uint64(1)<<(cluster%64)) != 0
+}
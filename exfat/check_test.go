@@ -0,0 +1,102 @@
+package exfat
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// checkImage 返回存在多种问题的卷，供 Check 比较不同 Workers 的结果：
+// 交叉链接、簇链过短、位图中未分配的簇、校验和不匹配和簇链越界的目录，另有 files 个正常文件
+func checkImage(files int) *testimage.Image {
+	var many []*testimage.Node
+	for i := range files {
+		f := testimage.File(fmt.Sprintf("file%04d.bin", i), fill(100+i%3000, byte(i)))
+		f.Fragmented = i%7 == 0
+		many = append(many, f)
+	}
+	a := testimage.File("a.bin", fill(2500, 1))
+	a.Fragmented = true
+	b := testimage.File("b.bin", fill(1500, 2))
+	b.Fragmented = true
+	short := testimage.File("short.bin", fill(3000, 4))
+	short.FATChain = true
+	clusters := uint32(1024)
+	if need := uint32(files)*8 + 256; need > clusters {
+		clusters = need
+	}
+	img := testimage.Build(testimage.Options{ClusterCount: clusters},
+		testimage.Dir("D", a, b, short, testimage.File("free.bin", fill(1200, 5)), testimage.File("sum.bin", []byte("sum"))),
+		testimage.Dir("Broken", testimage.File("hidden.bin", []byte("hidden"))),
+		testimage.Dir("Many", many...))
+
+	img.SetFAT(img.Entry("/D/b.bin").Clusters[0], img.Entry("/D/a.bin").Clusters[1])
+	img.SetFAT(img.Entry("/D/short.bin").Clusters[0], EndOfClusterChain)
+	c := img.Entry("/D/free.bin").Clusters[0]
+	img.Bytes[img.ClusterOffset(img.BitmapCluster)+int64(c-2)/8] &^= 1 << ((c - 2) % 8)
+	img.Slot(img.Entry("/D/sum.bin"), 0)[29] ^= 0xFF // 保留字节，只影响校验和
+	broken := img.Entry("/Broken")
+	binary.LittleEndian.PutUint32(img.Slot(broken, 1)[20:], clusters+100)
+	img.Resum(broken)
+	return img
+}
+
+func TestCheckWorkersDeterministic(t *testing.T) {
+	want, err := openImage(t, checkImage(300)).Check(context.Background(), CheckOptions{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := map[string]Anomaly{
+		"/":            AnomalyUsageDisagreement,
+		"/Broken":      AnomalyShortChain,
+		"/D/a.bin":     AnomalyCrossLinked,
+		"/D/b.bin":     AnomalyCrossLinked,
+		"/D/free.bin":  AnomalyUnallocated,
+		"/D/short.bin": AnomalyShortChain,
+		"/D/sum.bin":   AnomalyChecksumMismatch,
+	}
+	if want.Checked != 309 || len(want.Findings) != len(findings) {
+		t.Fatalf("Workers 1: %d checked, findings %+v", want.Checked, want.Findings)
+	}
+	for _, f := range want.Findings {
+		if !slices.Equal(f.Anomalies, []Anomaly{findings[f.Path]}) || f.Err != nil {
+			t.Errorf("Workers 1: %s %v %v", f.Path, f.Anomalies, f.Err)
+		}
+	}
+
+	// 每次用新打开的卷，避免缓存使结果相同；重复几次以覆盖不同的调度顺序
+	for _, workers := range []int{2, 3, 8, 0} {
+		for range 5 {
+			got, err := openImage(t, checkImage(300)).Check(context.Background(), CheckOptions{Workers: workers})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Workers %d: %+v, want %+v", workers, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkCheck(b *testing.B) {
+	img := checkImage(2000)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				fs, err := NewExFATFileSystem(img.Disk())
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := fs.Check(context.Background(), CheckOptions{Workers: workers}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Chtimes 修改 path 的修改时间和访问时间，并重新计算条目集校验和
+// 与 os.Chtimes 相同，零值时间表示保持原值。只改写文件条目本身，不需要完整的写支持；
+// 底层映像需要实现 io.WriterAt 且可写（容器映像以 WithWritable 打开），否则返回 ErrReadOnly。
+// 访问时间只有 2 秒精度，修改时间精确到 10 毫秒。
+func (fs *ExFATFileSystem) Chtimes(path string, mtime, atime time.Time) error {
+	w, _, set, offset, err := fs.entryForWrite(path)
+	if err != nil {
+		return err
+	}
+	if !mtime.IsZero() {
+		timestamp, increment, utcOffset, err := timeToExfat(mtime)
+		if err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint32(set[12:], timestamp)
+		set[21] = increment
+		set[23] = utcOffset
+	}
+	if !atime.IsZero() {
+		timestamp, _, utcOffset, err := timeToExfat(atime)
+		if err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint32(set[16:], timestamp)
+		set[24] = utcOffset
+	}
+	if err := writeEntrySet(w, set, offset); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package exfat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// storedSet 返回映像中 e 的条目集，以及其中记录的校验和是否与内容一致
+func storedSet(img *testimage.Image, e *testimage.Entry) ([]byte, bool) {
+	primary := img.Slot(e, 0)
+	var set []byte
+	for i := 0; i <= int(primary[1]); i++ {
+		set = append(set, img.Slot(e, i)...)
+	}
+	return set, testimage.SetChecksum(set) == binary.LittleEndian.Uint16(set[2:])
+}
+
+func TestChtimesRoundTrip(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 6, 0, time.UTC)
+	file := testimage.File("report with a long name.txt", []byte("data"))
+	file.Created, file.Modified, file.Accessed = created, created, created
+	img := testimage.Build(testimage.Options{}, testimage.Dir("D", file))
+
+	mtime := time.Date(2024, 3, 4, 5, 6, 7, 890e6, time.FixedZone("", 5*3600+1800))
+	atime := time.Date(2024, 3, 5, 6, 7, 9, 500e6, time.FixedZone("", -3*3600))
+	if err := openImage(t, img).Chtimes("/d/REPORT WITH A LONG NAME.TXT", mtime, atime); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := storedSet(img, img.Entry("/D/report with a long name.txt")); !ok {
+		t.Fatal("entry set checksum does not match after Chtimes")
+	}
+	// 严格模式重新打开：校验和不符时读取失败
+	e, err := openImage(t, img, WithStrict()).Stat("/D/report with a long name.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, gotOffset := e.ModTime.Zone()
+	if !e.ModTime.Equal(mtime) || gotOffset != 5*3600+1800 {
+		t.Errorf("ModTime %v, want %v", e.ModTime, mtime)
+	}
+	if want := atime.Truncate(2 * time.Second); !e.AccessTime.Equal(want) {
+		t.Errorf("AccessTime %v, want %v", e.AccessTime, want)
+	}
+	if _, off := e.AccessTime.Zone(); off != -3*3600 {
+		t.Errorf("AccessTime offset %d, want %d", off, -3*3600)
+	}
+	if !e.CreateTime.Equal(created) {
+		t.Errorf("CreateTime changed to %v", e.CreateTime)
+	}
+
+	// 零值时间保持原值
+	later := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := openImage(t, img).Chtimes("/D/report with a long name.txt", time.Time{}, later); err != nil {
+		t.Fatal(err)
+	}
+	e, _ = openImage(t, img, WithStrict()).Stat("/D/report with a long name.txt")
+	if !e.ModTime.Equal(mtime) || !e.AccessTime.Equal(later) {
+		t.Errorf("after zero mtime: ModTime %v, AccessTime %v", e.ModTime, e.AccessTime)
+	}
+}
+
+func TestChtimesDirectory(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, testimage.Dir("D", testimage.File("f", nil)))
+	mtime := time.Date(2022, 2, 2, 2, 2, 2, 0, time.UTC)
+	if err := openImage(t, img).Chtimes("/D", mtime, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	e, err := openImage(t, img, WithStrict()).Stat("/D")
+	if err != nil || !e.ModTime.Equal(mtime) {
+		t.Errorf("Stat: %v, %v", e.ModTime, err)
+	}
+}
+
+func TestChtimesErrors(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, testimage.File("f", nil))
+	before := bytes.Clone(img.Bytes)
+	fs := openImage(t, img)
+
+	if err := fs.Chtimes("/f", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}); err == nil {
+		t.Error("accepted a time before 1980")
+	}
+	if err := fs.Chtimes("/", time.Now(), time.Time{}); err == nil {
+		t.Error("modified the root directory")
+	}
+	if err := fs.Chtimes("/missing", time.Now(), time.Time{}); !errors.Is(err, ErrNotExist) {
+		t.Errorf("missing file: got %v, want ErrNotExist", err)
+	}
+	if !bytes.Equal(img.Bytes, before) {
+		t.Error("failed calls modified the image")
+	}
+
+	ro, err := NewExFATFileSystem(readerOnly{img.Disk()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ro.Chtimes("/f", time.Now(), time.Time{}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("read-only image: got %v, want ErrReadOnly", err)
+	}
+
+	// 校验和已经不符的条目集不改写，以免掩盖损坏
+	img.Slot(img.Entry("/f"), 1)[8] ^= 1
+	if err := openImage(t, img).Chtimes("/f", time.Now(), time.Time{}); err == nil {
+		t.Error("rewrote an entry set with a bad checksum")
+	}
+}
@@ -0,0 +1,82 @@
+package exfat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// sparseDisk 在映像之后模拟一个巨大的簇堆：映像范围内读取映像，之外的字节由偏移决定
+type sparseDisk struct {
+	img []byte
+}
+
+func (d sparseDisk) ReadAt(p []byte, off int64) (int, error) {
+	for i := range p {
+		p[i] = d.at(off + int64(i))
+	}
+	return len(p), nil
+}
+
+func (d sparseDisk) at(off int64) byte {
+	if off < int64(len(d.img)) {
+		return d.img[off]
+	}
+	return byte(off/512) ^ byte(off)
+}
+
+func TestClusterCountAboveOldLimit(t *testing.T) {
+	const (
+		clusterCount = 0x10000100
+		high         = 0x10000050 // 超过以前的 0x10000000 上限，但在簇堆之内
+	)
+	img := testimage.Build(testimage.Options{}, testimage.Dir("Dir", testimage.File("far.bin", fill(3000, 1))))
+	// 文件的第一个簇改为 high；真实的卷需要超过 1 GiB 的 FAT，这里只在打开之后放大簇数
+	e := img.Entry("/Dir/far.bin")
+	binary.LittleEndian.PutUint32(img.Slot(e, 1)[20:], high)
+	img.Resum(e)
+
+	fs := openImage(t, img)
+	fs.vhd = sparseDisk{img: img.Bytes}
+	fs.totalClusters = clusterCount
+
+	for _, c := range []struct {
+		cluster uint32
+		valid   bool
+	}{
+		{0x10000000, true},
+		{high, true},
+		{clusterCount + 1, true},
+		{clusterCount + 2, false},
+		{BadCluster, false},
+		{EndOfClusterChain, false},
+	} {
+		if got := fs.validCluster(c.cluster); got != c.valid {
+			t.Errorf("validCluster(0x%08X) = %v, want %v", c.cluster, got, c.valid)
+		}
+	}
+
+	// 偏移必须按 64 位计算：0x10000050 个 512 字节的簇远超 4 GiB
+	offset := fs.clusterToOffset(high)
+	if want := uint64(img.HeapOffset) + uint64(high-2)*uint64(img.BytesPerCluster); offset != want || offset < 1<<32 {
+		t.Fatalf("clusterToOffset(0x%08X) = %d, want %d", uint32(high), offset, want)
+	}
+	want := make([]byte, 3000)
+	for i := range want {
+		want[i] = sparseDisk{}.at(int64(offset) + int64(i))
+	}
+
+	entry, err := fs.getEntry("/Dir/far.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.cluster != high {
+		t.Fatalf("first cluster read as 0x%08X, want 0x%08X", entry.cluster, uint32(high))
+	}
+	got, err := fs.ReadFile("/Dir/far.bin")
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("ReadFile: %d bytes, %v", len(got), err)
+	}
+}
@@ -0,0 +1,83 @@
+package exfat
+
+import "fmt"
+
+// ClusterVerdict 簇过滤器对一个簇的判定，见 WithClusterFilter
+type ClusterVerdict int
+
+const (
+	ClusterAllow      ClusterVerdict = iota // 按 FAT 和簇号照常处理
+	ClusterTreatAsEOC                       // 簇链在到达该簇之前结束
+	ClusterTreatAsBad                       // 把该簇视为坏簇
+)
+
+// String 返回判定的名称
+func (v ClusterVerdict) String() string {
+	switch v {
+	case ClusterAllow:
+		return "allow"
+	case ClusterTreatAsEOC:
+		return "end of chain"
+	case ClusterTreatAsBad:
+		return "bad cluster"
+	}
+	return fmt.Sprintf("ClusterVerdict(%d)", int(v))
+}
+
+// ClusterFilter 在遍历簇链时对每个将要进入的簇给出判定，见 WithClusterFilter
+type ClusterFilter func(cluster uint32) ClusterVerdict
+
+// ClusterRange 闭区间 [First, Last] 内的簇
+type ClusterRange struct {
+	First, Last uint32
+}
+
+// ExcludeClusterRanges 返回把 ranges 中的簇视为链结束的过滤器
+// 用于厂商固件区等 FAT 错误地把它们标记为簇链延续的区域。
+func ExcludeClusterRanges(ranges ...ClusterRange) ClusterFilter {
+	ranges = append([]ClusterRange(nil), ranges...)
+	return func(cluster uint32) ClusterVerdict {
+		for _, r := range ranges {
+			if cluster >= r.First && cluster <= r.Last {
+				return ClusterTreatAsEOC
+			}
+		}
+		return ClusterAllow
+	}
+}
+
+// filter 按簇过滤器返回 cluster 本身，或代替它的 EndOfClusterChain、BadCluster
+// 只对簇堆中的簇调用过滤器；过滤器改变遍历时记录诊断。
+func (fs *ExFATFileSystem) filter(cluster uint32) uint32 {
+	if fs.opts.clusterFilter == nil || !fs.validCluster(cluster) {
+		return cluster
+	}
+	switch verdict := fs.opts.clusterFilter(cluster); verdict {
+	case ClusterTreatAsEOC:
+		fs.diagnose("", "cluster filter ends a chain before cluster %d", cluster)
+		return EndOfClusterChain
+	case ClusterTreatAsBad:
+		fs.diagnose("", "cluster filter treats cluster %d as bad", cluster)
+		return BadCluster
+	}
+	return cluster
+}
+
+// link 返回 fat 中 cluster 的 FAT 项，指向的簇经过簇过滤器
+// 调用者负责确认 cluster 在 fat 的范围内。
+func (fs *ExFATFileSystem) link(fat []uint32, cluster uint32) uint32 {
+	return fs.filter(fat[cluster])
+}
+
+// contiguousRun 返回从 start 开始的 count 个连续簇中，在第一个被过滤的簇之前的簇数
+func (fs *ExFATFileSystem) contiguousRun(start uint32, count uint64) uint64 {
+	if fs.opts.clusterFilter == nil {
+		return count
+	}
+	for i := uint64(0); i < count; i++ {
+		if c := start + uint32(i); fs.filter(c) != c {
+			return i
+		}
+	}
+	return count
+}
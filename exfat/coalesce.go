@@ -0,0 +1,43 @@
+package exfat
+
+// maxReadClusters 返回一次合并读取最多包含的簇数，至少为 1（见 WithMaxReadSize）
+func (fs *ExFATFileSystem) maxReadClusters() uint64 {
+	return max(uint64(fs.opts.maxReadSize)/uint64(fs.bytesPerCluster), 1)
+}
+
+// maxReadBytes 返回一次合并读取最多读取的字节数，至少为一个簇
+func (fs *ExFATFileSystem) maxReadBytes() int {
+	return int(fs.maxReadClusters()) * int(fs.bytesPerCluster)
+}
+
+// readRun 读取一段连续簇中的数据：从 cluster 簇内偏移 skip 字节处开始，读满 p
+// 跨多个簇的合并读取失败时逐簇重试，返回第一个无法读取的簇和它的错误，
+// 使坏扇区的报告精确到簇，而不是整个读取范围。重试全部成功时返回 nil。
+// 落在引导区或 FAT 中的簇（见 checkRegions）不会读取，返回该簇和 ErrRegionOverlap。
+// purpose 为读取的用途（Trace* 常量），只用于读取跟踪。
+func (fs *ExFATFileSystem) readRun(p []byte, cluster uint32, skip int64, purpose string) (uint32, error) {
+	if bad, err := fs.checkClusters(cluster, skip, int64(len(p))); err != nil {
+		// 重叠区域之前的簇照常读出，调用者按返回的簇计算已读取的字节数
+		if valid := int64(bad-cluster)*int64(fs.bytesPerCluster) - skip; valid > 0 {
+			if _, rerr := fs.readRun(p[:valid], cluster, skip, purpose); rerr != nil {
+				return cluster, rerr
+			}
+		}
+		return bad, err
+	}
+	off := int64(fs.clusterToOffset(cluster)) + skip
+	_, err := fs.readAt(p, off, purpose)
+	clusterSize := int64(fs.bytesPerCluster)
+	if err == nil || skip+int64(len(p)) <= clusterSize {
+		return cluster, err
+	}
+
+	for n := int64(0); n < int64(len(p)); {
+		chunk := min(int64(len(p))-n, clusterSize-(skip+n)%clusterSize)
+		if _, err := fs.readAt(p[n:n+chunk], off+n, purpose); err != nil {
+			return cluster + uint32((skip+n)/clusterSize), err
+		}
+		n += chunk
+	}
+	return cluster, nil
+}
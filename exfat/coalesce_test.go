@@ -0,0 +1,162 @@
+package exfat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// countingReaderAt 统计对底层映像的读取次数，读取范围与 bad 重叠时返回错误
+type countingReaderAt struct {
+	r     io.ReaderAt
+	calls atomic.Int64
+	bad   [2]int64 // 模拟坏扇区的字节范围 [bad[0], bad[1])
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.calls.Add(1)
+	if off < c.bad[1] && c.bad[0] < off+int64(len(p)) {
+		return 0, errors.New("bad sector")
+	}
+	return c.r.ReadAt(p, off)
+}
+
+// 轻度碎片化的文件：200 个簇通过 FAT 连接，第 100 到 109 个簇被移到卷的后部
+const (
+	lightClusters   = 200
+	lightFragment   = 100
+	lightMoved      = 10
+	lightMovedStart = 600
+)
+
+// lightImage 返回有一个轻度碎片化文件 /light.bin 的卷，文件内容为返回的 data
+func lightImage() (*testimage.Image, []byte) {
+	data := fill(lightClusters*512-100, 7)
+	light := testimage.File("light.bin", data)
+	light.FATChain = true
+	img := testimage.Build(testimage.Options{}, light)
+	clusters := img.Entry("/light.bin").Clusters
+	for i := 0; i < lightMoved; i++ {
+		from, to := clusters[lightFragment+i], uint32(lightMovedStart+i)
+		copy(img.Bytes[img.ClusterOffset(to):][:512], img.Bytes[img.ClusterOffset(from):][:512])
+		clear(img.Bytes[img.ClusterOffset(from):][:512])
+		img.SetFAT(from, 0)
+		img.SetFAT(clusters[lightFragment+i-1], to)
+		if i > 0 {
+			img.SetFAT(to-1, to)
+		}
+	}
+	img.SetFAT(lightMovedStart+lightMoved-1, clusters[lightFragment+lightMoved])
+	return img, data
+}
+
+// countReads 打开 img 并读取 /light.bin，返回内容、读取文件数据时对映像的读取次数和按用途跟踪到的数据读取次数
+func countReads(t *testing.T, img *testimage.Image, opts ...Option) ([]byte, int64, int) {
+	t.Helper()
+	disk := &countingReaderAt{r: img.Disk()}
+	var traced atomic.Int64
+	opts = append(opts, WithReadTracer(func(layer string, logical, physical, length int64, purpose string) {
+		if purpose == TraceData {
+			traced.Add(1)
+		}
+	}))
+	fs, err := NewExFATFileSystem(disk, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat("/light.bin"); err != nil {
+		t.Fatal(err)
+	}
+	before := disk.calls.Load()
+	data, err := fs.ReadFile("/light.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, disk.calls.Load() - before, int(traced.Load())
+}
+
+func TestCoalescedReads(t *testing.T) {
+	img, want := lightImage()
+
+	// 逐簇读取是对照：每个簇一次读取，另有与读取方式无关的元数据读取
+	naive, naiveCalls, naiveTraced := countReads(t, img, WithMaxReadSize(1))
+	other := naiveCalls - int64(naiveTraced)
+	if !bytes.Equal(naive, want) || naiveTraced != lightClusters || other > 2 {
+		t.Fatalf("per-cluster reads: %d calls (%d traced), equal %v", naiveCalls, naiveTraced, bytes.Equal(naive, want))
+	}
+
+	tests := []struct {
+		maxRead int
+		reads   int
+	}{
+		{0, 3},                // 默认上限：碎片前、碎片和碎片后各一次
+		{16 * 512, 7 + 1 + 6}, // 每次最多 16 个簇
+		{1000, 200},           // 小于两个簇的上限按一个簇读取
+	}
+	for _, tt := range tests {
+		got, calls, traced := countReads(t, img, WithMaxReadSize(tt.maxRead))
+		if !bytes.Equal(got, naive) {
+			t.Errorf("max read %d: output differs from per-cluster reads", tt.maxRead)
+		}
+		if traced != tt.reads || calls != int64(tt.reads)+other {
+			t.Errorf("max read %d: %d calls (%d traced), want %d", tt.maxRead, calls, traced, tt.reads)
+		}
+	}
+
+	// 通过 File 的 WriteTo 读取的内容相同
+	fs := openImage(t, img)
+	f, err := fs.Open("/light.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil || !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("WriteTo: %d bytes, %v", buf.Len(), err)
+	}
+}
+
+func TestCoalescedReadBadSector(t *testing.T) {
+	img, _ := lightImage()
+	clusters := img.Entry("/light.bin").Clusters
+	bad := clusters[50]
+	disk := &countingReaderAt{r: img.Disk(), bad: [2]int64{img.ClusterOffset(bad) + 100, img.ClusterOffset(bad) + 101}}
+	fs, err := NewExFATFileSystem(disk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 合并读取失败后逐簇重试，错误指出坏扇区所在的簇
+	_, err = fs.ReadFile("/light.bin")
+	if want := fmt.Sprintf("failed to read cluster %d: bad sector", bad); err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+}
+
+func BenchmarkCoalescedReads(b *testing.B) {
+	img, _ := lightImage()
+	for _, bm := range []struct {
+		name    string
+		maxRead int
+	}{{"per-cluster", 1}, {"coalesced", 0}} {
+		b.Run(bm.name, func(b *testing.B) {
+			disk := &countingReaderAt{r: img.Disk()}
+			fs, err := NewExFATFileSystem(disk, WithMaxReadSize(bm.maxRead))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(lightClusters * 512)
+			b.ResetTimer()
+			before := disk.calls.Load()
+			for i := 0; i < b.N; i++ {
+				if _, err := fs.ReadFile("/light.bin"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(disk.calls.Load()-before)/float64(b.N), "reads/op")
+		})
+	}
+}
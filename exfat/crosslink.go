@@ -0,0 +1,139 @@
+package exfat
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CrossLinkError 表示文件的簇链与另一个条目共用簇（交叉链接）
+// 读取较晚被遍历的文件会得到属于另一个文件的数据。可以用 errors.Is(err, ErrCrossLinked) 判断。
+type CrossLinkError struct {
+	Path    string // 本次遍历的文件
+	Other   string // 已经占用该簇的条目，找不到时为空
+	Cluster uint32 // 第一个共用的簇
+}
+
+func (e *CrossLinkError) Error() string {
+	if e.Other == "" {
+		return fmt.Sprintf("%v: %s: cluster %d is already in use", ErrCrossLinked, e.Path, e.Cluster)
+	}
+	return fmt.Sprintf("%v: %s shares cluster %d with %s", ErrCrossLinked, e.Path, e.Cluster, e.Other)
+}
+
+func (e *CrossLinkError) Unwrap() error {
+	return ErrCrossLinked
+}
+
+// claims 读取过程中已被文件占用的簇，见 WithCrosslinkDetection
+type claims struct {
+	mu      sync.Mutex
+	bits    []uint64             // 每个簇一位，第一次登记时分配
+	entries map[entryID]struct{} // 已经登记过簇链的条目，再次读取时不重复检查
+}
+
+// claimChain 把文件数据使用的簇登记为该条目占用，遇到已被其他条目占用的簇时返回 *CrossLinkError
+// fat 为读取该文件使用的 FAT。每个条目只检查一次：交叉链接在第一次遍历时报告，
+// 之后再读取同一个条目不再返回错误，调用者可以据此决定是否继续读取。未启用检测时什么都不做。
+func (fs *ExFATFileSystem) claimChain(fat []uint32, entry *DirEntry) error {
+	if !fs.opts.crosslinks || entry.Size <= 0 || !fs.validCluster(entry.cluster) {
+		return nil
+	}
+	c := &fs.claims
+	c.mu.Lock()
+	if _, ok := c.entries[entry.id]; ok {
+		c.mu.Unlock()
+		return nil
+	}
+	if c.bits == nil {
+		c.bits = make([]uint64, (uint64(fs.totalClusters)+2+63)/64)
+		c.entries = make(map[entryID]struct{})
+	}
+	c.entries[entry.id] = struct{}{}
+
+	// 先检查再登记：损坏的簇链在本条目内部循环时不算与其他条目共用
+	shared := uint32(0)
+	fs.chainClusters(fat, entry, func(cluster uint32) {
+		if shared == 0 && c.bits[cluster/64]&(1<<(cluster%64)) != 0 {
+			shared = cluster
+		}
+	})
+	fs.chainClusters(fat, entry, func(cluster uint32) {
+		c.bits[cluster/64] |= 1 << (cluster % 64)
+	})
+	c.mu.Unlock()
+
+	if shared == 0 {
+		return nil
+	}
+	err := &CrossLinkError{Path: entry.path, Cluster: shared}
+	if owner := fs.clusterOwner(shared, entry.id); owner != nil {
+		err.Other = owner.path
+	}
+	return err
+}
+
+// crossLinked 按读取文件时会使用的 FAT 登记条目的簇链，返回是否与其他条目共用簇
+func (fs *ExFATFileSystem) crossLinked(entry *DirEntry) bool {
+	if !fs.opts.crosslinks || entry.IsDir {
+		return false
+	}
+	fat, _, err := fs.chainFAT(entry)
+	if err != nil {
+		fat = fs.fat
+	}
+	return fs.claimChain(fat, entry) != nil
+}
+
+// chainClusters 对文件数据使用的每个簇调用 fn，遍历方式与 readChainInto 相同
+// 簇链在数据结束之前断开时停止，不猜测后续的簇；循环的链最多遍历数据需要的簇数。
+func (fs *ExFATFileSystem) chainClusters(fat []uint32, entry *DirEntry, fn func(cluster uint32)) {
+	needed := fs.clustersFor(entry.Size)
+	cluster := fs.filter(entry.cluster)
+	for i := uint64(0); i < needed && fs.validCluster(cluster); i++ {
+		fn(cluster)
+		switch {
+		case entry.noFatChain:
+			cluster = fs.filter(cluster + 1)
+		case int(cluster) < len(fat):
+			cluster = fs.link(fat, cluster)
+		default:
+			return
+		}
+	}
+}
+
+// clusterOwner 在整个卷中查找簇链包含 cluster 的另一个文件，exclude 为本次遍历的条目
+// 只在发现交叉链接时调用，按活动 FAT 遍历目录树。
+func (fs *ExFATFileSystem) clusterOwner(cluster uint32, exclude entryID) *DirEntry {
+	visited := make(map[uint32]bool)
+	var search func(dir *DirEntry) *DirEntry
+	search = func(dir *DirEntry) *DirEntry {
+		if !firstVisit(visited, dir) {
+			return nil
+		}
+		children, err := fs.readDirectoryEntries(dir)
+		if err != nil {
+			return nil
+		}
+		for _, child := range children {
+			if child.IsDir {
+				if owner := search(child); owner != nil {
+					return owner
+				}
+				continue
+			}
+			if child.id == exclude {
+				continue
+			}
+			found := false
+			fs.chainClusters(fs.fat, child, func(c uint32) {
+				found = found || c == cluster
+			})
+			if found {
+				return child
+			}
+		}
+		return nil
+	}
+	return search(fs.volumeRoot())
+}
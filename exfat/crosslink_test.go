@@ -0,0 +1,99 @@
+package exfat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// crosslinkImage 返回两个文件共用一段簇链的卷：/D/b.bin 的第一个簇之后接上 /D/a.bin 的第二、三个簇
+// 返回共用的第一个簇。
+func crosslinkImage() (*testimage.Image, uint32) {
+	a := testimage.File("a.bin", fill(2500, 1))
+	a.Fragmented = true
+	b := testimage.File("b.bin", fill(1500, 2))
+	b.Fragmented = true
+	img := testimage.Build(testimage.Options{}, testimage.Dir("D", a, b, testimage.File("c.bin", fill(900, 3))))
+	shared := img.Entry("/D/a.bin").Clusters[1]
+	img.SetFAT(img.Entry("/D/b.bin").Clusters[0], shared)
+	return img, shared
+}
+
+func TestCrosslinkedEntries(t *testing.T) {
+	img, shared := crosslinkImage()
+
+	// 未启用检测时照常读取，b.bin 读到的是 a.bin 的数据
+	plain := openImage(t, img)
+	if _, err := plain.ReadFile("/D/b.bin"); err != nil {
+		t.Errorf("without detection: %v", err)
+	}
+
+	for _, order := range [][2]string{{"/D/a.bin", "/D/b.bin"}, {"/D/b.bin", "/D/a.bin"}} {
+		fs := openImage(t, img, WithCrosslinkDetection())
+		first, second := order[0], order[1]
+		if _, err := fs.ReadFile(first); err != nil {
+			t.Fatalf("%s read first: %v", first, err)
+		}
+		// 后读取的文件报告交叉链接，并指出先占用这些簇的文件：两个条目都被报告
+		_, err := fs.ReadFile(second)
+		var cl *CrossLinkError
+		if !errors.As(err, &cl) || !errors.Is(err, ErrCrossLinked) || !errors.Is(err, ErrInvalidImage) {
+			t.Fatalf("%s read second: got %v, want a CrossLinkError", second, err)
+		}
+		if cl.Path != second || cl.Other != first || cl.Cluster != shared {
+			t.Errorf("CrossLinkError = %+v, want %s sharing cluster %d with %s", cl, second, shared, first)
+		}
+		// 每个条目只报告一次
+		if _, err := fs.ReadFile(second); err != nil {
+			t.Errorf("%s read again: %v", second, err)
+		}
+		if _, err := fs.ReadFile("/D/c.bin"); err != nil {
+			t.Errorf("unrelated file: %v", err)
+		}
+	}
+
+	// 按目录获取异常时，目录中后出现的条目带有 AnomalyCrossLinked
+	fs := openImage(t, img, WithCrosslinkDetection())
+	anomalies, err := fs.DirAnomalies("/D")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(anomalies["b.bin"], AnomalyCrossLinked) || slices.Contains(anomalies["a.bin"], AnomalyCrossLinked) || len(anomalies["c.bin"]) != 0 {
+		t.Errorf("DirAnomalies = %v", anomalies)
+	}
+
+	// Check 不依赖读取顺序，共用簇的两个条目都报告
+	result, err := openImage(t, img).Check(context.Background(), CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var crossLinked []string
+	for _, f := range result.Findings {
+		if slices.Contains(f.Anomalies, AnomalyCrossLinked) {
+			crossLinked = append(crossLinked, f.Path)
+		}
+	}
+	if !slices.Equal(crossLinked, []string{"/D/a.bin", "/D/b.bin"}) {
+		t.Errorf("Check cross-linked entries: %v", crossLinked)
+	}
+}
+
+func TestCrosslinkSelfLoop(t *testing.T) {
+	// 簇链在本条目内部循环不算交叉链接
+	a := testimage.File("a.bin", fill(2500, 1))
+	a.Fragmented = true
+	img := testimage.Build(testimage.Options{}, a, testimage.File("b.bin", fill(700, 2)))
+	clusters := img.Entry("/a.bin").Clusters
+	img.SetFAT(clusters[2], clusters[0])
+	fs := openImage(t, img, WithCrosslinkDetection())
+	if _, err := fs.ReadFile("/a.bin"); errors.Is(err, ErrCrossLinked) {
+		t.Errorf("self loop reported as cross-linked: %v", err)
+	}
+	if data, err := fs.ReadFile("/b.bin"); err != nil || !bytes.Equal(data, fill(700, 2)) {
+		t.Errorf("b.bin: %v", err)
+	}
+}
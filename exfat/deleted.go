@@ -0,0 +1,114 @@
+package exfat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path"
+)
+
+// DeletedEntry 目录中已删除（InUse 位为 0）的文件条目集
+// 删除只清除每个条目类型字节中的 InUse 位，条目的其余内容通常保持不变，直到槽位被新的条目重用。
+// 删除时 FAT 中的簇链被释放，只有连续分配的文件（Contiguous）能可靠地恢复：用 ReadChain(FirstCluster, Size, true)
+// 读取；碎片化的文件只能按连续簇读到第一段，之后的数据需要另行雕刻。
+type DeletedEntry struct {
+	FileEntry
+	Offset      int64 // 条目集在目录数据中的字节偏移
+	Complete    bool  // 次条目齐全，且恢复 InUse 位后条目集校验和匹配；否则条目可能已被部分覆盖
+	Reallocated bool  // 按连续分配计算的数据簇中有簇已在分配位图中重新分配，数据可能已被覆盖
+}
+
+// ListDeleted 列出目录中已删除的文件和子目录，按在目录中的顺序排列
+// 只返回看起来仍是文件条目集的槽位：已删除的文件条目（0x05）后面紧跟已删除的流扩展条目（0x40），
+// 名称取自其后已删除的文件名条目（0x41）。名称为空或起始簇无效的条目集被跳过。
+func (fs *ExFATFileSystem) ListDeleted(dirPath string) ([]DeletedEntry, error) {
+	dirPath = normalizePath(dirPath)
+	dir, err := fs.getEntry(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if !dir.IsDir {
+		return nil, fmt.Errorf("%w: %s", ErrNotDirectory, dirPath)
+	}
+	if !dir.hasClusters() {
+		return []DeletedEntry{}, nil
+	}
+	buf, err := fs.readDirectoryData(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.putBuffer(buf)
+	data := *buf
+	bitmap, _ := fs.allocationBitmap()
+	upcase, _ := fs.upcaseTable()
+
+	deleted := []DeletedEntry{}
+	for offset := 0; offset+64 <= len(data) && data[offset] != EntryTypeEndOfDirectory; {
+		if data[offset] != EntryTypeFile&^EntryInUse || data[offset+32] != EntryTypeFileInfo&^EntryInUse {
+			offset += 32
+			continue
+		}
+
+		// 收集紧跟在后面的已删除次条目，恢复 InUse 位后按正常的条目集解析
+		declared := int(data[offset+1])
+		end := offset + 32
+		for end+32 <= len(data) && (end-offset)/32 <= declared && data[end]&EntryInUse == 0 && classifySlot(data[end]|EntryInUse) == slotSecondary {
+			end += 32
+		}
+		set := append([]byte(nil), data[offset:end]...)
+		for i := 0; i < len(set); i += 32 {
+			set[i] |= EntryInUse
+		}
+		if e, ok := fs.deletedEntry(dir, set, offset, declared, bitmap, upcase); ok {
+			deleted = append(deleted, e)
+		}
+		offset = end
+	}
+	return deleted, nil
+}
+
+// deletedEntry 解析恢复了 InUse 位的条目集；set 至少包含文件条目和流扩展条目
+func (fs *ExFATFileSystem) deletedEntry(dir *DirEntry, set []byte, offset, declared int, bitmap []byte, upcase []uint16) (DeletedEntry, bool) {
+	fileEntry := &ExFATFileEntry{}
+	info := &ExFATFileInfoEntry{}
+	if binary.Read(bytes.NewReader(set[:32]), binary.LittleEndian, fileEntry) != nil ||
+		binary.Read(bytes.NewReader(set[32:64]), binary.LittleEndian, info) != nil {
+		return DeletedEntry{}, false
+	}
+	nameLength := min(int(info.NameLength), nameLengthLimit(uint8(len(set)/32-1)))
+	name := decodeName(setNameUnits(set, nameLength))
+	cluster := info.FirstCluster
+	if name == "" || (cluster != 0 && !fs.validCluster(cluster)) {
+		return DeletedEntry{}, false
+	}
+
+	entry := &DirEntry{
+		Name:       name,
+		Size:       int64(info.DataLength),
+		IsDir:      fileEntry.FileAttributes.Has(AttrDirectory),
+		ModTime:    exfatTimeToTime(fileEntry.LastModifiedTimestamp, fileEntry.LastModified10msIncrement, fileEntry.LastModifiedUtcOffset),
+		CreateTime: exfatTimeToTime(fileEntry.CreateTimestamp, fileEntry.Create10msIncrement, fileEntry.CreateUtcOffset),
+		AccessTime: exfatAccessTimeToTime(fileEntry.LastAccessedTimestamp, fileEntry.LastAccessedUtcOffset),
+		Attributes: fileEntry.FileAttributes,
+		cluster:    cluster,
+		validSize:  int64(min(info.ValidDataLength, info.DataLength)),
+		noFatChain: info.GeneralSecondaryFlags&NoFatChainFlag != 0,
+		path:       path.Join(dir.path, name),
+		anomalies:  metadataAnomalies(set, fileEntry, info, upcase),
+		streams:    1,
+		id:         entryID{serial: fs.bootSector.VolumeSerialNumber, dir: dir.cluster, offset: int64(offset)},
+	}
+
+	e := DeletedEntry{
+		FileEntry: entry.fileEntry(),
+		Offset:    int64(offset),
+		Complete:  len(set)/32-1 == declared && entrySetChecksum(set) == fileEntry.SetChecksum,
+	}
+	if bitmap != nil && entry.hasClusters() {
+		last := min(uint64(cluster)+max(fs.clustersFor(entry.Size), 1)-1, uint64(fs.totalClusters)+1)
+		for c := uint64(cluster); c <= last && !e.Reallocated; c++ {
+			e.Reallocated = clusterAllocated(bitmap, uint32(c))
+		}
+	}
+	return e, true
+}
@@ -0,0 +1,64 @@
+package exfat
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 最多保留的诊断条数，防止损坏严重的映像占用过多内存
+const maxDiagnostics = 1000
+
+// Diagnostic 记录解析过程中发现、但已按宽松策略处理的问题
+type Diagnostic struct {
+	Path    string // 相关路径（可能为空）
+	Message string // 问题描述
+}
+
+// String 返回诊断的文本形式
+func (d Diagnostic) String() string {
+	if d.Path == "" {
+		return d.Message
+	}
+	return d.Path + ": " + d.Message
+}
+
+// diagnostics 诊断记录，同一问题只记录一次
+type diagnostics struct {
+	mu      sync.Mutex
+	list    []Diagnostic
+	seen    map[Diagnostic]bool
+	dropped int // 超出上限而丢弃的条数
+}
+
+// Diagnostics 返回打开和读取过程中记录的诊断信息
+func (fs *ExFATFileSystem) Diagnostics() []Diagnostic {
+	fs.diag.mu.Lock()
+	defer fs.diag.mu.Unlock()
+
+	list := append([]Diagnostic(nil), fs.diag.list...)
+	if fs.diag.dropped > 0 {
+		list = append(list, Diagnostic{Message: fmt.Sprintf("%d further diagnostics dropped", fs.diag.dropped)})
+	}
+	return list
+}
+
+// diagnose 记录一条诊断信息
+func (fs *ExFATFileSystem) diagnose(path, format string, args ...interface{}) {
+	d := Diagnostic{Path: path, Message: fmt.Sprintf(format, args...)}
+
+	fs.diag.mu.Lock()
+	defer fs.diag.mu.Unlock()
+
+	if fs.diag.seen[d] {
+		return
+	}
+	if len(fs.diag.list) >= maxDiagnostics {
+		fs.diag.dropped++
+		return
+	}
+	if fs.diag.seen == nil {
+		fs.diag.seen = make(map[Diagnostic]bool)
+	}
+	fs.diag.seen[d] = true
+	fs.diag.list = append(fs.diag.list, d)
+}
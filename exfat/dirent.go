@@ -0,0 +1,66 @@
+package exfat
+
+import (
+	iofs "io/fs"
+	"sort"
+	"time"
+)
+
+// ReadDir 列出目录内容，返回标准库的 fs.DirEntry，可以替代 os.ReadDir 的结果
+// 与 os.ReadDir 一样按名称排序（ListDir 保持磁盘上的顺序）。Type() 与 Info().Mode() 的类型位一致，
+// Info() 返回的 fs.FileInfo 中 Sys() 为对应的 FileEntry。
+func (fs *ExFATFileSystem) ReadDir(path string) ([]iofs.DirEntry, error) {
+	entries, err := fs.ListDir(path)
+	if err != nil {
+		return nil, err
+	}
+	dirEntries := make([]iofs.DirEntry, len(entries))
+	for i, entry := range entries {
+		dirEntries[i] = dirEntry{entry}
+	}
+	sort.Slice(dirEntries, func(i, j int) bool {
+		return dirEntries[i].Name() < dirEntries[j].Name()
+	})
+	return dirEntries, nil
+}
+
+// Info 返回条目对应的 fs.FileInfo
+func (e FileEntry) Info() iofs.FileInfo {
+	return fileInfo{e}
+}
+
+// Mode 由属性位推导权限位：目录为 0755，文件为 0644，只读属性去掉写权限
+func (e FileEntry) Mode() iofs.FileMode {
+	mode := iofs.FileMode(0644)
+	if e.IsDir {
+		mode = iofs.ModeDir | 0755
+	}
+	if e.Attributes.Has(AttrReadOnly) {
+		mode &^= 0222
+	}
+	return mode
+}
+
+// dirEntry 实现 fs.DirEntry
+type dirEntry struct {
+	entry FileEntry
+}
+
+func (d dirEntry) Name() string                 { return d.entry.Name }
+func (d dirEntry) IsDir() bool                  { return d.entry.IsDir }
+func (d dirEntry) Type() iofs.FileMode          { return d.entry.Mode().Type() }
+func (d dirEntry) Info() (iofs.FileInfo, error) { return d.entry.Info(), nil }
+func (d dirEntry) String() string               { return iofs.FormatDirEntry(d) }
+
+// fileInfo 实现 fs.FileInfo
+type fileInfo struct {
+	entry FileEntry
+}
+
+func (fi fileInfo) Name() string        { return fi.entry.Name }
+func (fi fileInfo) Size() int64         { return fi.entry.Size }
+func (fi fileInfo) Mode() iofs.FileMode { return fi.entry.Mode() }
+func (fi fileInfo) ModTime() time.Time  { return fi.entry.ModTime }
+func (fi fileInfo) IsDir() bool         { return fi.entry.IsDir }
+func (fi fileInfo) Sys() interface{}    { return fi.entry }
+func (fi fileInfo) String() string      { return iofs.FormatFileInfo(fi) }
@@ -0,0 +1,26 @@
+package exfat
+
+import "time"
+
+// FileEntry 表示文件或目录的基本信息
+type FileEntry struct {
+	Name         string     // 文件/目录名
+	Size         int64      // 文件大小（目录为 0）
+	ValidSize    int64      // 有效数据长度（ValidDataLength）：之后到 Size 的部分未写入过，读取为零
+	IsDir        bool       // 是否为目录
+	ModTime      time.Time  // 修改时间（精确到 10 毫秒）
+	CreateTime   time.Time  // 创建时间（精确到 10 毫秒）
+	AccessTime   time.Time  // 最后访问时间（精确到 2 秒）
+	Attributes   Attributes // 文件属性位（AttrReadOnly 等）
+	Contiguous   bool       // 簇连续分配（NoFatChain 标志），读取时不经过 FAT
+	FirstCluster uint32     // 数据的起始簇，0 表示没有分配簇；数据的完整位置见 Extents
+	FileID       uint64     // 类似 inode 号的标识，由起始簇（没有簇时由条目位置）派生，只在映像未修改时稳定
+	Streams      int        // 条目集中流扩展条目的个数，正常为 1；多于 1 时只使用第一个（见 AnomalyExtraStreamExtension），根目录为 0
+
+	id entryID // 条目集的位置，通过 ID() 格式化
+}
+
+// AttributeString 以类似 attrib 的形式返回属性，如 "RHSA-"，与 Attributes.String 相同
+func (e FileEntry) AttributeString() string {
+	return e.Attributes.String()
+}
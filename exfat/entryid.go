@@ -0,0 +1,104 @@
+package exfat
+
+import (
+	"fmt"
+)
+
+// entryID 定位一个条目集：卷序列号、所在目录的起始簇和条目集在目录中的字节偏移
+type entryID struct {
+	serial uint32
+	dir    uint32
+	offset int64
+}
+
+// String 格式化为 VOLSER:DIRCLUSTER:OFFSET（十六进制）
+func (id entryID) String() string {
+	return fmt.Sprintf("%08X:%X:%X", id.serial, id.dir, id.offset)
+}
+
+// ID 返回条目在本映像中的确定性标识，参见 EntryID
+func (e FileEntry) ID() string {
+	return EntryID(e)
+}
+
+// EntryID 返回条目的确定性标识，格式为 VOLSER:DIRCLUSTER:OFFSET
+// 标识由卷序列号、所在目录的起始簇和条目集在目录中的字节偏移组成，与路径无关，
+// 同一个映像多次打开得到相同的结果。标识只在映像未被修改时稳定：
+// 重命名、删除或目录重组都可能改变条目集的位置。不是由本包返回的条目没有标识，返回空串。
+func EntryID(entry FileEntry) string {
+	if entry.id.serial == 0 && entry.id.dir == 0 && entry.id.offset == 0 {
+		return ""
+	}
+	return entry.id.String()
+}
+
+// fileID 返回类似 inode 号的标识，可以在两次列目录之间识别同一个文件（如 FUSE 需要的稳定 inode 号）
+// 有数据的条目使用起始簇号；没有簇的条目（空文件等）使用最高位置 1、
+// 由所在目录的起始簇和条目集序号（目录最大 256 MiB，不超过 2^23 个条目）组成的值，不会与簇号冲突。
+// 与 EntryID 一样只在映像未被修改时稳定：文件重写后簇会改变。
+func (e *DirEntry) fileID() uint64 {
+	if e.hasClusters() {
+		return uint64(e.cluster)
+	}
+	return 1<<63 | uint64(e.id.dir)<<23 | uint64(e.id.offset/32)&(1<<23-1)
+}
+
+// ResolveEntryID 根据 EntryID 返回的标识查找对应的条目
+func (fs *ExFATFileSystem) ResolveEntryID(id string) (FileEntry, error) {
+	var want entryID
+	if _, err := fmt.Sscanf(id, "%X:%X:%X", &want.serial, &want.dir, &want.offset); err != nil {
+		return FileEntry{}, fmt.Errorf("invalid entry ID %q: %v", id, err)
+	}
+	if want.serial != fs.bootSector.VolumeSerialNumber {
+		return FileEntry{}, fmt.Errorf("entry ID %s belongs to volume %08X, not %08X", id, want.serial, fs.bootSector.VolumeSerialNumber)
+	}
+
+	root := fs.rootEntry()
+	if want == root.id {
+		return root.fileEntry(), nil
+	}
+
+	dir, err := fs.findDirectoryByCluster(root, want.dir, make(map[uint32]bool))
+	if err != nil {
+		return FileEntry{}, err
+	}
+	if dir == nil {
+		return FileEntry{}, fmt.Errorf("entry ID %s: no directory starts at cluster %d", id, want.dir)
+	}
+
+	children, err := fs.readDirectoryEntries(dir)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	for _, child := range children {
+		if child.id == want {
+			return child.fileEntry(), nil
+		}
+	}
+	return FileEntry{}, fmt.Errorf("entry ID %s: no entry set at offset %d of %s", id, want.offset, dir.path)
+}
+
+// findDirectoryByCluster 在 dir 下深度优先查找起始簇为 cluster 的目录
+func (fs *ExFATFileSystem) findDirectoryByCluster(dir *DirEntry, cluster uint32, visited map[uint32]bool) (*DirEntry, error) {
+	if dir.cluster == cluster {
+		return dir, nil
+	}
+	if !dir.hasClusters() || !firstVisit(visited, dir) {
+		return nil, nil
+	}
+
+	children, err := fs.readDirectoryEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		if !child.IsDir {
+			continue
+		}
+		found, err := fs.findDirectoryByCluster(child, cluster, visited)
+		if found != nil || err != nil {
+			return found, err
+		}
+	}
+	return nil, nil
+}
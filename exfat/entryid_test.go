@@ -0,0 +1,167 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// entryIDTree 返回用于 EntryID 测试的目录树：嵌套目录、空文件、跨簇的目录和同名文件
+func entryIDTree() []*testimage.Node {
+	var many []*testimage.Node
+	for i := 0; i < 40; i++ {
+		many = append(many, testimage.File(fmt.Sprintf("file%02d.txt", i), []byte{byte(i)}))
+	}
+	return []*testimage.Node{
+		testimage.File("empty", nil),
+		testimage.Dir("DCIM", testimage.File("a.jpg", fill(5000, 1)), testimage.Dir("Sub", testimage.File("a.jpg", []byte("sub")))),
+		testimage.Dir("Many", many...),
+	}
+}
+
+// entryIDs 返回整个卷上路径到 EntryID 的映射
+func entryIDs(t *testing.T, fs *ExFATFileSystem) map[string]string {
+	t.Helper()
+	ids := map[string]string{}
+	err := fs.Walk("/", func(path string, e FileEntry) error {
+		if e.ID() != EntryID(e) {
+			t.Errorf("%s: ID() %s, EntryID %s", path, e.ID(), EntryID(e))
+		}
+		ids[path] = EntryID(e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+// renameInPlace 把条目改为同样长度的新名称，更新名称哈希和校验和，条目集的位置不变
+func renameInPlace(img *testimage.Image, path, name string) {
+	e := img.Entry(path)
+	u := utf16.Encode([]rune(name))
+	if int(img.Slot(e, 1)[3]) != len(u) || len(u) > 15 {
+		panic("renameInPlace: name length changes")
+	}
+	binary.LittleEndian.PutUint16(img.Slot(e, 1)[4:], testimage.NameHash(u, img.Upcase))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(img.Slot(e, 2)[2+2*i:], c)
+	}
+	img.Resum(e)
+}
+
+func TestEntryIDStableAcrossOpens(t *testing.T) {
+	img := testimage.Build(testimage.Options{Serial: 0xCAFE0042}, entryIDTree()...)
+	first := entryIDs(t, openImage(t, img))
+	second := entryIDs(t, openImage(t, img, WithStrict()))
+	if !maps.Equal(first, second) {
+		t.Fatalf("IDs differ between opens:\n%v\n%v", first, second)
+	}
+
+	fs := openImage(t, img)
+	seen := map[string]string{}
+	for path, id := range first {
+		var serial, dir uint32
+		var offset int64
+		if _, err := fmt.Sscanf(id, "%X:%X:%X", &serial, &dir, &offset); err != nil || serial != 0xCAFE0042 || offset%32 != 0 {
+			t.Errorf("%s: malformed ID %q", path, id)
+		}
+		if other, ok := seen[id]; ok {
+			t.Errorf("%s and %s share ID %s", path, other, id)
+		}
+		seen[id] = path
+
+		// 从标识回到条目：与按路径查到的是同一个条目
+		got, err := fs.ResolveEntryID(id)
+		if err != nil {
+			t.Errorf("ResolveEntryID(%s) for %s: %v", id, path, err)
+			continue
+		}
+		want, _ := fs.Stat(path)
+		if got.Name != want.Name || got.Size != want.Size || got.IsDir != want.IsDir || EntryID(got) != id {
+			t.Errorf("ResolveEntryID(%s) = %+v, want %s", id, got, path)
+		}
+	}
+	// 两个 a.jpg 名称相同，所在目录不同
+	if first["/DCIM/a.jpg"] == first["/DCIM/Sub/a.jpg"] {
+		t.Error("files with the same name in different directories share an ID")
+	}
+	if root, err := fs.Stat("/"); err != nil || root.ID() == "" {
+		t.Errorf("root has no ID: %+v, %v", root, err)
+	} else if got, err := fs.ResolveEntryID(root.ID()); err != nil || !got.IsDir {
+		t.Errorf("ResolveEntryID(root) = %+v, %v", got, err)
+	}
+	if id := EntryID(FileEntry{Name: "x"}); id != "" {
+		t.Errorf("entry not returned by the package has ID %q", id)
+	}
+}
+
+func TestEntryIDSurvivesRename(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, entryIDTree()...)
+	before := entryIDs(t, openImage(t, img))
+
+	// 写入路径只改写条目集本身，标识不变
+	fs := openImage(t, img)
+	if err := fs.Chtimes("/DCIM/a.jpg", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Chattr("/DCIM/a.jpg", AttrReadOnly); err != nil {
+		t.Fatal(err)
+	}
+	// 原位重命名：路径改变，条目集的位置和标识不变
+	renameInPlace(img, "/DCIM/a.jpg", "b.jpg")
+	renameInPlace(img, "/Many", "Lots")
+
+	fs = openImage(t, img, WithStrict())
+	after := entryIDs(t, fs)
+	renamed := map[string]string{"/DCIM/a.jpg": "/DCIM/b.jpg", "/Many": "/Lots"}
+	for path, id := range before {
+		newPath := path
+		if p, ok := renamed[path]; ok {
+			newPath = p
+		} else if rest, ok := strings.CutPrefix(path, "/Many/"); ok {
+			newPath = "/Lots/" + rest
+		}
+		if after[newPath] != id {
+			t.Errorf("%s (now %s): ID %s, was %s", path, newPath, after[newPath], id)
+		}
+	}
+	if _, ok := after["/DCIM/a.jpg"]; ok {
+		t.Error("old path still exists after the rename")
+	}
+	got, err := fs.ResolveEntryID(before["/DCIM/a.jpg"])
+	if err != nil || got.Name != "b.jpg" || got.Attributes != AttrReadOnly {
+		t.Errorf("ResolveEntryID after rename = %+v, %v", got, err)
+	}
+}
+
+func TestResolveEntryIDErrors(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, entryIDTree()...)
+	fs := openImage(t, img)
+	a, err := fs.Stat("/DCIM/a.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var serial, dir uint32
+	var offset int64
+	fmt.Sscanf(a.ID(), "%X:%X:%X", &serial, &dir, &offset)
+
+	for _, id := range []string{
+		"",
+		"not an id",
+		fmt.Sprintf("%08X:%X:%X", serial+1, dir, offset),    // 其他卷
+		fmt.Sprintf("%08X:%X:%X", serial, 0xFFFF, offset),   // 没有从这个簇开始的目录
+		fmt.Sprintf("%08X:%X:%X", serial, dir, offset+0x20), // 条目集中间的槽位
+		fmt.Sprintf("%08X:%X:%X", serial, dir, 0x10000),     // 超出目录
+	} {
+		if e, err := fs.ResolveEntryID(id); err == nil {
+			t.Errorf("ResolveEntryID(%q) = %+v", id, e)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package exfat
+
+// slotClass 目录槽位按类型字节的分类（exFAT 规范 6.2.1 的 TypeCode/TypeCategory/InUse）
+type slotClass int
+
+const (
+	slotEnd       slotClass = iota // 0x00：目录结束，之后的槽位都未使用，不再扫描
+	slotUnused                     // 0x01–0x7F：InUse 位为 0，槽位未使用（如已删除的条目），跳过后继续扫描
+	slotPrimary                    // 0x80–0xBF：在用的主条目，总是开始一个新的条目集
+	slotSecondary                  // 0xC0–0xFF：在用的次条目，只能属于前面尚未结束的条目集
+)
+
+// classifySlot 按类型字节对目录槽位分类
+func classifySlot(entryType byte) slotClass {
+	switch {
+	case entryType == EntryTypeEndOfDirectory:
+		return slotEnd
+	case entryType&EntryInUse == 0:
+		return slotUnused
+	case entryType&0x40 == 0:
+		return slotPrimary
+	default:
+		return slotSecondary
+	}
+}
+
+// secondaryCount 返回主条目声明的次条目数
+// 分配位图、大写表和卷标使用自己的格式，第二个字节不是 SecondaryCount，没有次条目；
+// 其他主条目（文件、卷 GUID、TexFAT 填充和未知的主条目）按通用主条目模板解释。
+func secondaryCount(slot []byte) int {
+	switch slot[0] {
+	case EntryTypeAllocationBitmap, EntryTypeUpcaseTable, EntryTypeVolumeLabel:
+		return 0
+	}
+	return int(slot[1])
+}
+
+// entrySet 目录中的一个条目集：主条目和实际跟在它后面的次条目
+type entrySet struct {
+	start, end int  // 在目录数据中的范围，只包含实际属于该条目集的槽位
+	declared   int  // 主条目声明的次条目数
+	truncated  bool // 在 declared 个次条目之前被打断（遇到主条目、未使用的槽位、目录结束或数据结束）
+}
+
+// slots 返回条目集的数据
+func (s entrySet) slots(data []byte) []byte {
+	return data[s.start:s.end]
+}
+
+// setState 扫描目录槽位时的状态
+type setState int
+
+const (
+	stateBetweenSets setState = iota // 在条目集之间，等待主条目；次条目在这里是孤立的，被跳过
+	stateInSet                       // 正在收集当前条目集的次条目
+	stateEnded                       // 遇到了目录结束，之后的槽位都不扫描
+)
+
+// setScanner 把目录槽位序列划分为条目集的状态机
+// 未使用的槽位被跳过；目录结束终止扫描；主条目总是开始新的条目集，不会被当作前一个条目集的次条目，
+// 前一个条目集因此被打断。未使用的槽位同样打断正在收集的条目集：次条目必须紧接在主条目之后。
+// 列出已删除的条目、重新同步等恢复功能依赖于这里的划分。
+type setScanner struct {
+	state     setState
+	set       entrySet // 正在收集的条目集
+	remaining int      // 当前条目集还需要的次条目数
+}
+
+// step 处理 offset 处的槽位，把因此结束的条目集（完整的或被打断的）交给 emit
+// 一个槽位最多结束两个条目集：被主条目打断的条目集，以及该主条目没有次条目时它自己的条目集。
+func (s *setScanner) step(data []byte, offset int, emit func(entrySet)) {
+	if s.state == stateEnded {
+		return
+	}
+	slot := data[offset : offset+32]
+	class := classifySlot(slot[0])
+
+	if s.state == stateInSet {
+		if class == slotSecondary {
+			s.set.end = offset + 32
+			if s.remaining--; s.remaining == 0 {
+				s.state = stateBetweenSets
+				emit(s.set)
+			}
+			return
+		}
+		// 主条目、未使用的槽位和目录结束都打断当前条目集
+		s.finish(emit)
+	}
+
+	switch class {
+	case slotEnd:
+		s.state = stateEnded
+	case slotPrimary:
+		n := secondaryCount(slot)
+		s.set = entrySet{start: offset, end: offset + 32, declared: n}
+		if n == 0 {
+			emit(s.set)
+			return
+		}
+		s.state = stateInSet
+		s.remaining = n
+	}
+	// 未使用的槽位和孤立的次条目被跳过
+}
+
+// finish 结束正在收集的条目集（作为被打断的条目集交给 emit）；数据结束时同样调用
+func (s *setScanner) finish(emit func(entrySet)) {
+	if s.state != stateInSet {
+		return
+	}
+	s.state = stateBetweenSets
+	done := s.set
+	done.truncated = true
+	emit(done)
+}
+
+// scanEntrySets 按顺序返回目录数据中的条目集，以及是否遇到了目录结束
+func scanEntrySets(data []byte) ([]entrySet, bool) {
+	var sets []entrySet
+	emit := func(set entrySet) { sets = append(sets, set) }
+	var s setScanner
+	for offset := 0; offset+32 <= len(data) && s.state != stateEnded; offset += 32 {
+		s.step(data, offset, emit)
+	}
+	s.finish(emit)
+	return sets, s.state == stateEnded
+}
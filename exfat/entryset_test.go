@@ -0,0 +1,207 @@
+package exfat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// slots 按 (类型, 第二个字节) 对构造目录数据
+func slots(pairs ...byte) []byte {
+	data := make([]byte, 0, 16*len(pairs))
+	for i := 0; i+1 < len(pairs); i += 2 {
+		slot := make([]byte, 32)
+		slot[0], slot[1] = pairs[i], pairs[i+1]
+		data = append(data, slot...)
+	}
+	return data
+}
+
+func TestClassifySlot(t *testing.T) {
+	tests := []struct {
+		from, to byte
+		want     slotClass
+	}{
+		{0x00, 0x00, slotEnd},
+		{0x01, 0x7F, slotUnused},
+		{0x80, 0xBF, slotPrimary},
+		{0xC0, 0xFF, slotSecondary},
+	}
+	for _, tt := range tests {
+		for b := int(tt.from); b <= int(tt.to); b++ {
+			if got := classifySlot(byte(b)); got != tt.want {
+				t.Errorf("classifySlot(0x%02X) = %d, want %d", b, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestScanEntrySets(t *testing.T) {
+	const s = 32
+	tests := []struct {
+		name       string
+		data       []byte
+		sets       []entrySet
+		terminated bool
+	}{
+		{"empty", nil, nil, false},
+		{"only end", slots(0x00, 0), nil, true},
+		{"complete set", slots(0x85, 2, 0xC0, 0, 0xC1, 0, 0x00, 0),
+			[]entrySet{{0, 3 * s, 2, false}}, true},
+		{"interrupted by a primary", slots(0x85, 2, 0xC0, 0, 0x85, 1, 0xC0, 0),
+			[]entrySet{{0, 2 * s, 2, true}, {2 * s, 4 * s, 1, false}}, false},
+		{"interrupted by an unused slot", slots(0x85, 2, 0xC0, 0, 0x05, 0, 0xC1, 0, 0x00, 0),
+			[]entrySet{{0, 2 * s, 2, true}}, true},
+		{"interrupted by the end", slots(0x85, 2, 0xC0, 0, 0x00, 0, 0x85, 1, 0xC0, 0),
+			[]entrySet{{0, 2 * s, 2, true}}, true},
+		{"interrupted by the data end", slots(0x85, 3, 0xC0, 0),
+			[]entrySet{{0, 2 * s, 3, true}}, false},
+		{"orphan secondary", slots(0xC0, 0, 0x85, 1, 0xC1, 0, 0x00, 0),
+			[]entrySet{{s, 3 * s, 1, false}}, true},
+		{"unused slot between sets", slots(0x85, 1, 0xC0, 0, 0x41, 0, 0x85, 1, 0xC0, 0, 0x00, 0),
+			[]entrySet{{0, 2 * s, 1, false}, {3 * s, 5 * s, 1, false}}, true},
+		// 位图、大写表和卷标的第二个字节不是 SecondaryCount
+		{"system entries", slots(0x81, 5, 0x82, 9, 0x83, 3, 0x85, 1, 0xC0, 0, 0x00, 0),
+			[]entrySet{{0, s, 0, false}, {s, 2 * s, 0, false}, {2 * s, 3 * s, 0, false}, {3 * s, 5 * s, 1, false}}, true},
+		{"benign primary", slots(0xA0, 1, 0xC0, 0, 0x00, 0),
+			[]entrySet{{0, 2 * s, 1, false}}, true},
+		{"primary without secondaries", slots(0x85, 0, 0xC0, 0, 0x00, 0),
+			[]entrySet{{0, s, 0, false}}, true},
+		// 没有次条目的主条目打断前一个条目集时，两个条目集在同一个槽位结束
+		{"two sets end on one slot", slots(0x85, 2, 0xC0, 0, 0xA1, 0, 0x00, 0),
+			[]entrySet{{0, 2 * s, 2, true}, {2 * s, 3 * s, 0, false}}, true},
+	}
+	for _, tt := range tests {
+		sets, terminated := scanEntrySets(tt.data)
+		if !slices.Equal(sets, tt.sets) || terminated != tt.terminated {
+			t.Errorf("%s: got %+v (terminated %v), want %+v (%v)", tt.name, sets, terminated, tt.sets, tt.terminated)
+		}
+	}
+}
+
+func TestSetScannerTransitions(t *testing.T) {
+	data := slots(0x85, 1, 0xC0, 0, 0x85, 2, 0xC0, 0, 0x00, 0, 0x85, 0)
+	var s setScanner
+	var emitted []entrySet
+	emit := func(set entrySet) { emitted = append(emitted, set) }
+
+	steps := []struct {
+		state   setState
+		emitted int
+	}{
+		{stateInSet, 0},       // 主条目开始条目集
+		{stateBetweenSets, 1}, // 最后一个次条目结束条目集
+		{stateInSet, 1},
+		{stateInSet, 1}, // 还差一个次条目
+		{stateEnded, 2}, // 目录结束打断条目集
+		{stateEnded, 2}, // 结束之后的槽位被忽略
+	}
+	for i, want := range steps {
+		s.step(data, i*32, emit)
+		if s.state != want.state || len(emitted) != want.emitted {
+			t.Fatalf("slot %d: state %d with %d sets, want %d with %d", i, s.state, len(emitted), want.state, want.emitted)
+		}
+	}
+	if !emitted[1].truncated || emitted[0].truncated {
+		t.Errorf("sets %+v", emitted)
+	}
+	// 结束之后 finish 不再产生条目集
+	s.finish(emit)
+	if len(emitted) != 2 {
+		t.Errorf("finish after the end emitted %d sets", len(emitted)-2)
+	}
+}
+
+// streamImage 返回目录 /S 中有两个文件的卷，streams 指定 f.txt 条目集中流扩展条目的个数：
+// 0 表示第一个次条目不是流扩展条目，2 表示在文件名之后多出一个指向其他簇的流扩展条目
+func streamImage(streams int) *testimage.Image {
+	img := testimage.Build(testimage.Options{},
+		testimage.Dir("S", testimage.File("ok.txt", []byte("untouched")), testimage.File("f.txt", []byte("first stream"))),
+		testimage.File("other.txt", []byte("second stream")))
+	e := img.Entry("/S/f.txt")
+	switch streams {
+	case 0:
+		img.Slot(e, 1)[0] = EntryTypeFileName
+	case 2:
+		// 条目集之后是目录结束标记，多出的槽位占用它
+		extra := img.Slot(e, 3)
+		copy(extra, img.Slot(e, 1))
+		other := img.Entry("/other.txt")
+		binary.LittleEndian.PutUint32(extra[20:], other.Clusters[0])
+		binary.LittleEndian.PutUint64(extra[8:], uint64(other.Size))
+		binary.LittleEndian.PutUint64(extra[24:], uint64(other.Size))
+		img.Slot(e, 0)[1]++
+	}
+	img.Resum(e)
+	return img
+}
+
+func TestStreamExtensionCount(t *testing.T) {
+	tests := []struct {
+		streams int
+		strict  string // 严格模式下错误包含的内容，为空表示没有错误
+		diag    string // 宽松模式下诊断包含的内容
+	}{
+		{0, "first secondary entry is not a stream extension (type 0xC1)", "set skipped"},
+		{1, "", ""},
+		{2, "2 stream extension entries", "2 stream extension entries; using the first"},
+	}
+	for _, tt := range tests {
+		img := streamImage(tt.streams)
+
+		// 严格模式拒绝整个目录
+		_, err := openImage(t, img, WithStrict()).ListDir("/S")
+		if tt.strict == "" && err != nil || tt.strict != "" && (err == nil || !strings.Contains(err.Error(), tt.strict)) {
+			t.Errorf("%d streams, strict: %v, want %q", tt.streams, err, tt.strict)
+		}
+
+		// 宽松模式：没有流扩展条目的条目集被跳过，多余的流扩展条目被忽略，使用第一个
+		fs := openImage(t, img)
+		entries, err := fs.ListDir("/S")
+		if err != nil {
+			t.Fatalf("%d streams, lenient: %v", tt.streams, err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		want := []string{"ok.txt", "f.txt"}
+		if tt.streams == 0 {
+			want = want[:1]
+		}
+		if !slices.Equal(names, want) {
+			t.Errorf("%d streams: entries %q, want %q", tt.streams, names, want)
+		}
+		anomalies, err := fs.DirAnomalies("/S")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.streams > 0 {
+			st, err := fs.Stat("/S/f.txt")
+			if err != nil || st.Streams != tt.streams || st.Size != int64(len("first stream")) {
+				t.Errorf("%d streams: Stat = %+v, %v", tt.streams, st, err)
+			}
+			if data, err := fs.ReadFile("/S/f.txt"); err != nil || !bytes.Equal(data, []byte("first stream")) {
+				t.Errorf("%d streams: ReadFile = %q, %v", tt.streams, data, err)
+			}
+			if got := slices.Contains(anomalies["f.txt"], AnomalyExtraStreamExtension); got != (tt.streams == 2) {
+				t.Errorf("%d streams: anomalies %v", tt.streams, anomalies["f.txt"])
+			}
+		}
+		if len(anomalies["ok.txt"]) != 0 {
+			t.Errorf("%d streams: neighbouring entry has anomalies %v", tt.streams, anomalies["ok.txt"])
+		}
+
+		var diagnosed bool
+		for _, d := range fs.Diagnostics() {
+			diagnosed = diagnosed || d.Path == "/S" && tt.diag != "" && strings.Contains(d.Message, tt.diag)
+		}
+		if diagnosed != (tt.diag != "") {
+			t.Errorf("%d streams: diagnostics %v, want %q", tt.streams, fs.Diagnostics(), tt.diag)
+		}
+	}
+}
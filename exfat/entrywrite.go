@@ -0,0 +1,72 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// 改写现有条目元数据的操作（Chtimes、Chattr）只改动文件条目本身，不分配簇，也不改变目录结构
+
+// entryForWrite 定位 path 的条目集，返回写入器、条目和条目集数据
+// 底层映像不可写时返回 ErrReadOnly；根目录没有条目集，不能改写。
+func (fs *ExFATFileSystem) entryForWrite(path string) (io.WriterAt, *DirEntry, []byte, int64, error) {
+	w, ok := fs.vhd.(io.WriterAt)
+	if !ok {
+		return nil, nil, nil, 0, ErrReadOnly
+	}
+	if wr, ok := fs.vhd.(interface{ Writable() bool }); ok && !wr.Writable() {
+		return nil, nil, nil, 0, ErrReadOnly
+	}
+
+	components := splitPath(normalizePath(path))
+	if len(components) == 0 {
+		return nil, nil, nil, 0, fmt.Errorf("the root directory has no directory entry to modify")
+	}
+	result, err := fs.resolvePath(fs.rootEntry(), components, resolveOpts{})
+	if err != nil {
+		return nil, nil, nil, 0, fmt.Errorf("%w: %s", err, path)
+	}
+
+	set, offset, err := fs.readEntrySet(result.parent, result.entry.id.offset)
+	if err != nil {
+		return nil, nil, nil, 0, fmt.Errorf("failed to read entry set of %s: %v", path, err)
+	}
+	return w, result.entry, set, offset, nil
+}
+
+// writeEntrySet 重新计算条目集校验和，并把文件条目写回 offset
+// 可改写的字段和校验和都在文件条目中，次条目不需要写回。
+func writeEntrySet(w io.WriterAt, set []byte, offset int64) error {
+	binary.LittleEndian.PutUint16(set[2:], entrySetChecksum(set))
+	if _, err := w.WriteAt(set[:32], offset); err != nil {
+		return fmt.Errorf("failed to write entry set: %w", err)
+	}
+	return nil
+}
+
+// readEntrySet 读取 dir 中从 pos 开始的条目集，返回条目集数据和文件条目在卷上的偏移
+// 条目集可能跨越簇边界，每个条目分别换算偏移；校验和不符的条目集不允许改写，以免掩盖损坏。
+func (fs *ExFATFileSystem) readEntrySet(dir *DirEntry, pos int64) ([]byte, int64, error) {
+	primary := make([]byte, 32)
+	offset := fs.directoryOffset(dir, pos)
+	if _, err := fs.readAt(primary, offset, TraceDir); err != nil {
+		return nil, 0, err
+	}
+	if primary[0] != EntryTypeFile {
+		return nil, 0, fmt.Errorf("entry at offset %d is of type 0x%02X, not a file entry", pos, primary[0])
+	}
+
+	count := int(primary[1]) + 1
+	set := make([]byte, count*32)
+	copy(set, primary)
+	for i := 1; i < count; i++ {
+		if _, err := fs.readAt(set[i*32:(i+1)*32], fs.directoryOffset(dir, pos+int64(i)*32), TraceDir); err != nil {
+			return nil, 0, err
+		}
+	}
+	if got, want := entrySetChecksum(set), binary.LittleEndian.Uint16(set[2:]); got != want {
+		return nil, 0, fmt.Errorf("entry set checksum mismatch (0x%04X, expected 0x%04X)", got, want)
+	}
+	return set, offset, nil
+}
@@ -0,0 +1,71 @@
+package exfat
+
+import "fmt"
+
+// Deviation 偏离规范的写法的种类
+type Deviation string
+
+// 偏离规范的写法
+const (
+	DeviationReservedEOC Deviation = "reserved-eoc" // 以 0xFFFFFFF8-0xFFFFFFFE 而不是 0xFFFFFFFF 结束簇链
+	DeviationFAT32EOC    Deviation = "fat32-eoc"    // 以 FAT32 风格的 0x0FFFFFFF 结束簇链
+)
+
+// ConformanceIssue 卷中偏离规范、但读取时照常接受的写法
+// 与 Diagnostics 中记录的问题不同，这些写法不影响数据：其他实现同样接受，文件完整可读。
+type ConformanceIssue struct {
+	Deviation Deviation
+	Count     int    // 出现的次数
+	Formatter string // 格式化工具的指纹（见 VolumeIdentity.Formatter），便于把写法与设备对应起来
+	Message   string
+}
+
+// String 返回问题的文本形式
+func (c ConformanceIssue) String() string {
+	return c.Message
+}
+
+// Conformance 返回打开卷时发现的偏离规范的写法，没有时返回 nil
+func (fs *ExFATFileSystem) Conformance() []ConformanceIssue {
+	return append([]ConformanceIssue(nil), fs.conformance...)
+}
+
+// endOfChain FAT 项是否表示簇链结束
+// 按规范 0xFFFFFFF8 到 0xFFFFFFFF 都是链结束；启用 FAT32 风格标记时 0x0FFFFFFF 也是
+func (fs *ExFATFileSystem) endOfChain(next uint32) bool {
+	return next >= ReservedCluster || (fs.fat32EOC && next == FAT32EndOfChain)
+}
+
+// checkEndOfChainMarkers 检查簇堆的 FAT 项中非标准的链结束标记，记入 Conformance 并记录诊断
+// 0x0FFFFFFF 不可能是有效簇号时自动把它视为链结束。这里不按格式化工具的指纹启用：
+// 指纹只覆盖跳转指令和引导代码，闪存设备格式化的卷大多不带引导代码，不同设备的指纹相同，
+// 按指纹启用会把同一指纹的其他卷也一起改变；而超出簇堆的 0x0FFFFFFF 在任何卷上都只能是链结束，
+// 按它判断不会误读符合规范的卷。指纹记录在 ConformanceIssue.Formatter 中。
+func (fs *ExFATFileSystem) checkEndOfChainMarkers() {
+	fs.fat32EOC = fs.opts.fat32EOC
+	fs.conformance = nil
+
+	last := min(uint64(fs.totalClusters)+1, uint64(len(fs.fat))-1)
+	var reserved, fat32 int
+	for c := uint64(2); c <= last && len(fs.fat) > 2; c++ {
+		switch next := fs.fat[c]; {
+		case next >= ReservedCluster && next != EndOfClusterChain:
+			reserved++
+		case next == FAT32EndOfChain && (fs.fat32EOC || !fs.validCluster(next)):
+			fat32++
+		}
+	}
+
+	formatter := fs.bootSector.formatterFingerprint()
+	if reserved > 0 {
+		msg := fmt.Sprintf("%d FAT entries use a non-standard end-of-chain marker (0xFFFFFFF8-0xFFFFFFFE)", reserved)
+		fs.conformance = append(fs.conformance, ConformanceIssue{DeviationReservedEOC, reserved, formatter, msg})
+		fs.diagnose("", "%s", msg)
+	}
+	if fat32 > 0 {
+		fs.fat32EOC = true
+		msg := fmt.Sprintf("%d FAT entries use the FAT32-style end-of-chain marker 0x0FFFFFFF", fat32)
+		fs.conformance = append(fs.conformance, ConformanceIssue{DeviationFAT32EOC, fat32, formatter, msg})
+		fs.diagnose("", "%s", msg)
+	}
+}
@@ -0,0 +1,162 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// eocImage 构造带 FAT 簇链的卷：碎片化的文件和目录、不设置 NoFatChain 的连续文件和跨簇的目录，
+// 再把所有簇链（包括根目录、位图和大写表的）的结束标记依次改为 markers 中的值
+func eocImage(markers ...uint32) (*testimage.Image, int) {
+	fragmented := testimage.File("a.bin", fill(2000, 1))
+	fragmented.Fragmented = true
+	chained := testimage.File("b.bin", fill(1500, 2))
+	chained.FATChain = true
+	dir := testimage.Dir("Photos", fragmented, chained, testimage.File("c.bin", fill(700, 3)))
+	dir.Fragmented, dir.DirClusters = true, 3
+	big := testimage.File("big.bin", fill(5000, 4))
+	big.Fragmented = true
+	img := testimage.Build(testimage.Options{}, dir, big, testimage.File("small.txt", []byte("small")))
+
+	ends := 0
+	clusterCount := binary.LittleEndian.Uint32(img.BootSector(false)[92:])
+	for c := uint32(2); c < clusterCount+2 && len(markers) > 0; c++ {
+		if img.FAT(c) == EndOfClusterChain {
+			img.SetFAT(c, markers[ends%len(markers)])
+			ends++
+		}
+	}
+	return img, ends
+}
+
+// sameContents 报告与 want 不同的路径
+func sameContents(t *testing.T, what string, got, want map[string]string) {
+	t.Helper()
+	for path, data := range want {
+		if got[path] != data {
+			t.Errorf("%s: %s reads %d bytes differently", what, path, len(got[path]))
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("%s: %d paths, want %d", what, len(got), len(want))
+	}
+}
+
+// volumeContents 返回卷上每个路径的内容（目录为 "dir"）
+func volumeContents(t *testing.T, fs *ExFATFileSystem) map[string]string {
+	t.Helper()
+	contents := map[string]string{}
+	err := fs.Walk("/", func(path string, e FileEntry) error {
+		if e.IsDir {
+			contents[path] = "dir"
+			return nil
+		}
+		data, err := fs.ReadFile(path)
+		contents[path] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contents
+}
+
+func TestEndOfChainVariants(t *testing.T) {
+	clean, _ := eocImage()
+	want := volumeContents(t, openImage(t, clean, WithStrict()))
+	if len(want) != 7 {
+		t.Fatalf("reference volume holds %v", want)
+	}
+	formatter, err := openImage(t, clean).Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var variants []uint32
+	for m := uint32(ReservedCluster); m != 0; m++ {
+		variants = append(variants, m)
+	}
+	variants = append(variants, FAT32EndOfChain)
+	for _, marker := range variants {
+		img, ends := eocImage(marker)
+		if ends < 6 {
+			t.Fatalf("only %d chains to terminate", ends)
+		}
+		// 严格模式下同样可读，内容与标准标记的卷完全相同
+		fs := openImage(t, img, WithStrict())
+		sameContents(t, fmt.Sprintf("0x%08X", marker), volumeContents(t, fs), want)
+
+		var wantIssues []ConformanceIssue
+		switch marker {
+		case EndOfClusterChain:
+		case FAT32EndOfChain:
+			wantIssues = []ConformanceIssue{{DeviationFAT32EOC, ends, formatter.Formatter,
+				fmt.Sprintf("%d FAT entries use the FAT32-style end-of-chain marker 0x0FFFFFFF", ends)}}
+		default:
+			wantIssues = []ConformanceIssue{{DeviationReservedEOC, ends, formatter.Formatter,
+				fmt.Sprintf("%d FAT entries use a non-standard end-of-chain marker (0xFFFFFFF8-0xFFFFFFFE)", ends)}}
+		}
+		got := fs.Conformance()
+		if len(got) != len(wantIssues) || (len(got) > 0 && got[0] != wantIssues[0]) {
+			t.Errorf("0x%08X: Conformance() = %+v, want %+v", marker, got, wantIssues)
+		}
+	}
+}
+
+func TestEndOfChainMixedVariants(t *testing.T) {
+	clean, _ := eocImage()
+	want := volumeContents(t, openImage(t, clean))
+	markers := []uint32{0xFFFFFFF8, FAT32EndOfChain, EndOfClusterChain, 0xFFFFFFFE}
+	img, ends := eocImage(markers...)
+	fs := openImage(t, img)
+	sameContents(t, "mixed markers", volumeContents(t, fs), want)
+
+	var reserved, fat32 int
+	for i := 0; i < ends; i++ {
+		switch markers[i%len(markers)] {
+		case FAT32EndOfChain:
+			fat32++
+		case 0xFFFFFFF8, 0xFFFFFFFE:
+			reserved++
+		}
+	}
+	issues := fs.Conformance()
+	if len(issues) != 2 || issues[0].Deviation != DeviationReservedEOC || issues[0].Count != reserved ||
+		issues[1].Deviation != DeviationFAT32EOC || issues[1].Count != fat32 {
+		t.Errorf("Conformance() = %+v, want %d reserved and %d FAT32-style markers", issues, reserved, fat32)
+	}
+	// Sub 视图共享同一个卷
+	sub, err := fs.Sub("/Photos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sub.Conformance()) != 2 {
+		t.Errorf("Sub view: %+v", sub.Conformance())
+	}
+}
+
+func TestFAT32StyleEOCOnHugeVolume(t *testing.T) {
+	img, _ := eocImage()
+	fs := openImage(t, img)
+	// 簇数超过 0x0FFFFFFD 时 0x0FFFFFFF 是有效簇号，只有显式指定时才是链结束
+	fs.totalClusters = 0x10000100
+	c := img.Entry("/big.bin").Clusters[0]
+	fs.fat[c] = FAT32EndOfChain
+
+	fs.checkEndOfChainMarkers()
+	if fs.endOfChain(FAT32EndOfChain) || len(fs.Conformance()) != 0 {
+		t.Errorf("0x0FFFFFFF treated as end of chain without the option: %+v", fs.Conformance())
+	}
+
+	fs.opts.fat32EOC = true
+	fs.checkEndOfChainMarkers()
+	if issues := fs.Conformance(); !fs.endOfChain(FAT32EndOfChain) || len(issues) != 1 || issues[0].Count != 1 {
+		t.Errorf("WithFAT32StyleEOC: end of chain %v, %+v", fs.endOfChain(FAT32EndOfChain), issues)
+	}
+	if got, err := fs.ReadFileRange("/big.bin", 0, 512); err != nil || string(got) != string(fill(5000, 4)[:512]) {
+		t.Errorf("first cluster: %v", err)
+	}
+}
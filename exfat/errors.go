@@ -0,0 +1,70 @@
+package exfat
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// ErrNotExist 表示路径不存在，可以用 errors.Is(err, fs.ErrNotExist) 判断
+// 按路径查找的功能（Stat、ListDir、ReadFile、Open 等）返回包装了它并带有路径的错误。
+var ErrNotExist error = &sentinel{msg: "path not found", parent: fs.ErrNotExist}
+
+// ErrNotDirectory 表示路径中间的组件是文件，或者对文件列目录
+var ErrNotDirectory = errors.New("not a directory")
+
+// ErrIsDirectory 表示对目录执行了只适用于文件的操作（如 ReadFile、Open）
+var ErrIsDirectory = errors.New("path is a directory, not a file")
+
+// ErrInvalidImage 表示映像不是有效的 exFAT 卷或其结构已损坏
+// 引导扇区签名不符时直接返回它；ErrBadCluster、ErrBrokenChain、ErrCrossLinked、ErrTruncatedImage、
+// ErrRegionOverlap、ErrBootChecksum 和 ErrChecksumMismatch 描述具体的损坏，也都可以用 errors.Is(err, ErrInvalidImage) 判断。
+var ErrInvalidImage = errors.New("not a valid exFAT filesystem")
+
+// ErrBadCluster 表示簇号无效：不在簇堆中，或者是坏簇标记等特殊值
+var ErrBadCluster error = &sentinel{msg: "invalid cluster", parent: ErrInvalidImage}
+
+// ErrBootChecksum 表示引导区的校验和与内容不符，映像被截断或引导扇区已损坏（打开时返回，见 WithoutBootChecksum）
+var ErrBootChecksum error = &sentinel{msg: "boot region checksum mismatch", parent: ErrInvalidImage}
+
+// ErrChecksumMismatch 表示条目集的校验和与内容不符，条目集可能已损坏（严格模式下列目录时返回）
+// 宽松模式下这样的条目照常返回，通过 AnomalyChecksumMismatch 标记，可以用 Anomalies 或 Check 找出。
+var ErrChecksumMismatch error = &sentinel{msg: "entry set checksum mismatch", parent: ErrInvalidImage}
+
+// sentinel 属于更一般的错误的哨兵错误：错误信息只有 msg，errors.Is 对 parent 同样成立
+type sentinel struct {
+	msg    string
+	parent error
+}
+
+func (e *sentinel) Error() string { return e.msg }
+func (e *sentinel) Unwrap() error { return e.parent }
+
+// ErrNoMatch 表示遍历结束时没有条目满足条件，可以用 errors.Is(err, fs.ErrNotExist) 判断
+var ErrNoMatch = fmt.Errorf("no matching entry: %w", fs.ErrNotExist)
+
+// ErrDirectoryTooLarge 表示目录超过了 WithMaxDirEntries 设置的条目数上限
+var ErrDirectoryTooLarge = errors.New("directory exceeds the entry limit")
+
+// ErrBrokenChain 表示文件的簇链在数据结束之前断开：链上某个簇的 FAT 项是空闲值 0，
+// 例如 FAT 区域被清零（TRIM 或稀疏映像）。连续分配（NoFatChain）的文件不经过 FAT，不受影响。
+var ErrBrokenChain error = &sentinel{msg: "cluster chain is broken", parent: ErrInvalidImage}
+
+// ErrReadOnly 表示底层映像不能写入，可以用 errors.Is(err, fs.ErrPermission) 判断
+var ErrReadOnly = fmt.Errorf("image is not writable: %w", fs.ErrPermission)
+
+// ErrTruncatedImage 表示引导扇区声明的卷大小超过了映像的实际大小，映像很可能被截断
+var ErrTruncatedImage error = &sentinel{msg: "volume extends past the end of the image", parent: ErrInvalidImage}
+
+// ErrCrossLinked 表示文件的簇链与另一个条目共用簇，见 WithCrosslinkDetection 和 CrossLinkError
+var ErrCrossLinked error = &sentinel{msg: "cluster chain is cross-linked", parent: ErrInvalidImage}
+
+// ErrRegionOverlap 表示引导扇区声明的区域（引导区、FAT、簇堆）相互重叠或超出卷的范围
+// 严格模式下打开时返回；宽松模式下读取落在引导区或 FAT 中的簇时返回，而不是返回这些区域中的字节。
+var ErrRegionOverlap error = &sentinel{msg: "volume regions overlap", parent: ErrInvalidImage}
+
+// ErrNoPreview 表示 ExtractPreview 无法从文件中取出预览图（没有嵌入的预览图、格式无法识别、读取失败或超过读取上限）
+var ErrNoPreview = errors.New("no preview available")
+
+// ErrSchema 表示 JSON 文档的 schema 字段不是预期的种类，或者版本比这个包支持的更新（见 CheckSchema）
+var ErrSchema = errors.New("unsupported document schema")
@@ -0,0 +1,120 @@
+package exfat
+
+import "fmt"
+
+// Extent 文件数据在卷上的一段连续簇
+type Extent struct {
+	Offset  int64  // 本段在文件中的字节偏移
+	Cluster uint32 // 起始簇号
+	Count   uint32 // 簇数
+}
+
+// Extents 返回文件或目录的数据在卷上的位置，按文件中的顺序排列，供数据雕刻和底层分析使用
+// 簇链按读取文件时的方式解析（同样的 FAT 选择和簇过滤器），相邻的簇合并为一段：只有一段表示没有碎片，
+// 连续分配（FileEntry.Contiguous）的文件总是一段。没有分配簇的条目返回空切片；簇链在数据结束之前中断时
+// 返回已解析的部分和错误（空闲的 FAT 项为 ErrBrokenChain）。目录的长度按簇链（连续目录按 DataLength）计算。
+func (fs *ExFATFileSystem) Extents(path string) ([]Extent, error) {
+	entry, err := fs.getEntry(normalizePath(path))
+	if err != nil {
+		return nil, err
+	}
+	if !entry.hasClusters() {
+		return []Extent{}, nil
+	}
+	if entry.IsDir {
+		size, err := fs.directoryDataSize(entry)
+		if err != nil {
+			return nil, err
+		}
+		dir := *entry
+		dir.Size = int64(size)
+		entry = &dir
+	}
+
+	f := &File{fs: fs, entry: entry, next: entry.cluster}
+	if f.fat, f.fatNum, err = fs.chainFAT(entry); err != nil {
+		return nil, err
+	}
+	if total := fs.clustersFor(entry.Size); total > 0 {
+		_, err = f.runFor(total - 1)
+	}
+
+	extents := make([]Extent, len(f.runs))
+	for i, run := range f.runs {
+		extents[i] = Extent{Offset: int64(run.index) * int64(fs.bytesPerCluster), Cluster: run.start, Count: run.count}
+	}
+	return extents, err
+}
+
+// ClusterRun 簇链中的一段连续簇及其在卷中的字节范围
+type ClusterRun struct {
+	StartCluster uint32 // 起始簇号
+	Count        uint32 // 簇数
+	Offset       int64  // 起始簇在卷中的字节偏移
+	Length       int64  // 本段中属于数据的字节数，最后一段可能不足整簇
+}
+
+// GetClusterChain 严格按 FAT 解析文件或目录的簇链，返回连续簇的游程及其在卷中的字节范围，
+// 可以直接按范围从映像复制数据而不经过文件系统
+// 与 Extents 不同，这里不做任何容错：只使用活动 FAT，簇链在数据结束之前遇到坏簇或超出簇堆的簇号时
+// 返回 ErrBadCluster，遇到链结束标记时返回簇链过短的错误，遇到空闲的 FAT 项时返回 ErrBrokenChain，
+// 同时返回已解析的部分。连续分配（NoFatChain）的条目只有一段，不查询 FAT。簇过滤器同样生效（见 WithClusterFilter）。
+// Offset 相对于卷的起始位置；原始映像中加上 VolumeIdentity.VolumeOffset 即为映像中的偏移，
+// 动态和差分 VHD 的数据块不连续存放，需要再经过容器层的块映射。
+func (fs *ExFATFileSystem) GetClusterChain(path string) ([]ClusterRun, error) {
+	entry, err := fs.getEntry(normalizePath(path))
+	if err != nil {
+		return nil, err
+	}
+	if !entry.hasClusters() {
+		return []ClusterRun{}, nil
+	}
+	size := uint64(entry.Size)
+	if entry.IsDir {
+		if size, err = fs.directoryDataSize(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	clusterSize := uint64(fs.bytesPerCluster)
+	needed := fs.clustersFor(int64(size))
+	var runs []ClusterRun
+	add := func(cluster uint32, index uint64) {
+		length := int64(min(clusterSize, size-index*clusterSize))
+		if last := len(runs) - 1; last >= 0 && runs[last].StartCluster+runs[last].Count == cluster {
+			runs[last].Count++
+			runs[last].Length += length
+			return
+		}
+		runs = append(runs, ClusterRun{StartCluster: cluster, Count: 1, Offset: int64(fs.clusterToOffset(cluster)), Length: length})
+	}
+
+	if entry.noFatChain {
+		if uint64(entry.cluster)+needed-1 > uint64(fs.totalClusters)+1 {
+			return []ClusterRun{}, fmt.Errorf("%w: %s: %d contiguous clusters from %d run past the cluster heap", ErrBadCluster, entry.path, needed, entry.cluster)
+		}
+		n := fs.contiguousRun(entry.cluster, needed)
+		for i := uint64(0); i < n; i++ {
+			add(entry.cluster+uint32(i), i)
+		}
+		if n < needed {
+			return runs, fmt.Errorf("contiguous clusters of %s end at a filtered cluster after %d of %d clusters", entry.path, n, needed)
+		}
+		return runs, nil
+	}
+
+	cluster := fs.filter(entry.cluster)
+	for i := uint64(0); i < needed; i++ {
+		switch {
+		case fs.endOfChain(cluster):
+			return runs, fmt.Errorf("cluster chain of %s ends after %d of %d clusters", entry.path, i, needed)
+		case cluster == 0:
+			return runs, fmt.Errorf("%w: %s: FAT entry is free after %d of %d clusters", ErrBrokenChain, entry.path, i, needed)
+		case !fs.validCluster(cluster) || int(cluster) >= len(fs.fat):
+			return runs, fmt.Errorf("%w: %s: cluster 0x%X after %d of %d clusters", ErrBadCluster, entry.path, cluster, i, needed)
+		}
+		add(cluster, i)
+		cluster = fs.link(fs.fat, cluster)
+	}
+	return runs, nil
+}
@@ -0,0 +1,93 @@
+package exfat
+
+import "fmt"
+
+// volumeFlagActiveFat VolumeFlags 中的 ActiveFat 位：为 1 时第二个 FAT 是活动 FAT
+const volumeFlagActiveFat = 0x0001
+
+// activeFAT 返回活动 FAT 的序号；只有一个 FAT 时忽略 ActiveFat 位
+func activeFAT(bs *ExFATBootSector) int {
+	if bs.NumberOfFats == 2 && bs.VolumeFlags&volumeFlagActiveFat != 0 {
+		return 1
+	}
+	return 0
+}
+
+// inactiveFAT 返回非活动 FAT，卷只有一个 FAT 或读取失败时返回 nil
+// TexFAT 卷和修复过的卷上两个 FAT 可能不一致，用于文件簇链在活动 FAT 中无效时的回退。
+func (fs *ExFATFileSystem) inactiveFAT() []uint32 {
+	if fs.bootSector.NumberOfFats < 2 {
+		return nil
+	}
+	fs.altFATOnce.Do(func() {
+		fat, err := fs.readFATCopy(1 - fs.activeFAT)
+		if err != nil {
+			fs.diagnose("", "cannot read FAT %d: %v", 2-fs.activeFAT, err)
+			return
+		}
+		fs.altFAT = fat
+	})
+	return fs.altFAT
+}
+
+// chainFAT 选择读取文件簇链使用的 FAT，返回 FAT 表和它的编号（1 或 2）
+// 簇链在活动 FAT 中无效（越界、空闲项、循环或在数据结束之前结束）而在另一个 FAT 中有效时改用另一个 FAT，
+// 并记录诊断；两个都无效时仍按活动 FAT 宽松读取。严格模式下不回退，直接返回 ErrBrokenChain。
+func (fs *ExFATFileSystem) chainFAT(entry *DirEntry) ([]uint32, int, error) {
+	active := fs.activeFAT + 1
+	if entry.noFatChain || entry.Size <= 0 || !fs.validCluster(entry.cluster) {
+		return fs.fat, active, nil
+	}
+
+	needed := fs.clustersFor(entry.Size)
+	err := fs.validateChain(fs.fat, entry.cluster, needed)
+	if err == nil {
+		return fs.fat, active, nil
+	}
+	if fs.opts.strict {
+		return nil, 0, fmt.Errorf("%w: %s: %v", ErrBrokenChain, entry.path, err)
+	}
+	if alt := fs.inactiveFAT(); alt != nil && fs.validateChain(alt, entry.cluster, needed) == nil {
+		fs.diagnose(entry.path, "cluster chain is invalid in FAT %d (%v); read using FAT %d", active, err, 3-active)
+		return alt, 3 - active, nil
+	}
+	return fs.fat, active, nil
+}
+
+// validateChain 检查 fat 中从 start 开始的簇链是否能提供 needed 个簇
+// 簇链超出 needed 个簇的部分不检查；只有一个簇的文件不经过 FAT，不检查。
+// 访问过的簇记录在随链增长的集合中，开销与 needed 而不是 FAT 的大小成正比。
+func (fs *ExFATFileSystem) validateChain(fat []uint32, start uint32, needed uint64) error {
+	if needed <= 1 {
+		return nil
+	}
+	seen := make(map[uint32]bool)
+	cluster := start
+	for i := uint64(1); i < needed; i++ {
+		if int(cluster) >= len(fat) {
+			return fmt.Errorf("cluster %d is beyond the FAT", cluster)
+		}
+		seen[cluster] = true
+
+		next := fs.link(fat, cluster)
+		switch {
+		case fs.endOfChain(next):
+			return fmt.Errorf("chain ends after %d of %d clusters", i, needed)
+		case next == 0:
+			return fmt.Errorf("FAT entry of cluster %d is free", cluster)
+		case next == BadCluster:
+			return fmt.Errorf("chain reaches a bad cluster after cluster %d", cluster)
+		case !fs.validCluster(next):
+			return fmt.Errorf("FAT entry of cluster %d is invalid (0x%08X)", cluster, next)
+		case seen[next]:
+			return fmt.Errorf("chain loops back to cluster %d", next)
+		}
+		cluster = next
+	}
+	return nil
+}
+
+// ActiveFAT 返回活动 FAT 的编号（1 或 2），由引导扇区 VolumeFlags 的 ActiveFat 位决定
+func (fs *ExFATFileSystem) ActiveFAT() int {
+	return fs.activeFAT + 1
+}
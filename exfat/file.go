@@ -0,0 +1,292 @@
+package exfat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"sync"
+)
+
+// File 以流的方式读取 exFAT 中的文件，实现 io.Reader、io.Seeker、io.ReaderAt 和 io.Closer
+// 簇链在读取时按需解析，并以连续簇的游程缓存：顺序读取加偶尔的回退查找（如 http.ServeContent
+// 的范围请求）只需遍历一次 FAT，缓存大小与碎片数量而不是文件大小成正比。
+// Read 和 Seek 共享当前位置，不能并发调用；ReadAt 可以并发调用。
+type File struct {
+	fs     *ExFATFileSystem
+	entry  *DirEntry
+	pos    int64
+	closed bool
+	fat    []uint32 // 读取簇链使用的 FAT
+	fatNum int      // fat 的编号（1 或 2）
+
+	mu       sync.Mutex
+	runs     []clusterRun // 已解析的簇链
+	resolved uint64       // runs 覆盖的簇数
+	next     uint32       // 下一个待解析的簇，0 表示簇链已结束
+	broken   bool         // 簇链因空闲的 FAT 项而结束（见 ErrBrokenChain）
+}
+
+// clusterRun 簇链中的一段连续簇
+type clusterRun struct {
+	index uint64 // 本段第一个簇在文件中的序号
+	start uint32 // 起始簇号
+	count uint32 // 簇数
+}
+
+// Open 打开文件用于流式读取
+func (fs *ExFATFileSystem) Open(path string) (*File, error) {
+	entry, err := fs.getEntry(normalizePath(path))
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir {
+		return nil, fmt.Errorf("%w: %s", ErrIsDirectory, path)
+	}
+
+	f := &File{fs: fs, entry: entry}
+	if entry.Size > 0 {
+		if !fs.validCluster(entry.cluster) {
+			return nil, fmt.Errorf("%w: start cluster %d", ErrBadCluster, entry.cluster)
+		}
+		f.next = entry.cluster
+	}
+	if f.fat, f.fatNum, err = fs.chainFAT(entry); err != nil {
+		return nil, err
+	}
+	if err := fs.claimChain(f.fat, entry); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// FAT 返回读取文件簇链使用的 FAT 的编号（1 或 2）
+// 簇链在活动 FAT 中无效时，宽松模式下可能改用另一个 FAT（见 WithStrict）。
+func (f *File) FAT() int {
+	return f.fatNum
+}
+
+// WriteTo 把文件的全部内容写入 w，返回写入的字节数
+// 数据经由 File.WriteTo 按连续簇的游程读取，不会整个读入内存，适合在服务器中直接向连接输出大文件。
+func (fs *ExFATFileSystem) WriteTo(path string, w io.Writer) (int64, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return f.WriteTo(w)
+}
+
+// ReadFileRange 读取文件中从 offset 开始的 length 字节
+// 簇链只解析到读取范围的最后一个簇，offset 之前的簇不读取。与 io.ReaderAt 相同，范围超出文件末尾时
+// 返回读到的部分和 io.EOF；offset 不小于文件大小时返回空数据和 io.EOF。
+func (fs *ExFATFileSystem) ReadFileRange(path string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("exfat: negative range (offset %d, length %d)", offset, length)
+	}
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// 按文件大小截断后再分配，过大的 length 不会分配多余的内存
+	if offset >= f.entry.Size {
+		return []byte{}, io.EOF
+	}
+	data := make([]byte, min(length, f.entry.Size-offset))
+	n, err := f.ReadAt(data, offset)
+	if err == nil && int64(n) < length {
+		err = io.EOF
+	}
+	return data[:n], err
+}
+
+// Stat 返回文件的条目信息
+func (f *File) Stat() FileEntry {
+	return f.entry.fileEntry()
+}
+
+// Read 从当前位置读取
+func (f *File) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.pos)
+	f.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek 设置下一次 Read 的位置，允许超过文件末尾（之后的 Read 返回 io.EOF）
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += f.entry.Size
+	default:
+		return 0, errors.New("exfat: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("exfat: negative position")
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+// ReadAt 从指定偏移读取，读到文件末尾时返回 io.EOF
+// 有效数据长度（ValidDataLength）之后的部分读取为零，与 ReadFile 相同。
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if off < 0 {
+		return 0, errors.New("exfat: negative offset")
+	}
+	if off >= f.entry.Size {
+		return 0, io.EOF
+	}
+
+	size := f.entry.Size
+	want := p
+	if int64(len(want)) > size-off {
+		want = want[:size-off]
+	}
+	// 有效数据长度之后的部分未写入过，不读取簇，直接填零
+	stored := want[:min(int64(len(want)), max(f.entry.validSize-off, 0))]
+	clear(want[len(stored):])
+
+	clusterSize := int64(f.fs.bytesPerCluster)
+	n := 0
+	for n < len(stored) {
+		pos := off + int64(n)
+		run, err := f.runFor(uint64(pos / clusterSize))
+		if err != nil {
+			return n, err
+		}
+
+		// 同一段连续簇可以一次读完，每次最多读取 maxReadBytes 字节
+		runOffset := pos - int64(run.index)*clusterSize
+		chunk := min(int64(len(stored)-n), int64(run.count)*clusterSize-runOffset, int64(f.fs.maxReadBytes()))
+		if bad, err := f.fs.readRun(stored[n:n+int(chunk)], run.start, runOffset, TraceData); err != nil && err != io.EOF {
+			// 坏簇之前的簇已经逐簇读出
+			n += int(max(int64(bad-run.start)*clusterSize-runOffset, 0))
+			return n, fmt.Errorf("failed to read cluster %d: %w", bad, err)
+		}
+		n += int(chunk)
+	}
+	n = len(want)
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteTo 把从当前位置到文件末尾的数据写入 w，实现 io.WriterTo
+// io.Copy 会直接使用它；中转使用文件系统的缓冲区池，不为每次调用分配缓冲区。
+// 缓冲区最大为一次合并读取的大小（见 WithMaxReadSize），小文件只使用与文件大小相当的缓冲区。
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	size := min(int64(f.fs.maxReadBytes()), max(f.entry.Size-f.pos, int64(f.fs.bytesPerCluster)))
+	buf := f.fs.getBuffer(int(size))
+	defer f.fs.putBuffer(buf)
+
+	var written int64
+	for {
+		n, err := f.Read(*buf)
+		if n > 0 {
+			m, werr := w.Write((*buf)[:n])
+			written += int64(m)
+			if werr != nil {
+				return written, werr
+			}
+			if m != n {
+				return written, io.ErrShortWrite
+			}
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// Close 关闭文件
+func (f *File) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+// runFor 返回包含文件中第 index 个簇的游程，必要时继续沿 FAT 解析
+func (f *File) runFor(index uint64) (clusterRun, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for f.resolved <= index {
+		if !f.extend() {
+			if f.broken {
+				return clusterRun{}, fmt.Errorf("%w: %s: FAT entry is free after %d clusters", ErrBrokenChain, f.entry.path, f.resolved)
+			}
+			return clusterRun{}, fmt.Errorf("cluster chain of %s ends after %d clusters", f.entry.path, f.resolved)
+		}
+	}
+	// 向前多解析紧接着的连续簇，使一次读取可以覆盖整段（最多 maxReadClusters 个簇）
+	for limit := index + f.fs.maxReadClusters(); f.resolved < limit && f.resolved < f.fs.clustersFor(f.entry.Size); {
+		last := f.runs[len(f.runs)-1]
+		if f.next != last.start+last.count || !f.extend() {
+			break
+		}
+	}
+
+	i := sort.Search(len(f.runs), func(i int) bool {
+		return f.runs[i].index+uint64(f.runs[i].count) > index
+	})
+	run := f.runs[i]
+	skip := uint32(index - run.index)
+	return clusterRun{index: index, start: run.start + skip, count: run.count - skip}, nil
+}
+
+// extend 解析下一个簇并并入缓存，簇链结束时返回 false
+// 簇的遍历方式与 readClusterChain 相同，保证两者读到的数据一致
+func (f *File) extend() bool {
+	cluster := f.fs.filter(f.next)
+	if !f.fs.validCluster(cluster) {
+		return false
+	}
+
+	if f.entry.noFatChain {
+		// 连续文件只有一段，在第一个被簇过滤器排除的簇之前结束
+		total := f.fs.contiguousRun(cluster, f.fs.clustersFor(f.entry.Size))
+		f.runs = append(f.runs, clusterRun{index: 0, start: cluster, count: uint32(total)})
+		f.resolved = total
+		f.next = 0
+		return true
+	}
+
+	if last := len(f.runs) - 1; last >= 0 && f.runs[last].start+f.runs[last].count == cluster {
+		f.runs[last].count++
+	} else {
+		f.runs = append(f.runs, clusterRun{index: f.resolved, start: cluster, count: 1})
+	}
+	f.resolved++
+
+	next := f.fs.nextIn(f.fat, cluster)
+	if freeLink(f.fat, cluster) {
+		next, f.broken = 0, true
+	}
+	if !f.fs.validCluster(next) {
+		next = 0
+	}
+	f.next = next
+	return true
+}
@@ -0,0 +1,729 @@
+package exfat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// NewExFATFileSystem 创建新的 exFAT 文件系统实例
+func NewExFATFileSystem(vhd io.ReaderAt, opts ...Option) (*ExFATFileSystem, error) {
+	// 读取引导扇区
+	o := applyOptions(opts)
+	bootSector, usedBackup, ignored, err := readBootSector(vhd, o)
+	if err != nil {
+		return nil, err
+	}
+
+	// 计算参数
+	bytesPerSector := uint32(1) << bootSector.BytesPerSectorShift
+	sectorsPerCluster := uint32(1) << bootSector.SectorsPerClusterShift
+	bytesPerCluster := bytesPerSector * sectorsPerCluster
+
+	fs := &ExFATFileSystem{volume: &volume{
+		vhd:               vhd,
+		bootSector:        bootSector,
+		bytesPerSector:    bytesPerSector,
+		sectorsPerCluster: sectorsPerCluster,
+		bytesPerCluster:   bytesPerCluster,
+		clusterHeapStart:  uint64(bootSector.ClusterHeapOffset) * uint64(bytesPerSector),
+		totalClusters:     bootSector.ClusterCount,
+		activeFAT:         activeFAT(bootSector),
+		usedBackupBoot:    usedBackup,
+		opts:              o,
+	}}
+	fs.buffers.New = func() interface{} {
+		buf := make([]byte, bytesPerCluster)
+		return &buf
+	}
+	if ignored != nil {
+		fs.diagnose("", "%v; ignored", ignored)
+	}
+	if err := fs.checkVolumeSize(); err != nil {
+		return nil, err
+	}
+	if err := fs.checkRegions(); err != nil {
+		return nil, err
+	}
+	if err := fs.checkFATLength(); err != nil {
+		return nil, err
+	}
+
+	// 读取 FAT 表；有可用的元数据缓存时直接使用缓存
+	if o.cache == nil || fs.LoadMetadataCache(o.cache) != nil {
+		if err := fs.readFAT(); err != nil {
+			return nil, err
+		}
+	}
+	fs.checkEndOfChainMarkers()
+
+	return fs, nil
+}
+
+// UsedBackupBootSector 返回打开时是否使用了备份引导区（见 WithBackupBootRecovery）
+func (fs *ExFATFileSystem) UsedBackupBootSector() bool {
+	return fs.usedBackupBoot
+}
+
+// VolumeSize 返回引导扇区声明的卷大小（VolumeLength 乘以每扇区字节数）
+func (fs *ExFATFileSystem) VolumeSize() uint64 {
+	return fs.bootSector.VolumeLength * uint64(fs.bytesPerSector)
+}
+
+// checkVolumeSize 在映像大小已知时检查声明的卷大小是否超出映像
+// 超出说明映像被截断，卷末尾的簇读不到；严格模式下返回 ErrTruncatedImage，否则记录诊断。
+func (fs *ExFATFileSystem) checkVolumeSize() error {
+	size, ok := readerSize(fs.vhd)
+	if !ok || size <= 0 || fs.VolumeSize() <= uint64(size) {
+		return nil
+	}
+	if fs.opts.strict {
+		return fmt.Errorf("%w: volume is %d bytes, image is %d bytes", ErrTruncatedImage, fs.VolumeSize(), size)
+	}
+	fs.diagnose("", "volume is %d bytes but the image is only %d bytes; the image is probably truncated", fs.VolumeSize(), size)
+	return nil
+}
+
+// readerSize 返回 r 的大小：r 有 Size 方法（容器后端、bytes.Reader、io.SectionReader）或是 *os.File 时可知
+func readerSize(r io.ReaderAt) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return r.Size(), true
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil {
+			return 0, false
+		}
+		return info.Size(), true
+	}
+	return 0, false
+}
+
+// readFAT 读取活动 FAT
+func (fs *ExFATFileSystem) readFAT() error {
+	fat, err := fs.readFATCopy(fs.activeFAT)
+	if err != nil {
+		return err
+	}
+	fs.fat = fat
+	return nil
+}
+
+// fatEntries 返回 FAT 中有意义的项数：两个保留项加每个簇一项
+// FAT 区域可能比这大得多（有的格式化工具有意多分配，损坏的 FatLength 更甚），之后的项从不使用。
+func (fs *ExFATFileSystem) fatEntries() uint64 {
+	return uint64(fs.totalClusters) + 2
+}
+
+// checkFATLength 检查 FatLength 是否足以容纳 ClusterCount 个簇的 FAT 项
+// 不足时簇链无从解析，无论是否严格模式都返回错误。
+func (fs *ExFATFileSystem) checkFATLength() error {
+	if capacity := uint64(fs.bootSector.FatLength) * uint64(fs.bytesPerSector) / 4; capacity < fs.fatEntries() {
+		return fmt.Errorf("%w: FAT length of %d sectors holds %d entries, too few for %d clusters", ErrInvalidImage, fs.bootSector.FatLength, capacity, fs.totalClusters)
+	}
+	return nil
+}
+
+// readFATCopy 读取第 index 个 FAT（从 0 开始）
+// 只读取和解析 fatEntries 项，内存占用与簇数而不是 FatLength 成正比。
+func (fs *ExFATFileSystem) readFATCopy(index int) ([]uint32, error) {
+	fatSize := fs.fatEntries() * 4
+	fatData := make([]byte, fatSize)
+
+	fatOffset := (uint64(fs.bootSector.FatOffset) + uint64(index)*uint64(fs.bootSector.FatLength)) * uint64(fs.bytesPerSector)
+	_, err := fs.readAt(fatData, int64(fatOffset), TraceFAT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FAT table: %v", err)
+	}
+
+	// 解析 FAT 表（每个条目 4 字节）
+	entryCount := fatSize / 4
+	fat := make([]uint32, entryCount)
+	for i := uint64(0); i < entryCount; i++ {
+		fat[i] = binary.LittleEndian.Uint32(fatData[i*4 : (i+1)*4])
+	}
+
+	return fat, nil
+}
+
+// clusterToOffset 将簇号转换为文件偏移
+func (fs *ExFATFileSystem) clusterToOffset(cluster uint32) uint64 {
+	if cluster < 2 {
+		return 0
+	}
+	return fs.clusterHeapStart + uint64(cluster-2)*uint64(fs.bytesPerCluster)
+}
+
+// validCluster 簇号是否指向簇堆中的数据簇
+// 按规范有效的数据簇为 2 到 ClusterCount+1，0xFFFFFFF7 及以上是坏簇和链结束等特殊值，
+// 最大的卷（2^32-11 个簇）上所有数据簇号都能通过检查。
+func (fs *ExFATFileSystem) validCluster(cluster uint32) bool {
+	return cluster >= 2 && uint64(cluster) <= uint64(fs.totalClusters)+1
+}
+
+// readClusterChain 读取簇链的数据，purpose 为读取的用途（Trace* 常量，见 WithReadTracer）
+// noFatChain 为 true 时簇是连续分配的，不查询 FAT
+func (fs *ExFATFileSystem) readClusterChain(startCluster uint32, size uint64, noFatChain bool, purpose string) ([]byte, error) {
+	if size == 0 {
+		return []byte{}, nil
+	}
+
+	data := make([]byte, size)
+	if err := fs.readClusterChainInto(data, startCluster, noFatChain, purpose); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readClusterChainInto 沿活动 FAT 中的簇链读取数据填满 data
+func (fs *ExFATFileSystem) readClusterChainInto(data []byte, startCluster uint32, noFatChain bool, purpose string) error {
+	return fs.readChainInto(fs.fat, data, startCluster, noFatChain, purpose)
+}
+
+// readChainInto 沿 fat 中的簇链读取数据填满 data
+func (fs *ExFATFileSystem) readChainInto(fat []uint32, data []byte, startCluster uint32, noFatChain bool, purpose string) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	// 检查起始簇号是否有效
+	if !fs.validCluster(startCluster) {
+		return fmt.Errorf("%w: start cluster %d", ErrBadCluster, startCluster)
+	}
+
+	// 连续的簇合并为一次读取，最多 maxReadClusters 个簇；碎片边界处分开读取
+	size := uint64(len(data))
+	clusterSize := uint64(fs.bytesPerCluster)
+	limit := fs.maxReadClusters()
+	offset := uint64(0)
+	cluster := fs.filter(startCluster)
+
+	for fs.validCluster(cluster) && offset < size {
+		start := cluster
+		count := uint64(1)
+		for {
+			if noFatChain {
+				cluster = fs.filter(cluster + 1)
+			} else {
+				cluster = fs.nextIn(fat, cluster)
+			}
+			if cluster != start+uint32(count) || count >= limit || offset+count*clusterSize >= size || !fs.validCluster(cluster) {
+				break
+			}
+			count++
+		}
+
+		readSize := min(count*clusterSize, size-offset)
+		if bad, err := fs.readRun(data[offset:offset+readSize], start, 0, purpose); err != nil {
+			return fmt.Errorf("failed to read cluster %d: %w", bad, err)
+		}
+		offset += readSize
+
+		// 检查新簇号是否仍然有效
+		if !fs.validCluster(cluster) {
+			break
+		}
+	}
+
+	// 簇链提前结束时其余部分为零（data 可能来自缓冲区池，不能假设已清零）
+	clear(data[offset:])
+	return nil
+}
+
+// nextValidCluster 在活动 FAT 中获取下一个有效簇号
+func (fs *ExFATFileSystem) nextValidCluster(cluster uint32) uint32 {
+	return fs.nextIn(fs.fat, cluster)
+}
+
+// nextIn 在 fat 中获取下一个有效簇号，FAT 项无效时按相邻簇猜测
+// 猜测的簇同样经过簇过滤器；FAT 项指向被过滤的簇时不再猜测。
+func (fs *ExFATFileSystem) nextIn(fat []uint32, cluster uint32) uint32 {
+	if cluster >= uint32(len(fat)) {
+		// FAT 只保留簇堆的项，遍历不应查询之外的簇；出现时说明调用者漏掉了簇号检查
+		fs.diagnose("", "FAT lookup for cluster %d beyond the %d entries of the FAT", cluster, len(fat))
+		return fs.filter(cluster + 1)
+	}
+	next := fat[cluster]
+	if !fs.validCluster(next) {
+		return fs.filter(cluster + 1)
+	}
+	return fs.filter(next)
+}
+
+// ListDir 列出目录内容
+func (fs *ExFATFileSystem) ListDir(path string) ([]FileEntry, error) {
+	path = normalizePath(path)
+
+	dir := fs.rootEntry()
+	if path != "/" && path != "" {
+		// 查找目录
+		entry, err := fs.getEntry(path)
+		if err != nil {
+			return nil, err
+		}
+		if !entry.IsDir {
+			return nil, fmt.Errorf("%w: %s", ErrNotDirectory, path)
+		}
+		dir = entry
+	}
+
+	return fs.readDirectory(dir)
+}
+
+// DirEntry 内部目录条目结构
+type DirEntry struct {
+	Name       string
+	Size       int64
+	IsDir      bool
+	ModTime    time.Time
+	CreateTime time.Time
+	AccessTime time.Time
+	Attributes Attributes
+	cluster    uint32    // 起始簇，0 表示没有分配簇（见 hasClusters）
+	validSize  int64     // 有效数据长度（ValidDataLength，不超过 Size），之后的部分读取为零
+	noFatChain bool      // 簇连续分配（NoFatChain 标志）
+	path       string    // 完整路径
+	anomalies  []Anomaly // 解析条目集时发现的元数据异常
+	streams    int       // 条目集中流扩展条目的个数
+	id         entryID   // 条目集的位置
+}
+
+// hasClusters 返回条目是否分配了簇
+// 起始簇为 0 是“没有分配簇”的唯一表示：空文件、空目录，以及解析时起始簇无效的目录。
+// 这样的文件读取为空数据，目录没有子条目；两者都不参与簇链、分配位图和交叉链接的检查，
+// 遍历目录树时也不记入已访问的簇（见 firstVisit）。
+func (e *DirEntry) hasClusters() bool {
+	return e.cluster != 0
+}
+
+// firstVisit 记录目录的起始簇，返回是否第一次访问；损坏的映像中指回上层的目录再次出现时返回 false
+// 没有簇的目录没有子条目，不会形成循环，总是返回 true。
+func firstVisit(visited map[uint32]bool, dir *DirEntry) bool {
+	if !dir.hasClusters() {
+		return true
+	}
+	if visited[dir.cluster] {
+		return false
+	}
+	visited[dir.cluster] = true
+	return true
+}
+
+// fileEntry 转换为公开的 FileEntry
+// 目录的 DataLength 只是目录簇占用的空间，公开的大小与文档一致为 0，与目录是否分配了簇无关。
+func (e *DirEntry) fileEntry() FileEntry {
+	size, validSize := e.Size, e.validSize
+	if e.IsDir {
+		size, validSize = 0, 0
+	}
+	return FileEntry{
+		Name:         e.Name,
+		Size:         size,
+		ValidSize:    validSize,
+		IsDir:        e.IsDir,
+		ModTime:      e.ModTime,
+		CreateTime:   e.CreateTime,
+		AccessTime:   e.AccessTime,
+		Attributes:   e.Attributes,
+		Contiguous:   e.noFatChain,
+		FirstCluster: e.cluster,
+		FileID:       e.fileID(),
+		Streams:      e.streams,
+		id:           e.id,
+	}
+}
+
+// rootEntry 返回路径查找的起点：视图的根目录，或卷的根目录
+func (fs *ExFATFileSystem) rootEntry() *DirEntry {
+	if fs.root != nil {
+		return fs.root
+	}
+	return fs.volumeRoot()
+}
+
+// volumeRoot 返回卷的根目录的内部条目，分配位图等关键条目总是从这里读取
+// 根目录没有流扩展条目，总是通过 FAT 链读取
+func (fs *ExFATFileSystem) volumeRoot() *DirEntry {
+	return &DirEntry{
+		Name:       "/",
+		IsDir:      true,
+		Attributes: AttrDirectory,
+		cluster:    fs.bootSector.FirstClusterOfRootDir,
+		path:       "/",
+		id:         entryID{serial: fs.bootSector.VolumeSerialNumber},
+	}
+}
+
+// readDirectoryData 读取目录的原始数据，数据放在池中的缓冲区里
+// 调用者用完后必须调用 putBuffer，且不能让返回的切片（或其子切片）逃逸
+func (fs *ExFATFileSystem) readDirectoryData(dir *DirEntry) (*[]byte, error) {
+	size, err := fs.directoryDataSize(dir)
+	if err != nil {
+		return nil, err
+	}
+	buf := fs.getBuffer(int(size))
+	if err := fs.readClusterChainInto(*buf, dir.cluster, dir.noFatChain, TraceDir); err != nil {
+		fs.putBuffer(buf)
+		return nil, err
+	}
+	return buf, nil
+}
+
+// directoryDataSize 返回读取目录时使用的数据长度，超过条目数上限时返回 ErrDirectoryTooLarge
+// 连续目录使用流扩展条目中的 DataLength；其他目录沿 FAT 中的簇链走到链结束，读取链上的全部簇。
+// 簇链断裂（如 FAT 被清零）时 readClusterChainInto 在断点之后按连续簇读取，这时至少读取 brokenDirClusters 个簇；
+// 簇链有循环时只读取第一次回到已经走过的簇之前的部分。
+// 走过的簇数不超过条目数上限对应的簇数，损坏的 FAT 不会使读取无限进行下去。
+func (fs *ExFATFileSystem) directoryDataSize(dir *DirEntry) (uint64, error) {
+	limit := uint64(fs.opts.maxDirEntries) * 32
+	if dir.noFatChain {
+		// 连续目录的大小由流扩展条目中的 DataLength 给出
+		size := uint64(dir.Size)
+		if size > limit {
+			return 0, fmt.Errorf("%w: %s declares %d entries, limit is %d", ErrDirectoryTooLarge, dir.path, size/32, fs.opts.maxDirEntries)
+		}
+		return size, nil
+	}
+
+	// 以 Brent 算法检测循环，不需要记录走过的簇：tortoise 每走过 2 的幂个簇移到当前位置
+	clusterSize := uint64(fs.bytesPerCluster)
+	size := clusterSize
+	tortoise, power, steps := dir.cluster, 1, 0
+	for cluster := dir.cluster; int(cluster) < len(fs.fat); {
+		next := fs.link(fs.fat, cluster)
+		if fs.endOfChain(next) {
+			break
+		}
+		if !fs.validCluster(next) {
+			return max(size, min(brokenDirClusters*clusterSize, limit)), nil
+		}
+		if next == tortoise {
+			n := fs.loopFreeLength(dir.cluster)
+			fs.diagnose(dir.path, "directory cluster chain loops after %d clusters; read up to the first repeated cluster", n)
+			return min(n*clusterSize, limit), nil
+		}
+		if size += clusterSize; size > limit {
+			return 0, fmt.Errorf("%w: %s has more than %d entries in its cluster chain, limit is %d", ErrDirectoryTooLarge, dir.path, limit/32, fs.opts.maxDirEntries)
+		}
+		cluster = next
+		if steps++; steps == power {
+			tortoise, power, steps = cluster, power*2, 0
+		}
+	}
+	return size, nil
+}
+
+// brokenDirClusters 簇链断裂的目录至少读取的簇数
+// 断点之后的簇无从得知，按连续分配估计；目录结束标记之后的数据不会被解析。
+const brokenDirClusters = 16
+
+// loopFreeLength 返回从 start 开始、第一次回到已经走过的簇之前的簇数
+func (fs *ExFATFileSystem) loopFreeLength(start uint32) uint64 {
+	seen := map[uint32]bool{start: true}
+	for cluster := start; int(cluster) < len(fs.fat); {
+		next := fs.link(fs.fat, cluster)
+		if fs.endOfChain(next) || !fs.validCluster(next) || seen[next] {
+			break
+		}
+		seen[next] = true
+		cluster = next
+	}
+	return uint64(len(seen))
+}
+
+// getEntry 查找文件或目录条目
+func (fs *ExFATFileSystem) getEntry(path string) (*DirEntry, error) {
+	result, err := fs.resolvePath(fs.rootEntry(), splitPath(path), resolveOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, path)
+	}
+	return result.entry, nil
+}
+
+// readDirectoryEntries 读取目录内容并返回内部目录条目
+func (fs *ExFATFileSystem) readDirectoryEntries(dir *DirEntry) ([]*DirEntry, error) {
+	// 没有簇的目录（见 hasClusters）和起始簇无效的根目录视为空目录
+	cluster := dir.cluster
+	if !fs.validCluster(cluster) {
+		return []*DirEntry{}, nil
+	}
+
+	// 读取目录数据；条目只引用从中复制出的值，函数返回后缓冲区交还给池
+	buf, err := fs.readDirectoryData(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.putBuffer(buf)
+	dirData := *buf
+
+	// 大写表用于校验名称哈希，读取失败时退回到简单的大写规则
+	upcase, _ := fs.upcaseTable()
+
+	var entries []*DirEntry
+	var setStarts []int // 每个条目所在条目集的起始偏移
+	lastGoodEnd := 0    // 最后一个有效条目（集）之后的偏移
+
+	// 按条目集的状态机划分槽位：未使用的槽位被跳过，主条目总是开始新的条目集，不会被前一个条目集吞掉
+	sets, terminated := scanEntrySets(dirData)
+	for _, set := range sets {
+		setStart := set.start
+		entryType := dirData[setStart]
+
+		// 跳过非文件条目
+		if entryType != EntryTypeFile {
+			switch {
+			case entryType == EntryTypeAllocationBitmap || entryType == EntryTypeUpcaseTable || entryType == EntryTypeVolumeLabel:
+				// 位图、大写表、卷标位于根目录开头，没有校验和
+				if len(entries) == 0 && lastGoodEnd == setStart {
+					lastGoodEnd = set.end
+				}
+			case entryType >= 0xA0 && entryType <= 0xBF:
+				// 良性主条目（卷 GUID、TexFAT 填充等）同样带有条目集校验和
+				if !set.truncated && entrySetChecksum(set.slots(dirData)) == binary.LittleEndian.Uint16(dirData[setStart+2:]) {
+					lastGoodEnd = set.end
+				}
+			}
+			continue
+		}
+
+		// 校验整个条目集；宽松模式下校验和不匹配的条目照常返回，并带有 AnomalyChecksumMismatch
+		stored, computed := binary.LittleEndian.Uint16(dirData[setStart+2:]), entrySetChecksum(set.slots(dirData))
+		if !set.truncated && computed == stored {
+			lastGoodEnd = set.end
+		}
+		if !set.truncated && computed != stored && fs.opts.strict {
+			return nil, fmt.Errorf("entry set at offset %d in %s: %w: stored 0x%04X, computed 0x%04X", setStart, dir.path, ErrChecksumMismatch, stored, computed)
+		}
+
+		if set.truncated {
+			// 次条目不足时只使用实际存在的部分，打断条目集的主条目另行解析
+			present := (set.end - setStart - 32) / 32
+			if fs.opts.strict {
+				return nil, fmt.Errorf("entry set at offset %d in %s: %d of %d secondary entries before the next primary entry, unused slot or end of directory", setStart, dir.path, present, set.declared)
+			}
+			fs.diagnose(dir.path, "entry set at offset %d: only %d of %d secondary entries present; set interrupted", setStart, present, set.declared)
+		}
+
+		// 流扩展条目是关键次条目，必须紧接在文件条目之后；否则条目集无效，跳过整个条目集
+		if set.end < setStart+64 || dirData[setStart+32] != EntryTypeFileInfo {
+			found := "none"
+			if set.declared > 0 && setStart+64 <= len(dirData) {
+				found = fmt.Sprintf("type 0x%02X", dirData[setStart+32])
+			}
+			if fs.opts.strict {
+				return nil, fmt.Errorf("entry set at offset %d in %s: first secondary entry is not a stream extension (%s)", setStart, dir.path, found)
+			}
+			fs.diagnose(dir.path, "entry set at offset %d: first secondary entry is not a stream extension (%s); set skipped", setStart, found)
+			continue
+		}
+
+		// 每个条目集只能有一个流扩展条目，多余的不参与解析
+		streams := 1
+		for off := setStart + 64; off < set.end; off += 32 {
+			if dirData[off] == EntryTypeFileInfo {
+				streams++
+			}
+		}
+		if streams > 1 {
+			if fs.opts.strict {
+				return nil, fmt.Errorf("entry set at offset %d in %s: %d stream extension entries", setStart, dir.path, streams)
+			}
+			fs.diagnose(dir.path, "entry set at offset %d: %d stream extension entries; using the first", setStart, streams)
+		}
+
+		// 解析文件条目
+		fileEntry := &ExFATFileEntry{}
+		if err := binary.Read(bytes.NewReader(dirData[setStart:setStart+32]), binary.LittleEndian, fileEntry); err != nil {
+			continue
+		}
+
+		// 读取文件信息条目
+		fileInfoEntry := &ExFATFileInfoEntry{}
+		if err := binary.Read(bytes.NewReader(dirData[setStart+32:setStart+64]), binary.LittleEndian, fileInfoEntry); err != nil {
+			continue
+		}
+
+		// 读取文件名
+		nameLength := int(fileInfoEntry.NameLength)
+		if limit := nameLengthLimit(fileEntry.SecondaryCount); nameLength > limit {
+			// NameLength 超出文件名条目能提供的字符数，按实际的文件名条目截断
+			if fs.opts.strict {
+				return nil, fmt.Errorf("entry set at offset %d in %s: name length %d exceeds the %d characters of its name entries", setStart, dir.path, nameLength, limit)
+			}
+			fs.diagnose(dir.path, "entry set at offset %d: name length %d exceeds the %d characters of its name entries; name truncated", setStart, nameLength, limit)
+			nameLength = limit
+		}
+		// 先取出整个条目集中的文件名码元再一次解码：代理对可能跨越两个文件名条目，逐条目解码会得到替换字符。
+		// 只使用实际属于该条目集的次条目，被打断的条目集的文件名可能不完整；多余的流扩展条目不是文件名。
+		fileName := decodeName(setNameUnits(set.slots(dirData), nameLength))
+
+		if fileName == "" {
+			continue
+		}
+
+		// 验证簇号是否有效（对于目录）
+		cluster := fileInfoEntry.FirstCluster
+		isDir := fileEntry.FileAttributes.Has(AttrDirectory)
+
+		// 簇号必须为 0（没有数据）或簇堆中的数据簇，坏簇、链结束等特殊值和超出簇堆的值都无效
+		if cluster != 0 && !fs.validCluster(cluster) {
+			if isDir {
+				cluster = 0 // 将无效的目录簇设为 0，按没有簇的空目录处理
+			} else {
+				// 对于文件，跳过有无效簇号的条目
+				continue
+			}
+		}
+
+		anomalies := metadataAnomalies(set.slots(dirData), fileEntry, fileInfoEntry, upcase)
+		if streams > 1 {
+			anomalies = append(anomalies, AnomalyExtraStreamExtension)
+		}
+
+		setStarts = append(setStarts, setStart)
+		entries = append(entries, &DirEntry{
+			Name:       fileName,
+			Size:       int64(fileInfoEntry.DataLength),
+			IsDir:      isDir,
+			ModTime:    exfatTimeToTime(fileEntry.LastModifiedTimestamp, fileEntry.LastModified10msIncrement, fileEntry.LastModifiedUtcOffset),
+			CreateTime: exfatTimeToTime(fileEntry.CreateTimestamp, fileEntry.Create10msIncrement, fileEntry.CreateUtcOffset),
+			AccessTime: exfatAccessTimeToTime(fileEntry.LastAccessedTimestamp, fileEntry.LastAccessedUtcOffset),
+			Attributes: fileEntry.FileAttributes,
+			cluster:    cluster,
+			validSize:  int64(min(fileInfoEntry.ValidDataLength, fileInfoEntry.DataLength)),
+			noFatChain: fileInfoEntry.GeneralSecondaryFlags&NoFatChainFlag != 0,
+			path:       path.Join(dir.path, fileName),
+			anomalies:  anomalies,
+			streams:    streams,
+			id:         entryID{serial: fs.bootSector.VolumeSerialNumber, dir: dir.cluster, offset: int64(setStart)},
+		})
+	}
+
+	if !terminated && lastGoodEnd < len(dirData) {
+		// 缺少目录结束标记：最后一个有效条目集之后的内容视为残留数据
+		trailing := (len(dirData) - lastGoodEnd) / 32
+		if fs.opts.strict {
+			return nil, fmt.Errorf("directory %s not terminated: %d trailing slots after the last valid entry set", dir.path, trailing)
+		}
+		fs.diagnose(dir.path, "directory not terminated; %d trailing slots ignored", trailing)
+
+		kept := entries[:0]
+		for i, entry := range entries {
+			if setStarts[i] < lastGoodEnd {
+				kept = append(kept, entry)
+			}
+		}
+		entries = kept
+	}
+
+	return entries, nil
+}
+
+// decodeName 把文件名的 UTF-16 码元解码为字符串，去掉末尾的空字符
+func decodeName(units []uint16) string {
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
+
+// nameLengthLimit 返回条目集能容纳的文件名字符数
+// 除流扩展条目外的每个次条目是一个文件名条目，各提供 15 个 UTF-16 字符，文件名最长 255 个字符
+func nameLengthLimit(secondaryCount uint8) int {
+	return min(max(int(secondaryCount)-1, 0)*15, MaxNameLength)
+}
+
+// entrySetChecksum 计算目录条目集的校验和（跳过主条目中的 SetChecksum 字段）
+func entrySetChecksum(set []byte) uint16 {
+	var sum uint16
+	for i, b := range set {
+		if i == 2 || i == 3 {
+			continue
+		}
+		sum = (sum<<15 | sum>>1) + uint16(b)
+	}
+	return sum
+}
+
+// readDirectory 读取目录内容
+func (fs *ExFATFileSystem) readDirectory(dir *DirEntry) ([]FileEntry, error) {
+	dirEntries, err := fs.readDirectoryEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]FileEntry, 0, len(dirEntries))
+	for _, entry := range dirEntries {
+		entries = append(entries, entry.fileEntry())
+	}
+
+	return entries, nil
+}
+
+// Stat 返回指定路径的文件或目录信息，"/" 返回根目录
+// 路径不存在时返回的错误可以用 errors.Is(err, fs.ErrNotExist) 判断。
+func (fs *ExFATFileSystem) Stat(path string) (FileEntry, error) {
+	entry, err := fs.getEntry(normalizePath(path))
+	if err != nil {
+		return FileEntry{}, err
+	}
+	return entry.fileEntry(), nil
+}
+
+// ReadFile 读取文件内容
+// 设置了 NoFatChain 标志的文件按从起始簇开始的连续簇读取，不查询 FAT；其他文件沿 FAT 中的簇链读取。
+// 有效数据长度（ValidDataLength）之后的部分未写入过，与 Windows 相同读取为零，不返回簇中残留的数据。
+func (fs *ExFATFileSystem) ReadFile(path string) ([]byte, error) {
+	entry, err := fs.getEntry(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.IsDir {
+		return nil, fmt.Errorf("%w: %s", ErrIsDirectory, path)
+	}
+	fat, _, err := fs.chainFAT(entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.checkFreeLinks(fat, entry); err != nil {
+		return nil, err
+	}
+	if err := fs.claimChain(fat, entry); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, entry.Size)
+	if err := fs.readChainInto(fat, data[:entry.validSize], entry.cluster, entry.noFatChain, TraceData); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// freeLink 返回 fat 中 cluster 的 FAT 项是否为空闲值 0
+// 簇链上的簇不会指向空闲项，出现时说明 FAT 被清零或损坏，不能再按相邻簇猜测后续数据。
+func freeLink(fat []uint32, cluster uint32) bool {
+	return int(cluster) < len(fat) && fat[cluster] == 0
+}
+
+// checkFreeLinks 沿 fat 检查文件数据需要的簇链，链在数据结束之前遇到空闲项时返回 ErrBrokenChain
+// 遍历方式与 readChainInto 相同；目录和元数据仍按原来的宽松方式读取。
+func (fs *ExFATFileSystem) checkFreeLinks(fat []uint32, entry *DirEntry) error {
+	if entry.noFatChain || entry.Size <= 0 || !fs.validCluster(entry.cluster) {
+		return nil
+	}
+	needed := fs.clustersFor(entry.Size)
+	cluster := entry.cluster
+	for i := uint64(1); i < needed; i++ {
+		if freeLink(fat, cluster) {
+			return fmt.Errorf("%w: %s: FAT entry of cluster %d is free after %d of %d clusters", ErrBrokenChain, entry.path, cluster, i, needed)
+		}
+		if cluster = fs.nextIn(fat, cluster); !fs.validCluster(cluster) {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package exfat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// openImage 以 opts 打开内存中构造的卷
+func openImage(t *testing.T, img *testimage.Image, opts ...Option) *ExFATFileSystem {
+	t.Helper()
+	fs, err := NewExFATFileSystem(img.Disk(), opts...)
+	if err != nil {
+		t.Fatalf("NewExFATFileSystem: %v", err)
+	}
+	return fs
+}
+
+// fill 返回长度为 n、内容随位置变化的数据，簇顺序错误时读出的内容不同
+func fill(n int, seed byte) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i*7+i/512) ^ seed
+	}
+	return data
+}
+
+func TestContiguousMultiClusterDirectory(t *testing.T) {
+	var files []*testimage.Node
+	for i := 0; i < 40; i++ {
+		files = append(files, testimage.File(fmt.Sprintf("file%02d.txt", i), fill(100+i, byte(i))))
+	}
+	dir := testimage.Dir("Contig", files...)
+	img := testimage.Build(testimage.Options{}, dir)
+	e := img.Entry("/Contig")
+	if len(e.Clusters) < 3 {
+		t.Fatalf("directory spans %d clusters, want several", len(e.Clusters))
+	}
+	// 连续目录不使用 FAT：在 FAT 中留下指向别处的项，沿 FAT 读取会读到错误的簇
+	img.SetFAT(e.Clusters[0], img.Entry("/Contig/file00.txt").Clusters[0])
+	img.SetFAT(e.Clusters[1], 0)
+
+	fs := openImage(t, img)
+	entries, err := fs.ListDir("/Contig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(files) {
+		t.Fatalf("ListDir returned %d entries, want %d", len(entries), len(files))
+	}
+	for i, entry := range entries {
+		if entry.Name != files[i].Name {
+			t.Errorf("entry %d is %q, want %q", i, entry.Name, files[i].Name)
+		}
+	}
+	// 最后一个簇中的条目
+	last := files[len(files)-1]
+	data, err := fs.ReadFile("/Contig/" + last.Name)
+	if err != nil || !bytes.Equal(data, last.Data) {
+		t.Errorf("ReadFile %s: %d bytes, %v", last.Name, len(data), err)
+	}
+	stat, err := fs.Stat("/contig")
+	if err != nil || !stat.IsDir || !stat.Contiguous {
+		t.Errorf("Stat /contig: %+v, %v", stat, err)
+	}
+}
+
+func TestFATChainedDirectory(t *testing.T) {
+	var files []*testimage.Node
+	for i := 0; i < 40; i++ {
+		files = append(files, testimage.File(fmt.Sprintf("f%02d", i), []byte{byte(i)}))
+	}
+	dir := testimage.Dir("Chained", files...)
+	dir.Fragmented = true
+	img := testimage.Build(testimage.Options{}, dir)
+
+	fs := openImage(t, img)
+	entries, err := fs.ListDir("/Chained")
+	if err != nil || len(entries) != len(files) {
+		t.Fatalf("ListDir: %d entries, %v", len(entries), err)
+	}
+	data, err := fs.ReadFile("/Chained/f39")
+	if err != nil || !bytes.Equal(data, []byte{39}) {
+		t.Errorf("ReadFile f39: %v, %v", data, err)
+	}
+}
+
+// unterminatedDir 返回目录 /D 中 a、b 两个条目集之后没有结束标记、只有残留数据的卷
+// 残留数据以 a 的条目集副本开头（名称被改为 zzz，校验和不再匹配），其余槽位为未使用的 0x41 条目。
+func unterminatedDir(t *testing.T) *testimage.Image {
+	t.Helper()
+	img := testimage.Build(testimage.Options{}, testimage.Dir("D",
+		testimage.File("a", []byte("a")), testimage.File("b", []byte("b"))))
+	dir := img.Entry("/D")
+	end := 2 * 3 * 32
+	for pos := end; pos < int(dir.Size); pos += 32 {
+		slot := img.Bytes[img.DirOffset(dir, pos):][:32]
+		for i := range slot {
+			slot[i] = 0x41
+		}
+	}
+	a := img.Entry("/D/a")
+	for i := 0; i < 3; i++ {
+		copy(img.Bytes[img.DirOffset(dir, end+32*i):], img.Slot(a, i))
+	}
+	copy(img.Bytes[img.DirOffset(dir, end+64)+2:], []byte{'z', 0})
+	return img
+}
+
+func TestUnterminatedDirectory(t *testing.T) {
+	img := unterminatedDir(t)
+
+	fs := openImage(t, img)
+	entries, err := fs.ListDir("/D")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if !slices.Equal(names, []string{"a", "b"}) {
+		t.Errorf("entries %q, want [a b]: trailing slots must be ignored", names)
+	}
+	found := false
+	for _, d := range fs.Diagnostics() {
+		found = found || d.Path == "/D" && strings.Contains(d.Message, "not terminated")
+	}
+	if !found {
+		t.Errorf("no diagnostic for the missing end marker: %v", fs.Diagnostics())
+	}
+
+	// 严格模式下残留的条目集先因校验和报错；只剩未使用的槽位时报告缺少结束标记
+	strict := openImage(t, img, WithStrict())
+	if _, err := strict.ListDir("/D"); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("strict: got %v, want ErrChecksumMismatch", err)
+	}
+	dir := img.Entry("/D")
+	for pos := 2 * 3 * 32; pos < 3*3*32; pos++ {
+		img.Bytes[img.DirOffset(dir, pos)] = 0x41
+	}
+	strict = openImage(t, img, WithStrict())
+	if _, err := strict.ListDir("/D"); err == nil || !strings.Contains(err.Error(), "not terminated") {
+		t.Errorf("strict: got %v, want a not-terminated error", err)
+	}
+}
+
+func TestTerminatedDirectoryIgnoresDataAfterEnd(t *testing.T) {
+	img := unterminatedDir(t)
+	// 在残留数据之前放一个结束标记：之后的内容不属于目录，不产生诊断
+	dir := img.Entry("/D")
+	img.Bytes[img.DirOffset(dir, 2*3*32)] = 0x00
+
+	fs := openImage(t, img, WithStrict())
+	entries, err := fs.ListDir("/D")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("ListDir: %d entries, %v", len(entries), err)
+	}
+	if d := fs.Diagnostics(); len(d) != 0 {
+		t.Errorf("diagnostics for a terminated directory: %v", d)
+	}
+}
+
+func TestMaxDirEntries(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, testimage.Dir("D", testimage.File("a", nil)))
+	limit := int(img.Entry("/D").Size/32) - 1
+	fs := openImage(t, img, WithMaxDirEntries(limit))
+	if _, err := fs.ListDir("/D"); !errors.Is(err, ErrDirectoryTooLarge) {
+		t.Errorf("limit %d: got %v, want ErrDirectoryTooLarge", limit, err)
+	}
+	fs = openImage(t, img, WithMaxDirEntries(limit+1))
+	if _, err := fs.ListDir("/D"); err != nil {
+		t.Errorf("limit %d: %v", limit+1, err)
+	}
+}
@@ -0,0 +1,111 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"unicode/utf16"
+)
+
+// VolumeIdentity 卷的标识信息
+type VolumeIdentity struct {
+	SerialNumber uint32 // 卷序列号
+	Label        string // 卷标（没有卷标时为空）
+	Capacity     uint64 // 卷的字节数
+	ClusterSize  uint32 // 每簇字节数
+	Formatter    string // 格式化工具的指纹：跳转指令和引导代码的 CRC-32
+}
+
+// Serial 以 Windows 的形式返回卷序列号，如 "1A2B-3C4D"
+func (id VolumeIdentity) Serial() string {
+	return fmt.Sprintf("%04X-%04X", id.SerialNumber>>16, id.SerialNumber&0xFFFF)
+}
+
+// IdentifyVolume 读取卷的标识信息
+// 只读取引导扇区和根目录的第一个簇，不读取 FAT，适合快速扫描大量映像。
+// 卷标条目按惯例位于根目录开头，超出第一个簇的卷标不会被找到。
+func IdentifyVolume(r io.ReaderAt) (VolumeIdentity, error) {
+	bootSector, err := parseBootSectorAt(r, 0)
+	if err != nil {
+		return VolumeIdentity{}, err
+	}
+	return identify(r, bootSector)
+}
+
+// Identity 返回已打开的卷的标识信息
+// 使用打开时采用的引导区（可能是备份引导区），卷标同样只在根目录的第一个簇中查找。
+func (fs *ExFATFileSystem) Identity() (VolumeIdentity, error) {
+	return identify(fs.vhd, fs.bootSector)
+}
+
+// identify 根据引导扇区和根目录的第一个簇组成卷的标识信息
+func identify(r io.ReaderAt, bootSector *ExFATBootSector) (VolumeIdentity, error) {
+	bytesPerSector := uint64(1) << bootSector.BytesPerSectorShift
+	bytesPerCluster := bytesPerSector << bootSector.SectorsPerClusterShift
+	id := VolumeIdentity{
+		SerialNumber: bootSector.VolumeSerialNumber,
+		Capacity:     bootSector.VolumeLength * bytesPerSector,
+		ClusterSize:  uint32(bytesPerCluster),
+		Formatter:    bootSector.formatterFingerprint(),
+	}
+
+	root := bootSector.FirstClusterOfRootDir
+	if root < 2 || root > bootSector.ClusterCount+1 {
+		return id, fmt.Errorf("invalid root directory cluster: %d", root)
+	}
+	data := make([]byte, bytesPerCluster)
+	offset := uint64(bootSector.ClusterHeapOffset)*bytesPerSector + uint64(root-2)*bytesPerCluster
+	if _, err := r.ReadAt(data, int64(offset)); err != nil && err != io.EOF {
+		return id, fmt.Errorf("failed to read root directory: %v", err)
+	}
+
+	for pos := 0; pos+32 <= len(data); pos += 32 {
+		switch data[pos] {
+		case EntryTypeEndOfDirectory:
+			return id, nil
+		case EntryTypeVolumeLabel:
+			id.Label = decodeVolumeLabel(data[pos : pos+32])
+			return id, nil
+		}
+	}
+	return id, nil
+}
+
+// formatterFingerprint 返回格式化工具的指纹：跳转指令和引导代码的 CRC-32
+func (b *ExFATBootSector) formatterFingerprint() string {
+	fingerprint := crc32.NewIEEE()
+	fingerprint.Write(b.JmpBoot[:])
+	fingerprint.Write(b.BootCode[:])
+	return fmt.Sprintf("%08x", fingerprint.Sum32())
+}
+
+// VolumeLabel 返回卷标，根目录中没有卷标条目时返回空字符串
+// 与 Identity 不同，它沿簇链扫描整个根目录，不只是第一个簇。
+func (fs *ExFATFileSystem) VolumeLabel() (string, error) {
+	buf, err := fs.readDirectoryData(fs.volumeRoot())
+	if err != nil {
+		return "", err
+	}
+	defer fs.putBuffer(buf)
+	data := *buf
+
+	for pos := 0; pos+32 <= len(data); pos += 32 {
+		switch data[pos] {
+		case EntryTypeEndOfDirectory:
+			return "", nil
+		case EntryTypeVolumeLabel:
+			return decodeVolumeLabel(data[pos : pos+32]), nil
+		}
+	}
+	return "", nil
+}
+
+// decodeVolumeLabel 解码卷标条目中的 UTF-16LE 卷标，字符数超过 11 时截断
+func decodeVolumeLabel(entry []byte) string {
+	units := make([]uint16, min(int(entry[1]), 11))
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(entry[2+i*2:])
+	}
+	return string(utf16.Decode(units))
+}
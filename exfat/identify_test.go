@@ -0,0 +1,65 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// countingReader 记录经过它的读取次数和字节数
+type countingReader struct {
+	r            io.ReaderAt
+	reads, bytes int64
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	c.bytes += int64(len(p))
+	return c.r.ReadAt(p, off)
+}
+
+func TestIdentifyVolumeByteBudget(t *testing.T) {
+	const size = 60 << 30
+	img := testimage.Build(testimage.Options{Label: "CAM_A", Serial: 0x1A2B3C4D}, testimage.File("a.jpg", fill(5000, 1)))
+	// 声明为 60 GiB 的卷：FAT 有几百 MiB，识别时一个字节也不能读
+	bs := img.BootSector(false)
+	binary.LittleEndian.PutUint64(bs[72:], size/uint64(img.BytesPerSector))
+	binary.LittleEndian.PutUint32(bs[92:], uint32((size-uint64(img.HeapOffset))/uint64(img.BytesPerCluster)))
+	img.SignBoot()
+
+	r := &countingReader{r: sparseDisk{img: img.Bytes}}
+	id, err := IdentifyVolume(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Label != "CAM_A" || id.Serial() != "1A2B-3C4D" || id.Capacity != size || id.ClusterSize != uint32(img.BytesPerCluster) {
+		t.Errorf("identity %+v", id)
+	}
+	// 只读取引导扇区和根目录的第一个簇
+	if budget := int64(512 + img.BytesPerCluster); r.reads != 2 || r.bytes > budget {
+		t.Errorf("read %d bytes in %d reads, budget is %d bytes in 2 reads", r.bytes, r.reads, budget)
+	}
+}
+
+func TestIdentityMatchesIdentifyVolume(t *testing.T) {
+	img := testimage.Build(testimage.Options{Label: "Ünïcode 卷", Serial: 0xDEADBEEF})
+	fs := openImage(t, img)
+	opened, err := fs.Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := IdentifyVolume(img.Disk())
+	if err != nil || id != opened {
+		t.Errorf("IdentifyVolume = %+v, %v; Identity = %+v", id, err, opened)
+	}
+	if label, err := fs.VolumeLabel(); err != nil || label != id.Label || id.Label != "Ünïcode 卷" {
+		t.Errorf("labels %q and %q, %v", id.Label, label, err)
+	}
+
+	// 不是 exFAT 的数据
+	if _, err := IdentifyVolume(&testimage.Disk{Data: make([]byte, 4096)}); err == nil {
+		t.Error("identified a volume of zeros")
+	}
+}
@@ -0,0 +1,317 @@
+package exfat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotIndexed 表示索引无法回答查询：路径在建立索引的目录之外，或者索引已降级为目录骨架而查询涉及文件。
+// 调用者应退回到实时解析（ExFATFileSystem 的同名方法）。
+var ErrNotIndexed = errors.New("path is not covered by the index")
+
+// ErrIndexTooLarge 表示即使只保留目录骨架，索引也超过了 IndexOptions.MaxMemory
+var ErrIndexTooLarge = errors.New("index exceeds the memory limit")
+
+// IndexOptions BuildIndex 的选项
+type IndexOptions struct {
+	Root      string              // 建立索引的目录，默认为根目录
+	MaxMemory int64               // 索引内存占用的估计上限（字节），超过时降级为只索引目录；0 表示不限制
+	Progress  func(IndexProgress) // 每索引一个目录调用一次（可选）
+}
+
+// IndexProgress 建立索引的进度
+type IndexProgress struct {
+	Dirs     int   // 已索引的目录数
+	Files    int   // 已索引的文件数（降级之后为 0）
+	Memory   int64 // 索引内存占用的估计值（字节）
+	Skeleton bool  // 是否已降级为只索引目录
+}
+
+// Index 目录树的只读快照，建立之后不再读取映像
+// 查找按路径直接定位（与 Stat 一样忽略大小写），列目录和通配符匹配只访问内存中的数据。
+// 快照反映建立时的映像，之后对映像的修改（如 Chtimes）不会反映到索引中。
+// 建立完成后可以被多个 goroutine 同时使用。
+type Index struct {
+	root     string                // 建立索引的目录（卷中的路径）
+	nodes    map[string]*indexNode // 按 key 查找
+	skeleton bool                  // 只包含目录
+	upcase   []uint16              // 卷上的大写表，无法读取时为 nil（见 upcaseWith）
+	memory   int64
+}
+
+// indexNode 索引中的一个条目
+type indexNode struct {
+	path     string
+	entry    FileEntry
+	children []*indexNode // 目录的子条目，按目录项在磁盘上的顺序
+}
+
+// 每个索引条目在路径和名称之外的估计内存占用：节点、FileEntry、map 项和子条目切片中的指针
+const indexNodeOverhead = 256
+
+// BuildIndex 遍历目录树，建立路径到条目的只读索引，供交互式前端（HTTP 等）重复查找
+// 建立索引只在调用它的 goroutine 中进行，前端可以在后台调用它，在索引可用之前照常实时解析。
+// ctx 取消时停止遍历并返回 ctx.Err()。估计的内存占用超过 MaxMemory 时丢弃已索引的文件，
+// 之后只索引目录（Skeleton 为 true），仍然超过时返回 ErrIndexTooLarge。
+// 损坏映像中重名的条目只保留第一个，与按路径查找时的结果一致。
+func (fs *ExFATFileSystem) BuildIndex(ctx context.Context, opts IndexOptions) (*Index, error) {
+	upcase, _ := fs.upcaseTable()
+	ix := &Index{nodes: make(map[string]*indexNode), upcase: upcase}
+	progress := IndexProgress{}
+
+	err := fs.walk(opts.Root, func(p string, e FileEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !e.IsDir && ix.skeleton {
+			return nil
+		}
+
+		key := ix.key(p)
+		if _, ok := ix.nodes[key]; ok {
+			return nil
+		}
+		node := &indexNode{path: p, entry: e}
+		if ix.root == "" {
+			ix.root = p
+		} else if parent := ix.nodes[ix.key(path.Dir(p))]; parent != nil {
+			parent.children = append(parent.children, node)
+		}
+		ix.nodes[key] = node
+		ix.memory += nodeMemory(node)
+
+		if e.IsDir {
+			progress.Dirs++
+		} else {
+			progress.Files++
+		}
+		if opts.MaxMemory > 0 && ix.memory > opts.MaxMemory {
+			if ix.skeleton {
+				return ErrIndexTooLarge
+			}
+			ix.dropFiles()
+			progress.Files = 0
+			if ix.memory > opts.MaxMemory {
+				return ErrIndexTooLarge
+			}
+		}
+		if e.IsDir && opts.Progress != nil {
+			progress.Memory, progress.Skeleton = ix.memory, ix.skeleton
+			opts.Progress(progress)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// dropFiles 把索引降级为目录骨架：删除所有文件条目并重新估计内存占用
+func (ix *Index) dropFiles() {
+	ix.skeleton = true
+	ix.memory = 0
+	for key, node := range ix.nodes {
+		if !node.entry.IsDir {
+			delete(ix.nodes, key)
+			continue
+		}
+		dirs := node.children[:0]
+		for _, child := range node.children {
+			if child.entry.IsDir {
+				dirs = append(dirs, child)
+			}
+		}
+		clear(node.children[len(dirs):])
+		node.children = dirs
+		ix.memory += nodeMemory(node)
+	}
+}
+
+// nodeMemory 估计一个索引条目的内存占用
+func nodeMemory(n *indexNode) int64 {
+	return indexNodeOverhead + int64(2*len(n.path)+len(n.entry.Name))
+}
+
+// key 返回路径在索引中的键：规范化后按大写表转为大写，与按路径查找时一样忽略大小写
+func (ix *Index) key(p string) string {
+	return upcaseWith(ix.upcase, normalizePath(p))
+}
+
+// Root 返回建立索引的目录
+func (ix *Index) Root() string {
+	return ix.root
+}
+
+// Skeleton 返回索引是否因为内存上限降级为只包含目录
+func (ix *Index) Skeleton() bool {
+	return ix.skeleton
+}
+
+// Len 返回索引中的条目数（包括建立索引的目录本身）
+func (ix *Index) Len() int {
+	return len(ix.nodes)
+}
+
+// Memory 返回索引内存占用的估计值（字节）
+func (ix *Index) Memory() int64 {
+	return ix.memory
+}
+
+// covers 判断路径是否在建立索引的目录之内
+func (ix *Index) covers(p string) bool {
+	root := ix.key(ix.root)
+	return root == "/" || p == root || strings.HasPrefix(p, root+"/")
+}
+
+// lookup 查找路径，返回的错误与实时解析相同；索引无法回答时返回 ErrNotIndexed
+func (ix *Index) lookup(p string) (*indexNode, error) {
+	key := ix.key(p)
+	if !ix.covers(key) {
+		return nil, ErrNotIndexed
+	}
+	if node, ok := ix.nodes[key]; ok {
+		return node, nil
+	}
+	if ix.skeleton {
+		// 骨架中没有文件，找不到的路径可能是文件
+		return nil, ErrNotIndexed
+	}
+	// 与实时解析一样区分路径不存在和经过了文件
+	for dir := path.Dir(key); ix.covers(dir); dir = path.Dir(dir) {
+		if parent, ok := ix.nodes[dir]; ok {
+			if !parent.entry.IsDir {
+				return nil, fmt.Errorf("%w: %s", ErrNotDirectory, normalizePath(p))
+			}
+			break
+		}
+		if dir == "/" {
+			break
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotExist, normalizePath(p))
+}
+
+// Stat 返回指定路径的文件或目录信息，结果与 ExFATFileSystem.Stat 相同
+func (ix *Index) Stat(p string) (FileEntry, error) {
+	node, err := ix.lookup(p)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	return node.entry, nil
+}
+
+// ListDir 列出目录内容，结果与 ExFATFileSystem.ListDir 相同
+func (ix *Index) ListDir(p string) ([]FileEntry, error) {
+	if ix.skeleton {
+		return nil, ErrNotIndexed
+	}
+	node, err := ix.lookup(p)
+	if err != nil {
+		return nil, err
+	}
+	if !node.entry.IsDir {
+		return nil, fmt.Errorf("%w: %s", ErrNotDirectory, normalizePath(p))
+	}
+	entries := make([]FileEntry, len(node.children))
+	for i, child := range node.children {
+		entries[i] = child.entry
+	}
+	return entries, nil
+}
+
+// Walk 按与 ExFATFileSystem.WalkPaths 相同的顺序对 root 下的每个条目（不含 root 本身）调用 fn
+// fn 返回 filepath.SkipDir 跳过目录，filepath.SkipAll 结束遍历且不报错。骨架索引中只有目录。
+func (ix *Index) Walk(root string, fn func(path string, e FileEntry) error) error {
+	node, err := ix.lookup(root)
+	if err != nil {
+		return err
+	}
+	err = walkIndex(node, fn)
+	if err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkIndex 深度优先遍历 dir 的子条目
+func walkIndex(dir *indexNode, fn func(path string, e FileEntry) error) error {
+	for _, child := range dir.children {
+		err := fn(child.path, child.entry)
+		if err == filepath.SkipDir {
+			if child.entry.IsDir {
+				continue
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := walkIndex(child, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Glob 返回与 pattern 匹配的路径（卷中的大小写），按目录树的遍历顺序排列
+// pattern 按 path.Match 的语法逐级匹配，与文件名比较一样忽略大小写，如 "/DCIM/*/*.jpg"。
+// 模式中的目录部分必须在建立索引的目录之内；骨架索引中只能匹配目录，匹配文件时返回 ErrNotIndexed。
+func (ix *Index) Glob(pattern string) ([]string, error) {
+	components := splitPath(strings.ReplaceAll(pattern, "\\", "/"))
+	for _, c := range components {
+		if _, err := path.Match(c, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	// 模式开头必须逐级写出建立索引的目录，否则可能匹配到索引之外的条目
+	rootComponents := splitPath(ix.root)
+	if len(components) < len(rootComponents) {
+		return nil, ErrNotIndexed
+	}
+	for i, c := range rootComponents {
+		if hasMeta(components[i]) || upcaseWith(ix.upcase, components[i]) != upcaseWith(ix.upcase, c) {
+			return nil, ErrNotIndexed
+		}
+	}
+
+	start := ix.nodes[ix.key(ix.root)]
+	var matches []string
+	var match func(node *indexNode, rest []string) error
+	match = func(node *indexNode, rest []string) error {
+		if len(rest) == 0 {
+			matches = append(matches, node.path)
+			return nil
+		}
+		if !node.entry.IsDir {
+			return nil
+		}
+		if ix.skeleton && len(rest) == 1 {
+			// 最后一级可能匹配文件，骨架中没有文件
+			return ErrNotIndexed
+		}
+		pattern := upcaseWith(ix.upcase, rest[0])
+		for _, child := range node.children {
+			if ok, _ := path.Match(pattern, upcaseWith(ix.upcase, child.entry.Name)); ok {
+				if err := match(child, rest[1:]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := match(start, components[len(rootComponents):]); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// hasMeta 判断路径组件中是否有通配符
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
@@ -0,0 +1,194 @@
+package exfat
+
+import (
+	"context"
+	"errors"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// indexFixtures 返回比较索引与实时解析所用的卷
+func indexFixtures() map[string]*testimage.Image {
+	eoc, _ := eocImage(0xFFFFFFF8, FAT32EndOfChain)
+	return map[string]*testimage.Image{
+		"entry IDs": testimage.Build(testimage.Options{}, entryIDTree()...),
+		"stats":     statsImage(),
+		"eoc":       eoc,
+		"names": testimage.Build(testimage.Options{Label: "NAMES"},
+			testimage.Dir("Mixed", testimage.File("ReadMe.TXT", []byte("a")), testimage.File("Ünïcode.txt", []byte("b")),
+				testimage.Dir("Empty"), testimage.Dir("Deep", testimage.Dir("Er", testimage.File("x.jpg", []byte("x"))))),
+			testimage.File("top.jpg", []byte("top"))),
+	}
+}
+
+// livePaths 返回实时解析得到的所有路径（含根目录）
+func livePaths(t *testing.T, fs *ExFATFileSystem) []string {
+	t.Helper()
+	var paths []string
+	if err := fs.Walk("/", func(p string, e FileEntry) error {
+		paths = append(paths, p)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+// liveGlob 用 ListDir 逐级匹配 pattern（与索引一样忽略 ASCII 大小写）
+func liveGlob(fs *ExFATFileSystem, pattern string) []string {
+	var matches []string
+	var match func(dir string, rest []string)
+	match = func(dir string, rest []string) {
+		if len(rest) == 0 {
+			matches = append(matches, dir)
+			return
+		}
+		entries, err := fs.ListDir(dir)
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			if ok, _ := path.Match(strings.ToUpper(rest[0]), strings.ToUpper(e.Name)); ok {
+				match(path.Join(dir, e.Name), rest[1:])
+			}
+		}
+	}
+	match("/", splitPath(pattern))
+	return matches
+}
+
+// sameError 判断索引与实时解析的错误是否属于同一类
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return errors.Is(a, ErrNotExist) == errors.Is(b, ErrNotExist) && errors.Is(a, ErrNotDirectory) == errors.Is(b, ErrNotDirectory)
+}
+
+// indexedStat 与 httpfs 一样先查索引，索引无法回答时退回到实时解析
+func indexedStat(ix *Index, fs *ExFATFileSystem, p string) (FileEntry, error) {
+	if e, err := ix.Stat(p); !errors.Is(err, ErrNotIndexed) {
+		return e, err
+	}
+	return fs.Stat(p)
+}
+
+// indexedListDir 与 indexedStat 相同，用于 ListDir
+func indexedListDir(ix *Index, fs *ExFATFileSystem, p string) ([]FileEntry, error) {
+	if entries, err := ix.ListDir(p); !errors.Is(err, ErrNotIndexed) {
+		return entries, err
+	}
+	return fs.ListDir(p)
+}
+
+func TestIndexMatchesLiveParsing(t *testing.T) {
+	patterns := []string{"/*", "/*/*", "/*/*/*", "/*/*.jpg", "/d*/*", "/MIXED/readme.txt", "/many/file0?.txt", "/*/[a-f]*", "/nothing/*"}
+	for name, img := range indexFixtures() {
+		fs := openImage(t, img)
+		ix, err := fs.BuildIndex(context.Background(), IndexOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		paths := livePaths(t, fs)
+		if ix.Skeleton() || ix.Len() != len(paths) || ix.Root() != "/" {
+			t.Errorf("%s: skeleton %v, %d entries for %d paths, root %q", name, ix.Skeleton(), ix.Len(), len(paths), ix.Root())
+		}
+
+		for _, p := range paths {
+			for _, q := range []string{p, strings.ToUpper(p), strings.ToLower(p), p + "/", p + "/missing", p + "/missing/deeper"} {
+				got, err := ix.Stat(q)
+				want, wantErr := fs.Stat(q)
+				if !sameError(err, wantErr) || !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: Stat(%q) = %+v, %v; live %+v, %v", name, q, got, err, want, wantErr)
+				}
+				list, err := ix.ListDir(q)
+				wantList, wantErr := fs.ListDir(q)
+				if !sameError(err, wantErr) || (err == nil && !reflect.DeepEqual(list, wantList)) {
+					t.Errorf("%s: ListDir(%q) = %d entries, %v; live %d entries, %v", name, q, len(list), err, len(wantList), wantErr)
+				}
+			}
+		}
+		for _, pattern := range patterns {
+			got, err := ix.Glob(pattern)
+			if want := liveGlob(fs, pattern); err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: Glob(%q) = %q, %v; live %q", name, pattern, got, err, want)
+			}
+		}
+	}
+}
+
+func TestIndexSkeletonFallback(t *testing.T) {
+	for name, img := range indexFixtures() {
+		fs := openImage(t, img)
+		full, err := fs.BuildIndex(context.Background(), IndexOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 内存上限不足以容纳文件：降级为目录骨架
+		ix, err := fs.BuildIndex(context.Background(), IndexOptions{MaxMemory: full.Memory() - 1})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !ix.Skeleton() || ix.Memory() >= full.Memory() {
+			t.Errorf("%s: skeleton %v, memory %d of %d", name, ix.Skeleton(), ix.Memory(), full.Memory())
+		}
+
+		for _, p := range livePaths(t, fs) {
+			want, _ := fs.Stat(p)
+			// 目录由索引回答，文件由索引拒绝后退回到实时解析，结果都与实时解析相同
+			if _, err := ix.Stat(p); want.IsDir != (err == nil) || (!want.IsDir && !errors.Is(err, ErrNotIndexed)) {
+				t.Errorf("%s: skeleton Stat(%q): %v", name, p, err)
+			}
+			if got, err := indexedStat(ix, fs, p); err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: Stat(%q) with fallback = %+v, %v; live %+v", name, p, got, err, want)
+			}
+			if !want.IsDir {
+				continue
+			}
+			wantList, _ := fs.ListDir(p)
+			if got, err := indexedListDir(ix, fs, p); err != nil || !reflect.DeepEqual(got, wantList) {
+				t.Errorf("%s: ListDir(%q) with fallback: %d entries, %v", name, p, len(got), err)
+			}
+		}
+		if _, err := ix.Glob("/*"); !errors.Is(err, ErrNotIndexed) {
+			t.Errorf("%s: skeleton Glob matched files: %v", name, err)
+		}
+	}
+
+	// 连目录骨架都超过上限
+	fs := openImage(t, indexFixtures()["entry IDs"])
+	if _, err := fs.BuildIndex(context.Background(), IndexOptions{MaxMemory: 1}); !errors.Is(err, ErrIndexTooLarge) {
+		t.Errorf("tiny limit: %v", err)
+	}
+}
+
+func TestIndexSubtree(t *testing.T) {
+	fs := openImage(t, indexFixtures()["names"])
+	ix, err := fs.BuildIndex(context.Background(), IndexOptions{Root: "/Mixed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/Mixed", "/mixed/readme.txt", "/Mixed/Deep/Er/x.jpg", "/Mixed/nothing"} {
+		got, err := ix.Stat(p)
+		want, wantErr := fs.Stat(p)
+		if !sameError(err, wantErr) || !reflect.DeepEqual(got, want) {
+			t.Errorf("Stat(%q) = %+v, %v; live %+v, %v", p, got, err, want, wantErr)
+		}
+	}
+	// 建立索引的目录之外的路径由实时解析回答
+	for _, p := range []string{"/", "/top.jpg", "/Other"} {
+		if _, err := ix.Stat(p); !errors.Is(err, ErrNotIndexed) {
+			t.Errorf("Stat(%q) outside the index: %v", p, err)
+		}
+	}
+	if got, err := ix.Glob("/Mixed/*/*"); err != nil || !reflect.DeepEqual(got, liveGlob(fs, "/Mixed/*/*")) {
+		t.Errorf("Glob in the subtree: %q, %v", got, err)
+	}
+	if _, err := ix.Glob("/*/*"); !errors.Is(err, ErrNotIndexed) {
+		t.Errorf("Glob outside the subtree: %v", err)
+	}
+}
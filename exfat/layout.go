@@ -0,0 +1,79 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// RootEntry 描述根目录中的一个主条目
+type RootEntry struct {
+	Type         uint8  // 条目类型（EntryTypeAllocationBitmap 等）
+	Kind         string // "bitmap"、"upcase"、"label"、"file"、"directory" 或 "other"
+	Name         string // 卷标或文件名
+	FirstCluster uint32 // 数据的起始簇（卷标为 0）
+	DataLength   uint64 // 数据长度（卷标为 0）
+	Offset       int64  // 主条目在卷上的字节偏移
+}
+
+// RootLayout 按磁盘顺序返回根目录中的所有主条目
+// 包括分配位图、大写表、卷标等关键条目和普通的文件条目，用于诊断和校验根目录结构。
+// 已删除的条目和次要条目不包含在内。
+func (fs *ExFATFileSystem) RootLayout() ([]RootEntry, error) {
+	root := fs.volumeRoot()
+	buf, err := fs.readDirectoryData(root)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.putBuffer(buf)
+	data := *buf
+
+	// 按条目集划分槽位，已删除的条目和孤立的次条目不在其中；次条目随所属主条目一起描述
+	sets, _ := scanEntrySets(data)
+	var layout []RootEntry
+	for _, set := range sets {
+		offset := set.start
+		entryType := data[offset]
+
+		entry := RootEntry{Type: entryType, Kind: "other", Offset: fs.directoryOffset(root, int64(offset))}
+		switch entryType {
+		case EntryTypeAllocationBitmap:
+			entry.Kind = "bitmap"
+			entry.FirstCluster = binary.LittleEndian.Uint32(data[offset+20:])
+			entry.DataLength = binary.LittleEndian.Uint64(data[offset+24:])
+		case EntryTypeUpcaseTable:
+			entry.Kind = "upcase"
+			entry.FirstCluster = binary.LittleEndian.Uint32(data[offset+20:])
+			entry.DataLength = binary.LittleEndian.Uint64(data[offset+24:])
+		case EntryTypeVolumeLabel:
+			entry.Kind = "label"
+			entry.Name = decodeVolumeLabel(data[offset : offset+32])
+		case EntryTypeFile:
+			entry.Kind = "file"
+			if Attributes(binary.LittleEndian.Uint16(data[offset+4:])).Has(AttrDirectory) {
+				entry.Kind = "directory"
+			}
+			if set.end >= offset+64 && data[offset+32] == EntryTypeFileInfo {
+				stream := data[offset+32:]
+				entry.FirstCluster = binary.LittleEndian.Uint32(stream[20:])
+				entry.DataLength = binary.LittleEndian.Uint64(stream[24:])
+				entry.Name = string(utf16.Decode(setNameUnits(set.slots(data), int(stream[3]))))
+			}
+		}
+		layout = append(layout, entry)
+	}
+	return layout, nil
+}
+
+// directoryOffset 把目录数据中的偏移换算为卷上的字节偏移
+// 簇的遍历方式与 readDirectoryData 读取目录时相同
+func (fs *ExFATFileSystem) directoryOffset(dir *DirEntry, pos int64) int64 {
+	cluster := dir.cluster
+	for i := pos / int64(fs.bytesPerCluster); i > 0; i-- {
+		if dir.noFatChain {
+			cluster++
+		} else {
+			cluster = fs.nextValidCluster(cluster)
+		}
+	}
+	return int64(fs.clusterToOffset(cluster)) + pos%int64(fs.bytesPerCluster)
+}
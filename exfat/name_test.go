@@ -0,0 +1,52 @@
+package exfat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+func TestNameLengthLimit(t *testing.T) {
+	for _, tt := range []struct {
+		secondary uint8
+		want      int
+	}{
+		{0, 0}, {1, 0}, {2, 15}, {3, 30}, {18, 255}, {19, 255}, {255, 255},
+	} {
+		if got := nameLengthLimit(tt.secondary); got != tt.want {
+			t.Errorf("nameLengthLimit(%d) = %d, want %d", tt.secondary, got, tt.want)
+		}
+	}
+}
+
+func TestNameLengthExceedsNameEntries(t *testing.T) {
+	const full = "fifteen-chars.x" // 正好占满一个文件名条目
+	img := testimage.Build(testimage.Options{}, testimage.Dir("D",
+		testimage.File(full, []byte("1")), testimage.File("next", []byte("2"))))
+	e := img.Entry("/D/" + full)
+	img.Slot(e, 1)[3] = 40 // NameLength：比唯一的文件名条目多 25 个字符
+	img.Resum(e)
+
+	fs := openImage(t, img)
+	entries, err := fs.ListDir("/D")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 名称在文件名条目的末尾截断，不会读入下一个条目集
+	if len(entries) != 2 || entries[0].Name != full || entries[1].Name != "next" {
+		t.Fatalf("entries %+v, want %q and \"next\"", entries, full)
+	}
+	found := false
+	for _, d := range fs.Diagnostics() {
+		found = found || strings.Contains(d.Message, "name length 40 exceeds the 15 characters")
+	}
+	if !found {
+		t.Errorf("no diagnostic for the name length: %v", fs.Diagnostics())
+	}
+
+	strict := openImage(t, img, WithStrict())
+	if _, err := strict.ListDir("/D"); err == nil || !strings.Contains(err.Error(), "name length 40") {
+		t.Errorf("strict: got %v, want a name length error", err)
+	}
+}
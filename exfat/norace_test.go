@@ -0,0 +1,5 @@
+//go:build !race
+
+package exfat
+
+const raceEnabled = false
@@ -0,0 +1,127 @@
+package exfat
+
+import "io"
+
+// Option 配置文件系统的解析方式
+type Option func(*options)
+
+// options 文件系统选项
+type options struct {
+	backupBootRecovery bool          // 主引导区校验失败时尝试使用备份引导区
+	skipBootChecksum   bool          // 引导区校验和不匹配时仍然使用主引导扇区，见 WithoutBootChecksum
+	strict             bool          // 严格模式：结构问题作为错误返回，而不是记录诊断后继续
+	fat32EOC           bool          // 把 FAT32 风格的 0x0FFFFFFF 视为链结束
+	maxDirEntries      int           // 单个目录最多读取的 32 字节条目数
+	cache              io.Reader     // 元数据缓存，见 WithMetadataCache
+	crosslinks         bool          // 读取时检测交叉链接，见 WithCrosslinkDetection
+	maxReadSize        int           // 合并连续簇时一次读取的字节数上限
+	clusterFilter      ClusterFilter // 遍历簇链时的簇过滤器，见 WithClusterFilter
+	tracer             ReadTracer    // 记录每次读取，见 WithReadTracer
+}
+
+// DefaultMaxDirEntries 单个目录默认最多读取的条目数（2^21 个，即 64 MiB 目录数据）
+const DefaultMaxDirEntries = 1 << 21
+
+// DefaultMaxReadSize 合并连续簇时一次读取的默认上限（8 MiB）
+const DefaultMaxReadSize = 8 << 20
+
+// WithBackupBootRecovery 启用备份引导区恢复
+// 主引导扇区签名有效但引导区校验和不匹配时，读取第 12 扇区开始的备份引导区，
+// 若备份引导区校验和正确且布局一致，则改用备份引导区中的参数；否则打开失败（除非同时使用 WithoutBootChecksum）
+func WithBackupBootRecovery() Option {
+	return func(o *options) {
+		o.backupBootRecovery = true
+	}
+}
+
+// WithoutBootChecksum 跳过引导区校验和的验证，用于恢复校验和扇区损坏或被其他工具改写过的映像
+// 默认打开时验证主引导区的校验和，不匹配时返回 ErrBootChecksum。使用这个选项时仍然使用主引导扇区，
+// 不匹配的原因记入诊断信息；与 WithBackupBootRecovery 同时使用时优先改用校验和正确的备份引导区。
+func WithoutBootChecksum() Option {
+	return func(o *options) {
+		o.skipBootChecksum = true
+	}
+}
+
+// WithStrict 启用严格模式
+// 默认的宽松模式会尽量容忍结构问题并通过 Diagnostics 报告；严格模式下这些问题作为错误返回。
+// 例如文件的簇链在活动 FAT 中无效时，宽松模式会改用第二个 FAT 中有效的簇链，严格模式返回 ErrBrokenChain；
+// 条目集的校验和不匹配时，宽松模式照常返回条目并标记 AnomalyChecksumMismatch，严格模式列目录时返回 ErrChecksumMismatch。
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithFAT32StyleEOC 把 FAT 中 FAT32 风格的 0x0FFFFFFF 视为链结束
+// 某些闪存控制器在 exFAT 的 FAT 中写入这个值。0x0FFFFFFF 超出簇堆时不可能是有效簇号，打开时自动启用，
+// 不依赖格式化工具的指纹；只有簇数超过 0x0FFFFFFD、这个值同时也是有效簇号时才需要显式指定。
+// 使用了这个标记的卷在 Conformance 中报告。
+func WithFAT32StyleEOC() Option {
+	return func(o *options) {
+		o.fat32EOC = true
+	}
+}
+
+// WithMaxDirEntries 设置单个目录最多读取的 32 字节条目数，n <= 0 时使用 DefaultMaxDirEntries
+// 目录的大小（连续目录按声明的 DataLength，其他目录按簇链的长度）超过上限时返回 ErrDirectoryTooLarge，
+// 防止损坏或恶意的映像耗尽内存。
+func WithMaxDirEntries(n int) Option {
+	return func(o *options) {
+		o.maxDirEntries = n
+	}
+}
+
+// WithMetadataCache 打开时先尝试从 SaveMetadataCache 写出的缓存中载入 FAT，跳过从映像读取和解析 FAT
+// 缓存损坏或与映像不一致时忽略缓存，照常读取 FAT；可以用 UsedMetadataCache 判断缓存是否生效。
+func WithMetadataCache(r io.Reader) Option {
+	return func(o *options) {
+		o.cache = r
+	}
+}
+
+// WithCrosslinkDetection 在读取文件时检测交叉链接：两个条目的簇链共用同一个簇
+// 读取和提取时把遍历过的簇登记为该文件占用（每个簇一位，第一次读取文件时分配），
+// 文件遇到已被另一个文件占用的簇时返回 *CrossLinkError，指出两个路径和共用的簇，
+// Anomalies 和 DirAnomalies 报告 AnomalyCrossLinked。只能发现已经读取过的文件之间的交叉链接；
+// 目录的簇链不登记。
+func WithCrosslinkDetection() Option {
+	return func(o *options) {
+		o.crosslinks = true
+	}
+}
+
+// WithMaxReadSize 设置读取文件时把连续簇合并为一次读取的字节数上限，n <= 0 时使用 DefaultMaxReadSize
+// 沿 FAT 分配的文件通常也由很长的连续簇组成，合并读取可以减少系统调用并发挥操作系统预读的作用；
+// 碎片边界处仍然分开读取。小于一个簇时每次读取一个簇。
+func WithMaxReadSize(n int) Option {
+	return func(o *options) {
+		o.maxReadSize = n
+	}
+}
+
+// WithClusterFilter 在每次遍历簇链（读取文件、目录、检查异常和修复计划）时，
+// 先用 filter 判定将要进入的簇，再做常规的有效性检查
+// 判定为 ClusterTreatAsEOC 时簇链在该簇之前结束，ClusterTreatAsBad 时视为坏簇；
+// 连续分配的文件同样在第一个被过滤的簇之前结束。过滤器改变遍历时记录诊断。
+// 用于厂商固件区等 FAT 错误地标记为簇链延续、又不便修改映像的区域，见 ExcludeClusterRanges。
+func WithClusterFilter(filter ClusterFilter) Option {
+	return func(o *options) {
+		o.clusterFilter = filter
+	}
+}
+
+// applyOptions 汇总选项
+func applyOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.maxDirEntries <= 0 {
+		o.maxDirEntries = DefaultMaxDirEntries
+	}
+	if o.maxReadSize <= 0 {
+		o.maxReadSize = DefaultMaxReadSize
+	}
+	return o
+}
@@ -0,0 +1,55 @@
+package exfat
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/0xXA/go-exfat/internal/preview"
+)
+
+// DefaultPreviewBytes ExtractPreview 在 maxBytes 不大于 0 时每个文件最多读取的字节数
+const DefaultPreviewBytes = 1 << 20
+
+// ExtractPreview 返回文件中嵌入的预览图和它的 MIME 类型，不读取整个文件
+// 支持 JPEG 的 EXIF 缩略图（只读取开头 128 KiB）、MP4/MOV 的封面（只读取盒子头和 moov 中的封面，
+// 不读取 mdat）和 HEIC/HEIF 的缩略图条目；格式按文件内容判断，与扩展名无关。
+// 每个文件最多读取 maxBytes 字节（不大于 0 时为 DefaultPreviewBytes），超过上限即放弃。
+// 路径不存在或不是文件时返回相应的错误；文件没有可用的预览图、格式无法识别、读取失败或超过上限时
+// 返回 (nil, "", 包装了 ErrNoPreview 的错误)。
+func (fs *ExFATFileSystem) ExtractPreview(path string, maxBytes int) ([]byte, string, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	if maxBytes <= 0 {
+		maxBytes = DefaultPreviewBytes
+	}
+
+	r := &budgetReader{r: f, left: int64(maxBytes)}
+	data, mime, err := preview.Find(r, f.entry.Size)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s: %v", ErrNoPreview, path, err)
+	}
+	return data, mime, nil
+}
+
+// budgetReader 限制读取总字节数的 io.ReaderAt
+type budgetReader struct {
+	r    io.ReaderAt
+	left int64
+}
+
+// ReadAt 超过上限的读取只读取剩余的字节数，并返回错误
+func (b *budgetReader) ReadAt(p []byte, off int64) (int, error) {
+	if int64(len(p)) <= b.left {
+		b.left -= int64(len(p))
+		return b.r.ReadAt(p, off)
+	}
+	n, err := b.r.ReadAt(p[:b.left], off)
+	b.left -= int64(n)
+	if err == nil || err == io.EOF {
+		err = fmt.Errorf("read limit reached at offset %d", off+int64(n))
+	}
+	return n, err
+}
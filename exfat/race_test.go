@@ -0,0 +1,6 @@
+//go:build race
+
+package exfat
+
+// raceEnabled 测试是否在竞态检测器下运行；这时 sync.Pool 会随机丢弃交还的对象，分配次数没有意义
+const raceEnabled = true
@@ -0,0 +1,79 @@
+package exfat
+
+import "fmt"
+
+// region 卷上的一段字节范围 [start, end)
+type region struct {
+	name       string
+	start, end uint64
+}
+
+// overlaps 判断两段范围是否重叠
+func (r region) overlaps(o region) bool {
+	return r.start < o.end && o.start < r.end
+}
+
+// regions 按引导扇区计算引导区（主、备份）、FAT 和簇堆的字节范围
+func (fs *ExFATFileSystem) regions() (boot, fats, heap region) {
+	bs := fs.bootSector
+	sector := uint64(fs.bytesPerSector)
+	boot = region{"boot region", 0, 2 * bootRegionSectors * sector}
+	fatStart := uint64(bs.FatOffset) * sector
+	fats = region{"FAT", fatStart, fatStart + uint64(bs.FatLength)*uint64(bs.NumberOfFats)*sector}
+	heap = region{"cluster heap", fs.clusterHeapStart, fs.clusterHeapStart + uint64(fs.totalClusters)*uint64(fs.bytesPerCluster)}
+	return boot, fats, heap
+}
+
+// checkRegions 检查引导区、FAT 和簇堆是否相互重叠或超出 VolumeLength
+// 严格模式下返回 ErrRegionOverlap；宽松模式下记录诊断，并把与簇堆重叠的引导区和 FAT 记入 reserved，
+// 之后读取落在其中的簇时返回 ErrRegionOverlap，不会把引导区或 FAT 的内容当作文件数据返回。
+func (fs *ExFATFileSystem) checkRegions() error {
+	boot, fats, heap := fs.regions()
+	var problems []string
+	if fats.overlaps(boot) {
+		problems = append(problems, fmt.Sprintf("the FAT (sectors %d-%d) overlaps the boot region", fats.start/uint64(fs.bytesPerSector), fats.end/uint64(fs.bytesPerSector)-1))
+	}
+	for _, r := range []region{boot, fats} {
+		if heap.overlaps(r) {
+			problems = append(problems, fmt.Sprintf("the cluster heap (offset %d) overlaps the %s", heap.start, r.name))
+			fs.reserved = append(fs.reserved, r)
+		}
+	}
+	if volume := fs.VolumeSize(); fs.bootSector.VolumeLength != 0 {
+		for _, r := range []region{fats, heap} {
+			if r.end > volume {
+				problems = append(problems, fmt.Sprintf("the %s ends at byte %d, past the end of the %d-byte volume", r.name, r.end, volume))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	if fs.opts.strict {
+		return fmt.Errorf("%w: %s", ErrRegionOverlap, problems[0])
+	}
+	for _, p := range problems {
+		fs.diagnose("", "%s", p)
+	}
+	return nil
+}
+
+// checkClusters 检查从 cluster 簇内偏移 skip 处开始的 n 个字节是否落在 reserved 中
+// 返回第一个落在其中的簇和包装了 ErrRegionOverlap 的错误，都不在其中时返回 nil。
+func (fs *ExFATFileSystem) checkClusters(cluster uint32, skip, n int64) (uint32, error) {
+	if len(fs.reserved) == 0 || n <= 0 {
+		return cluster, nil
+	}
+	clusterSize := int64(fs.bytesPerCluster)
+	for i := skip / clusterSize; i <= (skip+n-1)/clusterSize; i++ {
+		c := cluster + uint32(i)
+		start := fs.clusterToOffset(c)
+		r := region{start: start, end: start + uint64(clusterSize)}
+		for _, reserved := range fs.reserved {
+			if r.overlaps(reserved) {
+				return c, fmt.Errorf("%w: cluster %d lies inside the %s", ErrRegionOverlap, c, reserved.name)
+			}
+		}
+	}
+	return cluster, nil
+}
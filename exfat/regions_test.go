@@ -0,0 +1,98 @@
+package exfat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// regionsImage 返回区域测试使用的卷：一个连续分配的文件和一个通过 FAT 连接的碎片化文件
+func regionsImage() *testimage.Image {
+	frag := testimage.File("frag.bin", fill(1500, 2))
+	frag.Fragmented = true
+	return testimage.Build(testimage.Options{}, testimage.File("plain.bin", fill(2000, 1)), frag)
+}
+
+// setBootField 在主、备份引导扇区中写入 uint32 字段并重新计算校验和
+func setBootField(img *testimage.Image, offset int, v uint32) {
+	for _, backup := range []bool{false, true} {
+		binary.LittleEndian.PutUint32(img.BootSector(backup)[offset:], v)
+	}
+	img.SignBoot()
+}
+
+func TestRegionOverlap(t *testing.T) {
+	const spareCluster = 500 // 远在已分配的簇之后
+	tests := []struct {
+		name    string
+		corrupt func(img *testimage.Image)
+		diag    string // 宽松模式下的诊断
+		readErr string // 宽松模式下读取失败的文件，其余文件照常读取
+	}{
+		{"FAT overlaps the boot region", func(img *testimage.Image) {
+			// FAT 整体前移 4 个扇区，占用备份引导区的最后几个扇区
+			sector := int64(img.BytesPerSector)
+			copy(img.Bytes[img.FATOffset-4*sector:], img.Bytes[img.FATOffset:img.FATOffset+img.FATLength])
+			setBootField(img, 80, uint32(img.FATOffset/sector)-4)
+		}, "the FAT (sectors 20-28) overlaps the boot region", ""},
+		{"cluster heap overlaps the FAT", func(img *testimage.Image) {
+			// FAT 移到簇堆中的空闲簇，碎片化文件的簇链经过其中一个簇
+			e := img.Entry("/frag.bin")
+			img.SetFAT(e.Clusters[0], spareCluster+1)
+			img.SetFAT(spareCluster+1, e.Clusters[1])
+			copy(img.Bytes[img.ClusterOffset(spareCluster):], img.Bytes[img.FATOffset:img.FATOffset+img.FATLength])
+			setBootField(img, 80, uint32(img.ClusterOffset(spareCluster)/int64(img.BytesPerSector)))
+		}, "overlaps the FAT", "/frag.bin"},
+		{"cluster heap overlaps the boot region", func(img *testimage.Image) {
+			// 簇堆声明在引导区之内、比实际的位置前移 21 个扇区：按新的起点，根目录的簇落在 FAT 中
+			setBootField(img, 88, uint32(img.HeapOffset/int64(img.BytesPerSector))-21)
+		}, "overlaps the boot region", "/"},
+		{"cluster heap past the volume end", func(img *testimage.Image) {
+			volume := binary.LittleEndian.Uint64(img.BootSector(false)[72:])
+			for _, backup := range []bool{false, true} {
+				binary.LittleEndian.PutUint64(img.BootSector(backup)[72:], volume-8)
+			}
+			img.SignBoot()
+		}, "past the end of the", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := regionsImage()
+			tt.corrupt(img)
+
+			// 严格模式拒绝打开
+			if _, err := NewExFATFileSystem(img.Disk(), WithStrict()); !errors.Is(err, ErrRegionOverlap) || !errors.Is(err, ErrInvalidImage) {
+				t.Errorf("strict: got %v, want ErrRegionOverlap", err)
+			}
+
+			// 宽松模式记录诊断，落在引导区或 FAT 中的簇返回 ErrRegionOverlap 而不是其中的内容
+			fs := openImage(t, img)
+			var diagnosed bool
+			for _, d := range fs.Diagnostics() {
+				diagnosed = diagnosed || strings.Contains(d.Message, tt.diag)
+			}
+			if !diagnosed {
+				t.Errorf("diagnostics %v, want %q", fs.Diagnostics(), tt.diag)
+			}
+			if tt.readErr == "/" {
+				if _, err := fs.ListDir("/"); !errors.Is(err, ErrRegionOverlap) {
+					t.Errorf("ListDir(/): got %v, want ErrRegionOverlap", err)
+				}
+				return
+			}
+			for p, want := range map[string][]byte{"/plain.bin": fill(2000, 1), "/frag.bin": fill(1500, 2)} {
+				data, err := fs.ReadFile(p)
+				switch {
+				case p == tt.readErr && !errors.Is(err, ErrRegionOverlap):
+					t.Errorf("%s: got %v, want ErrRegionOverlap", p, err)
+				case p != tt.readErr && (err != nil || !bytes.Equal(data, want)):
+					t.Errorf("%s: %d bytes, %v", p, len(data), err)
+				}
+			}
+		})
+	}
+}
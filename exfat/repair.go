@@ -0,0 +1,262 @@
+package exfat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// RepairPlan 描述对一个簇链断裂的文件的修复假设
+// 计划只用于读取，不会修改映像。
+type RepairPlan struct {
+	Path        string   // 文件路径
+	Clusters    []uint32 // 完整的簇序列：FAT 中读到的部分加上推测的连续部分
+	ChainLength int      // 从 FAT 中读到的有效簇数，等于 len(Clusters) 表示链完整
+	Confidence  float64  // 假设成立的置信度（0 到 1）
+	Reasons     []string // 置信度的依据
+}
+
+// Broken 簇链是否在 DataLength 之前提前结束
+func (p RepairPlan) Broken() bool {
+	return p.ChainLength < len(p.Clusters)
+}
+
+// PlanChainRepair 为簇链提前结束的文件制定修复计划
+// 部分 FAT 被清零时，断点之后的簇往往仍然连续，这里假设剩余部分紧接在最后一个有效簇之后，
+// 并用分配位图（剩余簇必须已分配）和文件内容结构（JPEG、MP4）评估这个假设。
+// 簇链完整时返回的计划 Broken() 为 false，置信度为 1。
+func (fs *ExFATFileSystem) PlanChainRepair(path string) (RepairPlan, error) {
+	path = normalizePath(path)
+	entry, err := fs.getEntry(path)
+	if err != nil {
+		return RepairPlan{}, err
+	}
+	if entry.IsDir {
+		return RepairPlan{}, fmt.Errorf("%w: %s", ErrIsDirectory, path)
+	}
+
+	plan := RepairPlan{Path: path, Confidence: 1}
+	needed := fs.clustersFor(entry.Size)
+	if needed == 0 {
+		return plan, nil
+	}
+	if !fs.validCluster(entry.cluster) {
+		return RepairPlan{}, fmt.Errorf("cannot plan repair for %s: invalid first cluster %d", path, entry.cluster)
+	}
+
+	plan.Clusters = fs.strictChain(entry, needed)
+	plan.ChainLength = len(plan.Clusters)
+	if uint64(plan.ChainLength) == needed {
+		return plan, nil
+	}
+
+	// 假设剩余簇紧接在最后一个有效簇之后
+	last := plan.Clusters[len(plan.Clusters)-1]
+	missing := needed - uint64(plan.ChainLength)
+	if uint64(last)+missing > uint64(fs.totalClusters)+1 {
+		return RepairPlan{}, fmt.Errorf("cannot plan repair for %s: contiguous remainder of %d clusters after cluster %d exceeds the cluster heap", path, missing, last)
+	}
+	for i := uint64(1); i <= missing; i++ {
+		plan.Clusters = append(plan.Clusters, last+uint32(i))
+	}
+
+	plan.Confidence = 0.5
+	fs.scoreRepairPlan(&plan, entry)
+	return plan, nil
+}
+
+// strictChain 按 FAT 严格读取条目的簇链，最多 needed 个簇，遇到无效值或循环即停止
+func (fs *ExFATFileSystem) strictChain(entry *DirEntry, needed uint64) []uint32 {
+	clusters := make([]uint32, 0, needed)
+	seen := make(map[uint32]bool)
+	cluster := entry.cluster
+	for uint64(len(clusters)) < needed {
+		clusters = append(clusters, cluster)
+		seen[cluster] = true
+		if entry.noFatChain {
+			cluster = fs.filter(cluster + 1)
+			if !fs.validCluster(cluster) {
+				break
+			}
+			continue
+		}
+		next := fs.link(fs.fat, cluster)
+		if !fs.validCluster(next) || seen[next] {
+			break
+		}
+		cluster = next
+	}
+	return clusters
+}
+
+// scoreRepairPlan 根据分配位图和文件内容调整计划的置信度
+func (fs *ExFATFileSystem) scoreRepairPlan(plan *RepairPlan, entry *DirEntry) {
+	remainder := plan.Clusters[plan.ChainLength:]
+
+	if bitmap, err := fs.allocationBitmap(); err == nil {
+		unallocated := 0
+		for _, c := range remainder {
+			if !clusterAllocated(bitmap, c) {
+				unallocated++
+			}
+		}
+		if unallocated == 0 {
+			plan.Confidence += 0.3
+			plan.Reasons = append(plan.Reasons, fmt.Sprintf("all %d remaining clusters are allocated in the bitmap", len(remainder)))
+		} else {
+			plan.Confidence = 0.1
+			plan.Reasons = append(plan.Reasons, fmt.Sprintf("%d of %d remaining clusters are free in the bitmap", unallocated, len(remainder)))
+		}
+	} else {
+		plan.Reasons = append(plan.Reasons, "allocation bitmap unavailable")
+	}
+
+	// FAT 中剩余簇的项为 0（被清零）或依次指向下一簇时与假设一致
+	consistent := true
+	for i, c := range remainder {
+		next := fs.fat[c]
+		if next == 0 || fs.endOfChain(next) || (i+1 < len(remainder) && next == remainder[i+1]) {
+			continue
+		}
+		consistent = false
+		break
+	}
+	if !consistent {
+		plan.Confidence -= 0.2
+		plan.Reasons = append(plan.Reasons, "remaining clusters have FAT entries pointing elsewhere")
+	}
+
+	data, err := fs.readClusters(plan.Clusters, uint64(entry.Size), uint64(entry.validSize))
+	if err == nil {
+		if checked, ok := contentConsistent(data); checked && ok {
+			plan.Confidence += 0.15
+			plan.Reasons = append(plan.Reasons, "file structure continues through the break")
+		} else if checked {
+			plan.Confidence -= 0.3
+			plan.Reasons = append(plan.Reasons, "file structure does not continue through the break")
+		}
+	}
+
+	plan.Confidence = min(max(plan.Confidence, 0), 1)
+}
+
+// ReadFileWithPlan 按修复计划中的簇序列读取文件，不修改映像
+func (fs *ExFATFileSystem) ReadFileWithPlan(path string, plan RepairPlan) ([]byte, error) {
+	path = normalizePath(path)
+	entry, err := fs.getEntry(path)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir {
+		return nil, fmt.Errorf("%w: %s", ErrIsDirectory, path)
+	}
+	if plan.Path != path {
+		return nil, fmt.Errorf("repair plan is for %s, not %s", plan.Path, path)
+	}
+	if needed := fs.clustersFor(entry.Size); uint64(len(plan.Clusters)) != needed {
+		return nil, fmt.Errorf("repair plan has %d clusters, %s needs %d", len(plan.Clusters), path, needed)
+	}
+
+	return fs.readClusters(plan.Clusters, uint64(entry.Size), uint64(entry.validSize))
+}
+
+// ReadFileZeroFilled 读取文件，簇链在 DataLength 之前中断（提前结束、经过坏簇或出现循环）时，
+// 中断之后的部分以零填充，返回数据和填充的字节数
+// 与修复计划不同，它不推测剩余数据的位置，适合宁可丢失部分内容也不愿读入无关数据的场合。
+func (fs *ExFATFileSystem) ReadFileZeroFilled(path string) ([]byte, int64, error) {
+	path = normalizePath(path)
+	entry, err := fs.getEntry(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if entry.IsDir {
+		return nil, 0, fmt.Errorf("%w: %s", ErrIsDirectory, path)
+	}
+
+	var clusters []uint32
+	if needed := fs.clustersFor(entry.Size); needed > 0 && fs.validCluster(fs.filter(entry.cluster)) {
+		clusters = fs.strictChain(entry, needed)
+	}
+	data, err := fs.readClusters(clusters, uint64(entry.Size), uint64(entry.validSize))
+	if err != nil {
+		return nil, 0, err
+	}
+	filled := entry.Size - min(int64(len(clusters))*int64(fs.bytesPerCluster), entry.Size)
+	return data, filled, nil
+}
+
+// readClusters 按给定的簇序列读取 size 字节，valid 之后的部分为零
+func (fs *ExFATFileSystem) readClusters(clusters []uint32, size, valid uint64) ([]byte, error) {
+	data := make([]byte, size)
+	offset := uint64(0)
+	for _, cluster := range clusters {
+		if offset >= valid {
+			break
+		}
+		if !fs.validCluster(cluster) {
+			return nil, fmt.Errorf("%w %d", ErrBadCluster, cluster)
+		}
+		n := min(uint64(fs.bytesPerCluster), valid-offset)
+		if _, err := fs.checkClusters(cluster, 0, int64(n)); err != nil {
+			return nil, err
+		}
+		if _, err := fs.readAt(data[offset:offset+n], int64(fs.clusterToOffset(cluster)), TraceData); err != nil {
+			return nil, fmt.Errorf("failed to read cluster %d: %v", cluster, err)
+		}
+		offset += n
+	}
+	return data, nil
+}
+
+// clustersFor 返回容纳 size 字节所需的簇数
+func (fs *ExFATFileSystem) clustersFor(size int64) uint64 {
+	if size <= 0 {
+		return 0
+	}
+	return (uint64(size) + uint64(fs.bytesPerCluster) - 1) / uint64(fs.bytesPerCluster)
+}
+
+// clusterAllocated 检查分配位图中簇是否已分配（位图从簇 2 开始）
+func clusterAllocated(bitmap []byte, cluster uint32) bool {
+	index := cluster - 2
+	if int(index/8) >= len(bitmap) {
+		return false
+	}
+	return bitmap[index/8]&(1<<(index%8)) != 0
+}
+
+// contentConsistent 用文件格式的结构检查拼接后的数据是否连贯
+// checked 为 false 表示无法识别格式
+func contentConsistent(data []byte) (checked, ok bool) {
+	switch {
+	case len(data) >= 4 && data[0] == 0xFF && data[1] == 0xD8:
+		// JPEG 以 SOI 开头，以 EOI（FFD9）结尾，末尾可能有填充
+		return true, bytes.HasSuffix(bytes.TrimRight(data, "\x00"), []byte{0xFF, 0xD9})
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		// MP4 由顶层 box 首尾相接组成，box 类型为可打印字符
+		off := uint64(0)
+		for off+8 <= uint64(len(data)) {
+			size := uint64(binary.BigEndian.Uint32(data[off:]))
+			switch size {
+			case 0: // 延伸到文件末尾
+				return true, true
+			case 1: // 64 位大小
+				if off+16 > uint64(len(data)) {
+					return true, false
+				}
+				size = binary.BigEndian.Uint64(data[off+8:])
+			}
+			if size < 8 {
+				return true, false
+			}
+			for _, c := range data[off+4 : off+8] {
+				if c < 0x20 || c > 0x7E {
+					return true, false
+				}
+			}
+			off += size
+		}
+		return true, off == uint64(len(data))
+	}
+	return false, false
+}
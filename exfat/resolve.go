@@ -0,0 +1,73 @@
+package exfat
+
+import (
+	"strings"
+)
+
+// resolveOpts 路径解析选项
+type resolveOpts struct {
+	exact bool // 按原样比较名称，默认像 exFAT 一样按卷上的大写表忽略大小写
+}
+
+// resolveResult 路径解析的结果
+type resolveResult struct {
+	entry   *DirEntry // 终点条目，解析失败时为 nil
+	parent  *DirEntry // 终点所在的目录；解析失败时为停止处的目录
+	stopped int       // 第一个无法解析的组件序号，成功时等于组件数
+	notDir  bool      // 失败是因为前面的组件不是目录，而不是不存在
+}
+
+// resolvePath 从 start 目录开始逐个解析路径组件
+// 所有按路径查找条目的功能都通过这里解析，保证大小写规则和失败位置一致。
+// exFAT 没有符号链接，组件只会解析为文件或目录。没有组件时返回 start 本身。
+// 解析失败时结果中记录停止的位置，错误为 ErrNotExist 或 ErrNotDirectory；
+// 读取目录失败时返回读取错误。
+func (fs *ExFATFileSystem) resolvePath(start *DirEntry, components []string, opts resolveOpts) (resolveResult, error) {
+	result := resolveResult{entry: start, parent: start}
+	for i, name := range components {
+		upper := ""
+		if !opts.exact {
+			upper = fs.upcaseName(name)
+		}
+		dir := result.entry
+		if !dir.IsDir {
+			return resolveResult{parent: result.parent, stopped: i, notDir: true}, ErrNotDirectory
+		}
+
+		entries, err := fs.readDirectoryEntries(dir)
+		if err != nil {
+			return resolveResult{parent: dir, stopped: i}, err
+		}
+
+		var found *DirEntry
+		for _, entry := range entries {
+			if entry.Name == name || (!opts.exact && fs.upcaseName(entry.Name) == upper) {
+				found = entry
+				break
+			}
+		}
+		if found == nil {
+			return resolveResult{parent: dir, stopped: i}, ErrNotExist
+		}
+		result = resolveResult{entry: found, parent: dir, stopped: i + 1}
+	}
+	return result, nil
+}
+
+// splitPath 把路径拆分为组件，忽略开头、结尾和重复的斜杠以及 "."，".." 在根目录处截止
+func splitPath(path string) []string {
+	var components []string
+	for _, part := range strings.Split(path, "/") {
+		switch part {
+		case "", ".":
+		case "..":
+			// exFAT 的文件名不能是 "." 或 ".."，按上一级目录处理，到根目录为止
+			if len(components) > 0 {
+				components = components[:len(components)-1]
+			}
+		default:
+			components = append(components, part)
+		}
+	}
+	return components
+}
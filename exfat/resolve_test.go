@@ -0,0 +1,86 @@
+package exfat
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"", nil},
+		{"/", nil},
+		{"//", nil},
+		{"a", []string{"a"}},
+		{"/a/b/", []string{"a", "b"}},
+		{"a//b", []string{"a", "b"}},
+		{"/./a/./b/.", []string{"a", "b"}},
+		{"/a/../b", []string{"b"}},
+		{"/a/b/..", []string{"a"}},
+		{"/../a", []string{"a"}},
+		{"/a/../../..", nil},
+		{"/a b/ c /", []string{"a b", " c "}},
+		{"/...", []string{"..."}},
+	}
+	for _, tt := range tests {
+		if got := splitPath(tt.path); !slices.Equal(got, tt.want) {
+			t.Errorf("splitPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	fs := openImage(t, testimage.Build(testimage.Options{},
+		testimage.Dir("Docs",
+			testimage.File("Readme.TXT", []byte("r")),
+			testimage.Dir("Ünïcode", testimage.File("Ωmega", []byte("w")))),
+		testimage.File("top.bin", []byte("t")),
+		testimage.Dir("Empty")))
+
+	tests := []struct {
+		path    string
+		exact   bool
+		name    string // 终点条目的名称，根目录为 "/"
+		parent  string // 终点所在目录（失败时为停止处的目录）的名称
+		stopped int
+		notDir  bool
+		err     error
+	}{
+		{path: "/", name: "/", parent: "/"},
+		{path: "/Docs", name: "Docs", parent: "/", stopped: 1},
+		{path: "/docs/README.txt", name: "Readme.TXT", parent: "Docs", stopped: 2},
+		{path: "/Docs/README.txt", exact: true, parent: "Docs", stopped: 1, err: ErrNotExist},
+		{path: "/Docs/Readme.TXT", exact: true, name: "Readme.TXT", parent: "Docs", stopped: 2},
+		{path: "/DOCS/üNÏCODE/ωMEGA", name: "Ωmega", parent: "Ünïcode", stopped: 3},
+		{path: "/Docs/Ünïcode/../Readme.TXT", name: "Readme.TXT", parent: "Docs", stopped: 2},
+		{path: "/Docs/missing/x", parent: "Docs", stopped: 1, err: ErrNotExist},
+		{path: "/Empty/x", parent: "Empty", stopped: 1, err: ErrNotExist},
+		{path: "/top.bin/x", parent: "/", stopped: 1, notDir: true, err: ErrNotDirectory},
+		{path: "/Docs/Readme.TXT/x/y", parent: "Docs", stopped: 2, notDir: true, err: ErrNotDirectory},
+	}
+	for _, tt := range tests {
+		result, err := fs.resolvePath(fs.rootEntry(), splitPath(tt.path), resolveOpts{exact: tt.exact})
+		if !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+			t.Errorf("%s: error %v, want %v", tt.path, err, tt.err)
+			continue
+		}
+		if result.stopped != tt.stopped || result.notDir != tt.notDir {
+			t.Errorf("%s: stopped at %d (not a directory: %v), want %d (%v)", tt.path, result.stopped, result.notDir, tt.stopped, tt.notDir)
+		}
+		if tt.err != nil {
+			if result.entry != nil {
+				t.Errorf("%s: failed resolution returned entry %q", tt.path, result.entry.Name)
+			}
+		} else if result.entry == nil || result.entry.Name != tt.name {
+			t.Errorf("%s: resolved to %+v, want %q", tt.path, result.entry, tt.name)
+		}
+		if result.parent == nil || result.parent.Name != tt.parent {
+			t.Errorf("%s: parent %+v, want %q", tt.path, result.parent, tt.parent)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package exfat
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 这个模块写出的 JSON 文档的模式标识，格式为 "<种类>/<版本>"
+// 每个文档（NDJSON 的每一行）都带有 schema 和 generator 字段（见 Envelope）。字段的含义或类型改变时版本加一，
+// 同时在对应的解码函数中保留读取旧版本的路径；只增加字段不改变版本。
+const (
+	SchemaTrace    = "exfat.trace/1"    // WithReadTracing 写出的读取跟踪，每行一个 TraceRecord，见 DecodeTrace
+	SchemaIdentity = "exfat.identity/1" // identify 命令写出的卷标识，每行一个映像，见 DecodeIdentities
+	SchemaReport   = "exfat.report/1"   // extract -report 写出的提取报告，每行一个路径，见 DecodeReports
+)
+
+// modulePath 生成者名称中使用的模块路径
+const modulePath = "github.com/0xXA/go-exfat"
+
+// Envelope 每个 JSON 文档开头的自描述字段，嵌入到写出的结构中
+type Envelope struct {
+	Schema    string `json:"schema"`    // 模式标识，如 SchemaReport
+	Generator string `json:"generator"` // 写出文档的模块和版本，见 Generator
+}
+
+// NewEnvelope 返回 schema 种类的文档的 Envelope
+func NewEnvelope(schema string) Envelope {
+	return Envelope{Schema: schema, Generator: Generator()}
+}
+
+var (
+	generatorOnce sync.Once
+	generator     string
+)
+
+// Generator 返回写出文档的模块和版本，如 "go-exfat v1.4.0"
+// 版本来自程序的构建信息；从源码树直接构建时为 "go-exfat (devel)"。
+func Generator() string {
+	generatorOnce.Do(func() {
+		version := "(devel)"
+		if info, ok := debug.ReadBuildInfo(); ok {
+			if info.Main.Path == modulePath && info.Main.Version != "" {
+				version = info.Main.Version
+			}
+			for _, dep := range info.Deps {
+				if dep.Path == modulePath && dep.Version != "" {
+					version = dep.Version
+				}
+			}
+		}
+		generator = "go-exfat " + version
+	})
+	return generator
+}
+
+// CheckSchema 检查文档的 schema 字段是否属于 want（当前版本的模式标识）的种类，返回文档的版本
+// 没有 schema 字段的文档是加入模式标识之前写出的，返回版本 0；种类不同或版本比 want 新时返回包装了 ErrSchema 的错误。
+func CheckSchema(got, want string) (int, error) {
+	if got == "" {
+		return 0, nil
+	}
+	kind, version, ok := parseSchema(got)
+	wantKind, wantVersion, _ := parseSchema(want)
+	switch {
+	case !ok:
+		return 0, fmt.Errorf("%w: malformed schema %q", ErrSchema, got)
+	case kind != wantKind:
+		return 0, fmt.Errorf("%w: document is %s, want %s", ErrSchema, kind, wantKind)
+	case version > wantVersion:
+		return 0, fmt.Errorf("%w: %s is newer than the supported %s", ErrSchema, got, want)
+	}
+	return version, nil
+}
+
+// parseSchema 把模式标识拆分为种类和版本
+func parseSchema(s string) (string, int, bool) {
+	kind, v, ok := strings.Cut(s, "/")
+	if !ok || kind == "" {
+		return "", 0, false
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version < 1 {
+		return "", 0, false
+	}
+	return kind, version, true
+}
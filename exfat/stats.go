@@ -0,0 +1,178 @@
+package exfat
+
+import (
+	"fmt"
+)
+
+// 统计已用空间的方法（见 UsageEstimate.Method）
+const (
+	UsageFromBitmap = "bitmap" // 按分配位图中置位的簇统计，是规范的来源
+	UsageFromFAT    = "fat"    // 按不为 0（空闲）的 FAT 项统计；NoFatChain 的连续分配不写 FAT，因此偏少
+	UsageFromTree   = "tree"   // 遍历目录树，累加每个条目（以及位图、大写表和根目录）分配的簇数
+)
+
+// UsageEstimate 按一种方法得到的已用空间
+type UsageEstimate struct {
+	Method       string // UsageFrom* 常量
+	UsedClusters uint32 // 已用的簇数，Err 不为 nil 时无效
+	Err          error  // 该方法不可用的原因，如位图条目缺失或被截断
+}
+
+// FreeClusters 返回按该方法估计的空闲簇数
+func (u UsageEstimate) FreeClusters(total uint32) uint32 {
+	if u.UsedClusters > total {
+		return 0
+	}
+	return total - u.UsedClusters
+}
+
+// VolumeStats 卷的已用空间，以及各种统计方法的结果和差异
+// 位图可用时以位图为准，否则退回到 FAT（FAT 被覆盖时不可用）再退回到目录树，Method 记录实际使用的方法。
+// 退回到 FAT 时结果是下限：连续分配（NoFatChain）的文件不占用 FAT 项，这时应参考目录树的结果。
+// 各方法之间的差异本身就是损坏的信号：位图多于目录树说明有泄漏的簇，少于目录树说明有文件使用了空闲簇。
+type VolumeStats struct {
+	TotalClusters   uint32
+	BytesPerCluster uint32
+	Method          string // 产生 UsedClusters 的方法（UsageFrom* 常量），所有方法都不可用时为空
+	UsedClusters    uint32
+	FreeClusters    uint32
+
+	PercentInUse     uint8 // 按 UsedClusters 重新计算的使用百分比（向下取整）
+	BootPercentInUse uint8 // 引导扇区记录的使用百分比，0xFF 表示未记录
+
+	Estimates    []UsageEstimate // 按位图、FAT、目录树的顺序列出每种方法的结果
+	Disagreement uint32          // 其他方法与 Method 的结果相差的最大簇数；FAT 少于另一方的差不计入
+}
+
+// UsedBytes 返回已用空间的字节数
+func (s VolumeStats) UsedBytes() uint64 {
+	return uint64(s.UsedClusters) * uint64(s.BytesPerCluster)
+}
+
+// FreeBytes 返回空闲空间的字节数
+func (s VolumeStats) FreeBytes() uint64 {
+	return uint64(s.FreeClusters) * uint64(s.BytesPerCluster)
+}
+
+// VolumeStats 按分配位图、FAT 和目录树三种方法统计已用空间
+// 位图条目缺失或被截断时仍然返回 FAT 和目录树的结果；方法之间有差异时记录诊断信息（见 Diagnostics）。
+// 目录树的统计需要遍历整个卷，在大卷上较慢。
+func (fs *ExFATFileSystem) VolumeStats() (VolumeStats, error) {
+	stats := VolumeStats{
+		TotalClusters:    fs.totalClusters,
+		BytesPerCluster:  fs.bytesPerCluster,
+		BootPercentInUse: fs.bootSector.PercentInUse,
+	}
+
+	bitmap := UsageEstimate{Method: UsageFromBitmap}
+	bitmap.UsedClusters, bitmap.Err = fs.usedClusters()
+	fat := UsageEstimate{Method: UsageFromFAT}
+	fat.UsedClusters, fat.Err = fs.fatUsedClusters()
+	tree := UsageEstimate{Method: UsageFromTree}
+	tree.UsedClusters, tree.Err = fs.treeUsedClusters()
+	stats.Estimates = []UsageEstimate{bitmap, fat, tree}
+
+	var primary *UsageEstimate
+	for i := range stats.Estimates {
+		if stats.Estimates[i].Err == nil {
+			primary = &stats.Estimates[i]
+			break
+		}
+	}
+	if primary == nil {
+		return stats, fmt.Errorf("no usage estimate available: %v", bitmap.Err)
+	}
+	stats.Method = primary.Method
+	stats.UsedClusters = primary.UsedClusters
+	stats.FreeClusters = primary.FreeClusters(fs.totalClusters)
+	if fs.totalClusters > 0 {
+		stats.PercentInUse = uint8(uint64(min(stats.UsedClusters, fs.totalClusters)) * 100 / uint64(fs.totalClusters))
+	}
+
+	for _, e := range stats.Estimates {
+		if e.Err != nil || e.Method == primary.Method {
+			continue
+		}
+		// FAT 不记录连续分配的簇，比其他方法少是正常的
+		var diff uint32
+		switch {
+		case e.UsedClusters > primary.UsedClusters && primary.Method != UsageFromFAT:
+			diff = e.UsedClusters - primary.UsedClusters
+		case e.UsedClusters < primary.UsedClusters && e.Method != UsageFromFAT:
+			diff = primary.UsedClusters - e.UsedClusters
+		}
+		if diff > 0 {
+			fs.diagnose("", "%s counts %d used clusters, %s counts %d", e.Method, e.UsedClusters, primary.Method, primary.UsedClusters)
+		}
+		stats.Disagreement = max(stats.Disagreement, diff)
+	}
+	if stats.BootPercentInUse != 0xFF && stats.BootPercentInUse != stats.PercentInUse {
+		fs.diagnose("", "boot sector PercentInUse is %d%%, %s gives %d%%", stats.BootPercentInUse, stats.Method, stats.PercentInUse)
+	}
+	return stats, nil
+}
+
+// fatUsedClusters 统计簇堆中不为 0 的 FAT 项
+// 第一个保留项不是介质描述符 0xFFFFFFF8 时 FAT 已被覆盖（例如被清零），不能用于统计。
+func (fs *ExFATFileSystem) fatUsedClusters() (uint32, error) {
+	if len(fs.fat) < 2 || fs.fat[0] != ReservedCluster {
+		return 0, fmt.Errorf("FAT has been overwritten: media descriptor entry is not 0x%08X", uint32(ReservedCluster))
+	}
+	var used uint32
+	last := min(uint64(fs.totalClusters)+1, uint64(len(fs.fat))-1)
+	for c := uint64(2); c <= last && len(fs.fat) > 2; c++ {
+		if fs.fat[c] != 0 {
+			used++
+		}
+	}
+	return used, nil
+}
+
+// treeUsedClusters 遍历目录树，累加每个条目分配的簇数
+// 位图和大写表按根目录中的条目计入，根目录按 FAT 中的簇链计入。损坏映像中重复引用的目录只计一次。
+func (fs *ExFATFileSystem) treeUsedClusters() (uint32, error) {
+	clusterSize := uint64(fs.bytesPerCluster)
+	clusters := func(size uint64) uint64 {
+		return (size + clusterSize - 1) / clusterSize
+	}
+
+	root := fs.volumeRoot()
+	total, err := fs.chainLength(root.cluster)
+	if err != nil {
+		return 0, fmt.Errorf("root directory: %v", err)
+	}
+	layout, err := fs.RootLayout()
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range layout {
+		if (e.Kind == "bitmap" || e.Kind == "upcase") && e.FirstCluster != 0 {
+			total += clusters(e.DataLength)
+		}
+	}
+
+	visited := make(map[uint32]bool)
+	var sum func(dir *DirEntry) error
+	sum = func(dir *DirEntry) error {
+		if !firstVisit(visited, dir) {
+			return nil
+		}
+		children, err := fs.readDirectoryEntries(dir)
+		if err != nil {
+			return fmt.Errorf("%s: %v", dir.path, err)
+		}
+		for _, child := range children {
+			total += fs.allocatedSize(child) / clusterSize
+			if child.IsDir {
+				if err := sum(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := sum(root); err != nil {
+		return 0, err
+	}
+	return uint32(min(total, uint64(^uint32(0)))), nil
+}
@@ -0,0 +1,202 @@
+package exfat
+
+import (
+	"context"
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// statsImage 返回用于已用空间统计的卷：连续分配（不写 FAT）、碎片化和通过 FAT 连接的文件
+func statsImage() *testimage.Image {
+	fragmented := testimage.File("fragmented.bin", fill(3000, 1))
+	fragmented.Fragmented = true
+	chained := testimage.File("chained.bin", fill(1200, 2))
+	chained.FATChain = true
+	return testimage.Build(testimage.Options{ClusterCount: 200},
+		testimage.Dir("D", testimage.File("contiguous.bin", fill(5000, 3)), fragmented), chained)
+}
+
+// fatEntries 统计映像中簇堆范围内不为 0 的 FAT 项
+func fatEntries(img *testimage.Image) uint32 {
+	var n uint32
+	for c := uint32(2); c < 202; c++ {
+		if img.FAT(c) != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// lastCluster 映像的最后一个簇，statsImage 中远未分配到这里
+const lastCluster = 201
+
+// usageFinding 返回 Check 在根目录上报告的已用空间不一致
+func usageFinding(t *testing.T, fs *ExFATFileSystem) (*VolumeCheck, bool) {
+	t.Helper()
+	result, err := fs.Check(context.Background(), CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range result.Findings {
+		if f.Path == "/" && slices.Contains(f.Anomalies, AnomalyUsageDisagreement) {
+			return result, true
+		}
+	}
+	return result, false
+}
+
+func TestVolumeStatsAgree(t *testing.T) {
+	img := statsImage()
+	fs := openImage(t, img)
+	stats, err := fs.VolumeStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bitmap, fat, tree := stats.Estimates[0], stats.Estimates[1], stats.Estimates[2]
+	if stats.Method != UsageFromBitmap || stats.Disagreement != 0 || bitmap.UsedClusters != tree.UsedClusters ||
+		stats.UsedClusters != bitmap.UsedClusters || stats.FreeClusters != 200-bitmap.UsedClusters {
+		t.Errorf("clean volume: %+v", stats)
+	}
+	// 连续分配的文件不写 FAT，FAT 的结果偏少但不算不一致
+	if fat.UsedClusters != fatEntries(img) || fat.UsedClusters >= bitmap.UsedClusters {
+		t.Errorf("FAT estimate %d, %d entries in use, bitmap %d", fat.UsedClusters, fatEntries(img), bitmap.UsedClusters)
+	}
+	result, found := usageFinding(t, fs)
+	if found || result.Usage == nil || result.Usage.UsedClusters != stats.UsedClusters {
+		t.Errorf("Check on a clean volume: %+v, usage %+v", result.Findings, result.Usage)
+	}
+
+	// 只检查子目录时不统计整个卷
+	sub, err := fs.Check(context.Background(), CheckOptions{Root: "/D"})
+	if err != nil || sub.Usage != nil {
+		t.Errorf("Check of /D: usage %+v, %v", sub.Usage, err)
+	}
+}
+
+func TestVolumeStatsLeakedCluster(t *testing.T) {
+	img := statsImage()
+	clean, err := openImage(t, img).VolumeStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 位图中标记一个没有条目引用的簇：位图比目录树多一个簇
+	c := uint32(lastCluster)
+	img.Bytes[img.ClusterOffset(img.BitmapCluster)+int64(c-2)/8] |= 1 << ((c - 2) % 8)
+
+	fs := openImage(t, img)
+	stats, err := fs.VolumeStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Method != UsageFromBitmap || stats.UsedClusters != clean.UsedClusters+1 || stats.Disagreement != 1 ||
+		stats.Estimates[2].UsedClusters != clean.UsedClusters {
+		t.Errorf("leaked cluster: %+v", stats)
+	}
+	if result, found := usageFinding(t, fs); !found || result.Usage.Disagreement != 1 {
+		t.Errorf("Check did not report the disagreement: %+v", result.Findings)
+	}
+}
+
+func TestVolumeStatsWithoutBitmap(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(img *testimage.Image, slot []byte)
+	}{
+		// 位图条目被清除，或者 DataLength 不足以覆盖所有簇
+		{"removed", func(img *testimage.Image, slot []byte) { slot[0] = 0x01 }},
+		{"truncated", func(img *testimage.Image, slot []byte) { binary.LittleEndian.PutUint64(slot[24:], 3) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := statsImage()
+			clean, err := openImage(t, img).VolumeStats()
+			if err != nil {
+				t.Fatal(err)
+			}
+			slot := img.Bytes[img.ClusterOffset(img.Entry("/").Clusters[0]):][:32]
+			if slot[0] != EntryTypeAllocationBitmap {
+				t.Fatalf("first root slot is 0x%02X", slot[0])
+			}
+			tt.corrupt(img, slot)
+
+			// 退回到 FAT：FAT 少于目录树是连续分配造成的，不算不一致
+			fs := openImage(t, img)
+			stats, err := fs.VolumeStats()
+			if err != nil {
+				t.Fatal(err)
+			}
+			bitmap, fat, tree := stats.Estimates[0], stats.Estimates[1], stats.Estimates[2]
+			if bitmap.Err == nil || fat.Err != nil || tree.Err != nil {
+				t.Fatalf("estimates: %+v", stats.Estimates)
+			}
+			wantTree := clean.Estimates[2].UsedClusters
+			if tt.name == "removed" {
+				wantTree-- // 目录树按根目录中的条目计入位图所在的簇
+			}
+			if stats.Method != UsageFromFAT || stats.UsedClusters != fatEntries(img) || tree.UsedClusters != wantTree ||
+				stats.Disagreement != 0 {
+				t.Errorf("fallback: %+v", stats)
+			}
+			if _, found := usageFinding(t, fs); found {
+				t.Error("Check reported a disagreement for a FAT lower bound")
+			}
+
+			// FAT 中有没有条目引用的项，使 FAT 比目录树多 3 个簇
+			leaked := wantTree - fat.UsedClusters + 3
+			for c := uint32(lastCluster); c > lastCluster-leaked; c-- {
+				img.SetFAT(c, EndOfClusterChain)
+			}
+			fs = openImage(t, img)
+			stats, err = fs.VolumeStats()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Method != UsageFromFAT || stats.UsedClusters != wantTree+3 || stats.Disagreement != 3 {
+				t.Errorf("FAT with leaked entries: %+v", stats)
+			}
+			if result, found := usageFinding(t, fs); !found || result.Usage.Disagreement != 3 {
+				t.Errorf("Check did not report the disagreement: %+v", result.Findings)
+			}
+		})
+	}
+}
+
+func TestVolumeStatsTreeFallback(t *testing.T) {
+	img := statsImage()
+	clean, err := openImage(t, img).VolumeStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 位图条目被清除，FAT 开头的介质描述符也被覆盖：只剩目录树可用，目录树不再计入位图的簇
+	img.Bytes[img.ClusterOffset(img.Entry("/").Clusters[0])] = 0x01
+	clear(img.Bytes[img.FATOffset : img.FATOffset+4])
+	fs := openImage(t, img)
+	stats, err := fs.VolumeStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bitmap, fat, tree := stats.Estimates[0], stats.Estimates[1], stats.Estimates[2]
+	if bitmap.Err == nil || fat.Err == nil || tree.Err != nil {
+		t.Fatalf("estimates: %+v", stats.Estimates)
+	}
+	if stats.Method != UsageFromTree || stats.UsedClusters != clean.UsedClusters-1 || stats.Disagreement != 0 {
+		t.Errorf("tree fallback: %+v", stats)
+	}
+	if result, found := usageFinding(t, fs); found || result.Usage == nil || result.Usage.Method != UsageFromTree {
+		t.Errorf("Check: %+v, usage %+v", result.Findings, result.Usage)
+	}
+
+	// 根目录的簇链也断开：三种方法都不可用，Check 照常检查条目，不报告已用空间
+	root := img.Entry("/").Clusters[0]
+	clear(img.Bytes[img.FATOffset+4*int64(root):][:4])
+	fs = openImage(t, img)
+	if stats, err := fs.VolumeStats(); err == nil {
+		t.Errorf("no estimate available, got %+v", stats)
+	}
+	if result, _ := usageFinding(t, fs); result.Usage != nil || result.Checked == 0 {
+		t.Errorf("Check without estimates: %d checked, usage %+v", result.Checked, result.Usage)
+	}
+}
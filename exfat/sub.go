@@ -0,0 +1,21 @@
+package exfat
+
+import "fmt"
+
+// Sub 返回以 dir 为根目录的视图，与 io/fs.Sub 类似，之后的所有路径都相对于 dir
+// 目录只在创建视图时解析一次，之后的查找从它的起始簇开始；".." 不会越过视图的根目录。
+// 视图与原文件系统共享 FAT、缓存和诊断信息，EntryID 和 FileID 也与在原文件系统中相同。
+func (fs *ExFATFileSystem) Sub(dir string) (*ExFATFileSystem, error) {
+	entry, err := fs.getEntry(normalizePath(dir))
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsDir {
+		return nil, fmt.Errorf("%w: %s", ErrNotDirectory, dir)
+	}
+
+	root := *entry
+	root.Name = "/"
+	root.path = "/"
+	return &ExFATFileSystem{volume: fs.volume, root: &root}, nil
+}
@@ -0,0 +1,101 @@
+package exfat
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+func subImage() *testimage.Image {
+	return testimage.Build(testimage.Options{},
+		testimage.File("outside.txt", []byte("outside")),
+		testimage.Dir("A",
+			testimage.Dir("B",
+				testimage.File("inner.txt", []byte("inner")),
+				testimage.Dir("C", testimage.File("deep.bin", fill(2000, 3))))))
+}
+
+func TestSub(t *testing.T) {
+	fs := openImage(t, subImage())
+	sub, err := fs.Sub("/a/b") // 与其他路径一样不区分大小写
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := sub.ListDir("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := fs.ListDir("/A/B")
+	if !slices.EqualFunc(listed, want, func(a, b FileEntry) bool { return a.Name == b.Name && a.ID() == b.ID() && a.FileID == b.FileID }) {
+		t.Errorf("sub root lists %+v, want %+v", listed, want)
+	}
+
+	for _, p := range []string{"/C/deep.bin", "C/deep.bin", "/C/../C/deep.bin"} {
+		got, err := sub.ReadFile(p)
+		if err != nil || !bytes.Equal(got, fill(2000, 3)) {
+			t.Errorf("ReadFile(%q): %d bytes, %v", p, len(got), err)
+		}
+	}
+
+	// ".." 不会越过视图的根目录
+	for _, p := range []string{"/../outside.txt", "/../../A/B/inner.txt", "/C/../../outside.txt"} {
+		if _, err := sub.Stat(p); !errors.Is(err, ErrNotExist) {
+			t.Errorf("Stat(%q): got %v, want ErrNotExist", p, err)
+		}
+	}
+	if e, err := sub.Stat("/../inner.txt"); err != nil || e.Name != "inner.txt" {
+		t.Errorf("Stat(/../inner.txt): %+v, %v", e, err)
+	}
+
+	root, err := sub.Stat("/")
+	if err != nil || !root.IsDir {
+		t.Errorf("Stat(/): %+v, %v", root, err)
+	}
+
+	// 条目标识与原文件系统相同
+	inSub, _ := sub.Stat("/inner.txt")
+	inFS, _ := fs.Stat("/A/B/inner.txt")
+	if inSub.ID() != inFS.ID() || inSub.FileID != inFS.FileID {
+		t.Errorf("IDs differ: %s/%d in the view, %s/%d in the file system", inSub.ID(), inSub.FileID, inFS.ID(), inFS.FileID)
+	}
+}
+
+func TestSubNested(t *testing.T) {
+	fs := openImage(t, subImage())
+	a, err := fs.Sub("A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := a.Sub("/B/C/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.ReadFile("/deep.bin"); err != nil || !bytes.Equal(got, fill(2000, 3)) {
+		t.Errorf("ReadFile: %d bytes, %v", len(got), err)
+	}
+	if _, err := c.Stat("/../../outside.txt"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("nested view escaped its root: %v", err)
+	}
+	// 视图的根目录再取 Sub 等于自身
+	same, err := c.Sub("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := same.ListDir("/"); len(entries) != 1 || entries[0].Name != "deep.bin" {
+		t.Errorf("Sub(/) lists %+v", entries)
+	}
+}
+
+func TestSubErrors(t *testing.T) {
+	fs := openImage(t, subImage())
+	if _, err := fs.Sub("/outside.txt"); !errors.Is(err, ErrNotDirectory) {
+		t.Errorf("Sub of a file: got %v, want ErrNotDirectory", err)
+	}
+	if _, err := fs.Sub("/missing"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Sub of a missing directory: got %v, want ErrNotExist", err)
+	}
+}
@@ -0,0 +1,82 @@
+package exfat
+
+import (
+	"fmt"
+	"time"
+)
+
+// exfatTimeToTime 转换 exFAT 时间戳为 Go time.Time
+// 每个时间戳都有自己的 UTC 偏移字节：最高位表示偏移有效，低 7 位是以 15 分钟为单位的有符号偏移。
+// 偏移无效时（旧的实现不写入该字段）按本地时间解释。
+// increment 是创建和修改时间附带的 10 毫秒增量（0 到 199），在 2 秒精度的时间上补足毫秒；超出范围时忽略。
+func exfatTimeToTime(timestamp uint32, increment uint8, utcOffset uint8) time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+
+	date := timestamp >> 16
+	tm := timestamp & 0xFFFF
+
+	year := int((date>>9)&0x7F) + 1980
+	month := time.Month((date >> 5) & 0x0F)
+	day := int(date & 0x1F)
+	hour := int((tm >> 11) & 0x1F)
+	minute := int((tm >> 5) & 0x3F)
+	second := int(tm&0x1F) * 2
+
+	if month < 1 || month > 12 || day < 1 || day > 31 ||
+		hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}
+	}
+	t := time.Date(year, month, day, hour, minute, second, 0, utcOffsetLocation(utcOffset))
+	if increment <= 199 {
+		t = t.Add(time.Duration(increment) * 10 * time.Millisecond)
+	}
+	return t
+}
+
+// exfatAccessTimeToTime 转换最后访问时间戳
+// 访问时间没有 10 毫秒增量字段，只有 2 秒精度（仍有自己的 UTC 偏移）。
+func exfatAccessTimeToTime(timestamp uint32, utcOffset uint8) time.Time {
+	return exfatTimeToTime(timestamp, 0, utcOffset)
+}
+
+// utcOffsetLocation 把 UTC 偏移字节转换为时区
+func utcOffsetLocation(utcOffset uint8) *time.Location {
+	if utcOffset&0x80 == 0 {
+		return time.Local
+	}
+
+	// 低 7 位是补码表示的有符号数
+	minutes := int(int8(utcOffset<<1)>>1) * 15
+	if minutes == 0 {
+		return time.UTC
+	}
+
+	sign := '+'
+	abs := minutes
+	if minutes < 0 {
+		sign, abs = '-', -minutes
+	}
+	return time.FixedZone(fmt.Sprintf("UTC%c%02d:%02d", sign, abs/60, abs%60), minutes*60)
+}
+
+// timeToExfat 把 time.Time 编码为 exFAT 时间戳、10 毫秒增量和 UTC 偏移字节，是 exfatTimeToTime 的逆运算
+// 时间按 t 自身的时区写入并记录偏移；偏移不是 15 分钟的整数倍时改用 UTC。
+// exFAT 时间戳只能表示 1980 到 2107 年。
+func timeToExfat(t time.Time) (timestamp uint32, increment uint8, utcOffset uint8, err error) {
+	_, offset := t.Zone()
+	if offset%(15*60) != 0 || offset < -64*15*60 || offset > 63*15*60 {
+		t, offset = t.UTC(), 0
+	}
+	if t.Year() < 1980 || t.Year() > 2107 {
+		return 0, 0, 0, fmt.Errorf("time %v is outside the exFAT range 1980-2107", t)
+	}
+
+	date := uint32(t.Year()-1980)<<9 | uint32(t.Month())<<5 | uint32(t.Day())
+	tm := uint32(t.Hour())<<11 | uint32(t.Minute())<<5 | uint32(t.Second()/2)
+	timestamp = date<<16 | tm
+	increment = uint8(t.Second()%2*100 + t.Nanosecond()/int(10*time.Millisecond))
+	utcOffset = 0x80 | uint8(offset/(15*60))&0x7F
+	return timestamp, increment, utcOffset, nil
+}
@@ -0,0 +1,121 @@
+package exfat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+func TestUTCOffsetLocation(t *testing.T) {
+	tests := []struct {
+		offset  uint8
+		seconds int
+		local   bool
+	}{
+		{0x00, 0, true}, // 偏移无效
+		{0x7F, 0, true}, // 偏移无效，低 7 位被忽略
+		{0x80, 0, false},
+		{0x80 | 4, 3600, false},           // UTC+01:00
+		{0x80 | 0x7C, -3600, false},       // UTC-01:00
+		{0x80 | 22, 5*3600 + 1800, false}, // UTC+05:30
+		{0x80 | 0x3F, 63 * 15 * 60, false},
+		{0x80 | 0x40, -64 * 15 * 60, false},
+	}
+	for _, tt := range tests {
+		loc := utcOffsetLocation(tt.offset)
+		if tt.local {
+			if loc != time.Local {
+				t.Errorf("offset 0x%02X: got %v, want local time", tt.offset, loc)
+			}
+			continue
+		}
+		if _, got := time.Date(2020, 1, 1, 0, 0, 0, 0, loc).Zone(); got != tt.seconds {
+			t.Errorf("offset 0x%02X: got %d seconds, want %d", tt.offset, got, tt.seconds)
+		}
+	}
+}
+
+func TestTimestampsKeepTheirOwnOffsets(t *testing.T) {
+	tokyo := time.FixedZone("", 9*3600)
+	newYork := time.FixedZone("", -5*3600)
+	kolkata := time.FixedZone("", 5*3600+1800)
+	tests := []struct {
+		name                        string
+		created, modified, accessed time.Time
+	}{
+		{"three zones",
+			time.Date(2021, 1, 2, 3, 4, 6, 120e6, tokyo),
+			time.Date(2022, 7, 8, 9, 10, 12, 990e6, newYork),
+			time.Date(2023, 11, 12, 13, 14, 16, 0, kolkata)},
+		{"UTC and negative",
+			time.Date(2020, 2, 29, 23, 59, 58, 0, time.UTC),
+			time.Date(2020, 3, 1, 0, 0, 0, 10e6, newYork),
+			time.Date(2020, 3, 1, 0, 0, 2, 0, time.UTC)},
+		{"same instant, different zones",
+			time.Date(2024, 6, 1, 12, 0, 0, 0, tokyo),
+			time.Date(2024, 6, 1, 12, 0, 0, 0, tokyo).In(newYork),
+			time.Date(2024, 6, 1, 12, 0, 0, 0, tokyo).In(kolkata)},
+	}
+	for _, tt := range tests {
+		file := testimage.File("f", nil)
+		file.Created, file.Modified, file.Accessed = tt.created, tt.modified, tt.accessed
+		fs := openImage(t, testimage.Build(testimage.Options{}, file))
+		e, err := fs.Stat("/f")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range []struct {
+			field     string
+			got, want time.Time
+		}{
+			{"CreateTime", e.CreateTime, tt.created},
+			{"ModTime", e.ModTime, tt.modified},
+			{"AccessTime", e.AccessTime, tt.accessed},
+		} {
+			_, gotOffset := c.got.Zone()
+			_, wantOffset := c.want.Zone()
+			if !c.got.Equal(c.want) || gotOffset != wantOffset {
+				t.Errorf("%s: %s is %v, want %v", tt.name, c.field, c.got, c.want)
+			}
+		}
+	}
+}
+
+func TestAccessTimeResolution(t *testing.T) {
+	zone := time.FixedZone("", 2*3600)
+	stamp := time.Date(2023, 11, 12, 13, 14, 17, 990e6, zone)
+	file := testimage.File("f", nil)
+	file.Created, file.Modified, file.Accessed = stamp, stamp, stamp
+	fs := openImage(t, testimage.Build(testimage.Options{}, file))
+	e, err := fs.Stat("/f")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 创建和修改时间用 10 毫秒增量补足到 10 毫秒精度
+	if !e.CreateTime.Equal(stamp) || !e.ModTime.Equal(stamp) {
+		t.Errorf("create %v, modify %v; want %v", e.CreateTime, e.ModTime, stamp)
+	}
+	// 访问时间没有增量字段，只有 2 秒精度：奇数秒和毫秒都被截去
+	if want := time.Date(2023, 11, 12, 13, 14, 16, 0, zone); !e.AccessTime.Equal(want) {
+		t.Errorf("access %v, want %v", e.AccessTime, want)
+	}
+
+	ts, increment, offset, err := timeToExfat(stamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if increment != 199 {
+		t.Errorf("increment %d, want 199", increment)
+	}
+	for i, c := range []struct{ got, want time.Time }{
+		{exfatTimeToTime(ts, increment, offset), stamp},
+		{exfatTimeToTime(ts, 200, offset), stamp.Truncate(2 * time.Second)}, // 超出范围的增量被忽略
+		{exfatAccessTimeToTime(ts, offset), stamp.Truncate(2 * time.Second)},
+	} {
+		if !c.got.Equal(c.want) {
+			t.Errorf("case %d: got %v, want %v", i, c.got, c.want)
+		}
+	}
+}
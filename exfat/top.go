@@ -0,0 +1,140 @@
+package exfat
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Consumer 描述一个占用空间较多的文件或目录
+type Consumer struct {
+	Path    string  // 完整路径
+	IsDir   bool    // 是否为目录（目录的大小为递归汇总的大小）
+	Size    uint64  // 字节数（逻辑大小或已分配大小）
+	Percent float64 // 占卷已用空间（按分配位图统计）的百分比
+}
+
+// TopConsumers 返回 root 下最大的 n 个文件和最大的 n 个目录（按递归大小）
+// byAllocated 为 true 时按已分配的簇计算大小，否则按文件的逻辑大小。
+// 结果中文件在前、目录在后，各自按大小降序排列，大小相同时按路径排序，保证输出稳定。
+// 整个统计只遍历一次目录树，内存占用为 O(n)。
+func (fs *ExFATFileSystem) TopConsumers(root string, n int, byAllocated bool) ([]Consumer, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	rootEntry, err := fs.getEntry(normalizePath(root))
+	if err != nil {
+		return nil, err
+	}
+
+	files := &consumerHeap{}
+	dirs := &consumerHeap{}
+	push := func(h *consumerHeap, c Consumer) {
+		if h.Len() < n {
+			heap.Push(h, c)
+		} else if consumerLess((*h)[0], c) {
+			(*h)[0] = c
+			heap.Fix(h, 0)
+		}
+	}
+
+	size := func(e *DirEntry) uint64 {
+		if byAllocated {
+			return fs.allocatedSize(e)
+		}
+		if e.IsDir {
+			return 0
+		}
+		return uint64(e.Size)
+	}
+
+	visited := make(map[uint32]bool)
+	var sum func(dir *DirEntry) (uint64, error)
+	sum = func(dir *DirEntry) (uint64, error) {
+		total := size(dir)
+		if !firstVisit(visited, dir) {
+			return total, nil
+		}
+
+		children, err := fs.readDirectoryEntries(dir)
+		if err != nil {
+			return 0, err
+		}
+		for _, child := range children {
+			if child.IsDir {
+				childTotal, err := sum(child)
+				if err != nil {
+					return 0, err
+				}
+				total += childTotal
+				continue
+			}
+			childSize := size(child)
+			push(files, Consumer{Path: child.path, Size: childSize})
+			total += childSize
+		}
+
+		push(dirs, Consumer{Path: dir.path, IsDir: true, Size: total})
+		return total, nil
+	}
+
+	if rootEntry.IsDir {
+		if _, err := sum(rootEntry); err != nil {
+			return nil, err
+		}
+	} else {
+		push(files, Consumer{Path: rootEntry.path, Size: size(rootEntry)})
+	}
+
+	// 按分配位图计算百分比；位图不可用时百分比保持为 0
+	var used uint64
+	if clusters, err := fs.usedClusters(); err == nil {
+		used = uint64(clusters) * uint64(fs.bytesPerCluster)
+	}
+
+	result := append(files.sorted(), dirs.sorted()...)
+	for i := range result {
+		if used > 0 {
+			result[i].Percent = float64(result[i].Size) * 100 / float64(used)
+		}
+	}
+	return result, nil
+}
+
+// allocatedSize 返回条目实际占用的簇空间
+func (fs *ExFATFileSystem) allocatedSize(e *DirEntry) uint64 {
+	if !e.hasClusters() || e.Size <= 0 {
+		return 0
+	}
+	clusterSize := uint64(fs.bytesPerCluster)
+	return (uint64(e.Size) + clusterSize - 1) / clusterSize * clusterSize
+}
+
+// consumerLess 判断 a 是否排在 b 之后（更小，或大小相同时路径更大）
+func consumerLess(a, b Consumer) bool {
+	if a.Size != b.Size {
+		return a.Size < b.Size
+	}
+	return a.Path > b.Path
+}
+
+// consumerHeap 保留最大的若干项的最小堆
+type consumerHeap []Consumer
+
+func (h consumerHeap) Len() int            { return len(h) }
+func (h consumerHeap) Less(i, j int) bool  { return consumerLess(h[i], h[j]) }
+func (h consumerHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *consumerHeap) Push(x interface{}) { *h = append(*h, x.(Consumer)) }
+func (h *consumerHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// sorted 按从大到小返回堆中的项
+func (h *consumerHeap) sorted() []Consumer {
+	result := append([]Consumer(nil), (*h)...)
+	sort.Slice(result, func(i, j int) bool { return consumerLess(result[j], result[i]) })
+	return result
+}
@@ -0,0 +1,239 @@
+package exfat
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 读取的用途（见 WithReadTracer）
+const (
+	TraceBoot   = "boot"   // 引导扇区和引导区
+	TraceFAT    = "fat"    // FAT
+	TraceBitmap = "bitmap" // 分配位图
+	TraceUpcase = "upcase" // 大写表
+	TraceDir    = "dir"    // 目录数据
+	TraceData   = "data"   // 文件数据
+)
+
+// TraceLayer 文件系统层在读取跟踪中的层名
+const TraceLayer = "exfat"
+
+// ReadTracer 接收一次对底层数据的读取：所在的层、层内的逻辑偏移、下一层的物理偏移、长度和用途
+// 与 container.ReadTracer 的参数相同，两层可以共用同一个函数记录完整的读取路径。
+type ReadTracer func(layer string, logical, physical, length int64, purpose string)
+
+// WithReadTracer 在文件系统每次读取卷时调用 fn，用于调试偏移换算和报告读错数据的问题
+// 层名为 TraceLayer，逻辑偏移与物理偏移都是卷内的字节偏移，用途为 Trace* 常量。
+// fn 为 nil 时没有额外开销；fn 可能被多个 goroutine 同时调用。
+func WithReadTracer(fn ReadTracer) Option {
+	return func(o *options) {
+		o.tracer = fn
+	}
+}
+
+// readAt 从卷的 off 处读取，启用了读取跟踪时先记录
+func (fs *ExFATFileSystem) readAt(p []byte, off int64, purpose string) (int, error) {
+	if fs.opts.tracer != nil {
+		fs.opts.tracer(TraceLayer, off, off, int64(len(p)), purpose)
+	}
+	return fs.vhd.ReadAt(p, off)
+}
+
+// tracedReader 以固定的用途记录每次读取，用于文件系统建立之前读取引导区
+type tracedReader struct {
+	r       io.ReaderAt
+	tracer  ReadTracer
+	purpose string
+}
+
+func (t tracedReader) ReadAt(p []byte, off int64) (int, error) {
+	t.tracer(TraceLayer, off, off, int64(len(p)), t.purpose)
+	return t.r.ReadAt(p, off)
+}
+
+// TraceRecord 读取跟踪中的一条记录，以 NDJSON 的一行写出
+type TraceRecord struct {
+	Layer    string `json:"layer"`
+	Logical  int64  `json:"logical"`
+	Physical int64  `json:"physical"`
+	Length   int64  `json:"length"`
+	Purpose  string `json:"purpose"`
+}
+
+// traceLine 跟踪中的一行：模式标识和记录
+type traceLine struct {
+	Envelope
+	TraceRecord
+}
+
+// decodeTraceLine 解码跟踪中的一行
+// 版本 0（没有 schema 字段）与版本 1 的字段相同。
+func decodeTraceLine(data []byte) (TraceRecord, error) {
+	var l traceLine
+	if err := json.Unmarshal(data, &l); err != nil {
+		return TraceRecord{}, err
+	}
+	if _, err := CheckSchema(l.Schema, SchemaTrace); err != nil {
+		return TraceRecord{}, err
+	}
+	return l.TraceRecord, nil
+}
+
+// DecodeTrace 读取 WithReadTracing 写出的跟踪，按顺序返回记录
+// 空行被跳过；某一行无法解析或模式不符（见 CheckSchema）时返回带行号的错误。
+func DecodeTrace(r io.Reader) ([]TraceRecord, error) {
+	var records []TraceRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		rec, err := decodeTraceLine(scanner.Bytes())
+		if err != nil {
+			return records, fmt.Errorf("trace line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// FSInfo TraceCheck 检查读取位置时使用的卷布局，字节为单位
+type FSInfo struct {
+	BytesPerSector    int64
+	BytesPerCluster   int64
+	FATOffset         int64 // 第一个 FAT 的偏移
+	FATLength         int64 // 每个 FAT 的长度
+	NumberOfFATs      int64
+	ClusterHeapOffset int64
+	ClusterCount      int64
+	VolumeLength      int64
+}
+
+// FSInfo 返回卷的布局，供 TraceCheck 使用
+func (fs *ExFATFileSystem) FSInfo() FSInfo {
+	b := fs.bootSector
+	sector := int64(fs.bytesPerSector)
+	return FSInfo{
+		BytesPerSector:    sector,
+		BytesPerCluster:   int64(fs.bytesPerCluster),
+		FATOffset:         int64(b.FatOffset) * sector,
+		FATLength:         int64(b.FatLength) * sector,
+		NumberOfFATs:      int64(b.NumberOfFats),
+		ClusterHeapOffset: int64(fs.clusterHeapStart),
+		ClusterCount:      int64(fs.totalClusters),
+		VolumeLength:      int64(b.VolumeLength) * sector,
+	}
+}
+
+// TraceFinding TraceCheck 发现的一次可疑读取
+type TraceFinding struct {
+	Line    int         // 记录在跟踪中的行号（从 1 开始）
+	Record  TraceRecord // 可疑的读取
+	Problem string      // 问题描述
+}
+
+// String 返回发现的单行描述
+func (f TraceFinding) String() string {
+	r := f.Record
+	if r.Layer == "" {
+		// 无法解析的行没有记录
+		return fmt.Sprintf("line %d: %s", f.Line, f.Problem)
+	}
+	return fmt.Sprintf("line %d: %s %s read of %d bytes at %d (physical %d): %s", f.Line, r.Layer, r.Purpose, r.Length, r.Logical, r.Physical, f.Problem)
+}
+
+// traceRegion 卷中的一个区域
+type traceRegion struct {
+	name       string
+	start, end int64
+}
+
+// regions 按偏移顺序返回卷的区域：引导区、FAT、簇堆之前的空隙、簇堆和簇堆之后的部分
+func (info FSInfo) regions() []traceRegion {
+	fatEnd := info.FATOffset + info.FATLength*max(info.NumberOfFATs, 1)
+	heapEnd := info.ClusterHeapOffset + info.ClusterCount*info.BytesPerCluster
+	return []traceRegion{
+		{"boot region", 0, info.FATOffset},
+		{"FAT region", info.FATOffset, fatEnd},
+		{"gap before the cluster heap", fatEnd, info.ClusterHeapOffset},
+		{"cluster heap", info.ClusterHeapOffset, heapEnd},
+		{"space after the cluster heap", heapEnd, max(info.VolumeLength, heapEnd)},
+	}
+}
+
+// regionAt 返回 off 所在区域的名称，超出卷时返回 "outside the volume"
+func (info FSInfo) regionAt(off int64) string {
+	for _, r := range info.regions() {
+		if off >= r.start && off < r.end {
+			return r.name
+		}
+	}
+	return "outside the volume"
+}
+
+// expectedRegion 各用途的读取应当落在的区域
+var expectedRegion = map[string]string{
+	TraceBoot:   "boot region",
+	TraceFAT:    "FAT region",
+	TraceBitmap: "cluster heap",
+	TraceUpcase: "cluster heap",
+	TraceDir:    "cluster heap",
+	TraceData:   "cluster heap",
+}
+
+// TraceCheck 分析 WithReadTracing 写出的 NDJSON 跟踪，返回可疑的读取
+// 文件系统层的读取跨越区域边界（如从 FAT 读到簇堆）、落在与用途不符的区域（如标为数据的读取与 FAT 重叠），
+// 或者引导区和 FAT 的读取没有按扇区对齐时给出发现；容器层（VHD、分区）的读取在换算前后的偏移之差
+// 不是 512 字节的整数倍时给出发现，这通常说明块转换或分区基址算错了。无法解析或模式不符的行同样作为发现返回。
+func TraceCheck(trace io.Reader, info FSInfo) []TraceFinding {
+	var findings []TraceFinding
+	sector := max(info.BytesPerSector, 1)
+	scanner := bufio.NewScanner(trace)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		r, err := decodeTraceLine(scanner.Bytes())
+		if errors.Is(err, ErrSchema) {
+			findings = append(findings, TraceFinding{Line: line, Problem: err.Error()})
+			continue
+		}
+		if err != nil {
+			findings = append(findings, TraceFinding{Line: line, Problem: fmt.Sprintf("unparsable record: %v", err)})
+			continue
+		}
+		add := func(format string, args ...interface{}) {
+			findings = append(findings, TraceFinding{Line: line, Record: r, Problem: fmt.Sprintf(format, args...)})
+		}
+
+		if r.Layer != TraceLayer {
+			// 容器的扇区可能小于卷的扇区（如 4K 扇区的卷放在 512 字节扇区的 VHD 中），按最小的扇区检查
+			if (r.Physical-r.Logical)%512 != 0 {
+				add("translation shifts the offset by %d bytes, not a multiple of the 512-byte sector", r.Physical-r.Logical)
+			}
+			continue
+		}
+		if r.Length <= 0 {
+			continue
+		}
+		first, last := info.regionAt(r.Logical), info.regionAt(r.Logical+r.Length-1)
+		if first != last {
+			add("crosses from the %s into the %s", first, last)
+		} else if want, ok := expectedRegion[r.Purpose]; ok && first != want {
+			add("%s read lies in the %s, expected the %s", r.Purpose, first, want)
+		}
+		if (r.Purpose == TraceBoot || r.Purpose == TraceFAT) && r.Logical%sector != 0 {
+			add("not aligned to the %d-byte sector", sector)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		findings = append(findings, TraceFinding{Problem: fmt.Sprintf("failed to read trace: %v", err)})
+	}
+	return findings
+}
@@ -0,0 +1,143 @@
+package exfat
+
+import (
+	"io"
+	"sync"
+)
+
+// exFAT 目录条目类型
+const (
+	EntryTypeEndOfDirectory   = 0x00
+	EntryTypeFile             = 0x85
+	EntryTypeVolumeLabel      = 0x83
+	EntryTypeAllocationBitmap = 0x81
+	EntryTypeUpcaseTable      = 0x82
+	EntryTypeFileInfo         = 0xC0
+	EntryTypeFileName         = 0xC1
+)
+
+// EntryInUse 类型字节中的 InUse 位；删除条目时清除这一位，如已删除的文件条目为 0x05
+const EntryInUse = 0x80
+
+// 特殊簇值
+const (
+	EndOfClusterChain = 0xFFFFFFFF
+	BadCluster        = 0xFFFFFFF7
+	ReservedCluster   = 0xFFFFFFF8
+	FAT32EndOfChain   = 0x0FFFFFFF // 部分控制器写入的 FAT32 风格链结束标记（非标准）
+)
+
+// MaxNameLength 文件名的最大长度（UTF-16 字符数）
+const MaxNameLength = 255
+
+// 次要条目 GeneralSecondaryFlags 标志位
+const (
+	AllocationPossibleFlag = 0x01 // 已分配簇
+	NoFatChainFlag         = 0x02 // 簇连续，不使用 FAT 链
+)
+
+// ExFATBootSector exFAT 引导扇区结构
+type ExFATBootSector struct {
+	JmpBoot                [3]byte   // 跳转指令
+	FileSystemName         [8]byte   // "EXFAT   "
+	Reserved1              [53]byte  // 保留
+	PartitionOffset        uint64    // 分区偏移
+	VolumeLength           uint64    // 卷长度
+	FatOffset              uint32    // FAT 偏移
+	FatLength              uint32    // FAT 长度
+	ClusterHeapOffset      uint32    // 簇堆偏移
+	ClusterCount           uint32    // 簇数量
+	FirstClusterOfRootDir  uint32    // 根目录第一个簇
+	VolumeSerialNumber     uint32    // 卷序列号
+	FileSystemRevision     uint16    // 文件系统版本
+	VolumeFlags            uint16    // 卷标志
+	BytesPerSectorShift    uint8     // 每扇区字节数的位移
+	SectorsPerClusterShift uint8     // 每簇扇区数的位移
+	NumberOfFats           uint8     // FAT 数量
+	DriveSelect            uint8     // 驱动器选择
+	PercentInUse           uint8     // 使用百分比
+	Reserved2              [7]byte   // 保留
+	BootCode               [390]byte // 引导代码
+	BootSignature          uint16    // 引导签名 (0xAA55)
+}
+
+// ExFATFileEntry exFAT 文件目录条目
+type ExFATFileEntry struct {
+	EntryType                 uint8
+	SecondaryCount            uint8
+	SetChecksum               uint16
+	FileAttributes            Attributes
+	Reserved1                 uint16
+	CreateTimestamp           uint32
+	LastModifiedTimestamp     uint32
+	LastAccessedTimestamp     uint32
+	Create10msIncrement       uint8
+	LastModified10msIncrement uint8
+	CreateUtcOffset           uint8
+	LastModifiedUtcOffset     uint8
+	LastAccessedUtcOffset     uint8
+	Reserved2                 [7]byte
+}
+
+// ExFATFileInfoEntry exFAT 文件信息条目
+type ExFATFileInfoEntry struct {
+	EntryType             uint8
+	GeneralSecondaryFlags uint8
+	Reserved1             uint8
+	NameLength            uint8
+	NameHash              uint16
+	Reserved2             uint16
+	ValidDataLength       uint64
+	Reserved3             uint32
+	FirstCluster          uint32
+	DataLength            uint64
+}
+
+// ExFATFileNameEntry exFAT 文件名条目
+type ExFATFileNameEntry struct {
+	EntryType             uint8
+	GeneralSecondaryFlags uint8
+	FileName              [30]byte // UTF-16LE 编码的文件名
+}
+
+// ExFATFileSystem 表示 exFAT 文件系统
+// 通过 Sub 得到的视图与原文件系统共享同一个 volume，只有根目录不同。
+type ExFATFileSystem struct {
+	*volume
+	root *DirEntry // 视图的根目录，为 nil 时是卷的根目录
+}
+
+// volume 卷的解析状态，由同一个卷的所有视图共享
+type volume struct {
+	vhd               io.ReaderAt
+	bootSector        *ExFATBootSector
+	bytesPerSector    uint32
+	sectorsPerCluster uint32
+	bytesPerCluster   uint32
+	fat               []uint32 // 活动 FAT
+	activeFAT         int      // 活动 FAT 的序号（0 或 1，见 VolumeFlags 的 ActiveFat 位）
+	clusterHeapStart  uint64
+	reserved          []region // 与簇堆重叠的引导区和 FAT，其中的簇不能读取（见 checkRegions）
+	totalClusters     uint32
+	usedBackupBoot    bool               // 是否使用了备份引导区
+	usedCache         bool               // FAT 是否来自元数据缓存
+	fat32EOC          bool               // 把 FAT32EndOfChain 视为链结束（见 WithFAT32StyleEOC）
+	conformance       []ConformanceIssue // 打开时发现的偏离规范的写法（见 Conformance）
+	opts              *options
+	diag              diagnostics
+
+	bitmapOnce sync.Once // 分配位图只读取一次
+	bitmap     []byte
+	bitmapErr  error
+
+	upcaseOnce sync.Once // 大写表只读取一次
+	upcase     []uint16  // 展开后的大写表
+	upcaseErr  error
+
+	altFATOnce sync.Once // 非活动 FAT 只在需要回退时读取一次
+	altFAT     []uint32
+
+	claims claims // 读取时已被文件占用的簇，见 WithCrosslinkDetection
+
+	buffers sync.Pool // 可复用的簇大小缓冲区，见 getBuffer
+}
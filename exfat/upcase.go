@@ -0,0 +1,110 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// upcaseTable 读取（并缓存）根目录中的大写表
+func (fs *ExFATFileSystem) upcaseTable() ([]uint16, error) {
+	fs.upcaseOnce.Do(func() {
+		fs.upcase, fs.upcaseErr = fs.readUpcaseTable()
+	})
+	return fs.upcase, fs.upcaseErr
+}
+
+// upcaseName 按卷上的大写表把名称转为大写，用于像 exFAT 一样不区分大小写地比较文件名
+// 大写表无法读取时按 Unicode 简单大写规则，与校验名称哈希时的后备规则相同。
+func (fs *ExFATFileSystem) upcaseName(name string) string {
+	table, _ := fs.upcaseTable()
+	return upcaseWith(table, name)
+}
+
+// upcaseWith 按大写表 table 逐个 UTF-16 码元转换名称，table 为 nil 时按 Unicode 简单大写规则
+// 与规范一样只映射基本多文种平面的字符，代理对保持不变。
+func upcaseWith(table []uint16, name string) string {
+	units := utf16.Encode([]rune(name))
+	for i, u := range units {
+		if table != nil {
+			units[i] = table[u]
+		} else {
+			units[i] = upcaseUnicode(u)
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// readUpcaseTable 在根目录中查找大写表条目，校验并解压大写表
+func (fs *ExFATFileSystem) readUpcaseTable() ([]uint16, error) {
+	buf, err := fs.readDirectoryData(fs.volumeRoot())
+	if err != nil {
+		return nil, err
+	}
+	defer fs.putBuffer(buf)
+	rootData := *buf
+
+	for offset := 0; offset+32 <= len(rootData); offset += 32 {
+		entryType := rootData[offset]
+		if entryType == EntryTypeEndOfDirectory {
+			break
+		}
+		if entryType != EntryTypeUpcaseTable {
+			continue
+		}
+
+		checksum := binary.LittleEndian.Uint32(rootData[offset+4:])
+		firstCluster := binary.LittleEndian.Uint32(rootData[offset+20:])
+		dataLength := binary.LittleEndian.Uint64(rootData[offset+24:])
+		// 完整的大写表为 128 KiB，压缩形式更小；过大的长度说明条目已损坏
+		if dataLength == 0 || dataLength > 2*0x10000 || dataLength%2 != 0 {
+			return nil, fmt.Errorf("invalid upcase table length: %d", dataLength)
+		}
+
+		data, err := fs.readClusterChain(firstCluster, dataLength, false, TraceUpcase)
+		if err != nil {
+			return nil, err
+		}
+		if sum := upcaseChecksum(data); sum != checksum {
+			return nil, fmt.Errorf("upcase table checksum mismatch: computed %08X, recorded %08X", sum, checksum)
+		}
+		return decompressUpcaseTable(data), nil
+	}
+
+	return nil, fmt.Errorf("upcase table entry not found in root directory")
+}
+
+// decompressUpcaseTable 把大写表展开为 65536 项的映射
+// 压缩形式中 0xFFFF 后跟一个计数，表示接下来这么多个字符映射到自身；
+// 未压缩的表没有这种标记，按原样展开。表未覆盖的字符映射到自身。
+func decompressUpcaseTable(data []byte) []uint16 {
+	table := make([]uint16, 0x10000)
+	index := 0
+	for i := 0; i+1 < len(data) && index < len(table); i += 2 {
+		v := binary.LittleEndian.Uint16(data[i:])
+		if v == 0xFFFF && i+3 < len(data) {
+			count := int(binary.LittleEndian.Uint16(data[i+2:]))
+			for ; count > 0 && index < len(table); count-- {
+				table[index] = uint16(index)
+				index++
+			}
+			i += 2
+			continue
+		}
+		table[index] = v
+		index++
+	}
+	for ; index < len(table); index++ {
+		table[index] = uint16(index)
+	}
+	return table
+}
+
+// upcaseChecksum 计算大写表数据（压缩形式）的校验和
+func upcaseChecksum(data []byte) uint32 {
+	var sum uint32
+	for _, b := range data {
+		sum = (sum<<31 | sum>>1) + uint32(b)
+	}
+	return sum
+}
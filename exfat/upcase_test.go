@@ -0,0 +1,88 @@
+package exfat
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// encodeUnits 把 UTF-16 码元编码为小端字节
+func encodeUnits(units []uint16) []byte {
+	data := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(data[2*i:], u)
+	}
+	return data
+}
+
+func TestDecompressUpcaseTable(t *testing.T) {
+	// 0x00–0x60 映射到自身，a–z 映射到 A–Z，0x7B–0xDF 映射到自身，à–þ 显式列出（÷ 映射到自身），其余未覆盖
+	units := []uint16{0xFFFF, 0x61}
+	for c := uint16('a'); c <= 'z'; c++ {
+		units = append(units, c-0x20)
+	}
+	units = append(units, 0xFFFF, 0xE0-0x7B)
+	for c := uint16(0xE0); c <= 0xFE; c++ {
+		if c == 0xF7 {
+			units = append(units, c)
+		} else {
+			units = append(units, c-0x20)
+		}
+	}
+	table := decompressUpcaseTable(encodeUnits(units))
+
+	if len(table) != 0x10000 {
+		t.Fatalf("table has %d entries, want 65536", len(table))
+	}
+	for c := 0; c < len(table); c++ {
+		want := uint16(c)
+		if c >= 'a' && c <= 'z' || c >= 0xE0 && c <= 0xFE && c != 0xF7 {
+			want = uint16(c) - 0x20
+		}
+		if table[c] != want {
+			t.Fatalf("table[0x%04X] = 0x%04X, want 0x%04X", c, table[c], want)
+		}
+	}
+}
+
+func TestDecompressUpcaseTableMatchesFlat(t *testing.T) {
+	flat := testimage.UpcaseTable()
+	compressed := testimage.CompressUpcase(flat)
+	if len(compressed) >= len(flat)/10 {
+		t.Fatalf("compressed table has %d units, expected far fewer than %d", len(compressed), len(flat))
+	}
+	if got := decompressUpcaseTable(encodeUnits(compressed)); !slices.Equal(got, flat) {
+		t.Error("compressed table expands to a different mapping")
+	}
+	if got := decompressUpcaseTable(encodeUnits(flat)); !slices.Equal(got, flat) {
+		t.Error("flat table expands to a different mapping")
+	}
+	// 0xFFFF 是最后一个码元时没有计数，按普通映射处理
+	if got := decompressUpcaseTable(encodeUnits([]uint16{0xFFFF})); got[0] != 0xFFFF || got[1] != 1 {
+		t.Errorf("trailing 0xFFFF: got 0x%04X 0x%04X", got[0], got[1])
+	}
+}
+
+func TestCompressedUpcaseOnVolume(t *testing.T) {
+	for _, flat := range []bool{false, true} {
+		img := testimage.Build(testimage.Options{FlatUpcase: flat},
+			testimage.Dir("Ёлка", testimage.File("Straße.txt", []byte("x"))))
+		fs := openImage(t, img)
+		table, err := fs.upcaseTable()
+		if err != nil {
+			t.Fatalf("flat %v: %v", flat, err)
+		}
+		if !slices.Equal(table, img.Upcase) {
+			t.Errorf("flat %v: volume table differs from the one written", flat)
+		}
+		// 非 ASCII 名称按卷上的大写表不区分大小写地查找
+		if _, err := fs.Stat("/ЁЛКА/STRASSE.TXT"); err == nil {
+			t.Errorf("flat %v: ß matched SS, which the up-case table does not map", flat)
+		}
+		if e, err := fs.Stat("/ёлка/STRAßE.TXT"); err != nil || e.Name != "Straße.txt" {
+			t.Errorf("flat %v: Stat: %+v, %v", flat, e, err)
+		}
+	}
+}
@@ -2,9 +2,8 @@ package exfat
 
 import (
 	"fmt"
-	"os"
+	"path"
 	"strings"
-	"time"
 )
 
 // FormatFileSize 格式化文件大小显示
@@ -31,15 +30,8 @@ func FormatFileSize(size int64) string {
 }
 
 // normalizePath 标准化路径，确保使用正斜杠并以斜杠开头
+// "." 和 ".." 按词法处理，".." 不会越过根目录（包括 Sub 得到的视图的根目录）
 func normalizePath(p string) string {
 	p = strings.ReplaceAll(p, "\\", "/")
-	if !strings.HasPrefix(p, "/") {
-		p = "/" + p
-	}
-	return p
-}
-
-// setFileModTime 设置文件的修改时间
-func setFileModTime(path string, modTime time.Time) error {
-	return os.Chtimes(path, modTime, modTime)
+	return path.Clean("/" + p)
 }
@@ -0,0 +1,78 @@
+package exfat
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// readerOnly 隐藏 Size 方法，映像大小未知
+type readerOnly struct{ d *testimage.Disk }
+
+func (r readerOnly) ReadAt(p []byte, off int64) (int, error) { return r.d.ReadAt(p, off) }
+
+func TestCheckVolumeSize(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, testimage.File("a.txt", []byte("still readable")))
+	full := int64(len(img.Bytes))
+	truncated := &testimage.Disk{Data: img.Bytes[:full-64<<10]}
+
+	fs, err := NewExFATFileSystem(truncated)
+	if err != nil {
+		t.Fatalf("lenient: %v", err)
+	}
+	if fs.VolumeSize() != uint64(full) {
+		t.Errorf("VolumeSize %d, want %d", fs.VolumeSize(), full)
+	}
+	found := false
+	for _, d := range fs.Diagnostics() {
+		found = found || strings.Contains(d.Message, "truncated")
+	}
+	if !found {
+		t.Errorf("no truncation diagnostic: %v", fs.Diagnostics())
+	}
+	// 被截断的部分之前的数据照常可读
+	if got, err := fs.ReadFile("/a.txt"); err != nil || string(got) != "still readable" {
+		t.Errorf("ReadFile: %q, %v", got, err)
+	}
+
+	_, err = NewExFATFileSystem(truncated, WithStrict())
+	if !errors.Is(err, ErrTruncatedImage) || !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("strict: got %v, want ErrTruncatedImage", err)
+	}
+
+	// 大小未知时不检查；*os.File 通过 Stat 得到大小
+	if _, err := NewExFATFileSystem(readerOnly{truncated}, WithStrict()); err != nil {
+		t.Errorf("reader without a size: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "truncated.img")
+	if err := os.WriteFile(path, truncated.Data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := NewExFATFileSystem(f, WithStrict()); !errors.Is(err, ErrTruncatedImage) {
+		t.Errorf("os.File: got %v, want ErrTruncatedImage", err)
+	}
+}
+
+func TestCheckVolumeSizeExactAndLarger(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, testimage.File("a.txt", nil))
+	// 映像与卷一样大或更大（例如后面还有其他数据）都不是截断
+	for _, data := range [][]byte{img.Bytes, append(bytes.Clone(img.Bytes), make([]byte, 4096)...)} {
+		fs, err := NewExFATFileSystem(&testimage.Disk{Data: data}, WithStrict())
+		if err != nil {
+			t.Fatalf("%d-byte image: %v", len(data), err)
+		}
+		if d := fs.Diagnostics(); len(d) != 0 {
+			t.Errorf("%d-byte image: diagnostics %v", len(data), d)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package exfat
+
+import "fmt"
+
+// VolumeInfo 卷的几何参数和标识，取自打开时采用的引导扇区（可能是备份引导区）
+// 偏移和长度都以字节为单位，相对于卷的起始位置。
+type VolumeInfo struct {
+	SerialNumber      uint32 // 卷序列号
+	RevisionMajor     uint8  // 文件系统版本的主版本号（exFAT 1.00 为 1）
+	RevisionMinor     uint8  // 文件系统版本的次版本号
+	BytesPerSector    uint32
+	SectorsPerCluster uint32
+	BytesPerCluster   uint32
+	ClusterCount      uint32 // 簇堆中的簇数
+	ClusterHeapOffset uint64 // 簇堆（第 2 簇）的偏移
+	FATOffset         uint64 // 第一个 FAT 的偏移
+	FATLength         uint64 // 每个 FAT 的长度
+	NumberOfFATs      uint8  // FAT 的个数，TexFAT 卷为 2
+	ActiveFAT         int    // 读取时使用的 FAT（1 或 2，见 VolumeFlags 的 ActiveFat 位）
+	RootCluster       uint32 // 根目录的第一个簇
+	Capacity          uint64 // 卷的字节数（VolumeLength）
+	SkippedPrefix     int64  // 原始映像中卷之前被跳过的厂商头部的字节数，由打开映像的一层填写，文件系统本身不知道
+}
+
+// Serial 以 Windows 的形式返回卷序列号，如 "1A2B-3C4D"
+func (i VolumeInfo) Serial() string {
+	return fmt.Sprintf("%04X-%04X", i.SerialNumber>>16, i.SerialNumber&0xFFFF)
+}
+
+// Revision 以 "主版本.次版本" 的形式返回文件系统版本，如 "1.00"
+func (i VolumeInfo) Revision() string {
+	return fmt.Sprintf("%d.%02d", i.RevisionMajor, i.RevisionMinor)
+}
+
+// Info 返回卷的几何参数和标识，不读取映像
+func (fs *ExFATFileSystem) Info() VolumeInfo {
+	b := fs.bootSector
+	sector := uint64(fs.bytesPerSector)
+	return VolumeInfo{
+		SerialNumber:      b.VolumeSerialNumber,
+		RevisionMajor:     uint8(b.FileSystemRevision >> 8),
+		RevisionMinor:     uint8(b.FileSystemRevision),
+		BytesPerSector:    fs.bytesPerSector,
+		SectorsPerCluster: fs.sectorsPerCluster,
+		BytesPerCluster:   fs.bytesPerCluster,
+		ClusterCount:      fs.totalClusters,
+		ClusterHeapOffset: fs.clusterHeapStart,
+		FATOffset:         uint64(b.FatOffset) * sector,
+		FATLength:         uint64(b.FatLength) * sector,
+		NumberOfFATs:      b.NumberOfFats,
+		ActiveFAT:         fs.activeFAT + 1,
+		RootCluster:       b.FirstClusterOfRootDir,
+		Capacity:          b.VolumeLength * sector,
+	}
+}
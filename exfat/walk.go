@@ -0,0 +1,172 @@
+package exfat
+
+import (
+	iofs "io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// walk 深度优先遍历 root 及其下的目录树，对每个条目调用 fn
+// fn 返回 filepath.SkipDir 跳过当前目录（对文件返回则跳过其余同级条目），
+// 返回 filepath.SkipAll 立即结束遍历且不报错，返回其他错误则中止遍历并返回该错误
+func (fs *ExFATFileSystem) walk(root string, fn func(path string, entry FileEntry) error) error {
+	entry, err := fs.getEntry(normalizePath(root))
+	if err != nil {
+		return err
+	}
+
+	err = fn(entry.path, entry.fileEntry())
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	if err != nil || !entry.IsDir {
+		return err
+	}
+
+	err = fs.walkDir(entry, fn, make(map[uint32]bool))
+	if err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// Walk 深度优先遍历 root 及其下的目录树，对每个条目（包括 root 本身）调用 fn，只读取目录，不读取文件内容
+// 与 filepath.Walk 的约定相同：路径是以 / 开头、按卷中的大小写给出的完整路径，目录先于其内容，
+// 同一目录中按目录项在磁盘上的顺序。fn 返回 filepath.SkipDir 跳过当前目录（对文件返回则跳过其余同级条目），
+// 返回 filepath.SkipAll 结束遍历且不报错，返回其他错误时中止遍历并返回该错误。需要 fs.WalkDirFunc 时使用 WalkDir，
+// 需要限制深度或不含 root 本身时使用 WalkPaths。
+func (fs *ExFATFileSystem) Walk(root string, fn func(path string, entry FileEntry) error) error {
+	return fs.walk(root, fn)
+}
+
+// walkDir 遍历目录的子条目，visited 记录已访问的目录簇以防止损坏映像中的循环
+func (fs *ExFATFileSystem) walkDir(dir *DirEntry, fn func(path string, entry FileEntry) error, visited map[uint32]bool) error {
+	if !firstVisit(visited, dir) {
+		fs.diagnose(dir.path, "directory cluster %d already visited, not descending again", dir.cluster)
+		return nil
+	}
+
+	children, err := fs.readDirectoryEntries(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		err := fn(child.path, child.fileEntry())
+		if err == filepath.SkipDir {
+			if child.IsDir {
+				continue
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if child.IsDir {
+			if err := fs.walkDir(child, fn, visited); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WalkDir 与 fs.WalkDir 相同，深度优先遍历 root 及其下的目录树，对每个条目调用 fn
+// 同一目录中按目录项在磁盘上的顺序，目录先于其内容；路径按卷中的大小写给出。
+// fn 返回 fs.SkipDir 跳过当前目录（对文件返回则跳过其余同级条目），返回 fs.SkipAll 结束遍历且不报错。
+// root 不存在时以该错误调用一次 fn；目录无法读取时以该错误对目录再调用一次 fn，由 fn 决定是否继续。
+// 目录的起始簇已经访问过时（损坏的映像中指回上层的目录）不再进入，以防止无限循环。
+func (fs *ExFATFileSystem) WalkDir(root string, fn iofs.WalkDirFunc) error {
+	entry, err := fs.getEntry(normalizePath(root))
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = fs.walkDirFunc(entry, fn, make(map[uint32]bool))
+	}
+	if err == iofs.SkipDir || err == iofs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkDirFunc 对条目调用 fn，是目录时继续遍历其子条目，visited 的作用与 walkDir 相同
+func (fs *ExFATFileSystem) walkDirFunc(entry *DirEntry, fn iofs.WalkDirFunc, visited map[uint32]bool) error {
+	d := dirEntry{entry.fileEntry()}
+	if err := fn(entry.path, d, nil); err != nil || !entry.IsDir {
+		if err == iofs.SkipDir && entry.IsDir {
+			err = nil
+		}
+		return err
+	}
+
+	if !firstVisit(visited, entry) {
+		fs.diagnose(entry.path, "directory cluster %d already visited, not descending again", entry.cluster)
+		return nil
+	}
+
+	children, err := fs.readDirectoryEntries(entry)
+	if err != nil {
+		if err = fn(entry.path, d, err); err == iofs.SkipDir {
+			err = nil
+		}
+		return err
+	}
+
+	for _, child := range children {
+		if err := fs.walkDirFunc(child, fn, visited); err != nil {
+			if err == iofs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// FindFirst 遍历 root 下的目录树，返回第一个满足 pred 的条目及其路径
+// 找到后立即停止遍历；没有条目满足时返回 ErrNoMatch
+func (fs *ExFATFileSystem) FindFirst(root string, pred func(path string, e FileEntry) bool) (string, *FileEntry, error) {
+	var foundPath string
+	var found *FileEntry
+
+	err := fs.walk(root, func(path string, entry FileEntry) error {
+		if pred(path, entry) {
+			foundPath, found = path, &entry
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if found == nil {
+		return "", nil, ErrNoMatch
+	}
+
+	return foundPath, found, nil
+}
+
+// WalkPaths 以流的方式对 root 下的每个条目（不含 root 本身）调用 fn，不读取文件内容
+// 顺序固定：深度优先，同一目录中按目录项在磁盘上的顺序，目录先于其内容，
+// 因此同一映像多次遍历得到相同的序列。maxDepth 大于 0 时只遍历 root 下 maxDepth 层，
+// 1 表示只有 root 的直接子条目。fn 返回值的含义与 FindFirst 使用的遍历相同：
+// filepath.SkipDir 跳过目录，filepath.SkipAll 结束遍历且不报错。
+func (fs *ExFATFileSystem) WalkPaths(root string, maxDepth int, fn func(path string, e FileEntry) error) error {
+	base := ""
+	return fs.walk(root, func(p string, e FileEntry) error {
+		if base == "" {
+			// 第一次调用是 root 本身，路径按卷中的大小写给出
+			base = strings.TrimSuffix(p, "/") + "/"
+			return nil
+		}
+		if err := fn(p, e); err != nil || !e.IsDir {
+			return err
+		}
+		if maxDepth > 0 && strings.Count(strings.TrimPrefix(p, base), "/")+1 >= maxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
@@ -0,0 +1,142 @@
+package extract
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// AggregateProgress 多个提取工作者的汇总进度
+type AggregateProgress struct {
+	PlannedBytes int64          // 计划提取的总字节数（来自 TreeSize，未知时为 0）
+	Bytes        int64          // 已写出的字节数
+	Files        int            // 已成功写出的文件数
+	Failed       int            // 提取失败的文件数
+	Skipped      int            // 跳过的文件数
+	Current      map[int]string // 各工作者正在处理的源路径，空闲的工作者不在其中
+}
+
+// ProgressAggregator 汇总并发提取的进度，可以被多个 goroutine 同时使用
+// 每个工作者通过 WithAggregator 得到的选项报告进度，调用方通过 Snapshot 或 Tick 读取一致的汇总值，
+// 不需要自己处理来自不同 goroutine 的交错回调。
+type ProgressAggregator struct {
+	mu       sync.Mutex
+	progress AggregateProgress
+}
+
+// NewProgressAggregator 创建进度汇总器，plannedBytes 为计划提取的总字节数（未知时为 0）
+func NewProgressAggregator(plannedBytes int64) *ProgressAggregator {
+	return &ProgressAggregator{progress: AggregateProgress{
+		PlannedBytes: plannedBytes,
+		Current:      make(map[int]string),
+	}}
+}
+
+// Begin 记录工作者开始处理一个文件
+func (a *ProgressAggregator) Begin(worker int, srcPath string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.progress.Current[worker] = srcPath
+}
+
+// Finish 记录工作者处理完一个文件，err 不为 nil 表示提取失败
+func (a *ProgressAggregator) Finish(worker int, size int64, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.progress.Current, worker)
+	if err != nil {
+		a.progress.Failed++
+		return
+	}
+	a.progress.Files++
+	a.progress.Bytes += size
+}
+
+// Skip 记录工作者跳过一个文件
+func (a *ProgressAggregator) Skip(worker int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.progress.Current, worker)
+	a.progress.Skipped++
+}
+
+// Snapshot 返回当前汇总进度的副本
+func (a *ProgressAggregator) Snapshot() AggregateProgress {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	snapshot := a.progress
+	snapshot.Current = make(map[int]string, len(a.progress.Current))
+	for worker, p := range a.progress.Current {
+		snapshot.Current[worker] = p
+	}
+	return snapshot
+}
+
+// Tick 在一个单独的 goroutine 中每隔 interval 以快照调用一次 fn，直到调用返回的 stop
+// stop 返回时 fn 已不会再被调用，可以安全地输出最终结果。
+func (a *ProgressAggregator) Tick(interval time.Duration, fn func(AggregateProgress)) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fn(a.Snapshot())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+		})
+	}
+}
+
+// WithAggregator 把提取进度报告给汇总器，worker 为当前工作者的编号
+// 同一个汇总器可以供多个并发的提取调用使用，每个调用使用不同的编号。
+func WithAggregator(a *ProgressAggregator, worker int) Option {
+	return func(o *options) {
+		o.aggregator = a
+		o.worker = worker
+	}
+}
+
+// TreeSize 统计 srcPath 下（或 srcPath 本身）的文件数和总字节数，用作汇总器的计划值
+func TreeSize(fsys *exfat.ExFATFileSystem, srcPath string) (files int, bytes int64, err error) {
+	entry, err := fsys.Stat(srcPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get entry for %s: %w", srcPath, err)
+	}
+	if !entry.IsDir {
+		return 1, entry.Size, nil
+	}
+
+	entries, err := fsys.ListDir(srcPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list directory %s: %w", srcPath, err)
+	}
+	for _, e := range entries {
+		if !e.IsDir {
+			files++
+			bytes += e.Size
+			continue
+		}
+		// 与提取时一样，无法读取的子目录不计入
+		if n, b, err := TreeSize(fsys, path.Join(srcPath, e.Name)); err == nil {
+			files += n
+			bytes += b
+		}
+	}
+	return files, bytes, nil
+}
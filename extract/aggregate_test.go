@@ -0,0 +1,176 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+func TestProgressAggregatorWorkers(t *testing.T) {
+	const workers = 16
+	var dirs []*testimage.Node
+	for w := 0; w < workers; w++ {
+		var files []*testimage.Node
+		for i := 0; i < 8; i++ {
+			files = append(files, testimage.File(fmt.Sprintf("f%d.bin", i), make([]byte, 1000*w+i+1)))
+		}
+		files = append(files, testimage.File("skip.me", []byte("skipped")))
+		dirs = append(dirs, testimage.Dir(fmt.Sprintf("W%02d", w), files...))
+	}
+	// 碎片化的文件在 FAT 清零后无法读取，计为失败
+	broken := testimage.File("broken.bin", make([]byte, 3000))
+	broken.Fragmented = true
+	dirs[3].Children = append(dirs[3].Children, broken)
+	img := testimage.Build(testimage.Options{ClusterCount: 8192}, dirs...)
+	clear(img.Bytes[img.FATOffset+8 : img.FATOffset+img.FATLength])
+	fs := openFS(t, img)
+
+	files, planned, err := TreeSize(fs, "/")
+	if err != nil || files != workers*9+1 {
+		t.Fatalf("TreeSize = %d files, %d bytes, %v", files, planned, err)
+	}
+	a := NewProgressAggregator(planned)
+
+	// 定时回调和另一个读取者同时观察汇总值：每个观察者看到的计数只增不减，正在处理的文件不超过工作者数
+	observer := func() func(AggregateProgress) {
+		var last AggregateProgress
+		return func(p AggregateProgress) {
+			if p.Bytes < last.Bytes || p.Files < last.Files || p.Failed < last.Failed || p.Skipped < last.Skipped {
+				t.Errorf("progress went backwards: %+v after %+v", p, last)
+			}
+			if p.Bytes > planned || len(p.Current) > workers || p.PlannedBytes != planned {
+				t.Errorf("inconsistent snapshot %+v", p)
+			}
+			for w, src := range p.Current {
+				if !strings.HasPrefix(src, fmt.Sprintf("/W%02d/", w)) {
+					t.Errorf("worker %d reported as working on %s", w, src)
+				}
+			}
+			last = p
+		}
+	}
+	stop := a.Tick(time.Millisecond, observer())
+	done := make(chan struct{})
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		check := observer()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				check(a.Snapshot())
+			}
+		}
+	}()
+
+	dest := t.TempDir()
+	skipped := func(p string, e exfat.FileEntry) bool { return e.Name != "skip.me" }
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			src := fmt.Sprintf("/W%02d", w)
+			_, errs[w] = PathWithReport(fs, src, filepath.Join(dest, src), WithAggregator(a, w), WithFilter(skipped))
+		}()
+	}
+	wg.Wait()
+	close(done)
+	<-polled
+	stop()
+
+	for w, err := range errs {
+		if (w == 3) != errors.Is(err, ErrIncomplete) {
+			t.Errorf("worker %d: %v", w, err)
+		}
+	}
+	p := a.Snapshot()
+	want := AggregateProgress{
+		PlannedBytes: planned,
+		Bytes:        planned - 3000 - workers*int64(len("skipped")),
+		Files:        workers * 8,
+		Failed:       1,
+		Skipped:      workers,
+	}
+	if p.PlannedBytes != want.PlannedBytes || p.Bytes != want.Bytes || p.Files != want.Files ||
+		p.Failed != want.Failed || p.Skipped != want.Skipped || len(p.Current) != 0 {
+		t.Errorf("final progress %+v, want %+v", p, want)
+	}
+}
+
+func TestProgressAggregatorCalls(t *testing.T) {
+	a := NewProgressAggregator(0)
+	var wg sync.WaitGroup
+	for w := 0; w < 16; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				a.Begin(w, "/x")
+				switch i % 4 {
+				case 0:
+					a.Finish(w, 0, errors.New("failed"))
+				case 1:
+					a.Skip(w)
+				default:
+					a.Finish(w, 10, nil)
+				}
+			}
+		}()
+	}
+	a.Begin(99, "/busy")
+	wg.Wait()
+
+	p := a.Snapshot()
+	if p.Files != 16*50 || p.Bytes != 16*50*10 || p.Failed != 16*25 || p.Skipped != 16*25 {
+		t.Errorf("totals %+v", p)
+	}
+	if len(p.Current) != 1 || p.Current[99] != "/busy" {
+		t.Errorf("current %v, want only worker 99", p.Current)
+	}
+	// 快照是副本，修改它不影响汇总器
+	p.Current[1] = "/other"
+	delete(p.Current, 99)
+	if q := a.Snapshot(); len(q.Current) != 1 || q.Current[99] != "/busy" {
+		t.Errorf("snapshot aliases the aggregator: %v", q.Current)
+	}
+}
+
+func TestProgressAggregatorTickStop(t *testing.T) {
+	a := NewProgressAggregator(100)
+	var mu sync.Mutex
+	calls := 0
+	ticked := make(chan struct{}, 1)
+	stop := a.Tick(time.Millisecond, func(AggregateProgress) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		select {
+		case ticked <- struct{}{}:
+		default:
+		}
+	})
+	<-ticked
+	stop()
+	mu.Lock()
+	n := calls
+	mu.Unlock()
+	// stop 返回后回调不再被调用，重复调用 stop 也是安全的
+	time.Sleep(10 * time.Millisecond)
+	stop()
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != n {
+		t.Errorf("callback ran %d times after stop", calls-n)
+	}
+}
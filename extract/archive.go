@@ -0,0 +1,338 @@
+package extract
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// ArchiveFormat 导出归档的格式
+type ArchiveFormat int
+
+const (
+	// FormatPax POSIX pax 格式，exFAT 元数据保存在扩展头部中，可以无损还原
+	FormatPax ArchiveFormat = iota
+	// FormatCpio cpio newc 格式（initramfs 使用），只保留修改时间和权限位
+	FormatCpio
+)
+
+// pax 扩展头部中保存 exFAT 元数据的键
+const (
+	PaxAttr    = "EXFAT.attr"   // 属性位，十六进制
+	PaxCrtime  = "EXFAT.crtime" // 创建时间（RFC 3339，带 UTC 偏移）
+	PaxMtime   = "EXFAT.mtime"  // 修改时间（RFC 3339，带 UTC 偏移）
+	PaxAtime   = "EXFAT.atime"  // 访问时间（RFC 3339，带 UTC 偏移）
+	paxTimeFmt = time.RFC3339Nano
+)
+
+// ExportOptions 导出选项
+type ExportOptions struct {
+	Format ArchiveFormat // 归档格式，默认为 pax
+}
+
+// ArchiveMetadata 从 pax 扩展头部中解析出的 exFAT 元数据
+type ArchiveMetadata struct {
+	Attributes exfat.Attributes
+	CreateTime time.Time
+	ModTime    time.Time
+	AccessTime time.Time
+}
+
+// ExportPax 把 root 下的整棵目录树以流的方式写成归档
+// pax 格式在每个条目的扩展头部中保存属性位以及带 UTC 偏移的创建、修改、访问时间，
+// 可以用 ParsePaxRecords 读回。归档内的路径相对于 root。文件的簇按需读取，不会整个读入内存。
+// 无法读取的文件和目录被跳过，此时归档仍然完整写出，返回包装了 ErrIncomplete 的错误；需要逐条目的结果时使用 ExportPaxWithReport。
+func ExportPax(fsys *exfat.ExFATFileSystem, root string, w io.Writer, opts ExportOptions) error {
+	_, err := ExportPaxWithReport(fsys, root, w, opts)
+	return err
+}
+
+// ExportPaxWithReport 与 ExportPax 相同，并返回报告
+// 无法读取的文件和子目录记入报告后跳过；写入 w 失败时立即中止。
+func ExportPaxWithReport(fsys *exfat.ExFATFileSystem, root string, w io.Writer, opts ExportOptions) (*Report, error) {
+	var aw archiveWriter
+	switch opts.Format {
+	case FormatPax:
+		aw = &paxWriter{tw: tar.NewWriter(w)}
+	case FormatCpio:
+		aw = &cpioWriter{w: w}
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %d", opts.Format)
+	}
+
+	entry, err := fsys.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	x := &archiver{fsys: fsys, aw: aw, report: newReport(&options{})}
+	start := time.Now()
+	if entry.IsDir {
+		entries, err := fsys.ListDir(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list directory %s: %w", root, err)
+		}
+		err = x.dir(root, "", entries)
+	} else {
+		err = x.file(root, entry.Name, entry)
+	}
+	x.report.Duration = time.Since(start)
+	if err != nil {
+		return x.report, err
+	}
+	if err := aw.Close(); err != nil {
+		return x.report, err
+	}
+	return x.report, x.report.Err()
+}
+
+// archiver 导出归档时的状态
+type archiver struct {
+	fsys   *exfat.ExFATFileSystem
+	aw     archiveWriter
+	report *Report
+}
+
+// dir 递归写出目录内容，name 为目录在归档中的路径（根目录为空）
+func (x *archiver) dir(srcPath, name string, entries []exfat.FileEntry) error {
+	for _, entry := range entries {
+		childSrc := path.Join(srcPath, entry.Name)
+		childName := path.Join(name, entry.Name)
+		if !entry.IsDir {
+			if err := x.file(childSrc, childName, entry); err != nil {
+				return err
+			}
+			continue
+		}
+
+		children, err := x.fsys.ListDir(childSrc)
+		if err != nil {
+			x.report.add(FileReport{Path: childSrc, Err: fmt.Errorf("failed to list directory: %w", err)})
+			continue
+		}
+		if err := x.aw.WriteEntry(childName, entry, nil); err != nil {
+			return err
+		}
+		if err := x.dir(childSrc, childName, children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// file 以流的方式写出一个文件，簇按需读取，内存占用与文件大小无关
+// 无法打开的文件记入报告并跳过；头部写出之后才发生的读取错误无法再跳过，
+// 此时条目的其余部分以零填充，使归档保持完整，并把该文件记为失败。
+func (x *archiver) file(srcPath, name string, entry exfat.FileEntry) error {
+	f, err := x.fsys.Open(srcPath)
+	if err != nil {
+		x.report.add(FileReport{Path: srcPath, Err: err})
+		return nil
+	}
+	defer f.Close()
+
+	src := &zeroOnError{r: f}
+	if err := x.aw.WriteEntry(name, entry, src); err != nil {
+		return err
+	}
+	if src.err != nil {
+		x.report.add(FileReport{Path: srcPath, Err: fmt.Errorf("read failed after %d bytes, the rest is zero-filled: %v", src.n, src.err)})
+		return nil
+	}
+	x.report.Extracted++
+	x.report.Bytes += entry.Size
+	return nil
+}
+
+// zeroOnError 读取 r，遇到错误后记录错误并改为返回零
+type zeroOnError struct {
+	r   io.Reader
+	n   int64 // 出错之前读到的字节数
+	err error
+}
+
+func (z *zeroOnError) Read(p []byte) (int, error) {
+	if z.err != nil {
+		clear(p)
+		return len(p), nil
+	}
+	n, err := z.r.Read(p)
+	z.n += int64(n)
+	if err != nil && err != io.EOF {
+		z.err = err
+		clear(p[n:])
+		return len(p), nil
+	}
+	return n, err
+}
+
+// archiveWriter 归档格式的写入器
+// 文件的数据从 r 读取，长度为 entry.Size；目录的 r 为 nil。
+type archiveWriter interface {
+	WriteEntry(name string, entry exfat.FileEntry, r io.Reader) error
+	Close() error
+}
+
+// entryMode 由属性位推导权限位：只读属性去掉写权限
+func entryMode(entry exfat.FileEntry) int64 {
+	mode := int64(0644)
+	if entry.IsDir {
+		mode = 0755
+	}
+	if entry.Attributes.Has(exfat.AttrReadOnly) {
+		mode &^= 0222
+	}
+	return mode
+}
+
+// paxWriter 写 pax 格式的 tar 归档
+type paxWriter struct {
+	tw *tar.Writer
+}
+
+func (p *paxWriter) WriteEntry(name string, entry exfat.FileEntry, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:       name,
+		Mode:       entryMode(entry),
+		Size:       entry.Size,
+		ModTime:    entry.ModTime,
+		AccessTime: entry.AccessTime,
+		Typeflag:   tar.TypeReg,
+		Format:     tar.FormatPAX,
+		PAXRecords: PaxRecords(entry),
+	}
+	if entry.IsDir {
+		hdr.Name += "/"
+		hdr.Size = 0
+		hdr.Typeflag = tar.TypeDir
+	}
+	if err := p.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write header for %s: %v", name, err)
+	}
+	if r == nil {
+		return nil
+	}
+	if _, err := io.CopyN(p.tw, r, hdr.Size); err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return nil
+}
+
+func (p *paxWriter) Close() error {
+	return p.tw.Close()
+}
+
+// PaxRecords 返回保存条目 exFAT 元数据的 pax 扩展头部记录
+func PaxRecords(entry exfat.FileEntry) map[string]string {
+	records := map[string]string{
+		PaxAttr: fmt.Sprintf("0x%04x", uint16(entry.Attributes)),
+	}
+	for key, t := range map[string]time.Time{
+		PaxCrtime: entry.CreateTime,
+		PaxMtime:  entry.ModTime,
+		PaxAtime:  entry.AccessTime,
+	} {
+		if !t.IsZero() {
+			records[key] = t.Format(paxTimeFmt)
+		}
+	}
+	return records
+}
+
+// ParsePaxRecords 从 pax 扩展头部记录中解析 exFAT 元数据
+// 缺少的键保持零值，以便将来的写入支持据此还原属性和时间。
+func ParsePaxRecords(records map[string]string) (ArchiveMetadata, error) {
+	var meta ArchiveMetadata
+	if v, ok := records[PaxAttr]; ok {
+		attr, err := strconv.ParseUint(strings.TrimPrefix(v, "0x"), 16, 16)
+		if err != nil {
+			return meta, fmt.Errorf("invalid %s record %q: %v", PaxAttr, v, err)
+		}
+		meta.Attributes = exfat.Attributes(attr)
+	}
+	for key, dst := range map[string]*time.Time{
+		PaxCrtime: &meta.CreateTime,
+		PaxMtime:  &meta.ModTime,
+		PaxAtime:  &meta.AccessTime,
+	} {
+		v, ok := records[key]
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(paxTimeFmt, v)
+		if err != nil {
+			return meta, fmt.Errorf("invalid %s record %q: %v", key, v, err)
+		}
+		*dst = t
+	}
+	return meta, nil
+}
+
+// cpioWriter 写 cpio newc 格式的归档
+type cpioWriter struct {
+	w   io.Writer
+	ino uint32
+}
+
+// cpio newc 的文件类型位
+const (
+	cpioModeDir = 0040000
+	cpioModeReg = 0100000
+)
+
+func (c *cpioWriter) WriteEntry(name string, entry exfat.FileEntry, r io.Reader) error {
+	mode := uint32(entryMode(entry))
+	size := entry.Size
+	if entry.IsDir {
+		mode |= cpioModeDir
+		size = 0
+	} else {
+		mode |= cpioModeReg
+	}
+	var mtime int64
+	if !entry.ModTime.IsZero() {
+		mtime = entry.ModTime.Unix()
+	}
+	c.ino++
+	nlink := 1
+	if entry.IsDir {
+		nlink = 2
+	}
+	return c.write(name, mode, nlink, mtime, size, r)
+}
+
+// write 写出一个 newc 记录：头部、名称和从 r 读取的 size 字节数据分别按 4 字节对齐
+func (c *cpioWriter) write(name string, mode uint32, nlink int, mtime int64, size int64, r io.Reader) error {
+	hdr := fmt.Sprintf("070701%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X",
+		c.ino, mode, 0, 0, nlink, uint32(mtime), size, 0, 0, 0, 0, len(name)+1, 0)
+	record := append([]byte(hdr), name...)
+	record = append(record, 0)
+	record = append(record, make([]byte, pad4(len(record)))...)
+	if _, err := c.w.Write(record); err != nil {
+		return fmt.Errorf("failed to write header for %s: %v", name, err)
+	}
+	if size > 0 {
+		if _, err := io.CopyN(c.w, r, size); err != nil {
+			return fmt.Errorf("failed to write %s: %v", name, err)
+		}
+	}
+	if _, err := c.w.Write(make([]byte, pad4(int(size%4)))); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *cpioWriter) Close() error {
+	c.ino = 0
+	return c.write("TRAILER!!!", 0, 1, 0, 0, nil)
+}
+
+// pad4 返回对齐到 4 字节需要的填充长度
+func pad4(n int) int {
+	return (4 - n%4) % 4
+}
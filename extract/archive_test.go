@@ -0,0 +1,247 @@
+package extract
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// readTar 读出未压缩的 tar 归档
+func readTar(t *testing.T, data []byte) []tarEntry {
+	t.Helper()
+	tr := tar.NewReader(bytes.NewReader(data))
+	var entries []tarEntry
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, tarEntry{hdr, body})
+	}
+}
+
+// sameTime 比较时间点和 UTC 偏移
+func sameTime(a, b time.Time) bool {
+	_, aOffset := a.Zone()
+	_, bOffset := b.Zone()
+	return a.Equal(b) && aOffset == bOffset
+}
+
+// stripMetadata 返回结构和内容相同、属性和时间均为默认值的目录树
+func stripMetadata(nodes []*testimage.Node) []*testimage.Node {
+	var out []*testimage.Node
+	for _, n := range nodes {
+		c := &testimage.Node{Name: n.Name, Data: n.Data, Dir: n.Dir, Fragmented: n.Fragmented}
+		c.Children = stripMetadata(n.Children)
+		out = append(out, c)
+	}
+	return out
+}
+
+func TestExportPaxRoundTrip(t *testing.T) {
+	src := openFS(t, testimage.Build(testimage.Options{}, archiveTree()...))
+	var buf bytes.Buffer
+	if err := ExportPax(src, "/", &buf, ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	entries := readTar(t, buf.Bytes())
+	want := []string{"empty", "readonly.txt", "Sub/", "Sub/large.bin", "Sub/Hidden/", "Sub/Hidden/inner.txt", "Sub/Empty/"}
+	if len(entries) != len(want) {
+		t.Fatalf("archive holds %d entries, want %d", len(entries), len(want))
+	}
+
+	// 导出：每个条目的内容、权限位和全部 exFAT 元数据都与卷上一致
+	metas := make([]ArchiveMetadata, len(entries))
+	for i, e := range entries {
+		if e.hdr.Name != want[i] {
+			t.Fatalf("entry %d is %q, want %q", i, e.hdr.Name, want[i])
+		}
+		path := "/" + strings.TrimSuffix(e.hdr.Name, "/")
+		stat, err := src.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.IsDir != (e.hdr.Typeflag == tar.TypeDir) || e.hdr.Mode != entryMode(stat) {
+			t.Errorf("%s: type %c, mode %o", e.hdr.Name, e.hdr.Typeflag, e.hdr.Mode)
+		}
+		if !stat.IsDir {
+			data, _ := src.ReadFile(path)
+			if !bytes.Equal(e.data, data) {
+				t.Errorf("%s: %d bytes in the archive, %d on the volume", e.hdr.Name, len(e.data), len(data))
+			}
+		}
+		for _, key := range []string{PaxAttr, PaxCrtime, PaxMtime, PaxAtime} {
+			if _, ok := e.hdr.PAXRecords[key]; !ok {
+				t.Errorf("%s: no %s record", e.hdr.Name, key)
+			}
+		}
+		meta, err := ParsePaxRecords(e.hdr.PAXRecords)
+		if err != nil {
+			t.Fatalf("%s: %v", e.hdr.Name, err)
+		}
+		if meta.Attributes != stat.Attributes || !sameTime(meta.CreateTime, stat.CreateTime) ||
+			!sameTime(meta.ModTime, stat.ModTime) || !sameTime(meta.AccessTime, stat.AccessTime) {
+			t.Errorf("%s: archived %+v, volume has %v %v %v %v", e.hdr.Name, meta,
+				stat.Attributes, stat.CreateTime, stat.ModTime, stat.AccessTime)
+		}
+		metas[i] = meta
+	}
+	// 修改时间的 10 毫秒部分和非 UTC 偏移都保留下来
+	if mtime := metas[1].ModTime; mtime.Nanosecond() != 340e6 {
+		t.Errorf("readonly.txt: mtime %v lost its 10 ms increment", mtime)
+	}
+
+	// 导入：在只有默认元数据的同构卷上按归档还原属性和时间
+	img := testimage.Build(testimage.Options{}, stripMetadata(archiveTree())...)
+	dst := openFS(t, img)
+	for i, e := range entries {
+		path := "/" + strings.TrimSuffix(e.hdr.Name, "/")
+		if err := dst.Chattr(path, metas[i].Attributes); err != nil {
+			t.Fatalf("Chattr(%s): %v", path, err)
+		}
+		if err := dst.Chtimes(path, metas[i].ModTime, metas[i].AccessTime); err != nil {
+			t.Fatalf("Chtimes(%s): %v", path, err)
+		}
+	}
+	restored := openFS(t, img)
+	for i, e := range entries {
+		path := "/" + strings.TrimSuffix(e.hdr.Name, "/")
+		stat, err := restored.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 写入路径没有设置创建时间的接口，创建时间只在导出一侧校验
+		if stat.Attributes != metas[i].Attributes || !sameTime(stat.ModTime, metas[i].ModTime) || !sameTime(stat.AccessTime, metas[i].AccessTime) {
+			t.Errorf("%s: restored %v %v %v, want %+v", path, stat.Attributes, stat.ModTime, stat.AccessTime, metas[i])
+		}
+	}
+}
+
+func TestParsePaxRecords(t *testing.T) {
+	meta, err := ParsePaxRecords(map[string]string{"path": "x"})
+	if err != nil || meta != (ArchiveMetadata{}) {
+		t.Errorf("records without exFAT keys: %+v, %v", meta, err)
+	}
+	meta, err = ParsePaxRecords(map[string]string{PaxAttr: "0x0021", PaxMtime: "2020-01-02T03:04:05.67+05:30"})
+	if err != nil || meta.Attributes != exfat.AttrReadOnly|exfat.AttrArchive || !meta.CreateTime.IsZero() || !meta.AccessTime.IsZero() {
+		t.Errorf("partial records: %+v, %v", meta, err)
+	}
+	if _, offset := meta.ModTime.Zone(); offset != 5*3600+1800 || meta.ModTime.Nanosecond() != 670e6 {
+		t.Errorf("mtime %v", meta.ModTime)
+	}
+	for _, records := range []map[string]string{
+		{PaxAttr: "0x10000"},
+		{PaxAttr: "readonly"},
+		{PaxCrtime: "2020-01-02"},
+		{PaxAtime: "yesterday"},
+	} {
+		if _, err := ParsePaxRecords(records); err == nil {
+			t.Errorf("ParsePaxRecords(%v) accepted", records)
+		}
+	}
+}
+
+// cpioEntry 从 newc 归档中读出的一个记录
+type cpioEntry struct {
+	name        string
+	mode, mtime uint32
+	data        []byte
+}
+
+// readCpio 解析 newc 归档，直到 TRAILER!!! 为止
+func readCpio(t *testing.T, data []byte) []cpioEntry {
+	t.Helper()
+	var entries []cpioEntry
+	for pos := 0; ; {
+		if len(data) < pos+110 || string(data[pos:pos+6]) != "070701" {
+			t.Fatalf("no newc header at offset %d", pos)
+		}
+		field := func(i int) uint32 {
+			v, err := strconv.ParseUint(string(data[pos+6+8*i:pos+14+8*i]), 16, 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return uint32(v)
+		}
+		size, nameSize := int(field(6)), int(field(11))
+		e := cpioEntry{mode: field(1), mtime: field(5)}
+		pos += 110
+		e.name = string(data[pos : pos+nameSize-1])
+		pos += nameSize + pad4(110+nameSize)
+		e.data = data[pos : pos+size]
+		pos += size + pad4(size)
+		if e.name == "TRAILER!!!" {
+			if pos != len(data) {
+				t.Errorf("%d bytes after the trailer", len(data)-pos)
+			}
+			return entries
+		}
+		entries = append(entries, e)
+	}
+}
+
+func TestExportCpio(t *testing.T) {
+	fs := openFS(t, testimage.Build(testimage.Options{}, archiveTree()...))
+	var buf bytes.Buffer
+	if err := ExportPax(fs, "/", &buf, ExportOptions{Format: FormatCpio}); err != nil {
+		t.Fatal(err)
+	}
+	entries := readCpio(t, buf.Bytes())
+	want := []string{"empty", "readonly.txt", "Sub", "Sub/large.bin", "Sub/Hidden", "Sub/Hidden/inner.txt", "Sub/Empty"}
+	if len(entries) != len(want) {
+		t.Fatalf("archive holds %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e.name != want[i] {
+			t.Fatalf("entry %d is %q, want %q", i, e.name, want[i])
+		}
+		stat, err := fs.Stat("/" + e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kind := uint32(cpioModeReg)
+		if stat.IsDir {
+			kind = cpioModeDir
+		}
+		if e.mode != kind|uint32(entryMode(stat)) || int64(e.mtime) != stat.ModTime.Unix() {
+			t.Errorf("%s: mode %o, mtime %d", e.name, e.mode, e.mtime)
+		}
+		if !stat.IsDir {
+			data, _ := fs.ReadFile("/" + e.name)
+			if !bytes.Equal(e.data, data) {
+				t.Errorf("%s: %d bytes in the archive, %d on the volume", e.name, len(e.data), len(data))
+			}
+		}
+	}
+}
+
+func TestExportPaxIncomplete(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, archiveTree()...)
+	clear(img.Bytes[img.FATOffset+8 : img.FATOffset+img.FATLength])
+	var buf bytes.Buffer
+	report, err := ExportPaxWithReport(openFS(t, img), "/", &buf, ExportOptions{})
+	if !errors.Is(err, ErrIncomplete) || report == nil {
+		t.Fatalf("got %v, want ErrIncomplete", err)
+	}
+	if entries := readTar(t, buf.Bytes()); len(entries) != 7 {
+		t.Errorf("archive holds %d entries, want 7", len(entries))
+	}
+	if err := ExportPax(openFS(t, img), "/", io.Discard, ExportOptions{Format: ArchiveFormat(9)}); err == nil {
+		t.Error("accepted an unknown format")
+	}
+}
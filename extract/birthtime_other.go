@@ -0,0 +1,10 @@
+//go:build !windows
+
+package extract
+
+import "time"
+
+// setBirthTime 其他平台没有设置创建时间的通用接口，不做任何事
+func setBirthTime(path string, t time.Time) error {
+	return nil
+}
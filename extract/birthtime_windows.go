@@ -0,0 +1,26 @@
+//go:build windows
+
+package extract
+
+import (
+	"syscall"
+	"time"
+)
+
+// setBirthTime 设置文件或目录的创建时间
+// 目录需要 FILE_FLAG_BACKUP_SEMANTICS 才能打开；访问时间和修改时间传 nil，保持不变。
+func setBirthTime(path string, t time.Time) error {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	h, err := syscall.CreateFile(name, syscall.FILE_WRITE_ATTRIBUTES,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(h)
+	ft := syscall.NsecToFiletime(t.UnixNano())
+	return syscall.SetFileTime(h, &ft, nil, nil)
+}
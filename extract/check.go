@@ -0,0 +1,121 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// ErrCheckFailed 表示检查策略要求在某个条目上中止提取
+var ErrCheckFailed = errors.New("extraction stopped by check policy")
+
+// CheckAction 检查发现异常时对条目采取的处理方式
+// 按严重程度递增排列，条目有多种异常时采用其中最严重的处理方式。
+type CheckAction int
+
+const (
+	CheckExtract  CheckAction = iota // 照常提取
+	CheckZeroFill                    // 提取，簇链中断之后的部分以零填充
+	CheckRepair                      // 按修复计划提取
+	CheckSkip                        // 跳过该条目（目录连同其内容）
+	CheckFail                        // 中止整个提取
+)
+
+var checkActionNames = []string{"extract", "zero", "repair", "skip", "fail"}
+
+// String 返回处理方式的名称，与 ParseCheckAction 接受的名称相同
+func (a CheckAction) String() string {
+	if a >= 0 && int(a) < len(checkActionNames) {
+		return checkActionNames[a]
+	}
+	return fmt.Sprintf("CheckAction(%d)", int(a))
+}
+
+// ParseCheckAction 解析处理方式的名称：extract、zero、repair、skip 或 fail
+func ParseCheckAction(s string) (CheckAction, error) {
+	for i, name := range checkActionNames {
+		if s == name {
+			return CheckAction(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid check action %q: expected one of extract, zero, repair, skip, fail", s)
+}
+
+// CheckPolicy 把每类异常映射到处理方式，未列出的异常照常提取
+// 补零和修复计划只作用于文件，对目录等同于照常提取。
+type CheckPolicy map[exfat.Anomaly]CheckAction
+
+// Action 返回带有 anomalies 的条目应采取的处理方式
+func (p CheckPolicy) Action(anomalies []exfat.Anomaly) CheckAction {
+	action := CheckExtract
+	for _, a := range anomalies {
+		action = max(action, p[a])
+	}
+	return action
+}
+
+// CheckFinding 记录一个存在异常的条目及对它采取的处理方式
+type CheckFinding struct {
+	Path      string          // 源路径
+	IsDir     bool            // 是否为目录
+	Anomalies []exfat.Anomaly // 发现的异常
+	Action    CheckAction     // 采取的处理方式
+}
+
+// CheckReport 提取过程中顺带完成的检查结果
+type CheckReport struct {
+	Checked  int            // 检查的条目数
+	Findings []CheckFinding // 存在异常的条目，按遍历顺序排列
+}
+
+// WithAction 返回采取了指定处理方式的条目
+func (r *CheckReport) WithAction(a CheckAction) []CheckFinding {
+	var matched []CheckFinding
+	for _, f := range r.Findings {
+		if f.Action == a {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+// ExtractWithCheck 在一次遍历中检查并提取 srcPath，按 policy 决定如何处理存在异常的条目
+// 检查使用提取时本来就要取得的目录异常，不再单独遍历整棵树。
+// 策略要求中止时返回包装了 ErrCheckFailed 的错误，已经提取的内容保留在目标目录中。
+func ExtractWithCheck(fsys *exfat.ExFATFileSystem, srcPath, destDir string, policy CheckPolicy, opts ...Option) (*CheckReport, *Report, error) {
+	check := &CheckReport{}
+	opts = append(opts, func(o *options) {
+		o.policy = policy
+		o.check = check
+	})
+	report, err := PathWithReport(fsys, srcPath, destDir, opts...)
+	return check, report, err
+}
+
+// checkEntry 按检查策略决定条目的处理方式并记入检查报告；没有检查策略时照常提取
+func (x *extractor) checkEntry(entry exfat.FileEntry, srcPath string, anomalies []exfat.Anomaly) (CheckAction, error) {
+	if x.opts.check == nil {
+		return CheckExtract, nil
+	}
+	x.opts.check.Checked++
+	if len(anomalies) == 0 {
+		return CheckExtract, nil
+	}
+
+	action := x.opts.policy.Action(anomalies)
+	if entry.IsDir && (action == CheckZeroFill || action == CheckRepair) {
+		action = CheckExtract
+	}
+	x.opts.check.Findings = append(x.opts.check.Findings, CheckFinding{
+		Path:      srcPath,
+		IsDir:     entry.IsDir,
+		Anomalies: anomalies,
+		Action:    action,
+	})
+
+	if action == CheckFail {
+		return action, fmt.Errorf("%w: %s: %v", ErrCheckFailed, srcPath, anomalies)
+	}
+	return action, nil
+}
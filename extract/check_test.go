@@ -0,0 +1,102 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// shortData 簇链被截短的文件的内容：4 个 512 字节的簇，FAT 中只剩前 2 个
+var shortData = bytes.Repeat([]byte("0123456789abcdef"), 128)
+
+// checkFixture 返回有损坏条目的卷：簇链过短的文件、校验和不匹配的文件和目录
+func checkFixture() *testimage.Image {
+	short := testimage.File("short.jpg", shortData)
+	short.FATChain = true
+	img := testimage.Build(testimage.Options{},
+		testimage.Dir("DCIM", testimage.File("a.jpg", []byte("a")), short, testimage.File("sum.jpg", []byte("sum")), testimage.File("z.jpg", []byte("z"))),
+		testimage.Dir("Broken", testimage.File("inner.jpg", []byte("inner"))),
+		testimage.File("top.txt", []byte("top")))
+	img.SetFAT(img.Entry("/DCIM/short.jpg").Clusters[1], exfat.EndOfClusterChain)
+	// 改动文件条目中的保留字节：只影响校验和
+	for _, p := range []string{"/DCIM/sum.jpg", "/Broken"} {
+		img.Slot(img.Entry(p), 0)[29] ^= 0xFF
+	}
+	return img
+}
+
+func TestExtractWithCheckPolicies(t *testing.T) {
+	zeroFilled := append(bytes.Clone(shortData[:1024]), make([]byte, 1024)...)
+	clean := map[string]string{"DCIM/a.jpg": "a", "DCIM/z.jpg": "z", "top.txt": "top"}
+	withDamaged := maps.Clone(clean)
+	withDamaged["DCIM/sum.jpg"] = "sum"
+	withDamaged["Broken/inner.jpg"] = "inner"
+
+	tests := []struct {
+		name    string
+		policy  CheckPolicy
+		files   map[string]string // 写出的文件
+		checked int
+		actions map[string]CheckAction // 每个有异常的条目采取的处理方式
+		short   []byte                 // 写出的 short.jpg，files 为 nil 时使用
+		err     error
+	}{
+		{"skip", CheckPolicy{exfat.AnomalyShortChain: CheckSkip, exfat.AnomalyChecksumMismatch: CheckSkip},
+			clean, 7, map[string]CheckAction{"/DCIM/short.jpg": CheckSkip, "/DCIM/sum.jpg": CheckSkip, "/Broken": CheckSkip}, nil, nil},
+		// 中止时保留已经提取的文件，之后的条目不再处理
+		{"abort", CheckPolicy{exfat.AnomalyShortChain: CheckFail, exfat.AnomalyChecksumMismatch: CheckSkip},
+			map[string]string{"DCIM/a.jpg": "a"}, 3, map[string]CheckAction{"/DCIM/short.jpg": CheckFail}, nil, ErrCheckFailed},
+		// 照常提取：簇链过短的文件按宽松模式读取，断点之后按连续簇读出原来的数据
+		{"extract anyway", CheckPolicy{},
+			nil, 8, map[string]CheckAction{"/DCIM/short.jpg": CheckExtract, "/DCIM/sum.jpg": CheckExtract, "/Broken": CheckExtract}, shortData, nil},
+		{"zero fill", CheckPolicy{exfat.AnomalyShortChain: CheckZeroFill, exfat.AnomalyChecksumMismatch: CheckZeroFill},
+			nil, 8, map[string]CheckAction{"/DCIM/short.jpg": CheckZeroFill, "/DCIM/sum.jpg": CheckZeroFill, "/Broken": CheckExtract}, zeroFilled, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.files == nil {
+				tt.files = maps.Clone(withDamaged)
+				tt.files["DCIM/short.jpg"] = string(tt.short)
+			}
+			dest := t.TempDir()
+			check, report, err := ExtractWithCheck(openFS(t, checkFixture()), "/", dest, tt.policy)
+			if !errors.Is(err, tt.err) || (err != nil && !strings.Contains(err.Error(), "/DCIM/short.jpg")) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if got := treeFiles(t, dest); !maps.Equal(got, tt.files) {
+				t.Errorf("wrote %v, want %v", keys(got), keys(tt.files))
+			}
+
+			if check.Checked != tt.checked || len(check.Findings) != len(tt.actions) {
+				t.Errorf("checked %d, findings %+v", check.Checked, check.Findings)
+			}
+			for _, f := range check.Findings {
+				if want, ok := tt.actions[f.Path]; !ok || f.Action != want || len(f.Anomalies) == 0 {
+					t.Errorf("finding %+v, want action %v", f, want)
+				}
+			}
+			if skipped := len(check.WithAction(CheckSkip)); report.Skipped != skipped {
+				t.Errorf("report skipped %d, %d findings skipped", report.Skipped, skipped)
+			}
+			if report.Failures != 0 {
+				t.Errorf("%d failures: %+v", report.Failures, report.Files)
+			}
+		})
+	}
+}
+
+// keys 返回 map 的键，用于错误信息中省略内容
+func keys(m map[string]string) []string {
+	var list []string
+	for k := range m {
+		list = append(list, k)
+	}
+	slices.Sort(list)
+	return list
+}
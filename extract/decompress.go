@@ -0,0 +1,211 @@
+package extract
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// DefaultDecompressSuffixes Decompress 默认解压的文件后缀
+var DefaultDecompressSuffixes = []string{".gz", ".zlib", ".zz", ".zip"}
+
+// DefaultDecompressMaxSize Decompress 默认允许的解压结果大小（字节）
+const DefaultDecompressMaxSize = 1 << 30
+
+// DecompressOptions Decompress 的选项
+type DecompressOptions struct {
+	Suffixes []string // 要解压的文件后缀（不区分大小写），为空时使用 DefaultDecompressSuffixes
+	Depth    int      // 最多解压的层数，0 与 1 相同；解压结果仍是压缩文件且名称仍带后缀时（如 a.log.gz.gz）继续解压
+	MaxSize  int64    // 每个文件解压结果的大小上限（字节），0 使用 DefaultDecompressMaxSize，负数表示不限制
+}
+
+// Decompress 返回供 WithTransform 使用的函数：名称带有 Suffixes 中的后缀、且开头是 gzip、zlib 或 zip 格式的文件
+// 解压后写出，文件名去掉后缀（同名文件已存在时保留原名）。格式按文件开头的魔数判断，与后缀无关。
+// zip 只解压只包含一个文件的归档，且需要把整个归档读入内存；包含多个文件的归档原样写出。
+// 数据损坏无法解压，或解压结果超过 MaxSize（压缩炸弹）时改为原样写出并在报告中给出警告（见 FileReport.Warning）。
+// 清单中记录压缩前后的大小和 CRC-32（见 ManifestEntry.Note），Bytes 为实际写出的字节数。
+func Decompress(opts DecompressOptions) func(path string, e exfat.FileEntry) TransformFunc {
+	suffixes := opts.Suffixes
+	if len(suffixes) == 0 {
+		suffixes = DefaultDecompressSuffixes
+	}
+	depth := max(opts.Depth, 1)
+	limit := opts.MaxSize
+	if limit == 0 {
+		limit = DefaultDecompressMaxSize
+	}
+	return func(_ string, e exfat.FileEntry) TransformFunc {
+		if matchSuffix(e.Name, suffixes) == "" {
+			return nil
+		}
+		return func(w io.Writer, head []byte) (io.WriteCloser, string) {
+			format := compression(head)
+			if format == "" {
+				return nil, ""
+			}
+			return newDecompressWriter(w, e.Name, format, suffixes, depth, limit), "decompress-" + format
+		}
+	}
+}
+
+// matchSuffix 返回 name 带有的后缀（保留 name 中的大小写），没有时返回空串
+func matchSuffix(name string, suffixes []string) string {
+	for _, s := range suffixes {
+		if len(name) > len(s) && strings.EqualFold(name[len(name)-len(s):], s) {
+			return name[len(name)-len(s):]
+		}
+	}
+	return ""
+}
+
+// compression 按开头的魔数判断压缩格式：gzip、zlib、zip，无法识别时返回空串
+func compression(head []byte) string {
+	switch {
+	case len(head) >= 3 && head[0] == 0x1F && head[1] == 0x8B && head[2] == 8:
+		return "gzip"
+	case len(head) >= 4 && string(head[:4]) == "PK\x03\x04":
+		return "zip"
+	case len(head) >= 2 && head[0]&0x0F == 8 && head[0]>>4 <= 7 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0:
+		// CMF 为 deflate 且窗口不超过 32 KiB，CMF 与 FLG 组成的 16 位数是 31 的倍数
+		return "zlib"
+	}
+	return ""
+}
+
+// errNotDecompressed 表示压缩数据损坏、归档包含多个文件或解压结果过大而无法解压，文件应改为原样写出
+var errNotDecompressed = errors.New("cannot decompress")
+
+// decompressWriter 接收压缩数据，在后台 goroutine 中解码并写出
+type decompressWriter struct {
+	pw     *io.PipeWriter
+	done   chan error
+	inCRC  hash.Hash32
+	in     int64
+	out    *countingWriter
+	outCRC hash.Hash32
+	format string // 最外层的格式
+	name   string // 解压之后的文件名
+	levels int    // 实际解压的层数
+	limit  int64  // 解压结果的大小上限，负数表示不限制
+}
+
+func newDecompressWriter(w io.Writer, name, format string, suffixes []string, depth int, limit int64) *decompressWriter {
+	pr, pw := io.Pipe()
+	d := &decompressWriter{
+		pw:     pw,
+		done:   make(chan error, 1),
+		inCRC:  crc32.NewIEEE(),
+		outCRC: crc32.NewIEEE(),
+		format: format,
+		name:   name,
+		limit:  limit,
+	}
+	d.out = &countingWriter{w: io.MultiWriter(w, d.outCRC)}
+	go func() {
+		err := d.decode(pr, format, suffixes, depth)
+		if err == nil {
+			// 压缩流之后的剩余数据不写出，但要读完，写入方才不会阻塞
+			io.Copy(io.Discard, pr)
+		}
+		// 解码失败时让写入方立即失败，而不是阻塞在管道上
+		pr.CloseWithError(err)
+		d.done <- err
+	}()
+	return d
+}
+
+// decode 逐层解码 r 并写出，最多 depth 层
+func (d *decompressWriter) decode(r io.Reader, format string, suffixes []string, depth int) error {
+	for {
+		decoded, err := decoder(r, format)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", errNotDecompressed, format, err)
+		}
+		d.levels++
+		d.name = strings.TrimSuffix(d.name, matchSuffix(d.name, suffixes))
+		r = decoded
+		if d.levels >= depth || matchSuffix(d.name, suffixes) == "" {
+			break
+		}
+		br := bufio.NewReader(r)
+		head, _ := br.Peek(4)
+		r = br
+		if format = compression(head); format == "" {
+			break
+		}
+	}
+	if d.limit >= 0 {
+		// 多读一个字节，区分恰好达到上限和超过上限
+		r = io.LimitReader(r, d.limit+1)
+	}
+	n, err := io.Copy(d.out, r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNotDecompressed, err)
+	}
+	if d.limit >= 0 && n > d.limit {
+		return fmt.Errorf("%w: decompressed data exceeds %d bytes", errNotDecompressed, d.limit)
+	}
+	return nil
+}
+
+// decoder 返回解码 r 的 reader；zip 归档读入内存，只接受恰好包含一个文件的归档
+func decoder(r io.Reader, format string) (io.Reader, error) {
+	switch format {
+	case "gzip":
+		return gzip.NewReader(r)
+	case "zlib":
+		return zlib.NewReader(r)
+	case "zip":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		var files []*zip.File
+		for _, f := range archive.File {
+			if !f.FileInfo().IsDir() {
+				files = append(files, f)
+			}
+		}
+		if len(files) != 1 {
+			return nil, fmt.Errorf("archive holds %d files, only single-file archives are decompressed", len(files))
+		}
+		return files[0].Open()
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func (d *decompressWriter) Write(p []byte) (int, error) {
+	d.inCRC.Write(p)
+	d.in += int64(len(p))
+	return d.pw.Write(p)
+}
+
+// Close 结束输入并等待解码完成；无法解压时返回包装了 errNotDecompressed 的错误
+func (d *decompressWriter) Close() error {
+	d.pw.Close()
+	return <-d.done
+}
+
+// Rename 返回去掉压缩后缀的文件名
+func (d *decompressWriter) Rename() string {
+	return d.name
+}
+
+// Note 返回解压前后的大小和 CRC-32
+func (d *decompressWriter) Note() string {
+	return fmt.Sprintf("%s: %d bytes crc32 %08x -> %d bytes crc32 %08x", d.format, d.in, d.inCRC.Sum32(), d.out.n, d.outCRC.Sum32())
+}
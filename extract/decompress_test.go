@@ -0,0 +1,181 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+func gzipData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zlibData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipData(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decompressBytes 对名为 name 的文件数据应用 Decompress 变换
+func decompressBytes(t *testing.T, opts DecompressOptions, name string, data []byte) ([]byte, *transformed) {
+	t.Helper()
+	tf := Decompress(opts)(name, exfat.FileEntry{Name: name, Size: int64(len(data))})
+	out, tr, err := transformBytes(tf, data)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return out, tr
+}
+
+func TestDecompressFormats(t *testing.T) {
+	plain := []byte(strings.Repeat("log line\n", 1000))
+	tests := []struct {
+		name   string
+		data   []byte
+		format string
+		rename string
+	}{
+		{"app.log.gz", gzipData(t, plain), "gzip", "app.log"},
+		{"app.log.zlib", zlibData(t, plain), "zlib", "app.log"},
+		{"app.log.zz", zlibData(t, plain), "zlib", "app.log"},
+		{"APP.LOG.GZ", gzipData(t, plain), "gzip", "APP.LOG"},
+		{"app.log.zip", zipData(t, map[string][]byte{"app.log": plain}), "zip", "app.log"},
+	}
+	for _, tt := range tests {
+		out, tr := decompressBytes(t, DecompressOptions{}, tt.name, tt.data)
+		if !bytes.Equal(out, plain) {
+			t.Errorf("%s: decompressed %d bytes, want %d", tt.name, len(out), len(plain))
+		}
+		if tr == nil || tr.name != "decompress-"+tt.format || tr.rename != tt.rename || tr.warning != "" {
+			t.Errorf("%s: transformed %+v, want format %s renamed to %s", tt.name, tr, tt.format, tt.rename)
+			continue
+		}
+		if tr.bytes != int64(len(plain)) || !strings.HasPrefix(tr.note, tt.format+": ") {
+			t.Errorf("%s: %d bytes, note %q", tt.name, tr.bytes, tr.note)
+		}
+	}
+}
+
+func TestDecompressSkipsUnmatched(t *testing.T) {
+	gz := gzipData(t, []byte("data"))
+	// 后缀不匹配时不看内容
+	if tf := Decompress(DecompressOptions{})("data.bin", exfat.FileEntry{Name: "data.bin"}); tf != nil {
+		t.Error("data.bin: got a transform for a name without a compression suffix")
+	}
+	// 后缀匹配但内容不是压缩数据时原样写出，不给出警告
+	out, tr := decompressBytes(t, DecompressOptions{}, "notes.gz", []byte("plain text"))
+	if string(out) != "plain text" || tr != nil {
+		t.Errorf("notes.gz: got %q, %+v", out, tr)
+	}
+	// 自定义后缀取代默认值
+	out, tr = decompressBytes(t, DecompressOptions{Suffixes: []string{".gzip"}}, "a.gz", gz)
+	if !bytes.Equal(out, gz) || tr != nil {
+		t.Errorf("a.gz with suffix .gzip: got %d bytes, %+v", len(out), tr)
+	}
+}
+
+func TestDecompressCorruptFallback(t *testing.T) {
+	plain := []byte(strings.Repeat("0123456789", 500))
+	gz := gzipData(t, plain)
+	truncated := gz[:len(gz)/2]
+	corrupt := append([]byte(nil), gz...)
+	for i := 20; i < len(corrupt)-8; i++ {
+		corrupt[i] ^= 0x5A
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated.gz", truncated},
+		{"corrupt.gz", corrupt},
+		{"bad.zlib", append([]byte{0x78, 0x9C}, bytes.Repeat([]byte{0xFF}, 64)...)},
+		{"two.zip", zipData(t, map[string][]byte{"a": plain, "b": plain})},
+		{"broken.zip", []byte("PK\x03\x04 not really a zip archive")},
+	}
+	for _, tt := range tests {
+		out, tr := decompressBytes(t, DecompressOptions{}, tt.name, tt.data)
+		if !bytes.Equal(out, tt.data) {
+			t.Errorf("%s: fallback wrote %d bytes, want the %d original bytes", tt.name, len(out), len(tt.data))
+		}
+		if tr == nil || tr.name != "" || tr.rename != "" || tr.bytes != int64(len(tt.data)) || !strings.HasSuffix(tr.warning, "written unchanged") {
+			t.Errorf("%s: transformed %+v, want an unchanged write with a warning", tt.name, tr)
+		}
+	}
+}
+
+func TestDecompressDepth(t *testing.T) {
+	plain := []byte("nested")
+	twice := gzipData(t, gzipData(t, plain))
+	out, tr := decompressBytes(t, DecompressOptions{}, "a.log.gz.gz", twice)
+	if !bytes.Equal(out, gzipData(t, plain)) || tr.rename != "a.log.gz" {
+		t.Errorf("depth 1: got %d bytes named %q", len(out), tr.rename)
+	}
+	out, tr = decompressBytes(t, DecompressOptions{Depth: 2}, "a.log.gz.gz", twice)
+	if !bytes.Equal(out, plain) || tr.rename != "a.log" {
+		t.Errorf("depth 2: got %q named %q", out, tr.rename)
+	}
+}
+
+func TestDecompressMaxSize(t *testing.T) {
+	// 1 MiB 的零压缩后只有约 1 KiB
+	bomb := gzipData(t, make([]byte, 1<<20))
+	out, tr := decompressBytes(t, DecompressOptions{MaxSize: 64 << 10}, "bomb.gz", bomb)
+	if !bytes.Equal(out, bomb) || tr == nil || !strings.Contains(tr.warning, "exceeds 65536 bytes") {
+		t.Errorf("over the limit: got %d bytes, %+v", len(out), tr)
+	}
+
+	out, tr = decompressBytes(t, DecompressOptions{MaxSize: 1 << 20}, "exact.gz", bomb)
+	if len(out) != 1<<20 || tr == nil || tr.warning != "" {
+		t.Errorf("exactly at the limit: got %d bytes, %+v", len(out), tr)
+	}
+	out, tr = decompressBytes(t, DecompressOptions{MaxSize: -1}, "unlimited.gz", bomb)
+	if len(out) != 1<<20 || tr == nil || tr.warning != "" {
+		t.Errorf("no limit: got %d bytes, %+v", len(out), tr)
+	}
+
+	zipBomb := zipData(t, map[string][]byte{"zeros": make([]byte, 1<<20)})
+	out, tr = decompressBytes(t, DecompressOptions{MaxSize: 4096}, "bomb.zip", zipBomb)
+	if !bytes.Equal(out, zipBomb) || tr == nil || tr.warning == "" {
+		t.Errorf("zip over the limit: got %d bytes, %+v", len(out), tr)
+	}
+}
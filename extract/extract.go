@@ -0,0 +1,543 @@
+// Package extract 实现把 exFAT 文件系统中的文件提取到本地文件系统的策略
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// Path 提取文件或目录到指定目录下
+// 文件写入 destDir/<文件名>，目录则递归提取其内容到 destDir
+func Path(fsys *exfat.ExFATFileSystem, srcPath, destDir string) error {
+	entry, err := fsys.Stat(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to get entry for %s: %w", srcPath, err)
+	}
+
+	if entry.IsDir {
+		return All(fsys, srcPath, destDir)
+	}
+
+	return File(fsys, srcPath, filepath.Join(destDir, entry.Name))
+}
+
+// File 提取文件到本地路径
+// 文件以流的方式复制，不会整个读入内存
+func File(fsys *exfat.ExFATFileSystem, srcPath, destPath string) error {
+	_, _, errs := copyFile(fsys, srcPath, []string{destPath}, nil)
+	return errs[0]
+}
+
+// copyFile 把文件以流的方式同时复制到多个本地路径，返回读取簇链使用的 FAT 编号和每个目标各自的错误
+// 数据只从映像读取一次；某个目标写入失败后不再向它写入，其他目标继续。
+// tf 不为 nil 时按文件开头决定是否变换，变换过的文件同时返回变换的名称和写出的字节数。
+func copyFile(fsys *exfat.ExFATFileSystem, srcPath string, destPaths []string, tf TransformFunc) (int, *transformed, []error) {
+	errs := make([]error, len(destPaths))
+	src, err := fsys.Open(srcPath)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return 0, nil, errs
+	}
+	defer src.Close()
+
+	targets := make([]*target, len(destPaths))
+	var live []*target
+	for i, destPath := range destPaths {
+		// 确保目标目录存在
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			errs[i] = fmt.Errorf("failed to create destination directory: %v", err)
+			continue
+		}
+		f, err := os.Create(destPath)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to write file: %v", err)
+			continue
+		}
+		targets[i] = &target{f: f}
+		live = append(live, targets[i])
+	}
+	if len(live) == 0 {
+		return src.FAT(), nil, errs
+	}
+
+	tr, copyErr := copyTransformed(live, src, tf)
+	for i, t := range targets {
+		if t == nil {
+			continue
+		}
+		err := t.err
+		if err == nil {
+			err = copyErr
+		}
+		if closeErr := t.f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to write file: %v", err)
+		}
+	}
+	return src.FAT(), tr, errs
+}
+
+// copyTransformed 把 src 复制到每个目标，tf 决定变换时经过变换，并返回变换的结果
+// 判断用的文件开头经 ReadAt 读取，不影响之后从头开始的复制。
+// 无法解压（压缩数据损坏等）时清空目标，改为原样写出。
+func copyTransformed(targets []*target, src *exfat.File, tf TransformFunc) (*transformed, error) {
+	writers := make([]io.Writer, len(targets))
+	for i, t := range targets {
+		writers[i] = t
+	}
+	w := io.MultiWriter(writers...)
+	if tf == nil {
+		_, err := io.Copy(w, src)
+		return nil, err
+	}
+	head := make([]byte, min(src.Stat().Size, SniffSize))
+	n, _ := src.ReadAt(head, 0)
+	counter := &countingWriter{w: w}
+	wc, name := tf(counter, head[:n])
+	if wc == nil {
+		_, err := io.Copy(w, src)
+		return nil, err
+	}
+	_, err := io.Copy(wc, src)
+	if closeErr := wc.Close(); errors.Is(closeErr, errNotDecompressed) {
+		for _, t := range targets {
+			t.rewind()
+		}
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		n, err := io.Copy(w, src)
+		return fallback(closeErr, n), err
+	} else if err == nil {
+		err = closeErr
+	}
+	t := &transformed{name: name, bytes: counter.n}
+	t.finish(wc)
+	return t, err
+}
+
+// target 复制的一个目标文件
+// 写入失败时记录错误并丢弃后续数据，使 io.MultiWriter 中的其他目标不受影响
+type target struct {
+	f   *os.File
+	err error
+}
+
+func (t *target) Write(p []byte) (int, error) {
+	if t.err == nil {
+		_, t.err = t.f.Write(p)
+	}
+	return len(p), nil
+}
+
+// rewind 清空已经写入的数据，从头重新写入
+func (t *target) rewind() {
+	if t.err == nil {
+		_, t.err = t.f.Seek(0, io.SeekStart)
+	}
+	if t.err == nil {
+		t.err = t.f.Truncate(0)
+	}
+}
+
+// writeFile 把数据写入本地路径
+func writeFile(destPath string, data []byte) error {
+	// 确保目标目录存在
+	destDir := filepath.Dir(destPath)
+	err := os.MkdirAll(destDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create destination directory: %v", err)
+	}
+
+	// 写入文件
+	err = os.WriteFile(destPath, data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+
+	return nil
+}
+
+// All 递归提取目录内容到本地路径
+// 单个条目的失败不会中断提取，有条目失败时返回包装了 ErrIncomplete 的错误；需要逐条目的结果时使用 AllWithReport。
+func All(fsys *exfat.ExFATFileSystem, srcPath, destPath string) error {
+	_, err := AllWithReport(fsys, srcPath, destPath)
+	return err
+}
+
+// extractor 递归提取时的状态
+// 单个条目的失败不会中断提取，而是记入报告。
+type extractor struct {
+	fsys     *exfat.ExFATFileSystem
+	opts     *options
+	root     string // 主目标的根目录，镜像目标按相对于它的路径写入
+	report   *Report
+	progress Progress
+
+	placed  map[string]bool   // 按日期分组或平铺时已经使用的目标路径（小写）
+	sources map[string]string // 按日期分组或平铺时源路径对应的目标路径
+}
+
+// newExtractor 创建提取到 root 的 extractor
+func newExtractor(fsys *exfat.ExFATFileSystem, root string, opts []Option) *extractor {
+	o := applyOptions(opts)
+	return &extractor{fsys: fsys, opts: o, root: root, report: newReport(o)}
+}
+
+// dir 提取一个目录
+func (x *extractor) dir(srcPath, destPath string) error {
+	// 获取当前目录的内容
+	entries, err := x.fsys.ListDir(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to list directory %s: %w", srcPath, err)
+	}
+
+	// 确保目标目录存在；按日期分组和平铺时只创建根目录，文件的目录在写出时创建
+	if x.mirrored() || destPath == x.root {
+		if err := os.MkdirAll(destPath, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %v", destPath, err)
+		}
+	}
+
+	// 按目录一次性取得子条目的异常
+	anomalies, err := x.fsys.DirAnomalies(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to check directory %s: %v", srcPath, err)
+	}
+
+	// AppleDouble 文件推迟到同一目录的其他条目处理完之后，合并时需要对应的文件已经写出
+	var appleDouble []exfat.FileEntry
+	defer func() { x.appleDouble(appleDouble, srcPath, destPath) }()
+
+	for _, entry := range entries {
+		if x.opts.sampler != nil && x.opts.sampler.Stopped() != "" {
+			return errSampleDone
+		}
+		if x.opts.appleDouble != AppleDoubleKeep && isAppleDouble(entry) {
+			appleDouble = append(appleDouble, entry)
+			continue
+		}
+		// 构建源路径（在 VHD 中使用正斜杠）和目标路径
+		srcFullPath := path.Join(srcPath, entry.Name)
+		destFullPath := filepath.Join(destPath, entry.Name)
+		if x.filtered(entry, srcFullPath, destFullPath) {
+			continue
+		}
+		action, err := x.checkEntry(entry, srcFullPath, anomalies[entry.Name])
+		if err != nil {
+			return err
+		}
+		if action == CheckSkip {
+			x.skip(entry, srcFullPath, destFullPath, OutcomeSkippedCheck)
+			continue
+		}
+
+		if entry.IsDir {
+			// 创建目录；按日期分组和平铺时不创建卷中的目录，清单中目录的目标为空
+			dirDest := ""
+			var mirrors []string
+			var mirrorErrs []error
+			if x.mirrored() {
+				dirDest = destFullPath
+				if err := os.MkdirAll(destFullPath, 0755); err != nil {
+					x.record(entry, srcFullPath, destFullPath, anomalies[entry.Name], nil, 0, nil, err, 0)
+					continue
+				}
+				mirrors = x.mirrorPaths(destFullPath)
+				mirrorErrs = make([]error, len(mirrors))
+				for i, mirror := range mirrors {
+					mirrorErrs[i] = os.MkdirAll(mirror, 0755)
+				}
+			}
+
+			// 尝试递归处理子目录；检查策略要求中止时不再继续，达到抽样上限时记下已处理的部分后结束
+			err := x.dir(srcFullPath, destFullPath)
+			if dirDest != "" {
+				// 写入内容会更新目录的修改时间，因此在处理完内容之后设置（空目录同样设置）
+				for _, dir := range append([]string{dirDest}, mirrors...) {
+					_ = setFileTimes(dir, entry)
+				}
+			}
+			if errors.Is(err, ErrCheckFailed) {
+				return err
+			}
+			if errors.Is(err, errSampleDone) {
+				x.record(entry, srcFullPath, dirDest, anomalies[entry.Name], nil, 0, nil, nil, 0)
+				x.recordMirrors(entry, srcFullPath, mirrors, nil, mirrorErrs, 0)
+				return err
+			}
+			// 无法读取的目录（如簇号无效）记为失败，目录结构已经创建，继续处理其他项目
+			x.record(entry, srcFullPath, dirDest, anomalies[entry.Name], nil, 0, nil, err, 0)
+			x.recordMirrors(entry, srcFullPath, mirrors, nil, mirrorErrs, 0)
+			continue
+		}
+
+		// 处理文件，失败时继续处理其他文件，不中断整个提取过程
+		if !x.sample(entry, srcFullPath, destFullPath) {
+			if x.opts.sampler.Stopped() != "" {
+				return errSampleDone
+			}
+			continue
+		}
+		x.extractFile(entry, srcFullPath, destFullPath, anomalies[entry.Name], action)
+	}
+
+	return nil
+}
+
+// extractFile 提取一个文件到主目标和各镜像目标，并记录结果
+func (x *extractor) extractFile(entry exfat.FileEntry, srcPath, destPath string, anomalies []exfat.Anomaly, action CheckAction) {
+	if x.opts.aggregator != nil {
+		x.opts.aggregator.Begin(x.opts.worker, srcPath)
+	}
+	start := time.Now()
+	destPath = x.place(entry, destPath)
+	dests := append([]string{destPath}, x.mirrorPaths(destPath)...)
+	var tf TransformFunc
+	if x.opts.transform != nil {
+		tf = x.opts.transform(srcPath, entry)
+	}
+	repair, fat, t, errs := x.file(srcPath, dests, action, tf)
+	if t != nil && t.rename != "" {
+		renameTransformed(dests, errs, t)
+		destPath = dests[0]
+	}
+	if !x.mirrored() {
+		if x.sources == nil {
+			x.sources = make(map[string]string)
+		}
+		x.sources[srcPath] = destPath
+	}
+	elapsed := time.Since(start)
+	for i, dest := range dests {
+		if errs[i] == nil {
+			// 设置文件的时间（如果可用），每个目标分别设置；设置失败不影响提取结果
+			_ = setFileTimes(dest, entry)
+		}
+	}
+	x.record(entry, srcPath, destPath, anomalies, repair, fat, t, errs[0], elapsed)
+	x.recordMirrors(entry, srcPath, dests[1:], t, errs[1:], elapsed)
+}
+
+// renameTransformed 把变换过的文件改为变换要求的名称（如解压后去掉后缀），dests 中的路径随之更新
+// 同名文件已经存在时保留原名，原因记入清单的说明。
+func renameTransformed(dests []string, errs []error, t *transformed) {
+	for i, dest := range dests {
+		if errs[i] != nil {
+			continue
+		}
+		renamed := filepath.Join(filepath.Dir(dest), t.rename)
+		if _, err := os.Lstat(renamed); err == nil {
+			t.note = strings.TrimPrefix(t.note+"; ", "; ") + fmt.Sprintf("%s exists, name kept", renamed)
+			continue
+		}
+		if err := os.Rename(dest, renamed); err != nil {
+			errs[i] = fmt.Errorf("failed to rename decompressed file: %v", err)
+			continue
+		}
+		dests[i] = renamed
+	}
+}
+
+// filtered 判断条目是否被预设或过滤回调排除，被排除的条目记为跳过
+func (x *extractor) filtered(entry exfat.FileEntry, srcPath, destPath string) bool {
+	excluded := x.opts.filter != nil && !x.opts.filter(srcPath, entry)
+	for _, p := range x.opts.presets {
+		excluded = excluded || p.Excludes(srcPath, entry)
+	}
+	if !excluded {
+		return false
+	}
+	x.skip(entry, srcPath, destPath, OutcomeSkippedFilter)
+	return true
+}
+
+// sample 按抽样器决定是否提取文件；未被选中的文件记为跳过，达到上限时不做记录
+func (x *extractor) sample(entry exfat.FileEntry, srcPath, destPath string) bool {
+	if x.opts.sampler == nil || x.opts.sampler.take(entry.Size) {
+		return true
+	}
+	if x.opts.sampler.Stopped() == "" {
+		x.skip(entry, srcPath, destPath, OutcomeSkippedSample)
+	}
+	return false
+}
+
+// sampled 把触发停止的抽样上限记入报告
+func (x *extractor) sampled() {
+	if x.opts.sampler != nil {
+		x.report.Stopped = x.opts.sampler.Stopped()
+	}
+}
+
+// skip 记录被有意跳过的条目：计入报告和汇总器的跳过数，并通知清单回调
+func (x *extractor) skip(entry exfat.FileEntry, srcPath, destPath string, outcome Outcome) {
+	x.report.Skipped++
+	if !entry.IsDir && x.opts.aggregator != nil {
+		x.opts.aggregator.Skip(x.opts.worker)
+	}
+	x.manifest(entry, srcPath, destPath, outcome, nil, 0, nil)
+}
+
+// file 提取一个文件到 destPaths 中的每个目标，返回各目标的错误
+// 启用修复计划（或检查策略要求）且簇链断裂时按计划读取，并返回使用的计划；
+// 检查策略要求补零时，簇链中断之后的部分以零填充。按常规方式读取时同时返回使用的 FAT 编号。
+// tf 不为 nil 时写出的数据经过变换，变换过的文件同时返回变换的结果。
+func (x *extractor) file(srcPath string, destPaths []string, action CheckAction, tf TransformFunc) (*exfat.RepairPlan, int, *transformed, []error) {
+	if action == CheckZeroFill {
+		data, _, err := x.fsys.ReadFileZeroFilled(srcPath)
+		t, errs := writeAll(destPaths, data, err, tf)
+		return nil, 0, t, errs
+	}
+	if !x.opts.repair && action != CheckRepair {
+		fat, t, errs := copyFile(x.fsys, srcPath, destPaths, tf)
+		return nil, fat, t, errs
+	}
+
+	plan, err := x.fsys.PlanChainRepair(srcPath)
+	if err != nil || !plan.Broken() {
+		// 无法制定计划时按常规方式读取
+		fat, t, errs := copyFile(x.fsys, srcPath, destPaths, tf)
+		return nil, fat, t, errs
+	}
+	data, err := x.fsys.ReadFileWithPlan(srcPath, plan)
+	t, errs := writeAll(destPaths, data, err, tf)
+	return &plan, 0, t, errs
+}
+
+// writeAll 把读取的数据（经过 tf 变换）写入每个目标；读取失败（err 不为 nil）时每个目标都记为该错误
+func writeAll(destPaths []string, data []byte, err error, tf TransformFunc) (*transformed, []error) {
+	var t *transformed
+	if err == nil {
+		data, t, err = transformBytes(tf, data)
+	}
+	errs := make([]error, len(destPaths))
+	for i, destPath := range destPaths {
+		if errs[i] = err; err == nil {
+			errs[i] = writeFile(destPath, data)
+		}
+	}
+	return t, errs
+}
+
+// mirrorPaths 返回主目标路径在各镜像目标中对应的路径
+func (x *extractor) mirrorPaths(destPath string) []string {
+	if len(x.opts.mirrors) == 0 {
+		return nil
+	}
+	rel, err := filepath.Rel(x.root, destPath)
+	if err != nil {
+		rel = filepath.Base(destPath)
+	}
+	paths := make([]string, len(x.opts.mirrors))
+	for i, mirror := range x.opts.mirrors {
+		paths[i] = filepath.Join(mirror, rel)
+	}
+	return paths
+}
+
+// record 把条目的提取结果写入报告，并通知清单与进度回调
+// fat 是读取文件使用的 FAT 编号，只有使用了非活动 FAT 时才列入报告；t 是文件经过的变换，没有时为 nil
+func (x *extractor) record(entry exfat.FileEntry, srcPath, destPath string, anomalies []exfat.Anomaly, repair *exfat.RepairPlan, fat int, t *transformed, err error, elapsed time.Duration) {
+	if !entry.IsDir && x.opts.aggregator != nil {
+		x.opts.aggregator.Finish(x.opts.worker, entry.Size, err)
+	}
+	if !entry.IsDir && err == nil {
+		x.progress.Files++
+		x.progress.Bytes += entry.Size
+		x.progress.Path = srcPath
+		x.report.Extracted++
+		x.report.Bytes += entry.Size
+		if x.opts.progress != nil {
+			x.opts.progress(x.progress)
+		}
+	}
+	if fat == x.fsys.ActiveFAT() {
+		fat = 0
+	}
+	var warning string
+	if t != nil {
+		warning = t.warning
+	}
+	x.report.add(FileReport{Path: srcPath, Anomalies: anomalies, Err: err, Repair: repair, FAT: fat, Warning: warning})
+	x.manifest(entry, srcPath, destPath, outcomeOf(err), err, elapsed, t)
+}
+
+// recordMirrors 把条目在镜像目标上的结果写入报告并通知清单回调
+// 进度只按主目标统计
+func (x *extractor) recordMirrors(entry exfat.FileEntry, srcPath string, destPaths []string, t *transformed, errs []error, elapsed time.Duration) {
+	for i, destPath := range destPaths {
+		x.report.add(FileReport{Path: srcPath, Dest: destPath, Err: errs[i]})
+		x.manifest(entry, srcPath, destPath, outcomeOf(errs[i]), errs[i], elapsed, t)
+	}
+}
+
+// manifest 通知清单回调（如果设置了），t 为文件经过的变换
+func (x *extractor) manifest(entry exfat.FileEntry, srcPath, destPath string, outcome Outcome, err error, elapsed time.Duration, t *transformed) {
+	if x.opts.manifest == nil {
+		return
+	}
+	var written int64
+	var transform, note string
+	if outcome == OutcomeOK && !entry.IsDir {
+		written = entry.Size
+		if t != nil {
+			written, transform, note = t.bytes, t.name, t.note
+			if t.warning != "" {
+				note = t.warning
+			}
+		}
+	}
+	x.opts.manifest(ManifestEntry{
+		Path:      srcPath,
+		ID:        entry.ID(),
+		Dest:      destPath,
+		IsDir:     entry.IsDir,
+		Size:      entry.Size,
+		ModTime:   entry.ModTime,
+		Outcome:   outcome,
+		Bytes:     written,
+		Duration:  elapsed,
+		Transform: transform,
+		Note:      note,
+		Err:       err,
+	})
+}
+
+// outcomeOf 返回提取结果对应的 Outcome
+func outcomeOf(err error) Outcome {
+	if err != nil {
+		return OutcomeFailed
+	}
+	return OutcomeOK
+}
+
+// setFileTimes 设置文件或目录的修改时间和访问时间，平台支持时（Windows）还设置创建时间
+// 没有记录的时间（零值）保持不变；没有访问时间时与之前一样使用修改时间。
+func setFileTimes(path string, entry exfat.FileEntry) error {
+	if !entry.CreateTime.IsZero() {
+		if err := setBirthTime(path, entry.CreateTime); err != nil {
+			return err
+		}
+	}
+	atime := entry.AccessTime
+	if atime.IsZero() {
+		atime = entry.ModTime
+	}
+	if atime.IsZero() && entry.ModTime.IsZero() {
+		return nil
+	}
+	return os.Chtimes(path, atime, entry.ModTime)
+}
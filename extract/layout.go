@@ -0,0 +1,169 @@
+package extract
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// Layout 决定提取的文件在目标目录中的位置（见 WithLayout）
+type Layout struct {
+	kind    layoutKind
+	pattern string // 按日期分组时的 time 布局
+}
+
+type layoutKind int
+
+const (
+	layoutMirror layoutKind = iota
+	layoutByDate
+	layoutFlat
+)
+
+var (
+	// LayoutMirror 按卷中的目录结构写出（默认）
+	LayoutMirror = Layout{kind: layoutMirror}
+	// LayoutFlat 所有文件直接写入目标目录，不创建子目录；同名文件加后缀区分（见 WithLayout）
+	LayoutFlat = Layout{kind: layoutFlat}
+)
+
+// LayoutByDate 按文件的时间分组：以 pattern（Go 的 time 布局，如 "2006/01/02"）格式化时间作为目标目录下的子路径，
+// 文件直接写入该子路径，不保留卷中的目录结构。使用的时间由 WithDateSource 选择，按时间戳记录的时区格式化；
+// 时间为零（未记录）的文件写入 UndatedDir。
+func LayoutByDate(pattern string) Layout {
+	return Layout{kind: layoutByDate, pattern: pattern}
+}
+
+// String 返回布局的名称，如 "mirror"、"flat"、"date:2006/01/02"
+func (l Layout) String() string {
+	switch l.kind {
+	case layoutByDate:
+		return "date:" + l.pattern
+	case layoutFlat:
+		return "flat"
+	default:
+		return "mirror"
+	}
+}
+
+// ParseLayout 解析 Layout.String 的结果，"date" 不带布局时使用 DefaultDateLayout
+func ParseLayout(s string) (Layout, error) {
+	switch {
+	case s == "" || s == "mirror":
+		return LayoutMirror, nil
+	case s == "flat":
+		return LayoutFlat, nil
+	case s == "date":
+		return LayoutByDate(DefaultDateLayout), nil
+	case strings.HasPrefix(s, "date:") && len(s) > len("date:"):
+		return LayoutByDate(strings.TrimPrefix(s, "date:")), nil
+	}
+	return Layout{}, fmt.Errorf("unknown layout %q (want mirror, flat or date[:layout])", s)
+}
+
+// DefaultDateLayout 按日期分组时默认的子路径布局
+const DefaultDateLayout = "2006/01/02"
+
+// UndatedDir 按日期分组时没有时间的文件写入的子目录
+const UndatedDir = "undated"
+
+// DateSource 按日期分组时使用的时间
+type DateSource int
+
+const (
+	DateModified DateSource = iota // 修改时间（默认）
+	DateCreated                    // 创建时间，相机写入的文件通常就是拍摄时间
+)
+
+// String 返回时间来源的名称："modify" 或 "create"
+func (s DateSource) String() string {
+	if s == DateCreated {
+		return "create"
+	}
+	return "modify"
+}
+
+// ParseDateSource 解析 DateSource.String 的结果
+func ParseDateSource(s string) (DateSource, error) {
+	switch s {
+	case "", "modify":
+		return DateModified, nil
+	case "create":
+		return DateCreated, nil
+	}
+	return DateModified, fmt.Errorf("unknown date source %q (want modify or create)", s)
+}
+
+// WithLayout 设置提取的目标布局，默认为 LayoutMirror
+// LayoutByDate 和 LayoutFlat 不在目标中创建卷中的目录，清单中目录的 Dest 为空，文件的 Dest 为实际写出的路径。
+// 这两种布局下写到同一路径的文件（不区分大小写）按遇到的顺序在扩展名前加 "_2"、"_3" 等后缀，
+// 只与本次提取写出的文件比较，目标中已有的文件照常覆盖。
+func WithLayout(l Layout) Option {
+	return func(o *options) {
+		o.layout = l
+	}
+}
+
+// WithDateSource 设置 LayoutByDate 使用的时间，默认为修改时间
+func WithDateSource(s DateSource) Option {
+	return func(o *options) {
+		o.dateSource = s
+	}
+}
+
+// mirrored 判断是否按卷中的目录结构写出
+func (x *extractor) mirrored() bool {
+	return x.opts.layout.kind == layoutMirror
+}
+
+// place 按布局返回文件的目标路径，destPath 是按目录结构写出时的路径
+// 按日期分组和平铺时记录已经使用的路径，同名的文件加后缀。
+func (x *extractor) place(entry exfat.FileEntry, destPath string) string {
+	var dir string
+	switch x.opts.layout.kind {
+	case layoutByDate:
+		t := entry.ModTime
+		if x.opts.dateSource == DateCreated {
+			t = entry.CreateTime
+		}
+		dir = filepath.Join(x.root, dateDir(t, x.opts.layout.pattern))
+	case layoutFlat:
+		dir = x.root
+	default:
+		return destPath
+	}
+
+	if x.placed == nil {
+		x.placed = make(map[string]bool)
+	}
+	ext := filepath.Ext(entry.Name)
+	base := strings.TrimSuffix(entry.Name, ext)
+	name := entry.Name
+	for n := 2; x.placed[strings.ToLower(filepath.Join(dir, name))]; n++ {
+		name = fmt.Sprintf("%s_%d%s", base, n, ext)
+	}
+	placed := filepath.Join(dir, name)
+	x.placed[strings.ToLower(placed)] = true
+	return placed
+}
+
+// dateDir 以 pattern 格式化 t 作为子路径，t 为零时返回 UndatedDir
+// 格式化结果中的 "." 和 ".." 路径元素被去掉，使子路径总在目标目录之内。
+func dateDir(t time.Time, pattern string) string {
+	if t.IsZero() {
+		return UndatedDir
+	}
+	var parts []string
+	for _, part := range strings.Split(t.Format(pattern), "/") {
+		if part != "" && part != "." && part != ".." {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return UndatedDir
+	}
+	return filepath.Join(parts...)
+}
@@ -0,0 +1,190 @@
+package extract
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// treeFiles 返回 root 下所有文件的相对路径（使用 "/"）和内容
+func treeFiles(t *testing.T, root string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// dated 返回修改时间为 modified、创建时间为 created 的文件节点
+func dated(name, data string, modified, created time.Time) *testimage.Node {
+	n := testimage.File(name, []byte(data))
+	n.Modified, n.Created = modified, created
+	return n
+}
+
+func TestParseLayout(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"", "mirror"},
+		{"mirror", "mirror"},
+		{"flat", "flat"},
+		{"date", "date:2006/01/02"},
+		{"date:2006-01", "date:2006-01"},
+	} {
+		l, err := ParseLayout(tt.in)
+		if err != nil || l.String() != tt.want {
+			t.Errorf("ParseLayout(%q) = %v, %v; want %s", tt.in, l, err, tt.want)
+		}
+		if again, err := ParseLayout(l.String()); err != nil || again != l {
+			t.Errorf("%s does not round-trip: %v, %v", l, again, err)
+		}
+	}
+	for _, bad := range []string{"date:", "tree", "Flat"} {
+		if _, err := ParseLayout(bad); err == nil {
+			t.Errorf("ParseLayout(%q) accepted", bad)
+		}
+	}
+	for _, s := range []DateSource{DateModified, DateCreated} {
+		if got, err := ParseDateSource(s.String()); err != nil || got != s {
+			t.Errorf("ParseDateSource(%q) = %v, %v", s.String(), got, err)
+		}
+	}
+	if _, err := ParseDateSource("access"); err == nil {
+		t.Error("ParseDateSource accepted access")
+	}
+}
+
+func TestDateDir(t *testing.T) {
+	ts := time.Date(2024, 2, 3, 4, 5, 6, 0, time.FixedZone("", 9*3600))
+	for _, tt := range []struct {
+		t       time.Time
+		pattern string
+		want    string
+	}{
+		{ts, "2006/01/02", filepath.Join("2024", "02", "03")},
+		{ts, "2006-01", "2024-02"},
+		{ts, "/2006//01/", filepath.Join("2024", "02")},
+		{ts, "../2006/./01", filepath.Join("2024", "02")}, // 不能离开目标目录
+		{ts, "..", UndatedDir},
+		{time.Time{}, "2006", UndatedDir},
+		// 按时间戳记录的时区格式化：UTC 中仍是 2 月 2 日
+		{time.Date(2024, 2, 3, 1, 0, 0, 0, time.FixedZone("", 9*3600)), "2006/01/02", filepath.Join("2024", "02", "03")},
+	} {
+		if got := dateDir(tt.t, tt.pattern); got != tt.want {
+			t.Errorf("dateDir(%v, %q) = %q, want %q", tt.t, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestFlatLayoutCollisions(t *testing.T) {
+	img := testimage.Build(testimage.Options{},
+		testimage.Dir("A", testimage.File("x.jpg", []byte("A/x")), testimage.File("noext", []byte("A/noext"))),
+		testimage.Dir("B", testimage.File("X.JPG", []byte("B/X")), testimage.File("noext", []byte("B/noext"))),
+		testimage.Dir("C", testimage.File("x_2.jpg", []byte("C/x_2")), testimage.Dir("D", testimage.File("x.jpg", []byte("C/D/x")))))
+	dest := t.TempDir()
+	var manifest []ManifestEntry
+	_, err := PathWithReport(openFS(t, img), "/", dest, WithLayout(LayoutFlat), WithManifest(func(m ManifestEntry) {
+		manifest = append(manifest, m)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 同名文件不区分大小写，按遇到的顺序加后缀；已经带后缀的文件不会被覆盖
+	want := map[string]string{
+		"x.jpg":     "A/x",
+		"noext":     "A/noext",
+		"X_2.JPG":   "B/X",
+		"noext_2":   "B/noext",
+		"x_2_2.jpg": "C/x_2",
+		"x_3.jpg":   "C/D/x",
+	}
+	if got := treeFiles(t, dest); !maps.Equal(got, want) {
+		t.Errorf("flat layout wrote %v, want %v", got, want)
+	}
+	for _, m := range manifest {
+		if m.IsDir && m.Dest != "" {
+			t.Errorf("directory %s has destination %s", m.Path, m.Dest)
+		}
+		if !m.IsDir && filepath.Dir(m.Dest) != dest {
+			t.Errorf("file %s written to %s, outside the destination root", m.Path, m.Dest)
+		}
+	}
+}
+
+func TestDateLayout(t *testing.T) {
+	jan := time.Date(2023, 1, 15, 10, 0, 0, 0, time.UTC)
+	feb := time.Date(2023, 2, 20, 10, 0, 0, 0, time.UTC)
+	img := testimage.Build(testimage.Options{},
+		testimage.Dir("DCIM",
+			dated("a.jpg", "a", jan, feb),
+			dated("b.jpg", "b", feb, jan),
+			testimage.Dir("Sub", dated("a.jpg", "sub a", jan, jan))),
+		dated("undated.txt", "u", jan, jan))
+	// 把 undated.txt 的修改时间和创建时间清零（未记录）
+	e := img.Entry("/undated.txt")
+	binary.LittleEndian.PutUint32(img.Slot(e, 0)[8:], 0)
+	binary.LittleEndian.PutUint32(img.Slot(e, 0)[12:], 0)
+	img.Resum(e)
+
+	tests := []struct {
+		source DateSource
+		want   map[string]string
+	}{
+		{DateModified, map[string]string{
+			"2023-01/a.jpg":             "a",
+			"2023-02/b.jpg":             "b",
+			"2023-01/a_2.jpg":           "sub a",
+			UndatedDir + "/undated.txt": "u",
+		}},
+		{DateCreated, map[string]string{
+			"2023-02/a.jpg":             "a",
+			"2023-01/b.jpg":             "b",
+			"2023-01/a.jpg":             "sub a",
+			UndatedDir + "/undated.txt": "u",
+		}},
+	}
+	for _, tt := range tests {
+		dest := t.TempDir()
+		if _, err := PathWithReport(openFS(t, img), "/", dest, WithLayout(LayoutByDate("2006-01")), WithDateSource(tt.source)); err != nil {
+			t.Fatal(err)
+		}
+		if got := treeFiles(t, dest); !maps.Equal(got, tt.want) {
+			t.Errorf("%s: wrote %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestMirrorLayoutKeepsDuplicates(t *testing.T) {
+	img := testimage.Build(testimage.Options{},
+		testimage.Dir("A", testimage.File("x.jpg", []byte("A/x"))),
+		testimage.Dir("B", testimage.File("x.jpg", []byte("B/x"))))
+	dest := t.TempDir()
+	if _, err := PathWithReport(openFS(t, img), "/", dest); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"A/x.jpg": "A/x", "B/x.jpg": "B/x"}
+	if got := treeFiles(t, dest); !maps.Equal(got, want) {
+		t.Errorf("mirror layout wrote %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,136 @@
+package extract
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// ManifestEntry 描述一个已处理的条目，在条目处理完毕后立即交给清单回调
+type ManifestEntry struct {
+	Path      string        // 源路径
+	ID        string        // 条目的确定性标识（exfat.EntryID）
+	Dest      string        // 本地目标路径
+	IsDir     bool          // 是否为目录
+	Size      int64         // 文件大小（目录为 0）
+	ModTime   time.Time     // 修改时间
+	Outcome   Outcome       // 处理结果
+	Bytes     int64         // 写出的字节数，经过变换的文件可能与 Size 不同
+	Duration  time.Duration // 读取和写出用时
+	Transform string        // 写出时应用的变换（见 WithTransform），未变换时为空
+	Note      string        // 变换的说明，如解压前后的大小和 CRC-32，或改为原样写出的原因
+	Err       error         // 提取失败的原因（成功或跳过时为 nil）
+}
+
+// MarshalJSON 以 JSON 对象编码清单条目，Err 编码为字符串
+func (e ManifestEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path      string    `json:"path"`
+		ID        string    `json:"id,omitempty"`
+		Dest      string    `json:"dest"`
+		IsDir     bool      `json:"is_dir"`
+		Size      int64     `json:"size"`
+		ModTime   time.Time `json:"mtime"`
+		Outcome   Outcome   `json:"outcome"`
+		Bytes     int64     `json:"bytes"`
+		Duration  int64     `json:"duration_ns"`
+		Transform string    `json:"transform,omitempty"`
+		Note      string    `json:"note,omitempty"`
+		Err       string    `json:"error,omitempty"`
+	}{e.Path, e.ID, e.Dest, e.IsDir, e.Size, e.ModTime, e.Outcome, e.Bytes, int64(e.Duration), e.Transform, e.Note, errorString(e.Err)})
+}
+
+// Progress 提取进度（累计值）
+type Progress struct {
+	Files int    // 已成功写出的文件数
+	Bytes int64  // 已写出的字节数
+	Path  string // 刚处理完的源路径
+}
+
+// Option 配置提取过程
+type Option func(*options)
+
+// options 提取选项
+type options struct {
+	manifest func(ManifestEntry) // 每处理完一个条目调用一次
+	progress func(Progress)      // 每写出一个文件调用一次
+	repair   bool                // 簇链断裂的文件按修复计划读取
+
+	aggregator *ProgressAggregator // 汇总进度的汇总器
+	worker     int                 // 报告给汇总器时使用的工作者编号
+	mirrors    []string            // 同时写入的镜像目标目录
+
+	filter    func(path string, e exfat.FileEntry) bool          // 返回 false 的条目不提取
+	transform func(path string, e exfat.FileEntry) TransformFunc // 写出前对文件数据的变换，见 WithTransform
+	sampler   *Sampler                                           // 抽样上限，见 WithSampler
+
+	layout     Layout     // 目标布局，见 WithLayout
+	dateSource DateSource // 按日期分组时使用的时间
+
+	presets     []Preset        // 排除规则预设，见 WithPreset
+	appleDouble AppleDoubleMode // AppleDouble 文件的处理方式
+
+	reportLimit *int // 报告中最多列出的有问题条目数，为 nil 时使用 DefaultReportLimit
+
+	policy CheckPolicy  // 检查策略（见 ExtractWithCheck）
+	check  *CheckReport // 检查结果，为 nil 时不检查
+}
+
+// WithReportLimit 设置报告中最多列出的有问题条目数（默认 DefaultReportLimit）
+// 超出的条目只计入 Report.Omitted；为 0 时只保留计数。
+func WithReportLimit(n int) Option {
+	return func(o *options) {
+		o.reportLimit = &n
+	}
+}
+
+// WithManifest 设置清单回调，按处理顺序接收每个文件和目录的结果，包括被跳过的条目
+func WithManifest(fn func(ManifestEntry)) Option {
+	return func(o *options) {
+		o.manifest = fn
+	}
+}
+
+// WithProgress 设置进度回调，每写出一个文件后以累计值调用
+func WithProgress(fn func(Progress)) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// WithRepairPlans 对簇链提前结束的文件制定修复计划并按计划读取
+// 使用的计划记录在报告中，映像本身不会被修改
+func WithRepairPlans() Option {
+	return func(o *options) {
+		o.repair = true
+	}
+}
+
+// WithMirrors 把提取结果同时写入额外的目标目录，目录结构与主目标相同
+// 每个文件只从映像读取一次，数据同时写入所有目标；某个目标写入失败（如磁盘已满）不影响其他目标。
+// 修改时间分别设置到每个目标上。镜像目标的失败记录在报告中（FileReport.Dest 为该目标的路径），
+// 清单回调对每个目标各调用一次。
+func WithMirrors(dirs ...string) Option {
+	return func(o *options) {
+		o.mirrors = append(o.mirrors, dirs...)
+	}
+}
+
+// WithFilter 设置过滤回调，对遍历到的每个条目以源路径调用，返回 false 时跳过该条目
+// 过滤在读取文件数据之前进行，跳过的文件不产生任何读取；返回 false 的目录连同其内容整个跳过。
+// 跳过的条目以 OutcomeSkippedFilter 交给清单回调并计入 Report.Skipped，汇总器中计为跳过的文件。
+func WithFilter(fn func(path string, e exfat.FileEntry) bool) Option {
+	return func(o *options) {
+		o.filter = fn
+	}
+}
+
+// applyOptions 汇总选项
+func applyOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,162 @@
+package extract
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// Preset 一组命名的排除规则，用 WithPreset 与 WithFilter 组合使用
+// 模式按 path.Match 匹配，不区分大小写（与 exFAT 的文件名规则一致）：不含 "/" 的模式匹配条目名称，
+// 以 "/" 开头的模式匹配条目在卷中的完整路径。匹配 Include 的条目不会被排除。
+// 目录被排除时连同其内容一起跳过。
+type Preset struct {
+	Name    string
+	Exclude []string
+	Include []string
+}
+
+// PresetSkipOSMetadata 排除 macOS、Windows 在可移动介质上留下的元数据
+// AppleDouble 文件（._*）不在其中，由 WithAppleDouble 单独处理。
+var PresetSkipOSMetadata = Preset{
+	Name: "os-metadata",
+	Exclude: []string{
+		".DS_Store",
+		".Trashes",
+		".Spotlight-V100",
+		".fseventsd",
+		".TemporaryItems",
+		".VolumeIcon.icns",
+		".apdisk",
+		"System Volume Information",
+		"$RECYCLE.BIN",
+		"Thumbs.db",
+		"desktop.ini",
+	},
+}
+
+// PresetSkipAndroidCache 排除 Android 设备在存储卡上留下的缓存、缩略图和 .nomedia 标记
+var PresetSkipAndroidCache = Preset{
+	Name: "android-cache",
+	Exclude: []string{
+		".nomedia",
+		".thumbnails",
+		"LOST.DIR",
+		"/Android/data/*/cache",
+		"/Android/data/*/files/.cache",
+	},
+}
+
+// Excludes 判断 fullPath 处的条目是否被预设排除
+func (p Preset) Excludes(fullPath string, e exfat.FileEntry) bool {
+	return matchAny(p.Exclude, fullPath, e.Name) && !matchAny(p.Include, fullPath, e.Name)
+}
+
+// matchAny 判断名称或路径是否匹配任一模式
+func matchAny(patterns []string, fullPath, name string) bool {
+	for _, pattern := range patterns {
+		subject := name
+		if strings.HasPrefix(pattern, "/") {
+			subject = fullPath
+		}
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(subject)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPreset 提取时按预设排除条目，可以多次使用，也可以与 WithFilter 组合
+// 被预设排除的条目与被过滤回调排除的条目一样记为 OutcomeSkippedFilter。
+func WithPreset(presets ...Preset) Option {
+	return func(o *options) {
+		o.presets = append(o.presets, presets...)
+	}
+}
+
+// AppleDoubleMode AppleDouble 文件（macOS 在非 HFS 介质上为 foo 写出的 ._foo）的处理方式
+type AppleDoubleMode int
+
+const (
+	AppleDoubleKeep  AppleDoubleMode = iota // 作为普通文件提取
+	AppleDoubleDrop                         // 不提取
+	AppleDoubleMerge                        // 在 macOS 上把资源分支写回 foo，其他系统上不提取
+)
+
+// WithAppleDouble 设置 AppleDouble 文件的处理方式，默认作为普通文件提取
+// 合并时资源分支写入 foo 的 com.apple.ResourceFork 扩展属性（通过 foo/..namedfork/rsrc），
+// Finder 信息不会恢复；没有对应文件 foo 的 ._foo 直接丢弃。
+func WithAppleDouble(mode AppleDoubleMode) Option {
+	return func(o *options) {
+		o.appleDouble = mode
+	}
+}
+
+// isAppleDouble 判断条目是否为 AppleDouble 文件
+func isAppleDouble(e exfat.FileEntry) bool {
+	return !e.IsDir && strings.HasPrefix(e.Name, "._") && len(e.Name) > 2
+}
+
+// appleDoubleMagic AppleDouble 文件头部的魔数
+const appleDoubleMagic = 0x00051607
+
+// appleDoubleResourceFork 资源分支条目的 ID
+const appleDoubleResourceFork = 2
+
+// resourceFork 从 AppleDouble 数据中取出资源分支，没有资源分支时返回 nil
+func resourceFork(data []byte) ([]byte, error) {
+	if len(data) < 26 || binary.BigEndian.Uint32(data) != appleDoubleMagic {
+		return nil, fmt.Errorf("not an AppleDouble file")
+	}
+	count := int(binary.BigEndian.Uint16(data[24:]))
+	for i := 0; i < count; i++ {
+		desc := data[26+i*12:]
+		if len(desc) < 12 {
+			return nil, fmt.Errorf("AppleDouble entry table is truncated")
+		}
+		if binary.BigEndian.Uint32(desc) != appleDoubleResourceFork {
+			continue
+		}
+		offset, length := uint64(binary.BigEndian.Uint32(desc[4:])), uint64(binary.BigEndian.Uint32(desc[8:]))
+		if offset+length > uint64(len(data)) {
+			return nil, fmt.Errorf("AppleDouble resource fork extends past the end of the file")
+		}
+		return data[offset : offset+length], nil
+	}
+	return nil, nil
+}
+
+// appleDouble 处理目录中推迟的 AppleDouble 文件；此时同一目录中的普通文件都已提取
+func (x *extractor) appleDouble(entries []exfat.FileEntry, srcDir, destDir string) {
+	for _, entry := range entries {
+		srcPath := path.Join(srcDir, entry.Name)
+		destPath := filepath.Join(destDir, entry.Name)
+		partner := filepath.Join(destDir, entry.Name[2:])
+		if placed, ok := x.sources[path.Join(srcDir, entry.Name[2:])]; ok {
+			partner = placed
+		}
+		if info, err := os.Stat(partner); x.opts.appleDouble != AppleDoubleMerge || runtime.GOOS != "darwin" || err != nil || !info.Mode().IsRegular() {
+			x.skip(entry, srcPath, destPath, OutcomeSkippedFilter)
+			continue
+		}
+
+		data, err := x.fsys.ReadFile(srcPath)
+		var fork []byte
+		if err == nil {
+			fork, err = resourceFork(data)
+		}
+		if err == nil && len(fork) > 0 {
+			err = os.WriteFile(filepath.Join(partner, "..namedfork", "rsrc"), fork, 0644)
+		}
+		if err != nil {
+			x.report.add(FileReport{Path: srcPath, Err: fmt.Errorf("failed to merge AppleDouble file: %v", err)})
+		}
+		x.manifest(entry, srcPath, partner, outcomeOf(err), err, 0, nil)
+	}
+}
@@ -0,0 +1,188 @@
+package extract
+
+import (
+	"path"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// presetFixture 存储卡上常见的目录树：照片和音乐夹杂着 macOS、Windows 和 Android 留下的元数据
+func presetFixture() *testimage.Image {
+	f := func(name string) *testimage.Node { return testimage.File(name, []byte(name)) }
+	d := testimage.Dir
+	return testimage.Build(testimage.Options{ClusterCount: 2048},
+		f(".DS_Store"),
+		d(".Trashes", d("501", f("deleted.jpg"))),
+		d(".Spotlight-V100", f("Store-V2")),
+		d(".fseventsd", f("fseventsd-uuid")),
+		f(".VolumeIcon.icns"),
+		d("System Volume Information", f("IndexerVolumeGuid")),
+		d("$RECYCLE.BIN", f("desktop.ini")),
+		d("DCIM",
+			d("100CANON", f("IMG_0001.JPG"), f("._IMG_0001.JPG"), f(".DS_Store")),
+			f("Thumbs.db"),
+			d(".thumbnails", f("1.jpg")),
+			f(".nomedia")),
+		d("LOST.DIR", f("1234")),
+		d("Android", d("data", d("com.app",
+			d("cache", f("c.bin")),
+			d("files", d(".cache", f("x")), f("keep.txt"))))),
+		d("Music", f("song.mp3"), f(".ds_STORE"), f("._orphan")))
+}
+
+// 两个预设排除的路径；目录被排除时只有目录本身出现在清单中
+var (
+	osMetadataPaths = []string{
+		"/.DS_Store", "/.Trashes", "/.Spotlight-V100", "/.fseventsd", "/.VolumeIcon.icns",
+		"/System Volume Information", "/$RECYCLE.BIN",
+		"/DCIM/100CANON/.DS_Store", "/DCIM/Thumbs.db", "/Music/.ds_STORE",
+	}
+	androidCachePaths = []string{
+		"/DCIM/.thumbnails", "/DCIM/.nomedia", "/LOST.DIR",
+		"/Android/data/com.app/cache", "/Android/data/com.app/files/.cache",
+	}
+)
+
+// extractSkipped 提取整个卷，返回被排除的源路径（排序）和写出的文件
+func extractSkipped(t *testing.T, img *testimage.Image, opts ...Option) ([]string, map[string]string) {
+	t.Helper()
+	var skipped []string
+	dest := t.TempDir()
+	opts = append(opts, WithManifest(func(m ManifestEntry) {
+		if m.Outcome == OutcomeSkippedFilter {
+			skipped = append(skipped, m.Path)
+		}
+	}))
+	if _, err := PathWithReport(openFS(t, img), "/", dest, opts...); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(skipped)
+	return skipped, treeFiles(t, dest)
+}
+
+// allFiles 返回卷中所有文件的路径（不以 "/" 开头，与 treeFiles 一致）
+func allFiles(t *testing.T, img *testimage.Image) []string {
+	_, files := extractSkipped(t, img)
+	var paths []string
+	for p := range files {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// under 判断 p 是否为 dirs 中某个路径本身或在其之下
+func under(p string, dirs []string) bool {
+	for _, d := range dirs {
+		if p == d || strings.HasPrefix(p, d+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPresets(t *testing.T) {
+	img := presetFixture()
+	all := allFiles(t, img)
+	if len(all) != 20 {
+		t.Fatalf("fixture has %d files: %q", len(all), all)
+	}
+
+	sorted := func(lists ...[]string) []string {
+		out := slices.Concat(lists...)
+		slices.Sort(out)
+		return out
+	}
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"none", nil, nil},
+		{"os metadata", []Option{WithPreset(PresetSkipOSMetadata)}, sorted(osMetadataPaths)},
+		{"android cache", []Option{WithPreset(PresetSkipAndroidCache)}, sorted(androidCachePaths)},
+		{"both", []Option{WithPreset(PresetSkipOSMetadata, PresetSkipAndroidCache)}, sorted(osMetadataPaths, androidCachePaths)},
+		{"separate options", []Option{WithPreset(PresetSkipOSMetadata), WithPreset(PresetSkipAndroidCache)}, sorted(osMetadataPaths, androidCachePaths)},
+		// 预设与过滤回调组合：任何一个排除条目都会跳过它
+		{"with filter", []Option{
+			WithPreset(PresetSkipAndroidCache),
+			WithFilter(func(p string, e exfat.FileEntry) bool { return p != "/Music" }),
+		}, sorted(androidCachePaths, []string{"/Music"})},
+		// Include 优先于 Exclude
+		{"custom with include", []Option{WithPreset(Preset{
+			Name:    "jpeg-only",
+			Exclude: []string{"*.jpg", "*.JPG"},
+			Include: []string{"/DCIM/100CANON/*"},
+		})}, []string{"/.Trashes/501/deleted.jpg", "/DCIM/.thumbnails/1.jpg"}},
+	}
+	for _, tt := range tests {
+		skipped, files := extractSkipped(t, img, tt.opts...)
+		if !slices.Equal(skipped, tt.want) {
+			t.Errorf("%s: skipped %q, want %q", tt.name, skipped, tt.want)
+		}
+		// 其余文件全部写出，内容不变
+		for _, p := range all {
+			_, written := files[p]
+			if excluded := under("/"+p, tt.want); written == excluded {
+				t.Errorf("%s: %s written %v", tt.name, p, written)
+			}
+			if written && files[p] != path.Base(p) {
+				t.Errorf("%s: %s holds %q", tt.name, p, files[p])
+			}
+		}
+	}
+}
+
+func TestPresetMatching(t *testing.T) {
+	file := func(name string) exfat.FileEntry { return exfat.FileEntry{Name: name} }
+	for _, tt := range []struct {
+		preset Preset
+		path   string
+		want   bool
+	}{
+		{PresetSkipOSMetadata, "/a/b/.DS_Store", true},
+		{PresetSkipOSMetadata, "/a/.ds_store", true},
+		{PresetSkipOSMetadata, "/a/.DS_Store.bak", false},
+		{PresetSkipOSMetadata, "/a/._IMG.JPG", false}, // AppleDouble 由 WithAppleDouble 处理
+		{PresetSkipOSMetadata, "/desktop.INI", true},
+		{PresetSkipAndroidCache, "/Android/data/x/cache", true},
+		{PresetSkipAndroidCache, "/android/DATA/x/Cache", true},
+		{PresetSkipAndroidCache, "/Backup/Android/data/x/cache", false}, // 以 "/" 开头的模式匹配完整路径
+		{PresetSkipAndroidCache, "/Android/data/x/y/cache", false},
+		{PresetSkipAndroidCache, "/x/.nomedia", true},
+	} {
+		if got := tt.preset.Excludes(tt.path, file(path.Base(tt.path))); got != tt.want {
+			t.Errorf("%s.Excludes(%s) = %v, want %v", tt.preset.Name, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAppleDoubleModes(t *testing.T) {
+	img := presetFixture()
+	appleDouble := []string{"/DCIM/100CANON/._IMG_0001.JPG", "/Music/._orphan"}
+	for _, tt := range []struct {
+		mode AppleDoubleMode
+		want []string
+	}{
+		{AppleDoubleKeep, nil},
+		{AppleDoubleDrop, appleDouble},
+	} {
+		skipped, files := extractSkipped(t, img, WithAppleDouble(tt.mode))
+		if !slices.Equal(skipped, tt.want) {
+			t.Errorf("mode %d: skipped %q, want %q", tt.mode, skipped, tt.want)
+		}
+		if _, ok := files["DCIM/100CANON/IMG_0001.JPG"]; !ok {
+			t.Errorf("mode %d: the AppleDouble partner was not extracted", tt.mode)
+		}
+	}
+
+	// 合并时没有对应文件的 ._orphan 总是被丢弃
+	skipped, _ := extractSkipped(t, img, WithAppleDouble(AppleDoubleMerge))
+	if !slices.Contains(skipped, "/Music/._orphan") {
+		t.Errorf("merge kept the orphan: skipped %q", skipped)
+	}
+}
@@ -0,0 +1,259 @@
+package extract
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// ErrIncomplete 表示有条目提取失败，失败的数量和样本见报告
+var ErrIncomplete = errors.New("extraction incomplete")
+
+// Outcome 条目的处理结果
+type Outcome string
+
+const (
+	OutcomeOK            Outcome = "ok"             // 已提取
+	OutcomeSkippedFilter Outcome = "skipped-filter" // 被过滤回调排除（见 WithFilter）
+	OutcomeSkippedCheck  Outcome = "skipped-check"  // 被检查策略跳过（见 ExtractWithCheck）
+	OutcomeSkippedSample Outcome = "skipped-sample" // 抽样时未选中（见 SampleLimits.SampleEvery）
+	OutcomeFailed        Outcome = "failed"         // 提取失败
+)
+
+// DefaultReportLimit 报告中默认最多列出的有问题条目数
+const DefaultReportLimit = 1000
+
+// FileReport 记录单个有问题的文件或目录
+type FileReport struct {
+	Path      string            // 源路径
+	Dest      string            // 失败的镜像目标路径（主目标为空）
+	Anomalies []exfat.Anomaly   // 文件系统层发现的结构异常
+	Err       error             // 提取失败的原因（成功提取时为 nil）
+	Repair    *exfat.RepairPlan // 按修复计划读取时使用的计划
+	FAT       int               // 簇链在活动 FAT 中无效、改用另一个 FAT 读取时为该 FAT 的编号，否则为 0
+	Warning   string            // 提取成功但没有按要求变换的原因，如压缩数据损坏、改为原样写出（见 Decompress）
+}
+
+// MarshalJSON 把 Err 编码为字符串
+func (f FileReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path      string            `json:"path"`
+		Dest      string            `json:"dest,omitempty"`
+		Anomalies []exfat.Anomaly   `json:"anomalies,omitempty"`
+		Err       string            `json:"error,omitempty"`
+		Repair    *exfat.RepairPlan `json:"repair,omitempty"`
+		FAT       int               `json:"fat,omitempty"`
+		Warning   string            `json:"warning,omitempty"`
+	}{f.Path, f.Dest, f.Anomalies, errorString(f.Err), f.Repair, f.FAT, f.Warning})
+}
+
+// UnmarshalJSON 解码 MarshalJSON 写出的对象，Err 还原为只有文本的错误
+func (f *FileReport) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Path      string            `json:"path"`
+		Dest      string            `json:"dest"`
+		Anomalies []exfat.Anomaly   `json:"anomalies"`
+		Err       string            `json:"error"`
+		Repair    *exfat.RepairPlan `json:"repair"`
+		FAT       int               `json:"fat"`
+		Warning   string            `json:"warning"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = FileReport{Path: v.Path, Dest: v.Dest, Anomalies: v.Anomalies, Repair: v.Repair, FAT: v.FAT, Warning: v.Warning}
+	if v.Err != "" {
+		f.Err = errors.New(v.Err)
+	}
+	return nil
+}
+
+// Report 提取过程的汇总报告
+// 计数覆盖所有条目；Files 只列出存在异常、按修复计划或另一个 FAT 读取或提取失败的条目，最多列出 WithReportLimit 设置的个数，
+// 超出的部分只计入 Omitted，处理几百万个文件时内存占用仍然有界。逐条目的结果通过 WithManifest 获得。
+type Report struct {
+	Extracted int           `json:"extracted"`         // 成功写出的文件数
+	Failures  int           `json:"failures"`          // 失败的条目数，镜像目标上的失败分别计数
+	Skipped   int           `json:"skipped"`           // 被过滤回调、检查策略或抽样跳过的条目数
+	Bytes     int64         `json:"bytes"`             // 写出的字节数（只计主目标）
+	Duration  time.Duration `json:"duration_ns"`       // 提取用时
+	Files     []FileReport  `json:"files"`             // 有问题的条目，按遍历顺序排列
+	Omitted   int           `json:"omitted"`           // 超出上限没有列入 Files 的有问题条目数
+	Stopped   SampleBound   `json:"stopped,omitempty"` // 抽样提取因达到该上限而提前结束（见 WithSample）
+
+	limit int
+}
+
+// newReport 按选项中的上限创建报告
+func newReport(o *options) *Report {
+	limit := DefaultReportLimit
+	if o.reportLimit != nil {
+		limit = max(*o.reportLimit, 0)
+	}
+	return &Report{limit: limit}
+}
+
+// HasIssues 报告中是否有任何异常或失败
+func (r *Report) HasIssues() bool {
+	return len(r.Files) > 0 || r.Omitted > 0
+}
+
+// Err 有条目失败时返回包装了 ErrIncomplete 的错误，全部成功或被有意跳过时返回 nil
+func (r *Report) Err() error {
+	if r.Failures == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d entries failed", ErrIncomplete, r.Failures)
+}
+
+// Failed 返回提取失败的文件
+func (r *Report) Failed() []FileReport {
+	var failed []FileReport
+	for _, f := range r.Files {
+		if f.Err != nil {
+			failed = append(failed, f)
+		}
+	}
+	return failed
+}
+
+// WithAnomaly 返回带有指定异常的文件
+func (r *Report) WithAnomaly(a exfat.Anomaly) []FileReport {
+	var matched []FileReport
+	for _, f := range r.Files {
+		for _, fa := range f.Anomalies {
+			if fa == a {
+				matched = append(matched, f)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// add 记录一个条目的结果，没有问题的条目只计数，不进入列表
+func (r *Report) add(f FileReport) {
+	if f.Err != nil {
+		r.Failures++
+	}
+	if f.Err == nil && len(f.Anomalies) == 0 && f.Repair == nil && f.FAT == 0 && f.Warning == "" {
+		return
+	}
+	if len(r.Files) >= r.limit {
+		r.Omitted++
+		return
+	}
+	r.Files = append(r.Files, f)
+}
+
+// AllWithReport 递归提取目录内容到本地路径，并返回报告
+// 单个条目的失败不会中断提取；有条目失败时返回的错误包装了 ErrIncomplete，根目录无法读取时返回读取错误。
+func AllWithReport(fsys *exfat.ExFATFileSystem, srcPath, destPath string, opts ...Option) (*Report, error) {
+	x := newExtractor(fsys, destPath, opts)
+	start := time.Now()
+	// 子目录的镜像目标在处理对应条目时创建，这里只需创建根目录的镜像目标
+	for _, mirror := range x.mirrorPaths(destPath) {
+		if err := os.MkdirAll(mirror, 0755); err != nil {
+			x.report.add(FileReport{Path: srcPath, Dest: mirror, Err: fmt.Errorf("failed to create directory: %v", err)})
+		}
+	}
+	err := x.dir(srcPath, destPath)
+	x.report.Duration = time.Since(start)
+	if errors.Is(err, errSampleDone) {
+		err = nil
+	}
+	x.sampled()
+	if err != nil {
+		return x.report, err
+	}
+	return x.report, x.report.Err()
+}
+
+// PathWithReport 与 Path 相同，提取文件或目录到 destDir 下，并返回报告
+func PathWithReport(fsys *exfat.ExFATFileSystem, srcPath, destDir string, opts ...Option) (*Report, error) {
+	entry, err := fsys.Stat(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry for %s: %w", srcPath, err)
+	}
+
+	if entry.IsDir {
+		return AllWithReport(fsys, srcPath, destDir, opts...)
+	}
+
+	x := newExtractor(fsys, destDir, opts)
+	start := time.Now()
+	destPath := filepath.Join(destDir, entry.Name)
+	if x.filtered(entry, srcPath, destPath) {
+		x.report.Duration = time.Since(start)
+		return x.report, nil
+	}
+	anomalies, err := fsys.Anomalies(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	action, err := x.checkEntry(entry, srcPath, anomalies)
+	if err != nil {
+		return x.report, err
+	}
+	switch {
+	case action == CheckSkip:
+		x.skip(entry, srcPath, destPath, OutcomeSkippedCheck)
+	case !x.sample(entry, srcPath, destPath):
+	default:
+		x.extractFile(entry, srcPath, destPath, anomalies, action)
+	}
+	x.report.Duration = time.Since(start)
+	x.sampled()
+	return x.report, x.report.Err()
+}
+
+// SourceReport 报告文件（exfat-tool extract -report）中的一行：提取的源路径和它的报告
+type SourceReport struct {
+	Source string `json:"source"`
+	*Report
+}
+
+// DecodeReports 读取以 NDJSON 写出的报告文件，按顺序返回每个源路径的报告
+// 版本 0（没有 schema 字段）与版本 1 的字段相同；某一行无法解析或模式不符（见 exfat.CheckSchema）时返回带行号的错误。
+func DecodeReports(r io.Reader) ([]SourceReport, error) {
+	var reports []SourceReport
+	scanner := bufio.NewScanner(r)
+	// 报告最多列出 DefaultReportLimit 个条目，一行可能有几百 KiB
+	scanner.Buffer(make([]byte, 0, 64<<10), 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var v struct {
+			exfat.Envelope
+			SourceReport
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			return reports, fmt.Errorf("report line %d: %w", line, err)
+		}
+		if _, err := exfat.CheckSchema(v.Schema, exfat.SchemaReport); err != nil {
+			return reports, fmt.Errorf("report line %d: %w", line, err)
+		}
+		if v.Report == nil {
+			v.Report = &Report{}
+		}
+		reports = append(reports, v.SourceReport)
+	}
+	return reports, scanner.Err()
+}
+
+// errorString 返回错误的文本，nil 时为空
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
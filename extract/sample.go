@@ -0,0 +1,98 @@
+package extract
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// SampleBound 抽样提取中触发停止的上限
+type SampleBound string
+
+const (
+	BoundMaxFiles    SampleBound = "max-files"    // 达到 SampleLimits.MaxFiles
+	BoundMaxBytes    SampleBound = "max-bytes"    // 下一个文件会超过 SampleLimits.MaxBytes
+	BoundMaxDuration SampleBound = "max-duration" // 用时达到 SampleLimits.MaxDuration
+)
+
+// SampleLimits 抽样提取的上限，为 0 的字段不限制
+// 上限只计算通过过滤回调和检查策略的文件，先达到的上限使遍历正常结束（不返回错误）。
+type SampleLimits struct {
+	MaxFiles    int           // 最多提取的文件数
+	MaxBytes    int64         // 最多提取的字节数
+	MaxDuration time.Duration // 最长用时，从创建 Sampler 开始计算
+	SampleEvery int           // 每 N 个文件只提取第 1 个，其余记为跳过
+}
+
+// errSampleDone 在遍历中表示抽样上限已经达到，由提取入口转换为正常结束
+var errSampleDone = errors.New("sample limit reached")
+
+// Sampler 按 SampleLimits 决定提取哪些文件，可以在并发的多个提取之间共享
+// 某个提取触发上限后，其他提取在写完当前文件后停止，不会留下写到一半的文件。
+type Sampler struct {
+	mu      sync.Mutex
+	limits  SampleLimits
+	start   time.Time
+	seen    int   // 遇到的文件数（用于 SampleEvery）
+	files   int   // 已选中的文件数
+	bytes   int64 // 已选中的字节数
+	stopped SampleBound
+}
+
+// NewSampler 创建抽样器，MaxDuration 从此时开始计算
+func NewSampler(limits SampleLimits) *Sampler {
+	return &Sampler{limits: limits, start: time.Now()}
+}
+
+// WithSampler 提取时按 s 抽样；多个提取共享同一个 s 时上限对它们合计
+func WithSampler(s *Sampler) Option {
+	return func(o *options) {
+		o.sampler = s
+	}
+}
+
+// WithSample 提取时按 limits 抽样，等同于 WithSampler(NewSampler(limits))
+func WithSample(limits SampleLimits) Option {
+	return WithSampler(NewSampler(limits))
+}
+
+// Stopped 返回触发停止的上限，尚未停止时为空
+func (s *Sampler) Stopped() SampleBound {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkDuration()
+	return s.stopped
+}
+
+// take 决定是否提取大小为 size 的文件
+// 返回 false 且 Stopped 为空时该文件按 SampleEvery 被跳过；已达到上限时返回 false 并记录上限。
+func (s *Sampler) take(size int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.checkDuration(); s.stopped != "" {
+		return false
+	}
+
+	s.seen++
+	if s.limits.SampleEvery > 1 && (s.seen-1)%s.limits.SampleEvery != 0 {
+		return false
+	}
+	if s.limits.MaxBytes > 0 && s.bytes+size > s.limits.MaxBytes {
+		s.stopped = BoundMaxBytes
+		return false
+	}
+
+	s.files++
+	s.bytes += size
+	if s.limits.MaxFiles > 0 && s.files >= s.limits.MaxFiles {
+		s.stopped = BoundMaxFiles
+	}
+	return true
+}
+
+// checkDuration 用时达到上限时记录停止，调用者持有锁
+func (s *Sampler) checkDuration() {
+	if s.stopped == "" && s.limits.MaxDuration > 0 && time.Since(s.start) >= s.limits.MaxDuration {
+		s.stopped = BoundMaxDuration
+	}
+}
@@ -0,0 +1,46 @@
+package extract
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// TarGzOption WriteTarGz 的选项
+type TarGzOption func(*tarGzOptions)
+
+type tarGzOptions struct {
+	level int
+}
+
+// WithGzipLevel 设置 gzip 压缩级别，取值与 gzip.NewWriterLevel 相同，默认为 gzip.DefaultCompression
+func WithGzipLevel(level int) TarGzOption {
+	return func(o *tarGzOptions) {
+		o.level = level
+	}
+}
+
+// WriteTarGz 把 root 下的目录树以流的方式写成 gzip 压缩的 pax 归档
+// 与 ExportPax 相同，头部保留修改时间和 exFAT 属性、时间；文件的簇按需读取，内存占用与文件大小无关。
+// 无法读取的条目被跳过，此时归档仍然完整写出，返回包装了 ErrIncomplete 的错误。
+func WriteTarGz(fsys *exfat.ExFATFileSystem, root string, w io.Writer, opts ...TarGzOption) error {
+	o := tarGzOptions{level: gzip.DefaultCompression}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	gz, err := gzip.NewWriterLevel(w, o.level)
+	if err != nil {
+		return err
+	}
+	_, err = ExportPaxWithReport(fsys, root, gz, ExportOptions{Format: FormatPax})
+	if err != nil && !errors.Is(err, ErrIncomplete) {
+		return err
+	}
+	if cerr := gz.Close(); cerr != nil {
+		return cerr
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package extract
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// openFS 打开内存中构造的卷
+func openFS(t *testing.T, img *testimage.Image) *exfat.ExFATFileSystem {
+	t.Helper()
+	fs, err := exfat.NewExFATFileSystem(img.Disk())
+	if err != nil {
+		t.Fatalf("NewExFATFileSystem: %v", err)
+	}
+	return fs
+}
+
+// archiveTree 返回用于归档测试的目录树：嵌套目录、空文件、只读文件、碎片化的大文件和各自带 UTC 偏移的时间
+func archiveTree() []*testimage.Node {
+	readOnly := testimage.File("readonly.txt", []byte("do not touch"))
+	readOnly.Attributes = uint16(exfat.AttrReadOnly | exfat.AttrArchive)
+	readOnly.Created = time.Date(2019, 4, 5, 6, 7, 8, 0, time.FixedZone("", 9*3600))
+	readOnly.Modified = time.Date(2021, 8, 9, 10, 11, 12, 340e6, time.FixedZone("", -4*3600))
+	readOnly.Accessed = time.Date(2022, 1, 2, 3, 4, 6, 0, time.UTC)
+	large := testimage.File("large.bin", bytes.Repeat([]byte("0123456789abcdef"), 1000))
+	large.Fragmented = true
+	hidden := testimage.Dir("Hidden", testimage.File("inner.txt", []byte("inner")))
+	hidden.Attributes = uint16(exfat.AttrDirectory | exfat.AttrHidden)
+	return []*testimage.Node{
+		testimage.File("empty", nil),
+		readOnly,
+		testimage.Dir("Sub", large, hidden, testimage.Dir("Empty")),
+	}
+}
+
+// tarEntry 从归档中读出的一个条目
+type tarEntry struct {
+	hdr  *tar.Header
+	data []byte
+}
+
+func readTarGz(t *testing.T, data []byte) []tarEntry {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var entries []tarEntry
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, tarEntry{hdr, body})
+	}
+}
+
+func TestWriteTarGzRoundTrip(t *testing.T) {
+	fs := openFS(t, testimage.Build(testimage.Options{}, archiveTree()...))
+	var buf bytes.Buffer
+	if err := WriteTarGz(fs, "/", &buf, WithGzipLevel(gzip.BestCompression)); err != nil {
+		t.Fatal(err)
+	}
+	entries := readTarGz(t, buf.Bytes())
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.hdr.Name)
+	}
+	want := []string{"empty", "readonly.txt", "Sub/", "Sub/large.bin", "Sub/Hidden/", "Sub/Hidden/inner.txt", "Sub/Empty/"}
+	if len(names) != len(want) {
+		t.Fatalf("archive holds %q, want %q", names, want)
+	}
+	for i, e := range entries {
+		if e.hdr.Name != want[i] {
+			t.Fatalf("archive holds %q, want %q", names, want)
+		}
+		src := "/" + e.hdr.Name
+		stat, err := fs.Stat(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stat.IsDir {
+			data, _ := fs.ReadFile(src)
+			if !bytes.Equal(e.data, data) {
+				t.Errorf("%s: %d bytes in the archive, %d on the volume", e.hdr.Name, len(e.data), len(data))
+			}
+		}
+		if e.hdr.Mode != entryMode(stat) || !e.hdr.ModTime.Equal(stat.ModTime) {
+			t.Errorf("%s: mode %o, mtime %v", e.hdr.Name, e.hdr.Mode, e.hdr.ModTime)
+		}
+
+		meta, err := ParsePaxRecords(e.hdr.PAXRecords)
+		if err != nil {
+			t.Fatalf("%s: %v", e.hdr.Name, err)
+		}
+		for _, c := range []struct {
+			field     string
+			got, want time.Time
+		}{
+			{"crtime", meta.CreateTime, stat.CreateTime},
+			{"mtime", meta.ModTime, stat.ModTime},
+			{"atime", meta.AccessTime, stat.AccessTime},
+		} {
+			_, gotOffset := c.got.Zone()
+			_, wantOffset := c.want.Zone()
+			if !c.got.Equal(c.want) || gotOffset != wantOffset {
+				t.Errorf("%s: %s %v, want %v", e.hdr.Name, c.field, c.got, c.want)
+			}
+		}
+		if meta.Attributes != stat.Attributes {
+			t.Errorf("%s: attributes %v, want %v", e.hdr.Name, meta.Attributes, stat.Attributes)
+		}
+	}
+	if mode := entries[1].hdr.Mode; mode&0222 != 0 {
+		t.Errorf("read-only file archived with mode %o", mode)
+	}
+}
+
+func TestWriteTarGzSubtreeAndLevel(t *testing.T) {
+	fs := openFS(t, testimage.Build(testimage.Options{}, archiveTree()...))
+	var buf bytes.Buffer
+	if err := WriteTarGz(fs, "/Sub/Hidden", &buf, WithGzipLevel(gzip.NoCompression)); err != nil {
+		t.Fatal(err)
+	}
+	entries := readTarGz(t, buf.Bytes())
+	if len(entries) != 1 || entries[0].hdr.Name != "inner.txt" || string(entries[0].data) != "inner" {
+		t.Errorf("subtree archive: %+v", entries)
+	}
+	if err := WriteTarGz(fs, "/", io.Discard, WithGzipLevel(42)); err == nil {
+		t.Error("accepted an invalid gzip level")
+	}
+	if err := WriteTarGz(fs, "/missing", io.Discard); !errors.Is(err, exfat.ErrNotExist) {
+		t.Errorf("missing root: got %v, want ErrNotExist", err)
+	}
+}
+
+func TestWriteTarGzIncomplete(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, archiveTree()...)
+	// 清零 FAT：碎片化的 large.bin 无法读取，其余条目照常写出
+	clear(img.Bytes[img.FATOffset+8 : img.FATOffset+img.FATLength])
+	var buf bytes.Buffer
+	err := WriteTarGz(openFS(t, img), "/", &buf)
+	if !errors.Is(err, ErrIncomplete) {
+		t.Fatalf("got %v, want ErrIncomplete", err)
+	}
+	entries := readTarGz(t, buf.Bytes())
+	if len(entries) != 7 {
+		t.Fatalf("archive holds %d entries, want 7", len(entries))
+	}
+	// 读取失败的文件以零填充，归档保持完整
+	if large := entries[3]; large.hdr.Name != "Sub/large.bin" || int64(len(large.data)) != large.hdr.Size {
+		t.Errorf("large.bin: %s, %d of %d bytes", large.hdr.Name, len(large.data), large.hdr.Size)
+	}
+}
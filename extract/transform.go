@@ -0,0 +1,260 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// SniffSize 交给 TransformFunc 判断内容的文件开头的字节数
+const SniffSize = 8 << 10
+
+// TransformFunc 包装写出文件数据的 writer
+// head 是文件开头最多 SniffSize 字节（经 File.ReadAt 读取，不读取整个文件），用于按内容决定是否变换。
+// 返回 nil 时文件原样写出；否则文件的全部数据写入返回的 writer，写完后关闭它以刷出缓冲的数据
+// （不应关闭 w），name 作为变换的名称记录在清单中。变换对所有镜像目标只执行一次。
+type TransformFunc func(w io.Writer, head []byte) (wc io.WriteCloser, name string)
+
+// WithTransform 提取时对每个文件以源路径调用 fn，按返回的 TransformFunc 变换写出的数据
+// fn 返回 nil 的文件原样写出。被变换的文件在清单中记录变换的名称，ManifestEntry.Bytes 为写出的字节数，
+// 与 Size（映像中的大小）可能不同；只有 Bytes 与写出的文件一致，按映像中的数据做的校验不适用于这些文件。
+func WithTransform(fn func(path string, e exfat.FileEntry) TransformFunc) Option {
+	return func(o *options) {
+		o.transform = fn
+	}
+}
+
+// CRLF 把文本文件中单独的 LF 换行转换为 CRLF，已有的 CRLF 保持不变
+// 只转换按 IsText 判断为文本的文件，二进制文件原样写出。
+func CRLF(w io.Writer, head []byte) (io.WriteCloser, string) {
+	if !IsText(head) {
+		return nil, ""
+	}
+	return &crlfWriter{w: bufio.NewWriter(w)}, "crlf"
+}
+
+// StripBOM 去掉文件开头的 UTF-8 BOM（EF BB BF），其余数据原样写出；没有 BOM 的文件不变换
+func StripBOM(w io.Writer, head []byte) (io.WriteCloser, string) {
+	if !bytes.HasPrefix(head, utf8BOM) {
+		return nil, ""
+	}
+	return &bomWriter{w: w, skip: len(utf8BOM)}, "strip-bom"
+}
+
+// ChainTransforms 依次应用多个变换，只有决定变换的那些记入名称（以 "+" 连接）
+// 每个变换按原始的文件开头判断，如 ChainTransforms(StripBOM, CRLF) 先去掉 BOM 再转换换行。
+func ChainTransforms(fns ...TransformFunc) TransformFunc {
+	return func(w io.Writer, head []byte) (io.WriteCloser, string) {
+		var closers []io.WriteCloser
+		var names []string
+		// 后面的变换离 w 更近，先创建
+		for i := len(fns) - 1; i >= 0; i-- {
+			if wc, name := fns[i](w, head); wc != nil {
+				w = wc
+				closers = append(closers, wc)
+				names = append([]string{name}, names...)
+			}
+		}
+		if len(closers) == 0 {
+			return nil, ""
+		}
+		return &chainWriter{Writer: w, closers: closers}, strings.Join(names, "+")
+	}
+}
+
+// IsText 按文件开头判断内容是否为文本
+// 含 NUL 字节、以 UTF-16 BOM 开头，或换行、制表符等常见字符以外的控制字符超过 1% 时视为二进制，
+// 空文件也视为二进制。head 末尾被截断的 UTF-8 字符不影响判断。
+func IsText(head []byte) bool {
+	if len(head) == 0 || bytes.IndexByte(head, 0) >= 0 {
+		return false
+	}
+	if bytes.HasPrefix(head, []byte{0xFF, 0xFE}) || bytes.HasPrefix(head, []byte{0xFE, 0xFF}) {
+		return false
+	}
+	control := 0
+	for _, b := range head {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' && b != 0x1b {
+			control++
+		}
+	}
+	if control*100 > len(head) {
+		return false
+	}
+	// 不是 UTF-8 的文本（如 Latin-1 编码的配置文件）同样按文本处理，这里只排除明显的二进制
+	return control == 0 || utf8.Valid(trimPartialRune(head))
+}
+
+// trimPartialRune 去掉 p 末尾被截断的 UTF-8 字符
+func trimPartialRune(p []byte) []byte {
+	for i := 1; i <= utf8.UTFMax-1 && i <= len(p); i++ {
+		if utf8.RuneStart(p[len(p)-i]) {
+			if !utf8.FullRune(p[len(p)-i:]) {
+				return p[:len(p)-i]
+			}
+			break
+		}
+	}
+	return p
+}
+
+// utf8BOM UTF-8 编码的字节序标记
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// crlfWriter 把单独的 LF 转换为 CRLF
+type crlfWriter struct {
+	w  *bufio.Writer
+	cr bool // 上一个写出的字节是 CR
+}
+
+func (c *crlfWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b == '\n' && !c.cr {
+			if err := c.w.WriteByte('\r'); err != nil {
+				return 0, err
+			}
+		}
+		if err := c.w.WriteByte(b); err != nil {
+			return 0, err
+		}
+		c.cr = b == '\r'
+	}
+	return len(p), nil
+}
+
+func (c *crlfWriter) Close() error {
+	return c.w.Flush()
+}
+
+// bomWriter 丢弃数据开头的 skip 个字节
+type bomWriter struct {
+	w    io.Writer
+	skip int
+}
+
+func (b *bomWriter) Write(p []byte) (int, error) {
+	n := min(b.skip, len(p))
+	b.skip -= n
+	if _, err := b.w.Write(p[n:]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (b *bomWriter) Close() error {
+	return nil
+}
+
+// chainWriter 串联的变换，关闭时从外到内依次关闭
+type chainWriter struct {
+	io.Writer
+	closers []io.WriteCloser // 从内到外
+}
+
+func (c *chainWriter) Close() error {
+	var err error
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		if cerr := c.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Rename 返回串联的变换中最后一个要求改名的名称
+func (c *chainWriter) Rename() string {
+	name := ""
+	for _, wc := range c.closers {
+		if r, ok := wc.(renamer); ok && r.Rename() != "" {
+			name = r.Rename()
+		}
+	}
+	return name
+}
+
+// Note 合并串联的变换的说明
+func (c *chainWriter) Note() string {
+	var notes []string
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		if n, ok := c.closers[i].(noter); ok && n.Note() != "" {
+			notes = append(notes, n.Note())
+		}
+	}
+	return strings.Join(notes, "; ")
+}
+
+// renamer 由 TransformFunc 返回的 writer 可选实现：变换成功后写出的文件改用 Rename 返回的名称（为空时不改名）
+type renamer interface {
+	Rename() string
+}
+
+// noter 由 TransformFunc 返回的 writer 可选实现：关闭之后 Note 返回的说明记入清单
+type noter interface {
+	Note() string
+}
+
+// transformed 文件经过变换后写出的结果，记录在清单中
+type transformed struct {
+	name    string // 变换的名称，改为原样写出时为空
+	bytes   int64  // 写出的字节数
+	rename  string // 写出的文件改用的名称，为空时不改名
+	note    string // 记入清单的说明
+	warning string // 变换失败、改为原样写出的原因
+}
+
+// finish 从关闭后的变换 writer 中取出改名和说明
+func (t *transformed) finish(wc io.WriteCloser) {
+	if r, ok := wc.(renamer); ok {
+		t.rename = r.Rename()
+	}
+	if n, ok := wc.(noter); ok {
+		t.note = n.Note()
+	}
+}
+
+// fallback 返回无法解压、改为原样写出 size 字节时的结果
+func fallback(err error, size int64) *transformed {
+	return &transformed{bytes: size, warning: fmt.Sprintf("%v; written unchanged", err)}
+}
+
+// countingWriter 统计写入的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// transformBytes 对已经读入内存的文件数据应用变换，返回要写出的数据；tf 为 nil 或不变换时原样返回 data
+func transformBytes(tf TransformFunc, data []byte) ([]byte, *transformed, error) {
+	if tf == nil {
+		return data, nil, nil
+	}
+	var buf bytes.Buffer
+	wc, name := tf(&buf, data[:min(len(data), SniffSize)])
+	if wc == nil {
+		return data, nil, nil
+	}
+	_, err := wc.Write(data)
+	if closeErr := wc.Close(); errors.Is(closeErr, errNotDecompressed) {
+		return data, fallback(closeErr, int64(len(data))), nil
+	} else if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	t := &transformed{name: name, bytes: int64(buf.Len())}
+	t.finish(wc)
+	return buf.Bytes(), t, nil
+}
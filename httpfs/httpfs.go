@@ -0,0 +1,187 @@
+// Package httpfs 通过 HTTP 提供 exFAT 文件系统中的文件，可以放在浏览器或 CDN 之后使用
+//
+// Handler 支持范围请求、HEAD、基于强 ETag 和修改时间的条件请求（304），并为目录生成索引页。
+// 文件数据按需从映像读取，不会整个读入内存。
+package httpfs
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/0xXA/go-exfat/exfat"
+)
+
+// Options Handler 的选项
+type Options struct {
+	CacheControl string // 不为空时作为每个响应的 Cache-Control 头，如 "public, max-age=3600"
+	NoIndex      bool   // 不为目录生成索引页，请求目录时返回 404
+
+	// Index 不为 nil 时返回当前可用的目录树索引（见 ExFATFileSystem.BuildIndex），没有时返回 nil。
+	// 查找条目和列目录时先查询索引，索引无法回答时实时解析；索引可以在后台建立，不会阻塞请求。
+	Index func() *exfat.Index
+}
+
+// sniffSize 判断内容类型时读取的文件开头的字节数，与 http.DetectContentType 使用的相同
+const sniffSize = 512
+
+// Handler 返回提供 fsys 中文件的 http.Handler，请求路径即卷中的路径
+// 挂载在子路径下时配合 http.StripPrefix 使用。只接受 GET 和 HEAD。
+//
+// 文件的 ETag 由条目标识、大小和修改时间组成，映像不变时稳定；Content-Type 先按扩展名判断，
+// 没有已知扩展名时按文件开头的内容判断；Content-Disposition 带有原始文件名，
+// 非 ASCII 文件名按 RFC 2231 编码。URL 带有 download 参数时以附件形式下载。
+func Handler(fsys *exfat.ExFATFileSystem, opts Options) http.Handler {
+	return &handler{fsys: fsys, opts: opts}
+}
+
+type handler struct {
+	fsys *exfat.ExFATFileSystem
+	opts Options
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := path.Clean("/" + r.URL.Path)
+	entry, err := h.stat(name)
+	if err != nil {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	}
+	if h.opts.CacheControl != "" {
+		w.Header().Set("Cache-Control", h.opts.CacheControl)
+	}
+
+	if entry.IsDir {
+		if h.opts.NoIndex {
+			http.Error(w, "404 page not found", http.StatusNotFound)
+			return
+		}
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			redirect(w, r)
+			return
+		}
+		h.serveIndex(w, r, name)
+		return
+	}
+	h.serveFile(w, r, name, entry)
+}
+
+// serveFile 提供文件内容，范围请求和条件请求由 http.ServeContent 处理
+func (h *handler) serveFile(w http.ResponseWriter, r *http.Request, name string, entry exfat.FileEntry) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	header := w.Header()
+	header.Set("ETag", ETag(entry))
+	header.Set("Content-Type", contentType(entry.Name, f))
+	disposition := "inline"
+	if _, ok := r.URL.Query()["download"]; ok {
+		disposition = "attachment"
+	}
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": entry.Name}))
+	http.ServeContent(w, r, entry.Name, entry.ModTime, f)
+}
+
+// ETag 返回条目的强 ETag：条目标识、大小和修改时间（纳秒）
+// 同一映像中内容不变的文件 ETag 不变；文件被改写后修改时间或大小随之变化。
+func ETag(e exfat.FileEntry) string {
+	return fmt.Sprintf(`"%s-%x-%x"`, e.ID(), e.Size, e.ModTime.UnixNano())
+}
+
+// contentType 按扩展名判断内容类型，没有已知扩展名时读取文件开头判断
+func contentType(name string, f io.ReaderAt) string {
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		return ctype
+	}
+	head := make([]byte, sniffSize)
+	n, _ := f.ReadAt(head, 0)
+	return http.DetectContentType(head[:n])
+}
+
+// serveIndex 为目录生成索引页，子目录排在文件之前，各自按目录中的顺序
+func (h *handler) serveIndex(w http.ResponseWriter, r *http.Request, name string) {
+	entries, err := h.listDir(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	title := html.EscapeString(name)
+	fmt.Fprintf(w, "<!doctype html>\n<meta charset=\"utf-8\">\n<title>Index of %s</title>\n<h1>Index of %s</h1>\n<pre>\n", title, title)
+	if name != "/" {
+		fmt.Fprintf(w, "<a href=\"../\">../</a>\n")
+	}
+	for _, dirs := range []bool{true, false} {
+		for _, e := range entries {
+			if e.IsDir != dirs {
+				continue
+			}
+			display, href := e.Name, url.PathEscape(e.Name)
+			size := exfat.FormatFileSize(e.Size)
+			if e.IsDir {
+				display, href, size = display+"/", href+"/", "-"
+			}
+			fmt.Fprintf(w, "<a href=\"%s\">%s</a>  %s  %s\n", html.EscapeString(href), html.EscapeString(display), e.ModTime.Format("2006-01-02 15:04"), size)
+		}
+	}
+	fmt.Fprintf(w, "</pre>\n")
+}
+
+// index 返回当前可用的索引，没有时返回 nil
+func (h *handler) index() *exfat.Index {
+	if h.opts.Index == nil {
+		return nil
+	}
+	return h.opts.Index()
+}
+
+// stat 查找条目，索引无法回答时实时解析
+func (h *handler) stat(name string) (exfat.FileEntry, error) {
+	if ix := h.index(); ix != nil {
+		if entry, err := ix.Stat(name); !errors.Is(err, exfat.ErrNotIndexed) {
+			return entry, err
+		}
+	}
+	return h.fsys.Stat(name)
+}
+
+// listDir 列出目录，索引无法回答时实时解析
+func (h *handler) listDir(name string) ([]exfat.FileEntry, error) {
+	if ix := h.index(); ix != nil {
+		if entries, err := ix.ListDir(name); !errors.Is(err, exfat.ErrNotIndexed) {
+			return entries, err
+		}
+	}
+	return h.fsys.ListDir(name)
+}
+
+// redirect 把不以 "/" 结尾的目录请求以相对路径重定向到带 "/" 的地址，保留查询参数
+// 使用相对路径，挂载在子路径下（http.StripPrefix）时同样有效。
+func redirect(w http.ResponseWriter, r *http.Request) {
+	target := url.PathEscape(path.Base(r.URL.Path)) + "/"
+	if q := r.URL.RawQuery; q != "" {
+		target += "?" + q
+	}
+	w.Header().Set("Location", target)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
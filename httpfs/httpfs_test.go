@@ -0,0 +1,257 @@
+package httpfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+var (
+	hello   = []byte("hello, range requests")
+	pngData = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+)
+
+func newTestFS(t *testing.T) *exfat.ExFATFileSystem {
+	t.Helper()
+	modified := testimage.File("hello.txt", hello)
+	modified.Modified = time.Date(2023, 3, 4, 5, 6, 8, 0, time.UTC)
+	img := testimage.Build(testimage.Options{},
+		modified,
+		testimage.File("picture", pngData), // 没有扩展名，按内容判断类型
+		testimage.File("Фото 1.txt", []byte("non-ascii")),
+		testimage.File("Tom & Jerry.txt", []byte("escaped")),
+		testimage.Dir("Sub", testimage.File("inner.txt", []byte("inner")), testimage.Dir("Nested")))
+	fs, err := exfat.NewExFATFileSystem(img.Disk())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+// do 向 h 发送请求，header 为额外的请求头（键值交替）
+func do(h http.Handler, method, target string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServeFile(t *testing.T) {
+	fs := newTestFS(t)
+	h := Handler(fs, Options{CacheControl: "public, max-age=60"})
+
+	rec := do(h, http.MethodGet, "/hello.txt")
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), hello) {
+		t.Fatalf("GET: %d %q", rec.Code, rec.Body.Bytes())
+	}
+	entry, _ := fs.Stat("/hello.txt")
+	for key, want := range map[string]string{
+		"ETag":                ETag(entry),
+		"Content-Type":        "text/plain; charset=utf-8",
+		"Content-Disposition": `inline; filename=hello.txt`,
+		"Cache-Control":       "public, max-age=60",
+		"Accept-Ranges":       "bytes",
+		"Last-Modified":       "Sat, 04 Mar 2023 05:06:08 GMT",
+	} {
+		if got := rec.Header().Get(key); got != want {
+			t.Errorf("%s: %q, want %q", key, got, want)
+		}
+	}
+
+	if rec := do(h, http.MethodGet, "/picture"); rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("sniffed type %q, want image/png", rec.Header().Get("Content-Type"))
+	}
+	if rec := do(h, http.MethodGet, "/hello.txt?download"); !strings.HasPrefix(rec.Header().Get("Content-Disposition"), "attachment;") {
+		t.Errorf("download: Content-Disposition %q", rec.Header().Get("Content-Disposition"))
+	}
+
+	rec = do(h, http.MethodHead, "/hello.txt")
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 || rec.Header().Get("Content-Length") != "21" {
+		t.Errorf("HEAD: %d, %d body bytes, Content-Length %q", rec.Code, rec.Body.Len(), rec.Header().Get("Content-Length"))
+	}
+	rec = do(h, http.MethodPost, "/hello.txt")
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST: %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+	for _, p := range []string{"/missing", "/Sub/missing.txt", "/hello.txt/x"} {
+		if rec := do(h, http.MethodGet, p); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: %d, want 404", p, rec.Code)
+		}
+	}
+}
+
+func TestRanges(t *testing.T) {
+	h := Handler(newTestFS(t), Options{})
+
+	rec := do(h, http.MethodGet, "/hello.txt", "Range", "bytes=7-11")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "range" || rec.Header().Get("Content-Range") != "bytes 7-11/21" {
+		t.Errorf("single range: %d %q %q", rec.Code, rec.Body.String(), rec.Header().Get("Content-Range"))
+	}
+	rec = do(h, http.MethodGet, "/hello.txt", "Range", "bytes=-8")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "requests" {
+		t.Errorf("suffix range: %d %q", rec.Code, rec.Body.String())
+	}
+	rec = do(h, http.MethodGet, "/hello.txt", "Range", "bytes=100-")
+	if rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("unsatisfiable range: %d", rec.Code)
+	}
+
+	rec = do(h, http.MethodGet, "/hello.txt", "Range", "bytes=0-4,7-11")
+	mediaType, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	if rec.Code != http.StatusPartialContent || err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("multiple ranges: %d %q %v", rec.Code, rec.Header().Get("Content-Type"), err)
+	}
+	mr := multipart.NewReader(rec.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(part)
+		parts = append(parts, string(data))
+	}
+	if strings.Join(parts, "|") != "hello|range" {
+		t.Errorf("multiple ranges: parts %q", parts)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	fs := newTestFS(t)
+	h := Handler(fs, Options{})
+	entry, _ := fs.Stat("/hello.txt")
+	etag := ETag(entry)
+
+	tests := []struct {
+		name   string
+		header []string
+		code   int
+	}{
+		{"matching If-None-Match", []string{"If-None-Match", etag}, http.StatusNotModified},
+		{"one of several If-None-Match", []string{"If-None-Match", `"other", ` + etag}, http.StatusNotModified},
+		{"If-None-Match *", []string{"If-None-Match", "*"}, http.StatusNotModified},
+		{"stale If-None-Match", []string{"If-None-Match", `"stale"`}, http.StatusOK},
+		{"If-Modified-Since at the modification time", []string{"If-Modified-Since", "Sat, 04 Mar 2023 05:06:08 GMT"}, http.StatusNotModified},
+		{"If-Modified-Since before", []string{"If-Modified-Since", "Sat, 04 Mar 2023 05:06:07 GMT"}, http.StatusOK},
+		{"matching If-Match", []string{"If-Match", etag}, http.StatusOK},
+		{"failing If-Match", []string{"If-Match", `"stale"`}, http.StatusPreconditionFailed},
+		{"matching If-Range", []string{"Range", "bytes=0-4", "If-Range", etag}, http.StatusPartialContent},
+		{"stale If-Range sends the whole file", []string{"Range", "bytes=0-4", "If-Range", `"stale"`}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := do(h, http.MethodGet, "/hello.txt", tt.header...); rec.Code != tt.code {
+			t.Errorf("%s: %d, want %d", tt.name, rec.Code, tt.code)
+		}
+	}
+
+	// ETag 稳定，不同文件的 ETag 不同
+	other, _ := fs.Stat("/Sub/inner.txt")
+	again, _ := fs.Stat("/hello.txt")
+	if ETag(again) != etag || ETag(other) == etag {
+		t.Errorf("ETags %s, %s, %s", etag, ETag(again), ETag(other))
+	}
+	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) || strings.HasPrefix(etag, "W/") {
+		t.Errorf("ETag %s is not a strong ETag", etag)
+	}
+}
+
+func TestIndexPage(t *testing.T) {
+	h := Handler(newTestFS(t), Options{})
+
+	rec := do(h, http.MethodGet, "/")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("index: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	// 子目录排在文件之前，名称经过 HTML 转义，链接经过 URL 编码；根目录没有上级链接
+	sub := strings.Index(body, `<a href="Sub/">Sub/</a>`)
+	file := strings.Index(body, `<a href="hello.txt">hello.txt</a>`)
+	if sub < 0 || file < 0 || sub > file {
+		t.Errorf("directory before files: %d, %d\n%s", sub, file, body)
+	}
+	for _, want := range []string{
+		`<a href="Tom%20&amp;%20Jerry.txt">Tom &amp; Jerry.txt</a>`,
+		`<a href="` + url.PathEscape("Фото 1.txt") + `">Фото 1.txt</a>`,
+		"<title>Index of /</title>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("index lacks %s\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `href="../"`) {
+		t.Error("root index links to its parent")
+	}
+
+	rec = do(h, http.MethodGet, "/Sub/")
+	if !strings.Contains(rec.Body.String(), `<a href="../">../</a>`) || !strings.Contains(rec.Body.String(), `<a href="Nested/">Nested/</a>`) {
+		t.Errorf("subdirectory index:\n%s", rec.Body.String())
+	}
+	if rec := do(h, http.MethodHead, "/Sub/"); rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("HEAD index: %d, %d bytes", rec.Code, rec.Body.Len())
+	}
+
+	// 不以 "/" 结尾的目录以相对路径重定向，保留查询参数
+	rec = do(h, http.MethodGet, "/Sub/Nested?x=1")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "Nested/?x=1" {
+		t.Errorf("redirect: %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	noIndex := Handler(newTestFS(t), Options{NoIndex: true})
+	if rec := do(noIndex, http.MethodGet, "/Sub/"); rec.Code != http.StatusNotFound {
+		t.Errorf("NoIndex: %d, want 404", rec.Code)
+	}
+}
+
+func TestNonASCIINames(t *testing.T) {
+	h := Handler(newTestFS(t), Options{})
+	rec := do(h, http.MethodGet, "/"+url.PathEscape("Фото 1.txt"))
+	if rec.Code != http.StatusOK || rec.Body.String() != "non-ascii" {
+		t.Fatalf("GET: %d %q", rec.Code, rec.Body.String())
+	}
+	// 非 ASCII 文件名按 RFC 2231 编码，解析后得到原始名称
+	disposition := rec.Header().Get("Content-Disposition")
+	if !strings.Contains(disposition, "filename*=utf-8''") {
+		t.Errorf("Content-Disposition %q is not RFC 2231 encoded", disposition)
+	}
+	if _, params, err := mime.ParseMediaType(disposition); err != nil || params["filename"] != "Фото 1.txt" {
+		t.Errorf("Content-Disposition %q decodes to %q, %v", disposition, params["filename"], err)
+	}
+	// 路径与卷中的其他路径一样不区分大小写
+	if rec := do(h, http.MethodGet, "/"+url.PathEscape("фото 1.TXT")); rec.Code != http.StatusOK {
+		t.Errorf("case-insensitive GET: %d", rec.Code)
+	}
+}
+
+func TestIndexOption(t *testing.T) {
+	fs := newTestFS(t)
+	ix, err := fs.BuildIndex(context.Background(), exfat.IndexOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []*exfat.Index{nil, ix} {
+		h := Handler(fs, Options{Index: func() *exfat.Index { return index }})
+		if rec := do(h, http.MethodGet, "/Sub/inner.txt"); rec.Code != http.StatusOK || rec.Body.String() != "inner" {
+			t.Errorf("index %v: GET %d %q", index != nil, rec.Code, rec.Body.String())
+		}
+		if rec := do(h, http.MethodGet, "/Sub/"); !strings.Contains(rec.Body.String(), "inner.txt") {
+			t.Errorf("index %v: listing lacks inner.txt", index != nil)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package exfat_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	exfat "github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+func TestIdentifyLargeDynamicVHD(t *testing.T) {
+	img := testimage.Build(testimage.Options{Label: "CAM_A", Serial: 0x1A2B3C4D}, testimage.File("a.jpg", bytes.Repeat([]byte("jpeg"), 1000)))
+
+	// 通过容器读取的字节数与磁盘大小无关：只有引导扇区（探测扇区大小和识别各一次）和根目录的第一个簇。
+	// 打开时读取的 VHD 头部和 BAT 不经过读取跟踪，BAT 为每 2 MiB 的块 4 字节，60 GiB 时为 120 KiB。
+	budget := int64(2*512 + img.BytesPerCluster)
+	for _, size := range []uint64{1 << 30, 60 << 30} {
+		bs := img.BootSector(false)
+		binary.LittleEndian.PutUint64(bs[72:], size/uint64(img.BytesPerSector))
+		img.SignBoot()
+		path := filepath.Join(t.TempDir(), "cam_a.vhd")
+		if err := os.WriteFile(path, testimage.SparseDynamicVHD(img.Bytes, int64(size), 2<<20, 512), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var trace bytes.Buffer
+		id, err := exfat.Identify(path, exfat.WithReadTracing(&trace))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id.DiskType != "dynamic" || id.Label != "CAM_A" || id.Serial != "1A2B-3C4D" || id.Capacity != size {
+			t.Errorf("identity %+v", id)
+		}
+
+		records, err := exfat.DecodeTrace(&trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var read int64
+		for _, r := range records {
+			if strings.HasPrefix(r.Layer, "vhd:") {
+				read += r.Length
+			}
+			if r.Purpose == "fat" || r.Purpose == "bitmap" || r.Purpose == "upcase" {
+				t.Errorf("identify read %+v", r)
+			}
+		}
+		if read == 0 || read > budget || len(records) > 3 {
+			t.Errorf("%d GiB disk: read %d bytes in %d reads, budget is %d bytes in 3 reads", size>>30, read, len(records), budget)
+		}
+	}
+}
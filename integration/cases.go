@@ -0,0 +1,225 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// 每个用例的映像大小和卷标
+const (
+	imageSize  = 32 << 20
+	imageLabel = "GOEXFAT"
+)
+
+// Case 一个集成测试用例
+// 映像先由 mkfs.exfat 创建，经 Populate 通过内核驱动写入初始内容，再由 Modify 用本包修改，
+// 最后只读挂载，由 Verify 检查，并比较本包和内核看到的目录树、运行 fsck.exfat -n。
+type Case struct {
+	Name     string
+	Populate func(dir string) error             // 通过读写挂载的内核驱动准备内容（可以为 nil）
+	Modify   func(v *exfat.VHD) error           // 用本包修改以 WithWritable 打开的映像（可以为 nil）
+	Verify   func(m *Mount, v *exfat.VHD) error // 在只读挂载上检查修改的结果（可以为 nil）
+}
+
+// Result 一个用例的运行结果
+type Result struct {
+	Case       string
+	Err        error      // 用例失败的原因，通过时为 nil
+	Mismatches []Mismatch // 本包与内核看到的目录树之间的差异
+	Fsck       FsckResult
+}
+
+// Run 在 workDir 中依次运行用例，每个用例使用单独的映像
+// 调用者应先用 Available 确认可以运行。
+func Run(workDir string, cases []Case) []Result {
+	results := make([]Result, 0, len(cases))
+	for _, c := range cases {
+		result := Result{Case: c.Name}
+		result.Err = runCase(filepath.Join(workDir, c.Name+".img"), c, &result)
+		results = append(results, result)
+	}
+	return results
+}
+
+// runCase 运行一个用例，结果中的差异和 fsck 结果写入 result
+func runCase(image string, c Case, result *Result) error {
+	if err := NewImage(image, imageSize, imageLabel, c.Populate); err != nil {
+		return fmt.Errorf("failed to create image: %v", err)
+	}
+	if c.Modify != nil {
+		v, err := exfat.OpenVHD(image, exfat.WithWritable())
+		if err != nil {
+			return err
+		}
+		err = c.Modify(v)
+		if cerr := v.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to modify image: %v", err)
+		}
+	}
+
+	if err := verify(image, c, result); err != nil {
+		return err
+	}
+	fsck, err := Fsck(image)
+	result.Fsck = fsck
+	if err != nil {
+		return err
+	}
+	if !fsck.Clean() {
+		return fmt.Errorf("fsck.exfat: %s", fsck)
+	}
+	if len(result.Mismatches) > 0 {
+		return fmt.Errorf("%d differences between the package and the kernel driver", len(result.Mismatches))
+	}
+	return nil
+}
+
+// verify 只读挂载映像，运行用例的检查并比较目录树
+func verify(image string, c Case, result *Result) error {
+	v, err := exfat.OpenVHD(image)
+	if err != nil {
+		return err
+	}
+	defer v.Close()
+	m, err := MountImage(image, true)
+	if err != nil {
+		return err
+	}
+
+	if c.Verify != nil {
+		err = c.Verify(m, v)
+	}
+	if err == nil {
+		result.Mismatches, err = CompareTree(v, m.Dir)
+	}
+	if cerr := m.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// DefaultCases 返回覆盖读取路径和各写入功能的用例
+func DefaultCases() []Case {
+	return []Case{
+		{Name: "read-tree", Populate: populateTree, Verify: verifyLabel},
+		{Name: "usage", Populate: populateTree, Verify: verifyUsage},
+		{Name: "chtimes", Populate: populateFile, Modify: modifyTimes, Verify: verifyTimes},
+		{Name: "chattr", Populate: populateFile, Modify: modifyAttributes},
+		{Name: "empty-volume", Verify: verifyEmptyVolume},
+		{Name: "empty-entries", Populate: populateEmpty, Verify: verifyEmptyEntries},
+	}
+}
+
+// 用例中使用的固定时间
+var (
+	treeTime    = time.Date(2020, 2, 29, 23, 59, 58, 0, time.UTC)
+	chtimesTime = time.Date(2021, 3, 4, 5, 6, 7, 890_000_000, time.UTC)
+)
+
+// populateTree 写入覆盖常见名称和大小的目录树：嵌套目录、非 ASCII 和长文件名（包括跨越两个文件名条目的代理对）、
+// 空文件以及跨多个簇的文件
+func populateTree(dir string) error {
+	files := map[string][]byte{
+		"hello.txt":                    []byte("hello, world\n"),
+		"empty.txt":                    nil,
+		"Ёлка.txt":                     []byte("ёлка"),
+		"emoji😀.txt":                   []byte("emoji"),
+		"abcdefghijklmn😀.txt":          []byte("surrogate pair across two name entries"),
+		strings.Repeat("long", 50):     []byte("long name"),
+		"Dir/Nested/deep.bin":          pattern(100_000),
+		"Dir/MixedCase.TXT":            []byte("case"),
+		"big.bin":                      pattern(3 << 20),
+		"Dir/Nested/Another Dir/x.txt": []byte("x"),
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			return err
+		}
+		if err := os.Chtimes(p, treeTime, treeTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pattern 返回长度为 n、内容不重复的数据，用于发现簇顺序错误
+func pattern(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i*31 + i/4096)
+	}
+	return data
+}
+
+// populateFile 写入一个文件，供修改元数据的用例使用
+func populateFile(dir string) error {
+	return os.WriteFile(filepath.Join(dir, "file.txt"), []byte("metadata\n"), 0644)
+}
+
+// verifyLabel 检查本包和 blkid 读到的卷标一致
+func verifyLabel(m *Mount, v *exfat.VHD) error {
+	id, err := exfat.Identify(m.Image)
+	if err != nil {
+		return err
+	}
+	label, err := m.Label()
+	if err != nil {
+		return err
+	}
+	if id.Label != imageLabel || label != imageLabel {
+		return fmt.Errorf("label: package reads %q, blkid shows %q, want %q", id.Label, label, imageLabel)
+	}
+	return nil
+}
+
+// verifyUsage 检查本包按分配位图统计的容量和空闲空间与内核驱动（df）一致
+func verifyUsage(m *Mount, v *exfat.VHD) error {
+	total, used, free, err := v.Usage()
+	if err != nil {
+		return err
+	}
+	kernelTotal, kernelFree, err := m.Usage()
+	if err != nil {
+		return err
+	}
+	if total != kernelTotal || free != kernelFree || used != total-free {
+		return fmt.Errorf("usage: package reads total %d, free %d, used %d; kernel shows total %d, free %d",
+			total, free, used, kernelTotal, kernelFree)
+	}
+	return nil
+}
+
+// modifyTimes 用 Chtimes 修改文件的修改时间和访问时间
+func modifyTimes(v *exfat.VHD) error {
+	return v.Chtimes("/file.txt", chtimesTime, chtimesTime)
+}
+
+// verifyTimes 检查内核驱动看到的修改时间
+// 访问时间在 exFAT 中只精确到 2 秒，内核按挂载选项可能不报告，因此只检查修改时间。
+func verifyTimes(m *Mount, v *exfat.VHD) error {
+	info, err := os.Stat(filepath.Join(m.Dir, "file.txt"))
+	if err != nil {
+		return err
+	}
+	if !info.ModTime().Equal(chtimesTime) {
+		return fmt.Errorf("mtime: kernel shows %v, want %v", info.ModTime().UTC(), chtimesTime)
+	}
+	return nil
+}
+
+// modifyAttributes 用 Chattr 把文件设为只读；CompareTree 检查内核是否去掉了写权限
+func modifyAttributes(v *exfat.VHD) error {
+	return v.Chattr("/file.txt", exfat.AttrReadOnly|exfat.AttrArchive)
+}
@@ -0,0 +1,83 @@
+// exfat-integration 运行 integration 包中的用例：用内核的 exFAT 驱动挂载本包生成和修改的映像，
+// 检查内容和元数据并运行 fsck.exfat -n。
+// 需要 Linux、root 权限、util-linux、exfatprogs，并设置 EXFAT_INTEGRATION=1；
+// 条件不满足时打印跳过的原因并以 0 退出，因此可以无条件地放进 CI。
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/0xXA/go-exfat/integration"
+)
+
+func main() {
+	flags := flag.NewFlagSet("exfat-integration", flag.ExitOnError)
+	pattern := flags.String("run", "", "Only run cases whose name matches this regular expression")
+	keep := flags.Bool("keep", false, "Keep the work directory with the images for inspection")
+	verbose := flags.Bool("v", false, "Print the fsck.exfat output of every case")
+	flags.Usage = func() {
+		fmt.Println("Usage: EXFAT_INTEGRATION=1 exfat-integration [-run regexp] [-keep] [-v]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	match, err := regexp.Compile(*pattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "exfat-integration: invalid -run pattern: %v\n", err)
+		os.Exit(2)
+	}
+
+	if err := integration.Available(); errors.Is(err, integration.ErrSkipped) {
+		fmt.Printf("SKIP: %v\n", err)
+		return
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "exfat-integration: %v\n", err)
+		os.Exit(1)
+	}
+
+	var cases []integration.Case
+	for _, c := range integration.DefaultCases() {
+		if match.MatchString(c.Name) {
+			cases = append(cases, c)
+		}
+	}
+
+	workDir, err := os.MkdirTemp("", "exfat-integration-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "exfat-integration: %v\n", err)
+		os.Exit(1)
+	}
+	if *keep {
+		fmt.Printf("Images are kept in %s\n", workDir)
+	} else {
+		defer os.RemoveAll(workDir)
+	}
+
+	failed := 0
+	for _, r := range integration.Run(workDir, cases) {
+		if r.Err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", r.Case, r.Err)
+		} else {
+			fmt.Printf("PASS %s\n", r.Case)
+		}
+		for _, m := range r.Mismatches {
+			fmt.Printf("    %s\n", m)
+		}
+		if *verbose || !r.Fsck.Clean() {
+			fmt.Print(r.Fsck.Output)
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d cases failed\n", failed, len(cases))
+		// 延迟的清理不会在 os.Exit 时运行
+		if !*keep {
+			os.RemoveAll(workDir)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,139 @@
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// mtimeTolerance 比较修改时间时允许的误差：exFAT 的修改时间精度为 10 毫秒
+const mtimeTolerance = 10 * time.Millisecond
+
+// Mismatch 本包读到的条目与内核驱动看到的条目之间的一处差异
+type Mismatch struct {
+	Path string // 条目在卷中的路径
+	What string // 差异的种类，如 "missing"、"size"、"content"
+	Want string // 本包读到的值
+	Got  string // 内核驱动看到的值
+}
+
+// String 返回差异的文本形式
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: %s: package reads %s, kernel shows %s", m.Path, m.What, m.Want, m.Got)
+}
+
+// CompareTree 比较本包从 v 读到的目录树与挂载在 dir 的同一个卷，返回所有差异
+// 比较名称（区分大小写）、类型、大小、内容、修改时间和只读属性（内核驱动以去掉写权限表示）。
+func CompareTree(v *exfat.VHD, dir string) ([]Mismatch, error) {
+	var mismatches []Mismatch
+	err := compareDir(v, "/", dir, &mismatches)
+	return mismatches, err
+}
+
+// compareDir 递归比较一个目录
+func compareDir(v *exfat.VHD, srcPath, dir string, mismatches *[]Mismatch) error {
+	entries, err := v.ListDir(srcPath)
+	if err != nil {
+		return err
+	}
+	osEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(osEntries))
+	for _, e := range osEntries {
+		seen[e.Name()] = true
+	}
+	for _, entry := range entries {
+		p := path.Join(srcPath, entry.Name)
+		if !seen[entry.Name] {
+			*mismatches = append(*mismatches, Mismatch{Path: p, What: "missing", Want: "present", Got: "absent"})
+			continue
+		}
+		delete(seen, entry.Name)
+		if err := compareEntry(v, entry, p, filepath.Join(dir, entry.Name), mismatches); err != nil {
+			return err
+		}
+	}
+	for name := range seen {
+		*mismatches = append(*mismatches, Mismatch{Path: path.Join(srcPath, name), What: "missing", Want: "absent", Got: "present"})
+	}
+	return nil
+}
+
+// compareEntry 比较一个条目，目录继续递归
+func compareEntry(v *exfat.VHD, entry exfat.FileEntry, srcPath, osPath string, mismatches *[]Mismatch) error {
+	info, err := os.Lstat(osPath)
+	if err != nil {
+		return err
+	}
+	add := func(what string, want, got interface{}) {
+		*mismatches = append(*mismatches, Mismatch{Path: srcPath, What: what, Want: fmt.Sprint(want), Got: fmt.Sprint(got)})
+	}
+
+	if entry.IsDir != info.IsDir() {
+		add("directory", entry.IsDir, info.IsDir())
+		return nil
+	}
+	if readOnly := info.Mode().Perm()&0222 == 0; entry.Attributes.Has(exfat.AttrReadOnly) != readOnly {
+		add("read-only", entry.Attributes.Has(exfat.AttrReadOnly), readOnly)
+	}
+	if d := entry.ModTime.Sub(info.ModTime()); d >= mtimeTolerance || d <= -mtimeTolerance {
+		add("mtime", entry.ModTime.UTC().Format(time.RFC3339Nano), info.ModTime().UTC().Format(time.RFC3339Nano))
+	}
+	if entry.IsDir {
+		return compareDir(v, srcPath, osPath, mismatches)
+	}
+
+	if entry.Size != info.Size() {
+		add("size", entry.Size, info.Size())
+		return nil
+	}
+	same, err := sameContent(v, srcPath, osPath)
+	if err != nil {
+		return err
+	}
+	if !same {
+		add("content", "data", "different data")
+	}
+	return nil
+}
+
+// sameContent 以流的方式比较两个文件的内容
+func sameContent(v *exfat.VHD, srcPath, osPath string) (bool, error) {
+	src, err := v.Open(srcPath)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+	f, err := os.Open(osPath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	a, b := make([]byte, 64<<10), make([]byte, 64<<10)
+	for {
+		n, errA := io.ReadFull(src, a)
+		m, errB := io.ReadFull(f, b)
+		if n != m || !bytes.Equal(a[:n], b[:m]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB == io.EOF || errB == io.ErrUnexpectedEOF, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xXA/go-exfat"
+)
+
+// emptyTree 空条目用例的目录树：空文件、空目录，以及只包含空文件和空目录的目录
+// 以 / 结尾的是目录；目录排在其内容之后，设置修改时间时内容已经写好。
+var emptyTree = []string{
+	"empty.txt",
+	"emptydir/",
+	"hollow/zero.bin",
+	"hollow/sub/",
+	"hollow/sub2/zero.txt",
+	"hollow/sub2/",
+	"hollow/",
+}
+
+// populateEmpty 写入 emptyTree
+func populateEmpty(dir string) error {
+	for _, name := range emptyTree {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		var err error
+		if strings.HasSuffix(name, "/") {
+			err = os.MkdirAll(p, 0755)
+		} else if err = os.MkdirAll(filepath.Dir(p), 0755); err == nil {
+			err = os.WriteFile(p, nil, 0644)
+		}
+		if err == nil {
+			err = os.Chtimes(p, treeTime, treeTime)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyEmptyEntries 检查空文件读取为空数据、空目录没有子条目，检查没有发现问题，提取后恢复同样的树和修改时间
+func verifyEmptyEntries(m *Mount, v *exfat.VHD) error {
+	fsys := v.FileSystem()
+	for _, name := range emptyTree {
+		p := "/" + strings.TrimSuffix(name, "/")
+		e, err := fsys.Stat(p)
+		if err != nil {
+			return err
+		}
+		if e.Size != 0 || e.IsDir != strings.HasSuffix(name, "/") {
+			return fmt.Errorf("%s: size %d, directory %v", p, e.Size, e.IsDir)
+		}
+		if !e.IsDir {
+			if err := verifyEmptyFile(v, p); err != nil {
+				return err
+			}
+		}
+	}
+	for _, p := range []string{"/emptydir", "/hollow/sub"} {
+		if entries, err := v.ListDir(p); err != nil || len(entries) != 0 {
+			return fmt.Errorf("%s: %d entries, %v", p, len(entries), err)
+		}
+	}
+
+	if err := verifyCheckClean(v); err != nil {
+		return err
+	}
+	dest, err := os.MkdirTemp("", "exfat-extract-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dest)
+	report, err := v.ExtractAll("/", dest)
+	if err != nil {
+		return err
+	}
+	files := 0
+	for _, name := range emptyTree {
+		if !strings.HasSuffix(name, "/") {
+			files++
+		}
+	}
+	if report.Extracted != files {
+		return fmt.Errorf("extracted %d files, want %d", report.Extracted, files)
+	}
+	for _, name := range emptyTree {
+		info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		if info.IsDir() != strings.HasSuffix(name, "/") || (!info.IsDir() && info.Size() != 0) {
+			return fmt.Errorf("extracted %s: mode %v, size %d", name, info.Mode(), info.Size())
+		}
+		if !info.ModTime().Equal(treeTime) {
+			return fmt.Errorf("extracted %s: mtime %v, want %v", name, info.ModTime().UTC(), treeTime)
+		}
+	}
+	return nil
+}
+
+// verifyEmptyFile 检查空文件的各种读取方式都返回空数据
+func verifyEmptyFile(v *exfat.VHD, p string) error {
+	data, err := v.ReadFile(p)
+	if err != nil || len(data) != 0 {
+		return fmt.Errorf("ReadFile %s: %d bytes, %v", p, len(data), err)
+	}
+	if data, err := v.ReadFileRange(p, 0, 1); err != io.EOF || len(data) != 0 {
+		return fmt.Errorf("ReadFileRange %s: %d bytes, %v", p, len(data), err)
+	}
+	f, err := v.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if sum := sha256.Sum256(nil); !bytes.Equal(h.Sum(nil), sum[:]) {
+		return fmt.Errorf("%s: SHA-256 is %x, want the hash of empty input", p, h.Sum(nil))
+	}
+	return nil
+}
+
+// verifyEmptyVolume 检查刚格式化的卷（根目录只有分配位图、大写表和卷标）：没有条目、检查没有发现问题、提取和归档为空
+func verifyEmptyVolume(m *Mount, v *exfat.VHD) error {
+	entries, err := v.ListDir("/")
+	if err != nil || len(entries) != 0 {
+		return fmt.Errorf("root: %d entries, %v", len(entries), err)
+	}
+	if err := verifyCheckClean(v); err != nil {
+		return err
+	}
+	if _, err := v.VolumeStats(); err != nil {
+		return err
+	}
+
+	dest, err := os.MkdirTemp("", "exfat-extract-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dest)
+	if report, err := v.ExtractAll("/", dest); err != nil || report.Extracted != 0 {
+		return fmt.Errorf("extract: %+v, %v", report, err)
+	}
+	if err := v.WriteTarGz("/", io.Discard); err != nil {
+		return err
+	}
+	return nil
+}
+
+// verifyCheckClean 检查 Check 没有发现问题
+func verifyCheckClean(v *exfat.VHD) error {
+	result, err := v.Check(context.Background(), exfat.CheckOptions{})
+	if err != nil {
+		return err
+	}
+	if len(result.Findings) > 0 {
+		f := result.Findings[0]
+		return fmt.Errorf("check: %d findings, first %s: %v %v", len(result.Findings), f.Path, f.Anomalies, f.Err)
+	}
+	return nil
+}
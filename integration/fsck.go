@@ -0,0 +1,67 @@
+package integration
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// fsck.exfat 的退出码（与 e2fsck 相同的约定）
+const (
+	fsckNoErrors  = 0 // 没有问题
+	fsckOperation = 8 // fsck.exfat 自身出错；1 到 7 表示发现了问题
+)
+
+// FsckResult fsck.exfat -n 的检查结果
+type FsckResult struct {
+	ExitCode int      // 退出码：0 为没有问题，4 为有未修复的问题
+	Problems []string // 以 "ERROR:" 开头的输出行（已去掉前缀）
+	Output   string   // 完整输出
+}
+
+// Clean 文件系统是否没有任何问题
+func (r FsckResult) Clean() bool {
+	return r.ExitCode == fsckNoErrors && len(r.Problems) == 0
+}
+
+// String 返回检查结果的摘要
+func (r FsckResult) String() string {
+	if r.Clean() {
+		return "clean"
+	}
+	if len(r.Problems) == 0 {
+		return fmt.Sprintf("exit code %d", r.ExitCode)
+	}
+	return fmt.Sprintf("exit code %d: %s", r.ExitCode, strings.Join(r.Problems, "; "))
+}
+
+// Fsck 以只读方式（-n，不修复）检查映像
+// 检查发现问题时返回 Clean 为 false 的结果；只有 fsck.exfat 无法运行或自身出错时才返回错误。
+func Fsck(image string) (FsckResult, error) {
+	out, err := exec.Command("fsck.exfat", "-n", image).CombinedOutput()
+	result := parseFsck(string(out))
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr) && exitErr.ExitCode() < fsckOperation:
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		return result, fmt.Errorf("fsck.exfat -n %s: %v: %s", image, err, strings.TrimSpace(result.Output))
+	}
+	return result, nil
+}
+
+// parseFsck 从 fsck.exfat 的输出中取出问题行
+func parseFsck(out string) FsckResult {
+	result := FsckResult{Output: out}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		if problem, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "ERROR:"); ok {
+			result.Problems = append(result.Problems, strings.TrimSpace(problem))
+		}
+	}
+	return result
+}
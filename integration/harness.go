@@ -0,0 +1,137 @@
+// Package integration 用操作系统的 exFAT 驱动验证本包生成和修改的映像
+//
+// 映像通过 loop 设备挂载到内核的 exfat 驱动上，用普通的文件系统调用检查内容、名称、时间戳和卷标，
+// 再用 exfatprogs 的 fsck.exfat -n 检查结构。需要 root 权限、util-linux 和 exfatprogs，只支持 Linux；
+// 并且只有设置了环境变量 EXFAT_INTEGRATION=1 才会运行，条件不满足时 Available 返回包装了 ErrSkipped 的错误，
+// 说明跳过的原因。integration/cmd/exfat-integration 运行 DefaultCases 中的全部用例。
+//
+// 每个写入功能在 DefaultCases 中增加自己的用例：Populate 通过内核驱动准备映像内容，
+// Modify 用本包修改映像，Verify 在重新挂载后检查结果；之后还会比较本包读到的目录树和内核看到的目录树。
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// EnvVar 启用集成测试的环境变量，值为 1 时运行
+const EnvVar = "EXFAT_INTEGRATION"
+
+// ErrSkipped 表示运行环境不满足条件，集成测试被跳过
+var ErrSkipped = errors.New("integration tests skipped")
+
+// Available 检查能否运行集成测试：设置了 EnvVar、以 root 运行、工具齐全且内核支持 exfat
+// 不能运行时返回包装了 ErrSkipped 的错误，错误信息说明原因和解决办法。
+func Available() error {
+	if os.Getenv(EnvVar) != "1" {
+		return skipf("set %s=1 to mount images with the OS exFAT driver", EnvVar)
+	}
+	return available()
+}
+
+// skipf 返回包装了 ErrSkipped 的错误
+func skipf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrSkipped, fmt.Sprintf(format, args...))
+}
+
+// Mount 一个通过 loop 设备挂载的映像
+type Mount struct {
+	Image  string // 映像文件
+	Device string // loop 设备
+	Dir    string // 挂载点
+}
+
+// MountImage 把映像挂载到临时目录，readOnly 为 true 时只读挂载
+// 用完后调用 Close 卸载并释放 loop 设备。
+func MountImage(image string, readOnly bool) (*Mount, error) {
+	dir, err := os.MkdirTemp("", "exfat-mount-")
+	if err != nil {
+		return nil, err
+	}
+	device, err := attach(image)
+	if err != nil {
+		os.Remove(dir)
+		return nil, err
+	}
+	if err := mount(device, dir, readOnly); err != nil {
+		detach(device)
+		os.Remove(dir)
+		return nil, err
+	}
+	return &Mount{Image: image, Device: device, Dir: dir}, nil
+}
+
+// Close 卸载映像、释放 loop 设备并删除挂载点，返回遇到的第一个错误
+func (m *Mount) Close() error {
+	err := unmount(m.Dir)
+	if err == nil {
+		err = detach(m.Device)
+	}
+	if err == nil {
+		err = os.Remove(m.Dir)
+	}
+	return err
+}
+
+// Label 返回 blkid 从设备上读到的卷标
+func (m *Mount) Label() (string, error) {
+	out, err := run("blkid", "-p", "-s", "LABEL", "-o", "value", m.Device)
+	return strings.TrimSpace(out), err
+}
+
+// Usage 返回内核驱动报告的总容量和空闲空间（字节），即 df 显示的值
+func (m *Mount) Usage() (total, free uint64, err error) {
+	out, err := run("stat", "--file-system", "--format", "%S %b %f", m.Dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	var blockSize, blocks, freeBlocks uint64
+	if _, err := fmt.Sscan(out, &blockSize, &blocks, &freeBlocks); err != nil {
+		return 0, 0, fmt.Errorf("unexpected stat output %q: %v", out, err)
+	}
+	return blocks * blockSize, freeBlocks * blockSize, nil
+}
+
+// NewImage 创建 size 字节、卷标为 label 的空白 exFAT 映像，并通过内核驱动调用 populate 写入初始内容
+// populate 的参数是读写挂载的挂载点，为 nil 时映像保持空白。
+func NewImage(path string, size int64, label string, populate func(dir string) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = f.Truncate(size)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := run("mkfs.exfat", "-L", label, path); err != nil {
+		return err
+	}
+	if populate == nil {
+		return nil
+	}
+
+	m, err := MountImage(path, false)
+	if err != nil {
+		return err
+	}
+	err = populate(m.Dir)
+	if cerr := m.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// run 运行外部命令并返回合并的输出；失败时错误中包含命令行和输出
+func run(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("%s %s: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
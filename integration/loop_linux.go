@@ -0,0 +1,84 @@
+//go:build linux
+
+package integration
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// available 检查 root 权限、所需的工具和内核的 exfat 驱动
+func available() error {
+	if uid := os.Geteuid(); uid != 0 {
+		return skipf("attaching loop devices needs root (running as uid %d)", uid)
+	}
+	for _, tool := range []string{"losetup", "mount", "umount", "blkid"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return skipf("%s not found; install util-linux", tool)
+		}
+	}
+	for _, tool := range []string{"mkfs.exfat", "fsck.exfat"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return skipf("%s not found; install exfatprogs", tool)
+		}
+	}
+	if !kernelHasExfat() {
+		// 驱动可能编译为模块，尚未加载
+		exec.Command("modprobe", "exfat").Run()
+		if !kernelHasExfat() {
+			return skipf("the kernel has no exfat driver (modprobe exfat failed)")
+		}
+	}
+	return nil
+}
+
+// kernelHasExfat 判断 /proc/filesystems 中是否有 exfat
+func kernelHasExfat() bool {
+	f, err := os.Open("/proc/filesystems")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && fields[len(fields)-1] == "exfat" {
+			return true
+		}
+	}
+	return false
+}
+
+// attach 把映像关联到空闲的 loop 设备，返回设备路径
+func attach(image string) (string, error) {
+	out, err := run("losetup", "--find", "--show", image)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// detach 释放 loop 设备
+func detach(device string) error {
+	_, err := run("losetup", "--detach", device)
+	return err
+}
+
+// mount 用内核的 exfat 驱动挂载设备
+func mount(device, dir string, readOnly bool) error {
+	args := []string{"-t", "exfat"}
+	if readOnly {
+		args = append(args, "-o", "ro")
+	}
+	_, err := run("mount", append(args, device, dir)...)
+	return err
+}
+
+// unmount 卸载挂载点
+func unmount(dir string) error {
+	_, err := run("umount", dir)
+	return err
+}
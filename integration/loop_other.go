@@ -0,0 +1,28 @@
+//go:build !linux
+
+package integration
+
+import "errors"
+
+// errUnsupported loop 设备和内核 exfat 驱动只在 Linux 上可用
+var errUnsupported = errors.New("mounting images needs Linux loop devices")
+
+func available() error {
+	return skipf("the OS driver harness only runs on Linux")
+}
+
+func attach(image string) (string, error) {
+	return "", errUnsupported
+}
+
+func detach(device string) error {
+	return errUnsupported
+}
+
+func mount(device, dir string, readOnly bool) error {
+	return errUnsupported
+}
+
+func unmount(dir string) error {
+	return errUnsupported
+}
@@ -0,0 +1,145 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ISO BMFF（MP4、MOV、HEIF）的盒子
+
+// maxBoxes 一层中最多解析的盒子数，防止损坏的文件（如大小为 8 的空盒子连成一片）使解析过慢
+const maxBoxes = 4096
+
+// maxMetaSize 解析 HEIF 时读入内存的 meta 盒子的最大字节数；meta 只有条目的描述，通常只有几 KiB
+const maxMetaSize = 1 << 20
+
+// isTopLevelBox 判断 t 是否为文件开头常见的盒子类型
+// 较旧的 QuickTime 文件没有 ftyp，以 moov、mdat 或填充盒子开头。
+func isTopLevelBox(t string) bool {
+	switch t {
+	case "ftyp", "moov", "mdat", "wide", "free", "skip":
+		return true
+	}
+	return false
+}
+
+// heifBrands 表示 HEIF 图片（而不是视频）的 ftyp 主品牌
+var heifBrands = map[string]bool{
+	"heic": true, "heix": true, "heim": true, "heis": true,
+	"mif1": true, "mif2": true,
+}
+
+// box 一个盒子在文件中的位置
+type box struct {
+	typ   string
+	start int64 // 盒子头的偏移
+	body  int64 // 内容的偏移
+	end   int64 // 盒子之后的偏移
+}
+
+// boxes 从文件中读取 [start, end) 范围内同一层的盒子头，不读取内容
+func boxes(r io.ReaderAt, start, end int64) ([]box, error) {
+	var list []box
+	for off := start; off+8 <= end && len(list) < maxBoxes; {
+		hdr, err := readAt(r, off, 8)
+		if err != nil {
+			return list, err
+		}
+		size, typ := int64(binary.BigEndian.Uint32(hdr)), string(hdr[4:8])
+		body := off + 8
+		switch size {
+		case 0:
+			// 延伸到范围末尾
+			size = end - off
+		case 1:
+			// 64 位大小紧跟在类型之后
+			ext, err := readAt(r, off+8, 8)
+			if err != nil {
+				return list, err
+			}
+			size, body = int64(binary.BigEndian.Uint64(ext)), off+16
+		}
+		if size < body-off || off+size > end {
+			return list, fmt.Errorf("%w: box %q at %d has bad size %d", ErrNotFound, typ, off, size)
+		}
+		list = append(list, box{typ: typ, start: off, body: body, end: off + size})
+		off += size
+	}
+	return list, nil
+}
+
+// parseBoxes 与 boxes 相同，从内存中的数据读取；偏移相对于 data
+func parseBoxes(data []byte) ([]box, error) {
+	return boxes(bytes.NewReader(data), 0, int64(len(data)))
+}
+
+// findBox 返回 list 中第一个类型为 typ 的盒子
+func findBox(list []box, typ string) (box, bool) {
+	for _, b := range list {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// child 沿路径逐层查找盒子，meta 盒子的内容前面有 4 字节的版本和标志（QuickTime 中可能没有）
+func child(r io.ReaderAt, parent box, path ...string) (box, bool, error) {
+	cur := parent
+	for _, typ := range path {
+		body := cur.body
+		if cur.typ == "meta" {
+			// ISO 的 meta 是 FullBox；QuickTime 的 meta 直接是子盒子，第一个子盒子的大小不会是 0
+			flags, err := readAt(r, body, 4)
+			if err != nil {
+				return box{}, false, err
+			}
+			if binary.BigEndian.Uint32(flags) == 0 {
+				body += 4
+			}
+		}
+		list, err := boxes(r, body, cur.end)
+		if err != nil {
+			return box{}, false, err
+		}
+		next, ok := findBox(list, typ)
+		if !ok {
+			return box{}, false, nil
+		}
+		cur = next
+	}
+	return cur, true, nil
+}
+
+// mp4Cover 返回 MP4/MOV 文件中 iTunes 风格元数据里的封面（moov/udta/meta/ilst/covr 或 moov/meta/ilst/covr）
+// 只读取盒子头和封面数据，不读取 mdat 中的媒体数据。
+func mp4Cover(r io.ReaderAt, size int64) ([]byte, string, error) {
+	top, err := boxes(r, 0, size)
+	if err != nil && len(top) == 0 {
+		return nil, "", err
+	}
+	moov, ok := findBox(top, "moov")
+	if !ok {
+		return nil, "", fmt.Errorf("%w: no moov box", ErrNotFound)
+	}
+	for _, path := range [][]string{{"udta", "meta", "ilst", "covr", "data"}, {"meta", "ilst", "covr", "data"}} {
+		data, ok, err := child(r, moov, path...)
+		if err != nil {
+			return nil, "", err
+		}
+		if !ok || data.end-data.body <= 8 {
+			continue
+		}
+		// data 盒子：4 字节类型指示（13 为 JPEG，14 为 PNG）、4 字节区域设置，之后是图片
+		img, err := readAt(r, data.body+8, data.end-data.body-8)
+		if err != nil {
+			return nil, "", err
+		}
+		if mime := sniffMIME(img); mime != "" {
+			return img, mime, nil
+		}
+	}
+	return nil, "", fmt.Errorf("%w: no cover art", ErrNotFound)
+}
@@ -0,0 +1,152 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// hdlrBox 构造处理程序类型为 typ 的 hdlr 盒子
+func hdlrBox(typ string) []byte {
+	return mkFullBox("hdlr", 0, append(append(make([]byte, 4), typ...), make([]byte, 13)...))
+}
+
+// ilst 构造只含封面的 ilst 盒子；kind 是 data 盒子的类型指示
+func ilst(kind uint32, img []byte) []byte {
+	data := binary.BigEndian.AppendUint32(nil, kind)
+	data = append(binary.BigEndian.AppendUint32(data, 0), img...)
+	return mkBox("ilst", mkBox("covr", mkBox("data", data)))
+}
+
+// isoMeta 构造 moov/udta/meta（FullBox）
+func isoMeta(ilst []byte) []byte {
+	return mkBox("udta", mkFullBox("meta", 0, append(hdlrBox("mdir"), ilst...)))
+}
+
+// quickTimeMeta 构造 moov/meta（QuickTime 中没有版本和标志）
+func quickTimeMeta(ilst []byte) []byte {
+	return mkBox("meta", append(hdlrBox("mdir"), ilst...))
+}
+
+var mp4Ftyp = mkBox("ftyp", []byte("isom\x00\x00\x02\x00isommp41"))
+
+// mp4File 构造 ftyp、moov 和 1 MiB 的 mdat
+func mp4File(moov ...[]byte) []byte {
+	return bytes.Join([][]byte{
+		mp4Ftyp,
+		mkBox("moov", bytes.Join(moov, nil)),
+		mkBox("mdat", make([]byte, 1<<20)),
+	}, nil)
+}
+
+func TestParseBoxes(t *testing.T) {
+	large := append(binary.BigEndian.AppendUint32(nil, 1), "wide"...)
+	large = append(binary.BigEndian.AppendUint64(large, 20), "abcd"...)
+	data := bytes.Join([][]byte{
+		mkBox("ftyp", []byte("isom")),
+		large,
+		mkBox("free", nil),
+		{0, 0, 0, 0, 'm', 'd', 'a', 't', 1, 2, 3},
+	}, nil)
+	list, err := parseBoxes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []box{
+		{"ftyp", 0, 8, 12},
+		{"wide", 12, 28, 32}, // 64 位大小
+		{"free", 32, 40, 40},
+		{"mdat", 40, 48, 51}, // 大小为 0 时延伸到末尾
+	}
+	if !slices.Equal(list, want) {
+		t.Errorf("got %+v, want %+v", list, want)
+	}
+	if b, ok := findBox(list, "free"); !ok || b.start != 32 {
+		t.Errorf("findBox(free) = %+v, %v", b, ok)
+	}
+	if _, ok := findBox(list, "moov"); ok {
+		t.Error("found a moov box")
+	}
+
+	// 范围末尾不足一个盒子头的字节被忽略
+	if list, err := parseBoxes(append(mkBox("free", nil), 1, 2, 3)); err != nil || len(list) != 1 {
+		t.Errorf("trailing bytes: %+v, %v", list, err)
+	}
+	for _, bad := range [][]byte{
+		{0, 0, 0, 4, 'f', 'r', 'e', 'e'},          // 小于盒子头
+		{0, 0, 0, 9, 'f', 'r', 'e', 'e'},          // 超出范围
+		append(mkBox("free", nil), large[:12]...), // 64 位大小被截断
+	} {
+		if list, err := parseBoxes(bad); err == nil {
+			t.Errorf("%x: accepted, got %+v", bad, list)
+		}
+	}
+
+	// 一层中最多解析 maxBoxes 个盒子
+	many := bytes.Repeat(mkBox("free", nil), maxBoxes+10)
+	if list, err := parseBoxes(many); err != nil || len(list) != maxBoxes {
+		t.Errorf("parsed %d boxes, %v; want %d", len(list), err, maxBoxes)
+	}
+}
+
+func TestChild(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		moov []byte
+		path []string
+	}{
+		{"ISO meta", isoMeta(ilst(13, testJPEG)), []string{"udta", "meta", "ilst", "covr", "data"}},
+		{"QuickTime meta", quickTimeMeta(ilst(13, testJPEG)), []string{"meta", "ilst", "covr", "data"}},
+	} {
+		data := mp4File(tt.moov)
+		r := bytes.NewReader(data)
+		top, _ := boxes(r, 0, int64(len(data)))
+		moov, _ := findBox(top, "moov")
+		b, ok, err := child(r, moov, tt.path...)
+		if err != nil || !ok || b.typ != "data" || !bytes.HasSuffix(data[b.body:b.end], testJPEG) {
+			t.Errorf("%s: got %+v, %v, %v", tt.name, b, ok, err)
+		}
+		if _, ok, err := child(r, moov, "trak"); ok || err != nil {
+			t.Errorf("%s: found a missing box: %v, %v", tt.name, ok, err)
+		}
+	}
+}
+
+func TestMP4Cover(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+		mime string
+	}{
+		{"udta/meta JPEG", mp4File(isoMeta(ilst(13, testJPEG))), testJPEG, MIMEJPEG},
+		{"moov/meta PNG", mp4File(quickTimeMeta(ilst(14, testPNG))), testPNG, MIMEPNG},
+		// 类型指示与数据不符时按数据判断
+		{"mislabelled", mp4File(isoMeta(ilst(14, testJPEG))), testJPEG, MIMEJPEG},
+		// udta 中的封面无法识别时再找 moov/meta
+		{"fallback", mp4File(isoMeta(ilst(13, []byte("GIF89a"))), quickTimeMeta(ilst(14, testPNG))), testPNG, MIMEPNG},
+		// 没有 ftyp 的旧 QuickTime 文件
+		{"QuickTime", append(mkBox("wide", nil), mp4File(isoMeta(ilst(13, testJPEG)))[len(mp4Ftyp):]...), testJPEG, MIMEJPEG},
+		{"no cover", mp4File(isoMeta(mkBox("ilst", nil))), nil, ""},
+		{"empty data", mp4File(isoMeta(ilst(13, nil))), nil, ""},
+		{"unknown image", mp4File(isoMeta(ilst(12, []byte("GIF89a")))), nil, ""},
+		{"no moov", append(mkBox("ftyp", []byte("isom")), mkBox("mdat", []byte{1})...), nil, ""},
+	}
+	for _, tt := range tests {
+		r := &countingReader{r: bytes.NewReader(tt.data)}
+		data, mime, err := Find(r, int64(len(tt.data)))
+		if tt.want == nil {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: got %q, %v; want ErrNotFound", tt.name, data, err)
+			}
+		} else if err != nil || mime != tt.mime || !bytes.Equal(data, tt.want) {
+			t.Errorf("%s: got %q, %s, %v", tt.name, data, mime, err)
+		}
+		// 只读取盒子头和封面，不读取 mdat
+		if r.n > 4096 {
+			t.Errorf("%s: read %d bytes", tt.name, r.n)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// jpegThumbnail 在 JPEG 文件开头的 APP1 EXIF 段中查找 IFD1 记录的缩略图
+// 只读取文件开头 JPEGHeadSize 字节，遇到图像数据（SOS）即停止。
+func jpegThumbnail(r io.ReaderAt, size int64) ([]byte, string, error) {
+	n := min(size, JPEGHeadSize)
+	head := make([]byte, n)
+	// 读取上限可能截短开头，EXIF 段通常仍在读到的部分之内
+	m, err := r.ReadAt(head, 0)
+	if m == 0 && err != nil {
+		return nil, "", err
+	}
+	head = head[:m]
+
+	for i := 2; i+4 <= len(head); {
+		if head[i] != 0xFF {
+			return nil, "", fmt.Errorf("%w: malformed JPEG segment at offset %d", ErrNotFound, i)
+		}
+		marker := head[i+1]
+		if marker == 0xFF {
+			// 段之间的填充字节
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(head[i+2:]))
+		if length < 2 || i+2+length > len(head) {
+			break
+		}
+		segment := head[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			thumb, err := exifThumbnail(segment[6:])
+			if err != nil {
+				return nil, "", err
+			}
+			return thumb, MIMEJPEG, nil
+		}
+		i += 2 + length
+	}
+	return nil, "", fmt.Errorf("%w: no EXIF thumbnail in the first %d bytes", ErrNotFound, len(head))
+}
+
+// EXIF 中与缩略图有关的标签
+const (
+	tagCompression     = 0x0103
+	tagThumbnailOffset = 0x0201 // JPEGInterchangeFormat
+	tagThumbnailLength = 0x0202 // JPEGInterchangeFormatLength
+)
+
+// exifThumbnail 从 TIFF 结构的 EXIF 数据中取出 IFD1 记录的 JPEG 缩略图
+// 偏移都相对于 TIFF 头，缩略图必须完整地位于 tiff 之内。
+func exifThumbnail(tiff []byte) ([]byte, error) {
+	if len(tiff) < 8 {
+		return nil, fmt.Errorf("%w: EXIF data too short", ErrNotFound)
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("%w: bad TIFF byte order", ErrNotFound)
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return nil, fmt.Errorf("%w: bad TIFF magic", ErrNotFound)
+	}
+
+	// IFD0 之后的链接指向 IFD1（缩略图的 IFD）
+	ifd0 := int(order.Uint32(tiff[4:]))
+	next, err := ifdNext(tiff, order, ifd0)
+	if err != nil {
+		return nil, err
+	}
+	if next == 0 {
+		return nil, fmt.Errorf("%w: EXIF has no thumbnail IFD", ErrNotFound)
+	}
+	tags, err := ifdTags(tiff, order, next)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := tags[tagCompression]; ok && c != 6 {
+		// 6 表示 JPEG 压缩；未压缩的 TIFF 缩略图不提取
+		return nil, fmt.Errorf("%w: EXIF thumbnail is not JPEG (compression %d)", ErrNotFound, c)
+	}
+	off, length := int64(tags[tagThumbnailOffset]), int64(tags[tagThumbnailLength])
+	if off == 0 || length == 0 || off+length > int64(len(tiff)) {
+		return nil, fmt.Errorf("%w: EXIF thumbnail missing or out of range", ErrNotFound)
+	}
+	thumb := tiff[off : off+length]
+	if sniffMIME(thumb) != MIMEJPEG {
+		return nil, fmt.Errorf("%w: EXIF thumbnail is not a JPEG image", ErrNotFound)
+	}
+	return bytes.Clone(thumb), nil
+}
+
+// ifdNext 返回 off 处的 IFD 之后下一个 IFD 的偏移
+func ifdNext(tiff []byte, order binary.ByteOrder, off int) (int, error) {
+	if off < 8 || off+2 > len(tiff) {
+		return 0, fmt.Errorf("%w: IFD offset %d out of range", ErrNotFound, off)
+	}
+	end := off + 2 + int(order.Uint16(tiff[off:]))*12
+	if end+4 > len(tiff) {
+		return 0, fmt.Errorf("%w: IFD at %d truncated", ErrNotFound, off)
+	}
+	return int(order.Uint32(tiff[end:])), nil
+}
+
+// ifdTags 返回 off 处的 IFD 中值为单个 SHORT 或 LONG 的标签
+func ifdTags(tiff []byte, order binary.ByteOrder, off int) (map[uint16]uint32, error) {
+	if _, err := ifdNext(tiff, order, off); err != nil {
+		return nil, err
+	}
+	tags := make(map[uint16]uint32)
+	count := int(order.Uint16(tiff[off:]))
+	for i := 0; i < count; i++ {
+		entry := tiff[off+2+i*12:]
+		tag, typ, n := order.Uint16(entry), order.Uint16(entry[2:]), order.Uint32(entry[4:])
+		if n != 1 {
+			continue
+		}
+		switch typ {
+		case 3: // SHORT
+			tags[tag] = uint32(order.Uint16(entry[8:]))
+		case 4: // LONG
+			tags[tag] = order.Uint32(entry[8:])
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,165 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// byteOrder 既能读取也能追加的字节序
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+var (
+	le byteOrder = binary.LittleEndian
+	be byteOrder = binary.BigEndian
+)
+
+// IFD1 中缩略图长度的值在 exifTIFF 结果中的偏移
+const thumbLengthValue = 14 + 2 + 2*12 + 8
+
+// exifTIFF 构造 EXIF 的 TIFF 结构：空的 IFD0，IFD1 记录压缩方式和缩略图的位置，缩略图紧跟在 IFD1 之后
+func exifTIFF(order byteOrder, compression uint16, thumb []byte) []byte {
+	b := []byte("II")
+	if order == be {
+		b = []byte("MM")
+	}
+	b = order.AppendUint16(b, 42)
+	b = order.AppendUint32(b, 8)
+	b = order.AppendUint16(b, 0)  // IFD0 没有标签
+	b = order.AppendUint32(b, 14) // 下一个 IFD
+	entry := func(tag, typ uint16, v uint32) {
+		b = order.AppendUint16(b, tag)
+		b = order.AppendUint16(b, typ)
+		b = order.AppendUint32(b, 1)
+		if typ == 3 {
+			b = append(order.AppendUint16(b, uint16(v)), 0, 0)
+		} else {
+			b = order.AppendUint32(b, v)
+		}
+	}
+	b = order.AppendUint16(b, 3)
+	entry(tagCompression, 3, uint32(compression))
+	entry(tagThumbnailOffset, 4, 14+2+3*12+4)
+	entry(tagThumbnailLength, 4, uint32(len(thumb)))
+	b = order.AppendUint32(b, 0)
+	return append(b, thumb...)
+}
+
+// segment 构造 JPEG 的标记段
+func segment(marker byte, body []byte) []byte {
+	return append(binary.BigEndian.AppendUint16([]byte{0xFF, marker}, uint16(2+len(body))), body...)
+}
+
+// jpegWithExif 构造 JPEG 文件：SOI、JFIF、before 中的段、APP1 EXIF、图像数据
+func jpegWithExif(tiff []byte, before ...[]byte) []byte {
+	b := append([]byte{0xFF, 0xD8}, segment(0xE0, []byte("JFIF\x00\x01\x01"))...)
+	for _, s := range before {
+		b = append(b, s...)
+	}
+	b = append(b, segment(0xE1, append([]byte("Exif\x00\x00"), tiff...))...)
+	b = append(b, segment(0xDA, []byte{1, 2, 3})...)
+	return append(b, bytes.Repeat([]byte{0x55}, 4096)...)
+}
+
+func TestJPEGThumbnail(t *testing.T) {
+	big := bytes.Repeat([]byte{0}, 0xFFF0)
+	tests := []struct {
+		name  string
+		data  []byte
+		found bool
+	}{
+		{"little endian", jpegWithExif(exifTIFF(le, 6, testJPEG)), true},
+		{"big endian", jpegWithExif(exifTIFF(be, 6, testJPEG)), true},
+		{"fill bytes between segments", jpegWithExif(exifTIFF(le, 6, testJPEG), []byte{0xFF, 0xFF}), true},
+		{"after other APP segments", jpegWithExif(exifTIFF(le, 6, testJPEG), segment(0xE2, big)), true},
+		{"uncompressed thumbnail", jpegWithExif(exifTIFF(le, 1, testJPEG)), false},
+		{"thumbnail is not a JPEG", jpegWithExif(exifTIFF(le, 6, testPNG)), false},
+		{"EXIF beyond the head", jpegWithExif(exifTIFF(le, 6, testJPEG), segment(0xE2, big), segment(0xE3, big)), false},
+		{"no EXIF", append([]byte{0xFF, 0xD8}, segment(0xDA, nil)...), false},
+		{"malformed segment", []byte{0xFF, 0xD8, 0x00, 0xE1, 0, 8, 'E', 'x', 'i', 'f'}, false},
+	}
+	for _, tt := range tests {
+		r := &countingReader{r: bytes.NewReader(tt.data)}
+		data, mime, err := jpegThumbnail(r, int64(len(tt.data)))
+		switch {
+		case tt.found && (err != nil || mime != MIMEJPEG || !bytes.Equal(data, testJPEG)):
+			t.Errorf("%s: got %q, %s, %v", tt.name, data, mime, err)
+		case !tt.found && !errors.Is(err, ErrNotFound):
+			t.Errorf("%s: got %q, %v; want ErrNotFound", tt.name, data, err)
+		}
+		if r.n > JPEGHeadSize {
+			t.Errorf("%s: read %d bytes, more than the %d byte head", tt.name, r.n, JPEGHeadSize)
+		}
+	}
+}
+
+func TestEXIFThumbnailErrors(t *testing.T) {
+	valid := exifTIFF(le, 6, testJPEG)
+	mutate := func(f func(b []byte) []byte) []byte { return f(bytes.Clone(valid)) }
+	tests := []struct {
+		name string
+		tiff []byte
+	}{
+		{"too short", valid[:7]},
+		{"bad byte order", mutate(func(b []byte) []byte { b[0] = 'X'; return b })},
+		{"bad magic", mutate(func(b []byte) []byte { b[2] = 43; return b })},
+		{"IFD0 out of range", mutate(func(b []byte) []byte { le.PutUint32(b[4:], 4096); return b })},
+		{"IFD0 before the header end", mutate(func(b []byte) []byte { le.PutUint32(b[4:], 4); return b })},
+		{"no IFD1", mutate(func(b []byte) []byte { le.PutUint32(b[10:], 0); return b })},
+		{"IFD1 truncated", valid[:14+2+12]},
+		{"thumbnail out of range", mutate(func(b []byte) []byte { le.PutUint32(b[thumbLengthValue:], uint32(len(testJPEG)+1)); return b })},
+		{"zero length", mutate(func(b []byte) []byte { le.PutUint32(b[thumbLengthValue:], 0); return b })},
+	}
+	for _, tt := range tests {
+		if data, err := exifThumbnail(tt.tiff); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: got %q, %v; want ErrNotFound", tt.name, data, err)
+		}
+	}
+
+	// 返回的缩略图不与输入共用内存
+	data, err := exifThumbnail(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[4] = 'X'
+	if bytes.Contains(valid, data) {
+		t.Error("thumbnail aliases the EXIF data")
+	}
+}
+
+func TestIFDTags(t *testing.T) {
+	tiff := exifTIFF(be, 6, testJPEG)
+	tags, err := ifdTags(tiff, binary.BigEndian, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint16]uint32{tagCompression: 6, tagThumbnailOffset: 56, tagThumbnailLength: uint32(len(testJPEG))}
+	if len(tags) != len(want) {
+		t.Fatalf("tags %v, want %v", tags, want)
+	}
+	for tag, v := range want {
+		if tags[tag] != v {
+			t.Errorf("tag 0x%04X = %d, want %d", tag, tags[tag], v)
+		}
+	}
+
+	// 多个值和其他类型的标签被忽略
+	be.PutUint32(tiff[16+4:], 2)
+	be.PutUint16(tiff[16+12+2:], 2) // ASCII
+	if tags, err := ifdTags(tiff, binary.BigEndian, 14); err != nil || len(tags) != 1 {
+		t.Errorf("got %v, %v; want only the thumbnail length", tags, err)
+	}
+
+	if next, err := ifdNext(tiff, binary.BigEndian, 8); err != nil || next != 14 {
+		t.Errorf("ifdNext(8) = %d, %v", next, err)
+	}
+	for _, off := range []int{0, 7, len(tiff) - 1, 14 + 1} {
+		if _, err := ifdNext(tiff[:14+2+3*12+3], binary.BigEndian, off); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ifdNext(%d): got %v, want ErrNotFound", off, err)
+		}
+	}
+}
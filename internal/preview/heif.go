@@ -0,0 +1,407 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// heifItem HEIF 中的一个条目
+type heifItem struct {
+	id      uint32
+	typ     string     // 条目类型，如 "hvc1"、"jpeg"、"Exif"
+	extents [][2]int64 // 数据的范围（偏移、长度）
+	idat    bool       // 偏移相对于 meta 中的 idat 盒子，而不是文件
+	props   []heifProp // 关联的属性
+}
+
+// heifProp 条目关联的一个属性盒子
+type heifProp struct {
+	data      []byte // 整个属性盒子
+	essential bool
+}
+
+// heifMeta 解析出的 meta 盒子
+type heifMeta struct {
+	primary uint32
+	items   map[uint32]*heifItem
+	thumbs  [][2]uint32 // iref 中的 thmb 引用（缩略图条目、被引用的条目），按出现的顺序
+	idat    []byte
+}
+
+// heifThumbnail 返回 HEIF 图片的缩略图
+// 依次尝试 JPEG 编码的缩略图条目、Exif 条目中的 EXIF 缩略图和 HEVC 编码的缩略图条目；
+// HEVC 的缩略图包装为只含这一张图的最小 HEIC 文件返回。
+func heifThumbnail(r io.ReaderAt, size int64) ([]byte, string, error) {
+	top, err := boxes(r, 0, size)
+	if err != nil && len(top) == 0 {
+		return nil, "", err
+	}
+	metaBox, ok := findBox(top, "meta")
+	if !ok {
+		return nil, "", fmt.Errorf("%w: no meta box", ErrNotFound)
+	}
+	if metaBox.end-metaBox.body > maxMetaSize {
+		return nil, "", fmt.Errorf("%w: meta box of %d bytes is too large", ErrNotFound, metaBox.end-metaBox.body)
+	}
+	data, err := readAt(r, metaBox.body, metaBox.end-metaBox.body)
+	if err != nil {
+		return nil, "", err
+	}
+	meta, err := parseHEIFMeta(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// pitm 可能出现在 iref 之后，所以解析完再按主图筛选缩略图
+	var thumbs []*heifItem
+	for _, ref := range meta.thumbs {
+		if item := meta.items[ref[0]]; item != nil && ref[1] == meta.primary {
+			thumbs = append(thumbs, item)
+		}
+	}
+	for _, item := range thumbs {
+		if item.typ == "jpeg" {
+			img, err := meta.read(r, item)
+			if err != nil {
+				return nil, "", err
+			}
+			if sniffMIME(img) == MIMEJPEG {
+				return img, MIMEJPEG, nil
+			}
+		}
+	}
+	for _, item := range meta.items {
+		if item.typ != "Exif" {
+			continue
+		}
+		exif, err := meta.read(r, item)
+		if err != nil {
+			return nil, "", err
+		}
+		// Exif 条目以 4 字节的 TIFF 头偏移开始
+		if len(exif) >= 4 {
+			if off := 4 + int(binary.BigEndian.Uint32(exif)); off < len(exif) {
+				if thumb, err := exifThumbnail(exif[off:]); err == nil {
+					return thumb, MIMEJPEG, nil
+				}
+			}
+		}
+	}
+	for _, item := range thumbs {
+		if item.typ == "hvc1" {
+			img, err := meta.read(r, item)
+			if err != nil {
+				return nil, "", err
+			}
+			return wrapHEIC(item, img), MIMEHEIC, nil
+		}
+	}
+	return nil, "", fmt.Errorf("%w: no thumbnail item", ErrNotFound)
+}
+
+// read 读取条目的数据
+func (m *heifMeta) read(r io.ReaderAt, item *heifItem) ([]byte, error) {
+	var out []byte
+	for _, e := range item.extents {
+		if item.idat {
+			if e[0] < 0 || e[0]+e[1] > int64(len(m.idat)) {
+				return nil, fmt.Errorf("%w: item %d extent outside idat", ErrNotFound, item.id)
+			}
+			out = append(out, m.idat[e[0]:e[0]+e[1]]...)
+			continue
+		}
+		data, err := readAt(r, e[0], e[1])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, data...)
+	}
+	return out, nil
+}
+
+// parseHEIFMeta 解析 meta 盒子的内容（FullBox 的版本和标志之后）
+func parseHEIFMeta(data []byte) (*heifMeta, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: meta box too short", ErrNotFound)
+	}
+	data = data[4:]
+	list, err := parseBoxes(data)
+	if err != nil {
+		return nil, err
+	}
+	m := &heifMeta{items: make(map[uint32]*heifItem)}
+	body := func(b box) []byte { return data[b.body:b.end] }
+	item := func(id uint32) *heifItem {
+		if m.items[id] == nil {
+			m.items[id] = &heifItem{id: id}
+		}
+		return m.items[id]
+	}
+
+	for _, b := range list {
+		p := &reader{buf: body(b)}
+		switch b.typ {
+		case "pitm":
+			v := p.fullBox()
+			m.primary = p.id(v)
+		case "iinf":
+			v := p.fullBox()
+			if v == 0 {
+				p.u16()
+			} else {
+				p.u32()
+			}
+			infes, err := parseBoxes(p.rest())
+			if err != nil {
+				return nil, err
+			}
+			for _, infe := range infes {
+				q := &reader{buf: p.rest()[infe.body:infe.end]}
+				if v := q.fullBox(); v >= 2 {
+					id := q.id(v - 2)
+					q.u16() // item_protection_index
+					item(id).typ = string(q.bytes(4))
+				}
+				if q.err != nil {
+					return nil, fmt.Errorf("%w: %s box truncated", ErrNotFound, infe.typ)
+				}
+			}
+		case "iref":
+			v := p.fullBox()
+			refs, err := parseBoxes(p.rest())
+			if err != nil {
+				return nil, err
+			}
+			for _, ref := range refs {
+				q := &reader{buf: p.rest()[ref.body:ref.end]}
+				from := q.id(v)
+				count := int(q.u16())
+				for i := 0; i < count && q.err == nil; i++ {
+					if to := q.id(v); ref.typ == "thmb" {
+						m.thumbs = append(m.thumbs, [2]uint32{from, to})
+					}
+				}
+				if q.err != nil {
+					return nil, fmt.Errorf("%w: %s box truncated", ErrNotFound, ref.typ)
+				}
+			}
+		case "iloc":
+			if err := m.parseIloc(p, item); err != nil {
+				return nil, err
+			}
+		case "iprp":
+			if err := m.parseIprp(body(b), item); err != nil {
+				return nil, err
+			}
+		case "idat":
+			m.idat = body(b)
+		}
+		if p.err != nil {
+			return nil, fmt.Errorf("%w: %s box truncated", ErrNotFound, b.typ)
+		}
+	}
+	return m, nil
+}
+
+// parseIloc 解析 iloc 盒子中每个条目的数据范围
+func (m *heifMeta) parseIloc(p *reader, item func(uint32) *heifItem) error {
+	v := p.fullBox()
+	sizes := p.u8()
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0xF)
+	sizes = p.u8()
+	baseSize, indexSize := int(sizes>>4), 0
+	if v == 1 || v == 2 {
+		indexSize = int(sizes & 0xF)
+	}
+	var count uint32
+	if v < 2 {
+		count = uint32(p.u16())
+	} else {
+		count = p.u32()
+	}
+	for i := uint32(0); i < count && p.err == nil; i++ {
+		var id uint32
+		if v < 2 {
+			id = uint32(p.u16())
+		} else {
+			id = p.u32()
+		}
+		method := 0
+		if v == 1 || v == 2 {
+			method = int(p.u16() & 0xF)
+		}
+		p.u16() // data_reference_index
+		base := int64(p.uint(baseSize))
+		extents := int(p.u16())
+		it := item(id)
+		it.idat = method == 1
+		for j := 0; j < extents && p.err == nil; j++ {
+			p.uint(indexSize)
+			off, length := int64(p.uint(offsetSize)), int64(p.uint(lengthSize))
+			if method <= 1 {
+				it.extents = append(it.extents, [2]int64{base + off, length})
+			}
+		}
+		if method > 1 {
+			// 由其他条目构造的数据不支持
+			it.extents = nil
+		}
+	}
+	return nil
+}
+
+// parseIprp 解析 iprp 盒子，把 ipco 中的属性按 ipma 关联到条目
+func (m *heifMeta) parseIprp(data []byte, item func(uint32) *heifItem) error {
+	list, err := parseBoxes(data)
+	if err != nil {
+		return err
+	}
+	ipco, ok := findBox(list, "ipco")
+	if !ok {
+		return nil
+	}
+	props, err := parseBoxes(data[ipco.body:ipco.end])
+	if err != nil {
+		return err
+	}
+	propData := make([][]byte, len(props))
+	for i, b := range props {
+		propData[i] = data[ipco.body+b.start : ipco.body+b.end]
+	}
+	for _, b := range list {
+		if b.typ != "ipma" {
+			continue
+		}
+		p := &reader{buf: data[b.body:b.end]}
+		v := p.fullBox()
+		wide := p.flags&1 != 0
+		count := p.u32()
+		for i := uint32(0); i < count && p.err == nil; i++ {
+			it := item(p.id(v))
+			n := int(p.u8())
+			for j := 0; j < n && p.err == nil; j++ {
+				var index int
+				var essential bool
+				if wide {
+					a := p.u16()
+					index, essential = int(a&0x7FFF), a&0x8000 != 0
+				} else {
+					a := p.u8()
+					index, essential = int(a&0x7F), a&0x80 != 0
+				}
+				// 序号从 1 开始，0 表示没有属性
+				if index >= 1 && index <= len(propData) {
+					it.props = append(it.props, heifProp{data: propData[index-1], essential: essential})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// wrapHEIC 把 HEVC 编码的条目和它的属性（hvcC、ispe 等）包装为只含这一张图的最小 HEIC 文件
+func wrapHEIC(item *heifItem, data []byte) []byte {
+	var props bytes.Buffer
+	var assoc []byte
+	for i, p := range item.props {
+		if i >= 127 {
+			break
+		}
+		props.Write(p.data)
+		a := byte(i + 1)
+		if p.essential {
+			a |= 0x80
+		}
+		assoc = append(assoc, a)
+	}
+
+	ftyp := mkBox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	hdlr := mkFullBox("hdlr", 0, append(make([]byte, 4), append([]byte("pict"), make([]byte, 13)...)...))
+	pitm := mkFullBox("pitm", 0, []byte{0, 1})
+	infe := mkFullBox("infe", 2, []byte{0, 1, 0, 0, 'h', 'v', 'c', '1', 0})
+	iinf := mkFullBox("iinf", 0, append([]byte{0, 1}, infe...))
+	ipma := mkFullBox("ipma", 0, append([]byte{0, 0, 0, 1, 0, 1, byte(len(assoc))}, assoc...))
+	iprp := mkBox("iprp", append(mkBox("ipco", props.Bytes()), ipma...))
+
+	// iloc 的偏移指向 mdat 的内容，先以占位值构造 meta 得到它的长度
+	iloc := func(offset uint32) []byte {
+		b := []byte{0x44, 0x00, 0, 1, 0, 1, 0, 0, 0, 1}
+		b = binary.BigEndian.AppendUint32(b, offset)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(data)))
+		return mkFullBox("iloc", 0, b)
+	}
+	meta := func(offset uint32) []byte {
+		return mkFullBox("meta", 0, bytes.Join([][]byte{hdlr, pitm, iinf, iprp, iloc(offset)}, nil))
+	}
+	offset := uint32(len(ftyp) + len(meta(0)) + 8)
+	return bytes.Join([][]byte{ftyp, meta(offset), mkBox("mdat", data)}, nil)
+}
+
+// mkBox 构造盒子
+func mkBox(typ string, body []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(b, typ...), body...)
+}
+
+// mkFullBox 构造带版本（标志为 0）的 FullBox
+func mkFullBox(typ string, version byte, body []byte) []byte {
+	return mkBox(typ, append([]byte{version, 0, 0, 0}, body...))
+}
+
+// reader 按大端序读取盒子的内容，越界时记录错误并返回 0
+type reader struct {
+	buf   []byte
+	off   int
+	flags uint32 // 最近一次 fullBox 读到的标志
+	err   error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || r.off+n > len(r.buf) {
+		r.err = io.ErrUnexpectedEOF
+		return make([]byte, n)
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) u8() uint8   { return r.bytes(1)[0] }
+func (r *reader) u16() uint16 { return binary.BigEndian.Uint16(r.bytes(2)) }
+func (r *reader) u32() uint32 { return binary.BigEndian.Uint32(r.bytes(4)) }
+
+// uint 读取 n 字节（0、4 或 8）的无符号数
+func (r *reader) uint(n int) uint64 {
+	switch n {
+	case 4:
+		return uint64(r.u32())
+	case 8:
+		return binary.BigEndian.Uint64(r.bytes(8))
+	}
+	return 0
+}
+
+// fullBox 读取 FullBox 的版本和标志，返回版本
+func (r *reader) fullBox() uint8 {
+	vf := r.u32()
+	r.flags = vf & 0xFFFFFF
+	return uint8(vf >> 24)
+}
+
+// id 读取条目编号：版本 0 为 16 位，其他为 32 位
+func (r *reader) id(version uint8) uint32 {
+	if version == 0 {
+		return uint32(r.u16())
+	}
+	return r.u32()
+}
+
+// rest 返回尚未读取的数据
+func (r *reader) rest() []byte {
+	if r.off > len(r.buf) {
+		return nil
+	}
+	return r.buf[r.off:]
+}
@@ -0,0 +1,196 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// heicItem 测试文件中的一个条目
+type heicItem struct {
+	id   uint16
+	typ  string
+	data []byte
+}
+
+var heicFtyp = mkBox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+
+func u16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+// iinfBox 构造版本 0 的 iinf，其中每个条目是版本 2 的 infe
+func iinfBox(items []heicItem) []byte {
+	body := u16(uint16(len(items)))
+	for _, it := range items {
+		infe := append(append(u16(it.id), 0, 0), it.typ...)
+		body = append(body, mkFullBox("infe", 2, append(infe, 0))...)
+	}
+	return mkFullBox("iinf", 0, body)
+}
+
+// irefBox 构造版本 0 的 iref，refs 中的每一项是（缩略图条目、被引用的条目）
+func irefBox(refs [][2]uint16) []byte {
+	var body []byte
+	for _, r := range refs {
+		body = append(body, mkBox("thmb", bytes.Join([][]byte{u16(r[0]), u16(1), u16(r[1])}, nil))...)
+	}
+	return mkFullBox("iref", 0, body)
+}
+
+// heicFile 构造 HEIC 文件：ftyp、保存各条目数据的 mdat、meta
+// mdat 在 meta 之前，条目的偏移不依赖 meta 的长度；pitm 放在最后，检验解析不依赖盒子的顺序。
+func heicFile(primary uint16, items []heicItem, thumbs [][2]uint16, extra ...[]byte) []byte {
+	var mdat []byte
+	iloc := append([]byte{0x44, 0x00}, u16(uint16(len(items)))...)
+	off := len(heicFtyp) + 8
+	for _, it := range items {
+		iloc = append(iloc, u16(it.id)...)
+		iloc = append(iloc, 0, 0, 0, 1)
+		iloc = append(iloc, u32(uint32(off+len(mdat)))...)
+		iloc = append(iloc, u32(uint32(len(it.data)))...)
+		mdat = append(mdat, it.data...)
+	}
+	children := [][]byte{hdlrBox("pict"), iinfBox(items), irefBox(thumbs), mkFullBox("iloc", 0, iloc)}
+	children = append(append(children, extra...), mkFullBox("pitm", 0, u16(primary)))
+	return bytes.Join([][]byte{heicFtyp, mkBox("mdat", mdat), mkFullBox("meta", 0, bytes.Join(children, nil))}, nil)
+}
+
+// exifItem 构造 Exif 条目的数据：4 字节的 TIFF 头偏移、填充和 TIFF 结构
+func exifItem(tiff []byte) []byte {
+	return append(append(u32(2), "\x00\x00"...), tiff...)
+}
+
+func TestHEIFThumbnail(t *testing.T) {
+	main := heicItem{1, "hvc1", []byte("main image")}
+	tests := []struct {
+		name   string
+		data   []byte
+		want   []byte
+		wantOK bool
+	}{
+		{"jpeg item", heicFile(1, []heicItem{main, {2, "jpeg", testJPEG}}, [][2]uint16{{2, 1}}), testJPEG, true},
+		// JPEG 缩略图优先于 HEVC 缩略图，与引用的顺序无关
+		{"jpeg before hvc1", heicFile(1, []heicItem{main, {2, "hvc1", []byte("hevc")}, {3, "jpeg", testJPEG}}, [][2]uint16{{2, 1}, {3, 1}}), testJPEG, true},
+		{"exif item", heicFile(1, []heicItem{main, {5, "Exif", exifItem(exifTIFF(be, 6, testJPEG))}}, nil), testJPEG, true},
+		// 其他条目的缩略图被忽略
+		{"thumbnail of another item", heicFile(1, []heicItem{main, {2, "hvc1", nil}, {3, "jpeg", testJPEG}}, [][2]uint16{{3, 2}}), nil, false},
+		{"jpeg item with other data", heicFile(1, []heicItem{main, {2, "jpeg", testPNG}}, [][2]uint16{{2, 1}}), nil, false},
+		{"exif without a thumbnail", heicFile(1, []heicItem{main, {5, "Exif", exifItem(exifTIFF(be, 1, testJPEG))}}, nil), nil, false},
+		{"no thumbnail", heicFile(1, []heicItem{main}, nil), nil, false},
+		{"no meta", append(bytes.Clone(heicFtyp), mkBox("mdat", nil)...), nil, false},
+	}
+	for _, tt := range tests {
+		data, mime, err := Find(bytes.NewReader(tt.data), int64(len(tt.data)))
+		if !tt.wantOK {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: got %q, %v; want ErrNotFound", tt.name, data, err)
+			}
+		} else if err != nil || mime != MIMEJPEG || !bytes.Equal(data, tt.want) {
+			t.Errorf("%s: got %q, %s, %v", tt.name, data, mime, err)
+		}
+	}
+}
+
+func TestHEIFThumbnailIdat(t *testing.T) {
+	// 版本 1 的 iloc，构造方式 1：偏移相对于 idat 的内容
+	idat := append([]byte("pad"), testJPEG...)
+	iloc := []byte{0x44, 0x00, 0, 1}
+	iloc = append(iloc, u16(2)...)
+	iloc = append(iloc, 0, 1, 0, 0, 0, 1)
+	iloc = append(append(iloc, u32(3)...), u32(uint32(len(testJPEG)))...)
+	meta := bytes.Join([][]byte{
+		hdlrBox("pict"),
+		mkFullBox("pitm", 0, u16(1)),
+		iinfBox([]heicItem{{1, "hvc1", nil}, {2, "jpeg", nil}}),
+		irefBox([][2]uint16{{2, 1}}),
+		mkFullBox("iloc", 1, iloc),
+		mkBox("idat", idat),
+	}, nil)
+	data := append(bytes.Clone(heicFtyp), mkFullBox("meta", 0, meta)...)
+	thumb, mime, err := Find(bytes.NewReader(data), int64(len(data)))
+	if err != nil || mime != MIMEJPEG || !bytes.Equal(thumb, testJPEG) {
+		t.Errorf("got %q, %s, %v", thumb, mime, err)
+	}
+
+	// 超出 idat 的范围
+	binary.BigEndian.PutUint32(iloc[len(iloc)-4:], uint32(len(testJPEG)+1))
+	meta = bytes.Join([][]byte{
+		mkFullBox("pitm", 0, u16(1)),
+		iinfBox([]heicItem{{1, "hvc1", nil}, {2, "jpeg", nil}}),
+		irefBox([][2]uint16{{2, 1}}),
+		mkFullBox("iloc", 1, iloc),
+		mkBox("idat", idat),
+	}, nil)
+	data = append(bytes.Clone(heicFtyp), mkFullBox("meta", 0, meta)...)
+	if _, _, err := Find(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("extent outside idat: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestHEIFThumbnailHEVC(t *testing.T) {
+	hvcC := mkBox("hvcC", []byte{1, 2, 3, 4})
+	ispe := mkFullBox("ispe", 0, append(u32(160), u32(120)...))
+	// 缩略图关联 ispe（非必需）和 hvcC（必需）
+	ipma := mkFullBox("ipma", 0, bytes.Join([][]byte{u32(1), u16(2), {2, 0x02, 0x81}}, nil))
+	iprp := mkBox("iprp", append(mkBox("ipco", append(bytes.Clone(hvcC), ispe...)), ipma...))
+	hevc := []byte("hevc thumbnail")
+	data := heicFile(1, []heicItem{{1, "hvc1", []byte("main")}, {2, "hvc1", hevc}}, [][2]uint16{{2, 1}}, iprp)
+
+	out, mime, err := Find(bytes.NewReader(data), int64(len(data)))
+	if err != nil || mime != MIMEHEIC {
+		t.Fatalf("got %s, %v", mime, err)
+	}
+
+	// 包装出的文件只含缩略图这一个条目，属性的顺序和必需标志保持不变
+	top, err := parseBoxes(out)
+	if err != nil || len(top) != 3 || top[0].typ != "ftyp" || top[1].typ != "meta" || top[2].typ != "mdat" {
+		t.Fatalf("wrapped file has boxes %+v, %v", top, err)
+	}
+	m, err := parseHEIFMeta(out[top[1].body:top[1].end])
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := m.items[m.primary]
+	if m.primary != 1 || len(m.items) != 1 || item == nil || item.typ != "hvc1" {
+		t.Fatalf("wrapped meta: primary %d, items %+v", m.primary, m.items)
+	}
+	if img, err := m.read(bytes.NewReader(out), item); err != nil || !bytes.Equal(img, hevc) {
+		t.Errorf("wrapped item data %q, %v", img, err)
+	}
+	if len(item.props) != 2 ||
+		!bytes.Equal(item.props[0].data, ispe) || item.props[0].essential ||
+		!bytes.Equal(item.props[1].data, hvcC) || !item.props[1].essential {
+		t.Errorf("wrapped properties %+v", item.props)
+	}
+	// 包装出的文件本身能被识别为 HEIC
+	if _, _, err := Find(bytes.NewReader(out), int64(len(out))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("wrapped file: got %v, want ErrNotFound (it has no thumbnail)", err)
+	}
+}
+
+func TestParseHEIFMetaErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		meta []byte
+	}{
+		{"too short", []byte{0, 0}},
+		{"bad box size", append(make([]byte, 4), 0, 0, 0, 99, 'p', 'i', 't', 'm')},
+		{"pitm truncated", append(make([]byte, 4), mkFullBox("pitm", 0, []byte{1})...)},
+		{"iloc truncated", append(make([]byte, 4), mkFullBox("iloc", 0, []byte{0x44, 0, 0, 1, 0, 1})...)},
+		{"infe truncated", append(make([]byte, 4), mkFullBox("iinf", 0, append(u16(1), mkFullBox("infe", 2, []byte{0, 1, 0, 0, 'j'})...))...)},
+		{"iref truncated", append(make([]byte, 4), mkFullBox("iref", 0, mkBox("thmb", []byte{0, 2, 0, 3, 0, 1}))...)},
+	}
+	for _, tt := range tests {
+		if m, err := parseHEIFMeta(tt.meta); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: got %+v, %v; want ErrNotFound", tt.name, m, err)
+		}
+	}
+
+	// meta 过大时不读入内存
+	huge := append(bytes.Clone(heicFtyp), mkFullBox("meta", 0, make([]byte, maxMetaSize+1))...)
+	r := &countingReader{r: bytes.NewReader(huge)}
+	if _, _, err := Find(r, int64(len(huge))); !errors.Is(err, ErrNotFound) || r.n > 1024 {
+		t.Errorf("huge meta: %v after reading %d bytes", err, r.n)
+	}
+}
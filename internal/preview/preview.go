@@ -0,0 +1,74 @@
+// Package preview 从图片和视频文件的结构中找出嵌入的预览图，只读取解析所需的部分
+// 支持 JPEG 的 EXIF 缩略图、MP4/MOV 的封面（moov/udta/meta/ilst/covr）和 HEIC/HEIF 的缩略图项。
+// 所有读取都经过调用者提供的 io.ReaderAt，读取上限由调用者实现。
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotFound 表示文件中没有可以提取的预览图，或者文件格式无法识别
+var ErrNotFound = errors.New("no embedded preview")
+
+// JPEGHeadSize 查找 EXIF 缩略图时读取的 JPEG 文件开头的字节数
+// EXIF 位于 APP1 段中，最大 64 KiB，通常紧跟在 SOI 之后；前面可能还有 JFIF 等其他 APP 段。
+const JPEGHeadSize = 128 << 10
+
+// MIME 类型
+const (
+	MIMEJPEG = "image/jpeg"
+	MIMEPNG  = "image/png"
+	MIMEHEIC = "image/heic"
+)
+
+// Find 在大小为 size 的文件中查找嵌入的预览图，返回预览图的数据和 MIME 类型
+// 格式按文件开头的魔数判断，与扩展名无关。没有预览图时返回包装了 ErrNotFound 的错误，读取失败时返回读取错误。
+func Find(r io.ReaderAt, size int64) ([]byte, string, error) {
+	head, err := readAt(r, 0, min(size, 12))
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return jpegThumbnail(r, size)
+	case len(head) >= 8 && isTopLevelBox(string(head[4:8])):
+		if string(head[4:8]) == "ftyp" && len(head) >= 12 && heifBrands[string(head[8:12])] {
+			return heifThumbnail(r, size)
+		}
+		return mp4Cover(r, size)
+	}
+	return nil, "", fmt.Errorf("%w: unrecognised format", ErrNotFound)
+}
+
+// maxRead 单次读取的最大字节数；预览图不会这么大，更大的长度来自损坏的文件，不能据此分配内存
+const maxRead = 16 << 20
+
+// readAt 从 off 处读取 n 字节；文件提前结束时返回 io.ErrUnexpectedEOF
+func readAt(r io.ReaderAt, off, n int64) ([]byte, error) {
+	if n < 0 || n > maxRead || off < 0 {
+		return nil, fmt.Errorf("%w: read of %d bytes at offset %d out of range", ErrNotFound, n, off)
+	}
+	buf := make([]byte, n)
+	m, err := r.ReadAt(buf, off)
+	if m == len(buf) {
+		return buf, nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, err
+}
+
+// sniffMIME 按图片数据的魔数返回 MIME 类型，无法识别时返回空字符串
+func sniffMIME(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return MIMEJPEG
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return MIMEPNG
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// 测试用的图片数据，只需要魔数正确
+var (
+	testJPEG = []byte{0xFF, 0xD8, 0xFF, 0xE0, 't', 'h', 'u', 'm', 'b', 0xFF, 0xD9}
+	testPNG  = []byte("\x89PNG\r\n\x1a\ncover")
+)
+
+// countingReader 记录经过它读取的字节数
+type countingReader struct {
+	r io.ReaderAt
+	n int64
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestFindDispatch(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		mime string
+	}{
+		{"jpeg", jpegWithExif(exifTIFF(le, 6, testJPEG)), MIMEJPEG},
+		{"mp4", mp4File(isoMeta(ilst(13, testJPEG))), MIMEJPEG},
+		{"heic", heicFile(1, []heicItem{{1, "hvc1", []byte("main")}, {2, "jpeg", testJPEG}}, [][2]uint16{{2, 1}}), MIMEJPEG},
+	}
+	for _, tt := range tests {
+		data, mime, err := Find(bytes.NewReader(tt.data), int64(len(tt.data)))
+		if err != nil || mime != tt.mime || !bytes.Equal(data, testJPEG) {
+			t.Errorf("%s: got %q, %s, %v", tt.name, data, mime, err)
+		}
+	}
+
+	for _, data := range [][]byte{nil, []byte("GIF89a"), []byte("\xFF\xD8"), testPNG} {
+		if _, _, err := Find(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Find(%q): got %v, want ErrNotFound", data, err)
+		}
+	}
+	// 声明的大小超过实际数据时返回读取错误
+	if _, _, err := Find(bytes.NewReader([]byte("abc")), 100); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("short file: got %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	r := bytes.NewReader([]byte("0123456789"))
+	if b, err := readAt(r, 2, 3); err != nil || string(b) != "234" {
+		t.Errorf("readAt(2, 3) = %q, %v", b, err)
+	}
+	if _, err := readAt(r, 8, 3); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("read past the end: got %v, want io.ErrUnexpectedEOF", err)
+	}
+	for _, c := range [][2]int64{{0, -1}, {-1, 1}, {0, maxRead + 1}} {
+		if _, err := readAt(r, c[0], c[1]); !errors.Is(err, ErrNotFound) {
+			t.Errorf("readAt(%d, %d): got %v, want ErrNotFound", c[0], c[1], err)
+		}
+	}
+}
+
+func TestSniffMIME(t *testing.T) {
+	for _, tt := range []struct {
+		data []byte
+		want string
+	}{
+		{testJPEG, MIMEJPEG},
+		{testPNG, MIMEPNG},
+		{[]byte{0xFF, 0xD8}, ""},
+		{[]byte("GIF89a"), ""},
+		{nil, ""},
+	} {
+		if got := sniffMIME(tt.data); got != tt.want {
+			t.Errorf("sniffMIME(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
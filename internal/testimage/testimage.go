@@ -0,0 +1,527 @@
+// Package testimage 在内存中构造 exFAT 卷和 VHD 映像，供各包的测试使用
+//
+// 构造的卷与 mkfs.exfat 的布局相同：引导区与备份引导区、FAT、簇堆中依次是分配位图、大写表和目录树。
+// 测试可以在构造之后直接修改 Image.Bytes（改写 FAT 项、破坏引导区、去掉目录结束标记等），
+// 再通过 Entry 和各偏移方法定位要修改的结构。
+package testimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+)
+
+// DefaultTime 节点未指定时间时使用的时间
+var DefaultTime = time.Date(2023, 5, 6, 7, 8, 10, 0, time.UTC)
+
+// Options 卷的参数
+type Options struct {
+	SectorShift  uint8  // 每扇区字节数的位移，0 表示 9（512 字节）
+	ClusterShift uint8  // 每簇扇区数的位移
+	ClusterCount uint32 // 簇数，0 表示 1024
+	NumberOfFATs uint8  // FAT 数量，0 表示 1
+	Label        string // 卷标，为空时没有卷标条目
+	Serial       uint32 // 卷序列号，0 表示 0x1A2B3C4D
+	FlatUpcase   bool   // 以未压缩的形式写入大写表
+}
+
+// Node 目录树中的一个文件或目录
+type Node struct {
+	Name     string
+	Data     []byte  // 文件内容
+	Children []*Node // 目录的子条目
+	Dir      bool    // 是目录（Children 非空时自动视为目录）
+
+	Fragmented  bool   // 隔一个簇分配，通过 FAT 链连接
+	FATChain    bool   // 连续分配，但不设置 NoFatChain 标志，通过 FAT 链连接
+	DirClusters int    // 目录至少分配的簇数
+	Attributes  uint16 // 文件属性，0 时文件为 Archive，目录为 Directory
+
+	Created, Modified, Accessed time.Time // 零值时使用 DefaultTime；按各自的时区记录 UTC 偏移
+}
+
+// File 返回文件节点
+func File(name string, data []byte) *Node {
+	return &Node{Name: name, Data: data}
+}
+
+// Dir 返回目录节点
+func Dir(name string, children ...*Node) *Node {
+	return &Node{Name: name, Dir: true, Children: children}
+}
+
+func (n *Node) isDir() bool {
+	return n.Dir || n.Children != nil
+}
+
+// Entry 构造后的一个条目
+type Entry struct {
+	Node     *Node
+	Path     string
+	Offset   int64    // 条目集（文件条目）在映像中的偏移；根目录为 -1
+	Clusters []uint32 // 按顺序分配的簇
+	Size     int64    // 数据长度，目录为分配的簇的总长度
+}
+
+// Image 构造的 exFAT 卷
+type Image struct {
+	Bytes []byte
+
+	opts            Options
+	BytesPerSector  int
+	BytesPerCluster int
+	FATOffset       int64 // FAT 在映像中的字节偏移
+	FATLength       int64 // 每个 FAT 的字节数
+	HeapOffset      int64 // 簇堆在映像中的字节偏移
+
+	Upcase        []uint16 // 展开后的大写表
+	BitmapCluster uint32
+	UpcaseCluster uint32
+
+	fat     []uint32
+	bitmap  []byte
+	next    uint32
+	entries map[string]*Entry
+}
+
+// Build 按 opts 构造包含 root 中各节点的卷
+func Build(opts Options, root ...*Node) *Image {
+	if opts.SectorShift == 0 {
+		opts.SectorShift = 9
+	}
+	if opts.ClusterCount == 0 {
+		opts.ClusterCount = 1024
+	}
+	if opts.NumberOfFATs == 0 {
+		opts.NumberOfFATs = 1
+	}
+	if opts.Serial == 0 {
+		opts.Serial = 0x1A2B3C4D
+	}
+
+	bps := 1 << opts.SectorShift
+	spc := 1 << opts.ClusterShift
+	fatSectors := ((int(opts.ClusterCount)+2)*4 + bps - 1) / bps
+	heapSector := 24 + fatSectors*int(opts.NumberOfFATs)
+	heapSector = (heapSector + spc - 1) / spc * spc
+	totalSectors := heapSector + int(opts.ClusterCount)*spc
+
+	img := &Image{
+		Bytes:           make([]byte, totalSectors*bps),
+		opts:            opts,
+		BytesPerSector:  bps,
+		BytesPerCluster: bps * spc,
+		FATOffset:       int64(24 * bps),
+		FATLength:       int64(fatSectors * bps),
+		HeapOffset:      int64(heapSector * bps),
+		Upcase:          UpcaseTable(),
+		fat:             make([]uint32, opts.ClusterCount+2),
+		bitmap:          make([]byte, (opts.ClusterCount+7)/8),
+		next:            2,
+		entries:         map[string]*Entry{},
+	}
+	img.fat[0], img.fat[1] = 0xFFFFFFF8, 0xFFFFFFFF
+
+	bitmapClusters := img.alloc(img.clustersFor(len(img.bitmap)), true, false)
+	upcase := img.Upcase
+	if !opts.FlatUpcase {
+		upcase = CompressUpcase(upcase)
+	}
+	upcaseData := make([]byte, 2*len(upcase))
+	for i, u := range upcase {
+		binary.LittleEndian.PutUint16(upcaseData[2*i:], u)
+	}
+	upcaseClusters := img.alloc(img.clustersFor(len(upcaseData)), true, false)
+	img.writeClusters(upcaseClusters, upcaseData)
+	img.BitmapCluster, img.UpcaseCluster = bitmapClusters[0], upcaseClusters[0]
+
+	var system []byte
+	if opts.Label != "" {
+		label := utf16.Encode([]rune(opts.Label))
+		slot := make([]byte, 32)
+		slot[0], slot[1] = 0x83, byte(len(label))
+		for i, u := range label {
+			binary.LittleEndian.PutUint16(slot[2+2*i:], u)
+		}
+		system = append(system, slot...)
+	}
+	slot := make([]byte, 32)
+	slot[0] = 0x81
+	binary.LittleEndian.PutUint32(slot[20:], bitmapClusters[0])
+	binary.LittleEndian.PutUint64(slot[24:], uint64(len(img.bitmap)))
+	system = append(system, slot...)
+	slot = make([]byte, 32)
+	slot[0] = 0x82
+	binary.LittleEndian.PutUint32(slot[4:], checksum32(upcaseData, nil))
+	binary.LittleEndian.PutUint32(slot[20:], upcaseClusters[0])
+	binary.LittleEndian.PutUint64(slot[24:], uint64(len(upcaseData)))
+	system = append(system, slot...)
+
+	rootNode := &Node{Name: "", Dir: true, Children: root, FATChain: true}
+	img.buildDir(rootNode, "/", system)
+
+	img.writeClusters(bitmapClusters, img.bitmap)
+	img.WriteFAT()
+	img.writeBoot()
+	return img
+}
+
+// clustersFor 返回 n 字节需要的簇数
+func (img *Image) clustersFor(n int) int {
+	return (n + img.BytesPerCluster - 1) / img.BytesPerCluster
+}
+
+// alloc 分配 n 个簇；chain 为 true 时在 FAT 中写入簇链，fragmented 为 true 时隔一个簇分配
+func (img *Image) alloc(n int, chain, fragmented bool) []uint32 {
+	clusters := make([]uint32, n)
+	for i := range clusters {
+		clusters[i] = img.next
+		img.next++
+		if fragmented {
+			img.next++
+		}
+	}
+	if n > 0 && uint64(img.next) > uint64(img.opts.ClusterCount)+2 {
+		panic(fmt.Sprintf("testimage: volume of %d clusters is too small", img.opts.ClusterCount))
+	}
+	for _, c := range clusters {
+		img.bitmap[(c-2)/8] |= 1 << ((c - 2) % 8)
+	}
+	if chain || fragmented {
+		for i, c := range clusters {
+			if i+1 < n {
+				img.fat[c] = clusters[i+1]
+			} else {
+				img.fat[c] = 0xFFFFFFFF
+			}
+		}
+	}
+	return clusters
+}
+
+// writeClusters 把 data 依次写入 clusters
+func (img *Image) writeClusters(clusters []uint32, data []byte) {
+	for i, c := range clusters {
+		start := i * img.BytesPerCluster
+		if start >= len(data) {
+			break
+		}
+		copy(img.Bytes[img.ClusterOffset(c):], data[start:min(len(data), start+img.BytesPerCluster)])
+	}
+}
+
+// buildDir 分配并写入 dir 的子条目，再写入 dir 自身的数据；prefix 放在目录数据开头（根目录的系统条目）
+func (img *Image) buildDir(dir *Node, p string, prefix []byte) *Entry {
+	for _, child := range dir.Children {
+		cp := path.Join(p, child.Name)
+		if child.isDir() {
+			img.buildDir(child, cp, nil)
+			continue
+		}
+		n := img.clustersFor(len(child.Data))
+		clusters := img.alloc(n, child.FATChain, child.Fragmented)
+		img.writeClusters(clusters, child.Data)
+		img.entries[strings.ToUpper(cp)] = &Entry{Node: child, Path: cp, Clusters: clusters, Size: int64(len(child.Data))}
+	}
+
+	body := append([]byte(nil), prefix...)
+	offsets := make([]int, len(dir.Children))
+	for i, child := range dir.Children {
+		offsets[i] = len(body)
+		body = append(body, img.entrySet(child, img.entries[strings.ToUpper(path.Join(p, child.Name))])...)
+	}
+	// 至少留一个槽位作为目录结束标记
+	n := max(dir.DirClusters, img.clustersFor(len(body)+32))
+	clusters := img.alloc(n, dir.FATChain, dir.Fragmented)
+	img.writeClusters(clusters, body)
+
+	e := &Entry{Node: dir, Path: p, Offset: -1, Clusters: clusters, Size: int64(n * img.BytesPerCluster)}
+	img.entries[strings.ToUpper(p)] = e
+	for i, child := range dir.Children {
+		img.entries[strings.ToUpper(path.Join(p, child.Name))].Offset = img.DirOffset(e, offsets[i])
+	}
+	return e
+}
+
+// entrySet 编码节点的条目集
+func (img *Image) entrySet(n *Node, e *Entry) []byte {
+	name := utf16.Encode([]rune(n.Name))
+	nameEntries := (len(name) + 14) / 15
+	set := make([]byte, 32*(2+nameEntries))
+	set[0], set[1] = 0x85, byte(1+nameEntries)
+	attrs := n.Attributes
+	if attrs == 0 {
+		attrs = 0x20
+		if n.isDir() {
+			attrs = 0x10
+		}
+	}
+	binary.LittleEndian.PutUint16(set[4:], attrs)
+	for i, t := range []time.Time{n.Created, n.Modified, n.Accessed} {
+		if t.IsZero() {
+			t = DefaultTime
+		}
+		ts, inc, off := Timestamp(t)
+		binary.LittleEndian.PutUint32(set[8+4*i:], ts)
+		if i < 2 {
+			set[20+i] = inc
+		}
+		set[22+i] = off
+	}
+
+	stream := set[32:64]
+	stream[0] = 0xC0
+	if e != nil && len(e.Clusters) > 0 {
+		stream[1] = 0x01
+		if !n.FATChain && !n.Fragmented {
+			stream[1] |= 0x02
+		}
+		binary.LittleEndian.PutUint32(stream[20:], e.Clusters[0])
+		binary.LittleEndian.PutUint64(stream[8:], uint64(e.Size))
+		binary.LittleEndian.PutUint64(stream[24:], uint64(e.Size))
+	}
+	stream[3] = byte(len(name))
+	binary.LittleEndian.PutUint16(stream[4:], NameHash(name, img.Upcase))
+	for i, u := range name {
+		slot := set[64+32*(i/15):]
+		slot[0] = 0xC1
+		binary.LittleEndian.PutUint16(slot[2+2*(i%15):], u)
+	}
+	binary.LittleEndian.PutUint16(set[2:], SetChecksum(set))
+	return set
+}
+
+// Entry 返回路径（不区分大小写）对应的条目，不存在时 panic
+func (img *Image) Entry(p string) *Entry {
+	e, ok := img.entries[strings.ToUpper(path.Clean("/"+p))]
+	if !ok {
+		panic("testimage: no entry " + p)
+	}
+	return e
+}
+
+// ClusterOffset 返回簇在映像中的字节偏移
+func (img *Image) ClusterOffset(c uint32) int64 {
+	return img.HeapOffset + int64(c-2)*int64(img.BytesPerCluster)
+}
+
+// DirOffset 返回目录数据中 pos 处在映像中的字节偏移
+func (img *Image) DirOffset(dir *Entry, pos int) int64 {
+	return img.ClusterOffset(dir.Clusters[pos/img.BytesPerCluster]) + int64(pos%img.BytesPerCluster)
+}
+
+// SlotOffset 返回条目集中第 i 个槽位在映像中的字节偏移（条目集可能跨越簇边界）
+func (img *Image) SlotOffset(e *Entry, i int) int64 {
+	parent := img.Entry(path.Dir(e.Path))
+	for pos := 0; pos < int(parent.Size); pos += 32 {
+		if img.DirOffset(parent, pos) == e.Offset {
+			return img.DirOffset(parent, pos+32*i)
+		}
+	}
+	panic("testimage: entry set of " + e.Path + " not found in its parent")
+}
+
+// Slot 返回条目集中第 i 个槽位的数据（直接引用 Bytes）
+func (img *Image) Slot(e *Entry, i int) []byte {
+	off := img.SlotOffset(e, i)
+	return img.Bytes[off : off+32]
+}
+
+// Resum 在修改条目集之后重新计算并写入其校验和
+func (img *Image) Resum(e *Entry) {
+	primary := img.Slot(e, 0)
+	set := make([]byte, 0, 32*(int(primary[1])+1))
+	for i := 0; i <= int(primary[1]); i++ {
+		set = append(set, img.Slot(e, i)...)
+	}
+	binary.LittleEndian.PutUint16(primary[2:], SetChecksum(set))
+}
+
+// FAT 返回簇的 FAT 项
+func (img *Image) FAT(c uint32) uint32 {
+	return img.fat[c]
+}
+
+// SetFAT 修改簇的 FAT 项并写入所有 FAT 副本
+func (img *Image) SetFAT(c, v uint32) {
+	img.fat[c] = v
+	img.WriteFAT()
+}
+
+// WriteFAT 把 FAT 写入所有副本
+func (img *Image) WriteFAT() {
+	for copyIndex := int64(0); copyIndex < int64(img.opts.NumberOfFATs); copyIndex++ {
+		base := img.FATOffset + copyIndex*img.FATLength
+		for i, v := range img.fat {
+			binary.LittleEndian.PutUint32(img.Bytes[base+int64(4*i):], v)
+		}
+	}
+}
+
+// BootSector 返回主引导扇区（backup 为 true 时为备份引导扇区）的数据，直接引用 Bytes
+// 修改之后调用 SignBoot 重新计算校验和。
+func (img *Image) BootSector(backup bool) []byte {
+	base := 0
+	if backup {
+		base = 12 * img.BytesPerSector
+	}
+	return img.Bytes[base : base+512]
+}
+
+// writeBoot 写入主引导区和备份引导区
+func (img *Image) writeBoot() {
+	bs := img.BootSector(false)
+	copy(bs[0:], []byte{0xEB, 0x76, 0x90})
+	copy(bs[3:], "EXFAT   ")
+	le := binary.LittleEndian
+	le.PutUint64(bs[72:], uint64(len(img.Bytes)/img.BytesPerSector))
+	le.PutUint32(bs[80:], uint32(img.FATOffset)/uint32(img.BytesPerSector))
+	le.PutUint32(bs[84:], uint32(img.FATLength)/uint32(img.BytesPerSector))
+	le.PutUint32(bs[88:], uint32(img.HeapOffset)/uint32(img.BytesPerSector))
+	le.PutUint32(bs[92:], img.opts.ClusterCount)
+	le.PutUint32(bs[96:], img.Entry("/").Clusters[0])
+	le.PutUint32(bs[100:], img.opts.Serial)
+	le.PutUint16(bs[104:], 0x0100)
+	bs[108], bs[109], bs[110], bs[111], bs[112] = img.opts.SectorShift, img.opts.ClusterShift, img.opts.NumberOfFATs, 0x80, 0
+	bs[510], bs[511] = 0x55, 0xAA
+	for i := 1; i <= 8; i++ {
+		sector := img.Bytes[i*img.BytesPerSector : (i+1)*img.BytesPerSector]
+		copy(sector[len(sector)-4:], []byte{0, 0, 0x55, 0xAA})
+	}
+	copy(img.Bytes[12*img.BytesPerSector:24*img.BytesPerSector], img.Bytes[:12*img.BytesPerSector])
+	img.SignBoot()
+}
+
+// SignBoot 重新计算主引导区和备份引导区的校验和扇区
+func (img *Image) SignBoot() {
+	for _, base := range []int{0, 12 * img.BytesPerSector} {
+		region := img.Bytes[base : base+12*img.BytesPerSector]
+		sum := BootChecksum(region[:11*img.BytesPerSector])
+		for i := 11 * img.BytesPerSector; i < len(region); i += 4 {
+			binary.LittleEndian.PutUint32(region[i:], sum)
+		}
+	}
+}
+
+// Disk 返回基于 Bytes 的可读写磁盘，对它的写入直接修改 Bytes
+func (img *Image) Disk() *Disk {
+	return &Disk{Data: img.Bytes}
+}
+
+// Save 把映像写入文件
+func (img *Image) Save(name string) error {
+	return os.WriteFile(name, img.Bytes, 0644)
+}
+
+// Disk 内存中的磁盘，实现 io.ReaderAt、io.WriterAt 和 Size
+type Disk struct {
+	Data []byte
+}
+
+func (d *Disk) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(d.Data).ReadAt(p, off)
+}
+
+func (d *Disk) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 || off+int64(len(p)) > int64(len(d.Data)) {
+		return 0, fmt.Errorf("write at %d+%d is outside the %d-byte disk", off, len(p), len(d.Data))
+	}
+	return copy(d.Data[off:], p), nil
+}
+
+// Size 返回磁盘大小
+func (d *Disk) Size() int64 {
+	return int64(len(d.Data))
+}
+
+var _ interface {
+	io.ReaderAt
+	io.WriterAt
+} = (*Disk)(nil)
+
+// Timestamp 把 t 按其时区编码为 exFAT 时间戳、10 毫秒增量和 UTC 偏移字节
+func Timestamp(t time.Time) (ts uint32, increment, utcOffset uint8) {
+	_, offset := t.Zone()
+	date := uint32(t.Year()-1980)<<9 | uint32(t.Month())<<5 | uint32(t.Day())
+	tm := uint32(t.Hour())<<11 | uint32(t.Minute())<<5 | uint32(t.Second()/2)
+	increment = uint8(t.Second()%2*100 + t.Nanosecond()/int(10*time.Millisecond))
+	return date<<16 | tm, increment, 0x80 | uint8(offset/(15*60))&0x7F
+}
+
+// UpcaseTable 返回按 Unicode 简单大写规则构造的 65536 项大写表
+func UpcaseTable() []uint16 {
+	table := make([]uint16, 0x10000)
+	for i := range table {
+		table[i] = uint16(i)
+		if r := unicode.ToUpper(rune(i)); r <= 0xFFFF && !(i >= 0xD800 && i < 0xE000) {
+			table[i] = uint16(r)
+		}
+	}
+	return table
+}
+
+// CompressUpcase 把大写表压缩为规范中的形式：连续 3 个以上映射到自身的字符写为 0xFFFF 和个数
+func CompressUpcase(table []uint16) []uint16 {
+	var out []uint16
+	for i := 0; i < len(table); {
+		j := i
+		for j < len(table) && table[j] == uint16(j) && j-i < 0xFFFF {
+			j++
+		}
+		if j-i > 2 {
+			out = append(out, 0xFFFF, uint16(j-i))
+			i = j
+			continue
+		}
+		out = append(out, table[i])
+		i++
+	}
+	return out
+}
+
+// NameHash 按规范计算名称哈希
+func NameHash(name []uint16, upcase []uint16) uint16 {
+	var h uint16
+	for _, u := range name {
+		u = upcase[u]
+		for _, b := range []byte{byte(u), byte(u >> 8)} {
+			h = (h<<15 | h>>1) + uint16(b)
+		}
+	}
+	return h
+}
+
+// SetChecksum 计算条目集校验和（跳过文件条目中的校验和字段）
+func SetChecksum(set []byte) uint16 {
+	var sum uint16
+	for i, b := range set {
+		if i == 2 || i == 3 {
+			continue
+		}
+		sum = (sum<<15 | sum>>1) + uint16(b)
+	}
+	return sum
+}
+
+// BootChecksum 计算引导区前 11 个扇区的校验和（跳过 VolumeFlags 和 PercentInUse）
+func BootChecksum(region []byte) uint32 {
+	return checksum32(region, map[int]bool{106: true, 107: true, 112: true})
+}
+
+func checksum32(data []byte, skip map[int]bool) uint32 {
+	var sum uint32
+	for i, b := range data {
+		if skip[i] {
+			continue
+		}
+		sum = (sum<<31 | sum>>1) + uint32(b)
+	}
+	return sum
+}
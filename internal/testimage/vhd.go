@@ -0,0 +1,195 @@
+package testimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// VHD 磁盘类型
+const (
+	fixedDisk        = 2
+	dynamicDisk      = 3
+	differencingDisk = 4
+)
+
+// VHDID FixedVHD 和 DynamicVHD 写入尾部的唯一标识，差分磁盘以它引用这些磁盘
+var VHDID = [16]byte([]byte("testimage-vhd-id"))
+
+// vhdFooter 返回 VHD 尾部（动态磁盘的副本也用于文件开头）
+func vhdFooter(size int64, diskType uint32, dataOffset uint64, id [16]byte) []byte {
+	f := make([]byte, 512)
+	copy(f, "conectix")
+	be := binary.BigEndian
+	be.PutUint32(f[8:], 2)
+	be.PutUint32(f[12:], 0x00010000)
+	be.PutUint64(f[16:], dataOffset)
+	copy(f[28:], "tst ")
+	be.PutUint32(f[32:], 0x00010000)
+	be.PutUint32(f[36:], 0x5769326B)
+	be.PutUint64(f[40:], uint64(size))
+	be.PutUint64(f[48:], uint64(size))
+	be.PutUint32(f[60:], diskType)
+	copy(f[68:], id[:])
+	be.PutUint32(f[64:], vhdChecksum(f))
+	return f
+}
+
+// vhdChecksum 计算 VHD 尾部和动态头部的校验和（所有字节之和取反）
+func vhdChecksum(b []byte) uint32 {
+	var sum uint32
+	for _, c := range b {
+		sum += uint32(c)
+	}
+	return ^sum
+}
+
+// FixedVHD 把原始磁盘数据包装为固定 VHD
+func FixedVHD(raw []byte) []byte {
+	return append(append([]byte(nil), raw...), vhdFooter(int64(len(raw)), fixedDisk, ^uint64(0), VHDID)...)
+}
+
+// DynamicVHD 把原始磁盘数据包装为动态 VHD：块大小为 blockSize，BAT 项和扇区位图以 sectorSize（512 或 4096）字节的扇区为单位
+// 全零的块不分配。磁盘大小（CurrentSize）为 len(raw)，最后一个块可以超出磁盘大小，超出的部分填充 0xEE，
+// 读取时应当被截去。
+func DynamicVHD(raw []byte, blockSize uint32, sectorSize int) []byte {
+	return SparseDynamicVHD(raw, int64(len(raw)), blockSize, sectorSize)
+}
+
+// SparseDynamicVHD 与 DynamicVHD 相同，但磁盘大小为 size（不小于 len(raw)），raw 之后的数据全部为零，完全在 raw 之后的块不分配
+// 用于模拟很大的磁盘：文件只包含 BAT 和 raw 所在的块。
+func SparseDynamicVHD(raw []byte, size int64, blockSize uint32, sectorSize int) []byte {
+	blocks := int((size + int64(blockSize) - 1) / int64(blockSize))
+	align := func(n int) int { return (n + sectorSize - 1) / sectorSize * sectorSize }
+	bitmapSize := align((int(blockSize)/sectorSize + 7) / 8)
+
+	header := make([]byte, 1024)
+	tableOffset := align(512 + 1024)
+	batSize := align(4 * blocks)
+	be := binary.BigEndian
+	copy(header, "cxsparse")
+	be.PutUint64(header[8:], ^uint64(0))
+	be.PutUint64(header[16:], uint64(tableOffset))
+	be.PutUint32(header[24:], 0x00010000)
+	be.PutUint32(header[28:], uint32(blocks))
+	be.PutUint32(header[32:], blockSize)
+	be.PutUint32(header[36:], vhdChecksum(header))
+
+	footer := vhdFooter(size, dynamicDisk, 512, VHDID)
+	out := make([]byte, tableOffset+batSize)
+	copy(out, footer)
+	copy(out[512:], header)
+	for i := tableOffset; i < len(out); i++ {
+		out[i] = 0xFF
+	}
+
+	for b := 0; b*int(blockSize) < len(raw); b++ {
+		chunk := make([]byte, blockSize)
+		n := copy(chunk, raw[b*int(blockSize):])
+		if bytes.Count(chunk[:n], []byte{0}) == n {
+			continue
+		}
+		for i := n; i < len(chunk); i++ {
+			if int64(b)*int64(blockSize)+int64(i) >= size {
+				chunk[i] = 0xEE
+			}
+		}
+		be.PutUint32(out[tableOffset+4*b:], uint32(len(out)/sectorSize))
+		bitmap := bytes.Repeat([]byte{0xFF}, bitmapSize)
+		out = append(append(out, bitmap...), chunk...)
+	}
+	return append(out, footer...)
+}
+
+// Parent 差分磁盘记录的父磁盘
+type Parent struct {
+	ID       [16]byte // 父磁盘的唯一标识
+	Name     string   // 动态头部中的父磁盘名称，为空时不写入
+	Relative string   // W2ru（相对路径）定位器，为空时不写入
+	Absolute string   // W2ku（绝对路径）定位器，为空时不写入
+
+	LocatorLength uint32 // 非零时代替定位器中记录的数据长度，用于构造损坏的定位器
+}
+
+// DifferencingVHD 返回差分 VHD：磁盘内容为 raw，与 parent（父磁盘的内容，长度与 raw 相同）不同的扇区由本磁盘提供，
+// 扇区位图中只置位这些扇区；没有不同扇区的块不分配。id 为本磁盘的唯一标识。
+func DifferencingVHD(raw, parent []byte, blockSize uint32, id [16]byte, p Parent) []byte {
+	const sector = 512
+	blocks := (len(raw) + int(blockSize) - 1) / int(blockSize)
+	align := func(n int) int { return (n + sector - 1) / sector * sector }
+	bitmapSize := align((int(blockSize)/sector + 7) / 8)
+
+	be := binary.BigEndian
+	header := make([]byte, 1024)
+	tableOffset := align(512 + 1024)
+	batSize := align(4 * blocks)
+	copy(header, "cxsparse")
+	be.PutUint64(header[8:], ^uint64(0))
+	be.PutUint64(header[16:], uint64(tableOffset))
+	be.PutUint32(header[24:], 0x00010000)
+	be.PutUint32(header[28:], uint32(blocks))
+	be.PutUint32(header[32:], blockSize)
+	copy(header[40:], p.ID[:])
+	for i, u := range utf16.Encode([]rune(p.Name)) {
+		be.PutUint16(header[64+2*i:], u)
+	}
+
+	out := make([]byte, tableOffset+batSize)
+	for i := tableOffset; i < len(out); i++ {
+		out[i] = 0xFF
+	}
+
+	// 定位器数据（UTF-16LE）放在 BAT 之后，每个占一个扇区
+	locators := []struct {
+		code uint32
+		path string
+	}{{0x57327275, p.Relative}, {0x57326B75, p.Absolute}}
+	slot := 0
+	for _, l := range locators {
+		if l.path == "" {
+			continue
+		}
+		data := make([]byte, sector)
+		units := utf16.Encode([]rune(l.path))
+		for i, u := range units {
+			binary.LittleEndian.PutUint16(data[2*i:], u)
+		}
+		length := uint32(2 * len(units))
+		if p.LocatorLength != 0 {
+			length = p.LocatorLength
+		}
+		entry := header[576+24*slot:]
+		be.PutUint32(entry, l.code)
+		be.PutUint32(entry[4:], sector)
+		be.PutUint32(entry[8:], length)
+		be.PutUint64(entry[16:], uint64(len(out)))
+		out = append(out, data...)
+		slot++
+	}
+	be.PutUint32(header[36:], vhdChecksum(header))
+
+	for b := 0; b < blocks; b++ {
+		start := b * int(blockSize)
+		chunk := make([]byte, blockSize)
+		copy(chunk, raw[start:])
+		bitmap := make([]byte, bitmapSize)
+		changed := false
+		for s := 0; s < int(blockSize)/sector && start+s*sector < len(raw); s++ {
+			end := min(start+(s+1)*sector, len(raw))
+			if !bytes.Equal(raw[start+s*sector:end], parent[start+s*sector:end]) {
+				bitmap[s/8] |= 0x80 >> uint(s%8)
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		be.PutUint32(out[tableOffset+4*b:], uint32(len(out)/sector))
+		out = append(append(out, bitmap...), chunk...)
+	}
+
+	footer := vhdFooter(int64(len(raw)), differencingDisk, 512, id)
+	copy(out, footer)
+	copy(out[512:], header)
+	return append(out, footer...)
+}
@@ -0,0 +1,228 @@
+package exfat
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/0xXA/go-exfat/container"
+	exfatfs "github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/extract"
+)
+
+// Option 配置 OpenVHD 的行为
+type Option func(*openOptions)
+
+// openOptions 汇总各层的选项
+type openOptions struct {
+	container []container.Option
+	fs        []exfatfs.Option
+}
+
+// WithParentDir 添加一个查找差分磁盘父映像的目录
+func WithParentDir(dir string) Option {
+	return func(o *openOptions) {
+		o.container = append(o.container, container.WithParentDir(dir))
+	}
+}
+
+// WithoutProbe 禁止在原始映像开头探测厂商头部
+func WithoutProbe() Option {
+	return func(o *openOptions) {
+		o.container = append(o.container, container.WithoutProbe())
+	}
+}
+
+// WithSectorSize 指定动态磁盘 BAT 使用的扇区大小（512 或 4096），默认自动检测
+func WithSectorSize(size int) Option {
+	return func(o *openOptions) {
+		o.container = append(o.container, container.WithSectorSize(size))
+	}
+}
+
+// WithWritable 以读写方式打开映像，使 VHD.Chtimes 等写操作可用
+// 只支持原始映像、固定磁盘和动态磁盘中已分配的块，差分磁盘仍然只读
+func WithWritable() Option {
+	return func(o *openOptions) {
+		o.container = append(o.container, container.WithWritable())
+	}
+}
+
+// WithPartition 打开磁盘分区表中指定序号（从 1 开始）的分区，默认选择第一个 exFAT 分区
+func WithPartition(index int) Option {
+	return func(o *openOptions) {
+		o.container = append(o.container, container.WithPartition(index))
+	}
+}
+
+// WithBackupBootRecovery 主引导区校验失败时尝试使用备份引导区
+func WithBackupBootRecovery() Option {
+	return func(o *openOptions) {
+		o.fs = append(o.fs, exfatfs.WithBackupBootRecovery())
+	}
+}
+
+// WithoutBootChecksum 引导区校验和不匹配时仍然打开映像，不匹配的原因记入诊断信息
+func WithoutBootChecksum() Option {
+	return func(o *openOptions) {
+		o.fs = append(o.fs, exfatfs.WithoutBootChecksum())
+	}
+}
+
+// WithStrict 启用严格模式，结构问题作为错误返回
+func WithStrict() Option {
+	return func(o *openOptions) {
+		o.fs = append(o.fs, exfatfs.WithStrict())
+	}
+}
+
+// WithFAT32StyleEOC 把 FAT 中 FAT32 风格的 0x0FFFFFFF 视为链结束
+func WithFAT32StyleEOC() Option {
+	return func(o *openOptions) {
+		o.fs = append(o.fs, exfatfs.WithFAT32StyleEOC())
+	}
+}
+
+// WithMaxDirEntries 设置单个目录最多读取的条目数，超过时返回 ErrDirectoryTooLarge
+func WithMaxDirEntries(n int) Option {
+	return func(o *openOptions) {
+		o.fs = append(o.fs, exfatfs.WithMaxDirEntries(n))
+	}
+}
+
+// WithMaxReadSize 设置读取文件时把连续簇合并为一次读取的字节数上限（默认 8 MiB）
+func WithMaxReadSize(n int) Option {
+	return func(o *openOptions) {
+		o.fs = append(o.fs, exfatfs.WithMaxReadSize(n))
+	}
+}
+
+// WithClusterFilter 遍历簇链时先用 filter 判定将要进入的簇，可以把簇视为链结束或坏簇
+func WithClusterFilter(filter ClusterFilter) Option {
+	return func(o *openOptions) {
+		o.fs = append(o.fs, exfatfs.WithClusterFilter(filter))
+	}
+}
+
+// ExcludeClusterRanges 返回把 ranges 中的簇视为链结束的簇过滤器，用于 WithClusterFilter
+func ExcludeClusterRanges(ranges ...ClusterRange) ClusterFilter {
+	return exfatfs.ExcludeClusterRanges(ranges...)
+}
+
+// WithMetadataCache 打开时尝试从 SaveMetadataCache 写出的缓存中载入 FAT，缓存不可用时照常读取
+func WithMetadataCache(r io.Reader) Option {
+	return func(o *openOptions) {
+		o.fs = append(o.fs, exfatfs.WithMetadataCache(r))
+	}
+}
+
+// WithCrosslinkDetection 读取和提取时检测与其他文件共用簇的簇链，报告 ErrCrossLinked 和 AnomalyCrossLinked
+func WithCrosslinkDetection() Option {
+	return func(o *openOptions) {
+		o.fs = append(o.fs, exfatfs.WithCrosslinkDetection())
+	}
+}
+
+// WithReadTracing 把容器层和文件系统层的每次读取以 NDJSON（每行一个带 SchemaTrace 模式标识的 TraceRecord）写到 w，用于调试偏移换算
+// 记录按发生的顺序写出，可以交给 TraceCheck 检查；写入 w 失败时不影响读取。不使用时没有额外开销。
+func WithReadTracing(w io.Writer) Option {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	envelope := NewEnvelope(SchemaTrace)
+	trace := func(layer string, logical, physical, length int64, purpose string) {
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(struct {
+			Envelope
+			TraceRecord
+		}{envelope, TraceRecord{Layer: layer, Logical: logical, Physical: physical, Length: length, Purpose: purpose}})
+	}
+	return func(o *openOptions) {
+		o.container = append(o.container, container.WithReadTracer(trace))
+		o.fs = append(o.fs, exfatfs.WithReadTracer(trace))
+	}
+}
+
+// WithManifest 提取时按处理顺序接收每个条目的结果
+func WithManifest(fn func(ManifestEntry)) ExtractOption {
+	return extract.WithManifest(fn)
+}
+
+// WithProgress 提取时每写出一个文件接收一次累计进度
+func WithProgress(fn func(ExtractProgress)) ExtractOption {
+	return extract.WithProgress(fn)
+}
+
+// WithRepairPlans 提取时对簇链断裂的文件按修复计划读取
+func WithRepairPlans() ExtractOption {
+	return extract.WithRepairPlans()
+}
+
+// WithMirrors 提取时把结果同时写入额外的目标目录，每个文件只从映像读取一次
+func WithMirrors(dirs ...string) ExtractOption {
+	return extract.WithMirrors(dirs...)
+}
+
+// WithLayout 设置提取的目标布局：按目录结构（默认）、按日期分组或平铺
+func WithLayout(l Layout) ExtractOption {
+	return extract.WithLayout(l)
+}
+
+// WithDateSource 设置按日期分组时使用修改时间还是创建时间
+func WithDateSource(s DateSource) ExtractOption {
+	return extract.WithDateSource(s)
+}
+
+// WithAggregator 提取时把进度报告给汇总器，worker 为当前工作者的编号
+func WithAggregator(a *ProgressAggregator, worker int) ExtractOption {
+	return extract.WithAggregator(a, worker)
+}
+
+// WithReportLimit 设置提取报告中最多列出的有问题条目数，超出的只计入 ExtractReport.Omitted
+func WithReportLimit(n int) ExtractOption {
+	return extract.WithReportLimit(n)
+}
+
+// WithSample 提取时按 limits 抽样，先达到的上限使提取正常结束
+func WithSample(limits SampleLimits) ExtractOption {
+	return extract.WithSample(limits)
+}
+
+// WithSampler 提取时按共享的抽样器抽样，上限对所有使用它的提取合计
+func WithSampler(s *Sampler) ExtractOption {
+	return extract.WithSampler(s)
+}
+
+// WithPreset 提取时按预设排除条目，可以与 WithFilter 组合
+func WithPreset(presets ...Preset) ExtractOption {
+	return extract.WithPreset(presets...)
+}
+
+// WithAppleDouble 设置 AppleDouble 文件（._foo）的处理方式，默认作为普通文件提取
+func WithAppleDouble(mode AppleDoubleMode) ExtractOption {
+	return extract.WithAppleDouble(mode)
+}
+
+// WithFilter 提取时对每个条目调用 fn，返回 false 的文件不读取，返回 false 的目录整个跳过
+func WithFilter(fn func(path string, e FileEntry) bool) ExtractOption {
+	return extract.WithFilter(fn)
+}
+
+// WithTransform 提取时按 fn 为每个文件选择的变换写出数据，返回 nil 的文件原样写出
+func WithTransform(fn func(path string, e FileEntry) TransformFunc) ExtractOption {
+	return extract.WithTransform(fn)
+}
+
+// WithGzipLevel 设置 WriteTarGz 的 gzip 压缩级别，取值与 gzip.NewWriterLevel 相同
+func WithGzipLevel(level int) TarGzOption {
+	return extract.WithGzipLevel(level)
+}
+
+// applyOptions 汇总选项
+func applyOptions(opts []Option) *openOptions {
+	o := &openOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
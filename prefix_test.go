@@ -0,0 +1,68 @@
+package exfat_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	exfat "github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+func TestVendorHeaderPrefix(t *testing.T) {
+	content := []byte("behind a vendor header")
+	img := testimage.Build(testimage.Options{}, testimage.Dir("DCIM", testimage.File("a.jpg", content)))
+	for _, prefix := range []int{512, 4096} {
+		// 备份应用写在原始映像之前的专有头部：不含 exFAT 签名，也不像分区表
+		junk := bytes.Repeat([]byte("JUNK"), prefix/4)
+		path := filepath.Join(t.TempDir(), fmt.Sprintf("prefix%d.img", prefix))
+		if err := os.WriteFile(path, append(junk, img.Bytes...), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		v, err := exfat.OpenVHD(path)
+		if err != nil {
+			t.Fatalf("%d-byte prefix: %v", prefix, err)
+		}
+		if info := v.Info(); info.SkippedPrefix != int64(prefix) || info.Serial() != "1A2B-3C4D" {
+			t.Errorf("%d-byte prefix: Info() = %+v", prefix, info)
+		}
+		want := fmt.Sprintf("skipped %d-byte header before the exFAT boot sector", prefix)
+		found := false
+		for _, d := range v.Diagnostics() {
+			found = found || d.Message == want
+		}
+		if !found {
+			t.Errorf("%d-byte prefix: diagnostics %v", prefix, v.Diagnostics())
+		}
+		if got, err := v.ReadFile("/DCIM/a.jpg"); err != nil || !bytes.Equal(got, content) {
+			t.Errorf("%d-byte prefix: ReadFile: %q, %v", prefix, got, err)
+		}
+		v.Close()
+
+		// 关闭探测时不跳过头部
+		if v, err := exfat.OpenVHD(path, exfat.WithoutProbe()); err == nil {
+			v.Close()
+			t.Errorf("%d-byte prefix: opened with WithoutProbe", prefix)
+		} else if !strings.Contains(err.Error(), "invalid file format") {
+			t.Errorf("%d-byte prefix: WithoutProbe: %v", prefix, err)
+		}
+	}
+
+	// 没有头部的映像不跳过任何字节
+	path := filepath.Join(t.TempDir(), "plain.img")
+	if err := img.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	v, err := exfat.OpenVHD(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+	if info := v.Info(); info.SkippedPrefix != 0 {
+		t.Errorf("plain image: SkippedPrefix %d", info.SkippedPrefix)
+	}
+}
@@ -0,0 +1,196 @@
+package exfat_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	exfat "github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+var update = flag.Bool("update", false, "rewrite the current-version golden files in testdata/golden")
+
+// goldenGenerator 黄金文件中的生成者，写出时替换 Generator() 的结果，使文件与构建无关
+const goldenGenerator = "go-exfat v1.0.0"
+
+// 各种文档的示例内容。版本 0 的黄金文件是加入模式标识之前写出的，永远不重新生成；
+// 当前版本的黄金文件由 -update 按这些值重写。
+var (
+	goldenTrace = []exfat.TraceRecord{
+		{Layer: "vhd", Logical: 0, Physical: 1536, Length: 512, Purpose: "block"},
+		{Layer: "exfat", Logical: 0, Physical: 0, Length: 512, Purpose: "boot"},
+		{Layer: "exfat", Logical: 2048, Physical: 2048, Length: 4096, Purpose: "fat"},
+		{Layer: "exfat", Logical: 1 << 33, Physical: 1 << 33, Length: 32768, Purpose: "data"},
+	}
+	goldenIdentities = []exfat.VolumeIdentity{
+		{Path: "cam_a.vhd", DiskType: "dynamic", UUID: "6f1c3a52-0d4e-4b6f-9a1e-2c7d8e9f0a1b", VolumeOffset: 1 << 20,
+			Serial: "1A2B-3C4D", Label: "CAM_A", Capacity: 64 << 30, ClusterSize: 128 << 10, Formatter: "3c1f9a0e"},
+		{Path: "raw.img", DiskType: "raw", Partition: 2, VolumeOffset: 32256,
+			Serial: "0000-0001", Label: "", Capacity: 1 << 20, ClusterSize: 4096, Formatter: "00000000"},
+	}
+	goldenReports = []exfat.SourceReport{
+		{Source: "/", Report: &exfat.ExtractReport{Extracted: 3, Bytes: 12345, Duration: 1500000}},
+		{Source: "/DCIM", Report: &exfat.ExtractReport{
+			Extracted: 10, Failures: 1, Skipped: 2, Bytes: 1 << 30, Duration: 2e9, Omitted: 4, Stopped: exfat.BoundMaxFiles,
+			Files: []exfat.ExtractFileReport{
+				{Path: "/DCIM/broken.jpg", Anomalies: []exfat.Anomaly{exfat.AnomalyShortChain}, Err: errors.New("broken cluster chain"),
+					Repair: &exfat.RepairPlan{Path: "/DCIM/broken.jpg", Clusters: []uint32{5, 6, 7}, ChainLength: 2, Confidence: 0.75, Reasons: []string{"contiguous"}}},
+				{Path: "/DCIM/a.jpg", Dest: "/mirror/DCIM/a.jpg", Err: errors.New("disk full")},
+				{Path: "/DCIM/b.jpg", FAT: 2, Warning: "read from the second FAT"},
+			}}},
+	}
+)
+
+// goldenDocs 每种文档的黄金文件名、当前版本的模式标识、示例内容和解码函数
+var goldenDocs = []struct {
+	name   string
+	schema string
+	want   any
+	decode func(*bytes.Reader) (any, error)
+}{
+	{"trace", exfat.SchemaTrace, goldenTrace, func(r *bytes.Reader) (any, error) { return exfat.DecodeTrace(r) }},
+	{"identity", exfat.SchemaIdentity, goldenIdentities, func(r *bytes.Reader) (any, error) { return exfat.DecodeIdentities(r) }},
+	{"report", exfat.SchemaReport, goldenReports, func(r *bytes.Reader) (any, error) { return exfat.DecodeReports(r) }},
+}
+
+// encodeGolden 按写出者的方式（Envelope 嵌入在记录之前）把示例内容编码为 NDJSON
+func encodeGolden(t *testing.T, schema string, records any) []byte {
+	t.Helper()
+	envelope := exfat.Envelope{Schema: schema, Generator: goldenGenerator}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	v := reflect.ValueOf(records)
+	for i := 0; i < v.Len(); i++ {
+		var line any
+		switch r := v.Index(i).Interface().(type) {
+		case exfat.TraceRecord:
+			line = struct {
+				exfat.Envelope
+				exfat.TraceRecord
+			}{envelope, r}
+		case exfat.VolumeIdentity:
+			line = struct {
+				exfat.Envelope
+				exfat.VolumeIdentity
+			}{envelope, r}
+		case exfat.SourceReport:
+			line = struct {
+				exfat.Envelope
+				exfat.SourceReport
+			}{envelope, r}
+		}
+		if err := enc.Encode(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// sameJSON 比较两个值的 JSON 编码；报告中的错误只能按文本比较
+func sameJSON(t *testing.T, got, want any) bool {
+	t.Helper()
+	a, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Equal(a, b)
+}
+
+func TestSchemaGoldenFiles(t *testing.T) {
+	for _, doc := range goldenDocs {
+		_, version, _ := strings.Cut(doc.schema, "/")
+		current := filepath.Join("testdata", "golden", doc.name+".v"+version+".ndjson")
+		encoded := encodeGolden(t, doc.schema, doc.want)
+		if *update {
+			if err := os.WriteFile(current, encoded, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// 写出的格式与当前版本的黄金文件逐字节相同；不同时说明字段改变了，需要提升版本
+		golden, err := os.ReadFile(current)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encoded, golden) {
+			t.Errorf("%s: encoding differs from %s:\n%s", doc.name, current, encoded)
+		}
+
+		// 每个版本的黄金文件都解码为相同的内容
+		versions, err := filepath.Glob(filepath.Join("testdata", "golden", doc.name+".v*.ndjson"))
+		if err != nil || len(versions) < 2 {
+			t.Errorf("%s: golden files %v, %v; want one per version", doc.name, versions, err)
+			continue
+		}
+		for _, path := range versions {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := doc.decode(bytes.NewReader(data))
+			if err != nil {
+				t.Errorf("%s: %v", path, err)
+				continue
+			}
+			if !sameJSON(t, got, doc.want) {
+				t.Errorf("%s decoded to %+v, want %+v", path, got, doc.want)
+			}
+		}
+	}
+}
+
+func TestSchemaRejected(t *testing.T) {
+	for _, doc := range goldenDocs {
+		kind, _, _ := strings.Cut(doc.schema, "/")
+		for _, schema := range []string{kind + "/99", "exfat.other/1", kind, "/1"} {
+			line := `{"schema":"` + schema + `","generator":"x"}` + "\n"
+			if _, err := doc.decode(bytes.NewReader([]byte(line))); !errors.Is(err, exfat.ErrSchema) {
+				t.Errorf("%s with schema %q: got %v, want ErrSchema", doc.name, schema, err)
+			}
+		}
+		// 错误带有行号
+		data := encodeGolden(t, doc.schema, doc.want)
+		data = append(data, `{"schema":"`+kind+`/99"}`+"\n"...)
+		line := fmt.Sprintf("line %d:", reflect.ValueOf(doc.want).Len()+1)
+		if _, err := doc.decode(bytes.NewReader(data)); !errors.Is(err, exfat.ErrSchema) || !strings.Contains(err.Error(), line) {
+			t.Errorf("%s: error %v does not name the line", doc.name, err)
+		}
+	}
+}
+
+func TestReadTracingEnvelope(t *testing.T) {
+	img := testimage.Build(testimage.Options{}, testimage.File("a.txt", []byte("hello")))
+	var trace bytes.Buffer
+	fs, err := exfat.NewExFATFileSystem(img.Disk(), exfat.WithReadTracing(&trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.ReadFile("/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(trace.String(), "\n"), "\n")
+	for _, line := range lines {
+		var envelope exfat.Envelope
+		if err := json.Unmarshal([]byte(line), &envelope); err != nil {
+			t.Fatal(err)
+		}
+		if envelope.Schema != exfat.SchemaTrace || envelope.Generator != exfat.Generator() {
+			t.Fatalf("trace line %s has envelope %+v", line, envelope)
+		}
+	}
+	records, err := exfat.DecodeTrace(&trace)
+	if err != nil || len(records) != len(lines) || records[len(records)-1].Purpose != "data" {
+		t.Errorf("decoded %d of %d lines, %v", len(records), len(lines), err)
+	}
+}
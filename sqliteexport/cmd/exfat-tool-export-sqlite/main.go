@@ -0,0 +1,51 @@
+// exfat-tool-export-sqlite 实现 exfat-tool export-sqlite 子命令
+// 它是独立的程序，放在 PATH 中（或 exfat-tool 所在目录）时由 exfat-tool export-sqlite 调用，
+// 使 exfat-tool 本身不依赖 SQLite 驱动。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/0xXA/go-exfat"
+	"github.com/0xXA/go-exfat/sqliteexport"
+)
+
+func main() {
+	flags := flag.NewFlagSet("export-sqlite", flag.ExitOnError)
+	vhdPath := flags.String("vhd", "", "Path to the VHD file")
+	output := flags.String("o", "", "SQLite database to write; an existing database gets the volume appended")
+	root := flags.String("root", "/", "Directory inside the exFAT filesystem to export")
+	batch := flags.Int("batch", sqliteexport.DefaultBatchSize, "Number of entries inserted per transaction")
+	parentDir := flags.String("parent-dir", "", "Directory to search for parent disks when locators are stale (optional)")
+	quiet := flags.Bool("q", false, "Do not report progress")
+	flags.Usage = func() {
+		fmt.Println("Usage: exfat-tool export-sqlite -vhd <path_to_vhd> -o <database> [-root /] [-batch n]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	if *vhdPath == "" || *output == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	vhd, err := exfat.OpenVHD(*vhdPath, exfat.WithParentDir(*parentDir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open VHD file: %v\n", err)
+		os.Exit(1)
+	}
+	defer vhd.Close()
+
+	opts := sqliteexport.Options{BatchSize: *batch}
+	if !*quiet {
+		opts.Progress = func(entries int) {
+			fmt.Fprintf(os.Stderr, "Progress: %d entries\n", entries)
+		}
+	}
+	if err := sqliteexport.Export(vhd.FileSystem(), *root, *output, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export %s: %v\n", *root, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,246 @@
+// Package sqliteexport 把 exFAT 卷的目录树导出为 SQLite 数据库，用 SQL 查询大量映像的内容
+//
+// 本包是独立的模块，只有需要时才引入 SQLite 驱动（纯 Go 实现，不需要 cgo）。
+// 多个卷可以导出到同一个数据库中，每个卷在 volumes 表中占一行，条目和异常通过 volume_id 关联。
+package sqliteexport
+
+import (
+	"database/sql"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+	_ "modernc.org/sqlite"
+)
+
+// DefaultBatchSize 每个事务默认插入的条目数
+const DefaultBatchSize = 10000
+
+// Options 导出选项
+type Options struct {
+	BatchSize int               // 每个事务插入的条目数，为 0 时使用 DefaultBatchSize
+	Progress  func(entries int) // 每提交一个事务后以已导出的条目数调用
+}
+
+// schema 数据库结构；时间以 UTC 的 "YYYY-MM-DD HH:MM:SS.SSS" 存储，可以直接用于 SQLite 的日期函数
+const schema = `
+CREATE TABLE IF NOT EXISTS volumes (
+	id           INTEGER PRIMARY KEY,
+	serial       TEXT NOT NULL,
+	label        TEXT NOT NULL,
+	capacity     INTEGER NOT NULL,
+	cluster_size INTEGER NOT NULL,
+	root         TEXT NOT NULL,
+	exported_at  TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS entries (
+	volume_id      INTEGER NOT NULL REFERENCES volumes(id),
+	path           TEXT NOT NULL,
+	parent         TEXT NOT NULL,
+	name           TEXT NOT NULL,
+	is_dir         INTEGER NOT NULL,
+	size           INTEGER NOT NULL,
+	allocated      INTEGER NOT NULL,
+	modified       TEXT,
+	created        TEXT,
+	accessed       TEXT,
+	attributes     TEXT NOT NULL,
+	attribute_bits INTEGER NOT NULL,
+	first_cluster  INTEGER,
+	contiguous     INTEGER NOT NULL,
+	entry_id       TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS findings (
+	volume_id INTEGER NOT NULL REFERENCES volumes(id),
+	path      TEXT NOT NULL,
+	kind      TEXT NOT NULL,
+	detail    TEXT NOT NULL
+);
+`
+
+// indexes 在插入完成后创建，避免逐行维护索引
+const indexes = `
+CREATE INDEX IF NOT EXISTS entries_path ON entries(volume_id, path);
+CREATE INDEX IF NOT EXISTS entries_parent ON entries(volume_id, parent);
+CREATE INDEX IF NOT EXISTS entries_name ON entries(name);
+CREATE INDEX IF NOT EXISTS entries_size ON entries(size);
+CREATE INDEX IF NOT EXISTS entries_modified ON entries(modified);
+CREATE INDEX IF NOT EXISTS findings_kind ON findings(kind);
+`
+
+// 无法读取的目录在 findings 表中的类型
+const kindUnreadableDirectory = "unreadable directory"
+
+const timeLayout = "2006-01-02 15:04:05.000"
+
+// Export 把 root 下的目录树导出到 dbPath 的 SQLite 数据库
+// 数据库不存在时创建；已存在时追加一个新的卷。单个目录无法读取不会中断导出，而是记录在 findings 表中。
+func Export(fsys *exfat.ExFATFileSystem, root, dbPath string, opts Options) error {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	id, err := fsys.Identity()
+	if err != nil {
+		return fmt.Errorf("failed to identify volume: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create tables: %v", err)
+	}
+
+	res, err := db.Exec(`INSERT INTO volumes (serial, label, capacity, cluster_size, root, exported_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		id.Serial(), id.Label, int64(id.Capacity), int64(id.ClusterSize), root, formatTime(time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to record volume: %v", err)
+	}
+	volume, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	x := &exporter{fsys: fsys, db: db, opts: opts, volume: volume, clusterSize: int64(id.ClusterSize)}
+	if err := x.begin(); err != nil {
+		return err
+	}
+	if err := x.dir(root); err != nil {
+		x.tx.Rollback()
+		return err
+	}
+	if err := x.commit(); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(indexes); err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+	return nil
+}
+
+// exporter 导出过程的状态，条目按批在事务中插入
+type exporter struct {
+	fsys        *exfat.ExFATFileSystem
+	db          *sql.DB
+	opts        Options
+	volume      int64
+	clusterSize int64
+
+	tx       *sql.Tx
+	entries  *sql.Stmt
+	findings *sql.Stmt
+	pending  int // 当前事务中的条目数
+	total    int // 已提交的条目数
+}
+
+// begin 开始一个新的事务
+func (x *exporter) begin() error {
+	tx, err := x.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	x.tx = tx
+	if x.entries, err = tx.Prepare(`INSERT INTO entries (volume_id, path, parent, name, is_dir, size, allocated, modified, created, accessed,
+		attributes, attribute_bits, first_cluster, contiguous, entry_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if x.findings, err = tx.Prepare(`INSERT INTO findings (volume_id, path, kind, detail) VALUES (?, ?, ?, ?)`); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// commit 提交当前事务并报告进度
+func (x *exporter) commit() error {
+	if err := x.tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	x.total += x.pending
+	x.pending = 0
+	if x.opts.Progress != nil {
+		x.opts.Progress(x.total)
+	}
+	return nil
+}
+
+// dir 导出一个目录的内容
+func (x *exporter) dir(dirPath string) error {
+	entries, err := x.fsys.ListDir(dirPath)
+	if err != nil {
+		return x.finding(dirPath, kindUnreadableDirectory, err.Error())
+	}
+	anomalies, err := x.fsys.DirAnomalies(dirPath)
+	if err != nil {
+		return x.finding(dirPath, kindUnreadableDirectory, err.Error())
+	}
+
+	for _, entry := range entries {
+		fullPath := path.Join(dirPath, entry.Name)
+		if err := x.entry(dirPath, fullPath, entry); err != nil {
+			return err
+		}
+		for _, a := range anomalies[entry.Name] {
+			if err := x.finding(fullPath, string(a), ""); err != nil {
+				return err
+			}
+		}
+		if entry.IsDir {
+			if err := x.dir(fullPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// entry 插入一个条目，当前事务的条目数达到 BatchSize 时提交
+func (x *exporter) entry(parent, fullPath string, e exfat.FileEntry) error {
+	// FileID 最高位为 0 时就是起始簇号；没有簇的条目没有起始簇和已分配空间
+	var firstCluster interface{}
+	var allocated int64
+	if e.FileID < 1<<63 {
+		firstCluster = int64(e.FileID)
+		allocated = (e.Size + x.clusterSize - 1) / x.clusterSize * x.clusterSize
+	}
+
+	_, err := x.entries.Exec(x.volume, fullPath, parent, e.Name, e.IsDir, e.Size, allocated,
+		nullTime(e.ModTime), nullTime(e.CreateTime), nullTime(e.AccessTime),
+		e.Attributes.String(), int64(e.Attributes), firstCluster, e.Contiguous, exfat.EntryID(e))
+	if err != nil {
+		return fmt.Errorf("failed to insert %s: %v", fullPath, err)
+	}
+
+	if x.pending++; x.pending >= x.opts.BatchSize {
+		if err := x.commit(); err != nil {
+			return err
+		}
+		return x.begin()
+	}
+	return nil
+}
+
+// finding 插入一条异常记录
+func (x *exporter) finding(fullPath, kind, detail string) error {
+	if _, err := x.findings.Exec(x.volume, fullPath, kind, detail); err != nil {
+		return fmt.Errorf("failed to insert finding for %s: %v", fullPath, err)
+	}
+	return nil
+}
+
+// nullTime 把零值时间转换为 NULL
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return formatTime(t)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
@@ -0,0 +1,171 @@
+package sqliteexport
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/internal/testimage"
+)
+
+// fixture 返回用于导出的卷：带日期和属性的文件、一个校验和错误的条目集和一个超过条目数上限的目录
+func fixture(t *testing.T) *exfat.ExFATFileSystem {
+	t.Helper()
+	photo := func(name string, size int, modified time.Time) *testimage.Node {
+		n := testimage.File(name, make([]byte, size))
+		n.Modified, n.Created, n.Accessed = modified, modified, modified
+		return n
+	}
+	readOnly := testimage.File("readme.txt", []byte("hello"))
+	readOnly.Attributes = uint16(exfat.AttrReadOnly | exfat.AttrArchive)
+	var many []*testimage.Node
+	for i := 0; i < 40; i++ {
+		many = append(many, testimage.File(fmt.Sprintf("f%02d", i), nil))
+	}
+	img := testimage.Build(testimage.Options{Label: "CAM_A", Serial: 0x1A2B3C4D},
+		readOnly,
+		testimage.Dir("DCIM",
+			photo("a.jpg", 1500, time.Date(2023, 1, 15, 10, 0, 0, 0, time.UTC)),
+			photo("b.jpg", 700, time.Date(2023, 2, 20, 10, 0, 0, 0, time.UTC)),
+			photo("c.jpg", 0, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC))),
+		testimage.Dir("Big", many...))
+	// 改动 b.jpg 的条目集而不更新校验和
+	e := img.Entry("/DCIM/b.jpg")
+	img.Slot(e, 0)[4] ^= 0x20
+	fs, err := exfat.NewExFATFileSystem(img.Disk(), exfat.WithMaxDirEntries(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+// export 导出到新的数据库并打开它
+func export(t *testing.T, fs *exfat.ExFATFileSystem, opts Options) (*sql.DB, string) {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "contents.db")
+	if err := Export(fs, "/", dbPath, opts); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, dbPath
+}
+
+// queryStrings 执行只返回一列的查询
+func queryStrings(t *testing.T, db *sql.DB, query string, args ...any) []string {
+	t.Helper()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestExportQueries(t *testing.T) {
+	fs := fixture(t)
+	db, _ := export(t, fs, Options{})
+
+	var serial, label, root string
+	var capacity, clusterSize int64
+	if err := db.QueryRow(`SELECT serial, label, capacity, cluster_size, root FROM volumes WHERE id = 1`).
+		Scan(&serial, &label, &capacity, &clusterSize, &root); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := fs.Identity()
+	if serial != "1A2B-3C4D" || label != "CAM_A" || capacity != int64(id.Capacity) || clusterSize != int64(id.ClusterSize) || root != "/" {
+		t.Errorf("volume row: %s %s %d %d %s", serial, label, capacity, clusterSize, root)
+	}
+
+	for _, q := range []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		// Big 无法读取，只有它本身的条目
+		{"all paths", `SELECT path FROM entries ORDER BY path`,
+			[]string{"/Big", "/DCIM", "/DCIM/a.jpg", "/DCIM/b.jpg", "/DCIM/c.jpg", "/readme.txt"}},
+		{"directories", `SELECT path FROM entries WHERE is_dir ORDER BY path`, []string{"/Big", "/DCIM"}},
+		{"children", `SELECT name FROM entries WHERE parent = '/DCIM' ORDER BY name`, []string{"a.jpg", "b.jpg", "c.jpg"}},
+		{"size and allocation", `SELECT name || ':' || size || ':' || allocated FROM entries WHERE parent = '/DCIM' ORDER BY name`,
+			[]string{"a.jpg:1500:1536", "b.jpg:700:1024", "c.jpg:0:0"}},
+		{"date functions", `SELECT name FROM entries WHERE parent = '/DCIM' AND strftime('%Y', modified) = '2023' ORDER BY modified`, []string{"a.jpg", "b.jpg"}},
+		{"time layout", `SELECT modified FROM entries WHERE path = '/DCIM/a.jpg'`, []string{"2023-01-15 10:00:00.000"}},
+		{"attributes", `SELECT path || ' ' || attributes FROM entries WHERE attribute_bits & 1`, []string{"/readme.txt " + (exfat.AttrReadOnly | exfat.AttrArchive).String()}},
+		{"no cluster", `SELECT path FROM entries WHERE first_cluster IS NULL AND NOT is_dir`, []string{"/DCIM/c.jpg"}},
+		{"findings", `SELECT path || ': ' || kind FROM findings ORDER BY path`,
+			[]string{"/Big: " + kindUnreadableDirectory, "/DCIM/b.jpg: " + string(exfat.AnomalyChecksumMismatch)}},
+		{"join", `SELECT e.path FROM entries e JOIN findings f ON f.volume_id = e.volume_id AND f.path = e.path WHERE NOT e.is_dir`,
+			[]string{"/DCIM/b.jpg"}},
+		{"indexes", `SELECT name FROM sqlite_master WHERE type = 'index' ORDER BY name`,
+			[]string{"entries_modified", "entries_name", "entries_parent", "entries_path", "entries_size", "findings_kind"}},
+	} {
+		if got := queryStrings(t, db, q.query); !slices.Equal(got, q.want) {
+			t.Errorf("%s: got %q, want %q", q.name, got, q.want)
+		}
+	}
+
+	// 条目 ID 可以换回卷中的条目
+	for _, p := range queryStrings(t, db, `SELECT path FROM entries`) {
+		var entryID string
+		if err := db.QueryRow(`SELECT entry_id FROM entries WHERE path = ?`, p).Scan(&entryID); err != nil {
+			t.Fatal(err)
+		}
+		e, err := fs.ResolveEntryID(entryID)
+		if err != nil || filepath.Base(p) != e.Name {
+			t.Errorf("%s: entry ID %s resolves to %q, %v", p, entryID, e.Name, err)
+		}
+	}
+}
+
+func TestExportBatchesAndAppend(t *testing.T) {
+	fs := fixture(t)
+	var progress []int
+	db, dbPath := export(t, fs, Options{BatchSize: 4, Progress: func(n int) { progress = append(progress, n) }})
+	if want := []int{4, 6}; !slices.Equal(progress, want) {
+		t.Errorf("progress %v, want %v", progress, want)
+	}
+
+	// 同一个数据库再导出一个卷，条目按 volume_id 区分
+	if err := Export(fs, "/DCIM", dbPath, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	got := queryStrings(t, db, `SELECT v.id || ' ' || v.root || ' ' || count(e.path) FROM volumes v JOIN entries e ON e.volume_id = v.id GROUP BY v.id ORDER BY v.id`)
+	if want := []string{"1 / 6", "2 /DCIM 3"}; !slices.Equal(got, want) {
+		t.Errorf("volumes %q, want %q", got, want)
+	}
+}
+
+func TestExportBadTarget(t *testing.T) {
+	fs := fixture(t)
+	if err := Export(fs, "/", filepath.Join(t.TempDir(), "missing", "x.db"), Options{}); err == nil {
+		t.Error("exported into a missing directory")
+	}
+	// 根路径不存在时记录为无法读取的目录
+	db, dbPath := export(t, fs, Options{})
+	if err := Export(fs, "/nope", dbPath, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT count(*) FROM findings WHERE volume_id = 2 AND path = '/nope' AND kind = ?`, kindUnreadableDirectory).Scan(&n); err != nil || n != 1 {
+		t.Errorf("missing root recorded %d times, %v", n, err)
+	}
+}
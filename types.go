@@ -2,160 +2,284 @@ package exfat
 
 import (
 	"io"
-	"os"
+
+	exfatfs "github.com/0xXA/go-exfat/exfat"
+	"github.com/0xXA/go-exfat/extract"
+)
+
+// 文件系统层的类型
+type (
+	Diagnostic = exfatfs.Diagnostic
+	Deviation  = exfatfs.Deviation
+	Consumer   = exfatfs.Consumer
+	Anomaly    = exfatfs.Anomaly
+	RepairPlan = exfatfs.RepairPlan
+	Extent     = exfatfs.Extent
+	ClusterRun = exfatfs.ClusterRun
+	RootEntry  = exfatfs.RootEntry
+	File       = exfatfs.File
+	Attributes = exfatfs.Attributes
+
+	DeletedEntry = exfatfs.DeletedEntry
+
+	Index         = exfatfs.Index
+	IndexOptions  = exfatfs.IndexOptions
+	IndexProgress = exfatfs.IndexProgress
+
+	VolumeInfo    = exfatfs.VolumeInfo
+	VolumeStats   = exfatfs.VolumeStats
+	UsageEstimate = exfatfs.UsageEstimate
+
+	CheckOptions  = exfatfs.CheckOptions
+	VolumeCheck   = exfatfs.VolumeCheck
+	VolumeFinding = exfatfs.VolumeFinding
+
+	ConformanceIssue = exfatfs.ConformanceIssue
+
+	TraceRecord  = exfatfs.TraceRecord
+	TraceFinding = exfatfs.TraceFinding
+	FSInfo       = exfatfs.FSInfo
+	Envelope     = exfatfs.Envelope
+
+	CrossLinkError = exfatfs.CrossLinkError
+	ClusterFilter  = exfatfs.ClusterFilter
+	ClusterVerdict = exfatfs.ClusterVerdict
+	ClusterRange   = exfatfs.ClusterRange
+)
+
+// 提取层的类型
+type (
+	ExtractReport      = extract.Report
+	ExtractFileReport  = extract.FileReport
+	SourceReport       = extract.SourceReport
+	ExtractOption      = extract.Option
+	ManifestEntry      = extract.ManifestEntry
+	ExtractProgress    = extract.Progress
+	ExportOptions      = extract.ExportOptions
+	TarGzOption        = extract.TarGzOption
+	ArchiveMetadata    = extract.ArchiveMetadata
+	ProgressAggregator = extract.ProgressAggregator
+	AggregateProgress  = extract.AggregateProgress
+	CheckPolicy        = extract.CheckPolicy
+	CheckAction        = extract.CheckAction
+	CheckReport        = extract.CheckReport
+	CheckFinding       = extract.CheckFinding
+	Outcome            = extract.Outcome
+	SampleLimits       = extract.SampleLimits
+	SampleBound        = extract.SampleBound
+	Sampler            = extract.Sampler
+	Preset             = extract.Preset
+	AppleDoubleMode    = extract.AppleDoubleMode
+	TransformFunc      = extract.TransformFunc
+	DecompressOptions  = extract.DecompressOptions
+	Layout             = extract.Layout
+	DateSource         = extract.DateSource
+)
+
+// 条目异常类型
+const (
+	AnomalyChecksumMismatch     = exfatfs.AnomalyChecksumMismatch
+	AnomalyShortChain           = exfatfs.AnomalyShortChain
+	AnomalyBadCluster           = exfatfs.AnomalyBadCluster
+	AnomalyInvalidTimestamp     = exfatfs.AnomalyInvalidTimestamp
+	AnomalyNameHashMismatch     = exfatfs.AnomalyNameHashMismatch
+	AnomalyCrossLinked          = exfatfs.AnomalyCrossLinked
+	AnomalyExtraStreamExtension = exfatfs.AnomalyExtraStreamExtension
+	AnomalyUnallocated          = exfatfs.AnomalyUnallocated
+	AnomalyUsageDisagreement    = exfatfs.AnomalyUsageDisagreement
 )
 
-// exFAT 目录条目类型
+// 偏离规范的写法（见 ConformanceIssue）
 const (
-	EntryTypeEndOfDirectory   = 0x00
-	EntryTypeFile             = 0x85
-	EntryTypeVolumeLabel      = 0x83
-	EntryTypeAllocationBitmap = 0x81
-	EntryTypeUpcaseTable      = 0x82
-	EntryTypeFileInfo         = 0xC0
-	EntryTypeFileName         = 0xC1
+	DeviationReservedEOC = exfatfs.DeviationReservedEOC
+	DeviationFAT32EOC    = exfatfs.DeviationFAT32EOC
+)
+
+// 统计已用空间的方法
+const (
+	UsageFromBitmap = exfatfs.UsageFromBitmap
+	UsageFromFAT    = exfatfs.UsageFromFAT
+	UsageFromTree   = exfatfs.UsageFromTree
+)
+
+// 簇过滤器的判定
+const (
+	ClusterAllow      = exfatfs.ClusterAllow
+	ClusterTreatAsEOC = exfatfs.ClusterTreatAsEOC
+	ClusterTreatAsBad = exfatfs.ClusterTreatAsBad
+)
+
+// 检查策略的处理方式
+const (
+	CheckExtract  = extract.CheckExtract
+	CheckZeroFill = extract.CheckZeroFill
+	CheckRepair   = extract.CheckRepair
+	CheckSkip     = extract.CheckSkip
+	CheckFail     = extract.CheckFail
+)
+
+// 提取条目的处理结果（见 ManifestEntry.Outcome）
+const (
+	OutcomeOK            = extract.OutcomeOK
+	OutcomeSkippedFilter = extract.OutcomeSkippedFilter
+	OutcomeSkippedCheck  = extract.OutcomeSkippedCheck
+	OutcomeSkippedSample = extract.OutcomeSkippedSample
+	OutcomeFailed        = extract.OutcomeFailed
+)
+
+// 抽样提取的上限（见 ExtractReport.Stopped）
+const (
+	BoundMaxFiles    = extract.BoundMaxFiles
+	BoundMaxBytes    = extract.BoundMaxBytes
+	BoundMaxDuration = extract.BoundMaxDuration
+)
+
+// AppleDouble 文件的处理方式（见 WithAppleDouble）
+const (
+	AppleDoubleKeep  = extract.AppleDoubleKeep
+	AppleDoubleDrop  = extract.AppleDoubleDrop
+	AppleDoubleMerge = extract.AppleDoubleMerge
+)
+
+// 按日期分组时使用的时间（见 WithDateSource）
+const (
+	DateModified = extract.DateModified
+	DateCreated  = extract.DateCreated
+)
+
+// 按日期分组时默认的子路径布局和没有时间的文件所在的子目录
+const (
+	DefaultDateLayout = extract.DefaultDateLayout
+	UndatedDir        = extract.UndatedDir
+)
+
+// 提取的目标布局（见 WithLayout）
+var (
+	LayoutMirror = extract.LayoutMirror
+	LayoutFlat   = extract.LayoutFlat
+)
+
+// 提取时的排除规则预设（见 WithPreset）
+var (
+	PresetSkipOSMetadata   = extract.PresetSkipOSMetadata
+	PresetSkipAndroidCache = extract.PresetSkipAndroidCache
 )
 
-// 特殊簇值
+// 内置的提取变换（见 WithTransform）
+var (
+	CRLF     TransformFunc = extract.CRLF
+	StripBOM TransformFunc = extract.StripBOM
+)
+
+// DefaultDecompressSuffixes Decompress 默认解压的文件后缀
+var DefaultDecompressSuffixes = extract.DefaultDecompressSuffixes
+
+// DefaultDecompressMaxSize Decompress 默认允许的解压结果大小（字节）
+const DefaultDecompressMaxSize = extract.DefaultDecompressMaxSize
+
+// SniffSize 交给 TransformFunc 判断内容的文件开头的字节数
+const SniffSize = extract.SniffSize
+
+// DefaultPreviewBytes ExtractPreview 默认每个文件最多读取的字节数
+const DefaultPreviewBytes = exfatfs.DefaultPreviewBytes
+
+// DefaultReportLimit 提取报告中默认最多列出的有问题条目数
+const DefaultReportLimit = extract.DefaultReportLimit
+
+// 导出归档格式
 const (
-	EndOfClusterChain = 0xFFFFFFFF
-	BadCluster        = 0xFFFFFFF7
-	ReservedCluster   = 0xFFFFFFF8
-)
-
-// ExFATBootSector exFAT 引导扇区结构
-type ExFATBootSector struct {
-	JmpBoot                [3]byte   // 跳转指令
-	FileSystemName         [8]byte   // "EXFAT   "
-	Reserved1              [53]byte  // 保留
-	PartitionOffset        uint64    // 分区偏移
-	VolumeLength           uint64    // 卷长度
-	FatOffset              uint32    // FAT 偏移
-	FatLength              uint32    // FAT 长度
-	ClusterHeapOffset      uint32    // 簇堆偏移
-	ClusterCount           uint32    // 簇数量
-	FirstClusterOfRootDir  uint32    // 根目录第一个簇
-	VolumeSerialNumber     uint32    // 卷序列号
-	FileSystemRevision     uint16    // 文件系统版本
-	VolumeFlags            uint16    // 卷标志
-	BytesPerSectorShift    uint8     // 每扇区字节数的位移
-	SectorsPerClusterShift uint8     // 每簇扇区数的位移
-	NumberOfFats           uint8     // FAT 数量
-	DriveSelect            uint8     // 驱动器选择
-	PercentInUse           uint8     // 使用百分比
-	Reserved2              [7]byte   // 保留
-	BootCode               [390]byte // 引导代码
-	BootSignature          uint16    // 引导签名 (0xAA55)
-}
-
-// ExFATFileEntry exFAT 文件目录条目
-type ExFATFileEntry struct {
-	EntryType                 uint8
-	SecondaryCount            uint8
-	SetChecksum               uint16
-	FileAttributes            uint16
-	Reserved1                 uint16
-	CreateTimestamp           uint32
-	LastModifiedTimestamp     uint32
-	LastAccessedTimestamp     uint32
-	Create10msIncrement       uint8
-	LastModified10msIncrement uint8
-	CreateUtcOffset           uint8
-	LastModifiedUtcOffset     uint8
-	LastAccessedUtcOffset     uint8
-	Reserved2                 [7]byte
-}
-
-// ExFATFileInfoEntry exFAT 文件信息条目
-type ExFATFileInfoEntry struct {
-	EntryType             uint8
-	GeneralSecondaryFlags uint8
-	Reserved1             uint8
-	NameLength            uint8
-	NameHash              uint16
-	Reserved2             uint16
-	ValidDataLength       uint64
-	Reserved3             uint32
-	FirstCluster          uint32
-	DataLength            uint64
-}
-
-// ExFATFileNameEntry exFAT 文件名条目
-type ExFATFileNameEntry struct {
-	EntryType             uint8
-	GeneralSecondaryFlags uint8
-	FileName              [30]byte // UTF-16LE 编码的文件名
-}
-
-// ExFATFileSystem 表示 exFAT 文件系统
-type ExFATFileSystem struct {
-	vhd               io.ReaderAt
-	bootSector        *ExFATBootSector
-	bytesPerSector    uint32
-	sectorsPerCluster uint32
-	bytesPerCluster   uint32
-	fat               []uint32
-	clusterHeapStart  uint64
-	totalClusters     uint32
-}
-
-// VHD 文件类型和常量
+	FormatPax  = extract.FormatPax
+	FormatCpio = extract.FormatCpio
+)
+
+// NewProgressAggregator 创建汇总并发提取进度的汇总器，plannedBytes 可以来自 VHD.TreeSize
+func NewProgressAggregator(plannedBytes int64) *ProgressAggregator {
+	return extract.NewProgressAggregator(plannedBytes)
+}
+
+// NewSampler 创建抽样器，可以通过 WithSampler 在并发的多个提取之间共享上限
+func NewSampler(limits SampleLimits) *Sampler {
+	return extract.NewSampler(limits)
+}
+
+// ParseAttributes 解析 "R--A-" 形式（或 "RA" 这样的字母组合）的属性
+func ParseAttributes(s string) (Attributes, error) {
+	return exfatfs.ParseAttributes(s)
+}
+
+// ParseAttributeChanges 解析类似 attrib 的 "+h-r" 修改语法，返回需要设置和清除的属性位
+func ParseAttributeChanges(s string) (set, clear Attributes, err error) {
+	return exfatfs.ParseAttributeChanges(s)
+}
+
+// ChainTransforms 依次应用多个提取变换，如 ChainTransforms(StripBOM, CRLF)
+func ChainTransforms(fns ...TransformFunc) TransformFunc {
+	return extract.ChainTransforms(fns...)
+}
+
+// Decompress 返回供 WithTransform 使用的函数，把 gzip、zlib 和单文件 zip 解压后写出并去掉后缀
+func Decompress(opts DecompressOptions) func(path string, e FileEntry) TransformFunc {
+	return extract.Decompress(opts)
+}
+
+// JSON 文档的模式标识（见 Envelope）
 const (
-	BlockUnallocated = 0xFFFFFFFF
-	SectorSize       = 512
-	FixedDisk        = 2
-	DynamicDisk      = 3
-)
-
-// VHDHeader VHD 文件头部结构
-type VHDHeader struct {
-	Cookie             [8]byte   // "conectix"
-	Features           uint32    // 功能标志
-	FileFormatVersion  uint32    // 文件格式版本
-	DataOffset         uint64    // 数据偏移（动态磁盘）
-	TimeStamp          uint32    // 时间戳
-	CreatorApplication [4]byte   // 创建应用
-	CreatorVersion     uint32    // 创建版本
-	CreatorHostOS      uint32    // 创建者操作系统
-	OriginalSize       uint64    // 原始大小
-	CurrentSize        uint64    // 当前大小
-	DiskGeometry       uint32    // 磁盘几何
-	DiskType           uint32    // 磁盘类型
-	Checksum           uint32    // 校验和
-	UniqueID           [16]byte  // 唯一ID
-	SavedState         byte      // 保存状态
-	Reserved           [427]byte // 保留字段
-}
-
-// VHDDynamicHeader VHD 动态磁盘头部
-type VHDDynamicHeader struct {
-	Cookie            [8]byte     // "cxsparse"
-	DataOffset        uint64      // 数据偏移
-	TableOffset       uint64      // BAT 表偏移
-	HeaderVersion     uint32      // 头部版本
-	MaxTableEntries   uint32      // 最大表项数
-	BlockSize         uint32      // 块大小
-	Checksum          uint32      // 校验和
-	ParentUniqueID    [16]byte    // 父磁盘ID
-	ParentTimeStamp   uint32      // 父磁盘时间戳
-	Reserved1         uint32      // 保留
-	ParentUnicodeName [512]byte   // 父磁盘名称
-	ParentLocators    [8]struct { // 父磁盘定位器
-		PlatformCode       uint32
-		PlatformDataSpace  uint32
-		PlatformDataLength uint32
-		Reserved           uint32
-		PlatformDataOffset uint64
-	}
-	Reserved2 [256]byte // 保留
-}
-
-// VHDFile 表示一个 VHD 文件
-type VHDFile struct {
-	file          *os.File
-	header        *VHDHeader
-	dynamicHeader *VHDDynamicHeader
-	bat           []uint32 // Block Allocation Table
-	blockSize     uint32
-	isDynamic     bool
+	SchemaTrace    = exfatfs.SchemaTrace
+	SchemaIdentity = exfatfs.SchemaIdentity
+	SchemaReport   = exfatfs.SchemaReport
+)
+
+// NewEnvelope 返回 schema 种类的文档的 Envelope
+func NewEnvelope(schema string) Envelope {
+	return exfatfs.NewEnvelope(schema)
+}
+
+// Generator 返回写出文档的模块和版本，如 "go-exfat v1.4.0"
+func Generator() string {
+	return exfatfs.Generator()
+}
+
+// CheckSchema 检查文档的 schema 字段是否属于 want 的种类且不比它新，返回文档的版本（没有 schema 字段时为 0）
+func CheckSchema(got, want string) (int, error) {
+	return exfatfs.CheckSchema(got, want)
+}
+
+// DecodeTrace 读取 WithReadTracing 写出的跟踪，按顺序返回记录
+func DecodeTrace(r io.Reader) ([]TraceRecord, error) {
+	return exfatfs.DecodeTrace(r)
+}
+
+// DecodeReports 读取 exfat-tool extract -report 写出的报告文件，按顺序返回每个源路径的报告
+func DecodeReports(r io.Reader) ([]SourceReport, error) {
+	return extract.DecodeReports(r)
+}
+
+// TraceCheck 分析 WithReadTracing 写出的跟踪，返回跨越区域边界、用途与区域不符或没有对齐的读取
+func TraceCheck(trace io.Reader, info FSInfo) []TraceFinding {
+	return exfatfs.TraceCheck(trace, info)
+}
+
+// IsText 按文件开头（最多 SniffSize 字节）判断内容是否为文本
+func IsText(head []byte) bool {
+	return extract.IsText(head)
+}
+
+// ParseCheckAction 解析检查策略处理方式的名称：extract、zero、repair、skip 或 fail
+func ParseCheckAction(s string) (CheckAction, error) {
+	return extract.ParseCheckAction(s)
+}
+
+// LayoutByDate 返回按日期分组的提取布局，pattern 是 Go 的 time 布局，如 "2006/01/02"
+func LayoutByDate(pattern string) Layout {
+	return extract.LayoutByDate(pattern)
+}
+
+// ParseLayout 解析提取布局的名称：mirror、flat、date 或 date:<time 布局>
+func ParseLayout(s string) (Layout, error) {
+	return extract.ParseLayout(s)
+}
+
+// ParseDateSource 解析按日期分组时使用的时间：modify 或 create
+func ParseDateSource(s string) (DateSource, error) {
+	return extract.ParseDateSource(s)
 }